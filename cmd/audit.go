@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"io"
+	"os"
+)
+
+// openAuditLog opens the audit log of the commands run once, appending to the file at the path or writing to stderr
+// when the path is empty so the audit events don't mix with the command output, and returns a function closing it
+func openAuditLog(path string) (io.WriteCloser, func(), error) {
+	if path == "" {
+		return os.Stderr, func() {}, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, func() { _ = f.Close() }, nil
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/spf13/cobra"
+)
+
+// auditSchemaCmd prints the JSON schema of the audit event targets
+var auditSchemaCmd = &cobra.Command{
+	Use:   "audit-schema",
+	Short: "print the JSON schema of the audit event targets",
+	Long: `Prints the JSON schema of the audit event targets written by gov-okta-addon, with a definition per audit event
+type. The schema is also checked in at docs/audit-events.schema.json.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		schema, err := auctx.JSONSchema(auctx.Payloads...)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(schema))
+
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditSchemaCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_openAuditLog(t *testing.T) {
+	w, closeAudit, err := openAuditLog("")
+	require.NoError(t, err)
+	closeAudit()
+
+	// the one-shot commands write their results to stdout, the audit events must not mix with them
+	assert.Equal(t, os.Stderr, w)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	w, closeAudit, err = openAuditLog(path)
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("{}\n"))
+	require.NoError(t, err)
+
+	closeAudit()
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(got))
+}
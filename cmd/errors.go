@@ -3,22 +3,6 @@ package cmd
 import "errors"
 
 var (
-	// ErrNATSURLRequired is returned when a NATS url is missing
-	ErrNATSURLRequired = errors.New("nats url is required and cannot be empty")
-	// ErrOktaURLRequired is returned when an Okta URL is missing
-	ErrOktaURLRequired = errors.New("okta url is required and cannot be empty")
-	// ErrOktaTokenRequired is returned when an Okta token is missing
-	ErrOktaTokenRequired = errors.New("okta token is required and cannot be empty")
-	// ErrGovernorURLRequired is returned when a governor URL is missing
-	ErrGovernorURLRequired = errors.New("governor url is required and cannot be empty")
-	// ErrGovernorClientIDRequired is returned when a governor client id is missing
-	ErrGovernorClientIDRequired = errors.New("governor oauth client id is required and cannot be empty")
-	// ErrGovernorClientSecretRequired is returned when a governor client secret is missing
-	ErrGovernorClientSecretRequired = errors.New("governor oauth client secret is required and cannot be empty")
-	// ErrGovernorClientTokenURLRequired is returned when a governor token url is missing
-	ErrGovernorClientTokenURLRequired = errors.New("governor oauth client token url is required and cannot be empty")
-	// ErrGovernorClientAudienceRequired is returned when a governor client audience is missing
-	ErrGovernorClientAudienceRequired = errors.New("governor oauth client audience is required and cannot be empty")
 	// ErrOktaUserExternalIDNotString is returned when the okta user profile contains an external id that's not a string
 	ErrOktaUserExternalIDNotString = errors.New("okta user external id in profile is not a string")
 	// ErrOktaUserEmailNotString is returned when the okta user profile contains an email that's not a string
@@ -33,6 +17,36 @@ var (
 	ErrOktaUserTypeNotString = errors.New("okta user type in profile is not a string")
 	// ErrUserNotFound is returned when a user isn't found in the system
 	ErrUserNotFound = errors.New("user not found")
-	// ErrMissingNATSCreds is returned when nats creds are not provided
-	ErrMissingNATSCreds = errors.New("nats creds are required")
+	// ErrGroupNotFound is returned when a group isn't found in the system
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrGovernorGroupUpdate is returned when updating a governor group is unsuccessful
+	ErrGovernorGroupUpdate = errors.New("failed to update governor group")
+	// ErrStatusRequest is returned when the addon status can't be queried
+	ErrStatusRequest = errors.New("failed to get addon status")
+	// ErrLastRunFailed is returned when the last reconciler loop failed
+	ErrLastRunFailed = errors.New("last reconciler loop failed")
+	// ErrLogFormatInvalid is returned when the log format isn't json or console
+	ErrLogFormatInvalid = errors.New("log format must be json or console")
+	// ErrMissingNATSCreds is returned when no nats creds file, nkey, token or tls client certificate is provided
+	ErrMissingNATSCreds = errors.New("nats creds file, nkey file, token or tls client certificate are required")
+	// ErrNATSNKeyInvalid is returned when the nats nkey seed file can't be used
+	ErrNATSNKeyInvalid = errors.New("invalid nats nkey seed file")
+	// ErrNATSTokenFileEmpty is returned when the nats token file is empty
+	ErrNATSTokenFileEmpty = errors.New("nats token file is empty")
+	// ErrProfileKeyInvalid is returned when a migrated okta group profile key isn't a valid attribute name
+	ErrProfileKeyInvalid = errors.New("profile key must start with a letter and only contain letters, digits and underscores")
+	// ErrProfileKeyUnchanged is returned when the okta group profile key is migrated to itself
+	ErrProfileKeyUnchanged = errors.New("profile key to migrate to must differ from the key to migrate from")
+	// ErrProfileKeyNotVerified is returned when the migrated okta group profile key doesn't have the copied value
+	ErrProfileKeyNotVerified = errors.New("migrated profile key doesn't have the copied value")
+	// ErrMigrationIncomplete is returned when some okta groups couldn't be migrated
+	ErrMigrationIncomplete = errors.New("migration incomplete")
+	// ErrSyncIncomplete is returned when some governor groups couldn't be synced
+	ErrSyncIncomplete = errors.New("sync incomplete")
+	// ErrReportDaysInvalid is returned when the number of days of a report isn't positive
+	ErrReportDaysInvalid = errors.New("report days must be positive")
+	// ErrReportFormatInvalid is returned when the format of a report isn't json or csv
+	ErrReportFormatInvalid = errors.New("report format must be json or csv")
+	// ErrSuspendIncomplete is returned when some inactive governor users couldn't be suspended
+	ErrSuspendIncomplete = errors.New("suspending inactive users incomplete")
 )
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govauth"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// inspectCmd shows the read-only reconciliation state of governor and okta resources
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "inspect the reconciliation state of governor and okta resources",
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		// bind here instead of init so we don't clobber the serve and sync command bindings for the same keys
+		viperBindFlag("okta.url", cmd.Flags().Lookup("okta-url"))
+		viperBindFlag("okta.token", cmd.Flags().Lookup("okta-token"))
+		viperBindFlag("okta.nocache", cmd.Flags().Lookup("okta-nocache"))
+		viperBindFlag("okta.call-timeout", cmd.Flags().Lookup("okta-call-timeout"))
+		viperBindFlag("okta.list-timeout", cmd.Flags().Lookup("okta-list-timeout"))
+		viperBindFlag("okta.secondary-tokens", cmd.Flags().Lookup("okta-secondary-tokens"))
+		viperBindFlag("okta.token-strategy", cmd.Flags().Lookup("okta-token-strategy"))
+		viperBindFlag("governor.url", cmd.Flags().Lookup("governor-url"))
+		viperBindFlag("governor.client-id", cmd.Flags().Lookup("governor-client-id"))
+		viperBindFlag("governor.client-secret", cmd.Flags().Lookup("governor-client-secret"))
+		viperBindFlag("governor.token-url", cmd.Flags().Lookup("governor-token-url"))
+		viperBindFlag("governor.audience", cmd.Flags().Lookup("governor-audience"))
+		viperBindFlag("governor.token-skew", cmd.Flags().Lookup("governor-token-skew"))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	// Okta related flags
+	inspectCmd.PersistentFlags().String("okta-url", "https://example.okta.com", "url for Okta client calls")
+	inspectCmd.PersistentFlags().String("okta-token", "", "token for access to the Okta API")
+	inspectCmd.PersistentFlags().Bool("okta-nocache", false, "disable the okta client cache, useful for development")
+	inspectCmd.PersistentFlags().Duration("okta-call-timeout", okta.DefaultCallTimeout, "deadline for a single okta call, negative disables it")
+	inspectCmd.PersistentFlags().Duration("okta-list-timeout", okta.DefaultListTimeout, "deadline for okta calls listing all results, negative disables it")
+	inspectCmd.PersistentFlags().StringSlice("okta-secondary-tokens", []string{}, "additional okta api tokens to spread requests over, depends on the org rate limit policy")
+	inspectCmd.PersistentFlags().String("okta-token-strategy", okta.TokenStrategyRoundRobin, "how the okta api token of each request is selected (round-robin or least-used)")
+
+	// Governor related flags
+	inspectCmd.PersistentFlags().String("governor-url", "https://api.governor.metalkube.net", "url of the governor api")
+	inspectCmd.PersistentFlags().String("governor-client-id", "gov-okta-addon-governor", "oauth client ID for client credentials flow")
+	inspectCmd.PersistentFlags().String("governor-client-secret", "", "oauth client secret for client credentials flow")
+	inspectCmd.PersistentFlags().String("governor-token-url", "http://hydra:4444/oauth2/token", "url used for client credential flow")
+	inspectCmd.PersistentFlags().String("governor-audience", "https://api.governor.metalkube.net", "oauth audience for client credential flow")
+	inspectCmd.PersistentFlags().Duration("governor-token-skew", govauth.DefaultSkew, "how long before it expires the governor token is refreshed")
+}
+
+// newInspectClients returns the okta and read-only governor clients used by the inspect commands
+func newInspectClients() (*okta.Client, *governor.Client, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newCommandClients(cfg, governorScopes(cfg, "inspect")...)
+}
+
+// newCommandClients returns the okta client and the governor client with the scopes of the configuration of a
+// command run once, ie. inspect or report
+func newCommandClients(cfg *config.Config, scopes ...string) (*okta.Client, *governor.Client, error) {
+
+	if err := errors.Join(cfg.Okta.Validate(), cfg.Governor.Validate()); err != nil {
+		return nil, nil, err
+	}
+
+	oc, err := okta.NewClient(
+		okta.WithLogger(logger.Desugar()),
+		okta.WithURL(cfg.Okta.URL),
+		okta.WithToken(cfg.Okta.Token),
+		okta.WithCache(!cfg.Okta.NoCache),
+		okta.WithCallTimeout(cfg.Okta.CallTimeout),
+		okta.WithListTimeout(cfg.Okta.ListTimeout),
+		okta.WithSecondaryTokens(cfg.Okta.SecondaryTokens),
+		okta.WithTokenStrategy(cfg.Okta.TokenStrategy),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gc, err := newGovernorClient(logger.Desugar(), cfg.Governor, &http.Client{Timeout: governorTimeout}, scopes...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return oc, gc, nil
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gofrs/uuid"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// inspectTablePadding is the padding between the columns of the inspect tables
+	inspectTablePadding = 2
+)
+
+const (
+	inspectStateOK          = "ok"
+	inspectStateMissing     = "missing in okta"
+	inspectStateExtra       = "not in governor"
+	inspectStatePending     = "skipped (pending)"
+	inspectStateNoExternal  = "skipped (no external id)"
+	inspectStateNotAssigned = "not assigned in okta"
+	inspectStateAssigned    = "assigned in okta, not in governor"
+)
+
+// inspectGroupCmd shows the reconciliation state of a single governor group
+var inspectGroupCmd = &cobra.Command{
+	Use:   "group <slug|id>",
+	Short: "show the reconciliation state of a governor group",
+	Long: `Shows the Governor group, the matched Okta group (by governor_id), the membership diff and the
+application assignment diff between Governor and Okta. Nothing is changed in either system.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return inspectGroup(cmd.Context(), os.Stdout, args[0])
+	},
+}
+
+// inspectMemberRow is a row of the group membership diff
+type inspectMemberRow struct {
+	Email      string
+	GovernorID string
+	OktaID     string
+	State      string
+}
+
+// inspectAppRow is a row of the group application assignment diff
+type inspectAppRow struct {
+	Org   string
+	AppID string
+	State string
+}
+
+func init() {
+	inspectCmd.AddCommand(inspectGroupCmd)
+}
+
+func inspectGroup(ctx context.Context, out io.Writer, ident string) error {
+	oc, gc, err := newInspectClients()
+	if err != nil {
+		return err
+	}
+
+	group, err := findGovernorGroup(ctx, gc, ident)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, inspectTablePadding, ' ', 0)
+
+	fmt.Fprintf(w, "GOVERNOR GROUP\t%s\n", group.ID)
+	fmt.Fprintf(w, "  name\t%s\n", group.Name)
+	fmt.Fprintf(w, "  slug\t%s\n", group.Slug)
+	fmt.Fprintf(w, "  members\t%d\n", len(group.Members))
+
+	oktaGroupID, err := oc.GetGroupByGovernorID(ctx, group.ID)
+	if err != nil {
+		if !errors.Is(err, okta.ErrGroupsNotFound) {
+			return err
+		}
+
+		fmt.Fprintf(w, "OKTA GROUP\tnot found\n")
+
+		return w.Flush()
+	}
+
+	fmt.Fprintf(w, "OKTA GROUP\t%s\n\n", oktaGroupID)
+
+	govUsers, err := governorGroupMemberUsers(ctx, gc, group.ID)
+	if err != nil {
+		return err
+	}
+
+	oktaMembers, err := oc.ListGroupMembership(ctx, oktaGroupID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "EMAIL\tGOVERNOR USER\tOKTA USER\tMEMBERSHIP\n")
+
+	for _, row := range inspectMembershipDiff(govUsers, oktaMembers) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.Email, row.GovernorID, row.OktaID, row.State)
+	}
+
+	govOrgs, err := gc.Organizations(ctx)
+	if err != nil {
+		return err
+	}
+
+	apps, err := oc.GithubCloudApplications(ctx)
+	if err != nil {
+		return err
+	}
+
+	assigned := map[string]bool{}
+
+	for org, appID := range apps {
+		groups, err := oc.ListGroupApplicationAssignment(ctx, appID)
+		if err != nil {
+			return err
+		}
+
+		assigned[org] = contains(groups, oktaGroupID)
+	}
+
+	fmt.Fprintf(w, "\nORGANIZATION\tOKTA APP\tASSIGNMENT\n")
+
+	for _, row := range inspectApplicationDiff(group, govOrgs, apps, assigned) {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", row.Org, row.AppID, row.State)
+	}
+
+	return w.Flush()
+}
+
+// findGovernorGroup gets the governor group details by id or slug
+func findGovernorGroup(ctx context.Context, gc *governor.Client, ident string) (*v1alpha1.Group, error) {
+	if _, err := uuid.FromString(ident); err == nil {
+		return gc.Group(ctx, ident, false)
+	}
+
+	groups, err := gc.Groups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range groups {
+		if g.Slug == ident {
+			return gc.Group(ctx, g.ID, false)
+		}
+	}
+
+	return nil, ErrGroupNotFound
+}
+
+// governorGroupMemberUsers returns the governor users that are members of the group, fetched in bulk by email
+func governorGroupMemberUsers(ctx context.Context, gc *governor.Client, gid string) ([]*v1beta1.User, error) {
+	members, err := gc.GroupMembers(ctx, gid)
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make([]string, 0, len(members))
+
+	for _, m := range members {
+		if m.Email != "" {
+			emails = append(emails, m.Email)
+		}
+	}
+
+	return govclient.UsersByEmail(ctx, emails, gc.UsersV2)
+}
+
+// inspectMembershipDiff compares the governor group members to the okta group members, governor users
+// are matched to okta users by their external id the same way the reconciler does
+func inspectMembershipDiff(govUsers []*v1beta1.User, oktaMembers []*okt.User) []inspectMemberRow {
+	rows := []inspectMemberRow{}
+	matched := map[string]bool{}
+
+	oktaIDs := map[string]bool{}
+
+	for _, m := range oktaMembers {
+		oktaIDs[m.Id] = true
+	}
+
+	for _, u := range govUsers {
+		row := inspectMemberRow{
+			Email:      u.Email,
+			GovernorID: u.ID,
+			OktaID:     u.ExternalID.String,
+		}
+
+		switch {
+		case u.Status.String == v1alpha1.UserStatusPending:
+			row.State = inspectStatePending
+		case u.ExternalID.String == "":
+			row.State = inspectStateNoExternal
+		case oktaIDs[u.ExternalID.String]:
+			row.State = inspectStateOK
+			matched[u.ExternalID.String] = true
+		default:
+			row.State = inspectStateMissing
+		}
+
+		rows = append(rows, row)
+	}
+
+	for _, m := range oktaMembers {
+		if matched[m.Id] {
+			continue
+		}
+
+		email, _ := okta.EmailFromUserProfile(m)
+
+		rows = append(rows, inspectMemberRow{
+			Email:  email,
+			OktaID: m.Id,
+			State:  inspectStateExtra,
+		})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return strings.ToLower(rows[i].Email) < strings.ToLower(rows[j].Email)
+	})
+
+	return rows
+}
+
+// inspectApplicationDiff compares the governor group organizations to the okta github cloud application
+// assignments, okta applications for organizations not managed by governor are ignored like in the reconciler
+func inspectApplicationDiff(group *v1alpha1.Group, govOrgs []*v1alpha1.Organization, apps map[string]string, assigned map[string]bool) []inspectAppRow {
+	managed := map[string]bool{}
+
+	for _, o := range govOrgs {
+		managed[o.Slug] = true
+	}
+
+	want := map[string]bool{}
+
+	for _, id := range group.Organizations {
+		for _, o := range govOrgs {
+			if o.ID == id {
+				want[o.Slug] = true
+			}
+		}
+	}
+
+	rows := []inspectAppRow{}
+
+	for org, appID := range apps {
+		if !managed[org] {
+			continue
+		}
+
+		row := inspectAppRow{Org: org, AppID: appID}
+
+		switch {
+		case want[org] && assigned[org]:
+			row.State = inspectStateOK
+		case want[org]:
+			row.State = inspectStateNotAssigned
+		case assigned[org]:
+			row.State = inspectStateAssigned
+		default:
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Org < rows[j].Org })
+
+	return rows
+}
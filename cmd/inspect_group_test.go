@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+)
+
+func testInspectGovUser(t *testing.T, j string) *v1beta1.User {
+	t.Helper()
+
+	u := &v1beta1.User{}
+	if err := json.Unmarshal([]byte(j), u); err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func Test_inspectMembershipDiff(t *testing.T) {
+	govUsers := []*v1beta1.User{
+		testInspectGovUser(t, `{"id":"gov-1","email":"a@example.com","external_id":"okta-1","status":"active"}`),
+		testInspectGovUser(t, `{"id":"gov-2","email":"b@example.com","external_id":"okta-2","status":"active"}`),
+		testInspectGovUser(t, `{"id":"gov-3","email":"c@example.com","status":"pending"}`),
+		testInspectGovUser(t, `{"id":"gov-4","email":"d@example.com","status":"active"}`),
+	}
+
+	oktaMembers := []*okt.User{
+		{Id: "okta-1", Profile: &okt.UserProfile{"email": "a@example.com"}},
+		{Id: "okta-5", Profile: &okt.UserProfile{"email": "e@example.com"}},
+	}
+
+	want := []inspectMemberRow{
+		{Email: "a@example.com", GovernorID: "gov-1", OktaID: "okta-1", State: inspectStateOK},
+		{Email: "b@example.com", GovernorID: "gov-2", OktaID: "okta-2", State: inspectStateMissing},
+		{Email: "c@example.com", GovernorID: "gov-3", State: inspectStatePending},
+		{Email: "d@example.com", GovernorID: "gov-4", State: inspectStateNoExternal},
+		{Email: "e@example.com", OktaID: "okta-5", State: inspectStateExtra},
+	}
+
+	assert.Equal(t, want, inspectMembershipDiff(govUsers, oktaMembers))
+}
+
+func Test_inspectApplicationDiff(t *testing.T) {
+	orgs := []*v1alpha1.Organization{}
+	if err := json.Unmarshal([]byte(`[{"id":"org-1","slug":"one"},{"id":"org-2","slug":"two"},{"id":"org-3","slug":"three"}]`), &orgs); err != nil {
+		t.Fatal(err)
+	}
+
+	group := &v1alpha1.Group{Organizations: []string{"org-1", "org-2"}}
+
+	apps := map[string]string{
+		"one":       "app-1",
+		"two":       "app-2",
+		"three":     "app-3",
+		"unmanaged": "app-4",
+	}
+
+	assigned := map[string]bool{
+		"one":       true,
+		"three":     true,
+		"unmanaged": true,
+	}
+
+	want := []inspectAppRow{
+		{Org: "one", AppID: "app-1", State: inspectStateOK},
+		{Org: "three", AppID: "app-3", State: inspectStateAssigned},
+		{Org: "two", AppID: "app-2", State: inspectStateNotAssigned},
+	}
+
+	assert.Equal(t, want, inspectApplicationDiff(group, orgs, apps, assigned))
+}
@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	oktaUserStatusActive        = "ACTIVE"
+	oktaUserStatusSuspended     = "SUSPENDED"
+	oktaUserStatusDeprovisioned = "DEPROVISIONED"
+
+	// inspectNone is printed for values that don't exist in one of the systems
+	inspectNone = "-"
+)
+
+// inspectUserCmd shows the reconciliation state of a single user
+var inspectUserCmd = &cobra.Command{
+	Use:   "user <email>",
+	Short: "show the reconciliation state of a user",
+	Long: `Shows the Governor user and the Okta user with the given email side by side, including their status, ids
+and managed group memberships, along with suggested remediations for any differences. Nothing is changed in either system.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return inspectUser(cmd.Context(), os.Stdout, args[0])
+	},
+}
+
+// inspectUserGroupRow is a row of the user group membership comparison
+type inspectUserGroupRow struct {
+	Group    string
+	Governor bool
+	Okta     bool
+}
+
+func init() {
+	inspectCmd.AddCommand(inspectUserCmd)
+}
+
+func inspectUser(ctx context.Context, out io.Writer, email string) error {
+	oc, gc, err := newInspectClients()
+	if err != nil {
+		return err
+	}
+
+	govUser, err := findGovernorUser(ctx, gc, email)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+
+	oktaUser, oktaGroups, err := findOktaUser(ctx, oc, email)
+	if err != nil {
+		return err
+	}
+
+	if govUser == nil && oktaUser == nil {
+		return ErrUserNotFound
+	}
+
+	govGroups, err := gc.Groups(ctx)
+	if err != nil {
+		return err
+	}
+
+	slugs := make(map[string]string, len(govGroups))
+
+	for _, g := range govGroups {
+		slugs[g.ID] = g.Slug
+	}
+
+	oktaGroupGovIDs := []string{}
+
+	for _, g := range oktaGroups {
+		gid, err := okta.GroupGovernorID(g)
+		if err != nil {
+			// not managed by governor
+			continue
+		}
+
+		oktaGroupGovIDs = append(oktaGroupGovIDs, gid)
+	}
+
+	var govGroupIDs []string
+	if govUser != nil {
+		govGroupIDs = govUser.Memberships
+	}
+
+	groupRows := inspectUserGroups(govGroupIDs, oktaGroupGovIDs, slugs)
+
+	w := tabwriter.NewWriter(out, 0, 0, inspectTablePadding, ' ', 0)
+
+	fmt.Fprintf(w, "FIELD\tGOVERNOR\tOKTA\n")
+
+	for _, row := range inspectUserFields(govUser, oktaUser, groupRows) {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", row[0], row[1], row[2])
+	}
+
+	if len(groupRows) > 0 {
+		fmt.Fprintf(w, "\nGROUP\tGOVERNOR\tOKTA\n")
+
+		for _, row := range groupRows {
+			fmt.Fprintf(w, "%s\t%t\t%t\n", row.Group, row.Governor, row.Okta)
+		}
+	}
+
+	fmt.Fprintf(w, "\nSUGGESTED REMEDIATION\n")
+
+	remediations := inspectUserRemediations(govUser, oktaUser, groupRows)
+	if len(remediations) == 0 {
+		fmt.Fprintf(w, "  none\n")
+	}
+
+	for _, r := range remediations {
+		fmt.Fprintf(w, "  %s\n", r)
+	}
+
+	return w.Flush()
+}
+
+// findGovernorUser gets the governor user details (including deleted users) by email
+func findGovernorUser(ctx context.Context, gc *governor.Client, email string) (*v1alpha1.User, error) {
+	users, err := gc.UsersQuery(ctx, map[string][]string{"email": {email}, "deleted": {""}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	// prefer the live user when a deleted user with the same email exists
+	sort.SliceStable(users, func(i, j int) bool { return !users[i].DeletedAt.Valid && users[j].DeletedAt.Valid })
+
+	return gc.User(ctx, users[0].ID, users[0].DeletedAt.Valid)
+}
+
+// findOktaUser gets the okta user and its groups by email, a missing user returns nil without an error
+func findOktaUser(ctx context.Context, oc *okta.Client, email string) (*okt.User, []*okt.Group, error) {
+	uid, err := oc.GetUserIDByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, okta.ErrUnexpectedUsersCount) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	user, err := oc.GetUser(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups, err := oc.ListUserGroups(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, groups, nil
+}
+
+// inspectUserGroups compares the governor group memberships of a user to the governor managed okta groups
+// the user is a member of, keyed by the governor group id
+func inspectUserGroups(govGroupIDs, oktaGroupGovIDs []string, slugs map[string]string) []inspectUserGroupRow {
+	rows := map[string]*inspectUserGroupRow{}
+
+	row := func(id string) *inspectUserGroupRow {
+		if _, ok := rows[id]; !ok {
+			name := slugs[id]
+			if name == "" {
+				name = id
+			}
+
+			rows[id] = &inspectUserGroupRow{Group: name}
+		}
+
+		return rows[id]
+	}
+
+	for _, id := range govGroupIDs {
+		row(id).Governor = true
+	}
+
+	for _, id := range oktaGroupGovIDs {
+		row(id).Okta = true
+	}
+
+	out := make([]inspectUserGroupRow, 0, len(rows))
+
+	for _, r := range rows {
+		out = append(out, *r)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Group < out[j].Group })
+
+	return out
+}
+
+// inspectUserFields returns the field, governor value and okta value rows of the user comparison
+func inspectUserFields(govUser *v1alpha1.User, oktaUser *okt.User, groups []inspectUserGroupRow) [][3]string {
+	govVals := map[string]string{}
+	oktaVals := map[string]string{}
+
+	if govUser != nil {
+		govVals["id"] = govUser.ID
+		govVals["external id"] = govUser.ExternalID.String
+		govVals["email"] = govUser.Email
+		govVals["status"] = govUser.Status.String
+
+		if govUser.DeletedAt.Valid {
+			govVals["deleted"] = govUser.DeletedAt.Time.String()
+		}
+	}
+
+	if oktaUser != nil {
+		oktaVals["id"] = oktaUser.Id
+		oktaVals["status"] = oktaUser.Status
+		oktaVals["email"], _ = okta.EmailFromUserProfile(oktaUser)
+	}
+
+	govCount, oktaCount := 0, 0
+
+	for _, g := range groups {
+		if g.Governor {
+			govCount++
+		}
+
+		if g.Okta {
+			oktaCount++
+		}
+	}
+
+	rows := [][3]string{}
+
+	for _, f := range []string{"id", "external id", "email", "status", "deleted"} {
+		rows = append(rows, [3]string{f, inspectValue(govVals[f]), inspectValue(oktaVals[f])})
+	}
+
+	return append(rows, [3]string{"managed groups", fmt.Sprint(govCount), fmt.Sprint(oktaCount)})
+}
+
+// inspectUserRemediations suggests how to fix the differences between the governor and okta user
+func inspectUserRemediations(govUser *v1alpha1.User, oktaUser *okt.User, groups []inspectUserGroupRow) []string {
+	switch {
+	case govUser == nil:
+		return []string{"user missing in governor, run `sync users` to create it from okta"}
+	case oktaUser == nil && govUser.DeletedAt.Valid:
+		return nil
+	case oktaUser == nil:
+		return []string{"user missing in okta, create the okta user and set the governor external_id to its id"}
+	}
+
+	out := []string{}
+
+	switch {
+	case govUser.ExternalID.String == "":
+		out = append(out, fmt.Sprintf("external_id missing in governor, set it to the okta user id %s", oktaUser.Id))
+	case govUser.ExternalID.String != oktaUser.Id:
+		out = append(out, fmt.Sprintf("governor external_id %s doesn't match the okta user id %s", govUser.ExternalID.String, oktaUser.Id))
+	}
+
+	switch {
+	case govUser.DeletedAt.Valid:
+		if oktaUser.Status != oktaUserStatusDeprovisioned {
+			out = append(out, "user is deleted in governor but not deactivated in okta, deactivate or delete the okta user")
+		}
+
+		// group memberships don't matter for deleted users
+		return out
+	case govUser.Status.String == v1alpha1.UserStatusPending:
+		out = append(out, "user is pending in governor and is skipped by the reconciler until it's activated")
+	case govUser.Status.String == v1alpha1.UserStatusSuspended && oktaUser.Status == oktaUserStatusActive:
+		out = append(out, "user is suspended in governor but active in okta, suspend the okta user")
+	case govUser.Status.String == v1alpha1.UserStatusActive && oktaUser.Status == oktaUserStatusSuspended:
+		out = append(out, "user is active in governor but suspended in okta, unsuspend the okta user")
+	case govUser.Status.String == v1alpha1.UserStatusActive && oktaUser.Status == oktaUserStatusDeprovisioned:
+		out = append(out, "user is active in governor but deactivated in okta, reactivate the okta user or delete the governor user")
+	}
+
+	for _, g := range groups {
+		switch {
+		case g.Governor && !g.Okta:
+			out = append(out, fmt.Sprintf("member of %s in governor but not in okta, reconcile the group", g.Group))
+		case g.Okta && !g.Governor:
+			out = append(out, fmt.Sprintf("member of %s in okta but not in governor, reconcile the group", g.Group))
+		}
+	}
+
+	return out
+}
+
+func inspectValue(v string) string {
+	if strings.TrimSpace(v) == "" {
+		return inspectNone
+	}
+
+	return v
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+)
+
+func testInspectGovAlphaUser(t *testing.T, j string) *v1alpha1.User {
+	t.Helper()
+
+	u := &v1alpha1.User{}
+	if err := json.Unmarshal([]byte(j), u); err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func Test_inspectUserGroups(t *testing.T) {
+	slugs := map[string]string{"gov-1": "one", "gov-2": "two", "gov-3": "three"}
+
+	want := []inspectUserGroupRow{
+		{Group: "one", Governor: true, Okta: true},
+		{Group: "three", Okta: true},
+		{Group: "two", Governor: true},
+		{Group: "unknown-id", Okta: true},
+	}
+
+	assert.Equal(t, want, inspectUserGroups([]string{"gov-1", "gov-2"}, []string{"gov-1", "gov-3", "unknown-id"}, slugs))
+}
+
+func Test_inspectUserRemediations(t *testing.T) {
+	tests := []struct {
+		name     string
+		govUser  string
+		oktaUser *okt.User
+		groups   []inspectUserGroupRow
+		want     []string
+	}{
+		{
+			name:     "missing in governor",
+			oktaUser: &okt.User{Id: "okta-1", Status: oktaUserStatusActive},
+			want:     []string{"user missing in governor, run `sync users` to create it from okta"},
+		},
+		{
+			name:    "missing in okta",
+			govUser: `{"id":"gov-1","external_id":"okta-1","status":"active"}`,
+			want:    []string{"user missing in okta, create the okta user and set the governor external_id to its id"},
+		},
+		{
+			name:    "deleted in governor and missing in okta",
+			govUser: `{"id":"gov-1","external_id":"okta-1","status":"active","deleted_at":"2023-01-01T00:00:00Z"}`,
+			want:    nil,
+		},
+		{
+			name:     "in sync",
+			govUser:  `{"id":"gov-1","external_id":"okta-1","status":"active"}`,
+			oktaUser: &okt.User{Id: "okta-1", Status: oktaUserStatusActive},
+			groups:   []inspectUserGroupRow{{Group: "one", Governor: true, Okta: true}},
+			want:     []string{},
+		},
+		{
+			name:     "external id missing",
+			govUser:  `{"id":"gov-1","status":"active"}`,
+			oktaUser: &okt.User{Id: "okta-1", Status: oktaUserStatusActive},
+			want:     []string{"external_id missing in governor, set it to the okta user id okta-1"},
+		},
+		{
+			name:     "external id mismatch",
+			govUser:  `{"id":"gov-1","external_id":"okta-2","status":"active"}`,
+			oktaUser: &okt.User{Id: "okta-1", Status: oktaUserStatusActive},
+			want:     []string{"governor external_id okta-2 doesn't match the okta user id okta-1"},
+		},
+		{
+			name:     "suspended in governor",
+			govUser:  `{"id":"gov-1","external_id":"okta-1","status":"suspended"}`,
+			oktaUser: &okt.User{Id: "okta-1", Status: oktaUserStatusActive},
+			want:     []string{"user is suspended in governor but active in okta, suspend the okta user"},
+		},
+		{
+			name:     "suspended in okta",
+			govUser:  `{"id":"gov-1","external_id":"okta-1","status":"active"}`,
+			oktaUser: &okt.User{Id: "okta-1", Status: oktaUserStatusSuspended},
+			want:     []string{"user is active in governor but suspended in okta, unsuspend the okta user"},
+		},
+		{
+			name:     "deleted in governor",
+			govUser:  `{"id":"gov-1","external_id":"okta-1","status":"active","deleted_at":"2023-01-01T00:00:00Z"}`,
+			oktaUser: &okt.User{Id: "okta-1", Status: oktaUserStatusActive},
+			groups:   []inspectUserGroupRow{{Group: "one", Okta: true}},
+			want:     []string{"user is deleted in governor but not deactivated in okta, deactivate or delete the okta user"},
+		},
+		{
+			name:     "group differences",
+			govUser:  `{"id":"gov-1","external_id":"okta-1","status":"active"}`,
+			oktaUser: &okt.User{Id: "okta-1", Status: oktaUserStatusActive},
+			groups: []inspectUserGroupRow{
+				{Group: "one", Governor: true},
+				{Group: "two", Okta: true},
+			},
+			want: []string{
+				"member of one in governor but not in okta, reconcile the group",
+				"member of two in okta but not in governor, reconcile the group",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var govUser *v1alpha1.User
+			if tt.govUser != "" {
+				govUser = testInspectGovAlphaUser(t, tt.govUser)
+			}
+
+			assert.Equal(t, tt.want, inspectUserRemediations(govUser, tt.oktaUser, tt.groups))
+		})
+	}
+}
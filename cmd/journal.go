@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+// journalBucketName is the jetstream key-value bucket the change journal is stored in
+const journalBucketName = appName + "-journal"
+
+// journalCmd works with the change journal of applied okta mutations
+var journalCmd = &cobra.Command{
+	Use:   "journal",
+	Short: "work with the change journal of applied okta mutations",
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		// bind here instead of init so we don't clobber the serve command bindings for the same keys
+		viperBindFlag("nats.url", cmd.Flags().Lookup("nats-url"))
+		viperBindFlag("nats.creds-file", cmd.Flags().Lookup("nats-creds-file"))
+		bindNATSAuthFlags(cmd.Flags())
+	},
+}
+
+// journalQueryCmd queries the change journal
+var journalQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "query the change journal",
+	Long: `Queries the change journal of applied Okta mutations and writes the matching entries as JSON, one per line,
+ordered by time. Entries can be filtered by group id, user id (governor or Okta), mutation type and time range.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return queryJournal(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(journalCmd)
+	journalCmd.AddCommand(journalQueryCmd)
+
+	journalCmd.PersistentFlags().String("nats-url", "nats://127.0.0.1:4222", "NATS server connection url")
+	journalCmd.PersistentFlags().String("nats-creds-file", "", "Path to the file containing the NATS credentials file")
+	addNATSAuthFlags(journalCmd.PersistentFlags())
+
+	journalQueryCmd.Flags().String("group", "", "only return entries for this governor or okta group id")
+	journalQueryCmd.Flags().String("user", "", "only return entries for this governor or okta user id")
+	journalQueryCmd.Flags().String("type", "", "only return entries of this type (ie. GroupMemberAdd)")
+	journalQueryCmd.Flags().String("since", "", "only return entries at or after this time (RFC3339)")
+	journalQueryCmd.Flags().String("until", "", "only return entries at or before this time (RFC3339)")
+}
+
+func queryJournal(cmd *cobra.Command) error {
+	filter, err := journalFilterFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.NATS.Validate(); err != nil {
+		return err
+	}
+
+	nc, natsClose, err := newNATSConnection(cfg.NATS)
+	if err != nil {
+		return err
+	}
+
+	defer natsClose()
+
+	// retention only applies when the bucket doesn't exist yet
+	j, err := newJournal(nc, journal.DefaultRetention)
+	if err != nil {
+		return err
+	}
+
+	entries, err := j.Query(cmd.Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	logger.Debugw("got journal entries", "num.entries", len(entries))
+
+	enc := json.NewEncoder(os.Stdout)
+
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// journalFilterFromFlags builds a journal filter from the query command flags
+func journalFilterFromFlags(cmd *cobra.Command) (*journal.Filter, error) {
+	f := &journal.Filter{}
+
+	var err error
+
+	if f.GroupID, err = cmd.Flags().GetString("group"); err != nil {
+		return nil, err
+	}
+
+	if f.UserID, err = cmd.Flags().GetString("user"); err != nil {
+		return nil, err
+	}
+
+	if f.Type, err = cmd.Flags().GetString("type"); err != nil {
+		return nil, err
+	}
+
+	if f.Since, err = timeFlag(cmd, "since"); err != nil {
+		return nil, err
+	}
+
+	if f.Until, err = timeFlag(cmd, "until"); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// timeFlag parses an optional RFC3339 time flag, an empty value returns the zero time
+func timeFlag(cmd *cobra.Command, name string) (time.Time, error) {
+	v, err := cmd.Flags().GetString(name)
+	if err != nil || v == "" {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// defaultLogSampleInitial is the default number of lines logged each second with the same message and level
+	// before sampling
+	defaultLogSampleInitial = 100
+	// defaultLogSampleThereafter is the default sampling rate of the lines past the initial ones
+	defaultLogSampleThereafter = 100
+)
+
+// logEmailPattern matches the email addresses redacted from the log lines
+var logEmailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// newLogger returns the logger of the logging config.  Lines are json unless the format is console or pretty is
+// set, in which case they're also logged in development mode.
+func newLogger(lc config.LoggingConfig) (*zap.Logger, error) {
+	format := lc.Format
+	if format == "" {
+		format = config.LogFormatJSON
+	}
+
+	zc := zap.NewProductionConfig()
+
+	switch {
+	case lc.Pretty:
+		zc = zap.NewDevelopmentConfig()
+		format = config.LogFormatConsole
+	case format == config.LogFormatConsole:
+		zc.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	case format != config.LogFormatJSON:
+		return nil, fmt.Errorf("%w: %s", ErrLogFormatInvalid, format)
+	}
+
+	zc.Encoding = format
+	zc.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+	if lc.Debug {
+		zc.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+	}
+
+	zc.Sampling = nil
+
+	if lc.Sample {
+		zc.Sampling = &zap.SamplingConfig{Initial: lc.SampleInitial, Thereafter: lc.SampleThereafter}
+
+		if zc.Sampling.Initial <= 0 {
+			zc.Sampling.Initial = defaultLogSampleInitial
+		}
+
+		if zc.Sampling.Thereafter <= 0 {
+			zc.Sampling.Thereafter = defaultLogSampleThereafter
+		}
+	}
+
+	if !lc.RedactEmails {
+		return zc.Build()
+	}
+
+	// the encoder can't be swapped once the config is built, so the core is built like zap does it
+	enc := zapcore.NewJSONEncoder(zc.EncoderConfig)
+	if format == config.LogFormatConsole {
+		enc = zapcore.NewConsoleEncoder(zc.EncoderConfig)
+	}
+
+	sink, closeSink, err := zap.Open(zc.OutputPaths...)
+	if err != nil {
+		return nil, err
+	}
+
+	errSink, _, err := zap.Open(zc.ErrorOutputPaths...)
+	if err != nil {
+		closeSink()
+		return nil, err
+	}
+
+	core := zapcore.NewCore(&emailRedactingEncoder{Encoder: enc}, sink, zc.Level)
+
+	if zc.Sampling != nil {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, zc.Sampling.Initial, zc.Sampling.Thereafter)
+	}
+
+	opts := []zap.Option{zap.ErrorOutput(errSink), zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel)}
+
+	if zc.Development {
+		opts = append(opts, zap.Development(), zap.AddStacktrace(zap.WarnLevel))
+	}
+
+	return zap.New(core, opts...), nil
+}
+
+// emailRedactingEncoder masks the email addresses of the encoded log lines, including the ones in the maps and
+// structs of logged fields, keeping their first character and domain, ie. j***@example.com
+type emailRedactingEncoder struct {
+	zapcore.Encoder
+}
+
+// Clone clones the encoder and its fields
+func (e *emailRedactingEncoder) Clone() zapcore.Encoder {
+	return &emailRedactingEncoder{Encoder: e.Encoder.Clone()}
+}
+
+// EncodeEntry encodes the log line and redacts its email addresses
+func (e *emailRedactingEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if !logEmailPattern.Match(buf.Bytes()) {
+		return buf, nil
+	}
+
+	redacted := logEmailPattern.ReplaceAll(buf.Bytes(), []byte("$1***@$2"))
+
+	buf.Reset()
+	_, _ = buf.Write(redacted)
+
+	return buf, nil
+}
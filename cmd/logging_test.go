@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_newLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		lc      config.LoggingConfig
+		wantErr error
+	}{
+		{name: "default", lc: config.LoggingConfig{}},
+		{name: "json sampled", lc: config.LoggingConfig{Format: config.LogFormatJSON, Sample: true}},
+		{name: "console", lc: config.LoggingConfig{Format: config.LogFormatConsole, Debug: true}},
+		{name: "pretty", lc: config.LoggingConfig{Format: config.LogFormatJSON, Pretty: true}},
+		{name: "redacted", lc: config.LoggingConfig{RedactEmails: true, Sample: true, SampleInitial: 1, SampleThereafter: 10}},
+		{name: "invalid format", lc: config.LoggingConfig{Format: "logfmt"}, wantErr: ErrLogFormatInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := newLogger(tt.lc)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.lc.Debug, l.Core().Enabled(zap.DebugLevel))
+		})
+	}
+}
+
+func Test_emailRedactingEncoder(t *testing.T) {
+	enc := &emailRedactingEncoder{Encoder: zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())}
+	enc.AddString("app", "gov-okta-addon")
+
+	clone := enc.Clone()
+	clone.AddString("okta.user.email", "jane.doe@example.com")
+
+	buf, err := clone.EncodeEntry(zapcore.Entry{
+		Level:   zap.InfoLevel,
+		Time:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Message: "added john@corp.example.net to the group",
+	}, []zapcore.Field{zap.Strings("members", []string{"a@example.org", "not-an-email"})})
+	require.NoError(t, err)
+
+	line := buf.String()
+
+	assert.Contains(t, line, `"app":"gov-okta-addon"`)
+	assert.Contains(t, line, `"okta.user.email":"j***@example.com"`)
+	assert.Contains(t, line, `"msg":"added j***@corp.example.net to the group"`)
+	assert.Contains(t, line, `"members":["a***@example.org","not-an-email"]`)
+	assert.NotContains(t, line, "jane.doe")
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/metal-toolbox/auditevent"
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/ratelimit"
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd migrates the okta resources managed by the addon
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "migrate the okta resources managed by the addon",
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		// bind here instead of init so we don't clobber the serve, sync and inspect command bindings for the same keys
+		viperBindFlag("audit.log-path", cmd.Flags().Lookup("audit-log-path"))
+		viperBindFlag("okta.url", cmd.Flags().Lookup("okta-url"))
+		viperBindFlag("okta.token", cmd.Flags().Lookup("okta-token"))
+		viperBindFlag("okta.call-timeout", cmd.Flags().Lookup("okta-call-timeout"))
+		viperBindFlag("okta.list-timeout", cmd.Flags().Lookup("okta-list-timeout"))
+		viperBindFlag("okta.secondary-tokens", cmd.Flags().Lookup("okta-secondary-tokens"))
+		viperBindFlag("okta.token-strategy", cmd.Flags().Lookup("okta-token-strategy"))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.PersistentFlags().Bool("dry-run", false, "do not make any changes when running a migration")
+	migrateCmd.PersistentFlags().String("audit-log-path", "", "file path to write the audit events of okta changes to, stderr when empty")
+
+	// Okta related flags
+	migrateCmd.PersistentFlags().String("okta-url", "https://example.okta.com", "url for Okta client calls")
+	migrateCmd.PersistentFlags().String("okta-token", "", "token for access to the Okta API")
+	migrateCmd.PersistentFlags().Duration("okta-call-timeout", okta.DefaultCallTimeout, "deadline for a single okta call, negative disables it")
+	migrateCmd.PersistentFlags().Duration("okta-list-timeout", okta.DefaultListTimeout, "deadline for okta calls listing all results, negative disables it")
+	migrateCmd.PersistentFlags().StringSlice("okta-secondary-tokens", []string{}, "additional okta api tokens to spread requests over, depends on the org rate limit policy")
+	migrateCmd.PersistentFlags().String("okta-token-strategy", okta.TokenStrategyRoundRobin, "how the okta api token of each request is selected (round-robin or least-used)")
+	migrateCmd.PersistentFlags().Float64("okta-rate-limit", 0, "max okta requests per second, 0 is unlimited")
+	migrateCmd.PersistentFlags().Int("rate-limit-burst", 1, "number of requests allowed to burst past the okta rate limit")
+}
+
+// newMigrateOktaClient returns the okta client of the migrate commands with the rate limit of the flags.  The
+// cache is disabled since the migrations read back what they wrote to verify it.  An audit event is written for
+// every okta change to the audit log, the returned function closes it.
+func newMigrateOktaClient(cmd *cobra.Command) (*okta.Client, func(), error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.Okta.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	rate, err := cmd.Flags().GetFloat64("okta-rate-limit")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	burst, err := cmd.Flags().GetInt("rate-limit-burst")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	auf, closeAudit, err := openAuditLog(cfg.Audit.LogPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := []okta.Option{
+		okta.WithLogger(logger.Desugar()),
+		okta.WithURL(cfg.Okta.URL),
+		okta.WithToken(cfg.Okta.Token),
+		okta.WithCache(false),
+		okta.WithCallTimeout(cfg.Okta.CallTimeout),
+		okta.WithListTimeout(cfg.Okta.ListTimeout),
+		okta.WithSecondaryTokens(cfg.Okta.SecondaryTokens),
+		okta.WithTokenStrategy(cfg.Okta.TokenStrategy),
+		okta.WithAuditHook(auctx.OktaMutationHook(logger.Desugar(), auditevent.NewDefaultAuditEventWriter(auf), "migrate")),
+	}
+
+	if limiter := ratelimit.New(rate, burst); limiter != nil {
+		opts = append(opts, okta.WithHTTPClient(ratelimit.HTTPClient(&http.Client{Timeout: syncOktaTimeout}, limiter)))
+	}
+
+	oc, err := okta.NewClient(opts...)
+	if err != nil {
+		closeAudit()
+
+		return nil, nil, err
+	}
+
+	return oc, closeAudit, nil
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// profileKeyPattern matches the okta profile attribute names, which are also used in the group search
+var profileKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// migrateGroupProfileKeyCmd renames the okta group profile key of the governor id
+var migrateGroupProfileKeyCmd = &cobra.Command{
+	Use:   "group-profile-key",
+	Short: "copy an okta group profile attribute to another key, ie. governor_id to a custom schema attribute",
+	Long: `Walks all Okta groups with the --from profile attribute and copies its value to the --to attribute, reading
+the group back to verify the copy. With --remove-old the --from attribute is removed once the copy is verified. Groups
+that already have a different value in the --to attribute are reported as conflicts and left alone. The --to attribute
+must exist in the Okta group profile schema. It is strongly recommended that you use the dry-run flag first to see what
+groups would be updated in Okta.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		opts, err := groupProfileKeyOptionsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		oc, closeAudit, err := newMigrateOktaClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		defer closeAudit()
+
+		res, err := migrateGroupProfileKey(cmd.Context(), logger.Desugar(), oc, opts)
+		if err != nil {
+			return err
+		}
+
+		if res.Conflicts > 0 || res.Failed > 0 {
+			return fmt.Errorf("%w: %d conflicts, %d failed", ErrMigrationIncomplete, res.Conflicts, res.Failed)
+		}
+
+		return nil
+	},
+}
+
+// groupProfileKeyOptions are the options of the group profile key migration
+type groupProfileKeyOptions struct {
+	From      string
+	To        string
+	RemoveOld bool
+	DryRun    bool
+}
+
+// groupProfileKeyResult counts the okta groups of the group profile key migration
+type groupProfileKeyResult struct {
+	Migrated  int
+	Existing  int
+	Removed   int
+	Conflicts int
+	Failed    int
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateGroupProfileKeyCmd)
+
+	migrateGroupProfileKeyCmd.Flags().String("from", okta.GroupProfileGovernorIDKey, "okta group profile key to copy the value from")
+	migrateGroupProfileKeyCmd.Flags().String("to", "", "okta group profile key to copy the value to, it must exist in the group profile schema")
+	migrateGroupProfileKeyCmd.Flags().Bool("remove-old", false, "remove the key copied from once the copy is verified")
+}
+
+// groupProfileKeyOptionsFromFlags returns the validated group profile key migration options of the command flags
+func groupProfileKeyOptionsFromFlags(cmd *cobra.Command) (groupProfileKeyOptions, error) {
+	opts := groupProfileKeyOptions{}
+
+	var err error
+
+	if opts.From, err = cmd.Flags().GetString("from"); err != nil {
+		return opts, err
+	}
+
+	if opts.To, err = cmd.Flags().GetString("to"); err != nil {
+		return opts, err
+	}
+
+	if opts.RemoveOld, err = cmd.Flags().GetBool("remove-old"); err != nil {
+		return opts, err
+	}
+
+	if opts.DryRun, err = cmd.Flags().GetBool("dry-run"); err != nil {
+		return opts, err
+	}
+
+	return opts, opts.validate()
+}
+
+func (o groupProfileKeyOptions) validate() error {
+	for _, k := range []string{o.From, o.To} {
+		if !profileKeyPattern.MatchString(k) {
+			return fmt.Errorf("%w: %q", ErrProfileKeyInvalid, k)
+		}
+	}
+
+	if o.From == o.To {
+		return ErrProfileKeyUnchanged
+	}
+
+	return nil
+}
+
+// migrateGroupProfileKey copies the from profile attribute of the okta groups to the to attribute.  Groups that
+// fail or conflict are counted and logged, the migration goes on with the next group.
+func migrateGroupProfileKey(ctx context.Context, logger *zap.Logger, oc *okta.Client, opts groupProfileKeyOptions) (*groupProfileKeyResult, error) {
+	logger = logger.With(zap.String("profile.key.from", opts.From), zap.String("profile.key.to", opts.To))
+
+	logger.Info("starting migration of okta group profile key", zap.Bool("dry-run", opts.DryRun), zap.Bool("remove-old", opts.RemoveOld))
+
+	groups, err := oc.ListGroups(ctx, fmt.Sprintf("profile.%s pr", opts.From))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &groupProfileKeyResult{}
+
+	for _, g := range groups {
+		if g.Profile == nil {
+			continue
+		}
+
+		l := logger.With(zap.String("okta.group.id", g.Id), zap.String("okta.group.name", g.Profile.Name))
+
+		value := g.Profile.GroupProfileMap[opts.From]
+		current, ok := g.Profile.GroupProfileMap[opts.To]
+
+		switch {
+		case ok && current != nil && !reflect.DeepEqual(current, value):
+			l.Warn("okta group already has a different value in the key to migrate to, skipping",
+				zap.Any("profile.value.from", value),
+				zap.Any("profile.value.to", current),
+			)
+
+			res.Conflicts++
+
+			continue
+		case ok && current != nil:
+			l.Debug("okta group profile key already migrated")
+
+			res.Existing++
+		case opts.DryRun:
+			l.Info("SKIP copying okta group profile key", zap.Any("profile.value", value))
+
+			res.Migrated++
+		default:
+			if err := copyGroupProfileKey(ctx, oc, g.Id, opts.To, value); err != nil {
+				l.Error("error copying okta group profile key", zap.Error(err))
+
+				res.Failed++
+
+				continue
+			}
+
+			l.Info("copied okta group profile key", zap.Any("profile.value", value))
+
+			res.Migrated++
+		}
+
+		if !opts.RemoveOld {
+			continue
+		}
+
+		if opts.DryRun {
+			l.Info("SKIP removing old okta group profile key")
+
+			res.Removed++
+
+			continue
+		}
+
+		if err := setGroupProfileKey(ctx, oc, g.Id, opts.From, nil); err != nil {
+			l.Error("error removing old okta group profile key", zap.Error(err))
+
+			res.Failed++
+
+			continue
+		}
+
+		l.Info("removed old okta group profile key")
+
+		res.Removed++
+	}
+
+	logger.Info("completed migration of okta group profile key",
+		zap.Int("okta.groups.migrated", res.Migrated),
+		zap.Int("okta.groups.existing", res.Existing),
+		zap.Int("okta.groups.removed", res.Removed),
+		zap.Int("okta.groups.conflicts", res.Conflicts),
+		zap.Int("okta.groups.failed", res.Failed),
+	)
+
+	return res, nil
+}
+
+// copyGroupProfileKey sets the key of the okta group profile to the value and reads the group back to verify it
+func copyGroupProfileKey(ctx context.Context, oc *okta.Client, id, key string, value interface{}) error {
+	if err := setGroupProfileKey(ctx, oc, id, key, value); err != nil {
+		return err
+	}
+
+	g, err := oc.GetGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if g.Profile == nil || !reflect.DeepEqual(g.Profile.GroupProfileMap[key], value) {
+		return ErrProfileKeyNotVerified
+	}
+
+	return nil
+}
+
+// setGroupProfileKey sets the key of the okta group profile to the value, nil removes the key.  The name,
+// description and the other profile attributes are kept.
+func setGroupProfileKey(ctx context.Context, oc *okta.Client, id, key string, value interface{}) error {
+	g, err := oc.GetGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if g.Profile == nil {
+		return okta.ErrNilGroupProfile
+	}
+
+	_, _, err = oc.UpdateGroupMerge(ctx, id, g.Profile.Name, g.Profile.Description, map[string]interface{}{key: value})
+
+	return err
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_groupProfileKeyOptions_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    groupProfileKeyOptions
+		wantErr error
+	}{
+		{name: "valid", opts: groupProfileKeyOptions{From: "governor_id", To: "governorId"}},
+		{name: "missing to", opts: groupProfileKeyOptions{From: "governor_id"}, wantErr: ErrProfileKeyInvalid},
+		{name: "search injection", opts: groupProfileKeyOptions{From: "governor_id", To: `x pr or profile.name`}, wantErr: ErrProfileKeyInvalid},
+		{name: "same key", opts: groupProfileKeyOptions{From: "governor_id", To: "governor_id"}, wantErr: ErrProfileKeyUnchanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.opts.validate(), tt.wantErr)
+		})
+	}
+}
+
+func Test_migrateGroupProfileKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        groupProfileKeyOptions
+		want        groupProfileKeyResult
+		wantProfile map[string]map[string]interface{}
+	}{
+		{
+			name: "copy",
+			opts: groupProfileKeyOptions{From: "governor_id", To: "governorId"},
+			want: groupProfileKeyResult{Migrated: 1, Existing: 1, Conflicts: 1},
+			wantProfile: map[string]map[string]interface{}{
+				"00g-new":       {"governor_id": "group-1", "governorId": "group-1", "team": "platform"},
+				"00g-migrated":  {"governor_id": "group-2", "governorId": "group-2"},
+				"00g-conflict":  {"governor_id": "group-3", "governorId": "group-other"},
+				"00g-unmanaged": {"team": "security"},
+			},
+		},
+		{
+			name: "copy and remove old",
+			opts: groupProfileKeyOptions{From: "governor_id", To: "governorId", RemoveOld: true},
+			want: groupProfileKeyResult{Migrated: 1, Existing: 1, Removed: 2, Conflicts: 1},
+			wantProfile: map[string]map[string]interface{}{
+				"00g-new":       {"governor_id": nil, "governorId": "group-1", "team": "platform"},
+				"00g-migrated":  {"governor_id": nil, "governorId": "group-2"},
+				"00g-conflict":  {"governor_id": "group-3", "governorId": "group-other"},
+				"00g-unmanaged": {"team": "security"},
+			},
+		},
+		{
+			name: "dry run",
+			opts: groupProfileKeyOptions{From: "governor_id", To: "governorId", RemoveOld: true, DryRun: true},
+			want: groupProfileKeyResult{Migrated: 1, Existing: 1, Removed: 2, Conflicts: 1},
+			wantProfile: map[string]map[string]interface{}{
+				"00g-new":       {"governor_id": "group-1", "team": "platform"},
+				"00g-migrated":  {"governor_id": "group-2", "governorId": "group-2"},
+				"00g-conflict":  {"governor_id": "group-3", "governorId": "group-other"},
+				"00g-unmanaged": {"team": "security"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			o.AddGroup("00g-new", "New", map[string]interface{}{"governor_id": "group-1", "team": "platform"})
+			o.AddGroup("00g-migrated", "Migrated", map[string]interface{}{"governor_id": "group-2", "governorId": "group-2"})
+			o.AddGroup("00g-conflict", "Conflict", map[string]interface{}{"governor_id": "group-3", "governorId": "group-other"})
+			o.AddGroup("00g-unmanaged", "Unmanaged", map[string]interface{}{"team": "security"})
+
+			oc, err := okta.NewClient(
+				okta.WithURL(o.URL),
+				okta.WithToken("okta-token"),
+				okta.WithCache(false),
+				okta.WithHTTPClient(o.Client()),
+			)
+			require.NoError(t, err)
+
+			got, err := migrateGroupProfileKey(context.TODO(), zap.NewNop(), oc, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+
+			for id, want := range tt.wantProfile {
+				assert.Equal(t, want, map[string]interface{}(o.Group(id).Profile.GroupProfileMap), id)
+			}
+		})
+	}
+}
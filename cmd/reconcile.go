@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/metal-toolbox/auditevent"
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govauth"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// reconcileCmd runs parts of the reconciler once
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "run parts of the reconciler once and exit",
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		// bind here instead of init so we don't clobber the serve, sync and inspect command bindings for the same keys
+		bindCLIReconcilerFlags(cmd)
+	},
+}
+
+// reconcileAppAssignmentsCmd reconciles the okta application assignments of governor groups once
+var reconcileAppAssignmentsCmd = &cobra.Command{
+	Use:   "app-assignments",
+	Short: "reconcile the okta application assignments of governor groups",
+	Long: `Reconciles the Okta githubcloud application assignments of all Governor groups, or of the groups given with
+--group, once and exits. Group memberships and users are left alone. The application assignment overrides of the
+config file are honored, use --dry-run to only log the changes.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		slugs, err := cmd.Flags().GetStringSlice("group")
+		if err != nil {
+			return err
+		}
+
+		r, closeAudit, err := newCLIReconciler("reconcile")
+		if err != nil {
+			return err
+		}
+
+		defer closeAudit()
+
+		res, err := r.ApplicationAssignments(cmd.Context(), slugs...)
+
+		logger.Infow("reconciled okta application assignments", "app.assignments", res)
+
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+	reconcileCmd.AddCommand(reconcileAppAssignmentsCmd)
+
+	addCLIReconcilerFlags(reconcileCmd.PersistentFlags())
+
+	reconcileAppAssignmentsCmd.Flags().StringSlice("group", []string{}, "slugs of the governor groups to reconcile, all groups when empty")
+}
+
+// addCLIReconcilerFlags adds the flags of the commands running parts of the reconciler once
+func addCLIReconcilerFlags(flags *pflag.FlagSet) {
+	flags.Bool("dry-run", false, "do not make any changes, just log what would be done")
+	flags.String("audit-log-path", "", "file path to write audit logs to, stderr when empty")
+
+	// Okta related flags
+	flags.String("okta-url", "https://example.okta.com", "url for Okta client calls")
+	flags.String("okta-token", "", "token for access to the Okta API")
+	flags.Bool("okta-nocache", false, "disable the okta client cache, useful for development")
+	flags.Duration("okta-call-timeout", okta.DefaultCallTimeout, "deadline for a single okta call, negative disables it")
+	flags.Duration("okta-list-timeout", okta.DefaultListTimeout, "deadline for okta calls listing all results, negative disables it")
+	flags.Int("okta-page-retries", okta.DefaultPageRetries, "times a failed page of an okta listing is retried")
+	flags.Duration("okta-page-retry-wait", okta.DefaultPageRetryWait, "wait before the first retry of a failed okta listing page, doubles on each retry")
+	flags.Bool("okta-partial-pages", false, "return the pages listed so far when an okta listing page keeps failing instead of failing the listing")
+	flags.StringSlice("okta-secondary-tokens", []string{}, "additional okta api tokens to spread requests over, depends on the org rate limit policy")
+	flags.String("okta-token-strategy", okta.TokenStrategyRoundRobin, "how the okta api token of each request is selected (round-robin or least-used)")
+
+	// Governor related flags
+	flags.String("governor-url", "https://api.governor.metalkube.net", "url of the governor api")
+	flags.String("governor-client-id", "gov-okta-addon-governor", "oauth client ID for client credentials flow")
+	flags.String("governor-client-secret", "", "oauth client secret for client credentials flow")
+	flags.String("governor-token-url", "http://hydra:4444/oauth2/token", "url used for client credential flow")
+	flags.String("governor-audience", "https://api.governor.metalkube.net", "oauth audience for client credential flow")
+	flags.Duration("governor-token-skew", govauth.DefaultSkew, "how long before it expires the governor token is refreshed")
+}
+
+// bindCLIReconcilerFlags binds the flags added by addCLIReconcilerFlags
+func bindCLIReconcilerFlags(cmd *cobra.Command) {
+	viperBindFlag("dryrun", cmd.Flags().Lookup("dry-run"))
+	viperBindFlag("audit.log-path", cmd.Flags().Lookup("audit-log-path"))
+	viperBindFlag("okta.url", cmd.Flags().Lookup("okta-url"))
+	viperBindFlag("okta.token", cmd.Flags().Lookup("okta-token"))
+	viperBindFlag("okta.nocache", cmd.Flags().Lookup("okta-nocache"))
+	viperBindFlag("okta.call-timeout", cmd.Flags().Lookup("okta-call-timeout"))
+	viperBindFlag("okta.list-timeout", cmd.Flags().Lookup("okta-list-timeout"))
+	viperBindFlag("okta.page-retries", cmd.Flags().Lookup("okta-page-retries"))
+	viperBindFlag("okta.page-retry-wait", cmd.Flags().Lookup("okta-page-retry-wait"))
+	viperBindFlag("okta.partial-pages", cmd.Flags().Lookup("okta-partial-pages"))
+	viperBindFlag("okta.secondary-tokens", cmd.Flags().Lookup("okta-secondary-tokens"))
+	viperBindFlag("okta.token-strategy", cmd.Flags().Lookup("okta-token-strategy"))
+	viperBindFlag("governor.url", cmd.Flags().Lookup("governor-url"))
+	viperBindFlag("governor.client-id", cmd.Flags().Lookup("governor-client-id"))
+	viperBindFlag("governor.client-secret", cmd.Flags().Lookup("governor-client-secret"))
+	viperBindFlag("governor.token-url", cmd.Flags().Lookup("governor-token-url"))
+	viperBindFlag("governor.audience", cmd.Flags().Lookup("governor-audience"))
+	viperBindFlag("governor.token-skew", cmd.Flags().Lookup("governor-token-skew"))
+}
+
+// newCLIReconciler returns a reconciler for the reconcile and replay commands, with the governor scopes of the
+// command, and a function closing its audit log
+func newCLIReconciler(command string) (*reconciler.Reconciler, func(), error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := errors.Join(cfg.Okta.Validate(), cfg.Governor.Validate()); err != nil {
+		return nil, nil, err
+	}
+
+	l := logger.Desugar()
+
+	auf, closeAudit, err := openAuditLog(cfg.Audit.LogPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	auw := auditevent.NewDefaultAuditEventWriter(auf)
+
+	oc, err := okta.NewClient(
+		okta.WithLogger(l),
+		okta.WithURL(cfg.Okta.URL),
+		okta.WithToken(cfg.Okta.Token),
+		okta.WithCache(!cfg.Okta.NoCache),
+		okta.WithCallTimeout(cfg.Okta.CallTimeout),
+		okta.WithListTimeout(cfg.Okta.ListTimeout),
+		okta.WithPageRetries(cfg.Okta.PageRetries, cfg.Okta.PageRetryWait),
+		okta.WithPartialPages(cfg.Okta.PartialPages),
+		okta.WithSecondaryTokens(cfg.Okta.SecondaryTokens),
+		okta.WithTokenStrategy(cfg.Okta.TokenStrategy),
+		okta.WithAuditHook(auctx.OktaMutationHook(l, auw, command)),
+	)
+	if err != nil {
+		closeAudit()
+
+		return nil, nil, err
+	}
+
+	gc, err := newGovernorAPIClient(l, cfg.Governor, &http.Client{Timeout: governorTimeout}, governorScopes(cfg, command)...)
+	if err != nil {
+		closeAudit()
+
+		return nil, nil, err
+	}
+
+	r := reconciler.New(
+		reconciler.WithLogger(l),
+		reconciler.WithAuditEventWriter(auw),
+		reconciler.WithGovernorClient(gc),
+		reconciler.WithOktaClient(oc),
+		reconciler.WithDryRun(cfg.DryRun),
+		reconciler.WithSkipDelete(cfg.SkipDelete),
+		reconciler.WithOpTimeout(cfg.Reconciler.OpTimeout),
+		reconciler.WithAppAssignmentModes(cfg.Reconciler.AppAssignmentModes()),
+		reconciler.WithAppAssignmentSettings(cfg.Reconciler.AppGroupAssignments()),
+		reconciler.WithPushGroups(cfg.Reconciler.PushGroups...),
+		reconciler.WithEventlogProfileUpdates(cfg.Eventlog.ProfileUpdates),
+		reconciler.WithDefaultGroups(cfg.Reconciler.DefaultGroups...),
+		reconciler.WithConflictPolicy(cfg.Reconciler.ConflictPolicy()),
+		reconciler.WithNonHumanAccounts(cfg.Okta.NonHumanRules()),
+	)
+
+	return r, closeAudit, nil
+}
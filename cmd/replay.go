@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// replayCmd replays past okta events
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "replay past okta events and exit",
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		// bind here instead of init so we don't clobber the serve, sync and inspect command bindings for the same keys
+		bindCLIReconcilerFlags(cmd)
+	},
+}
+
+// replayEventlogCmd replays the okta log events of a time window through the event log handlers
+var replayEventlogCmd = &cobra.Command{
+	Use:   "eventlog",
+	Short: "replay the okta log events of a time window",
+	Long: `Fetches the Okta system log events published between --since and --until (now by default) and runs them
+through the handlers of the event log poller, ie. to catch up on the events missed while the addon was down. Only the
+event types given with --event-type are replayed, all of the handled types by default. Use --dry-run to only log the
+changes.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		since, err := timeFlag(cmd, "since")
+		if err != nil {
+			return err
+		}
+
+		until, err := timeFlag(cmd, "until")
+		if err != nil {
+			return err
+		}
+
+		if until.IsZero() {
+			until = time.Now().UTC()
+		}
+
+		eventTypes, err := cmd.Flags().GetStringSlice("event-type")
+		if err != nil {
+			return err
+		}
+
+		r, closeAudit, err := newCLIReconciler("replay")
+		if err != nil {
+			return err
+		}
+
+		defer closeAudit()
+
+		res, err := r.ReplayEventlog(cmd.Context(), since, until, eventTypes...)
+
+		logger.Infow("replayed okta log events", "eventlog.replay", res)
+
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.AddCommand(replayEventlogCmd)
+
+	addCLIReconcilerFlags(replayCmd.PersistentFlags())
+
+	replayEventlogCmd.Flags().String("since", "", "replay the okta log events published at or after this time (RFC3339)")
+	replayEventlogCmd.Flags().String("until", "", "replay the okta log events published before this time (RFC3339), now when empty")
+	replayEventlogCmd.Flags().StringSlice("event-type", []string{}, "okta event types to replay (ie. user.lifecycle.suspend), all of the handled types when empty")
+
+	if err := replayEventlogCmd.MarkFlagRequired("since"); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/metal-toolbox/gov-okta-addon/internal/govauth"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/spf13/cobra"
+)
+
+// reportCmd reports on the governor and okta users for access reviews
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "report on governor and okta users for access reviews",
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		// bind here instead of init so we don't clobber the serve, sync and inspect command bindings for the same keys
+		viperBindFlag("okta.url", cmd.Flags().Lookup("okta-url"))
+		viperBindFlag("okta.token", cmd.Flags().Lookup("okta-token"))
+		viperBindFlag("okta.nocache", cmd.Flags().Lookup("okta-nocache"))
+		viperBindFlag("okta.call-timeout", cmd.Flags().Lookup("okta-call-timeout"))
+		viperBindFlag("okta.list-timeout", cmd.Flags().Lookup("okta-list-timeout"))
+		viperBindFlag("okta.secondary-tokens", cmd.Flags().Lookup("okta-secondary-tokens"))
+		viperBindFlag("okta.token-strategy", cmd.Flags().Lookup("okta-token-strategy"))
+		viperBindFlag("governor.url", cmd.Flags().Lookup("governor-url"))
+		viperBindFlag("governor.client-id", cmd.Flags().Lookup("governor-client-id"))
+		viperBindFlag("governor.client-secret", cmd.Flags().Lookup("governor-client-secret"))
+		viperBindFlag("governor.token-url", cmd.Flags().Lookup("governor-token-url"))
+		viperBindFlag("governor.audience", cmd.Flags().Lookup("governor-audience"))
+		viperBindFlag("governor.token-skew", cmd.Flags().Lookup("governor-token-skew"))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	// Okta related flags
+	reportCmd.PersistentFlags().String("okta-url", "https://example.okta.com", "url for Okta client calls")
+	reportCmd.PersistentFlags().String("okta-token", "", "token for access to the Okta API")
+	reportCmd.PersistentFlags().Bool("okta-nocache", false, "disable the okta client cache, useful for development")
+	reportCmd.PersistentFlags().Duration("okta-call-timeout", okta.DefaultCallTimeout, "deadline for a single okta call, negative disables it")
+	reportCmd.PersistentFlags().Duration("okta-list-timeout", okta.DefaultListTimeout, "deadline for okta calls listing all results, negative disables it")
+	reportCmd.PersistentFlags().StringSlice("okta-secondary-tokens", []string{}, "additional okta api tokens to spread requests over, depends on the org rate limit policy")
+	reportCmd.PersistentFlags().String("okta-token-strategy", okta.TokenStrategyRoundRobin, "how the okta api token of each request is selected (round-robin or least-used)")
+
+	// Governor related flags
+	reportCmd.PersistentFlags().String("governor-url", "https://api.governor.metalkube.net", "url of the governor api")
+	reportCmd.PersistentFlags().String("governor-client-id", "gov-okta-addon-governor", "oauth client ID for client credentials flow")
+	reportCmd.PersistentFlags().String("governor-client-secret", "", "oauth client secret for client credentials flow")
+	reportCmd.PersistentFlags().String("governor-token-url", "http://hydra:4444/oauth2/token", "url used for client credential flow")
+	reportCmd.PersistentFlags().String("governor-audience", "https://api.governor.metalkube.net", "oauth audience for client credential flow")
+	reportCmd.PersistentFlags().Duration("governor-token-skew", govauth.DefaultSkew, "how long before it expires the governor token is refreshed")
+}
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const (
+	// reportFormatJSON writes a report as a json array
+	reportFormatJSON = "json"
+	// reportFormatCSV writes a report as csv with a header row
+	reportFormatCSV = "csv"
+
+	// reportInactiveDays is the default number of days without a login before a user is inactive
+	reportInactiveDays = 90
+
+	hoursPerDay = 24
+)
+
+// reportInactiveUsersCmd reports the governor users that haven't logged in to okta within a number of days
+var reportInactiveUsersCmd = &cobra.Command{
+	Use:   "inactive-users",
+	Short: "report the users that haven't logged in to okta within a number of days",
+	Long: `Cross-references the last login of the Okta users with the active Governor users and reports the users that
+haven't logged in within the given number of days, or never logged in and were created before then.  Non-human
+accounts are left out.  With --suspend the reported users are also suspended in Governor, it's strongly recommended
+to review the report without it first.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		opts, err := reportInactiveUsersFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		scopes := governorScopes(cfg, "report-inactive-users")
+		if opts.Suspend {
+			scopes = governorScopes(cfg, "report-inactive-users-suspend")
+		}
+
+		oc, gc, err := newCommandClients(cfg, scopes...)
+		if err != nil {
+			return err
+		}
+
+		opts.NonHuman = cfg.Okta.NonHumanRules()
+
+		return reportInactiveUsers(cmd.Context(), logger.Desugar(), os.Stdout, oc, gc, opts, time.Now())
+	},
+}
+
+// reportInactiveUsersOptions are the options of the inactive users report
+type reportInactiveUsersOptions struct {
+	Days     int
+	Format   string
+	Suspend  bool
+	NonHuman okta.AccountRules
+}
+
+// inactiveUser is a governor user that hasn't logged in to okta within the report window.  The last login is nil
+// when the user never logged in, the days inactive are then counted from the creation of the okta user.
+type inactiveUser struct {
+	GovernorUserID string     `json:"governor_user_id"`
+	Email          string     `json:"email"`
+	OktaUserID     string     `json:"okta_user_id"`
+	OktaStatus     string     `json:"okta_status"`
+	LastLogin      *time.Time `json:"last_login"`
+	DaysInactive   int        `json:"days_inactive"`
+	Suspended      bool       `json:"suspended"`
+}
+
+func init() {
+	reportCmd.AddCommand(reportInactiveUsersCmd)
+
+	reportInactiveUsersCmd.Flags().Int("days", reportInactiveDays, "number of days without a login before a user is reported as inactive")
+	reportInactiveUsersCmd.Flags().String("format", reportFormatJSON, "format of the report (json or csv)")
+	reportInactiveUsersCmd.Flags().Bool("suspend", false, "suspend the reported users in governor")
+}
+
+func reportInactiveUsersFlags(cmd *cobra.Command) (reportInactiveUsersOptions, error) {
+	opts := reportInactiveUsersOptions{}
+
+	days, err := cmd.Flags().GetInt("days")
+	if err != nil {
+		return opts, err
+	}
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return opts, err
+	}
+
+	suspend, err := cmd.Flags().GetBool("suspend")
+	if err != nil {
+		return opts, err
+	}
+
+	opts.Days, opts.Format, opts.Suspend = days, format, suspend
+
+	return opts, opts.validate()
+}
+
+func (o reportInactiveUsersOptions) validate() error {
+	if o.Days <= 0 {
+		return fmt.Errorf("%w: %d", ErrReportDaysInvalid, o.Days)
+	}
+
+	if o.Format != reportFormatJSON && o.Format != reportFormatCSV {
+		return fmt.Errorf("%w: %q", ErrReportFormatInvalid, o.Format)
+	}
+
+	return nil
+}
+
+// reportInactiveUsers writes the report of the inactive users to out, suspending them in governor first when asked
+// to.  Users that fail to be suspended are reported as not suspended and fail the report once it's written.
+func reportInactiveUsers(ctx context.Context, l *zap.Logger, out io.Writer, oc *okta.Client, gc *governor.Client, opts reportInactiveUsersOptions, now time.Time) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	oktaUsers, err := oc.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	govUsers, err := gc.Users(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	users := inactiveUsers(l, oktaUsers, govUsers, opts.NonHuman, opts.Days, now)
+
+	l.Info("found inactive users", zap.Int("days", opts.Days), zap.Int("num.users", len(users)), zap.Bool("suspend", opts.Suspend))
+
+	failed := 0
+
+	if opts.Suspend {
+		for _, u := range users {
+			if _, err := gc.UpdateUser(ctx, u.GovernorUserID, &v1alpha1.UserReq{Status: v1alpha1.UserStatusSuspended}); err != nil {
+				l.Error("failed to suspend inactive governor user", zap.String("governor.user.id", u.GovernorUserID), zap.Error(err))
+
+				failed++
+
+				continue
+			}
+
+			l.Info("suspended inactive governor user",
+				zap.String("governor.user.id", u.GovernorUserID),
+				zap.String("okta.user.id", u.OktaUserID),
+				zap.Int("days.inactive", u.DaysInactive),
+			)
+
+			u.Suspended = true
+		}
+	}
+
+	if err := writeInactiveUsers(out, opts.Format, users); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d users", ErrSuspendIncomplete, failed)
+	}
+
+	return nil
+}
+
+// inactiveUsers returns the active governor users, sorted by email, of the okta users that haven't logged in within
+// the number of days.  Okta users are matched to governor users by their id, and by their email when the governor
+// user has no external id.
+func inactiveUsers(l *zap.Logger, oktaUsers []*okt.User, govUsers []*v1alpha1.User, nonHuman okta.AccountRules, days int, now time.Time) []*inactiveUser {
+	cutoff := now.AddDate(0, 0, -days)
+
+	byExternalID := map[string]*v1alpha1.User{}
+	byEmail := map[string]*v1alpha1.User{}
+
+	for _, u := range govUsers {
+		if u.ExternalID.String != "" {
+			byExternalID[u.ExternalID.String] = u
+			continue
+		}
+
+		byEmail[strings.ToLower(u.Email)] = u
+	}
+
+	users := []*inactiveUser{}
+
+	for _, u := range oktaUsers {
+		if u.Status == oktaUserStatusDeprovisioned {
+			continue
+		}
+
+		if _, ok := nonHuman.Match(u); ok {
+			continue
+		}
+
+		email, err := okta.EmailFromUserProfile(u)
+		if err != nil {
+			l.Warn("skipping okta user without an email", zap.String("okta.user.id", u.Id), zap.Error(err))
+			continue
+		}
+
+		govUser, ok := byExternalID[u.Id]
+		if !ok {
+			govUser, ok = byEmail[strings.ToLower(email)]
+		}
+
+		if !ok || govUser.Status.String != v1alpha1.UserStatusActive {
+			continue
+		}
+
+		since := u.LastLogin
+		if since == nil {
+			since = u.Created
+		}
+
+		// users without a known creation time that never logged in are always inactive
+		if since != nil && !since.Before(cutoff) {
+			continue
+		}
+
+		inactive := &inactiveUser{
+			GovernorUserID: govUser.ID,
+			Email:          email,
+			OktaUserID:     u.Id,
+			OktaStatus:     u.Status,
+			LastLogin:      u.LastLogin,
+		}
+
+		if since != nil {
+			inactive.DaysInactive = int(now.Sub(*since).Hours() / hoursPerDay)
+		}
+
+		users = append(users, inactive)
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+
+	return users
+}
+
+// writeInactiveUsers writes the inactive users in the format of the report
+func writeInactiveUsers(out io.Writer, format string, users []*inactiveUser) error {
+	if format == reportFormatJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(users)
+	}
+
+	w := csv.NewWriter(out)
+
+	if err := w.Write([]string{"governor_user_id", "email", "okta_user_id", "okta_status", "last_login", "days_inactive", "suspended"}); err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		lastLogin := ""
+		if u.LastLogin != nil {
+			lastLogin = u.LastLogin.UTC().Format(time.RFC3339)
+		}
+
+		if err := w.Write([]string{
+			u.GovernorUserID,
+			u.Email,
+			u.OktaUserID,
+			u.OktaStatus,
+			lastLogin,
+			strconv.Itoa(u.DaysInactive),
+			strconv.FormatBool(u.Suspended),
+		}); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_reportInactiveUsers(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	daysAgo := func(d int) time.Time { return now.AddDate(0, 0, -d) }
+
+	tests := []struct {
+		name          string
+		opts          reportInactiveUsersOptions
+		wantEmails    []string
+		wantSuspended []string
+		wantErr       error
+	}{
+		{
+			name:       "report",
+			opts:       reportInactiveUsersOptions{Days: 90, Format: reportFormatJSON},
+			wantEmails: []string{"by-email@example.com", "inactive@example.com", "never@example.com"},
+		},
+		{
+			name:          "suspend",
+			opts:          reportInactiveUsersOptions{Days: 90, Format: reportFormatJSON, Suspend: true},
+			wantEmails:    []string{"by-email@example.com", "inactive@example.com", "never@example.com"},
+			wantSuspended: []string{"user-by-email", "user-inactive", "user-never"},
+		},
+		{
+			name:       "longer window",
+			opts:       reportInactiveUsersOptions{Days: 365, Format: reportFormatJSON},
+			wantEmails: []string{"never@example.com"},
+		},
+		{
+			name: "non-human accounts",
+			opts: reportInactiveUsersOptions{
+				Days:     90,
+				Format:   reportFormatJSON,
+				NonHuman: okta.AccountRules{{Attribute: "email", Values: []string{"never@example.com"}}},
+			},
+			wantEmails: []string{"by-email@example.com", "inactive@example.com"},
+		},
+		{
+			name:    "invalid days",
+			opts:    reportInactiveUsersOptions{Days: 0, Format: reportFormatJSON},
+			wantErr: ErrReportDaysInvalid,
+		},
+		{
+			name:    "invalid format",
+			opts:    reportInactiveUsersOptions{Days: 90, Format: "xml"},
+			wantErr: ErrReportFormatInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			addUser := func(name string, govStatus string, lastLogin, created time.Time) {
+				email := name + "@example.com"
+
+				g.AddUser(&testserver.GovernorUser{ID: "user-" + name, ExternalID: "okta-" + name, Email: email, Status: govStatus})
+				o.AddUser("okta-"+name, "ACTIVE", map[string]interface{}{"email": email, "login": email})
+				o.SetUserLastLogin("okta-"+name, lastLogin, created)
+			}
+
+			addUser("active", v1alpha1.UserStatusActive, daysAgo(10), daysAgo(500))
+			addUser("inactive", v1alpha1.UserStatusActive, daysAgo(100), daysAgo(500))
+			addUser("never", v1alpha1.UserStatusActive, time.Time{}, daysAgo(400))
+			addUser("new", v1alpha1.UserStatusActive, time.Time{}, daysAgo(5))
+			addUser("suspended", v1alpha1.UserStatusSuspended, daysAgo(100), daysAgo(500))
+
+			// governor users without an external id are matched by email
+			g.AddUser(&testserver.GovernorUser{ID: "user-by-email", Email: "By-Email@example.com", Status: v1alpha1.UserStatusActive})
+			o.AddUser("okta-by-email", "ACTIVE", map[string]interface{}{"email": "by-email@example.com", "login": "by-email@example.com"})
+			o.SetUserLastLogin("okta-by-email", daysAgo(120), daysAgo(500))
+
+			// okta users without a governor user aren't reported
+			o.AddUser("okta-unknown", "ACTIVE", map[string]interface{}{"email": "unknown@example.com", "login": "unknown@example.com"})
+			o.SetUserLastLogin("okta-unknown", daysAgo(200), daysAgo(500))
+
+			oc, err := okta.NewClient(
+				okta.WithURL(o.URL),
+				okta.WithToken("okta-token"),
+				okta.WithCache(false),
+				okta.WithHTTPClient(o.Client()),
+			)
+			require.NoError(t, err)
+
+			out := &bytes.Buffer{}
+
+			err = reportInactiveUsers(context.TODO(), zap.NewNop(), out, oc, newTestGovernorClient(t, g), tt.opts, now)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			got := []*inactiveUser{}
+			require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+
+			emails := []string{}
+
+			for _, u := range got {
+				emails = append(emails, u.Email)
+				assert.Equal(t, tt.opts.Suspend, u.Suspended, u.Email)
+			}
+
+			assert.Equal(t, tt.wantEmails, emails)
+
+			for _, id := range tt.wantSuspended {
+				assert.Equal(t, v1alpha1.UserStatusSuspended, g.User(id).Status, id)
+			}
+
+			if !tt.opts.Suspend {
+				assert.Equal(t, v1alpha1.UserStatusActive, g.User("user-inactive").Status)
+			}
+		})
+	}
+}
+
+func Test_writeInactiveUsers_csv(t *testing.T) {
+	lastLogin := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	users := []*inactiveUser{
+		{GovernorUserID: "user-1", Email: "one@example.com", OktaUserID: "okta-1", OktaStatus: "ACTIVE", LastLogin: &lastLogin, DaysInactive: 100, Suspended: true},
+		{GovernorUserID: "user-2", Email: "two@example.com", OktaUserID: "okta-2", OktaStatus: "ACTIVE", DaysInactive: 400},
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, writeInactiveUsers(out, reportFormatCSV, users))
+
+	assert.Equal(t, `governor_user_id,email,okta_user_id,okta_status,last_login,days_inactive,suspended
+user-1,one@example.com,okta-1,ACTIVE,2024-01-02T03:04:05Z,100,true
+user-2,two@example.com,okta-2,ACTIVE,,400,false
+`, out.String())
+}
@@ -2,20 +2,40 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
-
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govauth"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/proxy"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
-const appName = "gov-okta-addon"
+const (
+	appName = "gov-okta-addon"
+
+	// governorTimeout is the http timeout for governor requests, same as the governor client default
+	governorTimeout = 10 * time.Second
+)
 
 var (
 	cfgFile string
 	logger  *zap.SugaredLogger
+
+	// outboundProxy is the proxy for the okta, governor and tracing traffic, nil connects directly
+	outboundProxy *proxy.Proxy
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -41,6 +61,28 @@ func init() {
 
 	rootCmd.PersistentFlags().Bool("pretty", false, "enable pretty (human readable) logging output")
 	viperBindFlag("logging.pretty", rootCmd.PersistentFlags().Lookup("pretty"))
+
+	rootCmd.PersistentFlags().String("log-format", config.LogFormatJSON, "log line format, json or console")
+	viperBindFlag("logging.format", rootCmd.PersistentFlags().Lookup("log-format"))
+	rootCmd.PersistentFlags().Bool("log-sample", true, "sample the log lines repeating the same message and level within a second")
+	viperBindFlag("logging.sample", rootCmd.PersistentFlags().Lookup("log-sample"))
+	rootCmd.PersistentFlags().Int("log-sample-initial", defaultLogSampleInitial, "log lines with the same message and level logged each second before sampling")
+	viperBindFlag("logging.sample-initial", rootCmd.PersistentFlags().Lookup("log-sample-initial"))
+	rootCmd.PersistentFlags().Int("log-sample-thereafter", defaultLogSampleThereafter, "log every nth line with the same message and level past the initial ones")
+	viperBindFlag("logging.sample-thereafter", rootCmd.PersistentFlags().Lookup("log-sample-thereafter"))
+	rootCmd.PersistentFlags().Bool("log-redact-emails", false, "mask the email addresses in log lines, ie. j***@example.com")
+	viperBindFlag("logging.redact-emails", rootCmd.PersistentFlags().Lookup("log-redact-emails"))
+	rootCmd.PersistentFlags().Bool("log-redact-pii", false, "mask the names, emails, logins, phones and addresses of the okta and governor values in debug lines")
+	viperBindFlag("logging.redact-pii", rootCmd.PersistentFlags().Lookup("log-redact-pii"))
+
+	rootCmd.PersistentFlags().String("proxy-url", "", "http proxy for the okta, governor and tracing traffic")
+	viperBindFlag("proxy.url", rootCmd.PersistentFlags().Lookup("proxy-url"))
+	rootCmd.PersistentFlags().String("proxy-username", "", "username to authenticate with the http proxy")
+	viperBindFlag("proxy.username", rootCmd.PersistentFlags().Lookup("proxy-username"))
+	rootCmd.PersistentFlags().String("proxy-password-file", "", "path to the file containing the http proxy password, or set GOA_PROXY_PASSWORD")
+	viperBindFlag("proxy.password-file", rootCmd.PersistentFlags().Lookup("proxy-password-file"))
+	rootCmd.PersistentFlags().StringSlice("proxy-no-proxy", nil, "hosts, domains (.example.com) and cidrs that bypass the http proxy")
+	viperBindFlag("proxy.no-proxy", rootCmd.PersistentFlags().Lookup("proxy-no-proxy"))
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -74,26 +116,113 @@ func initConfig() {
 }
 
 func setupLogging() {
-	cfg := zap.NewProductionConfig()
-	if viper.GetBool("logging.pretty") {
-		cfg = zap.NewDevelopmentConfig()
-	}
+	lc := config.LoggingConfig{}
+	cobra.CheckErr(viper.UnmarshalKey("logging", &lc))
 
-	if viper.GetBool("logging.debug") {
-		cfg.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
-	} else {
-		cfg.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
-	}
-
-	l, err := cfg.Build()
+	l, err := newLogger(lc)
 	if err != nil {
 		panic(err)
 	}
 
+	redact.SetPII(lc.RedactPII)
+
 	logger = l.Sugar().With("app", "gov-okta-addon")
 	defer logger.Sync() //nolint:errcheck
 }
 
+// loadConfig decodes the typed configuration and sets up the outbound proxy, it must be called after the
+// command flags are bound
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(viper.GetViper())
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := cfg.Proxy.NewProxy()
+	if err != nil {
+		return nil, err
+	}
+
+	if p != nil {
+		logger.Infow("using outbound proxy", "proxy.url", p.String(), "proxy.no-proxy", cfg.Proxy.NoProxy)
+
+		p.Install()
+	}
+
+	outboundProxy = p
+
+	return cfg, nil
+}
+
+// checkProxy makes sure the targets are reachable through the outbound proxy, it's a no-op without a proxy
+func checkProxy(ctx context.Context, targets ...string) error {
+	if outboundProxy == nil {
+		return nil
+	}
+
+	for _, target := range targets {
+		if target == "" {
+			continue
+		}
+
+		if err := outboundProxy.Check(ctx, target); err != nil {
+			return fmt.Errorf("checking %s through proxy: %w", target, err)
+		}
+
+		logger.Debugw("reached target through outbound proxy", "target", target)
+	}
+
+	return nil
+}
+
+// governorClientCredentials returns the governor oauth client credentials config with the given scopes
+func governorClientCredentials(cfg config.GovernorConfig, scopes ...string) *clientcredentials.Config {
+	return &clientcredentials.Config{
+		ClientID:       cfg.ClientID,
+		ClientSecret:   cfg.ClientSecret,
+		TokenURL:       cfg.TokenURL,
+		EndpointParams: url.Values{"audience": {cfg.Audience}},
+		Scopes:         scopes,
+	}
+}
+
+// newGovernorClient returns a governor client authenticating with the client credentials with the given scopes.
+// The requests of the http client use a token that's refreshed the configured skew before it expires and is
+// shared between concurrent requests.  Requests governor refuses for a missing scope return a
+// govclient.InsufficientScopeError naming the scope.  With the list cache enabled the governor lists are revalidated
+// with conditional requests.
+func newGovernorClient(l *zap.Logger, cfg config.GovernorConfig, c *http.Client, scopes ...string) (*governor.Client, error) {
+	gc, err := newGovernorAPIClient(l, cfg, c, scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	return gc.Client, nil
+}
+
+// newGovernorAPIClient returns a governor client like newGovernorClient, extended with the governor api calls the
+// governor client doesn't support yet
+func newGovernorAPIClient(l *zap.Logger, cfg config.GovernorConfig, c *http.Client, scopes ...string) (*govclient.Client, error) {
+	creds := governorClientCredentials(cfg, scopes...)
+	hc := govclient.ScopeErrorsHTTPClient(govauth.HTTPClient(c, govauth.NewTokenSource(creds, cfg.TokenSkew)))
+
+	if cfg.ListCache {
+		hc = govclient.HTTPClient(hc, govclient.NewListCache())
+	}
+
+	gc, err := governor.NewClient(
+		governor.WithLogger(l),
+		governor.WithURL(cfg.URL),
+		governor.WithClientCredentialConfig(creds),
+		governor.WithHTTPClient(hc),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return govclient.New(gc, cfg.URL, hc), nil
+}
+
 // viperBindFlag provides a wrapper around the viper bindings that handles error checks
 func viperBindFlag(name string, flag *pflag.Flag) {
 	if err := viper.BindPFlag(name, flag); err != nil {
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+)
+
+// defaultGovernorScopes returns the governor scopes requested by each command, by the command name used in the
+// governor.scopes configuration
+var defaultGovernorScopes = map[string]func(cfg *config.Config) []string{
+	"serve": func(cfg *config.Config) []string {
+		scopes := []string{
+			govclient.ScopeReadUsers,
+			govclient.ScopeCreateUsers,
+			govclient.ScopeUpdateUsers,
+			govclient.ScopeReadGroups,
+			govclient.ScopeReadOrganizations,
+		}
+
+		// adding default group members, recording okta group ids and correcting governor group members from okta
+		// update governor groups
+		if len(cfg.Reconciler.DefaultGroups) > 0 || cfg.Reconciler.RecordOktaGroupIDs ||
+			cfg.Reconciler.ConflictPolicy().Members == reconciler.SourceOfTruthOkta {
+			scopes = append(scopes, govclient.ScopeUpdateGroups)
+		}
+
+		// governor only takes membership requests from openid tokens
+		if cfg.Reconciler.MemberChangesAsRequests {
+			scopes = append(scopes, govclient.ScopeOpenID)
+		}
+
+		return scopes
+	},
+	"reconcile": func(*config.Config) []string {
+		return []string{govclient.ScopeReadGroups, govclient.ScopeReadOrganizations}
+	},
+	"replay": func(cfg *config.Config) []string {
+		scopes := []string{govclient.ScopeReadUsers, govclient.ScopeCreateUsers, govclient.ScopeUpdateUsers}
+
+		// users created by the replayed events are added to the default groups, which are looked up first
+		if len(cfg.Reconciler.DefaultGroups) > 0 {
+			scopes = append(scopes, govclient.ScopeReadGroups, govclient.ScopeUpdateGroups)
+		}
+
+		return scopes
+	},
+	"inspect": func(*config.Config) []string {
+		return []string{govclient.ScopeReadUsers, govclient.ScopeReadGroups, govclient.ScopeReadOrganizations}
+	},
+	"report-inactive-users": func(*config.Config) []string {
+		return []string{govclient.ScopeReadUsers}
+	},
+	"report-inactive-users-suspend": func(*config.Config) []string {
+		return []string{govclient.ScopeReadUsers, govclient.ScopeWrite}
+	},
+	"sync-groups": func(cfg *config.Config) []string {
+		scopes := []string{govclient.ScopeWrite, govclient.ScopeReadGroups, govclient.ScopeReadOrganizations}
+		if cfg.Sync.Admins.Source != "" {
+			scopes = append(scopes, govclient.ScopeReadUsers)
+		}
+
+		return scopes
+	},
+	"sync-members": func(*config.Config) []string {
+		return []string{govclient.ScopeWrite, govclient.ScopeReadGroups, govclient.ScopeReadUsers}
+	},
+	"sync-users": func(*config.Config) []string {
+		return []string{govclient.ScopeWrite, govclient.ScopeReadUsers}
+	},
+	"sync-orgs": func(*config.Config) []string {
+		return []string{govclient.ScopeWrite, govclient.ScopeReadOrganizations}
+	},
+	"sync-backfill": func(*config.Config) []string {
+		return []string{govclient.ScopeReadGroups}
+	},
+}
+
+// governorScopes returns the governor scopes requested by a command, the scopes configured for the command replace
+// its defaults
+func governorScopes(cfg *config.Config, command string) []string {
+	for name := range cfg.Governor.Scopes {
+		if _, ok := defaultGovernorScopes[name]; !ok {
+			logger.Warnw("ignoring governor scopes of unknown command", "command", name)
+		}
+	}
+
+	if scopes, ok := cfg.Governor.Scopes[command]; ok {
+		return scopes
+	}
+
+	return defaultGovernorScopes[command](cfg)
+}
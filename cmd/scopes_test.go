@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_governorScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		cfg     *config.Config
+		want    []string
+	}{
+		{
+			name:    "defaults",
+			command: "sync-users",
+			cfg:     &config.Config{},
+			want:    []string{"write", "read:governor:users"},
+		},
+		{
+			name:    "serve with default groups",
+			command: "serve",
+			cfg:     &config.Config{Reconciler: config.ReconcilerConfig{DefaultGroups: []string{"all-employees"}}},
+			want: []string{
+				"read:governor:users",
+				"create:governor:users",
+				"update:governor:users",
+				"read:governor:groups",
+				"read:governor:organizations",
+				"update:governor:groups",
+			},
+		},
+		{
+			name:    "serve with okta as the source of truth of members",
+			command: "serve",
+			cfg:     &config.Config{Reconciler: config.ReconcilerConfig{SourceOfTruth: map[string]string{"members": "Okta"}}},
+			want: []string{
+				"read:governor:users",
+				"create:governor:users",
+				"update:governor:users",
+				"read:governor:groups",
+				"read:governor:organizations",
+				"update:governor:groups",
+			},
+		},
+		{
+			name:    "replay with default groups",
+			command: "replay",
+			cfg:     &config.Config{Reconciler: config.ReconcilerConfig{DefaultGroups: []string{"all-employees"}}},
+			want: []string{
+				"read:governor:users",
+				"create:governor:users",
+				"update:governor:users",
+				"read:governor:groups",
+				"update:governor:groups",
+			},
+		},
+		{
+			name:    "configured",
+			command: "sync-users",
+			cfg: &config.Config{Governor: config.GovernorConfig{Scopes: map[string][]string{
+				"sync-users": {"create:governor:users", "read:governor:users"},
+			}}},
+			want: []string{"create:governor:users", "read:governor:users"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, governorScopes(tt.cfg, tt.command))
+		})
+	}
+}
@@ -2,29 +2,39 @@ package cmd
 
 import (
 	"context"
-	"errors"
-	"net/url"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/metal-toolbox/addonx/natslock"
 	"github.com/metal-toolbox/auditevent"
 	audithelpers "github.com/metal-toolbox/auditevent/helpers"
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govauth"
+	"github.com/metal-toolbox/gov-okta-addon/internal/journal"
+	"github.com/metal-toolbox/gov-okta-addon/internal/notify"
 	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
 	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
 	"github.com/metal-toolbox/gov-okta-addon/internal/srv"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
 	"github.com/nats-io/nats.go"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"golang.org/x/oauth2/clientcredentials"
-
-	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	"github.com/spf13/pflag"
 )
 
 const (
-	// defaultNATSQueueSize is the default for the number of subscribers per subject and queue group
-	defaultNATSQueueSize = 10
+	// stateBucketName is the jetstream key-value bucket the reconciler state is stored in
+	stateBucketName = appName + "-state"
+	// stateBucketTTL is how long the reconciler state is kept without being updated, the eventlog checkpoint
+	// is only used within the cold start lookback anyway
+	stateBucketTTL = 7 * 24 * time.Hour
 )
 
 // serveCmd starts the gov-okta-addon service
@@ -32,7 +42,12 @@ var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "starts the gov-okta-addon service",
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		return serve(cmd.Context(), viper.GetViper())
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		return serve(cmd.Context(), cfg)
 	},
 }
 
@@ -46,17 +61,33 @@ func init() {
 	viperBindFlag("dryrun", serveCmd.PersistentFlags().Lookup("dry-run"))
 	serveCmd.PersistentFlags().Bool("skip-delete", true, "do not delete anything in okta during reconcile loop")
 	viperBindFlag("skip-delete", serveCmd.PersistentFlags().Lookup("skip-delete"))
+	serveCmd.Flags().Bool("what-if", false, "record the okta changes instead of making them, the recorded changes are served on /api/v1/what-if")
+	viperBindFlag("what-if", serveCmd.Flags().Lookup("what-if"))
+	serveCmd.Flags().String("admin-token", "", "bearer token of the reconciler admin endpoints, they're disabled when empty")
+	viperBindFlag("admin-token", serveCmd.Flags().Lookup("admin-token"))
 
 	serveCmd.Flags().String("nats-url", "nats://127.0.0.1:4222", "NATS server connection url")
 	viperBindFlag("nats.url", serveCmd.Flags().Lookup("nats-url"))
 	serveCmd.PersistentFlags().String("nats-creds-file", "", "Path to the file containing the NATS credentials file")
 	viperBindFlag("nats.creds-file", serveCmd.PersistentFlags().Lookup("nats-creds-file"))
+	addNATSAuthFlags(serveCmd.Flags())
+	bindNATSAuthFlags(serveCmd.Flags())
 	serveCmd.Flags().String("nats-subject-prefix", "governor.events", "prefix for NATS subjects")
 	viperBindFlag("nats.subject-prefix", serveCmd.Flags().Lookup("nats-subject-prefix"))
 	serveCmd.Flags().String("nats-queue-group", "governor.addons.gov-okta-addon", "queue group for load balancing messages across NATS consumers")
 	viperBindFlag("nats.queue-group", serveCmd.Flags().Lookup("nats-queue-group"))
-	serveCmd.Flags().Int("nats-queue-size", defaultNATSQueueSize, "queue size for load balancing messages across NATS consumers")
+	serveCmd.Flags().Int("nats-queue-size", config.DefaultNATSQueueSize, "queue size for load balancing messages across NATS consumers")
 	viperBindFlag("nats.queue-size", serveCmd.Flags().Lookup("nats-queue-size"))
+	serveCmd.Flags().Duration("nats-coalesce-window", srv.DefaultCoalesceWindow, "how long group reconcile requests from governor events are held to merge duplicates, negative disables it")
+	viperBindFlag("nats.coalesce-window", serveCmd.Flags().Lookup("nats-coalesce-window"))
+	serveCmd.Flags().Duration("nats-reconnect-wait", srv.DefaultNATSReconnectWait, "wait before the first NATS reconnect attempt, doubled on every failed attempt")
+	viperBindFlag("nats.reconnect-wait", serveCmd.Flags().Lookup("nats-reconnect-wait"))
+	serveCmd.Flags().Duration("nats-reconnect-max-wait", srv.DefaultNATSReconnectMaxWait, "max wait between NATS reconnect attempts")
+	viperBindFlag("nats.reconnect-max-wait", serveCmd.Flags().Lookup("nats-reconnect-max-wait"))
+	serveCmd.Flags().Int("nats-max-reconnects", -1, "max number of NATS reconnect attempts before giving up, negative reconnects forever")
+	viperBindFlag("nats.max-reconnects", serveCmd.Flags().Lookup("nats-max-reconnects"))
+	serveCmd.Flags().StringSlice("nats-subjects", srv.DefaultNATSSubjects, "governor event subjects (without the prefix) to subscribe to, subjects without a handler are logged and dropped")
+	viperBindFlag("nats.subjects", serveCmd.Flags().Lookup("nats-subjects"))
 
 	// Tracing Flags
 	serveCmd.Flags().Bool("tracing", false, "enable tracing support")
@@ -78,6 +109,30 @@ func init() {
 	viperBindFlag("okta.token", serveCmd.Flags().Lookup("okta-token"))
 	serveCmd.Flags().Bool("okta-nocache", false, "disable the okta client cache, useful for development")
 	viperBindFlag("okta.nocache", serveCmd.Flags().Lookup("okta-nocache"))
+	serveCmd.Flags().Duration("okta-call-timeout", okta.DefaultCallTimeout, "deadline for a single okta call, negative disables it")
+	viperBindFlag("okta.call-timeout", serveCmd.Flags().Lookup("okta-call-timeout"))
+	serveCmd.Flags().Duration("okta-list-timeout", okta.DefaultListTimeout, "deadline for okta calls listing all results, negative disables it")
+	viperBindFlag("okta.list-timeout", serveCmd.Flags().Lookup("okta-list-timeout"))
+	serveCmd.Flags().Int("okta-page-retries", okta.DefaultPageRetries, "times a failed page of an okta listing is retried")
+	viperBindFlag("okta.page-retries", serveCmd.Flags().Lookup("okta-page-retries"))
+	serveCmd.Flags().Duration("okta-page-retry-wait", okta.DefaultPageRetryWait, "wait before the first retry of a failed okta listing page, doubles on each retry")
+	viperBindFlag("okta.page-retry-wait", serveCmd.Flags().Lookup("okta-page-retry-wait"))
+	serveCmd.Flags().Bool("okta-partial-pages", false, "return the pages listed so far when an okta listing page keeps failing instead of failing the listing")
+	viperBindFlag("okta.partial-pages", serveCmd.Flags().Lookup("okta-partial-pages"))
+	serveCmd.Flags().StringSlice("okta-secondary-tokens", []string{}, "additional okta api tokens to spread requests over, depends on the org rate limit policy")
+	viperBindFlag("okta.secondary-tokens", serveCmd.Flags().Lookup("okta-secondary-tokens"))
+	serveCmd.Flags().String("okta-token-strategy", okta.TokenStrategyRoundRobin, "how the okta api token of each request is selected (round-robin or least-used)")
+	viperBindFlag("okta.token-strategy", serveCmd.Flags().Lookup("okta-token-strategy"))
+	serveCmd.Flags().String("okta-user-match-key", string(okta.UserMatchKeyEmail), "okta user attribute governor users are matched on (email, login or externalId)")
+	viperBindFlag("okta.user-match-key", serveCmd.Flags().Lookup("okta-user-match-key"))
+	serveCmd.Flags().StringSlice("okta-non-human-user-types", []string{}, "profile userType values of non-human okta accounts (ie. service), which are left out of governor")
+	viperBindFlag("okta.non-human-user-types", serveCmd.Flags().Lookup("okta-non-human-user-types"))
+	serveCmd.Flags().String("okta-group-name-template", "", "go template of the okta group names of governor groups, ie. gov-{{ .Slug }}")
+	viperBindFlag("okta.group-name-template", serveCmd.Flags().Lookup("okta-group-name-template"))
+	serveCmd.Flags().String("okta-group-description-prefix", "", "prefix of the descriptions of the okta groups managed by governor, ie. [managed by governor]")
+	viperBindFlag("okta.group-description-prefix", serveCmd.Flags().Lookup("okta-group-description-prefix"))
+	serveCmd.Flags().String("okta-group-description-suffix", "", "suffix of the descriptions of the okta groups managed by governor")
+	viperBindFlag("okta.group-description-suffix", serveCmd.Flags().Lookup("okta-group-description-suffix"))
 
 	// Governor related flags
 	serveCmd.Flags().String("governor-url", "https://api.governor.metalkube.net", "url of the governor api")
@@ -90,27 +145,137 @@ func init() {
 	viperBindFlag("governor.token-url", serveCmd.Flags().Lookup("governor-token-url"))
 	serveCmd.Flags().String("governor-audience", "https://api.governor.metalkube.net", "oauth audience for client credential flow")
 	viperBindFlag("governor.audience", serveCmd.Flags().Lookup("governor-audience"))
+	serveCmd.Flags().Duration("governor-token-skew", govauth.DefaultSkew, "how long before it expires the governor token is refreshed")
+	viperBindFlag("governor.token-skew", serveCmd.Flags().Lookup("governor-token-skew"))
+	serveCmd.Flags().Bool("governor-list-cache", false, "cache the governor group, organization and user lists and revalidate them with conditional requests")
+	viperBindFlag("governor.list-cache", serveCmd.Flags().Lookup("governor-list-cache"))
 
 	// Reconciler flags
 	serveCmd.Flags().Duration("reconciler-interval", reconciler.DefaultReconcileInterval, "interval for the reconciler loop")
 	viperBindFlag("reconciler.interval", serveCmd.Flags().Lookup("reconciler-interval"))
+	serveCmd.Flags().Duration("reconciler-initial-delay", 0, "delay before the first reconciler loop after starting")
+	viperBindFlag("reconciler.initial-delay", serveCmd.Flags().Lookup("reconciler-initial-delay"))
+	serveCmd.Flags().Duration("reconciler-jitter", 0, "max random delay added to the interval of each reconciler loop")
+	viperBindFlag("reconciler.jitter", serveCmd.Flags().Lookup("reconciler-jitter"))
+	serveCmd.Flags().Bool("reconciler-run-on-start", false, "run a reconciler loop on start instead of waiting for the first interval")
+	viperBindFlag("reconciler.run-on-start", serveCmd.Flags().Lookup("reconciler-run-on-start"))
 	serveCmd.Flags().Duration("eventlog-interval", reconciler.DefaultEventlogPollerInterval, "run interval for the okta eventlog poller")
 	viperBindFlag("eventlog.interval", serveCmd.Flags().Lookup("eventlog-interval"))
 	serveCmd.Flags().Duration("eventlog-lookback", reconciler.DefaultEventlogColdStartLookback, "coldstart lookback time period for the okta eventlog poller")
 	viperBindFlag("eventlog.lookback", serveCmd.Flags().Lookup("eventlog-lookback"))
+	serveCmd.Flags().Bool("eventlog-profile-updates", false, "update the name and email of governor users from okta user profile update events")
+	viperBindFlag("eventlog.profile-updates", serveCmd.Flags().Lookup("eventlog-profile-updates"))
 	serveCmd.Flags().Bool("reconciler-locking", false, "enable reconciler locking and leader election")
 	viperBindFlag("reconciler.locking", serveCmd.Flags().Lookup("reconciler-locking"))
+	serveCmd.Flags().Bool("reconciler-snapshot-short-circuit", false, "skip okta reads in the reconciler loop when governor state is unchanged")
+	viperBindFlag("reconciler.snapshot-short-circuit", serveCmd.Flags().Lookup("reconciler-snapshot-short-circuit"))
+	serveCmd.Flags().Duration("reconciler-group-schedule-resolution", reconciler.DefaultGroupScheduleResolution, "how often groups with a reconcile interval override are checked")
+	viperBindFlag("reconciler.group-schedule-resolution", serveCmd.Flags().Lookup("reconciler-group-schedule-resolution"))
+	serveCmd.Flags().Duration("reconciler-op-timeout", reconciler.DefaultOpTimeout, "deadline for each okta and governor operation of the reconciler, negative disables it")
+	viperBindFlag("reconciler.op-timeout", serveCmd.Flags().Lookup("reconciler-op-timeout"))
+	serveCmd.Flags().Int("reconciler-breaker-failures", reconciler.DefaultBreakerFailures, "consecutive okta or governor failures that open the circuit breaker of the backend, negative disables it")
+	viperBindFlag("reconciler.breaker-failures", serveCmd.Flags().Lookup("reconciler-breaker-failures"))
+	serveCmd.Flags().Duration("reconciler-breaker-cooldown", reconciler.DefaultBreakerCooldown, "time an open circuit breaker short-circuits okta or governor calls before trying again")
+	viperBindFlag("reconciler.breaker-cooldown", serveCmd.Flags().Lookup("reconciler-breaker-cooldown"))
+	serveCmd.Flags().Int("reconciler-quarantine-failures", reconciler.DefaultQuarantineFailures, "consecutive reconcile failures of a governor group before it's quarantined, negative disables the quarantine")
+	viperBindFlag("reconciler.quarantine-failures", serveCmd.Flags().Lookup("reconciler-quarantine-failures"))
+	serveCmd.Flags().Duration("reconciler-quarantine-backoff", reconciler.DefaultQuarantineBackoff, "time a quarantined governor group is skipped by the reconciler loop before it's retried")
+	viperBindFlag("reconciler.quarantine-backoff", serveCmd.Flags().Lookup("reconciler-quarantine-backoff"))
+	serveCmd.Flags().Duration("reconciler-group-delete-grace-period", 0, "flag the okta groups of deleted governor groups and delete them after this grace period, 0 deletes them right away")
+	viperBindFlag("reconciler.group-delete-grace-period", serveCmd.Flags().Lookup("reconciler-group-delete-grace-period"))
+	serveCmd.Flags().Bool("reconciler-orphan-cleanup", false, "clear the governor_id of okta groups whose governor group doesn't exist anymore")
+	viperBindFlag("reconciler.orphan-cleanup", serveCmd.Flags().Lookup("reconciler-orphan-cleanup"))
+	serveCmd.Flags().Bool("reconciler-group-owners", false, "reconcile governor group admins into okta group owners")
+	viperBindFlag("reconciler.group-owners", serveCmd.Flags().Lookup("reconciler-group-owners"))
+	serveCmd.Flags().Bool("reconciler-user-governor-id", false, "write the governor user id to the governor_id attribute of the okta user profile")
+	viperBindFlag("reconciler.user-governor-id", serveCmd.Flags().Lookup("reconciler-user-governor-id"))
+	serveCmd.Flags().String("reconciler-user-search", "", "okta search expression filtering the users listed by the reconciler loop")
+	viperBindFlag("reconciler.user-search", serveCmd.Flags().Lookup("reconciler-user-search"))
+	serveCmd.Flags().StringSlice("reconciler-user-statuses", []string{}, "only list okta users with these statuses in the reconciler loop (ie. ACTIVE,SUSPENDED), all when empty")
+	viperBindFlag("reconciler.user-statuses", serveCmd.Flags().Lookup("reconciler-user-statuses"))
+	serveCmd.Flags().StringSlice("reconciler-exclude-user-types", []string{}, "leave okta users with these profile userType values out of the reconciler loop")
+	viperBindFlag("reconciler.exclude-user-types", serveCmd.Flags().Lookup("reconciler-exclude-user-types"))
+	serveCmd.Flags().Bool("reconciler-permanent-user-delete", false, "permanently delete okta users deleted in governor instead of only deactivating them")
+	viperBindFlag("reconciler.permanent-user-delete", serveCmd.Flags().Lookup("reconciler-permanent-user-delete"))
+	serveCmd.Flags().Bool("reconciler-offboard-remove-groups", false, "remove okta users deleted in governor from all of their okta groups when they're deactivated")
+	viperBindFlag("reconciler.offboard-remove-groups", serveCmd.Flags().Lookup("reconciler-offboard-remove-groups"))
+	serveCmd.Flags().Bool("reconciler-member-changes-as-requests", false, "file governor membership requests for okta group members that aren't governor group members instead of removing them")
+	viperBindFlag("reconciler.member-changes-as-requests", serveCmd.Flags().Lookup("reconciler-member-changes-as-requests"))
+	serveCmd.Flags().Bool("reconciler-sync-user-email", false, "update the email of governor users to the email of their okta user when they differ")
+	viperBindFlag("reconciler.sync-user-email", serveCmd.Flags().Lookup("reconciler-sync-user-email"))
+	serveCmd.Flags().StringSlice("reconciler-default-groups", []string{}, "governor groups (by id or slug) the governor users created for new okta users are added to, ie. all-employees")
+	viperBindFlag("reconciler.default-groups", serveCmd.Flags().Lookup("reconciler-default-groups"))
+	serveCmd.Flags().Bool("reconciler-record-okta-group-ids", false, "record the okta group id in the note of the governor groups and look okta groups up by that id")
+	viperBindFlag("reconciler.record-okta-group-ids", serveCmd.Flags().Lookup("reconciler-record-okta-group-ids"))
+	serveCmd.Flags().Int("reconciler-verify-sample-size", 5, "number of applied okta changes randomly sampled and re-read from okta after each reconciler loop, 0 disables it")
+	viperBindFlag("reconciler.verify-sample-size", serveCmd.Flags().Lookup("reconciler-verify-sample-size"))
+	serveCmd.Flags().Int("reconciler-membership-concurrency", reconciler.DefaultMembershipConcurrency, "number of okta group membership changes in flight at once, shared by all of the groups being reconciled")
+	viperBindFlag("reconciler.membership-concurrency", serveCmd.Flags().Lookup("reconciler-membership-concurrency"))
+	serveCmd.Flags().Float64("reconciler-okta-rate-limit", 0, "max okta requests per second made by the reconciler, 0 is unlimited")
+	viperBindFlag("reconciler.okta-rate-limit", serveCmd.Flags().Lookup("reconciler-okta-rate-limit"))
+	serveCmd.Flags().Int("reconciler-rate-limit-burst", 1, "number of requests allowed to burst past the reconciler okta rate limit")
+	viperBindFlag("reconciler.rate-limit-burst", serveCmd.Flags().Lookup("reconciler-rate-limit-burst"))
+	serveCmd.Flags().Int("reconciler-user-cache-size", 0, "max number of governor users of group members cached by the reconciler, 0 disables the cache")
+	viperBindFlag("reconciler.user-cache.size", serveCmd.Flags().Lookup("reconciler-user-cache-size"))
+	serveCmd.Flags().Duration("reconciler-user-cache-ttl", reconciler.DefaultUserCacheTTL, "how long the reconciler caches the governor users of group members, 0 never expires them")
+	viperBindFlag("reconciler.user-cache.ttl", serveCmd.Flags().Lookup("reconciler-user-cache-ttl"))
+
+	// Invariants flags
+	serveCmd.Flags().Bool("invariants", false, "compare governor and okta counts at the end of each reconciler loop")
+	viperBindFlag("invariants.enabled", serveCmd.Flags().Lookup("invariants"))
+	serveCmd.Flags().Float64("invariants-users-tolerance", reconciler.DefaultInvariantTolerance, "allowed divergence of active user counts")
+	viperBindFlag("invariants.users-tolerance", serveCmd.Flags().Lookup("invariants-users-tolerance"))
+	serveCmd.Flags().Float64("invariants-groups-tolerance", reconciler.DefaultInvariantTolerance, "allowed divergence of managed group counts")
+	viperBindFlag("invariants.groups-tolerance", serveCmd.Flags().Lookup("invariants-groups-tolerance"))
+	serveCmd.Flags().Float64("invariants-memberships-tolerance", reconciler.DefaultInvariantTolerance, "allowed divergence of total group membership counts")
+	viperBindFlag("invariants.memberships-tolerance", serveCmd.Flags().Lookup("invariants-memberships-tolerance"))
+
+	// Pilot rollout flags, the cohorts are set in the config file or governor group notes
+	serveCmd.Flags().Bool("pilot", false, "only change the okta groups and users of the pilot cohorts, everything else is detect-only")
+	viperBindFlag("pilot.enabled", serveCmd.Flags().Lookup("pilot"))
+
+	// Shutdown flags
+	serveCmd.Flags().Bool("reconciler-persist-state", false, "persist the okta eventlog checkpoint and group schedule in a NATS key-value bucket across restarts")
+	viperBindFlag("reconciler.persist-state", serveCmd.Flags().Lookup("reconciler-persist-state"))
+	serveCmd.Flags().String("metrics-pushgateway-url", "", "prometheus pushgateway the final metrics are pushed to on shutdown")
+	viperBindFlag("metrics.pushgateway-url", serveCmd.Flags().Lookup("metrics-pushgateway-url"))
+
+	// Change event flags
+	serveCmd.Flags().Bool("publish-changes", false, "publish an event on NATS for every change the addon makes in okta and governor")
+	viperBindFlag("changes.enabled", serveCmd.Flags().Lookup("publish-changes"))
+	serveCmd.Flags().String("changes-subject", changes.DefaultSubject, "NATS subject the change events are published on")
+	viperBindFlag("changes.subject", serveCmd.Flags().Lookup("changes-subject"))
+	serveCmd.Flags().String("deprovision-subject", "", "NATS subject a notification is published on for every okta user deprovisioned after their governor user was deleted, disabled when empty")
+	viperBindFlag("changes.deprovision-subject", serveCmd.Flags().Lookup("deprovision-subject"))
+
+	// Notification flags
+	serveCmd.Flags().String("notify-webhook-url", "", "slack compatible webhook a notice is posted to for every destructive okta change, disabled when empty")
+	viperBindFlag("notify.webhook-url", serveCmd.Flags().Lookup("notify-webhook-url"))
+	serveCmd.Flags().StringSlice("notify-event-types", notify.DefaultEventTypes, "audit event types of the changes posted to the webhook")
+	viperBindFlag("notify.event-types", serveCmd.Flags().Lookup("notify-event-types"))
+	serveCmd.Flags().Duration("notify-timeout", notify.DefaultTimeout, "timeout of a webhook post")
+	viperBindFlag("notify.timeout", serveCmd.Flags().Lookup("notify-timeout"))
+
+	// Journal flags
+	serveCmd.Flags().Bool("journal", false, "enable the change journal of applied okta mutations")
+	viperBindFlag("journal.enabled", serveCmd.Flags().Lookup("journal"))
+	serveCmd.Flags().Duration("journal-retention", journal.DefaultRetention, "how long change journal entries are kept")
+	viperBindFlag("journal.retention", serveCmd.Flags().Lookup("journal-retention"))
 }
 
-func serve(cmdCtx context.Context, _ *viper.Viper) error {
-	initTracing()
+func serve(cmdCtx context.Context, cfg *config.Config) error {
+	initTracing(cfg.Tracing)
+
+	if err := cfg.ValidateServe(); err != nil {
+		return err
+	}
 
-	if err := validateMandatoryFlags(); err != nil {
+	if err := checkProxy(cmdCtx, cfg.Okta.URL, cfg.Governor.URL, cfg.Governor.TokenURL); err != nil {
 		return err
 	}
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	ctx, cancel := context.WithCancel(cmdCtx)
 
@@ -119,23 +284,19 @@ func serve(cmdCtx context.Context, _ *viper.Viper) error {
 		cancel()
 	}()
 
-	auditpath := viper.GetString("audit.log-path")
-
-	if auditpath == "" {
-		logger.Fatal("failed starting server. Audit log file path can't be empty")
-	}
-
 	// WARNING: This will block until the file is available;
 	// make sure an initContainer creates the file
-	auf, auerr := audithelpers.OpenAuditLogFileUntilSuccessWithContext(ctx, auditpath)
+	auf, auerr := audithelpers.OpenAuditLogFileUntilSuccessWithContext(ctx, cfg.Audit.LogPath)
 	if auerr != nil {
 		logger.Fatalw("couldn't open audit file.", "error", auerr)
 	}
 	defer auf.Close()
 
-	nc, natsClose, err := newNATSConnection(
-		viper.GetString("nats.creds-file"),
-		viper.GetString("nats.url"))
+	auw := auditevent.NewDefaultAuditEventWriter(auf)
+
+	nc, natsClose, err := newNATSConnection(cfg.NATS,
+		srv.NATSReconnectOptions(cfg.NATS.ReconnectWait, cfg.NATS.ReconnectMaxWait, cfg.NATS.MaxReconnects)...,
+	)
 	if err != nil {
 		logger.Fatalw("failed to create NATS client connection", "error", err)
 	}
@@ -145,48 +306,46 @@ func serve(cmdCtx context.Context, _ *viper.Viper) error {
 	natsClient, err := srv.NewNATSClient(
 		srv.WithNATSLogger(logger.Desugar()),
 		srv.WithNATSConn(nc),
-		srv.WithNATSPrefix(viper.GetString("nats.subject-prefix")),
-		srv.WithNATSQueueGroup(viper.GetString(("nats.queue-group")), viper.GetInt(("nats.queue-size"))),
+		srv.WithNATSPrefix(cfg.NATS.SubjectPrefix),
+		srv.WithNATSQueueGroup(cfg.NATS.QueueGroup, cfg.NATS.QueueSize),
+		srv.WithNATSSubjects(cfg.NATS.Subjects),
 	)
 	if err != nil {
 		logger.Fatalw("failed creating new NATS client", "error", err)
 	}
 
-	oc, err := okta.NewClient(
+	oktaOpts := []okta.Option{
 		okta.WithLogger(logger.Desugar()),
-		okta.WithURL(viper.GetString("okta.url")),
-		okta.WithToken(viper.GetString("okta.token")),
-		okta.WithCache((!viper.GetBool("okta.nocache"))),
-	)
+		okta.WithURL(cfg.Okta.URL),
+		okta.WithToken(cfg.Okta.Token),
+		okta.WithCache(!cfg.Okta.NoCache),
+		okta.WithCallTimeout(cfg.Okta.CallTimeout),
+		okta.WithListTimeout(cfg.Okta.ListTimeout),
+		okta.WithPageRetries(cfg.Okta.PageRetries, cfg.Okta.PageRetryWait),
+		okta.WithPartialPages(cfg.Okta.PartialPages),
+		okta.WithSecondaryTokens(cfg.Okta.SecondaryTokens),
+		okta.WithTokenStrategy(cfg.Okta.TokenStrategy),
+		okta.WithAuditHook(auctx.OktaMutationHook(logger.Desugar(), auw, "serve")),
+	}
+
+	if cfg.WhatIf {
+		oktaOpts = append(oktaOpts, okta.WithRecorder(okta.NewRecorder(0)))
+	}
+
+	oc, err := okta.NewClient(oktaOpts...)
 	if err != nil {
 		return err
 	}
 
-	gc, err := governor.NewClient(
-		governor.WithLogger(logger.Desugar()),
-		governor.WithURL(viper.GetString("governor.url")),
-		governor.WithClientCredentialConfig(&clientcredentials.Config{
-			ClientID:       viper.GetString("governor.client-id"),
-			ClientSecret:   viper.GetString("governor.client-secret"),
-			TokenURL:       viper.GetString("governor.token-url"),
-			EndpointParams: url.Values{"audience": {viper.GetString("governor.audience")}},
-			Scopes: []string{
-				"read:governor:users",
-				"create:governor:users",
-				"update:governor:users",
-				"read:governor:groups",
-				"read:governor:organizations",
-			},
-		}),
-	)
+	gc, err := newGovernorAPIClient(logger.Desugar(), cfg.Governor, &http.Client{Timeout: governorTimeout}, governorScopes(cfg, "serve")...)
 	if err != nil {
 		return err
 	}
 
 	var locker *natslock.Locker
 
-	if viper.GetBool("reconciler.locking") {
-		l, err := newNATSLocker(nc)
+	if cfg.Reconciler.Locking {
+		l, err := newNATSLocker(nc, cfg.Reconciler.Interval)
 		if err != nil {
 			logger.Warnw("failed to initialize NATS locker", "error", err)
 		}
@@ -196,33 +355,142 @@ func serve(cmdCtx context.Context, _ *viper.Viper) error {
 		}
 	}
 
+	var jrnl *journal.Journal
+
+	if cfg.Journal.Enabled {
+		j, err := newJournal(nc, cfg.Journal.Retention)
+		if err != nil {
+			logger.Fatalw("failed to initialize change journal", "error", err)
+		}
+
+		jrnl = j
+	}
+
+	var stateStore reconciler.StateStore
+
+	if cfg.Reconciler.PersistState {
+		s, err := newStateStore(nc)
+		if err != nil {
+			logger.Fatalw("failed to initialize reconciler state store", "error", err)
+		}
+
+		stateStore = s
+	}
+
+	var changePublisher *changes.Publisher
+
+	if cfg.Changes.Enabled {
+		changePublisher = changes.NewPublisher(nc, changes.WithSubject(cfg.Changes.Subject), changes.WithLogger(logger.Desugar()))
+	}
+
+	var deprovisionNotifier *changes.DeprovisionNotifier
+
+	if cfg.Changes.DeprovisionSubject != "" {
+		deprovisionNotifier = changes.NewDeprovisionNotifier(nc, cfg.Changes.DeprovisionSubject, logger.Desugar())
+	}
+
+	var notifier *notify.Notifier
+
+	if cfg.Notify.WebhookURL != "" {
+		notifier = notify.New(cfg.Notify.WebhookURL,
+			notify.WithEventTypes(cfg.Notify.EventTypes...),
+			notify.WithTimeout(cfg.Notify.Timeout),
+			notify.WithLogger(logger.Desugar()),
+		)
+	}
+
+	var invariants *reconciler.InvariantTolerances
+
+	if cfg.Invariants.Enabled {
+		invariants = &reconciler.InvariantTolerances{
+			Users:       cfg.Invariants.UsersTolerance,
+			Groups:      cfg.Invariants.GroupsTolerance,
+			Memberships: cfg.Invariants.MembershipsTolerance,
+		}
+	}
+
+	groupNames, err := cfg.Okta.GroupNameMapping()
+	if err != nil {
+		logger.Fatalw("failed to compile okta group name mapping", "error", err)
+	}
+
+	groupNameTemplate, err := cfg.Okta.ParseGroupNameTemplate()
+	if err != nil {
+		logger.Fatalw("failed to parse okta group name template", "error", err)
+	}
+
 	rec := reconciler.New(
-		reconciler.WithAuditEventWriter(auditevent.NewDefaultAuditEventWriter(auf)),
+		reconciler.WithAuditEventWriter(auw),
 		reconciler.WithLogger(logger.Desugar()),
-		reconciler.WithIntervals(viper.GetDuration("reconciler.interval"), viper.GetDuration("eventlog.interval"), viper.GetDuration("eventlog.lookback")),
+		reconciler.WithIntervals(cfg.Reconciler.Interval, cfg.Eventlog.Interval, cfg.Eventlog.Lookback),
 		reconciler.WithGovernorClient(gc),
 		reconciler.WithOktaClient(oc),
 		reconciler.WithLocker(locker),
-		reconciler.WithDryRun(viper.GetBool("dryrun")),
-		reconciler.WithSkipDelete(viper.GetBool("skip-delete")),
+		reconciler.WithJournal(jrnl),
+		reconciler.WithChangePublisher(changePublisher),
+		reconciler.WithDeprovisionNotifier(deprovisionNotifier),
+		reconciler.WithWebhookNotifier(notifier),
+		reconciler.WithStateStore(stateStore),
+		reconciler.WithInvariantsCheck(invariants),
+		reconciler.WithDryRun(cfg.DryRun),
+		reconciler.WithSkipDelete(cfg.SkipDelete),
+		reconciler.WithSnapshotShortCircuit(cfg.Reconciler.SnapshotShortCircuit),
+		reconciler.WithGroupScheduleResolution(cfg.Reconciler.GroupScheduleResolution),
+		reconciler.WithOpTimeout(cfg.Reconciler.OpTimeout),
+		reconciler.WithCircuitBreaker(cfg.Reconciler.BreakerFailures, cfg.Reconciler.BreakerCooldown),
+		reconciler.WithGroupQuarantine(cfg.Reconciler.QuarantineFailures, cfg.Reconciler.QuarantineBackoff),
+		reconciler.WithGroupDeleteGracePeriod(cfg.Reconciler.GroupDeleteGracePeriod),
+		reconciler.WithOrphanCleanup(cfg.Reconciler.OrphanCleanup),
+		reconciler.WithGroupOwners(cfg.Reconciler.GroupOwners),
+		reconciler.WithUserGovernorID(cfg.Reconciler.UserGovernorID),
+		reconciler.WithUserMatchKey(okta.UserMatchKey(cfg.Okta.UserMatchKey)),
+		reconciler.WithPermanentUserDelete(cfg.Reconciler.PermanentUserDelete),
+		reconciler.WithOffboardGroupRemoval(cfg.Reconciler.OffboardRemoveGroups),
+		reconciler.WithMemberChangesAsRequests(cfg.Reconciler.MemberChangesAsRequests),
+		reconciler.WithAppAssignmentModes(cfg.Reconciler.AppAssignmentModes()),
+		reconciler.WithAppAssignmentSettings(cfg.Reconciler.AppGroupAssignments()),
+		reconciler.WithPushGroups(cfg.Reconciler.PushGroups...),
+		reconciler.WithUserLifecycles(cfg.Reconciler.UserLifecycles()),
+		reconciler.WithConflictPolicy(cfg.Reconciler.ConflictPolicy()),
+		reconciler.WithInitialDelay(cfg.Reconciler.InitialDelay),
+		reconciler.WithLoopJitter(cfg.Reconciler.Jitter),
+		reconciler.WithRunOnStart(cfg.Reconciler.RunOnStart),
+		reconciler.WithEventlogProfileUpdates(cfg.Eventlog.ProfileUpdates),
+		reconciler.WithSyncUserEmail(cfg.Reconciler.SyncUserEmail),
+		reconciler.WithDefaultGroups(cfg.Reconciler.DefaultGroups...),
+		reconciler.WithOktaGroupIDRecording(cfg.Reconciler.RecordOktaGroupIDs),
+		reconciler.WithVerifySampleSize(cfg.Reconciler.VerifySampleSize),
+		reconciler.WithMembershipConcurrency(cfg.Reconciler.MembershipConcurrency),
+		reconciler.WithOktaRateLimit(cfg.Reconciler.OktaRateLimit, cfg.Reconciler.RateLimitBurst),
+		reconciler.WithUserCache(usercache.New[*v1beta1.User]("reconciler", cfg.Reconciler.UserCache.Size, cfg.Reconciler.UserCache.TTL)),
+		reconciler.WithListUsersOptions(cfg.Reconciler.ListUsersOptions()...),
+		reconciler.WithNonHumanAccounts(cfg.Okta.NonHumanRules()),
+		reconciler.WithGroupNames(groupNames),
+		reconciler.WithGroupNameTemplate(groupNameTemplate),
+		reconciler.WithGroupDescriptionMarker(cfg.Okta.GroupDescriptionMarker()),
+		reconciler.WithPilotCohorts(cfg.Pilot.ReconcilerCohorts()),
 	)
 
 	server := &srv.Server{
-		Debug:           viper.GetBool("logging.debug"),
-		DryRun:          viper.GetBool("dryrun"),
-		Listen:          viper.GetString("listen"),
+		Debug:           cfg.Logging.Debug,
+		DryRun:          cfg.DryRun,
+		Listen:          cfg.Listen,
 		Logger:          logger.Desugar(),
 		AuditFileWriter: auf,
 		NATSClient:      natsClient,
 		Reconciler:      rec,
+		CoalesceWindow:  cfg.NATS.CoalesceWindow,
+		PushgatewayURL:  cfg.Metrics.PushgatewayURL,
+		AdminToken:      cfg.AdminToken,
 	}
 
 	logger.Infow("starting server",
-		"address", viper.GetString("listen"),
+		"address", cfg.Listen,
 		"dryrun", server.DryRun,
-		"skip-delete", viper.GetBool("skip-delete"),
-		"governor-url", viper.GetString("governor.url"),
-		"okta-url", viper.GetString("okta.url"),
+		"skip-delete", cfg.SkipDelete,
+		"what-if", cfg.WhatIf,
+		"governor-url", cfg.Governor.URL,
+		"okta-url", cfg.Okta.URL,
 	)
 
 	if err := server.Run(ctx); err != nil {
@@ -232,19 +500,19 @@ func serve(cmdCtx context.Context, _ *viper.Viper) error {
 	return nil
 }
 
-// newNATSConnection creates a new NATS connection
-func newNATSConnection(credsFile, url string) (*nats.Conn, func(), error) {
+// newNATSConnection creates a new NATS connection authenticating with the configured creds file, nkey, token or
+// tls client certificate, extra options are applied after the defaults
+func newNATSConnection(cfg config.NATSConfig, extra ...nats.Option) (*nats.Conn, func(), error) {
 	opts := []nats.Option{
 		nats.Name(appName),
 	}
 
-	if credsFile != "" {
-		opts = append(opts, nats.UserCredentials(credsFile))
-	} else {
-		return nil, nil, ErrMissingNATSCreds
+	auth, err := natsAuthOptions(cfg)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	nc, err := nats.Connect(url, opts...)
+	nc, err := nats.Connect(cfg.URL, append(append(opts, auth...), extra...)...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -252,8 +520,95 @@ func newNATSConnection(credsFile, url string) (*nats.Conn, func(), error) {
 	return nc, nc.Close, nil
 }
 
-// newNATSLocker creates a new NATS jetstream locker from a NATS connection
-func newNATSLocker(nc *nats.Conn) (*natslock.Locker, error) {
+// natsAuthOptions returns the NATS connection options of the authentication configuration.  The token file is
+// read again on every (re)connect, so a rotated token is picked up without a restart.
+func natsAuthOptions(cfg config.NATSConfig) ([]nats.Option, error) {
+	if !cfg.HasAuth() {
+		return nil, ErrMissingNATSCreds
+	}
+
+	if err := cfg.ValidateAuth(); err != nil {
+		return nil, err
+	}
+
+	opts := []nats.Option{}
+
+	switch {
+	case cfg.CredsFile != "":
+		opts = append(opts, nats.UserCredentials(cfg.CredsFile))
+	case cfg.NKeyFile != "":
+		opt, err := nats.NkeyOptionFromSeed(cfg.NKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNATSNKeyInvalid, err)
+		}
+
+		opts = append(opts, opt)
+	case cfg.Token != "":
+		opts = append(opts, nats.Token(cfg.Token))
+	case cfg.TokenFile != "":
+		if _, err := readNATSToken(cfg.TokenFile); err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, nats.TokenHandler(func() string {
+			token, err := readNATSToken(cfg.TokenFile)
+			if err != nil {
+				logger.Errorw("failed reading NATS token file", "file", cfg.TokenFile, "error", err)
+			}
+
+			return token
+		}))
+	}
+
+	if cfg.TLSCert != "" {
+		opts = append(opts, nats.ClientCert(cfg.TLSCert, cfg.TLSKey))
+	}
+
+	if cfg.TLSCA != "" {
+		opts = append(opts, nats.RootCAs(cfg.TLSCA))
+	}
+
+	return opts, nil
+}
+
+// readNATSToken reads the NATS token from a file
+func readNATSToken(file string) (string, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("%w: %s", ErrNATSTokenFileEmpty, file)
+	}
+
+	return token, nil
+}
+
+// addNATSAuthFlags adds the NATS authentication flags, besides the creds file, to a flag set
+func addNATSAuthFlags(fs *pflag.FlagSet) {
+	fs.String("nats-nkey-file", "", "Path to the file containing the NATS nkey seed")
+	fs.String("nats-token", "", "NATS authentication token")
+	fs.String("nats-token-file", "", "Path to the file containing the NATS authentication token, read again on every reconnect")
+	fs.String("nats-tls-cert", "", "Path to the NATS tls client certificate")
+	fs.String("nats-tls-key", "", "Path to the NATS tls client certificate key")
+	fs.String("nats-tls-ca", "", "Path to the CA certificate verifying the NATS server")
+}
+
+// bindNATSAuthFlags binds the NATS authentication flags added by addNATSAuthFlags
+func bindNATSAuthFlags(fs *pflag.FlagSet) {
+	viperBindFlag("nats.nkey-file", fs.Lookup("nats-nkey-file"))
+	viperBindFlag("nats.token", fs.Lookup("nats-token"))
+	viperBindFlag("nats.token-file", fs.Lookup("nats-token-file"))
+	viperBindFlag("nats.tls-cert", fs.Lookup("nats-tls-cert"))
+	viperBindFlag("nats.tls-key", fs.Lookup("nats-tls-key"))
+	viperBindFlag("nats.tls-ca", fs.Lookup("nats-tls-ca"))
+}
+
+// newNATSLocker creates a new NATS jetstream locker from a NATS connection, the lock
+// expires shortly after the reconciler interval
+func newNATSLocker(nc *nats.Conn, interval time.Duration) (*natslock.Locker, error) {
 	jets, err := nc.JetStream()
 	if err != nil {
 		return nil, err
@@ -262,7 +617,7 @@ func newNATSLocker(nc *nats.Conn) (*natslock.Locker, error) {
 	const timePastInterval = 10 * time.Second
 
 	bucketName := appName + "-lock"
-	ttl := viper.GetDuration("reconciler.interval") + timePastInterval
+	ttl := interval + timePastInterval
 
 	kvStore, err := natslock.NewKeyValue(jets, bucketName, ttl)
 	if err != nil {
@@ -275,41 +630,40 @@ func newNATSLocker(nc *nats.Conn) (*natslock.Locker, error) {
 	)
 }
 
-// validateMandatoryFlags collects the mandatory flag validation
-func validateMandatoryFlags() error {
-	errs := []error{}
-
-	if viper.GetString("nats.url") == "" {
-		errs = append(errs, ErrNATSURLRequired)
-	}
-
-	if viper.GetString("okta.url") == "" {
-		errs = append(errs, ErrOktaURLRequired)
-	}
-
-	if viper.GetString("okta.token") == "" {
-		errs = append(errs, ErrOktaTokenRequired)
+// newStateStore creates the reconciler state store backed by a NATS jetstream key-value bucket
+func newStateStore(nc *nats.Conn) (*reconciler.KVStateStore, error) {
+	jets, err := nc.JetStream()
+	if err != nil {
+		return nil, err
 	}
 
-	if viper.GetString("governor.url") == "" {
-		errs = append(errs, ErrGovernorURLRequired)
+	kvStore, err := natslock.NewKeyValue(jets, stateBucketName, stateBucketTTL)
+	if err != nil {
+		return nil, err
 	}
 
-	if viper.GetString("governor.client-id") == "" {
-		errs = append(errs, ErrGovernorClientIDRequired)
-	}
+	return reconciler.NewKVStateStore(kvStore), nil
+}
 
-	if viper.GetString("governor.client-secret") == "" {
-		errs = append(errs, ErrGovernorClientSecretRequired)
+// newJournal creates a new change journal backed by a NATS jetstream key-value bucket
+func newJournal(nc *nats.Conn, retention time.Duration) (*journal.Journal, error) {
+	jets, err := nc.JetStream()
+	if err != nil {
+		return nil, err
 	}
 
-	if viper.GetString("governor.token-url") == "" {
-		errs = append(errs, ErrGovernorClientTokenURLRequired)
+	kvStore, err := natslock.NewKeyValue(jets, journalBucketName, retention)
+	if err != nil {
+		return nil, err
 	}
 
-	if viper.GetString("governor.audience") == "" {
-		errs = append(errs, ErrGovernorClientAudienceRequired)
+	store, err := journal.NewKVStore(kvStore)
+	if err != nil {
+		return nil, err
 	}
 
-	return errors.Join(errs...)
+	return journal.New(
+		journal.WithStore(store),
+		journal.WithLogger(logger.Desugar()),
+	)
 }
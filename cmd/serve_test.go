@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_natsAuthOptions(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name, content string) string {
+		f := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(f, []byte(content), 0o600))
+
+		return f
+	}
+
+	tokenFile := writeFile("token", "nats-token\n")
+	emptyTokenFile := writeFile("empty-token", "\n")
+	badSeed := writeFile("user.nk", "not a seed")
+
+	tests := []struct {
+		name      string
+		cfg       config.NATSConfig
+		wantOpts  int
+		wantToken string
+		wantErr   error
+	}{
+		{name: "no auth", cfg: config.NATSConfig{}, wantErr: ErrMissingNATSCreds},
+		{name: "creds file", cfg: config.NATSConfig{CredsFile: "/etc/nats/user.creds"}, wantOpts: 1},
+		{name: "token", cfg: config.NATSConfig{Token: "nats-token"}, wantOpts: 1, wantToken: "nats-token"},
+		{name: "token file", cfg: config.NATSConfig{TokenFile: tokenFile}, wantOpts: 1},
+		{name: "empty token file", cfg: config.NATSConfig{TokenFile: emptyTokenFile}, wantErr: ErrNATSTokenFileEmpty},
+		{name: "invalid nkey", cfg: config.NATSConfig{NKeyFile: badSeed}, wantErr: ErrNATSNKeyInvalid},
+		{name: "tls client certificate only", cfg: config.NATSConfig{TLSCert: "tls.crt", TLSKey: "tls.key"}, wantOpts: 1},
+		{
+			name:      "token with tls",
+			cfg:       config.NATSConfig{Token: "nats-token", TLSCert: "tls.crt", TLSKey: "tls.key", TLSCA: "ca.crt"},
+			wantOpts:  3,
+			wantToken: "nats-token",
+		},
+		{name: "conflict", cfg: config.NATSConfig{CredsFile: "/etc/nats/user.creds", Token: "nats-token"}, wantErr: config.ErrNATSAuthConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := natsAuthOptions(tt.cfg)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, opts, tt.wantOpts)
+
+			if tt.wantToken != "" {
+				no := nats.GetDefaultOptions()
+				require.NoError(t, opts[0](&no))
+				assert.Equal(t, tt.wantToken, no.Token)
+			}
+		})
+	}
+}
+
+func Test_natsAuthOptions_tokenFileRotation(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(f, []byte("first"), 0o600))
+
+	opts, err := natsAuthOptions(config.NATSConfig{TokenFile: f})
+	require.NoError(t, err)
+	require.Len(t, opts, 1)
+
+	no := nats.GetDefaultOptions()
+	require.NoError(t, opts[0](&no))
+	require.NotNil(t, no.TokenHandler)
+
+	assert.Equal(t, "first", no.TokenHandler())
+
+	require.NoError(t, os.WriteFile(f, []byte("second"), 0o600))
+	assert.Equal(t, "second", no.TokenHandler())
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+	"github.com/spf13/cobra"
+)
+
+// statusTimeout is the timeout of the request for the reconciler status
+const statusTimeout = 10 * time.Second
+
+// statusCmd shows the status of the reconciler of a running addon
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "show the status of the reconciler of a running addon",
+	Long: `Queries /api/v1/status of a running addon and shows the result and summary of its recent reconciler loops,
+the most recent first. The command fails when the last loop failed.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return showStatus(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().String("addon-url", "http://127.0.0.1:8000", "url of the running addon")
+	statusCmd.Flags().Bool("json", false, "write the status as JSON")
+}
+
+func showStatus(cmd *cobra.Command) error {
+	addonURL, err := cmd.Flags().GetString("addon-url")
+	if err != nil {
+		return err
+	}
+
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return err
+	}
+
+	st, err := getStatus(cmd.Context(), &http.Client{Timeout: statusTimeout}, addonURL)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(st); err != nil {
+			return err
+		}
+	} else if err := writeStatus(out, st); err != nil {
+		return err
+	}
+
+	if len(st.Runs) > 0 && st.Runs[0].Result == reconciler.RunResultFailed {
+		return fmt.Errorf("%w: %s", ErrLastRunFailed, st.Runs[0].Error)
+	}
+
+	return nil
+}
+
+// getStatus returns the reconciler status of the addon
+func getStatus(ctx context.Context, hc *http.Client, addonURL string) (*reconciler.Status, error) {
+	u := strings.TrimSuffix(addonURL, "/") + "/api/v1/status"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d", ErrStatusRequest, resp.StatusCode)
+	}
+
+	st := &reconciler.Status{}
+	if err := json.NewDecoder(resp.Body).Decode(st); err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+// writeStatus writes a table of the recent reconciler loops
+func writeStatus(out io.Writer, st *reconciler.Status) error {
+	w := tabwriter.NewWriter(out, 0, 0, inspectTablePadding, ' ', 0)
+
+	running := ""
+	if st.Running {
+		running = " (running)"
+	}
+
+	fmt.Fprintf(w, "RECONCILER\t%s%s\n", st.ID, running)
+	fmt.Fprintf(w, "  dry-run\t%t\n", st.DryRun)
+	fmt.Fprintf(w, "  skip-delete\t%t\n", st.SkipDelete)
+
+	if st.Paused && st.PausedSince != nil {
+		fmt.Fprintf(w, "  paused\tsince %s %s\n", st.PausedSince.UTC().Format(time.RFC3339), st.PauseReason)
+	}
+
+	fmt.Fprintf(w, "  failing groups\t%d\n", len(st.FailingGroups))
+	fmt.Fprintf(w, "  failing app assignments\t%d\n", len(st.FailingAppAssignments))
+	fmt.Fprintf(w, "  quarantined groups\t%d\n", len(st.QuarantinedGroups))
+	fmt.Fprintf(w, "  pending deletions\t%d\n\n", st.PendingDeletionsTotal)
+
+	if len(st.Runs) == 0 {
+		fmt.Fprintf(w, "no reconciler loops yet\n")
+
+		return w.Flush()
+	}
+
+	fmt.Fprintf(w, "STARTED\tDURATION\tRESULT\tGROUPS\tADDED\tREMOVED\tSKIPPED\tERRORS\tERROR\n")
+
+	for _, run := range st.Runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			run.Started.UTC().Format(time.RFC3339),
+			run.Duration.Round(time.Millisecond),
+			run.Result,
+			run.Summary.GroupsChecked,
+			run.Summary.MembersAdded,
+			run.Summary.MembersRemoved,
+			run.Summary.MembersSkipped,
+			run.Summary.Errors,
+			run.Error,
+		)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getStatus(t *testing.T) {
+	started := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	st := reconciler.Status{
+		ID: "reconciler-1",
+		Runs: []reconciler.RunStatus{{
+			Started:  started,
+			Finished: started.Add(time.Minute),
+			Duration: time.Minute,
+			Result:   reconciler.RunResultPartial,
+			Summary:  reconciler.RunSummary{GroupsChecked: 12, MembersAdded: 3, MembersRemoved: 1, MembersSkipped: 2, Errors: 1},
+		}},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/status" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(st)
+	}))
+	defer srv.Close()
+
+	got, err := getStatus(context.TODO(), srv.Client(), srv.URL+"/")
+	require.NoError(t, err)
+	assert.Equal(t, st.Runs, got.Runs)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, writeStatus(out, got))
+	assert.Contains(t, out.String(), "2023-01-01T00:00:00Z  1m0s      partial  12      3      1        2        1")
+
+	_, err = getStatus(context.TODO(), srv.Client(), srv.URL+"/addon")
+	assert.ErrorIs(t, err, ErrStatusRequest)
+}
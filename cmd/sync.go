@@ -1,13 +1,48 @@
 package cmd
 
 import (
+	"net/http"
+	"time"
+
+	"github.com/metal-toolbox/auditevent"
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govauth"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/ratelimit"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const (
+	// syncOktaTimeout is the http timeout for okta requests, same as the okta sdk default
+	syncOktaTimeout = 30 * time.Second
+
+	// syncUserCacheSize and syncUserCacheTTL are the default bounds of the governor user cache of the sync commands
+	syncUserCacheSize = 10000
+	syncUserCacheTTL  = 10 * time.Minute
 )
 
 // syncCmd governor resources
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "sync governor and okta resources",
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		// bind here instead of init so we don't clobber the serve command bindings for the same keys
+		viperBindFlag("audit.log-path", cmd.Flags().Lookup("audit-log-path"))
+		viperBindFlag("changes.enabled", cmd.Flags().Lookup("publish-changes"))
+		viperBindFlag("changes.subject", cmd.Flags().Lookup("changes-subject"))
+		viperBindFlag("nats.url", cmd.Flags().Lookup("nats-url"))
+		viperBindFlag("nats.creds-file", cmd.Flags().Lookup("nats-creds-file"))
+		bindNATSAuthFlags(cmd.Flags())
+		viperBindFlag("governor.token-skew", cmd.Flags().Lookup("governor-token-skew"))
+		viperBindFlag("okta.group-name-template", cmd.Flags().Lookup("okta-group-name-template"))
+		viperBindFlag("okta.group-description-prefix", cmd.Flags().Lookup("okta-group-description-prefix"))
+		viperBindFlag("okta.group-description-suffix", cmd.Flags().Lookup("okta-group-description-suffix"))
+	},
 }
 
 func init() {
@@ -15,6 +50,7 @@ func init() {
 
 	syncCmd.PersistentFlags().Bool("dry-run", false, "do not make any changes when running a sync")
 	viperBindFlag("sync.dryrun", syncCmd.PersistentFlags().Lookup("dry-run"))
+	syncCmd.PersistentFlags().String("audit-log-path", "", "file path to write the audit events of okta changes to, stderr when empty")
 
 	// Okta related flags
 	syncCmd.PersistentFlags().String("okta-url", "https://example.okta.com", "url for Okta client calls")
@@ -23,6 +59,21 @@ func init() {
 	viperBindFlag("okta.token", syncCmd.PersistentFlags().Lookup("okta-token"))
 	syncCmd.PersistentFlags().Bool("okta-nocache", false, "disable the okta client cache, useful for development")
 	viperBindFlag("okta.nocache", syncCmd.PersistentFlags().Lookup("okta-nocache"))
+	syncCmd.PersistentFlags().Duration("okta-call-timeout", okta.DefaultCallTimeout, "deadline for a single okta call, negative disables it")
+	viperBindFlag("okta.call-timeout", syncCmd.PersistentFlags().Lookup("okta-call-timeout"))
+	syncCmd.PersistentFlags().Duration("okta-list-timeout", okta.DefaultListTimeout, "deadline for okta calls listing all results, negative disables it")
+	viperBindFlag("okta.list-timeout", syncCmd.PersistentFlags().Lookup("okta-list-timeout"))
+	syncCmd.PersistentFlags().StringSlice("okta-secondary-tokens", []string{}, "additional okta api tokens to spread requests over, depends on the org rate limit policy")
+	viperBindFlag("okta.secondary-tokens", syncCmd.PersistentFlags().Lookup("okta-secondary-tokens"))
+	syncCmd.PersistentFlags().String("okta-token-strategy", okta.TokenStrategyRoundRobin, "how the okta api token of each request is selected (round-robin or least-used)")
+	viperBindFlag("okta.token-strategy", syncCmd.PersistentFlags().Lookup("okta-token-strategy"))
+	syncCmd.PersistentFlags().String("okta-user-match-key", string(okta.UserMatchKeyEmail), "okta user attribute governor users are matched on (email, login or externalId)")
+	viperBindFlag("okta.user-match-key", syncCmd.PersistentFlags().Lookup("okta-user-match-key"))
+	syncCmd.PersistentFlags().StringSlice("okta-non-human-user-types", []string{}, "profile userType values of non-human okta accounts (ie. service), which are left out of governor")
+	viperBindFlag("okta.non-human-user-types", syncCmd.PersistentFlags().Lookup("okta-non-human-user-types"))
+	syncCmd.PersistentFlags().String("okta-group-name-template", "", "go template of the okta group names of governor groups, okta groups matching it are synced to the governor group they're named after")
+	syncCmd.PersistentFlags().String("okta-group-description-prefix", "", "prefix of the descriptions of the okta groups managed by governor, stripped from the descriptions synced to governor")
+	syncCmd.PersistentFlags().String("okta-group-description-suffix", "", "suffix of the descriptions of the okta groups managed by governor, stripped from the descriptions synced to governor")
 
 	// Governor related flags
 	syncCmd.PersistentFlags().String("governor-url", "https://api.governor.metalkube.net", "url of the governor api")
@@ -35,6 +86,156 @@ func init() {
 	viperBindFlag("governor.token-url", syncCmd.PersistentFlags().Lookup("governor-token-url"))
 	syncCmd.PersistentFlags().String("governor-audience", "https://api.governor.metalkube.net", "oauth audience for client credential flow")
 	viperBindFlag("governor.audience", syncCmd.PersistentFlags().Lookup("governor-audience"))
+	syncCmd.PersistentFlags().Duration("governor-token-skew", govauth.DefaultSkew, "how long before it expires the governor token is refreshed")
+
+	// Change event flags, the events are published on NATS
+	syncCmd.PersistentFlags().Bool("publish-changes", false, "publish an event on NATS for every change made in governor")
+	syncCmd.PersistentFlags().String("changes-subject", changes.DefaultSubject, "NATS subject the change events are published on")
+	syncCmd.PersistentFlags().String("nats-url", "", "NATS server connection url, required to publish changes")
+	syncCmd.PersistentFlags().String("nats-creds-file", "", "Path to the file containing the NATS credentials file")
+	addNATSAuthFlags(syncCmd.PersistentFlags())
+
+	// Concurrency and rate limit flags
+	syncCmd.PersistentFlags().Int("concurrency", 1, "number of okta objects to process at once")
+	viperBindFlag("sync.concurrency", syncCmd.PersistentFlags().Lookup("concurrency"))
+	syncCmd.PersistentFlags().Float64("okta-rate-limit", 0, "max okta requests per second shared by all workers, 0 is unlimited")
+	viperBindFlag("sync.okta-rate-limit", syncCmd.PersistentFlags().Lookup("okta-rate-limit"))
+	syncCmd.PersistentFlags().Float64("governor-rate-limit", 0, "max governor requests per second shared by all workers, 0 is unlimited")
+	viperBindFlag("sync.governor-rate-limit", syncCmd.PersistentFlags().Lookup("governor-rate-limit"))
+	syncCmd.PersistentFlags().Int("rate-limit-burst", 1, "number of requests allowed to burst past the okta and governor rate limits")
+	viperBindFlag("sync.rate-limit-burst", syncCmd.PersistentFlags().Lookup("rate-limit-burst"))
+
+	// Governor user cache flags
+	syncCmd.PersistentFlags().Int("user-cache-size", syncUserCacheSize, "max number of governor users cached by the users and members syncs, 0 disables the cache")
+	viperBindFlag("sync.user-cache.size", syncCmd.PersistentFlags().Lookup("user-cache-size"))
+	syncCmd.PersistentFlags().Duration("user-cache-ttl", syncUserCacheTTL, "how long the governor users are cached by the users and members syncs, 0 never expires them")
+	viperBindFlag("sync.user-cache.ttl", syncCmd.PersistentFlags().Lookup("user-cache-ttl"))
+}
+
+// loadSyncConfig loads and validates the configuration for the sync commands
+func loadSyncConfig() (*config.Config, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.ValidateSync(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// newSyncOktaClient returns an okta client for the sync commands with the configured concurrency and rate limit,
+// writing an audit event for every okta change to the audit log, and a function closing the audit log
+func newSyncOktaClient(l *zap.Logger, cfg *config.Config) (*okta.Client, func(), error) {
+	auf, closeAudit, err := openAuditLog(cfg.Audit.LogPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := []okta.Option{
+		okta.WithLogger(l),
+		okta.WithURL(cfg.Okta.URL),
+		okta.WithToken(cfg.Okta.Token),
+		okta.WithCache(!cfg.Okta.NoCache),
+		okta.WithConcurrency(cfg.Sync.Concurrency),
+		okta.WithCallTimeout(cfg.Okta.CallTimeout),
+		okta.WithListTimeout(cfg.Okta.ListTimeout),
+		okta.WithSecondaryTokens(cfg.Okta.SecondaryTokens),
+		okta.WithTokenStrategy(cfg.Okta.TokenStrategy),
+		okta.WithAuditHook(auctx.OktaMutationHook(l, auditevent.NewDefaultAuditEventWriter(auf), "sync")),
+	}
+
+	if limiter := ratelimit.New(cfg.Sync.OktaRateLimit, cfg.Sync.RateLimitBurst); limiter != nil {
+		opts = append(opts, okta.WithHTTPClient(ratelimit.HTTPClient(&http.Client{Timeout: syncOktaTimeout}, limiter)))
+	}
+
+	oc, err := okta.NewClient(opts...)
+	if err != nil {
+		closeAudit()
+
+		return nil, nil, err
+	}
+
+	return oc, closeAudit, nil
+}
+
+// newSyncGovernorClient returns a governor client for the sync commands with the given scopes and configured rate limit
+func newSyncGovernorClient(l *zap.Logger, cfg *config.Config, scopes ...string) (*governor.Client, error) {
+	hc := ratelimit.HTTPClient(&http.Client{Timeout: governorTimeout}, ratelimit.New(cfg.Sync.GovernorRateLimit, cfg.Sync.RateLimitBurst))
+
+	return newGovernorClient(l, cfg.Governor, hc, scopes...)
+}
+
+// newSyncGovernorAPIClient returns a governor client like newSyncGovernorClient, extended with the governor api
+// calls the governor client doesn't support yet
+func newSyncGovernorAPIClient(l *zap.Logger, cfg *config.Config, scopes ...string) (*govclient.Client, error) {
+	hc := ratelimit.HTTPClient(&http.Client{Timeout: governorTimeout}, ratelimit.New(cfg.Sync.GovernorRateLimit, cfg.Sync.RateLimitBurst))
+
+	return newGovernorAPIClient(l, cfg.Governor, hc, scopes...)
+}
+
+// newSyncChangePublisher returns the publisher of the changes made by the sync commands and a function closing
+// its NATS connection, the publisher is nil when publishing changes is disabled
+func newSyncChangePublisher(l *zap.Logger, cfg *config.Config) (*changes.Publisher, func(), error) {
+	if !cfg.Changes.Enabled {
+		return nil, func() {}, nil
+	}
+
+	nc, natsClose, err := newNATSConnection(cfg.NATS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return changes.NewPublisher(nc, changes.WithSubject(cfg.Changes.Subject), changes.WithLogger(l)), natsClose, nil
+}
+
+// addSyncOktaUserFlags adds the okta users search and filter flags to a sync command.  They're bound when the
+// command runs instead of in init, since more than one sync command has them.
+func addSyncOktaUserFlags(cmd *cobra.Command) {
+	cmd.Flags().String("okta-search", "", `okta search expression of the users to sync (ie. profile.department eq "Engineering"), all users when empty`)
+	cmd.Flags().String("okta-filter", "", `okta filter expression of the users to sync (ie. status eq "ACTIVE"), all users when empty`)
+
+	cmd.PreRun = func(cmd *cobra.Command, _ []string) {
+		viperBindFlag("sync.okta-search", cmd.Flags().Lookup("okta-search"))
+		viperBindFlag("sync.okta-filter", cmd.Flags().Lookup("okta-filter"))
+	}
+}
+
+// partialUserSync returns true if only the okta users matching the search or filter are synced
+func partialUserSync(cfg config.SyncConfig) bool {
+	return cfg.OktaSearch != "" || cfg.OktaFilter != ""
+}
+
+// inSyncScope returns true if an okta user matching value is synced, every value is when the scope is nil
+func inSyncScope(scope map[string]string, value string) bool {
+	if scope == nil {
+		return true
+	}
+
+	_, ok := scope[value]
+
+	return ok
+}
+
+// governorUserQuery returns the governor users query for an okta user matching value, users are matched on
+// the governor external id when matching by externalId and on the governor email otherwise
+func governorUserQuery(key okta.UserMatchKey, value string) map[string][]string {
+	if key == okta.UserMatchKeyExternalID {
+		return map[string][]string{"external_id": {value}}
+	}
+
+	return map[string][]string{"email": {value}}
+}
+
+// governorUserMatchValue returns the value of a governor user that is matched with the okta user matching value
+func governorUserMatchValue(key okta.UserMatchKey, email, externalID string) string {
+	if key == okta.UserMatchKeyExternalID {
+		return externalID
+	}
+
+	return email
 }
 
 func contains(list []string, item string) bool {
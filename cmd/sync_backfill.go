@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gosimple/slug"
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/okta/okta-sdk-golang/v2/okta/query"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// syncBackfillGovernorIDsCmd backfills the governor id on okta group profiles
+var syncBackfillGovernorIDsCmd = &cobra.Command{
+	Use:   "backfill-governor-ids",
+	Short: "backfill the governor_id on okta group profiles",
+	Long: `Scans all Okta groups and matches them to Governor groups by slug, writing the governor_id
+to the Okta group profile where it is missing. Groups are never created or deleted in either system, and
+Okta groups that already have a governor_id are left untouched. A governor_id already used by another Okta
+group is never written again, the group is reported as a conflict instead. It is strongly recommended that you use
+the dry-run flag first to see what groups would be updated in Okta.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, err := loadSyncConfig()
+		if err != nil {
+			return err
+		}
+
+		return syncBackfillGovernorIDs(cmd.Context(), cfg)
+	},
+}
+
+func init() {
+	syncCmd.AddCommand(syncBackfillGovernorIDsCmd)
+
+	syncBackfillGovernorIDsCmd.Flags().String("selector-prefix", "", "if set, only group names that start with this string will be processed")
+	viperBindFlag("sync.backfill.selector-prefix", syncBackfillGovernorIDsCmd.Flags().Lookup("selector-prefix"))
+
+	syncBackfillGovernorIDsCmd.Flags().StringSlice("skip-groups", []string{"Everyone", "catchall"}, "groups to skip during the backfill")
+	viperBindFlag("sync.backfill.skip-groups", syncBackfillGovernorIDsCmd.Flags().Lookup("skip-groups"))
+}
+
+// backfillOptions are the options of the governor id backfill
+type backfillOptions struct {
+	SelectorPrefix string
+	SkipGroups     []string
+	Marker         okta.GroupDescriptionMarker
+	DryRun         bool
+}
+
+// backfillResult counts the okta groups of the governor id backfill
+type backfillResult struct {
+	Updated   int64
+	Existing  int64
+	Unmatched int64
+	Conflicts int64
+	Skipped   int64
+}
+
+func syncBackfillGovernorIDs(ctx context.Context, cfg *config.Config) error {
+	logger := logger.Desugar()
+
+	oc, closeAudit, err := newSyncOktaClient(logger, cfg)
+	if err != nil {
+		return err
+	}
+
+	defer closeAudit()
+
+	gc, err := newSyncGovernorClient(logger, cfg, governorScopes(cfg, "sync-backfill")...)
+	if err != nil {
+		return err
+	}
+
+	res, err := backfillGovernorIDs(ctx, logger, oc, gc, backfillOptions{
+		SelectorPrefix: cfg.Sync.Backfill.SelectorPrefix,
+		SkipGroups:     cfg.Sync.Backfill.SkipGroups,
+		Marker:         cfg.Okta.GroupDescriptionMarker(),
+		DryRun:         cfg.Sync.DryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("completed governor id backfill",
+		zap.Int64("okta.groups.updated", res.Updated),
+		zap.Int64("okta.groups.existing", res.Existing),
+		zap.Int64("okta.groups.unmatched", res.Unmatched),
+		zap.Int64("okta.groups.conflicts", res.Conflicts),
+		zap.Int64("okta.groups.skipped", res.Skipped),
+	)
+
+	return nil
+}
+
+// backfillGovernorIDs writes the governor id of the governor group with the same slug on the okta groups without
+// one.  A governor id already used by another okta group is never written again, the group is counted as a conflict
+// and left alone so the governor group keeps resolving to a single okta group.
+func backfillGovernorIDs(ctx context.Context, logger *zap.Logger, oc *okta.Client, gc *governor.Client, opts backfillOptions) (*backfillResult, error) {
+	logger.Info("starting backfill of governor ids on okta groups", zap.Bool("dry-run", opts.DryRun))
+
+	linked, err := oc.ListGroupsWithGovernorID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the governor ids in use, guarded by the mutex since the modifier can run concurrently
+	var mu sync.Mutex
+
+	inUse := make(map[string]string, len(linked))
+
+	for _, g := range linked {
+		if gid, err := okta.GroupGovernorID(g); err == nil {
+			inUse[gid] = g.Id
+		}
+	}
+
+	// claim reserves the governor id for the okta group, returning the okta group already using it
+	claim := func(gid, oktaGID string) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if other, ok := inUse[gid]; ok {
+			return other, false
+		}
+
+		inUse[gid] = oktaGID
+
+		return "", true
+	}
+
+	// counters are atomic since the modifier can run concurrently
+	var updated, existing, unmatched, conflicts, skipped atomic.Int64
+
+	backfillFunc := func(ctx context.Context, g *okt.Group) (*okt.Group, error) {
+		l := logger.With(zap.String("okta.group.id", g.Id))
+
+		if g.Profile == nil {
+			return nil, okta.ErrNilGroupProfile
+		}
+
+		groupName := g.Profile.Name
+
+		l = l.With(zap.String("okta.group.name", groupName))
+
+		if g.Type == "APP_GROUP" {
+			l.Debug("skipping app group")
+
+			skipped.Add(1)
+
+			return nil, nil
+		}
+
+		if !strings.HasPrefix(strings.ToLower(groupName), strings.ToLower(opts.SelectorPrefix)) {
+			l.Debug("skipping non-selected group")
+
+			skipped.Add(1)
+
+			return nil, nil
+		}
+
+		for _, sg := range opts.SkipGroups {
+			if strings.EqualFold(groupName, sg) {
+				l.Info("skipping group in skip list")
+
+				skipped.Add(1)
+
+				return nil, nil
+			}
+		}
+
+		governorID, err := okta.GroupGovernorID(g)
+		if err == nil {
+			l.Debug("okta group already has a governor id", zap.String("governor.group.id", governorID))
+
+			existing.Add(1)
+
+			return nil, nil
+		}
+
+		if !errors.Is(err, okta.ErrGroupGovernorIDNotFound) {
+			return nil, err
+		}
+
+		govGroup, err := groupFromGroupSlug(ctx, gc, slug.Make(groupName), l)
+		if err != nil {
+			return nil, err
+		}
+
+		if govGroup == nil {
+			l.Info("no governor group found matching okta group slug, skipping")
+
+			unmatched.Add(1)
+
+			return nil, nil
+		}
+
+		l = l.With(
+			zap.String("governor.group.id", govGroup.ID),
+			zap.String("governor.group.slug", govGroup.Slug),
+		)
+
+		if other, ok := claim(govGroup.ID, g.Id); !ok {
+			l.Warn("governor id already used by another okta group, skipping", zap.String("okta.group.conflict.id", other))
+
+			conflicts.Add(1)
+
+			return nil, nil
+		}
+
+		if opts.DryRun {
+			l.Info("SKIP writing governor id on okta group profile")
+
+			updated.Add(1)
+
+			return g, nil
+		}
+
+		// keep any existing custom profile attributes, okta replaces the whole profile on update
+		profile := map[string]interface{}{}
+		for k, v := range g.Profile.GroupProfileMap {
+			profile[k] = v
+		}
+
+		profile[okta.GroupProfileGovernorIDKey] = govGroup.ID
+
+		l.Info("writing governor id on okta group profile")
+
+		grp, err := oc.UpdateGroup(ctx, g.Id, groupName, opts.Marker.Mark(g.Profile.Description), profile)
+		if err != nil {
+			return nil, err
+		}
+
+		updated.Add(1)
+
+		return grp, nil
+	}
+
+	qp := &query.Params{}
+	if opts.SelectorPrefix != "" {
+		qp.Q = opts.SelectorPrefix
+	}
+
+	if _, err := oc.ListGroupsWithModifier(ctx, backfillFunc, qp); err != nil {
+		return nil, err
+	}
+
+	return &backfillResult{
+		Updated:   updated.Load(),
+		Existing:  existing.Load(),
+		Unmatched: unmatched.Load(),
+		Conflicts: conflicts.Load(),
+		Skipped:   skipped.Load(),
+	}, nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_backfillGovernorIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  backfillOptions
+		want  backfillResult
+		wantG map[string]interface{}
+	}{
+		{
+			name: "backfill",
+			opts: backfillOptions{SkipGroups: []string{"Everyone"}},
+			want: backfillResult{Updated: 1, Existing: 1, Unmatched: 1, Conflicts: 1, Skipped: 1},
+			wantG: map[string]interface{}{
+				"00g-platform": "group-1",
+				"00g-linked":   "group-2",
+				"00g-storage":  nil,
+				"00g-unknown":  nil,
+				"00g-everyone": nil,
+			},
+		},
+		{
+			name: "dry run",
+			opts: backfillOptions{SkipGroups: []string{"Everyone"}, DryRun: true},
+			want: backfillResult{Updated: 1, Existing: 1, Unmatched: 1, Conflicts: 1, Skipped: 1},
+			wantG: map[string]interface{}{
+				"00g-platform": nil,
+				"00g-linked":   "group-2",
+				"00g-storage":  nil,
+				"00g-unknown":  nil,
+				"00g-everyone": nil,
+			},
+		},
+		{
+			name: "selector prefix",
+			opts: backfillOptions{SelectorPrefix: "plat"},
+			want: backfillResult{Updated: 1},
+			wantG: map[string]interface{}{
+				"00g-platform": "group-1",
+				"00g-storage":  nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform"})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-2", Name: "Storage", Slug: "storage"})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-3", Name: "Everyone", Slug: "everyone"})
+
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{"team": "platform"})
+			o.AddGroup("00g-linked", "Storage Team", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-2"})
+			o.AddGroup("00g-storage", "Storage", nil)
+			o.AddGroup("00g-unknown", "Unknown", nil)
+			o.AddGroup("00g-everyone", "Everyone", nil)
+
+			oc, err := okta.NewClient(
+				okta.WithURL(o.URL),
+				okta.WithToken("okta-token"),
+				okta.WithCache(false),
+				okta.WithHTTPClient(o.Client()),
+			)
+			require.NoError(t, err)
+
+			got, err := backfillGovernorIDs(context.TODO(), zap.NewNop(), oc, newTestGovernorClient(t, g), tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+
+			for id, want := range tt.wantG {
+				assert.Equal(t, want, o.Group(id).Profile.GroupProfileMap[okta.GroupProfileGovernorIDKey], id)
+			}
+
+			// custom profile attributes are kept
+			assert.Equal(t, "platform", o.Group("00g-platform").Profile.GroupProfileMap["team"])
+		})
+	}
+}
@@ -3,19 +3,22 @@ package cmd
 import (
 	"context"
 	"errors"
-	"net/url"
+	"net/http"
 	"strings"
+	"sync/atomic"
 
 	"github.com/gosimple/slug"
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
 	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
 	governor "github.com/metal-toolbox/governor-api/pkg/client"
 	okt "github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
-	"golang.org/x/oauth2/clientcredentials"
 )
 
 // syncGroupsCmd syncs okta groups into governor
@@ -27,7 +30,12 @@ Groups that exist in Okta but not in Governor, will be created. Groups that exis
 This command is intended for doing an initial load of groups. It is strongly recommended that you use the dry-run flag first 
 to see what groups would be created/deleted in Governor.`,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		return syncGroupsToGovernor(cmd.Context())
+		cfg, err := loadSyncConfig()
+		if err != nil {
+			return err
+		}
+
+		return syncGroupsToGovernor(cmd.Context(), cfg)
 	},
 }
 
@@ -42,51 +50,97 @@ func init() {
 
 	syncGroupsCmd.PersistentFlags().StringSlice("skip-groups", []string{"Everyone", "catchall"}, "groups to skip during the sync")
 	viperBindFlag("sync.skip-groups", syncGroupsCmd.PersistentFlags().Lookup("skip-groups"))
+
+	syncGroupsCmd.PersistentFlags().String("metadata-target", "", "sync the okta group description and metadata attributes into the governor group description or note")
+	viperBindFlag("sync.metadata.target", syncGroupsCmd.PersistentFlags().Lookup("metadata-target"))
+
+	syncGroupsCmd.PersistentFlags().StringSlice("metadata-attributes", []string{}, "okta group profile attributes to include in the synced metadata")
+	viperBindFlag("sync.metadata.attributes", syncGroupsCmd.PersistentFlags().Lookup("metadata-attributes"))
+
+	syncGroupsCmd.PersistentFlags().String("metadata-strategy", config.MetadataStrategyKeep, "how to handle existing governor values when syncing metadata (keep, replace or append)")
+	viperBindFlag("sync.metadata.strategy", syncGroupsCmd.PersistentFlags().Lookup("metadata-strategy"))
+
+	syncGroupsCmd.PersistentFlags().String("admins-source", "", "import the admins of the created governor groups from the okta group owners or admins group (owners or group), disabled when empty")
+	viperBindFlag("sync.admins.source", syncGroupsCmd.PersistentFlags().Lookup("admins-source"))
+
+	syncGroupsCmd.PersistentFlags().String("admins-group-suffix", config.DefaultAdminsGroupSuffix, "name suffix of the okta group holding the admins of a group, used with the group admins source")
+	viperBindFlag("sync.admins.group-suffix", syncGroupsCmd.PersistentFlags().Lookup("admins-group-suffix"))
 }
 
-func syncGroupsToGovernor(ctx context.Context) error {
+func syncGroupsToGovernor(ctx context.Context, cfg *config.Config) error {
 	logger := logger.Desugar()
-	dryRun := viper.GetBool("sync.dryrun")
-	selectorPrefix := viper.GetString("sync.selector-prefix")
+	dryRun := cfg.Sync.DryRun
+	selectorPrefix := cfg.Sync.SelectorPrefix
 
 	logger.Info("starting sync to governor groups", zap.Bool("dry-run", dryRun))
 
-	oc, err := okta.NewClient(
-		okta.WithLogger(logger),
-		okta.WithURL(viper.GetString("okta.url")),
-		okta.WithToken(viper.GetString("okta.token")),
-		okta.WithCache((!viper.GetBool("okta.nocache"))),
-	)
+	oc, closeAudit, err := newSyncOktaClient(logger, cfg)
 	if err != nil {
 		return err
 	}
 
-	gc, err := governor.NewClient(
-		governor.WithLogger(logger),
-		governor.WithURL(viper.GetString("governor.url")),
-		governor.WithClientCredentialConfig(&clientcredentials.Config{
-			ClientID:       viper.GetString("governor.client-id"),
-			ClientSecret:   viper.GetString("governor.client-secret"),
-			TokenURL:       viper.GetString("governor.token-url"),
-			EndpointParams: url.Values{"audience": {viper.GetString("governor.audience")}},
-			Scopes: []string{
-				"write",
-				"read:governor:groups",
-				"read:governor:organizations",
-			},
-		}),
-	)
+	defer closeAudit()
+
+	scopes := governorScopes(cfg, "sync-groups")
+
+	gc, err := newSyncGovernorClient(logger, cfg, scopes...)
+	if err != nil {
+		return err
+	}
+
+	pub, closePub, err := newSyncChangePublisher(logger, cfg)
 	if err != nil {
 		return err
 	}
 
-	var created, skipped int
+	defer closePub()
+
+	// admins are only imported into the governor groups created by the sync
+	var admins *groupAdminsImporter
+
+	if cfg.Sync.Admins.Source != "" {
+		ac, err := newSyncGovernorAPIClient(logger, cfg, scopes...)
+		if err != nil {
+			return err
+		}
+
+		admins = &groupAdminsImporter{
+			oc:       oc,
+			gc:       gc,
+			ac:       ac,
+			pub:      pub,
+			users:    usercache.New[*v1alpha1.User]("sync-groups", cfg.Sync.UserCache.Size, cfg.Sync.UserCache.TTL),
+			matchKey: okta.UserMatchKey(cfg.Okta.UserMatchKey),
+			cfg:      cfg.Sync.Admins,
+		}
+	}
+
+	// the governor client can't update groups, so descriptions are updated with our own oauth client
+	var govHTTPClient *http.Client
+	if cfg.Sync.Metadata.Target == config.MetadataTargetDescription {
+		govHTTPClient = governorClientCredentials(cfg.Governor, scopes...).Client(ctx)
+	}
+
+	// counters are atomic since the modifier can run concurrently
+	var created, skipped, adminsAdded atomic.Int64
 
 	govOrgs, err := govOrgsMap(ctx, gc)
 	if err != nil {
 		return err
 	}
 
+	names, err := cfg.Okta.GroupNameMapping()
+	if err != nil {
+		return err
+	}
+
+	nameTemplate, err := cfg.Okta.ParseGroupNameTemplate()
+	if err != nil {
+		return err
+	}
+
+	marker := cfg.Okta.GroupDescriptionMarker()
+
 	syncFunc := func(ctx context.Context, g *okt.Group) (*okt.Group, error) {
 		l := logger.With(zap.String("okta.group.id", g.Id))
 
@@ -95,14 +149,14 @@ func syncGroupsToGovernor(ctx context.Context) error {
 		}
 
 		groupName := g.Profile.Name
-		groupDesc := g.Profile.Description
+		groupDesc := marker.Strip(g.Profile.Description)
 
 		l = l.With(zap.String("okta.group.name", groupName))
 
 		if g.Type == "APP_GROUP" {
 			l.Info("skipping app group")
 
-			skipped++
+			skipped.Add(1)
 
 			return nil, nil
 		}
@@ -110,21 +164,25 @@ func syncGroupsToGovernor(ctx context.Context) error {
 		if !strings.HasPrefix(strings.ToLower(groupName), strings.ToLower(selectorPrefix)) {
 			l.Info("skipping non-selected group")
 
-			skipped++
+			skipped.Add(1)
 
 			return nil, nil
 		}
 
-		for _, g := range viper.GetStringSlice("sync.skip-groups") {
+		for _, g := range cfg.Sync.SkipGroups {
 			if strings.EqualFold(groupName, g) {
 				l.Info("skipping group in skip list")
 
-				skipped++
+				skipped.Add(1)
 
 				return nil, nil
 			}
 		}
 
+		govName := governorGroupFromOktaName(names, nameTemplate, groupName)
+
+		l = l.With(zap.String("governor.group.name", govName.Name))
+
 		l.Debug("processing okta group")
 
 		governorID, err := okta.GroupGovernorID(g)
@@ -133,6 +191,9 @@ func syncGroupsToGovernor(ctx context.Context) error {
 			if !errors.Is(err, okta.ErrGroupGovernorIDNotFound) {
 				return nil, err
 			}
+
+			// okta groups named after the group name template have the governor id in their name
+			governorID = govName.ID
 		}
 
 		govGroup, found, err := groupFromGroupID(ctx, gc, governorID, l)
@@ -141,22 +202,36 @@ func syncGroupsToGovernor(ctx context.Context) error {
 		}
 
 		if govGroup == nil {
-			govGroup, err = groupFromGroupSlug(ctx, gc, slug.Make(groupName), l)
+			govGroup, err = groupFromGroupSlug(ctx, gc, govName.Slug, l)
 			if err != nil {
 				return nil, err
 			}
 		}
 
+		metadata := ""
+		if cfg.Sync.Metadata.Target != "" {
+			metadata = oktaGroupMetadata(g, marker, cfg.Sync.Metadata.Attributes)
+		}
+
 		if govGroup == nil {
 			l.Info("group not found in governor, creating")
 
+			req := &v1alpha1.GroupReq{
+				Name:        govName.Name,
+				Description: groupDesc,
+			}
+
+			switch cfg.Sync.Metadata.Target {
+			case config.MetadataTargetDescription:
+				req.Description, _ = mergeGroupMetadata("", metadata, cfg.Sync.Metadata.Strategy)
+			case config.MetadataTargetNote:
+				req.Note = metadata
+			}
+
 			if !dryRun {
 				var err error
 
-				govGroup, err = gc.CreateGroup(ctx, &v1alpha1.GroupReq{
-					Name:        groupName,
-					Description: groupDesc,
-				})
+				govGroup, err = gc.CreateGroup(ctx, req)
 				if err != nil {
 					return nil, err
 				}
@@ -167,15 +242,34 @@ func syncGroupsToGovernor(ctx context.Context) error {
 				)
 
 				l.Debug("created governor group from okta sync")
+
+				pub.Publish(ctx, changes.SystemGovernor, "GovernorGroupCreate", map[string]string{
+					"governor.group.id":   govGroup.ID,
+					"governor.group.slug": govGroup.Slug,
+					"okta.group.id":       g.Id,
+				})
+
+				if admins != nil {
+					n, err := admins.importGroupAdmins(ctx, g, govGroup, l)
+
+					adminsAdded.Add(int64(n))
+
+					if err != nil {
+						l.Warn("failed to import okta group admins")
+						return nil, err
+					}
+				}
 			}
 
-			created++
+			created.Add(1)
+		} else if err := syncGovernorGroupMetadata(ctx, govHTTPClient, cfg, govGroup, metadata, l); err != nil {
+			return nil, err
 		}
 
 		// if we found the group by slug or if we created the group, we should update the okta
 		// group profile to contain the correct governor id
 		if !found {
-			grp, err := updateOktaGroupProfile(ctx, oc, g.Id, groupName, groupDesc, govGroup, l)
+			grp, err := updateOktaGroupProfile(ctx, oc, &cfg.Sync, g.Id, groupName, marker.Mark(groupDesc), govGroup, l)
 			if err != nil {
 				return nil, err
 			}
@@ -188,10 +282,10 @@ func syncGroupsToGovernor(ctx context.Context) error {
 			return nil, err
 		}
 
-		l.Debug("okta github applications assigned to group", zap.Any("okta.applications", apps))
+		l.Debug("okta github applications assigned to group", redact.Any("okta.applications", apps))
 
 		if !dryRun {
-			govExpectedOrganizations, err := linkGovernorGroupOrganizations(ctx, gc, apps, govGroup, govOrgs, l)
+			govExpectedOrganizations, err := linkGovernorGroupOrganizations(ctx, gc, pub, apps, govGroup, govOrgs, l)
 			if err != nil {
 				l.Warn("failed to link governor group organizations")
 				return nil, err
@@ -199,7 +293,7 @@ func syncGroupsToGovernor(ctx context.Context) error {
 
 			l.Debug("pruning orphaned governor group organization assignments")
 
-			if err := pruneOrphanGovernorGroupOrganizations(ctx, gc, govGroup.ID, govExpectedOrganizations, govGroup.Organizations, l); err != nil {
+			if err := pruneOrphanGovernorGroupOrganizations(ctx, gc, pub, govGroup.ID, govExpectedOrganizations, govGroup.Organizations, l); err != nil {
 				l.Warn("failed to unlink orphaned governor group organizations")
 				return nil, err
 			}
@@ -208,27 +302,67 @@ func syncGroupsToGovernor(ctx context.Context) error {
 		return g, nil
 	}
 
-	groups, err := oc.ListGroupsWithModifier(ctx, syncFunc, &query.Params{})
+	// when selecting by prefix, let okta do the filtering so we don't list every group in the org
+	qp := &query.Params{}
+	if selectorPrefix != "" {
+		qp.Q = selectorPrefix
+	}
+
+	groups, err := oc.ListGroupsWithModifier(ctx, syncFunc, qp)
 	if err != nil {
 		return err
 	}
 
-	logger.Debug("groups from okta", zap.Any("okta.groups", groups))
+	logger.Debug("groups from okta", redact.Any("okta.groups", groups))
 
-	deleted, err := deleteOrphanGovernorGroups(ctx, gc, uniqueGovernorGroupIDs(groups), logger)
+	deleted, err := deleteOrphanGovernorGroups(ctx, gc, pub, &cfg.Sync, names, nameTemplate, uniqueGovernorGroupIDs(groups), logger)
 	if err != nil {
 		return err
 	}
 
 	logger.Info("completed group sync",
-		zap.Int("governor.groups.created", created),
+		zap.Int64("governor.groups.created", created.Load()),
 		zap.Int("governor.groups.deleted", len(deleted)),
-		zap.Int("governor.groups.skipped", skipped),
+		zap.Int64("governor.groups.skipped", skipped.Load()),
+		zap.Int64("governor.groups.admins", adminsAdded.Load()),
 	)
 
 	return nil
 }
 
+// syncGovernorGroupMetadata merges the okta group metadata into an existing governor group
+func syncGovernorGroupMetadata(ctx context.Context, hc *http.Client, cfg *config.Config, govGroup *v1alpha1.Group, metadata string, l *zap.Logger) error {
+	switch cfg.Sync.Metadata.Target {
+	case config.MetadataTargetDescription:
+		desc, changed := mergeGroupMetadata(govGroup.Description, metadata, cfg.Sync.Metadata.Strategy)
+		if !changed {
+			return nil
+		}
+
+		l.Info("updating governor group description from okta metadata",
+			zap.String("governor.group.id", govGroup.ID),
+			zap.String("governor.group.description", desc),
+		)
+
+		if cfg.Sync.DryRun {
+			return nil
+		}
+
+		if err := updateGovernorGroupDescription(ctx, hc, cfg.Governor.URL, govGroup, desc); err != nil {
+			l.Warn("failed to update governor group description", zap.Error(err))
+			return err
+		}
+	case config.MetadataTargetNote:
+		if _, changed := mergeGroupMetadata(govGroup.Note, metadata, cfg.Sync.Metadata.Strategy); changed {
+			l.Info("governor group notes can only be set when the group is created, skipping okta metadata",
+				zap.String("governor.group.id", govGroup.ID),
+			)
+		}
+	}
+
+	return nil
+}
+
 // govOrgMaps returns a list of governor org names to
 func govOrgsMap(ctx context.Context, gc *governor.Client) (map[string]*v1alpha1.Organization, error) {
 	resp := map[string]*v1alpha1.Organization{}
@@ -248,6 +382,7 @@ func govOrgsMap(ctx context.Context, gc *governor.Client) (map[string]*v1alpha1.
 func linkGovernorGroupOrganizations(
 	ctx context.Context,
 	gc *governor.Client,
+	pub *changes.Publisher,
 	oktaApps map[string]string,
 	govGroup *v1alpha1.Group,
 	govOrgs map[string]*v1alpha1.Organization,
@@ -294,13 +429,15 @@ func linkGovernorGroupOrganizations(
 
 				continue
 			}
+
+			pub.Publish(ctx, changes.SystemGovernor, "GovernorGroupOrganizationAdd", governorGroupOrganizationChangeTarget(govGroup.ID, org.ID))
 		}
 	}
 
 	return govExpectedOrganizations, nil
 }
 
-func pruneOrphanGovernorGroupOrganizations(ctx context.Context, gc *governor.Client, groupID string, expected, actual []string, l *zap.Logger) error {
+func pruneOrphanGovernorGroupOrganizations(ctx context.Context, gc *governor.Client, pub *changes.Publisher, groupID string, expected, actual []string, l *zap.Logger) error {
 	// remove any organization links that are unexpected
 	for _, org := range actual {
 		if !contains(expected, org) {
@@ -315,6 +452,8 @@ func pruneOrphanGovernorGroupOrganizations(ctx context.Context, gc *governor.Cli
 
 				continue
 			}
+
+			pub.Publish(ctx, changes.SystemGovernor, "GovernorGroupOrganizationRemove", governorGroupOrganizationChangeTarget(groupID, org))
 		}
 	}
 
@@ -387,9 +526,38 @@ func groupFromGroupSlug(ctx context.Context, gc *governor.Client, slug string, l
 	return govGroup, nil
 }
 
-func deleteOrphanGovernorGroups(ctx context.Context, gc *governor.Client, gIDs map[string]struct{}, l *zap.Logger) ([]string, error) {
-	dryRun := viper.GetBool("sync.dryrun")
-	selectorPrefix := viper.GetString("sync.selector-prefix")
+// governorGroupFromOktaName returns the governor group name and slug of an okta group name.  Okta group names
+// following a naming convention are mapped to the governor group name and okta groups named after the group name
+// template are matched back to the governor group they're named after, including its id if the template has it.
+func governorGroupFromOktaName(names okta.GroupNameMapping, tmpl *okta.GroupNameTemplate, oktaName string) okta.GroupNameFields {
+	if tmpl != nil {
+		if f, ok := tmpl.Match(oktaName); ok {
+			switch {
+			case f.Name != "":
+				f.Name = names.GovernorName(f.Name)
+			case f.Slug != "":
+				f.Name = f.Slug
+			default:
+				// only the id is in the name
+				f.Name = names.GovernorName(oktaName)
+			}
+
+			if f.Slug == "" {
+				f.Slug = slug.Make(f.Name)
+			}
+
+			return f
+		}
+	}
+
+	name := names.GovernorName(oktaName)
+
+	return okta.GroupNameFields{Name: name, Slug: slug.Make(name)}
+}
+
+func deleteOrphanGovernorGroups(ctx context.Context, gc *governor.Client, pub *changes.Publisher, cfg *config.SyncConfig, names okta.GroupNameMapping, tmpl *okta.GroupNameTemplate, gIDs map[string]struct{}, l *zap.Logger) ([]string, error) {
+	dryRun := cfg.DryRun
+	selectorPrefix := cfg.SelectorPrefix
 
 	groups, err := gc.Groups(ctx)
 	if err != nil {
@@ -399,7 +567,19 @@ func deleteOrphanGovernorGroups(ctx context.Context, gc *governor.Client, gIDs m
 	deleted := []string{}
 
 	for _, group := range groups {
-		if !strings.HasPrefix(strings.ToLower(group.Name), strings.ToLower(selectorPrefix)) {
+		// the selector prefix is an okta group name prefix
+		oktaName := names.OktaName(group.Name)
+
+		if tmpl != nil {
+			n, err := tmpl.Execute(okta.GroupNameFields{ID: group.ID, Name: oktaName, Slug: group.Slug})
+			if err != nil {
+				return nil, err
+			}
+
+			oktaName = n
+		}
+
+		if !strings.HasPrefix(strings.ToLower(oktaName), strings.ToLower(selectorPrefix)) {
 			l.Debug("skipping delete of non-selected group",
 				zap.String("governor.group.id", group.ID),
 				zap.String("governor.group.name", group.Name),
@@ -427,6 +607,11 @@ func deleteOrphanGovernorGroups(ctx context.Context, gc *governor.Client, gIDs m
 
 					continue
 				}
+
+				pub.Publish(ctx, changes.SystemGovernor, "GovernorGroupDelete", map[string]string{
+					"governor.group.id":   group.ID,
+					"governor.group.slug": group.Slug,
+				})
 			}
 
 			deleted = append(deleted, group.Slug)
@@ -437,6 +622,14 @@ func deleteOrphanGovernorGroups(ctx context.Context, gc *governor.Client, gIDs m
 }
 
 // uniqueGovernorGroupIDs returns a map of unique governor ids from a slice of okta groups
+// governorGroupOrganizationChangeTarget returns the target of a governor group organization link change event
+func governorGroupOrganizationChangeTarget(groupID, orgID string) map[string]string {
+	return map[string]string{
+		"governor.group.id": groupID,
+		"governor.org.id":   orgID,
+	}
+}
+
 func uniqueGovernorGroupIDs(groups []*okt.Group) map[string]struct{} {
 	l := logger.Desugar()
 
@@ -462,12 +655,13 @@ func uniqueGovernorGroupIDs(groups []*okt.Group) map[string]struct{} {
 func updateOktaGroupProfile(
 	ctx context.Context,
 	oc *okta.Client,
+	cfg *config.SyncConfig,
 	gID, groupName, groupDesc string,
 	govGroup *v1alpha1.Group,
 	l *zap.Logger,
 ) (*okt.Group, error) {
-	skipOkta := viper.GetBool("sync.skip-okta-update")
-	dryRun := viper.GetBool("sync.dryrun")
+	skipOkta := cfg.SkipOktaUpdate
+	dryRun := cfg.DryRun
 
 	if skipOkta || dryRun {
 		l.Info("skipping okta update of governor id")
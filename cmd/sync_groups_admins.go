@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"go.uber.org/zap"
+)
+
+// groupAdminsImporter imports the admins of okta groups into the governor groups created from them
+type groupAdminsImporter struct {
+	oc       *okta.Client
+	gc       *governor.Client
+	ac       *govclient.Client
+	pub      *changes.Publisher
+	users    usercache.Store[*v1alpha1.User]
+	matchKey okta.UserMatchKey
+	cfg      config.AdminsConfig
+}
+
+// oktaGroupAdmins returns the okta users administering an okta group, either its owners or the members of its admins
+// counterpart group.  Groups without an admins counterpart have no admins.
+func (i *groupAdminsImporter) oktaGroupAdmins(ctx context.Context, g *okt.Group) ([]*okt.User, error) {
+	if i.cfg.Source == config.AdminsSourceOwners {
+		ids, err := i.oc.ListGroupOwners(ctx, g.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		users := make([]*okt.User, 0, len(ids))
+
+		for _, id := range ids {
+			u, err := i.oc.GetUser(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+
+			users = append(users, u)
+		}
+
+		return users, nil
+	}
+
+	name := g.Profile.Name + i.cfg.GroupSuffix
+
+	groups, err := i.oc.FindGroupsByNamePrefix(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ag := range groups {
+		if ag.Profile != nil && strings.EqualFold(ag.Profile.Name, name) {
+			return i.oc.ListGroupMembership(ctx, ag.Id)
+		}
+	}
+
+	return nil, nil
+}
+
+// importGroupAdmins adds the admins of an okta group as admins of the governor group created from it and returns
+// the number of admins added.  Admins without a governor user are skipped.
+func (i *groupAdminsImporter) importGroupAdmins(ctx context.Context, g *okt.Group, govGroup *v1alpha1.Group, l *zap.Logger) (int, error) {
+	admins, err := i.oktaGroupAdmins(ctx, g)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+
+	for _, admin := range admins {
+		user, err := governorUserFromOktaUser(ctx, i.gc, i.oc, i.users, i.matchKey, admin)
+		if err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				l.Info("okta group admin not found in governor, skipping", zap.String("okta.user.id", admin.Id))
+				continue
+			}
+
+			return added, err
+		}
+
+		lg := l.With(zap.String("governor.user.id", user.ID), zap.String("okta.user.id", admin.Id))
+
+		lg.Info("adding okta group admin as governor group admin")
+
+		if err := i.ac.AddGroupMember(ctx, govGroup.ID, user.ID, true); err != nil {
+			lg.Error("failed to add governor group admin")
+			return added, err
+		}
+
+		i.pub.Publish(ctx, changes.SystemGovernor, "GovernorGroupMemberAdd", map[string]string{
+			"governor.group.id": govGroup.ID,
+			"governor.user.id":  user.ID,
+			"okta.user.id":      admin.Id,
+			"admin":             "true",
+		})
+
+		added++
+	}
+
+	return added, nil
+}
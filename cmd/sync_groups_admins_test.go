@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_groupAdminsImporter_importGroupAdmins(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.AdminsConfig
+		oktaGroup  string
+		wantAdmins []string
+	}{
+		{
+			name:       "owners",
+			cfg:        config.AdminsConfig{Source: config.AdminsSourceOwners},
+			oktaGroup:  "okta-platform",
+			wantAdmins: []string{"user-1"},
+		},
+		{
+			name:       "admins group",
+			cfg:        config.AdminsConfig{Source: config.AdminsSourceGroup, GroupSuffix: config.DefaultAdminsGroupSuffix},
+			oktaGroup:  "okta-platform",
+			wantAdmins: []string{"user-2"},
+		},
+		{
+			name:      "no admins group",
+			cfg:       config.AdminsConfig{Source: config.AdminsSourceGroup, GroupSuffix: config.DefaultAdminsGroupSuffix},
+			oktaGroup: "okta-security",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			for _, name := range []string{"one", "two", "unknown"} {
+				email := name + "@example.com"
+				o.AddUser("okta-"+name, "ACTIVE", map[string]interface{}{"email": email, "login": email})
+			}
+
+			g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-one", Email: "one@example.com"})
+			g.AddUser(&testserver.GovernorUser{ID: "user-2", ExternalID: "okta-two", Email: "two@example.com"})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform"})
+
+			o.AddGroup("okta-platform", "Platform", nil)
+			o.AddGroup("okta-platform-admins", "Platform-admins", nil, "okta-two", "okta-unknown")
+			o.AddGroup("okta-platform-admins-old", "Platform-admins-old", nil, "okta-one")
+			o.AddGroup("okta-security", "Security", nil)
+
+			oc, err := okta.NewClient(
+				okta.WithURL(o.URL),
+				okta.WithToken("okta-token"),
+				okta.WithCache(false),
+				okta.WithHTTPClient(o.Client()),
+			)
+			require.NoError(t, err)
+
+			// okta users without a governor user aren't imported
+			require.NoError(t, oc.AddGroupOwner(context.TODO(), "okta-platform", "okta-one"))
+			require.NoError(t, oc.AddGroupOwner(context.TODO(), "okta-platform", "okta-unknown"))
+
+			gc := newTestGovernorClient(t, g)
+
+			i := &groupAdminsImporter{
+				oc:       oc,
+				gc:       gc,
+				ac:       govclient.New(gc, g.URL, g.Client()),
+				users:    usercache.New[*v1alpha1.User]("test", 0, 0),
+				matchKey: okta.UserMatchKeyEmail,
+				cfg:      tt.cfg,
+			}
+
+			oktaGroup, err := oc.GetGroup(context.TODO(), tt.oktaGroup)
+			require.NoError(t, err)
+
+			govGroup, err := gc.Group(context.TODO(), "group-1", false)
+			require.NoError(t, err)
+
+			added, err := i.importGroupAdmins(context.TODO(), oktaGroup, govGroup, zap.NewNop())
+			require.NoError(t, err)
+
+			assert.Equal(t, len(tt.wantAdmins), added)
+			assert.ElementsMatch(t, tt.wantAdmins, g.Group("group-1").Admins)
+			assert.ElementsMatch(t, tt.wantAdmins, g.Group("group-1").Members)
+		})
+	}
+}
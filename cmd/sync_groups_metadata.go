@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// oktaGroupMetadata returns the okta group description, without the managed group marker, followed by a
+// `key: value` line for each of the selected okta group profile attributes that are set
+func oktaGroupMetadata(g *okt.Group, marker okta.GroupDescriptionMarker, attributes []string) string {
+	if g == nil || g.Profile == nil {
+		return ""
+	}
+
+	lines := []string{}
+
+	if desc := marker.Strip(g.Profile.Description); desc != "" {
+		lines = append(lines, desc)
+	}
+
+	for _, a := range attributes {
+		v, ok := g.Profile.GroupProfileMap[a]
+		if !ok || v == nil {
+			continue
+		}
+
+		s := strings.TrimSpace(fmt.Sprint(v))
+		if s == "" {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%s: %s", a, s))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// mergeGroupMetadata merges the okta metadata into the current governor value using the conflict strategy and
+// returns the new value and whether it changed
+func mergeGroupMetadata(current, metadata, strategy string) (string, bool) {
+	if metadata == "" {
+		return current, false
+	}
+
+	switch strategy {
+	case config.MetadataStrategyReplace:
+		return metadata, current != metadata
+	case config.MetadataStrategyAppend:
+		if strings.Contains(current, metadata) {
+			return current, false
+		}
+
+		if strings.TrimSpace(current) == "" {
+			return metadata, true
+		}
+
+		return current + "\n\n" + metadata, true
+	default:
+		if strings.TrimSpace(current) != "" {
+			return current, false
+		}
+
+		return metadata, true
+	}
+}
+
+// updateGovernorGroupDescription updates the description of a governor group, the governor client doesn't support
+// group updates so the request is made directly. The approver group is sent as is since the update replaces it.
+func updateGovernorGroupDescription(ctx context.Context, hc *http.Client, governorURL string, group *v1alpha1.Group, desc string) error {
+	b, err := json.Marshal(&v1alpha1.GroupReq{
+		Name:            group.Name,
+		Description:     desc,
+		ApproverGroupID: group.ApproverGroup.String,
+	})
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/api/v1alpha1/groups/%s", strings.TrimSuffix(governorURL, "/"), group.ID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("%w: %d", ErrGovernorGroupUpdate, resp.StatusCode)
+	}
+
+	return nil
+}
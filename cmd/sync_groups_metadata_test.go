@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_oktaGroupMetadata(t *testing.T) {
+	group := &okt.Group{
+		Profile: &okt.GroupProfile{
+			Description: " ops on-call rotation ",
+			GroupProfileMap: okt.GroupProfileMap{
+				"owner": "jane@example.com",
+				"team":  "",
+				"tier":  1,
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		group      *okt.Group
+		marker     okta.GroupDescriptionMarker
+		attributes []string
+		want       string
+	}{
+		{
+			name:  "description only",
+			group: group,
+			want:  "ops on-call rotation",
+		},
+		{
+			name:       "selected attributes in order, empty and missing skipped",
+			group:      group,
+			attributes: []string{"tier", "team", "missing", "owner"},
+			want:       "ops on-call rotation\ntier: 1\nowner: jane@example.com",
+		},
+		{
+			name: "managed group marker stripped",
+			group: &okt.Group{
+				Profile: &okt.GroupProfile{Description: "[managed by governor] ops on-call rotation"},
+			},
+			marker: okta.GroupDescriptionMarker{Prefix: "[managed by governor]"},
+			want:   "ops on-call rotation",
+		},
+		{
+			name: "nil profile",
+			group: &okt.Group{
+				Id: "group1",
+			},
+			attributes: []string{"owner"},
+			want:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, oktaGroupMetadata(tt.group, tt.marker, tt.attributes))
+		})
+	}
+}
+
+func Test_mergeGroupMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     string
+		metadata    string
+		strategy    string
+		want        string
+		wantChanged bool
+	}{
+		{
+			name:     "no metadata",
+			current:  "governor",
+			strategy: config.MetadataStrategyReplace,
+			want:     "governor",
+		},
+		{
+			name:     "keep existing",
+			current:  "governor",
+			metadata: "okta",
+			strategy: config.MetadataStrategyKeep,
+			want:     "governor",
+		},
+		{
+			name:        "keep fills empty",
+			metadata:    "okta",
+			strategy:    config.MetadataStrategyKeep,
+			want:        "okta",
+			wantChanged: true,
+		},
+		{
+			name:        "replace",
+			current:     "governor",
+			metadata:    "okta",
+			strategy:    config.MetadataStrategyReplace,
+			want:        "okta",
+			wantChanged: true,
+		},
+		{
+			name:     "replace same",
+			current:  "okta",
+			metadata: "okta",
+			strategy: config.MetadataStrategyReplace,
+			want:     "okta",
+		},
+		{
+			name:        "append",
+			current:     "governor",
+			metadata:    "okta",
+			strategy:    config.MetadataStrategyAppend,
+			want:        "governor\n\nokta",
+			wantChanged: true,
+		},
+		{
+			name:     "append already present",
+			current:  "governor\n\nokta",
+			metadata: "okta",
+			strategy: config.MetadataStrategyAppend,
+			want:     "governor\n\nokta",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := mergeGroupMetadata(tt.current, tt.metadata, tt.strategy)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantChanged, changed)
+		})
+	}
+}
+
+func Test_updateGovernorGroupDescription(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{
+			name:   "success",
+			status: http.StatusAccepted,
+		},
+		{
+			name:    "non success",
+			status:  http.StatusBadRequest,
+			wantErr: ErrGovernorGroupUpdate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got v1alpha1.GroupReq
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPut, r.Method)
+				assert.Equal(t, "/api/v1alpha1/groups/group1", r.URL.Path)
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			group := &v1alpha1.Group{}
+			assert.NoError(t, json.Unmarshal([]byte(`{"id":"group1","name":"Group 1","approver_group":"approvers"}`), group))
+
+			err := updateGovernorGroupDescription(context.TODO(), ts.Client(), ts.URL+"/", group, "okta")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, v1alpha1.GroupReq{Name: "Group 1", Description: "okta", ApproverGroupID: "approvers"}, got)
+		})
+	}
+}
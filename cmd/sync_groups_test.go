@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_governorGroupFromOktaName(t *testing.T) {
+	names := okta.GroupNameMapping{
+		ToGovernor: []okta.GroupNameRule{{Pattern: regexp.MustCompile(`^app-([a-z]+)-([a-z]+)$`), Replace: "$2 $1"}},
+	}
+
+	parse := func(text string) *okta.GroupNameTemplate {
+		tmpl, err := okta.ParseGroupNameTemplate(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tmpl
+	}
+
+	tests := []struct {
+		name     string
+		tmpl     *okta.GroupNameTemplate
+		oktaName string
+		want     okta.GroupNameFields
+	}{
+		{
+			name:     "okta name",
+			oktaName: "Platform Eng",
+			want:     okta.GroupNameFields{Name: "Platform Eng", Slug: "platform-eng"},
+		},
+		{
+			name:     "mapped okta name",
+			oktaName: "app-billing-payments",
+			want:     okta.GroupNameFields{Name: "payments billing", Slug: "payments-billing"},
+		},
+		{
+			name:     "template slug",
+			tmpl:     parse("gov-{{ .Slug }}"),
+			oktaName: "gov-platform-eng",
+			want:     okta.GroupNameFields{Name: "platform-eng", Slug: "platform-eng"},
+		},
+		{
+			name:     "template mapped name",
+			tmpl:     parse("gov-{{ .Name }}"),
+			oktaName: "gov-app-billing-payments",
+			want:     okta.GroupNameFields{Name: "payments billing", Slug: "payments-billing"},
+		},
+		{
+			name:     "template id",
+			tmpl:     parse("{{ .Name }} [{{ .ID }}]"),
+			oktaName: "Platform Eng [ba6ffb77]",
+			want:     okta.GroupNameFields{ID: "ba6ffb77", Name: "Platform Eng", Slug: "platform-eng"},
+		},
+		{
+			name:     "not matching the template",
+			tmpl:     parse("gov-{{ .Slug }}"),
+			oktaName: "Platform Eng",
+			want:     okta.GroupNameFields{Name: "Platform Eng", Slug: "platform-eng"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, governorGroupFromOktaName(names, tt.tmpl, tt.oktaName))
+		})
+	}
+}
@@ -4,17 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/url"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
 	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
 	governor "github.com/metal-toolbox/governor-api/pkg/client"
 	okt "github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
 	"go.uber.org/zap"
-	"golang.org/x/oauth2/clientcredentials"
 )
 
 type memberSummary struct {
@@ -23,7 +27,44 @@ type memberSummary struct {
 	removed []string
 }
 
-var userCache = make(map[string]*v1alpha1.User)
+// membersSyncOptions are the options of the group members sync
+type membersSyncOptions struct {
+	DryRun      bool
+	Concurrency int
+	MatchKey    okta.UserMatchKey
+	// Scope is the matching values of the okta users synced in a partial sync, nil syncs every member
+	Scope map[string]string
+	// UserCacheSize and UserCacheTTL bound the cache of the governor users, a size of 0 disables it
+	UserCacheSize int
+	UserCacheTTL  time.Duration
+}
+
+// membersSyncResult aggregates the group member summaries of all of the synced governor groups
+type membersSyncResult struct {
+	UpdatedGroups int
+	SkippedGroups int
+	SkippedUsers  int
+	AddedUsers    int
+	RemovedUsers  int
+	// FailedGroups are the slugs of the governor groups that failed to sync
+	FailedGroups []string
+}
+
+// add adds the member summary of a synced group to the result, a nil summary is a skipped group
+func (r *membersSyncResult) add(summary *memberSummary) {
+	if summary == nil {
+		r.SkippedGroups++
+		return
+	}
+
+	r.SkippedUsers += len(summary.skipped)
+	r.AddedUsers += len(summary.added)
+	r.RemovedUsers += len(summary.removed)
+
+	if len(summary.added) > 0 || len(summary.removed) > 0 {
+		r.UpdatedGroups++
+	}
+}
 
 // syncMembersCmd syncs okta groups members into governor
 var syncMembersCmd = &cobra.Command{
@@ -36,100 +77,173 @@ must exist in Governor. It is strongly recommended that you use the dry-run flag
 group memberships would be created/deleted in Governor.
 `,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		return syncGroupMembersToGovernor(cmd.Context())
+		cfg, err := loadSyncConfig()
+		if err != nil {
+			return err
+		}
+
+		return syncGroupMembersToGovernor(cmd.Context(), cfg)
 	},
 }
 
 func init() {
 	syncCmd.AddCommand(syncMembersCmd)
+	addSyncOktaUserFlags(syncMembersCmd)
 }
 
-func syncGroupMembersToGovernor(ctx context.Context) error {
+func syncGroupMembersToGovernor(ctx context.Context, cfg *config.Config) error {
 	logger := logger.Desugar()
-	dryRun := viper.GetBool("sync.dryrun")
-
-	logger.Info("starting sync to governor group members", zap.Bool("dry-run", dryRun))
 
-	oc, err := okta.NewClient(
-		okta.WithLogger(logger),
-		okta.WithURL(viper.GetString("okta.url")),
-		okta.WithToken(viper.GetString("okta.token")),
-		okta.WithCache((!viper.GetBool("okta.nocache"))),
-	)
+	oc, closeAudit, err := newSyncOktaClient(logger, cfg)
 	if err != nil {
 		return err
 	}
 
-	gc, err := governor.NewClient(
-		governor.WithLogger(logger),
-		governor.WithURL(viper.GetString("governor.url")),
-		governor.WithClientCredentialConfig(&clientcredentials.Config{
-			ClientID:       viper.GetString("governor.client-id"),
-			ClientSecret:   viper.GetString("governor.client-secret"),
-			TokenURL:       viper.GetString("governor.token-url"),
-			EndpointParams: url.Values{"audience": {viper.GetString("governor.audience")}},
-			Scopes: []string{
-				"write",
-				"read:governor:groups",
-				"read:governor:users",
-			},
-		}),
-	)
+	defer closeAudit()
+
+	gc, err := newSyncGovernorClient(logger, cfg, governorScopes(cfg, "sync-members")...)
 	if err != nil {
 		return err
 	}
 
-	govGroups, err := gc.Groups(ctx)
+	pub, closePub, err := newSyncChangePublisher(logger, cfg)
 	if err != nil {
 		return err
 	}
 
-	logger.Debug("processing list of governor groups", zap.Int("governor.groups.count", len(govGroups)))
+	defer closePub()
 
-	var updatedGroups, skippedGroups, skippedUsers, addedUsers, removedUsers int
+	opts := membersSyncOptions{
+		DryRun:      cfg.Sync.DryRun,
+		Concurrency: cfg.Sync.Concurrency,
+		MatchKey:    okta.UserMatchKey(cfg.Okta.UserMatchKey),
 
-	for _, g := range govGroups {
-		summary, err := syncGroup(ctx, gc, oc, g)
+		UserCacheSize: cfg.Sync.UserCache.Size,
+		UserCacheTTL:  cfg.Sync.UserCache.TTL,
+	}
+
+	if partialUserSync(cfg.Sync) {
+		users, err := oc.ListUsers(ctx, okta.WithUserSearch(cfg.Sync.OktaSearch), okta.WithUserFilter(cfg.Sync.OktaFilter))
 		if err != nil {
 			return err
 		}
 
-		// if the summary is nil but there was no error, group was skipped
-		if summary == nil {
-			skippedGroups++
-			continue
-		}
+		opts.Scope = uniqueMatchValues(users, opts.MatchKey)
 
-		logger.Debug("group membership summary",
-			zap.String("governor.group.id", g.ID),
-			zap.String("governor.group.slug", g.Slug),
-			zap.Any("summary", summary),
+		logger.Info("syncing the group members of the matching okta users",
+			zap.String("okta.search", cfg.Sync.OktaSearch),
+			zap.String("okta.filter", cfg.Sync.OktaFilter),
+			zap.Int("num.okta.users", len(opts.Scope)),
 		)
+	}
 
-		skippedUsers += len(summary.skipped)
-		addedUsers += len(summary.added)
-		removedUsers += len(summary.removed)
+	res, err := syncMembers(ctx, logger, gc, oc, pub, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(res.FailedGroups) > 0 {
+		return fmt.Errorf("%w: %d groups failed", ErrSyncIncomplete, len(res.FailedGroups))
+	}
+
+	return nil
+}
+
+// syncMembers syncs the okta group members of all of the governor groups with a pool of up to the configured
+// concurrency workers.  Groups that fail are logged and counted, the sync goes on with the next group.
+func syncMembers(ctx context.Context, logger *zap.Logger, gc *governor.Client, oc *okta.Client, pub *changes.Publisher, opts membersSyncOptions) (*membersSyncResult, error) {
+	logger.Info("starting sync to governor group members", zap.Bool("dry-run", opts.DryRun), zap.Int("concurrency", opts.Concurrency))
+
+	govGroups, err := gc.Groups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("processing list of governor groups", zap.Int("governor.groups.count", len(govGroups)))
+
+	var (
+		// mu protects the result, which is aggregated by all of the workers
+		mu  sync.Mutex
+		res = &membersSyncResult{FailedGroups: []string{}}
+
+		wg     sync.WaitGroup
+		groups = make(chan *v1alpha1.Group)
+		users  = usercache.New[*v1alpha1.User]("sync-members", opts.UserCacheSize, opts.UserCacheTTL)
+	)
+
+	for range max(opts.Concurrency, 1) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for g := range groups {
+				summary, err := syncGroup(ctx, logger, gc, oc, pub, users, opts, g)
+
+				mu.Lock()
+
+				if err != nil {
+					logger.Error("failed to sync governor group members",
+						zap.String("governor.group.id", g.ID),
+						zap.String("governor.group.slug", g.Slug),
+						zap.Error(err),
+					)
+
+					res.FailedGroups = append(res.FailedGroups, g.Slug)
+				} else {
+					if summary != nil {
+						logger.Debug("group membership summary",
+							zap.String("governor.group.id", g.ID),
+							zap.String("governor.group.slug", g.Slug),
+							zap.Any("summary", summary),
+						)
+					}
+
+					res.add(summary)
+				}
+
+				mu.Unlock()
+			}
+		}()
+	}
 
-		if len(summary.added) > 0 || len(summary.removed) > 0 {
-			updatedGroups++
+dispatch:
+	for _, g := range govGroups {
+		select {
+		case groups <- g:
+		case <-ctx.Done():
+			break dispatch
 		}
 	}
 
+	close(groups)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(res.FailedGroups)
+
 	logger.Info("completed group membership sync",
-		zap.Int("governor.groups.updated", updatedGroups),
-		zap.Int("governor.groups.skipped", skippedGroups),
-		zap.Int("governor.users.added", addedUsers),
-		zap.Int("governor.users.removed", removedUsers),
-		zap.Int("governor.users.skipped", skippedUsers),
+		zap.Int("governor.groups.updated", res.UpdatedGroups),
+		zap.Int("governor.groups.skipped", res.SkippedGroups),
+		zap.Int("governor.groups.failed", len(res.FailedGroups)),
+		zap.Strings("governor.groups.failed.slugs", res.FailedGroups),
+		zap.Int("governor.users.added", res.AddedUsers),
+		zap.Int("governor.users.removed", res.RemovedUsers),
+		zap.Int("governor.users.skipped", res.SkippedUsers),
 	)
 
-	return nil
+	return res, nil
 }
 
-func syncGroup(ctx context.Context, gc *governor.Client, oc *okta.Client, g *v1alpha1.Group) (*memberSummary, error) {
-	dryRun := viper.GetBool("sync.dryrun")
+// syncGroup syncs the okta group members into the governor group, only the members of the okta users in the scope
+// are added or removed when it isn't nil
+func syncGroup(ctx context.Context, logger *zap.Logger, gc *governor.Client, oc *okta.Client, pub *changes.Publisher, users usercache.Store[*v1alpha1.User], opts membersSyncOptions, g *v1alpha1.Group) (*memberSummary, error) {
+	dryRun, matchKey, scope := opts.DryRun, opts.MatchKey, opts.Scope
 
-	l := logger.Desugar().With(
+	l := logger.With(
 		zap.String("governor.group.id", g.ID),
 		zap.String("governor.group.slug", g.Slug),
 	)
@@ -140,7 +254,7 @@ func syncGroup(ctx context.Context, gc *governor.Client, oc *okta.Client, g *v1a
 		return nil, err
 	}
 
-	l.Debug("got governor group details", zap.Any("governor.group", govGroup))
+	l.Debug("got governor group details", redact.Any("governor.group", govGroup))
 
 	// get the okta group from the governor id
 	oktaGroupID, err := oc.GetGroupByGovernorID(ctx, govGroup.ID)
@@ -165,7 +279,7 @@ func syncGroup(ctx context.Context, gc *governor.Client, oc *okta.Client, g *v1a
 		return nil, err
 	}
 
-	l.Debug("got okta group membership", zap.Any("okta.group.members", oktaGroupMembership))
+	l.Debug("got okta group membership", redact.Any("okta.group.members", oktaGroupMembership))
 
 	expectedMembers := []string{}
 	skipped := []string{}
@@ -173,7 +287,15 @@ func syncGroup(ctx context.Context, gc *governor.Client, oc *okta.Client, g *v1a
 	removed := []string{}
 
 	for _, member := range oktaGroupMembership {
-		user, err := governorUserFromOktaUser(ctx, gc, member, l)
+		if scope != nil {
+			value, err := okta.UserMatchValue(member, matchKey)
+			if err != nil || !inSyncScope(scope, value) {
+				l.Debug("okta group member isn't part of the partial sync, skipping", zap.String("okta.user.id", member.Id))
+				continue
+			}
+		}
+
+		user, err := governorUserFromOktaUser(ctx, gc, oc, users, matchKey, member)
 		if err != nil {
 			if errors.Is(err, ErrUserNotFound) {
 				l.Info("user not found in governor, skipping",
@@ -206,6 +328,12 @@ func syncGroup(ctx context.Context, gc *governor.Client, oc *okta.Client, g *v1a
 					lg.Error("failed to add group member")
 					return nil, err
 				}
+
+				pub.Publish(ctx, changes.SystemGovernor, "GovernorGroupMemberAdd", map[string]string{
+					"governor.group.id": govGroup.ID,
+					"governor.user.id":  user.ID,
+					"okta.user.id":      member.Id,
+				})
 			}
 
 			added = append(added, member.Id)
@@ -224,6 +352,10 @@ func syncGroup(ctx context.Context, gc *governor.Client, oc *okta.Client, g *v1a
 				continue
 			}
 
+			if !inSyncScope(scope, governorUserMatchValue(matchKey, user.Email, user.ExternalID.String)) {
+				continue
+			}
+
 			l.Info("pruning user from governor group",
 				zap.String("goveror.user.id", m),
 			)
@@ -236,6 +368,11 @@ func syncGroup(ctx context.Context, gc *governor.Client, oc *okta.Client, g *v1a
 
 					return nil, err
 				}
+
+				pub.Publish(ctx, changes.SystemGovernor, "GovernorGroupMemberRemove", map[string]string{
+					"governor.group.id": govGroup.ID,
+					"governor.user.id":  m,
+				})
 			}
 
 			removed = append(removed, m)
@@ -249,32 +386,89 @@ func syncGroup(ctx context.Context, gc *governor.Client, oc *okta.Client, g *v1a
 	}, nil
 }
 
-func governorUserFromOktaUser(ctx context.Context, gc *governor.Client, oktaUser *okt.User, _ *zap.Logger) (*v1alpha1.User, error) {
-	email, err := okta.EmailFromUserProfile(oktaUser)
+// governorUserFromOktaUser returns the governor user matching an okta user from the cache, or ErrUserNotFound.  Users
+// that aren't found by the matching key are looked up by the match aliases of the okta user (ie. the secondEmail), a
+// governor user found by an alias only matches when it isn't linked to another okta user, by its external id or by
+// its email being the matching key of another okta user.  Users that aren't found are cached as nil so that they're
+// only queried once.
+func governorUserFromOktaUser(ctx context.Context, gc *governor.Client, oc *okta.Client, users usercache.Store[*v1alpha1.User], matchKey okta.UserMatchKey, oktaUser *okt.User) (*v1alpha1.User, error) {
+	value, err := okta.UserMatchValue(oktaUser, matchKey)
 	if err != nil {
 		return nil, err
 	}
 
-	// get the governor user
-	user, ok := userCache[email]
-	if !ok {
-		u, err := gc.UsersQuery(ctx, map[string][]string{"email": {email}})
+	user, err := governorUserByMatchValue(ctx, gc, users, matchKey, value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, alias := range okta.UserMatchAliases(oktaUser, matchKey) {
+		if user != nil {
+			break
+		}
+
+		user, err = governorUserByMatchValue(ctx, gc, users, matchKey, alias)
 		if err != nil {
 			return nil, err
 		}
 
-		count := len(u)
+		if user == nil {
+			continue
+		}
 
-		switch {
-		case count == 0:
-			return nil, ErrUserNotFound
-		case count > 1:
-			return nil, fmt.Errorf("unexpected user count: %d expected 1", count) //nolint:goerr113
+		if user.ExternalID.String != "" && user.ExternalID.String != oktaUser.Id {
+			user = nil
+			continue
+		}
+
+		claimed, err := claimedByOtherOktaUser(ctx, oc, matchKey, user.Email, oktaUser.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		if claimed {
+			user = nil
 		}
+	}
 
-		userCache[email] = u[0]
-		user = u[0]
+	if user == nil {
+		return nil, ErrUserNotFound
 	}
 
 	return user, nil
 }
+
+// claimedByOtherOktaUser returns true when the governor user email is the matching key of an okta user other than
+// the given one, that okta user owns the governor user and an alias of another okta user can't take it
+func claimedByOtherOktaUser(ctx context.Context, oc *okta.Client, matchKey okta.UserMatchKey, email, oktaID string) (bool, error) {
+	uid, err := oc.GetUserIDBy(ctx, matchKey, email)
+	if err != nil {
+		// no okta user (or several) has the email as its matching key
+		if errors.Is(err, okta.ErrUnexpectedUsersCount) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return uid != oktaID, nil
+}
+
+// governorUserByMatchValue returns the governor user with the matching value from the cache, nil when there's none
+func governorUserByMatchValue(ctx context.Context, gc *governor.Client, users usercache.Store[*v1alpha1.User], matchKey okta.UserMatchKey, value string) (*v1alpha1.User, error) {
+	return users.GetOrLoad(ctx, value, func(ctx context.Context) (*v1alpha1.User, error) {
+		u, err := gc.UsersQuery(ctx, governorUserQuery(matchKey, value))
+		if err != nil {
+			return nil, err
+		}
+
+		switch count := len(u); {
+		case count == 0:
+			return nil, nil
+		case count > 1:
+			return nil, fmt.Errorf("unexpected user count: %d expected 1", count) //nolint:goerr113
+		}
+
+		return u[0], nil
+	})
+}
@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+func Test_syncMembers(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         membersSyncOptions
+		want         membersSyncResult
+		wantPlatform []string
+		wantSecurity []string
+	}{
+		{
+			name: "concurrent",
+			opts: membersSyncOptions{Concurrency: 4, MatchKey: okta.UserMatchKeyEmail, UserCacheSize: 100, UserCacheTTL: time.Minute},
+			want: membersSyncResult{
+				UpdatedGroups: 2,
+				SkippedGroups: 1,
+				SkippedUsers:  2,
+				AddedUsers:    2,
+				RemovedUsers:  1,
+				FailedGroups:  []string{"broken"},
+			},
+			wantPlatform: []string{"user-1", "user-3"},
+			wantSecurity: []string{"user-1", "user-3"},
+		},
+		{
+			name: "dry run",
+			opts: membersSyncOptions{DryRun: true, Concurrency: 1, MatchKey: okta.UserMatchKeyEmail},
+			want: membersSyncResult{
+				UpdatedGroups: 2,
+				SkippedGroups: 1,
+				SkippedUsers:  2,
+				AddedUsers:    2,
+				RemovedUsers:  1,
+				FailedGroups:  []string{"broken"},
+			},
+			wantPlatform: []string{"user-1", "user-2"},
+			wantSecurity: []string{"user-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			for _, id := range []string{"1", "2", "3"} {
+				email := "user-" + id + "@example.com"
+
+				g.AddUser(&testserver.GovernorUser{ID: "user-" + id, ExternalID: "okta-" + id, Email: email, Status: v1alpha1.UserStatusActive})
+				o.AddUser("okta-"+id, "ACTIVE", map[string]interface{}{"email": email, "login": email})
+			}
+
+			o.AddUser("okta-4", "ACTIVE", map[string]interface{}{"email": "user-4@example.com", "login": "user-4@example.com"})
+
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1", "user-2"}})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-2", Name: "Security", Slug: "security", Members: []string{"user-1"}})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-3", Name: "Storage", Slug: "storage"})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-4", Name: "Broken", Slug: "broken"})
+
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-1", "okta-3", "okta-4")
+			o.AddGroup("00g-security", "Security", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-2"}, "okta-1", "okta-3", "okta-4")
+			o.AddGroup("00g-broken", "Broken", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-4"}, "okta-1")
+
+			o.FailRequests(func(req testserver.Request) int {
+				if req.Method == http.MethodGet && strings.HasPrefix(req.Path, "/api/v1/groups/00g-broken/users") {
+					return http.StatusInternalServerError
+				}
+
+				return 0
+			})
+
+			oc, err := okta.NewClient(
+				okta.WithURL(o.URL),
+				okta.WithToken("okta-token"),
+				okta.WithCache(false),
+				okta.WithHTTPClient(o.Client()),
+				okta.WithPageRetries(0, 0),
+			)
+			require.NoError(t, err)
+
+			gc := newTestGovernorClient(t, g)
+
+			got, err := syncMembers(context.TODO(), zap.NewNop(), gc, oc, nil, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+
+			assert.ElementsMatch(t, tt.wantPlatform, g.Group("group-1").Members)
+			assert.ElementsMatch(t, tt.wantSecurity, g.Group("group-2").Members)
+		})
+	}
+}
+
+func Test_syncMembersAliasCollision(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	// the governor user isn't linked by an external id, but its email is the primary email of okta-1
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform"})
+
+	o.AddUser("okta-1", "ACTIVE", map[string]interface{}{"email": "user-1@example.com", "login": "user-1@example.com"})
+	o.AddUser("okta-2", "ACTIVE", map[string]interface{}{
+		"email":       "user-2@example.com",
+		"secondEmail": "user-1@example.com",
+		"login":       "user-2@example.com",
+	})
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-2")
+
+	got, err := syncMembers(context.TODO(), zap.NewNop(), newTestGovernorClient(t, g), newTestOktaClient(t, o), nil,
+		membersSyncOptions{Concurrency: 1, MatchKey: okta.UserMatchKeyEmail})
+	require.NoError(t, err)
+	assert.Equal(t, membersSyncResult{SkippedUsers: 1, FailedGroups: []string{}}, *got)
+
+	assert.Empty(t, g.Group("group-1").Members)
+}
+
+func Test_governorUserFromOktaUser(t *testing.T) {
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	gc := newTestGovernorClient(t, g)
+	oc := newTestOktaClient(t, o)
+	users := usercache.New[*v1alpha1.User]("test", 10, time.Minute)
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			u, err := governorUserFromOktaUser(context.TODO(), gc, oc, users, okta.UserMatchKeyEmail, &okt.User{Id: "okta-1", Profile: &okt.UserProfile{"email": "user-1@example.com"}})
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", u.ID)
+
+			_, err = governorUserFromOktaUser(context.TODO(), gc, oc, users, okta.UserMatchKeyEmail, &okt.User{Id: "okta-2", Profile: &okt.UserProfile{"email": "user-2@example.com"}})
+			assert.ErrorIs(t, err, ErrUserNotFound)
+		}()
+	}
+
+	wg.Wait()
+
+	queries := 0
+
+	for _, req := range g.Requests() {
+		if req.Path == "/api/v1alpha1/users" {
+			queries++
+		}
+	}
+
+	assert.Equal(t, 2, queries)
+}
+
+func Test_governorUserFromOktaUserAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile okt.UserProfile
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "primary email wins over the secondary email",
+			profile: okt.UserProfile{"email": "alice@example.com", "secondEmail": "bob@example.com", "login": "alice"},
+			want:    "user-1",
+		},
+		{
+			name:    "secondary email",
+			profile: okt.UserProfile{"email": "alice@new.example.com", "secondEmail": "alice@example.com", "login": "alice"},
+			want:    "user-1",
+		},
+		{
+			name:    "login after the secondary email",
+			profile: okt.UserProfile{"email": "alice@new.example.com", "secondEmail": nil, "login": "alice@example.com"},
+			want:    "user-1",
+		},
+		{
+			name:    "alias of the governor user of another okta user",
+			profile: okt.UserProfile{"email": "robert@example.com", "secondEmail": "bob@example.com", "login": "robert"},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name:    "alias of the primary email of another okta user",
+			profile: okt.UserProfile{"email": "carol@new.example.com", "secondEmail": "carol@example.com", "login": "carol"},
+			wantErr: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			g.AddUser(&testserver.GovernorUser{ID: "user-1", Email: "alice@example.com", Status: v1alpha1.UserStatusActive})
+			g.AddUser(&testserver.GovernorUser{ID: "user-2", ExternalID: "okta-bob", Email: "bob@example.com", Status: v1alpha1.UserStatusActive})
+			g.AddUser(&testserver.GovernorUser{ID: "user-3", Email: "carol@example.com", Status: v1alpha1.UserStatusActive})
+
+			// carol's governor user has no external id, but her okta user owns it by its primary email
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			o.AddUser("okta-carol", "ACTIVE", map[string]interface{}{"email": "carol@example.com", "login": "carol"})
+
+			users := usercache.New[*v1alpha1.User]("test", 10, time.Minute)
+
+			u, err := governorUserFromOktaUser(context.TODO(), newTestGovernorClient(t, g), newTestOktaClient(t, o), users, okta.UserMatchKeyEmail, &okt.User{Id: "okta-1", Profile: &tt.profile})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, u.ID)
+		})
+	}
+}
+
+// newTestOktaClient returns an okta client of the okta test server
+func newTestOktaClient(t *testing.T, o *testserver.Okta) *okta.Client {
+	t.Helper()
+
+	oc, err := okta.NewClient(
+		okta.WithURL(o.URL),
+		okta.WithToken("okta-token"),
+		okta.WithCache(false),
+		okta.WithHTTPClient(o.Client()),
+		okta.WithPageRetries(0, 0),
+	)
+	require.NoError(t, err)
+
+	return oc
+}
+
+// newTestGovernorClient returns a governor client of the governor test server
+func newTestGovernorClient(t *testing.T, g *testserver.Governor) *governor.Client {
+	t.Helper()
+
+	gc, err := governor.NewClient(
+		governor.WithURL(g.URL),
+		governor.WithClientCredentialConfig(&clientcredentials.Config{
+			ClientID:     "gov-okta-addon",
+			ClientSecret: "secret",
+			TokenURL:     g.TokenURL(),
+		}),
+		governor.WithHTTPClient(g.Client()),
+	)
+	require.NoError(t, err)
+
+	return gc
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gosimple/slug"
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// syncOrgsCmd syncs okta githubcloud applications into governor organizations
+var syncOrgsCmd = &cobra.Command{
+	Use:   "orgs",
+	Short: "sync okta githubcloud applications into governor organizations",
+	Long: `Performs a one-way organization sync from Okta to Governor.
+Github organizations of Okta githubcloud applications that don't exist in Governor, will be created. Organizations that
+exist in Governor but have no githubcloud application in Okta are reported, but not deleted.
+This command is intended for bootstrapping the Governor organizations before syncing groups.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, err := loadSyncConfig()
+		if err != nil {
+			return err
+		}
+
+		return syncOrgsToGovernor(cmd.Context(), cfg)
+	},
+}
+
+func init() {
+	syncCmd.AddCommand(syncOrgsCmd)
+}
+
+// syncOrgsToGovernor creates the governor organizations of the okta githubcloud applications
+func syncOrgsToGovernor(ctx context.Context, cfg *config.Config) error {
+	logger := logger.Desugar()
+	dryRun := cfg.Sync.DryRun
+
+	logger.Info("starting sync to governor organizations", zap.Bool("dry-run", dryRun))
+
+	oc, closeAudit, err := newSyncOktaClient(logger, cfg)
+	if err != nil {
+		return err
+	}
+
+	defer closeAudit()
+
+	gc, err := newSyncGovernorAPIClient(logger, cfg, governorScopes(cfg, "sync-orgs")...)
+	if err != nil {
+		return err
+	}
+
+	pub, closePub, err := newSyncChangePublisher(logger, cfg)
+	if err != nil {
+		return err
+	}
+
+	defer closePub()
+
+	apps, err := oc.GithubCloudApplications(ctx)
+	if err != nil {
+		return err
+	}
+
+	govOrgs, err := gc.Organizations(ctx)
+	if err != nil {
+		return err
+	}
+
+	missing, orphaned := orgsDiff(apps, govOrgs)
+
+	var created, skipped int
+
+	for _, name := range missing {
+		l := logger.With(zap.String("okta.application.org", name), zap.String("okta.application.id", apps[name]))
+
+		// governor slugifies the organization name, the github orgs are matched on the governor slug
+		if slug.Make(name) != name {
+			l.Warn("okta application org isn't a valid governor organization slug, skipping")
+
+			skipped++
+
+			continue
+		}
+
+		l.Info("organization not found in governor, creating")
+
+		if !dryRun {
+			org, err := gc.CreateOrganization(ctx, &v1alpha1.OrganizationReq{Name: name})
+			if err != nil {
+				return err
+			}
+
+			l.Debug("created governor organization from okta sync", zap.String("governor.org.id", org.ID))
+
+			pub.Publish(ctx, changes.SystemGovernor, "GovernorOrganizationCreate", map[string]string{
+				"governor.org.id":     org.ID,
+				"governor.org.slug":   org.Slug,
+				"okta.application.id": apps[name],
+			})
+		}
+
+		created++
+	}
+
+	for _, org := range orphaned {
+		logger.Warn("governor organization has no githubcloud application in okta",
+			zap.String("governor.org.id", org.ID),
+			zap.String("governor.org.slug", org.Slug),
+		)
+	}
+
+	logger.Info("completed organization sync",
+		zap.Int("governor.orgs.created", created),
+		zap.Int("governor.orgs.skipped", skipped),
+		zap.Int("governor.orgs.orphaned", len(orphaned)),
+	)
+
+	return nil
+}
+
+// orgsDiff returns the sorted github orgs of the okta applications that don't have a governor organization and
+// the governor organizations without an okta application
+func orgsDiff(apps map[string]string, orgs []*v1alpha1.Organization) ([]string, []*v1alpha1.Organization) {
+	slugs := make(map[string]bool, len(orgs))
+	orphaned := []*v1alpha1.Organization{}
+
+	for _, org := range orgs {
+		slugs[org.Slug] = true
+
+		if _, ok := apps[org.Slug]; !ok {
+			orphaned = append(orphaned, org)
+		}
+	}
+
+	missing := []string{}
+
+	for name := range apps {
+		if !slugs[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing, orphaned
+}
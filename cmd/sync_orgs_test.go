@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_orgsDiff(t *testing.T) {
+	orgs := []*v1alpha1.Organization{}
+	if err := json.Unmarshal([]byte(`[{"id":"org-1","slug":"metal-toolbox"},{"id":"org-2","slug":"archived"}]`), &orgs); err != nil {
+		t.Fatal(err)
+	}
+
+	apps := map[string]string{
+		"metal-toolbox": "app-1",
+		"zeta":          "app-3",
+		"equinix-labs":  "app-2",
+	}
+
+	missing, orphaned := orgsDiff(apps, orgs)
+
+	assert.Equal(t, []string{"equinix-labs", "zeta"}, missing)
+
+	if assert.Len(t, orphaned, 1) {
+		assert.Equal(t, "org-2", orphaned[0].ID)
+	}
+
+	missing, orphaned = orgsDiff(map[string]string{}, nil)
+	assert.Empty(t, missing)
+	assert.Empty(t, orphaned)
+}
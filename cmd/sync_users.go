@@ -3,17 +3,19 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"net/url"
+	"sync/atomic"
 
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
 	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
 	governor "github.com/metal-toolbox/governor-api/pkg/client"
 	okt "github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
-	"golang.org/x/oauth2/clientcredentials"
 )
 
 // syncUsersCmd syncs okta users into governor
@@ -25,55 +27,106 @@ Users that exist in Okta but not in Governor, will be created. Users that exist
 This command is intended for doing an initial load of users. It is strongly recommended that you use the dry-run flag first 
 to see what users would be created/deleted in Governor.`,
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		return syncUsersToGovernor(cmd.Context())
+		cfg, err := loadSyncConfig()
+		if err != nil {
+			return err
+		}
+
+		return syncUsersToGovernor(cmd.Context(), cfg)
 	},
 }
 
 func init() {
 	syncCmd.AddCommand(syncUsersCmd)
+	addSyncOktaUserFlags(syncUsersCmd)
+
+	syncUsersCmd.Flags().Bool("include-suspended", false, "create suspended okta users as suspended governor users instead of skipping them")
+	viperBindFlag("sync.users.include-suspended", syncUsersCmd.Flags().Lookup("include-suspended"))
+}
+
+// syncUserStatus returns the governor status of an okta user status and false when the okta user isn't created
+// in governor.  Deprovisioned users are never created and suspended users are only created (as suspended) when
+// they're included, every other okta status is active.
+func syncUserStatus(oktaStatus string, includeSuspended bool) (string, bool) {
+	switch oktaStatus {
+	case oktaUserStatusDeprovisioned:
+		return "", false
+	case oktaUserStatusSuspended:
+		return v1alpha1.UserStatusSuspended, includeSuspended
+	default:
+		return v1alpha1.UserStatusActive, true
+	}
 }
 
 // syncUsersToGovernor syncs users from okta to governor
-func syncUsersToGovernor(ctx context.Context) error {
+func syncUsersToGovernor(ctx context.Context, cfg *config.Config) error {
 	logger := logger.Desugar()
-	dryRun := viper.GetBool("sync.dryrun")
+	dryRun := cfg.Sync.DryRun
+	matchKey := okta.UserMatchKey(cfg.Okta.UserMatchKey)
+	nonHuman := cfg.Okta.NonHumanRules()
+	includeSuspended := cfg.Sync.Users.IncludeSuspended
+
+	logger.Info("starting sync to governor users",
+		zap.Bool("dry-run", dryRun),
+		zap.String("user.match_key", string(matchKey)),
+		zap.Bool("include-suspended", includeSuspended),
+		zap.String("okta.search", cfg.Sync.OktaSearch),
+		zap.String("okta.filter", cfg.Sync.OktaFilter),
+	)
 
-	logger.Info("starting sync to governor users", zap.Bool("dry-run", dryRun))
+	oc, closeAudit, err := newSyncOktaClient(logger, cfg)
+	if err != nil {
+		return err
+	}
 
-	oc, err := okta.NewClient(
-		okta.WithLogger(logger),
-		okta.WithURL(viper.GetString("okta.url")),
-		okta.WithToken(viper.GetString("okta.token")),
-		okta.WithCache((!viper.GetBool("okta.nocache"))),
-	)
+	defer closeAudit()
+
+	gc, err := newSyncGovernorClient(logger, cfg, governorScopes(cfg, "sync-users")...)
 	if err != nil {
 		return err
 	}
 
-	gc, err := governor.NewClient(
-		governor.WithLogger(logger),
-		governor.WithURL(viper.GetString("governor.url")),
-		governor.WithClientCredentialConfig(&clientcredentials.Config{
-			ClientID:       viper.GetString("governor.client-id"),
-			ClientSecret:   viper.GetString("governor.client-secret"),
-			TokenURL:       viper.GetString("governor.token-url"),
-			EndpointParams: url.Values{"audience": {viper.GetString("governor.audience")}},
-			Scopes: []string{
-				"write",
-				"read:governor:users",
-			},
-		}),
-	)
+	pub, closePub, err := newSyncChangePublisher(logger, cfg)
 	if err != nil {
 		return err
 	}
 
-	created, skipped, updated := 0, 0, 0
+	defer closePub()
+
+	// counters are atomic since the modifier can run concurrently
+	var created, skipped, updated atomic.Int64
+
+	// govUsers caches the governor users of a matching value, ie. for okta users sharing an email
+	govUsers := usercache.New[[]*v1alpha1.User]("sync-users", cfg.Sync.UserCache.Size, cfg.Sync.UserCache.TTL)
 
 	// modifier function to get okta users that don't exist in governor and create them
 	syncFunc := func(ctx context.Context, u *okt.User) (*okt.User, error) {
 		logger.Debug("processing okta user", zap.String("okta.user.id", u.Id))
 
+		// non-human accounts are kept in the list of okta users so their existing governor users aren't deleted
+		if rule, ok := nonHuman.Match(u); ok {
+			logger.Debug("skipping non-human okta account", zap.String("okta.user.id", u.Id), zap.Stringer("rule", rule))
+
+			skipped.Add(1)
+
+			return u, nil
+		}
+
+		status, ok := syncUserStatus(u.Status, includeSuspended)
+		if !ok {
+			logger.Debug("skipping okta user with a status that isn't synced", zap.String("okta.user.id", u.Id), zap.String("okta.user.status", u.Status))
+
+			skipped.Add(1)
+
+			// deprovisioned users are gone from okta, so they're left out of the list and their governor users
+			// are deleted, but skipped suspended users still exist in okta
+			if u.Status == oktaUserStatusDeprovisioned {
+				return nil, nil
+			}
+
+			return u, nil
+		}
+
 		email, err := okta.EmailFromUserProfile(u)
 		if err != nil {
 			return nil, err
@@ -92,16 +145,24 @@ func syncUsersToGovernor(ctx context.Context) error {
 		// the external id in governor is simply the okta id
 		extID := u.Id
 
+		matchValue, err := okta.UserMatchValue(u, matchKey)
+		if err != nil {
+			return nil, err
+		}
+
 		// check if user exists in governor
-		gUsers, err := gc.UsersQuery(ctx, map[string][]string{"email": {email}})
+		gUsers, err := govUsers.GetOrLoad(ctx, matchValue, func(ctx context.Context) ([]*v1alpha1.User, error) {
+			return gc.UsersQuery(ctx, governorUserQuery(matchKey, matchValue))
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		logger.Debug("got governor users response for email", zap.Any("governor.users", gUsers))
+		logger.Debug("got governor users response for matching value", zap.String("user.match_value", matchValue), redact.Any("governor.users", gUsers))
 
 		if len(gUsers) > 1 {
-			logger.Warn("unexpected user count for email",
+			logger.Warn("unexpected user count for matching value",
+				zap.String("user.match_value", matchValue),
 				zap.String("okta.user.email", email),
 				zap.String("okta.user.id", u.Id),
 				zap.Int("num.governor.users", len(gUsers)),
@@ -122,9 +183,10 @@ func syncUsersToGovernor(ctx context.Context) error {
 				return u, nil
 			}
 
-			logger.Info("user exists in governor and is marked pending, marking active",
+			logger.Info("user exists in governor and is marked pending, updating status",
 				zap.String("okta.user.id", u.Id),
 				zap.String("okta.user.email", email),
+				zap.String("governor.user.status", status),
 			)
 
 			if !dryRun {
@@ -133,20 +195,24 @@ func syncUsersToGovernor(ctx context.Context) error {
 						Email:      email,
 						ExternalID: extID,
 						Name:       fmt.Sprintf("%s %s", first, last),
-						Status:     v1alpha1.UserStatusActive,
+						Status:     status,
 					})
 				if err != nil {
 					return nil, err
 				}
 
+				govUsers.Delete(matchValue)
+
 				l.Debug("updated governor user from okta sync",
 					zap.String("governor.user.id", gUser.ID),
 					zap.String("okta.user.id", u.Id),
 					zap.String("okta.user.email", email),
 				)
+
+				pub.Publish(ctx, changes.SystemGovernor, "GovernorUserUpdate", governorUserChangeTarget(gUser.ID, email, u.Id))
 			}
 
-			updated++
+			updated.Add(1)
 
 			return u, nil
 		}
@@ -154,6 +220,7 @@ func syncUsersToGovernor(ctx context.Context) error {
 		logger.Info("user not found in governor, creating",
 			zap.String("okta.user.id", u.Id),
 			zap.String("okta.user.email", email),
+			zap.String("governor.user.status", status),
 		)
 
 		if !dryRun {
@@ -161,49 +228,60 @@ func syncUsersToGovernor(ctx context.Context) error {
 				Email:      email,
 				ExternalID: extID,
 				Name:       fmt.Sprintf("%s %s", first, last),
-				Status:     v1alpha1.UserStatusActive,
+				Status:     status,
 			})
 			if err != nil {
 				return nil, err
 			}
 
+			govUsers.Delete(matchValue)
+
 			logger.Debug("created governor user from okta sync",
 				zap.String("governor.user.id", gUser.ID),
 				zap.String("okta.user.id", u.Id),
 				zap.String("okta.user.email", email),
 			)
+
+			pub.Publish(ctx, changes.SystemGovernor, "GovernorUserCreate", governorUserChangeTarget(gUser.ID, email, u.Id))
 		}
 
-		created++
+		created.Add(1)
 
 		return u, nil
 	}
 
 	logger.Info("starting to sync missing okta users into governor", zap.Bool("dry-run", dryRun))
 
-	users, err := oc.ListUsersWithModifier(ctx, syncFunc, &query.Params{})
+	users, err := oc.ListUsersWithModifier(ctx, syncFunc, &query.Params{Search: cfg.Sync.OktaSearch, Filter: cfg.Sync.OktaFilter})
 	if err != nil {
 		return err
 	}
 
-	deleted, err := deleteOrphanGovernorUsers(ctx, gc, uniqueEmails(users))
-	if err != nil {
-		return err
+	var deleted int
+
+	// the governor users of okta users outside of a partial sync would look like orphans, so they're left alone
+	if partialUserSync(cfg.Sync) {
+		logger.Info("skipping the clean up of orphan governor users in a partial user sync")
+	} else {
+		deleted, err = deleteOrphanGovernorUsers(ctx, gc, pub, dryRun, matchKey, uniqueMatchValues(users, matchKey))
+		if err != nil {
+			return err
+		}
 	}
 
 	logger.Info("completed user sync",
-		zap.Int("governor.users.created", created),
+		zap.Int64("governor.users.created", created.Load()),
 		zap.Int("governor.users.deleted", deleted),
-		zap.Int("governor.users.skipped", skipped),
-		zap.Int("governor.users.updated", updated),
+		zap.Int64("governor.users.skipped", skipped.Load()),
+		zap.Int64("governor.users.updated", updated.Load()),
 	)
 
 	return nil
 }
 
-// deleteOrphanGovernorUsers is a helper function to delete governor users that not longer exist in okta
-func deleteOrphanGovernorUsers(ctx context.Context, gc *governor.Client, emailIDMap map[string]string) (int, error) {
-	dryRun := viper.GetBool("sync.dryrun")
+// deleteOrphanGovernorUsers is a helper function to delete governor users that not longer exist in okta, the
+// okta users are mapped by their matching value
+func deleteOrphanGovernorUsers(ctx context.Context, gc *governor.Client, pub *changes.Publisher, dryRun bool, matchKey okta.UserMatchKey, matchIDMap map[string]string) (int, error) {
 	l := logger.Desugar()
 
 	l.Info("starting to clean orphan governor users", zap.Bool("dry-run", dryRun))
@@ -217,7 +295,7 @@ func deleteOrphanGovernorUsers(ctx context.Context, gc *governor.Client, emailID
 
 	l.Debug("got list of governor users to compare to okta users",
 		zap.Int("num.governor.users", len(govUsers)),
-		zap.Int("num.okta.users", len(emailIDMap)),
+		zap.Int("num.okta.users", len(matchIDMap)),
 	)
 
 	for _, gu := range govUsers {
@@ -229,17 +307,18 @@ func deleteOrphanGovernorUsers(ctx context.Context, gc *governor.Client, emailID
 			continue
 		}
 
-		govEmail := gu.Email
-		if govEmail == "" {
-			l.Warn("governor user is missing email, won't delete",
+		govValue := governorUserMatchValue(matchKey, gu.Email, gu.ExternalID.String)
+		if govValue == "" {
+			l.Warn("governor user is missing matching value, won't delete",
 				zap.String("governor.user.id", gu.ID),
 				zap.String("governor.user.email", gu.Email),
+				zap.String("user.match_key", string(matchKey)),
 			)
 
 			continue
 		}
 
-		if id, ok := emailIDMap[govEmail]; ok {
+		if id, ok := matchIDMap[govValue]; ok {
 			l.Debug("governor user exists in okta, continuing",
 				zap.String("governor.user.id", gu.ID),
 				zap.String("okta.user.id", id),
@@ -258,6 +337,8 @@ func deleteOrphanGovernorUsers(ctx context.Context, gc *governor.Client, emailID
 			if err := gc.DeleteUser(ctx, gu.ID); err != nil {
 				return deleted, err
 			}
+
+			pub.Publish(ctx, changes.SystemGovernor, "GovernorUserDelete", governorUserChangeTarget(gu.ID, gu.Email, gu.ExternalID.String))
 		}
 
 		deleted++
@@ -266,20 +347,21 @@ func deleteOrphanGovernorUsers(ctx context.Context, gc *governor.Client, emailID
 	return deleted, nil
 }
 
-// uniqueExternalIDs builds a map of unique emails from a list of okta users
-func uniqueEmails(users []*okt.User) map[string]string {
+// uniqueMatchValues builds a map of unique matching values (ie. emails) to ids from a list of okta users
+func uniqueMatchValues(users []*okt.User, key okta.UserMatchKey) map[string]string {
 	l := logger.Desugar()
 
-	l.Debug("generating list of unique emails from okta users",
+	l.Debug("generating list of unique matching values from okta users",
 		zap.Int("num.okta.users", len(users)),
+		zap.String("user.match_key", string(key)),
 	)
 
-	emails := map[string]string{}
+	values := map[string]string{}
 
 	for _, u := range users {
-		email, err := okta.EmailFromUserProfile(u)
+		value, err := okta.UserMatchValue(u, key)
 		if err != nil {
-			l.Error("error getting email address from okta user",
+			l.Error("error getting matching value from okta user",
 				zap.Error(err),
 				zap.String("okta.user.id", u.Id),
 			)
@@ -287,21 +369,30 @@ func uniqueEmails(users []*okt.User) map[string]string {
 			continue
 		}
 
-		if _, ok := emails[email]; ok {
-			l.Info("email already exists in list of emails",
+		if _, ok := values[value]; ok {
+			l.Info("matching value already exists in list of matching values",
 				zap.String("okta.user.id", u.Id),
-				zap.String("okta.user.email", email),
+				zap.String("user.match_value", value),
 			)
 		}
 
-		emails[email] = u.Id
+		values[value] = u.Id
 	}
 
-	l.Debug("returning list of unique email address from okta users",
-		zap.Int("num.okta.users", len(emails)),
+	l.Debug("returning list of unique matching values from okta users",
+		zap.Int("num.okta.users", len(values)),
 	)
 
-	return emails
+	return values
+}
+
+// governorUserChangeTarget returns the target of a governor user change event
+func governorUserChangeTarget(govID, email, oktaID string) map[string]string {
+	return map[string]string{
+		"governor.user.id":    govID,
+		"governor.user.email": email,
+		"okta.user.id":        oktaID,
+	}
 }
 
 // userType parses the userType from the okta user profile
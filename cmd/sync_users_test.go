@@ -3,11 +3,13 @@ package cmd
 import (
 	"testing"
 
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
 	okt "github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/stretchr/testify/assert"
 )
 
-func Test_uniqueEmails(t *testing.T) {
+func Test_uniqueMatchValues(t *testing.T) {
 	setupLogging()
 
 	tests := []struct {
@@ -97,7 +99,7 @@ func Test_uniqueEmails(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := uniqueEmails(tt.users)
+			got := uniqueMatchValues(tt.users, okta.UserMatchKeyEmail)
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -153,3 +155,38 @@ func Test_userType(t *testing.T) {
 		})
 	}
 }
+
+func Test_syncUserStatus(t *testing.T) {
+	tests := []struct {
+		name             string
+		oktaStatus       string
+		includeSuspended bool
+		want             string
+		wantOK           bool
+	}{
+		{name: "active", oktaStatus: "ACTIVE", want: v1alpha1.UserStatusActive, wantOK: true},
+		{name: "staged", oktaStatus: "STAGED", want: v1alpha1.UserStatusActive, wantOK: true},
+		{name: "locked out", oktaStatus: "LOCKED_OUT", want: v1alpha1.UserStatusActive, wantOK: true},
+		{name: "suspended skipped", oktaStatus: "SUSPENDED", want: v1alpha1.UserStatusSuspended, wantOK: false},
+		{name: "suspended included", oktaStatus: "SUSPENDED", includeSuspended: true, want: v1alpha1.UserStatusSuspended, wantOK: true},
+		{name: "deprovisioned", oktaStatus: "DEPROVISIONED", wantOK: false},
+		{name: "deprovisioned with suspended", oktaStatus: "DEPROVISIONED", includeSuspended: true, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := syncUserStatus(tt.oktaStatus, tt.includeSuspended)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_inSyncScope(t *testing.T) {
+	scope := map[string]string{"user@example.com": "okta-1"}
+
+	assert.True(t, inSyncScope(nil, "other@example.com"))
+	assert.True(t, inSyncScope(scope, "user@example.com"))
+	assert.False(t, inSyncScope(scope, "other@example.com"))
+	assert.False(t, inSyncScope(map[string]string{}, "user@example.com"))
+}
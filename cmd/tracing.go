@@ -5,7 +5,7 @@ import (
 	"net/url"
 	"time"
 
-	"github.com/spf13/viper"
+	"github.com/metal-toolbox/gov-okta-addon/internal/config"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
@@ -13,16 +13,14 @@ import (
 	"go.opentelemetry.io/otel/sdk/resource"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"google.golang.org/grpc"
 )
 
 const timeout = 10 * time.Second
 
-func initTracing() {
-	if viper.GetBool("tracing.enabled") {
-		initTracer(
-			viper.GetString("tracing.endpoint"),
-			viper.GetBool("tracing.insecure"),
-		)
+func initTracing(cfg config.TracingConfig) {
+	if cfg.Enabled {
+		initTracer(cfg.Endpoint, cfg.Insecure, cfg.Environment)
 	}
 }
 
@@ -30,7 +28,7 @@ func initTracing() {
 // the OTLP GRPC exporter that will send spans to the provided endpoint. The returned
 // TracerProvider will also use a Resource configured with all the information
 // about the application.
-func initTracer(endpoint string, insecure bool) *tracesdk.TracerProvider {
+func initTracer(endpoint string, insecure bool, environment string) *tracesdk.TracerProvider {
 	_, err := url.Parse(endpoint)
 	if err != nil {
 		logger.Fatalw("invalid tracing endpoint", "error", err)
@@ -45,6 +43,10 @@ func initTracer(endpoint string, insecure bool) *tracesdk.TracerProvider {
 		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
 	}
 
+	if outboundProxy != nil {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithDialOption(grpc.WithContextDialer(outboundProxy.DialContext)))
+	}
+
 	exp, err := otlptracegrpc.New(context.Background(), exporterOpts...)
 	if err != nil {
 		logger.Fatalf("failed to initialize tracing", "error", err)
@@ -57,7 +59,7 @@ func initTracer(endpoint string, insecure bool) *tracesdk.TracerProvider {
 		tracesdk.WithResource(resource.NewWithAttributes(
 			semconv.SchemaURL,
 			semconv.ServiceNameKey.String("gov-okta-addon"),
-			attribute.String("environment", viper.GetString("tracing.environment")),
+			attribute.String("environment", environment),
 		)),
 	)
 
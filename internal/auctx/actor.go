@@ -0,0 +1,42 @@
+package auctx
+
+const (
+	// ActorSourceGovernor is the source of the actor of a governor event
+	ActorSourceGovernor = "governor"
+	// ActorSourceOkta is the source of the actor of an okta log event
+	ActorSourceOkta = "okta"
+)
+
+// Actor is who triggered the changes an audit event is written for, ie. the governor user of a governor event
+// or the okta actor of an okta log event
+type Actor struct {
+	Source string
+	ID     string
+	Type   string
+	Name   string
+}
+
+// Subjects returns the audit event subjects with the keys of the actor added, empty fields are left out so an
+// unknown actor leaves the subjects unchanged
+func (a Actor) Subjects(subjects map[string]string) map[string]string {
+	if a.ID == "" {
+		return subjects
+	}
+
+	if subjects == nil {
+		subjects = map[string]string{}
+	}
+
+	for k, v := range map[string]string{
+		"actor.source": a.Source,
+		"actor.id":     a.ID,
+		"actor.type":   a.Type,
+		"actor.name":   a.Name,
+	} {
+		if v != "" {
+			subjects[k] = v
+		}
+	}
+
+	return subjects
+}
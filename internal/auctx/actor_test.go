@@ -0,0 +1,45 @@
+package auctx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActor_Subjects(t *testing.T) {
+	tests := []struct {
+		name     string
+		actor    Actor
+		subjects map[string]string
+		want     map[string]string
+	}{
+		{
+			name:     "governor actor",
+			actor:    Actor{Source: ActorSourceGovernor, ID: "gov-user1"},
+			subjects: map[string]string{"event": "governor"},
+			want:     map[string]string{"event": "governor", "actor.source": "governor", "actor.id": "gov-user1"},
+		},
+		{
+			name:  "okta actor",
+			actor: Actor{Source: ActorSourceOkta, ID: "okta-user1", Type: "User", Name: "user1@example.com"},
+			want: map[string]string{
+				"actor.source": "okta",
+				"actor.id":     "okta-user1",
+				"actor.type":   "User",
+				"actor.name":   "user1@example.com",
+			},
+		},
+		{
+			name:     "unknown actor",
+			actor:    Actor{Source: ActorSourceGovernor},
+			subjects: map[string]string{"event": "governor"},
+			want:     map[string]string{"event": "governor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.actor.Subjects(tt.subjects))
+		})
+	}
+}
@@ -31,14 +31,14 @@ func GetAuditEvent(ctx context.Context) *auditevent.AuditEvent {
 	return auEvent
 }
 
-// WriteAuditEvent assembles a complete audit event and writes it to the event writer
-func WriteAuditEvent(ctx context.Context, evWriter *auditevent.EventWriter, evType string, evTarget map[string]string) error {
+// WriteAuditEvent assembles a complete audit event from the payload and writes it to the event writer
+func WriteAuditEvent(ctx context.Context, evWriter *auditevent.EventWriter, p Payload) error {
 	ae := GetAuditEvent(ctx)
 	if ae == nil {
 		return ErrAuditEventKeyNotFound
 	}
 
-	ae.Type = evType
+	ae.Type = p.EventType()
 
-	return evWriter.Write(ae.WithTarget(evTarget))
+	return evWriter.Write(ae.WithTarget(Target(p)))
 }
@@ -0,0 +1,45 @@
+package auctx
+
+import (
+	"context"
+	"maps"
+
+	"github.com/metal-toolbox/auditevent"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"go.uber.org/zap"
+)
+
+// OktaMutationHook returns an okta client audit hook writing an OktaMutation event for every mutating okta call.
+// The event has the source and subjects of the audit event of the context when there is one, ie. the reconciler's,
+// and a local source otherwise, ie. a sync command.  Failed calls are written with the failed outcome.
+func OktaMutationHook(l *zap.Logger, w *auditevent.EventWriter, source string) okta.AuditHook {
+	return func(ctx context.Context, m okta.Mutation, err error) {
+		evSource := auditevent.EventSource{Type: "local", Value: source}
+		subjects := map[string]string{"event": "okta"}
+
+		if ae := GetAuditEvent(ctx); ae != nil {
+			evSource = ae.Source
+			subjects = maps.Clone(ae.Subjects)
+		}
+
+		p := OktaMutation{Method: m.Method, Args: make(map[string]string, len(m.Args))}
+
+		for k, v := range m.Args {
+			p.Args["okta."+k] = v
+		}
+
+		outcome := auditevent.OutcomeSucceeded
+
+		if err != nil {
+			outcome = auditevent.OutcomeFailed
+			p.Error = err.Error()
+		}
+
+		ev := auditevent.NewAuditEvent(p.EventType(), evSource, outcome, subjects, "gov-okta-addon")
+		ev.LoggedAt = m.Time
+
+		if err := w.Write(ev.WithTarget(Target(p))); err != nil {
+			l.Error("failed to write okta mutation audit event", zap.String("okta.method", m.Method), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package auctx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/auditevent"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestOktaMutationHook(t *testing.T) {
+	reconcilerEvent := auditevent.NewAuditEvent("", auditevent.EventSource{Type: "local", Value: "reconciler"},
+		auditevent.OutcomeSucceeded, map[string]string{"event": "reconciler"}, "gov-okta-addon")
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		err          error
+		wantSource   string
+		wantSubjects map[string]string
+		wantOutcome  string
+		wantTarget   map[string]string
+	}{
+		{
+			name:         "command",
+			ctx:          context.TODO(),
+			wantSource:   "sync",
+			wantSubjects: map[string]string{"event": "okta"},
+			wantOutcome:  auditevent.OutcomeSucceeded,
+			wantTarget:   map[string]string{"okta.method": "AddGroupUser", "okta.group.id": "00g-1", "okta.user.id": "00u-1"},
+		},
+		{
+			name:         "audit event of the context",
+			ctx:          WithAuditEvent(context.TODO(), reconcilerEvent),
+			wantSource:   "reconciler",
+			wantSubjects: map[string]string{"event": "reconciler"},
+			wantOutcome:  auditevent.OutcomeSucceeded,
+			wantTarget:   map[string]string{"okta.method": "AddGroupUser", "okta.group.id": "00g-1", "okta.user.id": "00u-1"},
+		},
+		{
+			name:         "failed call",
+			ctx:          context.TODO(),
+			err:          okta.ErrNotFound,
+			wantSource:   "sync",
+			wantSubjects: map[string]string{"event": "okta"},
+			wantOutcome:  auditevent.OutcomeFailed,
+			wantTarget: map[string]string{
+				"okta.method":   "AddGroupUser",
+				"okta.error":    okta.ErrNotFound.Error(),
+				"okta.group.id": "00g-1",
+				"okta.user.id":  "00u-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			hook := OktaMutationHook(zap.NewNop(), auditevent.NewDefaultAuditEventWriter(buf), "sync")
+
+			at := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+			hook(tt.ctx, okta.Mutation{Time: at, Method: "AddGroupUser", Args: map[string]string{"group.id": "00g-1", "user.id": "00u-1"}}, tt.err)
+
+			got := &auditevent.AuditEvent{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), got))
+
+			assert.Equal(t, "OktaMutation", got.Type)
+			assert.Equal(t, tt.wantSource, got.Source.Value)
+			assert.Equal(t, tt.wantSubjects, got.Subjects)
+			assert.Equal(t, tt.wantOutcome, got.Outcome)
+			assert.Equal(t, tt.wantTarget, got.Target)
+			assert.True(t, at.Equal(got.LoggedAt))
+		})
+	}
+
+	// the audit event of the context is left as is
+	assert.Empty(t, reconcilerEvent.Type)
+	assert.Nil(t, reconcilerEvent.Target)
+}
@@ -0,0 +1,481 @@
+package auctx
+
+// Payload is the typed target of an audit event. The string fields are written as the audit event target
+// keys from their audit tag, fields tagged omitempty are left out when empty and a map[string]string field
+// tagged inline adds its own keys.
+type Payload interface {
+	// EventType returns the audit event type
+	EventType() string
+}
+
+// Payloads are the audit event payloads written by gov-okta-addon, one per event type, used to generate
+// the audit events JSON schema
+var Payloads = []Payload{
+	GroupCreate{},
+	GroupUpdate{},
+	GroupUpdateConflict{},
+	GroupDelete{},
+	GroupDeletePending{},
+	GroupDeleteCancel{},
+	GroupGovernorIDClear{},
+	GroupMemberAdd{},
+	GroupMemberRemove{},
+	GroupOwnerAdd{},
+	GroupOwnerRemove{},
+	GroupApplicationAdd{},
+	GroupApplicationUpdate{},
+	GroupApplicationRemove{},
+	GroupPushMappingAdd{},
+	GroupPushMappingRemove{},
+	UserUpdate{},
+	UserDeactivate{},
+	UserDelete{},
+	UserOffboardIncomplete{},
+	UserSuspend{},
+	UserUnsuspend{},
+	UserGovernorIDUpdate{},
+	GovernorUserCreate{},
+	GovernorUserUpdate{},
+	GovernorUserSuspend{},
+	GovernorUserUnsuspend{},
+	GovernorUserEmailUpdate{},
+	GovernorUserProfileUpdate{},
+	GovernorGroupMemberRequest{},
+	GovernorGroupMemberAdd{},
+	GovernorGroupMemberRemove{},
+	GovernorGroupOktaIDUpdate{},
+	InvariantViolation{},
+	ChangeVerificationFailed{},
+	ReconcileRunCompleted{},
+	OktaMutation{},
+}
+
+// GroupCreate is written when a governor group is created in okta
+type GroupCreate struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	OktaGroupID       string `audit:"okta.group.id"`
+}
+
+// EventType returns the audit event type
+func (GroupCreate) EventType() string { return "GroupCreate" }
+
+// GroupUpdate is written when an okta group is updated from its governor group
+type GroupUpdate struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	OktaGroupID       string `audit:"okta.group.id"`
+}
+
+// EventType returns the audit event type
+func (GroupUpdate) EventType() string { return "GroupUpdate" }
+
+// GroupUpdateConflict is written when an okta group changed while it was updated and the changes were merged
+type GroupUpdateConflict struct {
+	GovernorGroupSlug   string `audit:"governor.group.slug"`
+	GovernorGroupID     string `audit:"governor.group.id"`
+	OktaGroupID         string `audit:"okta.group.id"`
+	Conflicts           string `audit:"okta.group.conflicts"`
+	PreservedAttributes string `audit:"okta.group.preserved_attrs"`
+}
+
+// EventType returns the audit event type
+func (GroupUpdateConflict) EventType() string { return "GroupUpdateConflict" }
+
+// GroupDelete is written when the okta group of a deleted governor group is deleted
+type GroupDelete struct {
+	GovernorGroupID string `audit:"governor.group.id"`
+	OktaGroupID     string `audit:"okta.group.id"`
+}
+
+// EventType returns the audit event type
+func (GroupDelete) EventType() string { return "GroupDelete" }
+
+// GroupDeletePending is written when the okta group of a deleted governor group is flagged for deletion after the
+// delete grace period
+type GroupDeletePending struct {
+	GovernorGroupID string `audit:"governor.group.id"`
+	OktaGroupID     string `audit:"okta.group.id"`
+	PendingDeleteAt string `audit:"okta.group.pending_delete_at"`
+}
+
+// EventType returns the audit event type
+func (GroupDeletePending) EventType() string { return "GroupDeletePending" }
+
+// GroupDeleteCancel is written when the deletion flag is cleared from an okta group because its governor group
+// exists again
+type GroupDeleteCancel struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	OktaGroupID       string `audit:"okta.group.id"`
+}
+
+// EventType returns the audit event type
+func (GroupDeleteCancel) EventType() string { return "GroupDeleteCancel" }
+
+// GroupGovernorIDClear is written when the governor id is cleared from an okta group because its governor group
+// doesn't exist anymore
+type GroupGovernorIDClear struct {
+	GovernorGroupID string `audit:"governor.group.id"`
+	OktaGroupID     string `audit:"okta.group.id"`
+}
+
+// EventType returns the audit event type
+func (GroupGovernorIDClear) EventType() string { return "GroupGovernorIDClear" }
+
+// GroupMemberAdd is written when a user is added to an okta group
+type GroupMemberAdd struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaGroupID       string `audit:"okta.group.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GroupMemberAdd) EventType() string { return "GroupMemberAdd" }
+
+// GroupMemberRemove is written when a user is removed from an okta group, the governor user is unknown when
+// the reconciler removes an okta member that isn't in governor
+type GroupMemberRemove struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	GovernorUserEmail string `audit:"governor.user.email,omitempty"`
+	GovernorUserID    string `audit:"governor.user.id,omitempty"`
+	OktaGroupID       string `audit:"okta.group.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GroupMemberRemove) EventType() string { return "GroupMemberRemove" }
+
+// GroupOwnerAdd is written when a user is added as an owner of an okta group
+type GroupOwnerAdd struct {
+	GovernorGroupID string `audit:"governor.group.id"`
+	OktaGroupID     string `audit:"okta.group.id"`
+	OktaUserID      string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GroupOwnerAdd) EventType() string { return "GroupOwnerAdd" }
+
+// GroupOwnerRemove is written when a user is removed from the owners of an okta group
+type GroupOwnerRemove struct {
+	GovernorGroupID string `audit:"governor.group.id"`
+	OktaGroupID     string `audit:"okta.group.id"`
+	OktaUserID      string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GroupOwnerRemove) EventType() string { return "GroupOwnerRemove" }
+
+// GroupApplicationAdd is written when an okta group is assigned to an application
+type GroupApplicationAdd struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	GovernorAppSlug   string `audit:"governor.app.slug"`
+	OktaGroupID       string `audit:"okta.group.id"`
+	OktaAppID         string `audit:"okta.app.id"`
+	OktaAppSlug       string `audit:"okta.app.slug"`
+}
+
+// EventType returns the audit event type
+func (GroupApplicationAdd) EventType() string { return "GroupApplicationAdd" }
+
+// GroupApplicationUpdate is written when the drifted assignment of an okta group to an application is updated
+// with the assignment settings
+type GroupApplicationUpdate struct {
+	GovernorGroupSlug  string `audit:"governor.group.slug"`
+	GovernorGroupID    string `audit:"governor.group.id"`
+	GovernorAppSlug    string `audit:"governor.app.slug"`
+	OktaGroupID        string `audit:"okta.group.id"`
+	OktaAppID          string `audit:"okta.app.id"`
+	OktaAppSlug        string `audit:"okta.app.slug"`
+	OktaPriorityBefore string `audit:"okta.assignment.priority.before,omitempty"`
+	OktaPriorityAfter  string `audit:"okta.assignment.priority.after,omitempty"`
+}
+
+// EventType returns the audit event type
+func (GroupApplicationUpdate) EventType() string { return "GroupApplicationUpdate" }
+
+// GroupApplicationRemove is written when an okta group is unassigned from an application
+type GroupApplicationRemove struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	GovernorAppSlug   string `audit:"governor.app.slug"`
+	OktaGroupID       string `audit:"okta.group.id"`
+	OktaAppID         string `audit:"okta.app.id"`
+	OktaAppSlug       string `audit:"okta.app.slug"`
+}
+
+// EventType returns the audit event type
+func (GroupApplicationRemove) EventType() string { return "GroupApplicationRemove" }
+
+// GroupPushMappingAdd is written when an okta group is pushed to an application, or its inactive push group
+// mapping is activated
+type GroupPushMappingAdd struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	GovernorAppSlug   string `audit:"governor.app.slug"`
+	OktaGroupID       string `audit:"okta.group.id"`
+	OktaAppID         string `audit:"okta.app.id"`
+	OktaAppSlug       string `audit:"okta.app.slug"`
+	OktaMappingID     string `audit:"okta.mapping.id"`
+}
+
+// EventType returns the audit event type
+func (GroupPushMappingAdd) EventType() string { return "GroupPushMappingAdd" }
+
+// GroupPushMappingRemove is written when the push group mapping of an okta group is deleted from an application
+type GroupPushMappingRemove struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	GovernorAppSlug   string `audit:"governor.app.slug"`
+	OktaGroupID       string `audit:"okta.group.id"`
+	OktaAppID         string `audit:"okta.app.id"`
+	OktaAppSlug       string `audit:"okta.app.slug"`
+	OktaMappingID     string `audit:"okta.mapping.id"`
+}
+
+// EventType returns the audit event type
+func (GroupPushMappingRemove) EventType() string { return "GroupPushMappingRemove" }
+
+// UserUpdate is written when the status of an okta user is updated from its governor user
+type UserUpdate struct {
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (UserUpdate) EventType() string { return "UserUpdate" }
+
+// UserDeactivate is written when the okta user of a deleted governor user is deactivated
+type UserDeactivate struct {
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (UserDeactivate) EventType() string { return "UserDeactivate" }
+
+// UserOffboardIncomplete is written when some of the offboarding steps of the okta user of a deleted governor
+// user failed, the steps have the status of each step, ie. deactivate=done,clear-sessions=failed
+type UserOffboardIncomplete struct {
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+	Steps             string `audit:"okta.user.offboard.steps"`
+	Error             string `audit:"okta.user.offboard.error"`
+}
+
+// EventType returns the audit event type
+func (UserOffboardIncomplete) EventType() string { return "UserOffboardIncomplete" }
+
+// UserSuspend is written when the okta user of a suspended governor user is suspended
+type UserSuspend struct {
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (UserSuspend) EventType() string { return "UserSuspend" }
+
+// UserUnsuspend is written when the okta user of an un-suspended governor user is un-suspended
+type UserUnsuspend struct {
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (UserUnsuspend) EventType() string { return "UserUnsuspend" }
+
+// UserDelete is written when the okta user of a deleted governor user is permanently deleted
+type UserDelete struct {
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (UserDelete) EventType() string { return "UserDelete" }
+
+// UserGovernorIDUpdate is written when the governor id is written to an okta user profile
+type UserGovernorIDUpdate struct {
+	GovernorUserID string `audit:"governor.user.id"`
+	OktaUserID     string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (UserGovernorIDUpdate) EventType() string { return "UserGovernorIDUpdate" }
+
+// GovernorUserCreate is written when a governor user is created for a new okta user
+type GovernorUserCreate struct {
+	GovernorUserID    string `audit:"governor.user.id"`
+	GovernorUserEmail string `audit:"governor.user.email"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorUserCreate) EventType() string { return "GovernorUserCreate" }
+
+// GovernorUserUpdate is written when a pending governor user is activated for a new okta user
+type GovernorUserUpdate struct {
+	GovernorUserID    string `audit:"governor.user.id"`
+	GovernorUserEmail string `audit:"governor.user.email"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorUserUpdate) EventType() string { return "GovernorUserUpdate" }
+
+// GovernorUserSuspend is written when a governor user is suspended because their okta user was suspended
+type GovernorUserSuspend struct {
+	GovernorUserID    string `audit:"governor.user.id"`
+	GovernorUserEmail string `audit:"governor.user.email"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorUserSuspend) EventType() string { return "GovernorUserSuspend" }
+
+// GovernorUserUnsuspend is written when a governor user is un-suspended because their okta user was un-suspended
+type GovernorUserUnsuspend struct {
+	GovernorUserID    string `audit:"governor.user.id"`
+	GovernorUserEmail string `audit:"governor.user.email"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorUserUnsuspend) EventType() string { return "GovernorUserUnsuspend" }
+
+// GovernorUserEmailUpdate is written when the email of a governor user is updated to their okta email
+type GovernorUserEmailUpdate struct {
+	GovernorUserID       string `audit:"governor.user.id"`
+	GovernorUserEmail    string `audit:"governor.user.email"`
+	GovernorUserNewEmail string `audit:"governor.user.new_email"`
+	OktaUserID           string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorUserEmailUpdate) EventType() string { return "GovernorUserEmailUpdate" }
+
+// GovernorUserProfileUpdate is written when the name or email of a governor user is updated from an okta user
+// profile update
+type GovernorUserProfileUpdate struct {
+	GovernorUserID       string `audit:"governor.user.id"`
+	GovernorUserEmail    string `audit:"governor.user.email"`
+	GovernorUserNewEmail string `audit:"governor.user.new_email"`
+	GovernorUserName     string `audit:"governor.user.name"`
+	OktaUserID           string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorUserProfileUpdate) EventType() string { return "GovernorUserProfileUpdate" }
+
+// GovernorGroupMemberRequest is written when a governor membership request is filed for an okta group member
+// that isn't a member of the governor group, instead of removing them from the okta group
+type GovernorGroupMemberRequest struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaGroupID       string `audit:"okta.group.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorGroupMemberRequest) EventType() string { return "GovernorGroupMemberRequest" }
+
+// GovernorGroupMemberAdd is written when a governor user created for a new okta user is added to a default
+// governor group, or when an okta group member is added to the governor group because okta is the source of truth
+// of the members
+type GovernorGroupMemberAdd struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaGroupID       string `audit:"okta.group.id,omitempty"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorGroupMemberAdd) EventType() string { return "GovernorGroupMemberAdd" }
+
+// GovernorGroupMemberRemove is written when a governor group member missing from the okta group is removed from
+// the governor group because okta is the source of truth of the members
+type GovernorGroupMemberRemove struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	GovernorUserEmail string `audit:"governor.user.email"`
+	GovernorUserID    string `audit:"governor.user.id"`
+	OktaGroupID       string `audit:"okta.group.id"`
+	OktaUserID        string `audit:"okta.user.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorGroupMemberRemove) EventType() string { return "GovernorGroupMemberRemove" }
+
+// GovernorGroupOktaIDUpdate is written when the okta group id is recorded on a governor group
+type GovernorGroupOktaIDUpdate struct {
+	GovernorGroupSlug string `audit:"governor.group.slug"`
+	GovernorGroupID   string `audit:"governor.group.id"`
+	OktaGroupID       string `audit:"okta.group.id"`
+}
+
+// EventType returns the audit event type
+func (GovernorGroupOktaIDUpdate) EventType() string { return "GovernorGroupOktaIDUpdate" }
+
+// InvariantViolation is written when a governor and okta count diverge by more than the tolerance
+type InvariantViolation struct {
+	Invariant     string `audit:"invariant"`
+	GovernorCount string `audit:"governor.count"`
+	OktaCount     string `audit:"okta.count"`
+	Divergence    string `audit:"divergence"`
+	Tolerance     string `audit:"tolerance"`
+}
+
+// EventType returns the audit event type
+func (InvariantViolation) EventType() string { return "InvariantViolation" }
+
+// ChangeVerificationFailed is written when a sampled okta change didn't take effect, the target of the
+// change's own event is included
+type ChangeVerificationFailed struct {
+	ChangeType string            `audit:"change.type"`
+	Change     map[string]string `audit:",inline"`
+}
+
+// EventType returns the audit event type
+func (ChangeVerificationFailed) EventType() string { return "ChangeVerificationFailed" }
+
+// ReconcileRunCompleted is written at the end of every reconciler loop with its result and counts, as evidence
+// the loop ran on schedule
+type ReconcileRunCompleted struct {
+	Result         string `audit:"run.result"`
+	Started        string `audit:"run.started"`
+	Duration       string `audit:"run.duration"`
+	GroupsChecked  string `audit:"run.groups_checked"`
+	MembersAdded   string `audit:"run.members_added"`
+	MembersRemoved string `audit:"run.members_removed"`
+	UsersSuspended string `audit:"run.users_suspended"`
+	Errors         string `audit:"run.errors"`
+	Error          string `audit:"run.error,omitempty"`
+}
+
+// EventType returns the audit event type
+func (ReconcileRunCompleted) EventType() string { return "ReconcileRunCompleted" }
+
+// OktaMutation is written by the okta client for every mutating okta call it makes, whichever command or reconciler
+// path made it, with the okta call arguments prefixed with okta
+type OktaMutation struct {
+	Method string            `audit:"okta.method"`
+	Error  string            `audit:"okta.error,omitempty"`
+	Args   map[string]string `audit:",inline"`
+}
+
+// EventType returns the audit event type
+func (OktaMutation) EventType() string { return "OktaMutation" }
@@ -0,0 +1,112 @@
+package auctx
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// schemaDialect is the JSON schema dialect of the generated audit events schema
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// payloadField is an audit target key of a payload field
+type payloadField struct {
+	index     int
+	key       string
+	omitempty bool
+	inline    bool
+}
+
+// payloadFields returns the audit target keys of a payload type from the audit tags
+func payloadFields(t reflect.Type) []payloadField {
+	fields := []payloadField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("audit")
+		if !ok {
+			continue
+		}
+
+		key, opts, _ := strings.Cut(tag, ",")
+
+		fields = append(fields, payloadField{
+			index:     i,
+			key:       key,
+			omitempty: opts == "omitempty",
+			inline:    opts == "inline",
+		})
+	}
+
+	return fields
+}
+
+// Target returns the audit event target of a payload
+func Target(p Payload) map[string]string {
+	v := reflect.Indirect(reflect.ValueOf(p))
+	target := map[string]string{}
+
+	for _, f := range payloadFields(v.Type()) {
+		fv := v.Field(f.index)
+
+		if f.inline {
+			for k, val := range fv.Interface().(map[string]string) {
+				target[k] = val
+			}
+
+			continue
+		}
+
+		if f.omitempty && fv.String() == "" {
+			continue
+		}
+
+		target[f.key] = fv.String()
+	}
+
+	return target
+}
+
+// JSONSchema returns the JSON schema of the audit event targets, with a definition per event type
+func JSONSchema(payloads ...Payload) ([]byte, error) {
+	defs := map[string]interface{}{}
+	refs := []interface{}{}
+
+	for _, p := range payloads {
+		t := reflect.Indirect(reflect.ValueOf(p)).Type()
+
+		props := map[string]interface{}{}
+		required := []string{}
+
+		var additional interface{} = false
+
+		for _, f := range payloadFields(t) {
+			if f.inline {
+				additional = map[string]string{"type": "string"}
+				continue
+			}
+
+			props[f.key] = map[string]string{"type": "string"}
+
+			if !f.omitempty {
+				required = append(required, f.key)
+			}
+		}
+
+		defs[p.EventType()] = map[string]interface{}{
+			"type":                 "object",
+			"properties":           props,
+			"required":             required,
+			"additionalProperties": additional,
+		}
+
+		refs = append(refs, map[string]string{"$ref": "#/$defs/" + p.EventType()})
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":     schemaDialect,
+		"title":       "gov-okta-addon audit event targets",
+		"description": "The target of a gov-okta-addon audit event, the definitions are keyed by the audit event type.",
+		"anyOf":       refs,
+		"$defs":       defs,
+	}, "", "  ")
+}
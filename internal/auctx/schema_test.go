@@ -0,0 +1,164 @@
+package auctx
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaArtifact is the checked in audit events schema, regenerate it with make audit-schema
+const schemaArtifact = "../../docs/audit-events.schema.json"
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload Payload
+		want    map[string]string
+	}{
+		{
+			name:    "all keys",
+			payload: GroupDelete{GovernorGroupID: "gov-group1", OktaGroupID: "okta-group1"},
+			want:    map[string]string{"governor.group.id": "gov-group1", "okta.group.id": "okta-group1"},
+		},
+		{
+			name:    "required keys are written when empty",
+			payload: GroupDelete{GovernorGroupID: "gov-group1"},
+			want:    map[string]string{"governor.group.id": "gov-group1", "okta.group.id": ""},
+		},
+		{
+			name: "empty optional keys are left out",
+			payload: GroupMemberRemove{
+				GovernorGroupSlug: "group-1",
+				GovernorGroupID:   "gov-group1",
+				OktaGroupID:       "okta-group1",
+				OktaUserID:        "okta-user1",
+			},
+			want: map[string]string{
+				"governor.group.slug": "group-1",
+				"governor.group.id":   "gov-group1",
+				"okta.group.id":       "okta-group1",
+				"okta.user.id":        "okta-user1",
+			},
+		},
+		{
+			name: "inline keys",
+			payload: ChangeVerificationFailed{
+				ChangeType: "GroupMemberAdd",
+				Change:     map[string]string{"okta.group.id": "okta-group1"},
+			},
+			want: map[string]string{"change.type": "GroupMemberAdd", "okta.group.id": "okta-group1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Target(tt.payload))
+		})
+	}
+}
+
+// TestPayloads makes sure every payload only has string keys and writes the keys its schema describes
+func TestPayloads(t *testing.T) {
+	var schema struct {
+		Defs map[string]struct {
+			Properties           map[string]interface{} `json:"properties"`
+			Required             []string               `json:"required"`
+			AdditionalProperties interface{}            `json:"additionalProperties"`
+		} `json:"$defs"`
+	}
+
+	b, err := JSONSchema(Payloads...)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, &schema))
+
+	types := map[string]bool{}
+
+	for _, p := range Payloads {
+		evType := p.EventType()
+
+		t.Run(evType, func(t *testing.T) {
+			assert.False(t, types[evType], "duplicate event type")
+			types[evType] = true
+
+			// fill every field so optional keys are written too
+			v := reflect.New(reflect.TypeOf(p)).Elem()
+
+			for i := 0; i < v.NumField(); i++ {
+				f := v.Type().Field(i)
+
+				_, ok := f.Tag.Lookup("audit")
+				require.True(t, ok, "field %s has no audit tag", f.Name)
+
+				switch f.Type.Kind() {
+				case reflect.String:
+					v.Field(i).SetString("value")
+				case reflect.Map:
+					require.Equal(t, reflect.TypeOf(map[string]string{}), f.Type, "field %s", f.Name)
+					v.Field(i).Set(reflect.ValueOf(map[string]string{"extra": "value"}))
+				default:
+					t.Fatalf("field %s has an unsupported type %s", f.Name, f.Type)
+				}
+			}
+
+			def, ok := schema.Defs[evType]
+			require.True(t, ok)
+
+			target := Target(v.Interface().(Payload))
+
+			for _, k := range def.Required {
+				assert.Contains(t, target, k)
+			}
+
+			for k := range target {
+				if _, ok := def.Properties[k]; !ok {
+					assert.NotEqual(t, false, def.AdditionalProperties, "key %s is not in the schema", k)
+				}
+			}
+		})
+	}
+}
+
+// TestPayloadsRegistered makes sure every payload type in the package is in Payloads, so it's in the schema
+func TestPayloadsRegistered(t *testing.T) {
+	fset := token.NewFileSet()
+
+	f, err := parser.ParseFile(fset, "payloads.go", nil, 0)
+	require.NoError(t, err)
+
+	declared := []string{}
+
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || fn.Name.Name != "EventType" {
+			continue
+		}
+
+		if ident, ok := fn.Recv.List[0].Type.(*ast.Ident); ok {
+			declared = append(declared, ident.Name)
+		}
+	}
+
+	registered := []string{}
+	for _, p := range Payloads {
+		registered = append(registered, reflect.TypeOf(p).Name())
+	}
+
+	assert.ElementsMatch(t, declared, registered)
+}
+
+func TestJSONSchemaArtifact(t *testing.T) {
+	want, err := JSONSchema(Payloads...)
+	require.NoError(t, err)
+
+	got, err := os.ReadFile(schemaArtifact)
+	require.NoError(t, err)
+
+	assert.Equal(t, string(want)+"\n", string(got), "audit event schema is out of date, run make audit-schema")
+}
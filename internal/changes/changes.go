@@ -0,0 +1,181 @@
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/metal-toolbox/governor-api/pkg/events/v1alpha1"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSubject is the default NATS subject the change events are published on
+	DefaultSubject = "governor.addons.gov-okta-addon.changes"
+
+	// SystemOkta is the system of the changes made in okta
+	SystemOkta = "okta"
+	// SystemGovernor is the system of the changes made in governor
+	SystemGovernor = "governor"
+
+	// source is the source of the change events
+	source = "gov-okta-addon"
+)
+
+var (
+	changesPublishedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "changes_published_total",
+			Help:      "Total count of change events published.",
+		},
+	)
+
+	changesPublishErrorsCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "changes_publish_errors_total",
+			Help:      "Total count of change events that failed to publish.",
+		},
+	)
+)
+
+// Event is a change made by gov-okta-addon.  It has the fields of a governor event, so consumers of governor
+// events can decode it, along with the system that was changed, the change type (the audit event type) and
+// its target.
+type Event struct {
+	v1alpha1.Event
+
+	Source string            `json:"source"`
+	System string            `json:"system"`
+	Type   string            `json:"type"`
+	Target map[string]string `json:"target"`
+	Time   time.Time         `json:"time"`
+}
+
+// Conn is the NATS connection the change events are published with
+type Conn interface {
+	PublishMsg(*nats.Msg) error
+}
+
+// Publisher publishes the change events on a NATS subject
+type Publisher struct {
+	conn    Conn
+	logger  *zap.Logger
+	subject string
+}
+
+// Option is a functional configuration option
+type Option func(p *Publisher)
+
+// WithLogger sets logger
+func WithLogger(l *zap.Logger) Option {
+	return func(p *Publisher) {
+		p.logger = l
+	}
+}
+
+// WithSubject sets the subject the change events are published on
+func WithSubject(s string) Option {
+	return func(p *Publisher) {
+		if s != "" {
+			p.subject = s
+		}
+	}
+}
+
+// NewPublisher returns a publisher for the NATS connection
+func NewPublisher(conn Conn, opts ...Option) *Publisher {
+	p := &Publisher{
+		conn:    conn,
+		logger:  zap.NewNop(),
+		subject: DefaultSubject,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// NewEvent returns the change event for a change of the given type to the system, the governor group and user
+// ids are taken from the target
+func NewEvent(ctx context.Context, system, changeType string, target map[string]string) *Event {
+	ev := &Event{
+		Event: v1alpha1.Event{
+			Version:      v1alpha1.Version,
+			Action:       Action(changeType),
+			GroupID:      target["governor.group.id"],
+			UserID:       target["governor.user.id"],
+			TraceContext: map[string]string{},
+		},
+		Source: source,
+		System: system,
+		Type:   changeType,
+		Target: target,
+		Time:   time.Now().UTC(),
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(ev.TraceContext))
+
+	return ev
+}
+
+// Action returns the governor event action for a change type, ie. CREATE for GroupCreate and GroupMemberAdd
+func Action(changeType string) string {
+	switch {
+	case strings.HasSuffix(changeType, "Create"), strings.HasSuffix(changeType, "Add"):
+		return v1alpha1.GovernorEventCreate
+	case strings.HasSuffix(changeType, "Delete"), strings.HasSuffix(changeType, "Remove"), strings.HasSuffix(changeType, "Deactivate"):
+		return v1alpha1.GovernorEventDelete
+	default:
+		return v1alpha1.GovernorEventUpdate
+	}
+}
+
+// Publish publishes a change event, publishing with a nil publisher does nothing.  Errors are logged and
+// counted, but the change was already made so they're not returned.
+func (p *Publisher) Publish(ctx context.Context, system, changeType string, target map[string]string) {
+	if p == nil {
+		return
+	}
+
+	ev := NewEvent(ctx, system, changeType, target)
+
+	logger := p.logger.With(zap.String("change.system", system), zap.String("change.type", changeType), zap.String("nats.subject", p.subject))
+
+	b, err := json.Marshal(ev)
+	if err != nil {
+		changesPublishErrorsCounter.Inc()
+
+		logger.Error("error encoding change event", zap.Error(err))
+
+		return
+	}
+
+	msg := nats.NewMsg(p.subject)
+	msg.Data = b
+
+	if cid := v1alpha1.ExtractCorrelationID(ctx); cid != "" {
+		msg.Header.Set(v1alpha1.GovernorEventCorrelationIDHeader, cid)
+	}
+
+	if err := p.conn.PublishMsg(msg); err != nil {
+		changesPublishErrorsCounter.Inc()
+
+		logger.Error("error publishing change event", zap.Error(err))
+
+		return
+	}
+
+	changesPublishedCounter.Inc()
+
+	logger.Debug("published change event")
+}
@@ -0,0 +1,96 @@
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/events/v1alpha1"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockConn struct {
+	err  error
+	msgs []*nats.Msg
+}
+
+func (m *mockConn) PublishMsg(msg *nats.Msg) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.msgs = append(m.msgs, msg)
+
+	return nil
+}
+
+func TestAction(t *testing.T) {
+	tests := []struct {
+		changeType string
+		want       string
+	}{
+		{changeType: "GroupCreate", want: v1alpha1.GovernorEventCreate},
+		{changeType: "GroupMemberAdd", want: v1alpha1.GovernorEventCreate},
+		{changeType: "GroupUpdate", want: v1alpha1.GovernorEventUpdate},
+		{changeType: "UserGovernorIDUpdate", want: v1alpha1.GovernorEventUpdate},
+		{changeType: "GroupOwnerRemove", want: v1alpha1.GovernorEventDelete},
+		{changeType: "UserDeactivate", want: v1alpha1.GovernorEventDelete},
+		{changeType: "GovernorUserDelete", want: v1alpha1.GovernorEventDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.changeType, func(t *testing.T) {
+			assert.Equal(t, tt.want, Action(tt.changeType))
+		})
+	}
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	conn := &mockConn{}
+	p := NewPublisher(conn, WithSubject("test.changes"))
+
+	ctx := v1alpha1.InjectCorrelationID(context.Background(), "correlation-1")
+
+	p.Publish(ctx, SystemOkta, "GroupMemberAdd", map[string]string{
+		"governor.group.id": "group-1",
+		"governor.user.id":  "user-1",
+		"okta.group.id":     "okta-group-1",
+	})
+
+	require.Len(t, conn.msgs, 1)
+
+	msg := conn.msgs[0]
+	assert.Equal(t, "test.changes", msg.Subject)
+	assert.Equal(t, "correlation-1", msg.Header.Get(v1alpha1.GovernorEventCorrelationIDHeader))
+
+	// governor event consumers can decode the change event
+	govEvent := &v1alpha1.Event{}
+	require.NoError(t, json.Unmarshal(msg.Data, govEvent))
+	assert.Equal(t, v1alpha1.Version, govEvent.Version)
+	assert.Equal(t, v1alpha1.GovernorEventCreate, govEvent.Action)
+	assert.Equal(t, "group-1", govEvent.GroupID)
+	assert.Equal(t, "user-1", govEvent.UserID)
+
+	ev := &Event{}
+	require.NoError(t, json.Unmarshal(msg.Data, ev))
+	assert.Equal(t, "gov-okta-addon", ev.Source)
+	assert.Equal(t, SystemOkta, ev.System)
+	assert.Equal(t, "GroupMemberAdd", ev.Type)
+	assert.Equal(t, "okta-group-1", ev.Target["okta.group.id"])
+}
+
+func TestPublisher_PublishError(t *testing.T) {
+	conn := &mockConn{err: errors.New("boom")} //nolint:goerr113
+
+	// errors are only logged
+	NewPublisher(conn).Publish(context.Background(), SystemGovernor, "GovernorUserCreate", map[string]string{})
+
+	var p *Publisher
+
+	p.Publish(context.Background(), SystemGovernor, "GovernorUserCreate", map[string]string{})
+
+	assert.Empty(t, conn.msgs)
+}
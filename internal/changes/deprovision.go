@@ -0,0 +1,104 @@
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/metal-toolbox/governor-api/pkg/events/v1alpha1"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// deprovision notification results
+const (
+	deprovisionPublished  = "published"
+	deprovisionFailed     = "failed"
+	deprovisionSuppressed = "suppressed"
+)
+
+var deprovisionNotificationsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Subsystem: "gov_okta_addon",
+		Name:      "deprovision_notifications_total",
+		Help:      "Total count of okta user deprovisioning notifications by result (published, failed or suppressed).",
+	},
+	[]string{"result"},
+)
+
+// Deprovision is the notification of an okta user deprovisioned after their governor user was deleted, so
+// downstream systems (ie. github org removal or license reclamation) can react
+type Deprovision struct {
+	Email          string    `json:"email"`
+	OktaUserID     string    `json:"okta_user_id"`
+	GovernorUserID string    `json:"governor_user_id"`
+	Permanent      bool      `json:"permanent"`
+	Time           time.Time `json:"time"`
+}
+
+// DeprovisionNotifier publishes the okta user deprovisioning notifications on a NATS subject
+type DeprovisionNotifier struct {
+	conn    Conn
+	logger  *zap.Logger
+	subject string
+}
+
+// NewDeprovisionNotifier returns a notifier publishing on the subject of the NATS connection
+func NewDeprovisionNotifier(conn Conn, subject string, logger *zap.Logger) *DeprovisionNotifier {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &DeprovisionNotifier{
+		conn:    conn,
+		logger:  logger,
+		subject: subject,
+	}
+}
+
+// Notify publishes a deprovisioning notification, notifying with a nil notifier does nothing.  Errors are logged
+// and counted, but the user was already deprovisioned so they're not returned.
+func (n *DeprovisionNotifier) Notify(ctx context.Context, d Deprovision) {
+	if n == nil {
+		return
+	}
+
+	logger := n.logger.With(zap.String("okta.user.id", d.OktaUserID), zap.String("nats.subject", n.subject))
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		deprovisionNotificationsCounter.WithLabelValues(deprovisionFailed).Inc()
+		logger.Error("error encoding deprovision notification", zap.Error(err))
+
+		return
+	}
+
+	msg := nats.NewMsg(n.subject)
+	msg.Data = b
+
+	if cid := v1alpha1.ExtractCorrelationID(ctx); cid != "" {
+		msg.Header.Set(v1alpha1.GovernorEventCorrelationIDHeader, cid)
+	}
+
+	if err := n.conn.PublishMsg(msg); err != nil {
+		deprovisionNotificationsCounter.WithLabelValues(deprovisionFailed).Inc()
+		logger.Error("error publishing deprovision notification", zap.Error(err))
+
+		return
+	}
+
+	deprovisionNotificationsCounter.WithLabelValues(deprovisionPublished).Inc()
+	logger.Debug("published deprovision notification")
+}
+
+// Suppress logs and counts a deprovisioning notification that isn't published, ie. in dry-run
+func (n *DeprovisionNotifier) Suppress(d Deprovision) {
+	if n == nil {
+		return
+	}
+
+	deprovisionNotificationsCounter.WithLabelValues(deprovisionSuppressed).Inc()
+	n.logger.Info("SKIP publishing deprovision notification", zap.String("okta.user.id", d.OktaUserID), zap.String("nats.subject", n.subject))
+}
@@ -0,0 +1,73 @@
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/governor-api/pkg/events/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprovisionNotifier_Notify(t *testing.T) {
+	conn := &mockConn{}
+	n := NewDeprovisionNotifier(conn, "test.deprovisioned", nil)
+
+	ctx := v1alpha1.InjectCorrelationID(context.Background(), "correlation-1")
+
+	d := Deprovision{
+		Email:          "one@example.com",
+		OktaUserID:     "okta-user-1",
+		GovernorUserID: "user-1",
+		Time:           time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	published := testutil.ToFloat64(deprovisionNotificationsCounter.WithLabelValues(deprovisionPublished))
+
+	n.Notify(ctx, d)
+
+	require.Len(t, conn.msgs, 1)
+	assert.Equal(t, "test.deprovisioned", conn.msgs[0].Subject)
+	assert.Equal(t, "correlation-1", conn.msgs[0].Header.Get(v1alpha1.GovernorEventCorrelationIDHeader))
+	assert.JSONEq(t, `{
+		"email": "one@example.com",
+		"okta_user_id": "okta-user-1",
+		"governor_user_id": "user-1",
+		"permanent": false,
+		"time": "2023-01-01T00:00:00Z"
+	}`, string(conn.msgs[0].Data))
+	assert.Equal(t, published+1, testutil.ToFloat64(deprovisionNotificationsCounter.WithLabelValues(deprovisionPublished)))
+
+	got := Deprovision{}
+	require.NoError(t, json.Unmarshal(conn.msgs[0].Data, &got))
+	assert.Equal(t, d, got)
+
+	suppressed := testutil.ToFloat64(deprovisionNotificationsCounter.WithLabelValues(deprovisionSuppressed))
+
+	n.Suppress(d)
+
+	assert.Len(t, conn.msgs, 1)
+	assert.Equal(t, suppressed+1, testutil.ToFloat64(deprovisionNotificationsCounter.WithLabelValues(deprovisionSuppressed)))
+}
+
+func TestDeprovisionNotifier_NotifyError(t *testing.T) {
+	conn := &mockConn{err: errors.New("boom")} //nolint:goerr113
+
+	failed := testutil.ToFloat64(deprovisionNotificationsCounter.WithLabelValues(deprovisionFailed))
+
+	// errors are only logged
+	NewDeprovisionNotifier(conn, "test.deprovisioned", nil).Notify(context.Background(), Deprovision{OktaUserID: "okta-user-1"})
+
+	assert.Equal(t, failed+1, testutil.ToFloat64(deprovisionNotificationsCounter.WithLabelValues(deprovisionFailed)))
+
+	var n *DeprovisionNotifier
+
+	n.Notify(context.Background(), Deprovision{})
+	n.Suppress(Deprovision{})
+
+	assert.Empty(t, conn.msgs)
+}
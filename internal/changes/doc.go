@@ -0,0 +1,2 @@
+// Package changes publishes governor compatible events for the changes made by gov-okta-addon
+package changes
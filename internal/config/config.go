@@ -0,0 +1,930 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govauth"
+	"github.com/metal-toolbox/gov-okta-addon/internal/journal"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/proxy"
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+	"github.com/metal-toolbox/gov-okta-addon/internal/srv"
+)
+
+const (
+	// MetadataTargetDescription syncs okta group metadata into the governor group description
+	MetadataTargetDescription = "description"
+	// MetadataTargetNote syncs okta group metadata into the governor group note, which can only be set on create
+	MetadataTargetNote = "note"
+
+	// MetadataStrategyKeep only sets the metadata when the governor value is empty
+	MetadataStrategyKeep = "keep"
+	// MetadataStrategyReplace overwrites the governor value with the okta metadata
+	MetadataStrategyReplace = "replace"
+	// MetadataStrategyAppend appends the okta metadata to the governor value when it's not already there
+	MetadataStrategyAppend = "append"
+
+	// AdminsSourceOwners imports the owners of an okta group as the admins of the governor group created from it
+	AdminsSourceOwners = "owners"
+	// AdminsSourceGroup imports the members of the admins counterpart of an okta group, named after it with the
+	// admins group suffix, as the admins of the governor group created from it
+	AdminsSourceGroup = "group"
+	// DefaultAdminsGroupSuffix is the default name suffix of the admins counterpart of an okta group
+	DefaultAdminsGroupSuffix = "-admins"
+
+	// DefaultNATSQueueSize is the default for the number of subscribers per subject and queue group
+	DefaultNATSQueueSize = 10
+)
+
+// Config is the gov-okta-addon configuration
+type Config struct {
+	Listen     string           `mapstructure:"listen"`
+	DryRun     bool             `mapstructure:"dryrun"`
+	SkipDelete bool             `mapstructure:"skip-delete"`
+	WhatIf     bool             `mapstructure:"what-if"`
+	AdminToken string           `mapstructure:"admin-token"`
+	Logging    LoggingConfig    `mapstructure:"logging"`
+	Audit      AuditConfig      `mapstructure:"audit"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	NATS       NATSConfig       `mapstructure:"nats"`
+	Okta       OktaConfig       `mapstructure:"okta"`
+	Governor   GovernorConfig   `mapstructure:"governor"`
+	Reconciler ReconcilerConfig `mapstructure:"reconciler"`
+	Eventlog   EventlogConfig   `mapstructure:"eventlog"`
+	Invariants InvariantsConfig `mapstructure:"invariants"`
+	Journal    JournalConfig    `mapstructure:"journal"`
+	Sync       SyncConfig       `mapstructure:"sync"`
+	Proxy      ProxyConfig      `mapstructure:"proxy"`
+	Pilot      PilotConfig      `mapstructure:"pilot"`
+	Changes    ChangesConfig    `mapstructure:"changes"`
+	Notify     NotifyConfig     `mapstructure:"notify"`
+	Metrics    MetricsConfig    `mapstructure:"metrics"`
+}
+
+const (
+	// LogFormatJSON logs json lines
+	LogFormatJSON = "json"
+	// LogFormatConsole logs human readable lines
+	LogFormatConsole = "console"
+)
+
+// LoggingConfig is the logging configuration
+type LoggingConfig struct {
+	Debug  bool   `mapstructure:"debug"`
+	Pretty bool   `mapstructure:"pretty"`
+	Format string `mapstructure:"format"`
+
+	// Sample logs the first SampleInitial lines with the same message and level each second, then every
+	// SampleThereafter line
+	Sample           bool `mapstructure:"sample"`
+	SampleInitial    int  `mapstructure:"sample-initial"`
+	SampleThereafter int  `mapstructure:"sample-thereafter"`
+
+	RedactEmails bool `mapstructure:"redact-emails"`
+	RedactPII    bool `mapstructure:"redact-pii"`
+}
+
+// AuditConfig is the audit log configuration
+type AuditConfig struct {
+	LogPath string `mapstructure:"log-path"`
+}
+
+// TracingConfig is the tracing configuration
+type TracingConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Provider    string `mapstructure:"provider"`
+	Endpoint    string `mapstructure:"endpoint"`
+	Environment string `mapstructure:"environment"`
+	Insecure    bool   `mapstructure:"insecure"`
+}
+
+// NATSConfig is the NATS connection and subscription configuration, a negative coalesce window disables
+// coalescing group reconcile requests and negative max reconnects reconnects forever
+type NATSConfig struct {
+	URL              string        `mapstructure:"url"`
+	CredsFile        string        `mapstructure:"creds-file"`
+	NKeyFile         string        `mapstructure:"nkey-file"`
+	Token            string        `mapstructure:"token"`
+	TokenFile        string        `mapstructure:"token-file"`
+	TLSCert          string        `mapstructure:"tls-cert"`
+	TLSKey           string        `mapstructure:"tls-key"`
+	TLSCA            string        `mapstructure:"tls-ca"`
+	SubjectPrefix    string        `mapstructure:"subject-prefix"`
+	QueueGroup       string        `mapstructure:"queue-group"`
+	QueueSize        int           `mapstructure:"queue-size"`
+	Subjects         []string      `mapstructure:"subjects"`
+	CoalesceWindow   time.Duration `mapstructure:"coalesce-window"`
+	ReconnectWait    time.Duration `mapstructure:"reconnect-wait"`
+	ReconnectMaxWait time.Duration `mapstructure:"reconnect-max-wait"`
+	MaxReconnects    int           `mapstructure:"max-reconnects"`
+}
+
+// OktaConfig is the okta client configuration, negative timeouts disable the deadline
+type OktaConfig struct {
+	URL         string        `mapstructure:"url"`
+	Token       string        `mapstructure:"token"`
+	NoCache     bool          `mapstructure:"nocache"`
+	CallTimeout time.Duration `mapstructure:"call-timeout"`
+	ListTimeout time.Duration `mapstructure:"list-timeout"`
+
+	PageRetries   int           `mapstructure:"page-retries"`
+	PageRetryWait time.Duration `mapstructure:"page-retry-wait"`
+	PartialPages  bool          `mapstructure:"partial-pages"`
+
+	SecondaryTokens []string `mapstructure:"secondary-tokens"`
+	TokenStrategy   string   `mapstructure:"token-strategy"`
+
+	UserMatchKey string `mapstructure:"user-match-key"`
+
+	NonHumanUserTypes []string            `mapstructure:"non-human-user-types"`
+	NonHumanAccounts  []AccountRuleConfig `mapstructure:"non-human-accounts"`
+
+	GroupNames        GroupNamesConfig `mapstructure:"group-names"`
+	GroupNameTemplate string           `mapstructure:"group-name-template"`
+
+	GroupDescriptionPrefix string `mapstructure:"group-description-prefix"`
+	GroupDescriptionSuffix string `mapstructure:"group-description-suffix"`
+}
+
+// GroupNamesConfig maps okta group names to governor group names when syncing groups to governor and governor
+// group names back to okta group names when the reconciler creates or updates okta groups
+type GroupNamesConfig struct {
+	ToGovernor []GroupNameRuleConfig `mapstructure:"to-governor"`
+	ToOkta     []GroupNameRuleConfig `mapstructure:"to-okta"`
+}
+
+// GroupNameRuleConfig renames groups matching the regular expression, the replacement can refer to the capture
+// groups of the pattern, ie. $1 or ${team}
+type GroupNameRuleConfig struct {
+	Pattern string `mapstructure:"pattern"`
+	Replace string `mapstructure:"replace"`
+}
+
+// AccountRuleConfig matches non-human okta accounts by a profile attribute, string values match one of the
+// values or end with one of the suffixes and an attribute without values or suffixes matches when it's set
+type AccountRuleConfig struct {
+	Attribute string   `mapstructure:"attribute"`
+	Values    []string `mapstructure:"values"`
+	Suffixes  []string `mapstructure:"suffixes"`
+}
+
+// NonHumanRules returns the rules matching non-human okta accounts, the user types are matched on the
+// userType profile attribute
+func (c OktaConfig) NonHumanRules() okta.AccountRules {
+	rules := okta.AccountRules{}
+
+	if len(c.NonHumanUserTypes) > 0 {
+		rules = append(rules, okta.AccountRule{Attribute: "userType", Values: c.NonHumanUserTypes})
+	}
+
+	for _, rc := range c.NonHumanAccounts {
+		rules = append(rules, okta.AccountRule{Attribute: rc.Attribute, Values: rc.Values, Suffixes: rc.Suffixes})
+	}
+
+	return rules
+}
+
+// GroupNameMapping compiles the okta group name mapping rules
+func (c OktaConfig) GroupNameMapping() (okta.GroupNameMapping, error) {
+	toGovernor, err := groupNameRules(c.GroupNames.ToGovernor)
+	if err != nil {
+		return okta.GroupNameMapping{}, err
+	}
+
+	toOkta, err := groupNameRules(c.GroupNames.ToOkta)
+	if err != nil {
+		return okta.GroupNameMapping{}, err
+	}
+
+	return okta.GroupNameMapping{ToGovernor: toGovernor, ToOkta: toOkta}, nil
+}
+
+// GroupDescriptionMarker returns the marker of the descriptions of the okta groups managed by governor
+func (c OktaConfig) GroupDescriptionMarker() okta.GroupDescriptionMarker {
+	return okta.GroupDescriptionMarker{Prefix: c.GroupDescriptionPrefix, Suffix: c.GroupDescriptionSuffix}
+}
+
+// ParseGroupNameTemplate parses the template of the okta group names of governor groups, nil when there's none
+func (c OktaConfig) ParseGroupNameTemplate() (*okta.GroupNameTemplate, error) {
+	if c.GroupNameTemplate == "" {
+		return nil, nil
+	}
+
+	return okta.ParseGroupNameTemplate(c.GroupNameTemplate)
+}
+
+func groupNameRules(rcs []GroupNameRuleConfig) ([]okta.GroupNameRule, error) {
+	rules := make([]okta.GroupNameRule, 0, len(rcs))
+
+	for _, rc := range rcs {
+		re, err := regexp.Compile(rc.Pattern)
+		if err != nil || rc.Pattern == "" {
+			return nil, fmt.Errorf("%w: %q", ErrOktaGroupNamePatternInvalid, rc.Pattern)
+		}
+
+		rules = append(rules, okta.GroupNameRule{Pattern: re, Replace: rc.Replace})
+	}
+
+	return rules, nil
+}
+
+// ProxyConfig is the outbound http proxy configuration, the password can be read from a file instead.
+// An empty url connects directly.
+type ProxyConfig struct {
+	URL          string   `mapstructure:"url"`
+	Username     string   `mapstructure:"username"`
+	Password     string   `mapstructure:"password"`
+	PasswordFile string   `mapstructure:"password-file"`
+	NoProxy      []string `mapstructure:"no-proxy"`
+}
+
+// GovernorConfig is the governor client configuration
+type GovernorConfig struct {
+	URL          string        `mapstructure:"url"`
+	ClientID     string        `mapstructure:"client-id"`
+	ClientSecret string        `mapstructure:"client-secret"`
+	TokenURL     string        `mapstructure:"token-url"`
+	Audience     string        `mapstructure:"audience"`
+	TokenSkew    time.Duration `mapstructure:"token-skew"`
+	ListCache    bool          `mapstructure:"list-cache"`
+
+	// Scopes replaces the governor scopes requested by a command, by command name (ie. serve or sync-groups)
+	Scopes map[string][]string `mapstructure:"scopes"`
+}
+
+// ReconcilerConfig is the reconciler loop configuration
+type ReconcilerConfig struct {
+	Interval                time.Duration `mapstructure:"interval"`
+	InitialDelay            time.Duration `mapstructure:"initial-delay"`
+	Jitter                  time.Duration `mapstructure:"jitter"`
+	RunOnStart              bool          `mapstructure:"run-on-start"`
+	Locking                 bool          `mapstructure:"locking"`
+	SnapshotShortCircuit    bool          `mapstructure:"snapshot-short-circuit"`
+	GroupScheduleResolution time.Duration `mapstructure:"group-schedule-resolution"`
+	OpTimeout               time.Duration `mapstructure:"op-timeout"`
+	BreakerFailures         int           `mapstructure:"breaker-failures"`
+	BreakerCooldown         time.Duration `mapstructure:"breaker-cooldown"`
+	QuarantineFailures      int           `mapstructure:"quarantine-failures"`
+	QuarantineBackoff       time.Duration `mapstructure:"quarantine-backoff"`
+	GroupDeleteGracePeriod  time.Duration `mapstructure:"group-delete-grace-period"`
+	OrphanCleanup           bool          `mapstructure:"orphan-cleanup"`
+	GroupOwners             bool          `mapstructure:"group-owners"`
+	UserGovernorID          bool          `mapstructure:"user-governor-id"`
+	PermanentUserDelete     bool          `mapstructure:"permanent-user-delete"`
+	OffboardRemoveGroups    bool          `mapstructure:"offboard-remove-groups"`
+	MemberChangesAsRequests bool          `mapstructure:"member-changes-as-requests"`
+	SyncUserEmail           bool          `mapstructure:"sync-user-email"`
+	VerifySampleSize        int           `mapstructure:"verify-sample-size"`
+	MembershipConcurrency   int           `mapstructure:"membership-concurrency"`
+	OktaRateLimit           float64       `mapstructure:"okta-rate-limit"`
+	RateLimitBurst          int           `mapstructure:"rate-limit-burst"`
+	UserSearch              string        `mapstructure:"user-search"`
+	UserStatuses            []string      `mapstructure:"user-statuses"`
+	ExcludeUserTypes        []string      `mapstructure:"exclude-user-types"`
+	PersistState            bool          `mapstructure:"persist-state"`
+	DefaultGroups           []string      `mapstructure:"default-groups"`
+	RecordOktaGroupIDs      bool          `mapstructure:"record-okta-group-ids"`
+
+	// UserCache bounds the cache of the governor users of group members
+	UserCache UserCacheConfig `mapstructure:"user-cache"`
+
+	// AppAssignments are the application assignment modes of github orgs, by org slug
+	AppAssignments map[string]string `mapstructure:"app-assignments"`
+	// AppAssignmentSettings are the okta group assignment settings of the application of github orgs, by org slug
+	AppAssignmentSettings map[string]AppAssignmentSettingsConfig `mapstructure:"app-assignment-settings"`
+	// PushGroups are the slugs of the github orgs the okta groups are pushed to as github teams
+	PushGroups []string `mapstructure:"push-groups"`
+	// UserStatusMap are the okta lifecycle states of governor user statuses, by governor status
+	UserStatusMap map[string]string `mapstructure:"user-status-map"`
+	// SourceOfTruth are the sides winning when governor and okta disagree, by resource type
+	SourceOfTruth map[string]string `mapstructure:"source-of-truth"`
+}
+
+// AppAssignmentSettingsConfig are the settings of the okta group assignments of an application, the priority is left
+// to okta when it's not set
+type AppAssignmentSettingsConfig struct {
+	Priority *int64                 `mapstructure:"priority"`
+	Profile  map[string]interface{} `mapstructure:"profile"`
+}
+
+// ListUsersOptions returns the okta options filtering the users listed by the reconciler loop
+func (c ReconcilerConfig) ListUsersOptions() []okta.ListUsersOption {
+	opts := []okta.ListUsersOption{}
+
+	if c.UserSearch != "" {
+		opts = append(opts, okta.WithUserSearch(c.UserSearch))
+	}
+
+	if len(c.UserStatuses) > 0 {
+		opts = append(opts, okta.WithUserStatuses(c.UserStatuses...))
+	}
+
+	if len(c.ExcludeUserTypes) > 0 {
+		opts = append(opts, okta.WithoutUserTypes(c.ExcludeUserTypes...))
+	}
+
+	return opts
+}
+
+// AppAssignmentModes returns the application assignment mode of the github orgs with an override
+func (c ReconcilerConfig) AppAssignmentModes() map[string]reconciler.AppAssignmentMode {
+	modes := make(map[string]reconciler.AppAssignmentMode, len(c.AppAssignments))
+
+	for org, mode := range c.AppAssignments {
+		modes[org] = reconciler.AppAssignmentMode(mode)
+	}
+
+	return modes
+}
+
+// AppGroupAssignments returns the okta group assignment settings of the application of the github orgs with settings
+func (c ReconcilerConfig) AppGroupAssignments() map[string]*okta.AppGroupAssignment {
+	settings := make(map[string]*okta.AppGroupAssignment, len(c.AppAssignmentSettings))
+
+	for org, s := range c.AppAssignmentSettings {
+		settings[org] = &okta.AppGroupAssignment{Priority: s.Priority, Profile: s.Profile}
+	}
+
+	return settings
+}
+
+// UserLifecycles returns the okta lifecycle state of the governor user statuses with an override
+func (c ReconcilerConfig) UserLifecycles() map[string]reconciler.UserLifecycle {
+	lifecycles := make(map[string]reconciler.UserLifecycle, len(c.UserStatusMap))
+
+	for status, l := range c.UserStatusMap {
+		lifecycles[strings.ToLower(status)] = reconciler.UserLifecycle(strings.ToLower(l))
+	}
+
+	return lifecycles
+}
+
+// ConflictPolicy returns the source of truth of the resource types with one
+func (c ReconcilerConfig) ConflictPolicy() reconciler.ConflictPolicy {
+	p := reconciler.ConflictPolicy{}
+
+	for resource, s := range c.SourceOfTruth {
+		sot := reconciler.SourceOfTruth(strings.ToLower(s))
+
+		switch strings.ToLower(resource) {
+		case reconciler.ConflictResourceGroups:
+			p.Groups = sot
+		case reconciler.ConflictResourceMembers:
+			p.Members = sot
+		case reconciler.ConflictResourceUsers:
+			p.Users = sot
+		}
+	}
+
+	return p
+}
+
+// PilotConfig is the pilot rollout configuration, only the groups and users of the cohorts are changed in okta
+// when it's enabled.  Cohorts can also be set with a "gov-okta-addon.cohort: <name>" line in a governor group note.
+type PilotConfig struct {
+	Enabled bool           `mapstructure:"enabled"`
+	Cohorts []CohortConfig `mapstructure:"cohorts"`
+}
+
+// CohortConfig is a pilot cohort, groups are governor group slugs or ids and users are governor user emails or ids
+type CohortConfig struct {
+	Name   string   `mapstructure:"name"`
+	Groups []string `mapstructure:"groups"`
+	Users  []string `mapstructure:"users"`
+}
+
+// ReconcilerCohorts returns the pilot cohorts for the reconciler, nil when the pilot is disabled
+func (c PilotConfig) ReconcilerCohorts() []reconciler.Cohort {
+	if !c.Enabled {
+		return nil
+	}
+
+	cohorts := make([]reconciler.Cohort, 0, len(c.Cohorts))
+
+	for _, cc := range c.Cohorts {
+		cohorts = append(cohorts, reconciler.Cohort{Name: cc.Name, Groups: cc.Groups, Users: cc.Users})
+	}
+
+	return cohorts
+}
+
+// MetricsConfig is the metrics configuration, the final metrics are pushed to the pushgateway on shutdown
+// when its url is set
+type MetricsConfig struct {
+	PushgatewayURL string `mapstructure:"pushgateway-url"`
+}
+
+// ChangesConfig is the configuration for publishing events for the changes made by the addon on NATS
+type ChangesConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Subject string `mapstructure:"subject"`
+
+	// DeprovisionSubject is the NATS subject okta user deprovisioning notifications are published on, empty
+	// disables them
+	DeprovisionSubject string `mapstructure:"deprovision-subject"`
+}
+
+// NotifyConfig is the configuration of the webhook notices of destructive changes
+type NotifyConfig struct {
+	// WebhookURL is the Slack compatible webhook the notices are posted to, empty disables them
+	WebhookURL string        `mapstructure:"webhook-url"`
+	EventTypes []string      `mapstructure:"event-types"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+}
+
+// EventlogConfig is the okta eventlog poller configuration
+type EventlogConfig struct {
+	Interval       time.Duration `mapstructure:"interval"`
+	Lookback       time.Duration `mapstructure:"lookback"`
+	ProfileUpdates bool          `mapstructure:"profile-updates"`
+}
+
+// InvariantsConfig is the reconciler invariants check configuration
+type InvariantsConfig struct {
+	Enabled              bool    `mapstructure:"enabled"`
+	UsersTolerance       float64 `mapstructure:"users-tolerance"`
+	GroupsTolerance      float64 `mapstructure:"groups-tolerance"`
+	MembershipsTolerance float64 `mapstructure:"memberships-tolerance"`
+}
+
+// JournalConfig is the change journal configuration
+type JournalConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// SyncConfig is the configuration for the sync commands
+type SyncConfig struct {
+	DryRun            bool            `mapstructure:"dryrun"`
+	SkipOktaUpdate    bool            `mapstructure:"skip-okta-update"`
+	SelectorPrefix    string          `mapstructure:"selector-prefix"`
+	SkipGroups        []string        `mapstructure:"skip-groups"`
+	Concurrency       int             `mapstructure:"concurrency"`
+	OktaRateLimit     float64         `mapstructure:"okta-rate-limit"`
+	GovernorRateLimit float64         `mapstructure:"governor-rate-limit"`
+	RateLimitBurst    int             `mapstructure:"rate-limit-burst"`
+	Backfill          BackfillConfig  `mapstructure:"backfill"`
+	Metadata          MetadataConfig  `mapstructure:"metadata"`
+	Admins            AdminsConfig    `mapstructure:"admins"`
+	Users             SyncUsersConfig `mapstructure:"users"`
+	OktaSearch        string          `mapstructure:"okta-search"`
+	OktaFilter        string          `mapstructure:"okta-filter"`
+	UserCache         UserCacheConfig `mapstructure:"user-cache"`
+}
+
+// UserCacheConfig bounds a cache of governor users, a size of 0 disables the cache and a ttl of 0 never expires
+// the cached users
+type UserCacheConfig struct {
+	Size int           `mapstructure:"size"`
+	TTL  time.Duration `mapstructure:"ttl"`
+}
+
+// Validate validates the user cache configuration
+func (c UserCacheConfig) Validate() error {
+	if c.Size < 0 || c.TTL < 0 {
+		return ErrUserCacheInvalid
+	}
+
+	return nil
+}
+
+// SyncUsersConfig is the configuration for the sync users command
+type SyncUsersConfig struct {
+	IncludeSuspended bool `mapstructure:"include-suspended"`
+}
+
+// BackfillConfig is the configuration for the backfill governor ids sync command
+type BackfillConfig struct {
+	SelectorPrefix string   `mapstructure:"selector-prefix"`
+	SkipGroups     []string `mapstructure:"skip-groups"`
+}
+
+// AdminsConfig is the configuration for importing the admins of the okta groups into the governor groups created from
+// them, the admins aren't imported when the source is empty
+type AdminsConfig struct {
+	Source      string `mapstructure:"source"`
+	GroupSuffix string `mapstructure:"group-suffix"`
+}
+
+// MetadataConfig is the configuration for syncing okta group metadata into governor groups
+type MetadataConfig struct {
+	Target     string   `mapstructure:"target"`
+	Attributes []string `mapstructure:"attributes"`
+	Strategy   string   `mapstructure:"strategy"`
+}
+
+// Load decodes the configuration from viper and applies the defaults to unset values, it should be
+// called once the command flags are bound
+func Load(v *viper.Viper) (*Config, error) {
+	cfg := &Config{}
+
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.setDefaults()
+
+	return cfg, nil
+}
+
+// setDefaults sets the defaults for values that are unset and have no meaningful zero value
+func (c *Config) setDefaults() {
+	if c.NATS.QueueSize == 0 {
+		c.NATS.QueueSize = DefaultNATSQueueSize
+	}
+
+	if len(c.NATS.Subjects) == 0 {
+		c.NATS.Subjects = srv.DefaultNATSSubjects
+	}
+
+	if c.Changes.Subject == "" {
+		c.Changes.Subject = changes.DefaultSubject
+	}
+
+	if c.NATS.CoalesceWindow == 0 {
+		c.NATS.CoalesceWindow = srv.DefaultCoalesceWindow
+	}
+
+	if c.NATS.ReconnectWait <= 0 {
+		c.NATS.ReconnectWait = srv.DefaultNATSReconnectWait
+	}
+
+	if c.NATS.ReconnectMaxWait <= 0 {
+		c.NATS.ReconnectMaxWait = srv.DefaultNATSReconnectMaxWait
+	}
+
+	if c.Okta.CallTimeout == 0 {
+		c.Okta.CallTimeout = okta.DefaultCallTimeout
+	}
+
+	if c.Okta.ListTimeout == 0 {
+		c.Okta.ListTimeout = okta.DefaultListTimeout
+	}
+
+	if c.Okta.TokenStrategy == "" {
+		c.Okta.TokenStrategy = okta.TokenStrategyRoundRobin
+	}
+
+	if c.Okta.UserMatchKey == "" {
+		c.Okta.UserMatchKey = string(okta.UserMatchKeyEmail)
+	}
+
+	if c.Governor.TokenSkew == 0 {
+		c.Governor.TokenSkew = govauth.DefaultSkew
+	}
+
+	if c.Reconciler.Interval == 0 {
+		c.Reconciler.Interval = reconciler.DefaultReconcileInterval
+	}
+
+	if c.Reconciler.OpTimeout == 0 {
+		c.Reconciler.OpTimeout = reconciler.DefaultOpTimeout
+	}
+
+	if c.Reconciler.BreakerFailures == 0 {
+		c.Reconciler.BreakerFailures = reconciler.DefaultBreakerFailures
+	}
+
+	if c.Reconciler.BreakerCooldown == 0 {
+		c.Reconciler.BreakerCooldown = reconciler.DefaultBreakerCooldown
+	}
+
+	if c.Reconciler.QuarantineFailures == 0 {
+		c.Reconciler.QuarantineFailures = reconciler.DefaultQuarantineFailures
+	}
+
+	if c.Reconciler.QuarantineBackoff == 0 {
+		c.Reconciler.QuarantineBackoff = reconciler.DefaultQuarantineBackoff
+	}
+
+	if c.Reconciler.GroupScheduleResolution == 0 {
+		c.Reconciler.GroupScheduleResolution = reconciler.DefaultGroupScheduleResolution
+	}
+
+	if c.Eventlog.Interval == 0 {
+		c.Eventlog.Interval = reconciler.DefaultEventlogPollerInterval
+	}
+
+	if c.Eventlog.Lookback == 0 {
+		c.Eventlog.Lookback = reconciler.DefaultEventlogColdStartLookback
+	}
+
+	if c.Journal.Retention == 0 {
+		c.Journal.Retention = journal.DefaultRetention
+	}
+
+	if c.Reconciler.MembershipConcurrency == 0 {
+		c.Reconciler.MembershipConcurrency = reconciler.DefaultMembershipConcurrency
+	}
+
+	if c.Reconciler.RateLimitBurst == 0 {
+		c.Reconciler.RateLimitBurst = 1
+	}
+
+	if c.Sync.Concurrency == 0 {
+		c.Sync.Concurrency = 1
+	}
+
+	if c.Sync.Metadata.Strategy == "" {
+		c.Sync.Metadata.Strategy = MetadataStrategyKeep
+	}
+
+	if c.Sync.RateLimitBurst == 0 {
+		c.Sync.RateLimitBurst = 1
+	}
+
+	if c.Sync.Admins.GroupSuffix == "" {
+		c.Sync.Admins.GroupSuffix = DefaultAdminsGroupSuffix
+	}
+}
+
+// ValidateServe validates the configuration used by the serve command
+func (c *Config) ValidateServe() error {
+	errs := []error{
+		c.NATS.Validate(),
+		c.Okta.Validate(),
+		c.Governor.Validate(),
+	}
+
+	if c.Audit.LogPath == "" {
+		errs = append(errs, ErrAuditLogPathRequired)
+	}
+
+	if c.Reconciler.Interval <= 0 || c.Reconciler.GroupScheduleResolution <= 0 ||
+		c.Eventlog.Interval <= 0 || c.Eventlog.Lookback <= 0 {
+		errs = append(errs, ErrIntervalInvalid)
+	}
+
+	if c.Pilot.Enabled {
+		for _, cohort := range c.Pilot.Cohorts {
+			if cohort.Name == "" {
+				errs = append(errs, ErrPilotCohortNameRequired)
+				break
+			}
+		}
+	}
+
+	if c.Reconciler.MembershipConcurrency < 1 {
+		errs = append(errs, ErrMembershipConcurrencyInvalid)
+	}
+
+	if c.Reconciler.OktaRateLimit < 0 || c.Reconciler.RateLimitBurst < 1 {
+		errs = append(errs, ErrReconcilerRateLimitInvalid)
+	}
+
+	errs = append(errs, c.Reconciler.UserCache.Validate())
+
+	for _, mode := range c.Reconciler.AppAssignmentModes() {
+		if !mode.Valid() {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrAppAssignmentModeInvalid, mode))
+			break
+		}
+	}
+
+	for org, s := range c.Reconciler.AppAssignmentSettings {
+		if s.Priority != nil && *s.Priority < 0 {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrAppAssignmentPriorityInvalid, org))
+			break
+		}
+	}
+
+	for _, l := range c.Reconciler.UserLifecycles() {
+		if !l.Valid() {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUserLifecycleInvalid, l))
+			break
+		}
+	}
+
+	for resource, s := range c.Reconciler.SourceOfTruth {
+		switch strings.ToLower(resource) {
+		case reconciler.ConflictResourceGroups, reconciler.ConflictResourceMembers, reconciler.ConflictResourceUsers:
+		default:
+			errs = append(errs, fmt.Errorf("%w: %s", ErrSourceOfTruthInvalid, resource))
+			continue
+		}
+
+		sot := reconciler.SourceOfTruth(strings.ToLower(s))
+
+		switch {
+		case !sot.Valid():
+			errs = append(errs, fmt.Errorf("%w: %s", ErrSourceOfTruthInvalid, s))
+		case sot == reconciler.SourceOfTruthNewest && strings.EqualFold(resource, reconciler.ConflictResourceMembers):
+			// there's no governor timestamp of the last membership change to compare with okta's
+			errs = append(errs, ErrSourceOfTruthMembersNewest)
+		}
+	}
+
+	if c.Notify.WebhookURL != "" {
+		if u, err := url.Parse(c.Notify.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, ErrNotifyWebhookURLInvalid)
+		}
+	}
+
+	if c.Invariants.Enabled {
+		for _, t := range []float64{c.Invariants.UsersTolerance, c.Invariants.GroupsTolerance, c.Invariants.MembershipsTolerance} {
+			if t < 0 || t > 1 {
+				errs = append(errs, ErrToleranceInvalid)
+				break
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateSync validates the configuration used by the sync commands
+func (c *Config) ValidateSync() error {
+	errs := []error{
+		c.Okta.Validate(),
+		c.Governor.Validate(),
+	}
+
+	if c.Changes.Enabled {
+		if c.NATS.URL == "" {
+			errs = append(errs, ErrNATSURLRequired)
+		}
+
+		errs = append(errs, c.NATS.ValidateAuth())
+	}
+
+	if c.Sync.Concurrency < 1 {
+		errs = append(errs, ErrConcurrencyInvalid)
+	}
+
+	if c.Sync.OktaRateLimit < 0 || c.Sync.GovernorRateLimit < 0 || c.Sync.RateLimitBurst < 1 {
+		errs = append(errs, ErrRateLimitInvalid)
+	}
+
+	if c.Sync.OktaSearch != "" && c.Sync.OktaFilter != "" {
+		errs = append(errs, ErrSyncOktaQueryConflict)
+	}
+
+	errs = append(errs, c.Sync.UserCache.Validate())
+
+	switch c.Sync.Metadata.Target {
+	case "", MetadataTargetDescription, MetadataTargetNote:
+	default:
+		errs = append(errs, ErrMetadataTargetInvalid)
+	}
+
+	switch c.Sync.Metadata.Strategy {
+	case MetadataStrategyKeep, MetadataStrategyReplace, MetadataStrategyAppend:
+	default:
+		errs = append(errs, ErrMetadataStrategyInvalid)
+	}
+
+	switch c.Sync.Admins.Source {
+	case "", AdminsSourceOwners, AdminsSourceGroup:
+	default:
+		errs = append(errs, ErrAdminsSourceInvalid)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate validates the NATS configuration
+func (c NATSConfig) Validate() error {
+	errs := []error{}
+
+	if c.URL == "" {
+		errs = append(errs, ErrNATSURLRequired)
+	}
+
+	if c.QueueSize < 1 {
+		errs = append(errs, ErrNATSQueueSizeInvalid)
+	}
+
+	errs = append(errs, c.ValidateAuth())
+
+	return errors.Join(errs...)
+}
+
+// ValidateAuth validates the NATS authentication configuration, at most one of the creds file, nkey file, token
+// and token file can be set and the tls client certificate needs both a certificate and a key
+func (c NATSConfig) ValidateAuth() error {
+	errs := []error{}
+
+	methods := 0
+
+	for _, v := range []string{c.CredsFile, c.NKeyFile, c.Token, c.TokenFile} {
+		if v != "" {
+			methods++
+		}
+	}
+
+	if methods > 1 {
+		errs = append(errs, ErrNATSAuthConflict)
+	}
+
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs = append(errs, ErrNATSTLSKeyPairIncomplete)
+	}
+
+	return errors.Join(errs...)
+}
+
+// HasAuth returns true when the NATS configuration has a way to authenticate
+func (c NATSConfig) HasAuth() bool {
+	return c.CredsFile != "" || c.NKeyFile != "" || c.Token != "" || c.TokenFile != "" || c.TLSCert != ""
+}
+
+// Validate validates the okta client configuration
+func (c OktaConfig) Validate() error {
+	errs := []error{}
+
+	if c.URL == "" {
+		errs = append(errs, ErrOktaURLRequired)
+	}
+
+	if c.Token == "" {
+		errs = append(errs, ErrOktaTokenRequired)
+	}
+
+	if c.TokenStrategy != okta.TokenStrategyRoundRobin && c.TokenStrategy != okta.TokenStrategyLeastUsed {
+		errs = append(errs, ErrOktaTokenStrategyInvalid)
+	}
+
+	if c.PageRetries < 0 {
+		errs = append(errs, ErrOktaPageRetriesInvalid)
+	}
+
+	if _, err := okta.ParseUserMatchKey(c.UserMatchKey); err != nil {
+		errs = append(errs, ErrOktaUserMatchKeyInvalid)
+	}
+
+	for _, rc := range c.NonHumanAccounts {
+		if rc.Attribute == "" {
+			errs = append(errs, ErrOktaAccountRuleAttributeRequired)
+			break
+		}
+	}
+
+	if _, err := c.GroupNameMapping(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := c.ParseGroupNameTemplate(); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrOktaGroupNameTemplateInvalid, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// NewProxy returns the configured outbound proxy, nil when no proxy is configured
+func (c ProxyConfig) NewProxy() (*proxy.Proxy, error) {
+	password := c.Password
+
+	if c.PasswordFile != "" {
+		b, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+
+		password = strings.TrimSpace(string(b))
+	}
+
+	return proxy.New(c.URL, c.Username, password, c.NoProxy)
+}
+
+// Validate validates the governor client configuration
+func (c GovernorConfig) Validate() error {
+	errs := []error{}
+
+	if c.URL == "" {
+		errs = append(errs, ErrGovernorURLRequired)
+	}
+
+	if c.ClientID == "" {
+		errs = append(errs, ErrGovernorClientIDRequired)
+	}
+
+	if c.ClientSecret == "" {
+		errs = append(errs, ErrGovernorClientSecretRequired)
+	}
+
+	if c.TokenURL == "" {
+		errs = append(errs, ErrGovernorClientTokenURLRequired)
+	}
+
+	if c.Audience == "" {
+		errs = append(errs, ErrGovernorClientAudienceRequired)
+	}
+
+	for cmd, scopes := range c.Scopes {
+		if len(scopes) == 0 || slices.Contains(scopes, "") {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrGovernorScopesEmpty, cmd))
+		}
+	}
+
+	return errors.Join(errs...)
+}
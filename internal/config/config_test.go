@@ -0,0 +1,532 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govauth"
+	"github.com/metal-toolbox/gov-okta-addon/internal/journal"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+	"github.com/metal-toolbox/gov-okta-addon/internal/srv"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   func(c *Config)
+	}{
+		{
+			name: "defaults",
+			want: func(c *Config) {
+				c.NATS.QueueSize = DefaultNATSQueueSize
+				c.NATS.Subjects = srv.DefaultNATSSubjects
+				c.NATS.CoalesceWindow = srv.DefaultCoalesceWindow
+				c.NATS.ReconnectWait = srv.DefaultNATSReconnectWait
+				c.NATS.ReconnectMaxWait = srv.DefaultNATSReconnectMaxWait
+				c.Okta.CallTimeout = okta.DefaultCallTimeout
+				c.Okta.ListTimeout = okta.DefaultListTimeout
+				c.Okta.TokenStrategy = okta.TokenStrategyRoundRobin
+				c.Okta.UserMatchKey = string(okta.UserMatchKeyEmail)
+				c.Governor.TokenSkew = govauth.DefaultSkew
+				c.Reconciler.Interval = reconciler.DefaultReconcileInterval
+				c.Reconciler.GroupScheduleResolution = reconciler.DefaultGroupScheduleResolution
+				c.Reconciler.OpTimeout = reconciler.DefaultOpTimeout
+				c.Reconciler.BreakerFailures = reconciler.DefaultBreakerFailures
+				c.Reconciler.BreakerCooldown = reconciler.DefaultBreakerCooldown
+				c.Reconciler.QuarantineFailures = reconciler.DefaultQuarantineFailures
+				c.Reconciler.QuarantineBackoff = reconciler.DefaultQuarantineBackoff
+				c.Reconciler.MembershipConcurrency = reconciler.DefaultMembershipConcurrency
+				c.Reconciler.RateLimitBurst = 1
+				c.Eventlog.Interval = reconciler.DefaultEventlogPollerInterval
+				c.Eventlog.Lookback = reconciler.DefaultEventlogColdStartLookback
+				c.Journal.Retention = journal.DefaultRetention
+				c.Sync.Concurrency = 1
+				c.Sync.RateLimitBurst = 1
+				c.Sync.Metadata.Strategy = MetadataStrategyKeep
+				c.Sync.Admins.GroupSuffix = DefaultAdminsGroupSuffix
+				c.Changes.Subject = changes.DefaultSubject
+			},
+		},
+		{
+			name: "values",
+			values: map[string]interface{}{
+				"dryrun":                     true,
+				"skip-delete":                true,
+				"nats.url":                   "nats://nats:4222",
+				"nats.queue-size":            3,
+				"nats.subjects":              "groups,users",
+				"nats.coalesce-window":       "-1s",
+				"nats.reconnect-wait":        "5s",
+				"nats.max-reconnects":        -1,
+				"nats.nkey-file":             "/etc/nats/user.nk",
+				"nats.tls-cert":              "/etc/nats/tls.crt",
+				"nats.tls-key":               "/etc/nats/tls.key",
+				"okta.url":                   "https://example.okta.com",
+				"okta.nocache":               true,
+				"okta.call-timeout":          "-1s",
+				"okta.user-match-key":        "login",
+				"governor.client-id":         "client",
+				"governor.token-skew":        "30s",
+				"reconciler.interval":        "5m",
+				"reconciler.user-statuses":   "ACTIVE,SUSPENDED",
+				"invariants.users-tolerance": 0.1,
+				"sync.concurrency":           4,
+				"sync.backfill.skip-groups":  []string{"Everyone"},
+				"changes.enabled":            true,
+			},
+			want: func(c *Config) {
+				c.DryRun = true
+				c.SkipDelete = true
+				c.NATS.URL = "nats://nats:4222"
+				c.NATS.QueueSize = 3
+				c.NATS.Subjects = []string{"groups", "users"}
+				c.NATS.CoalesceWindow = -time.Second
+				c.NATS.ReconnectWait = 5 * time.Second
+				c.NATS.ReconnectMaxWait = srv.DefaultNATSReconnectMaxWait
+				c.NATS.MaxReconnects = -1
+				c.NATS.NKeyFile = "/etc/nats/user.nk"
+				c.NATS.TLSCert = "/etc/nats/tls.crt"
+				c.NATS.TLSKey = "/etc/nats/tls.key"
+				c.Okta.URL = "https://example.okta.com"
+				c.Okta.NoCache = true
+				c.Okta.CallTimeout = -time.Second
+				c.Okta.ListTimeout = okta.DefaultListTimeout
+				c.Okta.TokenStrategy = okta.TokenStrategyRoundRobin
+				c.Okta.UserMatchKey = string(okta.UserMatchKeyLogin)
+				c.Governor.ClientID = "client"
+				c.Governor.TokenSkew = 30 * time.Second
+				c.Reconciler.Interval = 5 * time.Minute
+				c.Reconciler.UserStatuses = []string{"ACTIVE", "SUSPENDED"}
+				c.Reconciler.GroupScheduleResolution = reconciler.DefaultGroupScheduleResolution
+				c.Reconciler.OpTimeout = reconciler.DefaultOpTimeout
+				c.Reconciler.BreakerFailures = reconciler.DefaultBreakerFailures
+				c.Reconciler.BreakerCooldown = reconciler.DefaultBreakerCooldown
+				c.Reconciler.QuarantineFailures = reconciler.DefaultQuarantineFailures
+				c.Reconciler.QuarantineBackoff = reconciler.DefaultQuarantineBackoff
+				c.Reconciler.MembershipConcurrency = reconciler.DefaultMembershipConcurrency
+				c.Reconciler.RateLimitBurst = 1
+				c.Eventlog.Interval = reconciler.DefaultEventlogPollerInterval
+				c.Eventlog.Lookback = reconciler.DefaultEventlogColdStartLookback
+				c.Invariants.UsersTolerance = 0.1
+				c.Journal.Retention = journal.DefaultRetention
+				c.Sync.Concurrency = 4
+				c.Sync.RateLimitBurst = 1
+				c.Sync.Metadata.Strategy = MetadataStrategyKeep
+				c.Sync.Admins.GroupSuffix = DefaultAdminsGroupSuffix
+				c.Sync.Backfill.SkipGroups = []string{"Everyone"}
+				c.Changes.Enabled = true
+				c.Changes.Subject = changes.DefaultSubject
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+
+			for k, val := range tt.values {
+				v.Set(k, val)
+			}
+
+			want := &Config{}
+			tt.want(want)
+
+			got, err := Load(v)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func testConfig() *Config {
+	c := &Config{
+		Audit: AuditConfig{LogPath: "audit.log"},
+		NATS:  NATSConfig{URL: "nats://nats:4222"},
+		Okta:  OktaConfig{URL: "https://example.okta.com", Token: "token"},
+		Governor: GovernorConfig{
+			URL:          "https://governor",
+			ClientID:     "client",
+			ClientSecret: "secret",
+			TokenURL:     "https://hydra/oauth2/token",
+			Audience:     "https://governor",
+		},
+	}
+
+	c.setDefaults()
+
+	return c
+}
+
+func TestConfig_ValidateServe(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr []error
+	}{
+		{
+			name:   "valid",
+			modify: func(_ *Config) {},
+		},
+		{
+			name: "missing required values",
+			modify: func(c *Config) {
+				c.NATS.URL = ""
+				c.Okta.Token = ""
+				c.Governor.ClientSecret = ""
+				c.Audit.LogPath = ""
+			},
+			wantErr: []error{ErrNATSURLRequired, ErrOktaTokenRequired, ErrGovernorClientSecretRequired, ErrAuditLogPathRequired},
+		},
+		{
+			name:    "negative queue size",
+			modify:  func(c *Config) { c.NATS.QueueSize = -1 },
+			wantErr: []error{ErrNATSQueueSizeInvalid},
+		},
+		{
+			name: "conflicting nats auth",
+			modify: func(c *Config) {
+				c.NATS.CredsFile = "/etc/nats/user.creds"
+				c.NATS.Token = "token"
+				c.NATS.TLSCert = "/etc/nats/tls.crt"
+			},
+			wantErr: []error{ErrNATSAuthConflict, ErrNATSTLSKeyPairIncomplete},
+		},
+		{
+			name: "governor scopes",
+			modify: func(c *Config) {
+				c.Governor.Scopes = map[string][]string{"serve": {"read:governor:users"}, "sync-users": {}, "sync-orgs": {""}}
+			},
+			wantErr: []error{ErrGovernorScopesEmpty},
+		},
+		{
+			name:    "negative interval",
+			modify:  func(c *Config) { c.Eventlog.Interval = -time.Second },
+			wantErr: []error{ErrIntervalInvalid},
+		},
+		{
+			name:    "bad notify webhook url",
+			modify:  func(c *Config) { c.Notify.WebhookURL = "hooks.slack.com/services/x" },
+			wantErr: []error{ErrNotifyWebhookURLInvalid},
+		},
+		{
+			name: "bad tolerance",
+			modify: func(c *Config) {
+				c.Invariants.Enabled = true
+				c.Invariants.GroupsTolerance = 1.5
+			},
+			wantErr: []error{ErrToleranceInvalid},
+		},
+		{
+			name:   "bad tolerance ignored when invariants are disabled",
+			modify: func(c *Config) { c.Invariants.GroupsTolerance = 1.5 },
+		},
+		{
+			name: "app assignment modes",
+			modify: func(c *Config) {
+				c.Reconciler.AppAssignments = map[string]string{"legal-hold": "assign-only", "archived": "ignore", "main": "full"}
+			},
+		},
+		{
+			name:    "bad membership concurrency",
+			modify:  func(c *Config) { c.Reconciler.MembershipConcurrency = -1 },
+			wantErr: []error{ErrMembershipConcurrencyInvalid},
+		},
+		{
+			name:    "negative reconciler rate limit",
+			modify:  func(c *Config) { c.Reconciler.OktaRateLimit = -1 },
+			wantErr: []error{ErrReconcilerRateLimitInvalid},
+		},
+		{
+			name:    "negative reconciler user cache size",
+			modify:  func(c *Config) { c.Reconciler.UserCache.Size = -1 },
+			wantErr: []error{ErrUserCacheInvalid},
+		},
+		{
+			name:    "bad app assignment mode",
+			modify:  func(c *Config) { c.Reconciler.AppAssignments = map[string]string{"legal-hold": "never"} },
+			wantErr: []error{ErrAppAssignmentModeInvalid},
+		},
+		{
+			name: "app assignment settings",
+			modify: func(c *Config) {
+				c.Reconciler.AppAssignmentSettings = map[string]AppAssignmentSettingsConfig{"main": {Priority: okt.Int64Ptr(0)}}
+			},
+		},
+		{
+			name: "bad app assignment priority",
+			modify: func(c *Config) {
+				c.Reconciler.AppAssignmentSettings = map[string]AppAssignmentSettingsConfig{"main": {Priority: okt.Int64Ptr(-1)}}
+			},
+			wantErr: []error{ErrAppAssignmentPriorityInvalid},
+		},
+		{
+			name:   "user status map",
+			modify: func(c *Config) { c.Reconciler.UserStatusMap = map[string]string{"suspended": "DEPROVISIONED"} },
+		},
+		{
+			name:    "bad user status lifecycle",
+			modify:  func(c *Config) { c.Reconciler.UserStatusMap = map[string]string{"suspended": "locked"} },
+			wantErr: []error{ErrUserLifecycleInvalid},
+		},
+		{
+			name:   "source of truth",
+			modify: func(c *Config) { c.Reconciler.SourceOfTruth = map[string]string{"members": "okta", "users": "Newest"} },
+		},
+		{
+			name:    "bad source of truth",
+			modify:  func(c *Config) { c.Reconciler.SourceOfTruth = map[string]string{"members": "slack"} },
+			wantErr: []error{ErrSourceOfTruthInvalid},
+		},
+		{
+			name:    "newest members source of truth",
+			modify:  func(c *Config) { c.Reconciler.SourceOfTruth = map[string]string{"Members": "newest"} },
+			wantErr: []error{ErrSourceOfTruthMembersNewest},
+		},
+		{
+			name:    "bad source of truth resource",
+			modify:  func(c *Config) { c.Reconciler.SourceOfTruth = map[string]string{"apps": "okta"} },
+			wantErr: []error{ErrSourceOfTruthInvalid},
+		},
+		{
+			name: "pilot cohort without a name",
+			modify: func(c *Config) {
+				c.Pilot.Enabled = true
+				c.Pilot.Cohorts = []CohortConfig{{Groups: []string{"group-a"}}}
+			},
+			wantErr: []error{ErrPilotCohortNameRequired},
+		},
+		{
+			name:    "non-human account rule without an attribute",
+			modify:  func(c *Config) { c.Okta.NonHumanAccounts = []AccountRuleConfig{{Values: []string{"true"}}} },
+			wantErr: []error{ErrOktaAccountRuleAttributeRequired},
+		},
+		{
+			name: "group name rule with an invalid pattern",
+			modify: func(c *Config) {
+				c.Okta.GroupNames.ToOkta = []GroupNameRuleConfig{{Pattern: "^team-(", Replace: "$1"}}
+			},
+			wantErr: []error{ErrOktaGroupNamePatternInvalid},
+		},
+		{
+			name: "group name rule without a pattern",
+			modify: func(c *Config) {
+				c.Okta.GroupNames.ToGovernor = []GroupNameRuleConfig{{Replace: "$1"}}
+			},
+			wantErr: []error{ErrOktaGroupNamePatternInvalid},
+		},
+		{
+			name:    "group name template without fields",
+			modify:  func(c *Config) { c.Okta.GroupNameTemplate = "governor" },
+			wantErr: []error{ErrOktaGroupNameTemplateInvalid},
+		},
+		{
+			name:   "group name template",
+			modify: func(c *Config) { c.Okta.GroupNameTemplate = "gov-{{ .Slug }}" },
+		},
+		{
+			name: "group name rules",
+			modify: func(c *Config) {
+				c.Okta.GroupNames.ToGovernor = []GroupNameRuleConfig{{Pattern: "^team-(.+)$", Replace: "$1"}}
+				c.Okta.GroupNames.ToOkta = []GroupNameRuleConfig{{Pattern: "^(.+)$", Replace: "team-$1"}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testConfig()
+			tt.modify(c)
+
+			err := c.ValidateServe()
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			for _, e := range tt.wantErr {
+				assert.ErrorIs(t, err, e)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateSync(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr []error
+	}{
+		{
+			name: "valid without nats or audit",
+			modify: func(c *Config) {
+				c.NATS.URL = ""
+				c.Audit.LogPath = ""
+			},
+		},
+		{
+			name:    "missing okta url",
+			modify:  func(c *Config) { c.Okta.URL = "" },
+			wantErr: []error{ErrOktaURLRequired},
+		},
+		{
+			name:    "bad concurrency",
+			modify:  func(c *Config) { c.Sync.Concurrency = -1 },
+			wantErr: []error{ErrConcurrencyInvalid},
+		},
+		{
+			name:    "negative rate limit",
+			modify:  func(c *Config) { c.Sync.OktaRateLimit = -1 },
+			wantErr: []error{ErrRateLimitInvalid},
+		},
+		{
+			name: "okta search and filter",
+			modify: func(c *Config) {
+				c.Sync.OktaSearch = `profile.department eq "Engineering"`
+				c.Sync.OktaFilter = `status eq "ACTIVE"`
+			},
+			wantErr: []error{ErrSyncOktaQueryConflict},
+		},
+		{
+			name:    "negative user cache ttl",
+			modify:  func(c *Config) { c.Sync.UserCache.TTL = -time.Minute },
+			wantErr: []error{ErrUserCacheInvalid},
+		},
+		{
+			name:    "bad okta token strategy",
+			modify:  func(c *Config) { c.Okta.TokenStrategy = "random" },
+			wantErr: []error{ErrOktaTokenStrategyInvalid},
+		},
+		{
+			name:    "negative okta page retries",
+			modify:  func(c *Config) { c.Okta.PageRetries = -1 },
+			wantErr: []error{ErrOktaPageRetriesInvalid},
+		},
+		{
+			name:    "invalid okta user match key",
+			modify:  func(c *Config) { c.Okta.UserMatchKey = "name" },
+			wantErr: []error{ErrOktaUserMatchKeyInvalid},
+		},
+		{
+			name: "publishing changes without nats",
+			modify: func(c *Config) {
+				c.NATS.URL = ""
+				c.Changes.Enabled = true
+			},
+			wantErr: []error{ErrNATSURLRequired},
+		},
+		{
+			name: "publishing changes with conflicting nats auth",
+			modify: func(c *Config) {
+				c.NATS.URL = "nats://nats:4222"
+				c.NATS.NKeyFile = "/etc/nats/user.nk"
+				c.NATS.TokenFile = "/etc/nats/token"
+				c.Changes.Enabled = true
+			},
+			wantErr: []error{ErrNATSAuthConflict},
+		},
+		{
+			name: "bad metadata target and strategy",
+			modify: func(c *Config) {
+				c.Sync.Metadata.Target = "notes"
+				c.Sync.Metadata.Strategy = "merge"
+			},
+			wantErr: []error{ErrMetadataTargetInvalid, ErrMetadataStrategyInvalid},
+		},
+		{
+			name: "bad admins source",
+			modify: func(c *Config) {
+				c.Sync.Admins.Source = "managers"
+			},
+			wantErr: []error{ErrAdminsSourceInvalid},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testConfig()
+			tt.modify(c)
+
+			err := c.ValidateSync()
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			for _, e := range tt.wantErr {
+				assert.ErrorIs(t, err, e)
+			}
+		})
+	}
+}
+
+func TestReconcilerConfig_ListUsersOptions(t *testing.T) {
+	assert.Empty(t, ReconcilerConfig{}.ListUsersOptions())
+
+	opts := ReconcilerConfig{
+		UserSearch:       `profile.department eq "Engineering"`,
+		UserStatuses:     []string{"ACTIVE", "SUSPENDED"},
+		ExcludeUserTypes: []string{"service"},
+	}.ListUsersOptions()
+
+	assert.Len(t, opts, 3)
+}
+
+func TestPilotConfig_ReconcilerCohorts(t *testing.T) {
+	assert.Nil(t, PilotConfig{Cohorts: []CohortConfig{{Name: "wave-1"}}}.ReconcilerCohorts())
+	assert.Equal(t, []reconciler.Cohort{}, PilotConfig{Enabled: true}.ReconcilerCohorts())
+	assert.Equal(t,
+		[]reconciler.Cohort{{Name: "wave-1", Groups: []string{"group-a"}, Users: []string{"alice@example.com"}}},
+		PilotConfig{Enabled: true, Cohorts: []CohortConfig{{Name: "wave-1", Groups: []string{"group-a"}, Users: []string{"alice@example.com"}}}}.ReconcilerCohorts(),
+	)
+}
+
+func TestOktaConfig_NonHumanRules(t *testing.T) {
+	assert.Equal(t, okta.AccountRules{}, OktaConfig{}.NonHumanRules())
+	assert.Equal(t,
+		okta.AccountRules{
+			{Attribute: "userType", Values: []string{"service"}},
+			{Attribute: "email", Suffixes: []string{"@bots.example.com"}},
+		},
+		OktaConfig{
+			NonHumanUserTypes: []string{"service"},
+			NonHumanAccounts:  []AccountRuleConfig{{Attribute: "email", Suffixes: []string{"@bots.example.com"}}},
+		}.NonHumanRules(),
+	)
+}
+
+func TestProxyConfig_NewProxy(t *testing.T) {
+	p, err := ProxyConfig{}.NewProxy()
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	pwFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(pwFile, []byte("secret\n"), 0o600))
+
+	p, err = ProxyConfig{
+		URL:          "http://proxy.example.com:3128",
+		Username:     "user",
+		Password:     "ignored",
+		PasswordFile: pwFile,
+	}.NewProxy()
+	require.NoError(t, err)
+
+	u, err := p.URL(&url.URL{Scheme: "https", Host: "example.okta.com"})
+	require.NoError(t, err)
+
+	pw, _ := u.User.Password()
+	assert.Equal(t, "secret", pw)
+
+	_, err = ProxyConfig{URL: "http://proxy.example.com:3128", PasswordFile: filepath.Join(t.TempDir(), "missing")}.NewProxy()
+	assert.Error(t, err)
+}
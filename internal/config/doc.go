@@ -0,0 +1,2 @@
+// Package config is the typed gov-okta-addon configuration, decoded once from viper at startup
+package config
@@ -0,0 +1,81 @@
+package config
+
+import "errors"
+
+var (
+	// ErrNATSURLRequired is returned when a NATS url is missing
+	ErrNATSURLRequired = errors.New("nats url is required and cannot be empty")
+	// ErrNATSQueueSizeInvalid is returned when the NATS queue size is less than one
+	ErrNATSQueueSizeInvalid = errors.New("nats queue size must be at least 1")
+	// ErrNATSAuthConflict is returned when more than one of the NATS creds file, nkey file, token and token file is set
+	ErrNATSAuthConflict = errors.New("only one of the nats creds file, nkey file, token and token file can be set")
+	// ErrNATSTLSKeyPairIncomplete is returned when only one of the NATS tls client certificate and key is set
+	ErrNATSTLSKeyPairIncomplete = errors.New("nats tls client certificate and key must be set together")
+	// ErrOktaURLRequired is returned when an Okta URL is missing
+	ErrOktaURLRequired = errors.New("okta url is required and cannot be empty")
+	// ErrOktaTokenRequired is returned when an Okta token is missing
+	ErrOktaTokenRequired = errors.New("okta token is required and cannot be empty")
+	// ErrOktaTokenStrategyInvalid is returned when the okta token selection strategy is unknown
+	ErrOktaTokenStrategyInvalid = errors.New("okta token strategy must be round-robin or least-used")
+	// ErrOktaPageRetriesInvalid is returned when the number of okta listing page retries is negative
+	ErrOktaPageRetriesInvalid = errors.New("okta page retries cannot be negative")
+	// ErrOktaUserMatchKeyInvalid is returned when the okta user matching key is unknown
+	ErrOktaUserMatchKeyInvalid = errors.New("okta user match key must be email, login or externalId")
+	// ErrOktaAccountRuleAttributeRequired is returned when a non-human okta account rule has no attribute
+	ErrOktaAccountRuleAttributeRequired = errors.New("okta non-human account rules must have an attribute")
+	// ErrOktaGroupNamePatternInvalid is returned when an okta group name mapping pattern is empty or doesn't compile
+	ErrOktaGroupNamePatternInvalid = errors.New("okta group name patterns must be valid regular expressions")
+	// ErrOktaGroupNameTemplateInvalid is returned when the okta group name template is invalid
+	ErrOktaGroupNameTemplateInvalid = errors.New("okta group name template must use the governor group id, name or slug")
+	// ErrGovernorURLRequired is returned when a governor URL is missing
+	ErrGovernorURLRequired = errors.New("governor url is required and cannot be empty")
+	// ErrGovernorClientIDRequired is returned when a governor client id is missing
+	ErrGovernorClientIDRequired = errors.New("governor oauth client id is required and cannot be empty")
+	// ErrGovernorClientSecretRequired is returned when a governor client secret is missing
+	ErrGovernorClientSecretRequired = errors.New("governor oauth client secret is required and cannot be empty")
+	// ErrGovernorClientTokenURLRequired is returned when a governor token url is missing
+	ErrGovernorClientTokenURLRequired = errors.New("governor oauth client token url is required and cannot be empty")
+	// ErrGovernorClientAudienceRequired is returned when a governor client audience is missing
+	ErrGovernorClientAudienceRequired = errors.New("governor oauth client audience is required and cannot be empty")
+	// ErrGovernorScopesEmpty is returned when the governor scopes of a command are configured without a scope
+	ErrGovernorScopesEmpty = errors.New("governor scopes of a command cannot be empty")
+	// ErrAuditLogPathRequired is returned when the audit log path is missing
+	ErrAuditLogPathRequired = errors.New("audit log path is required and cannot be empty")
+	// ErrIntervalInvalid is returned when a reconciler or eventlog interval is not positive
+	ErrIntervalInvalid = errors.New("intervals must be greater than 0")
+	// ErrToleranceInvalid is returned when an invariant tolerance is not between 0 and 1
+	ErrToleranceInvalid = errors.New("invariant tolerances must be between 0 and 1")
+	// ErrAppAssignmentModeInvalid is returned when an application assignment mode is unknown
+	ErrAppAssignmentModeInvalid = errors.New("application assignment modes must be full, assign-only or ignore")
+	// ErrAppAssignmentPriorityInvalid is returned when an application group assignment priority is negative
+	ErrAppAssignmentPriorityInvalid = errors.New("application group assignment priority must not be negative")
+	// ErrUserLifecycleInvalid is returned when the okta lifecycle state of a governor user status is unknown
+	ErrUserLifecycleInvalid = errors.New("user status lifecycles must be skip, keep, active, suspended, deprovisioned or staged")
+	// ErrSourceOfTruthInvalid is returned when the source of truth of a resource type is unknown
+	ErrSourceOfTruthInvalid = errors.New("sources of truth must be governor, okta or newest for groups, members or users")
+	// ErrSourceOfTruthMembersNewest is returned when the source of truth of members is newest, governor doesn't
+	// change the group updated_at when members are added
+	ErrSourceOfTruthMembersNewest = errors.New("the source of truth of members can't be newest")
+	// ErrPilotCohortNameRequired is returned when a pilot cohort has no name
+	ErrPilotCohortNameRequired = errors.New("pilot cohorts must have a name")
+	// ErrConcurrencyInvalid is returned when the sync concurrency is less than one
+	ErrConcurrencyInvalid = errors.New("sync concurrency must be at least 1")
+	// ErrRateLimitInvalid is returned when a sync rate limit is negative or the burst is less than one
+	ErrRateLimitInvalid = errors.New("sync rate limits cannot be negative and the burst must be at least 1")
+	// ErrMembershipConcurrencyInvalid is returned when the reconciler membership concurrency is less than one
+	ErrMembershipConcurrencyInvalid = errors.New("reconciler membership concurrency must be at least 1")
+	// ErrReconcilerRateLimitInvalid is returned when the reconciler okta rate limit is negative or the burst is less than one
+	ErrReconcilerRateLimitInvalid = errors.New("reconciler okta rate limit cannot be negative and the burst must be at least 1")
+	// ErrUserCacheInvalid is returned when the governor user cache size or ttl is negative
+	ErrUserCacheInvalid = errors.New("user cache size and ttl cannot be negative")
+	// ErrSyncOktaQueryConflict is returned when the sync okta users search and filter are both set
+	ErrSyncOktaQueryConflict = errors.New("sync okta search and okta filter cannot be used together")
+	// ErrMetadataTargetInvalid is returned when the group metadata sync target is unknown
+	ErrMetadataTargetInvalid = errors.New("group metadata target must be empty, description or note")
+	// ErrMetadataStrategyInvalid is returned when the group metadata conflict strategy is unknown
+	ErrMetadataStrategyInvalid = errors.New("group metadata strategy must be keep, replace or append")
+	// ErrAdminsSourceInvalid is returned when the source of the imported okta group admins is unknown
+	ErrAdminsSourceInvalid = errors.New("group admins source must be empty, owners or group")
+	// ErrNotifyWebhookURLInvalid is returned when the notification webhook url isn't an http or https url
+	ErrNotifyWebhookURLInvalid = errors.New("notify webhook url must be an http or https url")
+)
@@ -0,0 +1,3 @@
+// Package govauth authenticates the requests of the governor client with a client credentials token that is
+// refreshed before it expires and shared between concurrent requests
+package govauth
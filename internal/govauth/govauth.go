@@ -0,0 +1,111 @@
+package govauth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultSkew is the default for how long before it expires a token is refreshed
+const DefaultSkew = time.Minute
+
+// Tokener fetches a new token, ie. a *clientcredentials.Config
+type Tokener interface {
+	Token(ctx context.Context) (*oauth2.Token, error)
+}
+
+// TokenSource caches the token of a Tokener and refreshes it the skew before it expires, so a request doesn't
+// go out with a token that expires in flight.  Concurrent callers share a single refresh.  It's safe to
+// share between goroutines.
+type TokenSource struct {
+	tokener Tokener
+	skew    time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// NewTokenSource returns a token source refreshing the token of the tokener the skew before it expires, a
+// negative skew only refreshes expired tokens
+func NewTokenSource(t Tokener, skew time.Duration) *TokenSource {
+	if skew < 0 {
+		skew = 0
+	}
+
+	return &TokenSource{
+		tokener: t,
+		skew:    skew,
+		now:     time.Now,
+	}
+}
+
+// Token returns the cached token, refreshing it if it expires within the skew.  The lock is held while
+// refreshing, so callers waiting on it get the refreshed token instead of fetching their own.
+func (s *TokenSource) Token(ctx context.Context) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.valid() {
+		return s.token, nil
+	}
+
+	t, err := s.tokener.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.token = t
+
+	return t, nil
+}
+
+// valid returns true if the cached token doesn't expire within the skew, tokens without an expiry don't expire
+func (s *TokenSource) valid() bool {
+	if s.token == nil || s.token.AccessToken == "" {
+		return false
+	}
+
+	if s.token.Expiry.IsZero() {
+		return true
+	}
+
+	return s.now().Add(s.skew).Before(s.token.Expiry)
+}
+
+// transport is a http.RoundTripper that sets the authorization header of each request from the token source
+type transport struct {
+	source *TokenSource
+	next   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	// a RoundTripper must not modify the request
+	authed := req.Clone(req.Context())
+	token.SetAuthHeader(authed)
+
+	return t.next.RoundTrip(authed)
+}
+
+// HTTPClient returns a copy of the http client with its requests authenticated by the token source, replacing
+// the authorization header set by the governor client
+func HTTPClient(c *http.Client, s *TokenSource) *http.Client {
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	authed := *c
+	authed.Transport = &transport{source: s, next: next}
+
+	return &authed
+}
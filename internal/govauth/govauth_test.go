@@ -0,0 +1,167 @@
+package govauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// countingTokener returns a new token expiring after ttl on each call
+type countingTokener struct {
+	ttl   time.Duration
+	err   error
+	calls atomic.Int32
+}
+
+func (t *countingTokener) Token(_ context.Context) (*oauth2.Token, error) {
+	n := t.calls.Add(1)
+
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	// make concurrent callers pile up behind the refresh
+	time.Sleep(10 * time.Millisecond)
+
+	return &oauth2.Token{
+		AccessToken: fmt.Sprintf("token-%d", n),
+		TokenType:   "bearer",
+		Expiry:      time.Now().Add(t.ttl),
+	}, nil
+}
+
+func TestTokenSource_Token(t *testing.T) {
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		skew      time.Duration
+		elapsed   time.Duration
+		wantToken string
+	}{
+		{name: "valid token is reused", ttl: time.Hour, skew: time.Minute, elapsed: 30 * time.Minute, wantToken: "token-1"},
+		{name: "token expiring within the skew is refreshed", ttl: time.Hour, skew: time.Minute, elapsed: 59*time.Minute + 30*time.Second, wantToken: "token-2"},
+		{name: "expired token is refreshed", ttl: time.Hour, elapsed: 2 * time.Hour, wantToken: "token-2"},
+		{name: "negative skew only refreshes expired tokens", ttl: time.Hour, skew: -time.Minute, elapsed: 59*time.Minute + 30*time.Second, wantToken: "token-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewTokenSource(&countingTokener{ttl: tt.ttl}, tt.skew)
+
+			tok, err := s.Token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "token-1", tok.AccessToken)
+
+			s.now = func() time.Time { return time.Now().Add(tt.elapsed) }
+
+			tok, err = s.Token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantToken, tok.AccessToken)
+		})
+	}
+}
+
+func TestTokenSource_TokenError(t *testing.T) {
+	errBoom := errors.New("boom") //nolint:goerr113
+
+	s := NewTokenSource(&countingTokener{err: errBoom}, DefaultSkew)
+
+	_, err := s.Token(context.Background())
+	assert.ErrorIs(t, err, errBoom)
+}
+
+func TestTokenSource_TokenConcurrent(t *testing.T) {
+	tokener := &countingTokener{ttl: time.Hour}
+	s := NewTokenSource(tokener, DefaultSkew)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			tok, err := s.Token(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "token-1", tok.AccessToken)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), tokener.calls.Load())
+}
+
+// TestHTTPClient_governor hammers a governor client concurrently and checks every request is authenticated with
+// the single token fetched by the token source
+func TestHTTPClient_governor(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		authz = map[string]int{}
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"governor-client-token","token_type":"bearer","expires_in":3600}`)
+
+			return
+		}
+
+		mu.Lock()
+		authz[r.Header.Get("Authorization")]++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[]`)
+	}))
+	defer ts.Close()
+
+	creds := &clientcredentials.Config{
+		ClientID:       "client",
+		ClientSecret:   "secret",
+		TokenURL:       ts.URL + "/oauth2/token",
+		EndpointParams: url.Values{"audience": {"test"}},
+	}
+
+	tokener := &countingTokener{ttl: time.Hour}
+
+	gc, err := governor.NewClient(
+		governor.WithURL(ts.URL),
+		governor.WithClientCredentialConfig(creds),
+		governor.WithHTTPClient(HTTPClient(&http.Client{Timeout: 10 * time.Second}, NewTokenSource(tokener, DefaultSkew))),
+	)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := gc.Organizations(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), tokener.calls.Load())
+	assert.Equal(t, map[string]int{"Bearer token-1": 50}, authz)
+}
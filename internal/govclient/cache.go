@@ -0,0 +1,181 @@
+package govclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// listPathSuffixes are the suffixes of the governor list endpoints whose responses are cached
+var listPathSuffixes = []string{"/groups", "/organizations", "/users"}
+
+var listCacheRequestsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Subsystem: "gov_okta_addon",
+		Name:      "governor_list_cache_requests_total",
+		Help:      "Total count of governor list requests by cache result, hit when governor responded not modified.",
+	},
+	[]string{"result"},
+)
+
+// ListCache caches the responses of the governor group, organization and user list endpoints with their ETag and
+// Last-Modified validators.  The cached lists are revalidated with conditional requests, so a list that didn't change
+// costs governor a 304 instead of the whole list.  It's safe to share between goroutines.
+type ListCache struct {
+	mu      sync.Mutex
+	entries map[string]*listCacheEntry
+}
+
+// listCacheEntry is a cached list response
+type listCacheEntry struct {
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// NewListCache returns an empty governor list cache
+func NewListCache() *ListCache {
+	return &ListCache{entries: map[string]*listCacheEntry{}}
+}
+
+func (c *ListCache) get(key string) *listCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.entries[key]
+}
+
+func (c *ListCache) set(key string, e *listCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e == nil {
+		delete(c.entries, key)
+		return
+	}
+
+	c.entries[key] = e
+}
+
+// cacheTransport sends conditional requests for the cached governor lists and serves the cached list when governor
+// responds not modified
+type cacheTransport struct {
+	cache *ListCache
+	next  http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || !isListPath(req.URL.Path) {
+		return t.next.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	cached := t.cache.get(key)
+
+	if cached != nil {
+		req = req.Clone(req.Context())
+
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		listCacheRequestsCounter.WithLabelValues("hit").Inc()
+
+		return cachedResponse(req, resp, cached), nil
+	case resp.StatusCode != http.StatusOK:
+		return resp, nil
+	}
+
+	listCacheRequestsCounter.WithLabelValues("miss").Inc()
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		t.cache.set(key, nil)
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.cache.set(key, &listCacheEntry{
+		etag:         etag,
+		lastModified: lastModified,
+		header:       resp.Header.Clone(),
+		body:         body,
+	})
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+// cachedResponse returns the cached list as the 200 response of the request
+func cachedResponse(req *http.Request, notModified *http.Response, e *listCacheEntry) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// isListPath returns true if the path is a governor list endpoint
+func isListPath(p string) bool {
+	p = strings.TrimSuffix(p, "/")
+
+	for _, s := range listPathSuffixes {
+		if strings.HasSuffix(p, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HTTPClient returns a copy of the http client caching the governor list responses in the cache.  A nil cache
+// returns the client unchanged.
+func HTTPClient(c *http.Client, cache *ListCache) *http.Client {
+	if cache == nil {
+		return c
+	}
+
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	cached := *c
+	cached.Transport = &cacheTransport{cache: cache, next: next}
+
+	return &cached
+}
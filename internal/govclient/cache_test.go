@@ -0,0 +1,134 @@
+package govclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClient_listCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		etag         string
+		lastModified string
+		wantCached   bool
+	}{
+		{name: "etag", path: "/api/v1alpha1/groups", etag: `"v1"`, wantCached: true},
+		{name: "last modified", path: "/api/v1beta1/users?next_cursor=abc", lastModified: "Mon, 01 Jan 2024 12:00:00 GMT", wantCached: true},
+		{name: "no validators", path: "/api/v1alpha1/organizations"},
+		{name: "not a list", path: "/api/v1alpha1/groups/group-1", etag: `"v1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conditional []string
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if v := r.Header.Get("If-None-Match") + r.Header.Get("If-Modified-Since"); v != "" {
+					conditional = append(conditional, v)
+
+					w.WriteHeader(http.StatusNotModified)
+
+					return
+				}
+
+				if tt.etag != "" {
+					w.Header().Set("ETag", tt.etag)
+				}
+
+				if tt.lastModified != "" {
+					w.Header().Set("Last-Modified", tt.lastModified)
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`[{"id":"group-1"}]`))
+			}))
+			defer ts.Close()
+
+			hc := HTTPClient(ts.Client(), NewListCache())
+
+			get := func() (int, string) {
+				req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, ts.URL+tt.path, nil)
+				require.NoError(t, err)
+
+				resp, err := hc.Do(req)
+				require.NoError(t, err)
+
+				defer resp.Body.Close()
+
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				return resp.StatusCode, string(body)
+			}
+
+			for i := 0; i < 2; i++ {
+				status, body := get()
+				assert.Equal(t, http.StatusOK, status)
+				assert.JSONEq(t, `[{"id":"group-1"}]`, body)
+			}
+
+			if !tt.wantCached {
+				assert.Empty(t, conditional)
+				return
+			}
+
+			assert.Equal(t, []string{tt.etag + tt.lastModified}, conditional)
+		})
+	}
+}
+
+func TestHTTPClient_listCacheChanged(t *testing.T) {
+	version := "v1"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + version + `"`
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(version))
+	}))
+	defer ts.Close()
+
+	hc := HTTPClient(ts.Client(), NewListCache())
+
+	get := func() string {
+		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, ts.URL+"/api/v1alpha1/groups", nil)
+		require.NoError(t, err)
+
+		resp, err := hc.Do(req)
+		require.NoError(t, err)
+
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return string(body)
+	}
+
+	assert.Equal(t, "v1", get())
+	assert.Equal(t, "v1", get())
+
+	// a changed list replaces the cached one
+	version = "v2"
+
+	assert.Equal(t, "v2", get())
+	assert.Equal(t, "v2", get())
+}
+
+func TestHTTPClient_nilCache(t *testing.T) {
+	hc := &http.Client{}
+
+	assert.Same(t, hc, HTTPClient(hc, nil))
+}
@@ -0,0 +1,3 @@
+// Package govclient extends the governor client with the governor api calls it doesn't support yet, and the group
+// member calls with errors callers can tell apart
+package govclient
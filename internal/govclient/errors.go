@@ -0,0 +1,26 @@
+package govclient
+
+import "errors"
+
+var (
+	// ErrMissingGroupID is returned when a request is missing the governor group id
+	ErrMissingGroupID = errors.New("missing governor group id")
+	// ErrMissingUserID is returned when a membership request is missing the governor user id
+	ErrMissingUserID = errors.New("missing governor user id")
+	// ErrMissingOrganizationID is returned when a request is missing the governor organization id
+	ErrMissingOrganizationID = errors.New("missing governor organization id")
+	// ErrMissingOrganizationName is returned when an organization request is missing the organization name
+	ErrMissingOrganizationName = errors.New("missing governor organization name")
+	// ErrMemberRequestExists is returned when the user already requested to join the governor group
+	ErrMemberRequestExists = errors.New("governor membership request already exists")
+	// ErrGroupMemberNotFound is returned when governor doesn't find the group, the user or the group membership
+	ErrGroupMemberNotFound = errors.New("governor group member not found")
+	// ErrGroupNotFound is returned when governor doesn't find the group
+	ErrGroupNotFound = errors.New("governor group not found")
+	// ErrOrganizationNotFound is returned when governor doesn't find the organization
+	ErrOrganizationNotFound = errors.New("governor organization not found")
+	// ErrRequestNonSuccess is returned when governor responds with a non-success status
+	ErrRequestNonSuccess = errors.New("governor request failed")
+	// ErrInsufficientScope is returned when governor refuses a request because the token is missing a scope
+	ErrInsufficientScope = errors.New("governor token is missing a scope")
+)
@@ -0,0 +1,111 @@
+package govclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+)
+
+// OktaGroupIDNoteKey is the key of the note line the okta group id of a governor group is recorded in.  Governor
+// groups don't have metadata, so the id is kept in the group note as a `okta_group_id: <id>` line.
+const OktaGroupIDNoteKey = "okta_group_id"
+
+var oktaGroupIDNotePattern = regexp.MustCompile(`(?m)^` + OktaGroupIDNoteKey + `:[ \t]*(\S*)[ \t]*$`)
+
+// OktaGroupID returns the okta group id recorded on a governor group, empty if there's none
+func OktaGroupID(group *v1alpha1.Group) string {
+	if group == nil || group.Group == nil {
+		return ""
+	}
+
+	m := oktaGroupIDNotePattern.FindStringSubmatch(group.Note)
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
+
+// withOktaGroupIDNote returns the note with the okta group id line set to the id, the rest of the note is kept
+func withOktaGroupIDNote(note, id string) string {
+	line := OktaGroupIDNoteKey + ": " + id
+
+	if oktaGroupIDNotePattern.MatchString(note) {
+		return oktaGroupIDNotePattern.ReplaceAllLiteralString(note, line)
+	}
+
+	if strings.TrimSpace(note) == "" {
+		return line
+	}
+
+	return strings.TrimRight(note, "\n") + "\n" + line
+}
+
+// UpdateGroup updates the name, description, note and approver group of a governor group, the governor client
+// doesn't support group updates.  A missing group is ErrGroupNotFound.
+func (c *Client) UpdateGroup(ctx context.Context, id string, groupReq *v1alpha1.GroupReq) error {
+	if id == "" {
+		return ErrMissingGroupID
+	}
+
+	b, err := json.Marshal(groupReq)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.url+"/api/v1alpha1/groups/"+id, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrGroupNotFound, id)
+	default:
+		return fmt.Errorf("%w: %d", ErrRequestNonSuccess, resp.StatusCode)
+	}
+}
+
+// RecordOktaGroupID records the okta group id on a governor group, it's a no-op when the group already has that
+// id.  The rest of the group is sent as is since the update replaces it.
+func (c *Client) RecordOktaGroupID(ctx context.Context, group *v1alpha1.Group, oktaGroupID string) error {
+	if group == nil || group.Group == nil || group.ID == "" {
+		return ErrMissingGroupID
+	}
+
+	if OktaGroupID(group) == oktaGroupID {
+		return nil
+	}
+
+	note := withOktaGroupIDNote(group.Note, oktaGroupID)
+
+	if err := c.UpdateGroup(ctx, group.ID, &v1alpha1.GroupReq{
+		Name:            group.Name,
+		Description:     group.Description,
+		Note:            note,
+		ApproverGroupID: group.ApproverGroup.String,
+	}); err != nil {
+		return err
+	}
+
+	group.Note = note
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package govclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testGroup(t *testing.T, note string) *v1alpha1.Group {
+	t.Helper()
+
+	g := &v1alpha1.Group{}
+
+	b, err := json.Marshal(map[string]interface{}{
+		"id":             "group-1",
+		"name":           "Platform",
+		"slug":           "platform",
+		"description":    "the platform team",
+		"note":           note,
+		"approver_group": "group-2",
+	})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, g))
+
+	return g
+}
+
+func TestOktaGroupID(t *testing.T) {
+	tests := []struct {
+		name string
+		note string
+		want string
+	}{
+		{name: "empty note", note: ""},
+		{name: "only the id", note: "okta_group_id: 00g-1", want: "00g-1"},
+		{name: "among other lines", note: "owned by platform\nokta_group_id: 00g-1\nsee the wiki", want: "00g-1"},
+		{name: "not at the start of a line", note: "old okta_group_id: 00g-1"},
+		{name: "no id", note: "okta_group_id:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, OktaGroupID(testGroup(t, tt.note)))
+		})
+	}
+
+	assert.Empty(t, OktaGroupID(nil))
+	assert.Empty(t, OktaGroupID(&v1alpha1.Group{}))
+}
+
+func Test_withOktaGroupIDNote(t *testing.T) {
+	tests := []struct {
+		name string
+		note string
+		want string
+	}{
+		{name: "empty note", note: "", want: "okta_group_id: 00g-2"},
+		{name: "appended", note: "owned by platform\n", want: "owned by platform\nokta_group_id: 00g-2"},
+		{name: "replaced", note: "owned by platform\nokta_group_id: 00g-1\nsee the wiki", want: "owned by platform\nokta_group_id: 00g-2\nsee the wiki"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, withOktaGroupIDNote(tt.note, "00g-2"))
+		})
+	}
+}
+
+func TestClient_RecordOktaGroupID(t *testing.T) {
+	tests := []struct {
+		name        string
+		note        string
+		status      int
+		wantRequest bool
+		wantNote    string
+		wantErr     error
+	}{
+		{name: "recorded", note: "owned by platform", status: http.StatusOK, wantRequest: true, wantNote: "owned by platform\nokta_group_id: 00g-1"},
+		{name: "already recorded", note: "okta_group_id: 00g-1", wantNote: "okta_group_id: 00g-1"},
+		{name: "not found", status: http.StatusNotFound, wantRequest: true, wantErr: ErrGroupNotFound},
+		{name: "governor error", status: http.StatusInternalServerError, wantRequest: true, wantErr: ErrRequestNonSuccess},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *v1alpha1.GroupReq
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPut, r.Method)
+				assert.Equal(t, "/api/v1alpha1/groups/group-1", r.URL.Path)
+
+				got = &v1alpha1.GroupReq{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(got))
+
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := New(nil, srv.URL, srv.Client())
+			g := testGroup(t, tt.note)
+
+			err := c.RecordOktaGroupID(context.TODO(), g, "00g-1")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, tt.note, g.Note, "the note isn't changed when the update fails")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNote, g.Note)
+
+			if !tt.wantRequest {
+				assert.Nil(t, got)
+				return
+			}
+
+			require.NotNil(t, got)
+			assert.Equal(t, v1alpha1.GroupReq{
+				Name:            "Platform",
+				Description:     "the platform team",
+				Note:            tt.wantNote,
+				ApproverGroupID: "group-2",
+			}, *got)
+		})
+	}
+
+	assert.ErrorIs(t, New(nil, "", nil).RecordOktaGroupID(context.TODO(), nil, "00g-1"), ErrMissingGroupID)
+}
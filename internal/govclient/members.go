@@ -0,0 +1,74 @@
+package govclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// AddGroupMember adds a user to a governor group, as an admin of the group when admin is true.  Unlike the
+// governor client, the response status is part of the error and a missing group or user is ErrGroupMemberNotFound.
+func (c *Client) AddGroupMember(ctx context.Context, groupID, userID string, admin bool) error {
+	if groupID == "" {
+		return ErrMissingGroupID
+	}
+
+	if userID == "" {
+		return ErrMissingUserID
+	}
+
+	b, err := json.Marshal(struct {
+		IsAdmin bool `json:"is_admin"`
+	}{admin})
+	if err != nil {
+		return err
+	}
+
+	return c.groupMemberRequest(ctx, http.MethodPut, groupID, userID, b)
+}
+
+// RemoveGroupMember removes a user from a governor group.  Removing a user that isn't a member of the group is
+// ErrGroupMemberNotFound, so callers can tell it apart from a failed removal.
+func (c *Client) RemoveGroupMember(ctx context.Context, groupID, userID string) error {
+	if groupID == "" {
+		return ErrMissingGroupID
+	}
+
+	if userID == "" {
+		return ErrMissingUserID
+	}
+
+	return c.groupMemberRequest(ctx, http.MethodDelete, groupID, userID, nil)
+}
+
+// groupMemberRequest sends a governor group member request with the body, if any
+func (c *Client) groupMemberRequest(ctx context.Context, method, groupID, userID string, body []byte) error {
+	u := fmt.Sprintf("%s/api/v1alpha1/groups/%s/users/%s", c.url, groupID, userID)
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: group %s user %s", ErrGroupMemberNotFound, groupID, userID)
+	default:
+		return fmt.Errorf("%w: %d", ErrRequestNonSuccess, resp.StatusCode)
+	}
+}
@@ -0,0 +1,95 @@
+package govclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_AddGroupMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupID string
+		userID  string
+		admin   bool
+		status  int
+		wantErr error
+	}{
+		{name: "added", groupID: "group-1", userID: "user-1", status: http.StatusNoContent},
+		{name: "added as admin", groupID: "group-1", userID: "user-1", admin: true, status: http.StatusAccepted},
+		{name: "not found", groupID: "group-1", userID: "user-1", status: http.StatusNotFound, wantErr: ErrGroupMemberNotFound},
+		{name: "governor error", groupID: "group-1", userID: "user-1", status: http.StatusInternalServerError, wantErr: ErrRequestNonSuccess},
+		{name: "missing group", userID: "user-1", wantErr: ErrMissingGroupID},
+		{name: "missing user", groupID: "group-1", wantErr: ErrMissingUserID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				IsAdmin bool `json:"is_admin"`
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPut, r.Method)
+				assert.Equal(t, "/api/v1alpha1/groups/group-1/users/user-1", r.URL.Path)
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := New(nil, srv.URL+"/", srv.Client())
+
+			err := c.AddGroupMember(context.TODO(), tt.groupID, tt.userID, tt.admin)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.admin, got.IsAdmin)
+		})
+	}
+}
+
+func TestClient_RemoveGroupMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupID string
+		userID  string
+		status  int
+		wantErr error
+	}{
+		{name: "removed", groupID: "group-1", userID: "user-1", status: http.StatusNoContent},
+		{name: "not a member", groupID: "group-1", userID: "user-1", status: http.StatusNotFound, wantErr: ErrGroupMemberNotFound},
+		{name: "governor error", groupID: "group-1", userID: "user-1", status: http.StatusBadGateway, wantErr: ErrRequestNonSuccess},
+		{name: "missing group", userID: "user-1", wantErr: ErrMissingGroupID},
+		{name: "missing user", groupID: "group-1", wantErr: ErrMissingUserID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodDelete, r.Method)
+				assert.Equal(t, "/api/v1alpha1/groups/group-1/users/user-1", r.URL.Path)
+
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := New(nil, srv.URL+"/", srv.Client())
+
+			err := c.RemoveGroupMember(context.TODO(), tt.groupID, tt.userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package govclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+)
+
+// CreateOrganization creates a governor organization, governor sets its slug from the name
+func (c *Client) CreateOrganization(ctx context.Context, orgReq *v1alpha1.OrganizationReq) (*v1alpha1.Organization, error) {
+	if orgReq == nil || orgReq.Name == "" {
+		return nil, ErrMissingOrganizationName
+	}
+
+	return c.organizationRequest(ctx, http.MethodPost, c.url+"/api/v1alpha1/organizations", orgReq)
+}
+
+// UpdateOrganization updates the name of a governor organization.  It needs a governor version supporting
+// organization updates, older versions respond with ErrOrganizationNotFound.
+func (c *Client) UpdateOrganization(ctx context.Context, id string, orgReq *v1alpha1.OrganizationReq) (*v1alpha1.Organization, error) {
+	if id == "" {
+		return nil, ErrMissingOrganizationID
+	}
+
+	if orgReq == nil || orgReq.Name == "" {
+		return nil, ErrMissingOrganizationName
+	}
+
+	return c.organizationRequest(ctx, http.MethodPut, c.url+"/api/v1alpha1/organizations/"+id, orgReq)
+}
+
+// DeleteOrganization deletes a governor organization
+func (c *Client) DeleteOrganization(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrMissingOrganizationID
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.url+"/api/v1alpha1/organizations/"+id, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	return organizationStatusErr(resp.StatusCode, id)
+}
+
+// organizationRequest sends an organization request to governor and returns the organization in the response
+func (c *Client) organizationRequest(ctx context.Context, method, u string, orgReq *v1alpha1.OrganizationReq) (*v1alpha1.Organization, error) {
+	b, err := json.Marshal(orgReq)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if err := organizationStatusErr(resp.StatusCode, orgReq.Name); err != nil {
+		return nil, err
+	}
+
+	out := v1alpha1.Organization{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// organizationStatusErr returns the error of a governor organization response status
+func organizationStatusErr(status int, org string) error {
+	switch status {
+	case http.StatusOK, http.StatusAccepted:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrOrganizationNotFound, org)
+	default:
+		return fmt.Errorf("%w: %d", ErrRequestNonSuccess, status)
+	}
+}
@@ -0,0 +1,154 @@
+package govclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+// testOrganizationServer returns a governor server responding to organization requests with the status, the
+// organization in the response is named after the request
+func testOrganizationServer(t *testing.T, method, path string, status int) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, method, r.Method)
+		assert.Equal(t, path, r.URL.Path)
+
+		got := v1alpha1.OrganizationReq{Name: "metal-toolbox"}
+		if r.Method != http.MethodDelete {
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		}
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"id":"org-1","name":"` + got.Name + `","slug":"` + got.Name + `"}`))
+	}))
+}
+
+func TestClient_CreateOrganization(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *v1alpha1.OrganizationReq
+		status  int
+		wantErr error
+	}{
+		{
+			name:   "created",
+			req:    &v1alpha1.OrganizationReq{Name: "metal-toolbox"},
+			status: http.StatusAccepted,
+		},
+		{
+			name:    "governor error",
+			req:     &v1alpha1.OrganizationReq{Name: "metal-toolbox"},
+			status:  http.StatusBadRequest,
+			wantErr: ErrRequestNonSuccess,
+		},
+		{
+			name:    "missing name",
+			req:     &v1alpha1.OrganizationReq{},
+			wantErr: ErrMissingOrganizationName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testOrganizationServer(t, http.MethodPost, "/api/v1alpha1/organizations", tt.status)
+			defer srv.Close()
+
+			got, err := New(nil, srv.URL, srv.Client()).CreateOrganization(context.TODO(), tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "org-1", got.ID)
+			assert.Equal(t, "metal-toolbox", got.Slug)
+		})
+	}
+}
+
+func TestClient_UpdateOrganization(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		req     *v1alpha1.OrganizationReq
+		status  int
+		wantErr error
+	}{
+		{
+			name:   "updated",
+			id:     "org-1",
+			req:    &v1alpha1.OrganizationReq{Name: "metal-toolbox"},
+			status: http.StatusOK,
+		},
+		{
+			name:    "not found",
+			id:      "org-1",
+			req:     &v1alpha1.OrganizationReq{Name: "metal-toolbox"},
+			status:  http.StatusNotFound,
+			wantErr: ErrOrganizationNotFound,
+		},
+		{
+			name:    "missing id",
+			req:     &v1alpha1.OrganizationReq{Name: "metal-toolbox"},
+			wantErr: ErrMissingOrganizationID,
+		},
+		{
+			name:    "missing name",
+			id:      "org-1",
+			wantErr: ErrMissingOrganizationName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testOrganizationServer(t, http.MethodPut, "/api/v1alpha1/organizations/org-1", tt.status)
+			defer srv.Close()
+
+			got, err := New(nil, srv.URL, srv.Client()).UpdateOrganization(context.TODO(), tt.id, tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "org-1", got.ID)
+			assert.Equal(t, "metal-toolbox", got.Name)
+		})
+	}
+}
+
+func TestClient_DeleteOrganization(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		status  int
+		wantErr error
+	}{
+		{name: "deleted", id: "org-1", status: http.StatusAccepted},
+		{name: "not found", id: "org-1", status: http.StatusNotFound, wantErr: ErrOrganizationNotFound},
+		{name: "governor error", id: "org-1", status: http.StatusInternalServerError, wantErr: ErrRequestNonSuccess},
+		{name: "missing id", wantErr: ErrMissingOrganizationID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testOrganizationServer(t, http.MethodDelete, "/api/v1alpha1/organizations/org-1", tt.status)
+			defer srv.Close()
+
+			err := New(nil, srv.URL, srv.Client()).DeleteOrganization(context.TODO(), tt.id)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package govclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+)
+
+// Client is a governor client with the group membership request calls.  The http client must authenticate the
+// requests, ie. the http client given to the governor client.
+type Client struct {
+	*governor.Client
+
+	url        string
+	httpClient *http.Client
+}
+
+// New returns a client extending the governor client, the url is the governor api url
+func New(c *governor.Client, url string, hc *http.Client) *Client {
+	return &Client{
+		Client:     c,
+		url:        strings.TrimSuffix(url, "/"),
+		httpClient: hc,
+	}
+}
+
+// MemberRequest is a request for a user to join a governor group, it's approved or denied by the group admins.
+// The kind defaults to v1alpha1.NewMemberRequest.
+type MemberRequest struct {
+	UserID string `json:"user_id"`
+	Note   string `json:"note"`
+	Kind   string `json:"kind"`
+}
+
+// CreateGroupMemberRequest files a request for a user to join a governor group.  The request is made on behalf
+// of the user, which needs a governor version accepting membership requests from clients for other users.
+func (c *Client) CreateGroupMemberRequest(ctx context.Context, groupID string, mr *MemberRequest) error {
+	if groupID == "" {
+		return ErrMissingGroupID
+	}
+
+	if mr == nil || mr.UserID == "" {
+		return ErrMissingUserID
+	}
+
+	body := *mr
+	if body.Kind == "" {
+		body.Kind = v1alpha1.NewMemberRequest
+	}
+
+	b, err := json.Marshal(&body)
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/api/v1alpha1/groups/%s/requests", c.url, groupID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %s", ErrMemberRequestExists, mr.UserID)
+	default:
+		return fmt.Errorf("%w: %d", ErrRequestNonSuccess, resp.StatusCode)
+	}
+}
+
+// GroupMemberRequestsByUser returns the pending membership requests of a governor group, by governor user id
+func (c *Client) GroupMemberRequestsByUser(ctx context.Context, groupID string) (map[string]*v1alpha1.GroupMemberRequest, error) {
+	reqs, err := c.GroupMemberRequests(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]*v1alpha1.GroupMemberRequest, len(reqs))
+
+	for _, r := range reqs {
+		if r.Kind == "" || r.Kind == v1alpha1.NewMemberRequest {
+			out[r.UserID] = r
+		}
+	}
+
+	return out, nil
+}
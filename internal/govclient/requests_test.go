@@ -0,0 +1,117 @@
+package govclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+func TestClient_CreateGroupMemberRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupID string
+		req     *MemberRequest
+		status  int
+		wantErr error
+	}{
+		{
+			name:    "created",
+			groupID: "group-1",
+			req:     &MemberRequest{UserID: "user-1", Note: "please"},
+			status:  http.StatusNoContent,
+		},
+		{
+			name:    "already requested",
+			groupID: "group-1",
+			req:     &MemberRequest{UserID: "user-1"},
+			status:  http.StatusConflict,
+			wantErr: ErrMemberRequestExists,
+		},
+		{
+			name:    "governor error",
+			groupID: "group-1",
+			req:     &MemberRequest{UserID: "user-1"},
+			status:  http.StatusInternalServerError,
+			wantErr: ErrRequestNonSuccess,
+		},
+		{
+			name:    "missing group",
+			req:     &MemberRequest{UserID: "user-1"},
+			wantErr: ErrMissingGroupID,
+		},
+		{
+			name:    "missing user",
+			groupID: "group-1",
+			req:     &MemberRequest{},
+			wantErr: ErrMissingUserID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got MemberRequest
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/api/v1alpha1/groups/group-1/requests", r.URL.Path)
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := New(nil, srv.URL+"/", srv.Client())
+
+			err := c.CreateGroupMemberRequest(context.TODO(), tt.groupID, tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, MemberRequest{UserID: "user-1", Note: "please", Kind: v1alpha1.NewMemberRequest}, got)
+		})
+	}
+}
+
+func TestClient_GroupMemberRequestsByUser(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":3600}`))
+
+			return
+		}
+
+		assert.Equal(t, "/api/v1alpha1/groups/group-1/requests", r.URL.Path)
+
+		_ = json.NewEncoder(w).Encode([]*v1alpha1.GroupMemberRequest{
+			{ID: "req-1", UserID: "user-1", Kind: v1alpha1.NewMemberRequest},
+			{ID: "req-2", UserID: "user-2", Kind: v1alpha1.AdminPromotionRequest},
+			{ID: "req-3", UserID: "user-3"},
+		})
+	}))
+	defer srv.Close()
+
+	gc, err := governor.NewClient(
+		governor.WithURL(srv.URL),
+		governor.WithClientCredentialConfig(&clientcredentials.Config{TokenURL: srv.URL + "/oauth2/token"}),
+		governor.WithHTTPClient(srv.Client()),
+	)
+	require.NoError(t, err)
+
+	got, err := New(gc, srv.URL, srv.Client()).GroupMemberRequestsByUser(context.TODO(), "group-1")
+	require.NoError(t, err)
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, "req-1", got["user-1"].ID)
+	assert.Equal(t, "req-3", got["user-3"].ID)
+}
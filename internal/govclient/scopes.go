@@ -0,0 +1,114 @@
+package govclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ScopeReadUsers is the governor scope to list and get users
+	ScopeReadUsers = "read:governor:users"
+	// ScopeCreateUsers is the governor scope to create users
+	ScopeCreateUsers = "create:governor:users"
+	// ScopeUpdateUsers is the governor scope to update users
+	ScopeUpdateUsers = "update:governor:users"
+	// ScopeReadGroups is the governor scope to list and get groups, their members and requests
+	ScopeReadGroups = "read:governor:groups"
+	// ScopeUpdateGroups is the governor scope to update groups and their members
+	ScopeUpdateGroups = "update:governor:groups"
+	// ScopeReadOrganizations is the governor scope to list and get organizations
+	ScopeReadOrganizations = "read:governor:organizations"
+	// ScopeWrite is the governor scope allowing every change
+	ScopeWrite = "write"
+	// ScopeOpenID is the scope of the openid tokens governor takes membership requests from
+	ScopeOpenID = "openid"
+)
+
+// InsufficientScopeError is returned when governor refuses a request because the token is missing a scope, with the
+// scope the request needed.  It matches ErrInsufficientScope.
+type InsufficientScopeError struct {
+	Method string
+	Path   string
+	Scope  string
+}
+
+func (e *InsufficientScopeError) Error() string {
+	if e.Scope == "" {
+		return fmt.Sprintf("%s: %s %s", ErrInsufficientScope, e.Method, e.Path)
+	}
+
+	return fmt.Sprintf("%s: %s %s requires the %s scope", ErrInsufficientScope, e.Method, e.Path, e.Scope)
+}
+
+// Unwrap returns ErrInsufficientScope
+func (e *InsufficientScopeError) Unwrap() error {
+	return ErrInsufficientScope
+}
+
+// RequiredScope returns the governor scope a request needs, from the method and the resource of the path: the
+// changes of the members, requests and applications of a group need the update scope of the groups.  It returns an
+// empty string for paths that aren't a governor api resource.
+func RequiredScope(method, path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "api" {
+		return ""
+	}
+
+	resource := parts[2]
+	nested := len(parts) > 4
+
+	var verb string
+
+	switch {
+	case method == http.MethodGet || method == http.MethodHead:
+		verb = "read"
+	case nested, method == http.MethodPut, method == http.MethodPatch:
+		verb = "update"
+	case method == http.MethodPost:
+		verb = "create"
+	case method == http.MethodDelete:
+		verb = "delete"
+	default:
+		return ""
+	}
+
+	return verb + ":governor:" + resource
+}
+
+// scopeTransport turns the forbidden responses of governor into an InsufficientScopeError, governor responds
+// unauthorized when the token is invalid or the user isn't allowed
+type scopeTransport struct {
+	next http.RoundTripper
+}
+
+func (t *scopeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return nil, &InsufficientScopeError{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Scope:  RequiredScope(req.Method, req.URL.Path),
+	}
+}
+
+// ScopeErrorsHTTPClient returns a copy of the http client returning an InsufficientScopeError for the requests governor
+// refuses for a missing scope, instead of the non-success error of the caller
+func ScopeErrorsHTTPClient(c *http.Client) *http.Client {
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	scoped := *c
+	scoped.Transport = &scopeTransport{next: next}
+
+	return &scoped
+}
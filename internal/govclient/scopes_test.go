@@ -0,0 +1,74 @@
+package govclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{method: http.MethodGet, path: "/api/v1alpha1/users", want: "read:governor:users"},
+		{method: http.MethodGet, path: "/api/v1alpha1/groups/group-1/users", want: "read:governor:groups"},
+		{method: http.MethodPost, path: "/api/v1alpha1/users", want: "create:governor:users"},
+		{method: http.MethodPut, path: "/api/v1alpha1/users/user-1", want: "update:governor:users"},
+		{method: http.MethodDelete, path: "/api/v1alpha1/organizations/org-1", want: "delete:governor:organizations"},
+		{method: http.MethodPut, path: "/api/v1alpha1/groups/group-1/users/user-1", want: "update:governor:groups"},
+		{method: http.MethodDelete, path: "/api/v1alpha1/groups/group-1/users/user-1", want: "update:governor:groups"},
+		{method: http.MethodPost, path: "/api/v1alpha1/groups/group-1/requests", want: "update:governor:groups"},
+		{method: http.MethodGet, path: "/healthz"},
+		{method: http.MethodOptions, path: "/api/v1alpha1/users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, RequiredScope(tt.method, tt.path))
+		})
+	}
+}
+
+func TestScopeErrorsHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	hc := ScopeErrorsHTTPClient(srv.Client())
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodDelete, srv.URL+"/api/v1alpha1/groups/group-1", nil)
+	require.NoError(t, err)
+
+	_, err = hc.Do(req) //nolint:bodyclose
+	require.ErrorIs(t, err, ErrInsufficientScope)
+
+	var scopeErr *InsufficientScopeError
+
+	require.True(t, errors.As(err, &scopeErr))
+	assert.Equal(t, "delete:governor:groups", scopeErr.Scope)
+	assert.Contains(t, err.Error(), "requires the delete:governor:groups scope")
+
+	// other failures are left to the caller
+	req, err = http.NewRequestWithContext(context.TODO(), http.MethodGet, srv.URL+"/api/v1alpha1/groups", nil)
+	require.NoError(t, err)
+
+	resp, err := hc.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
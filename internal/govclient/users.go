@@ -0,0 +1,32 @@
+package govclient
+
+import (
+	"context"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+)
+
+// UsersQueryBatchSize is the max number of emails to query governor users with in a single request
+const UsersQueryBatchSize = 100
+
+// UsersQueryFunc queries governor users, ie. the UsersV2 method of the governor client
+type UsersQueryFunc func(ctx context.Context, query map[string][]string) ([]*v1beta1.User, error)
+
+// UsersByEmail returns the governor users with the given emails.  The emails are queried in batches of
+// UsersQueryBatchSize so that the request url doesn't blow up for very large groups.
+func UsersByEmail(ctx context.Context, emails []string, queryFn UsersQueryFunc) ([]*v1beta1.User, error) {
+	users := []*v1beta1.User{}
+
+	for start := 0; start < len(emails); start += UsersQueryBatchSize {
+		end := min(start+UsersQueryBatchSize, len(emails))
+
+		batch, err := queryFn(ctx, map[string][]string{"email": emails[start:end]})
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, batch...)
+	}
+
+	return users, nil
+}
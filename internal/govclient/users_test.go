@@ -0,0 +1,80 @@
+package govclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsersByEmail(t *testing.T) {
+	manyEmails := []string{}
+
+	for i := 0; i < UsersQueryBatchSize+1; i++ {
+		manyEmails = append(manyEmails, fmt.Sprintf("user-%d@example.com", i))
+	}
+
+	errQuery := errors.New("boom")
+
+	tests := []struct {
+		name        string
+		emails      []string
+		err         error
+		wantUsers   int
+		wantQueries []int
+	}{
+		{
+			name: "no emails",
+		},
+		{
+			name:        "single batch",
+			emails:      []string{"one@example.com", "two@example.com"},
+			wantUsers:   2,
+			wantQueries: []int{2},
+		},
+		{
+			name:        "batched",
+			emails:      manyEmails,
+			wantUsers:   UsersQueryBatchSize + 1,
+			wantQueries: []int{UsersQueryBatchSize, 1},
+		},
+		{
+			name:   "query error",
+			emails: []string{"one@example.com"},
+			err:    errQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []int
+
+			users, err := UsersByEmail(context.TODO(), tt.emails, func(_ context.Context, q map[string][]string) ([]*v1beta1.User, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+
+				queries = append(queries, len(q["email"]))
+
+				batch := []*v1beta1.User{}
+				for range q["email"] {
+					batch = append(batch, &v1beta1.User{})
+				}
+
+				return batch, nil
+			})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, users, tt.wantUsers)
+			assert.Equal(t, tt.wantQueries, queries)
+		})
+	}
+}
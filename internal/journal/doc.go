@@ -0,0 +1,2 @@
+// Package journal keeps a queryable record of the mutations applied to okta
+package journal
@@ -0,0 +1,12 @@
+package journal
+
+import "errors"
+
+var (
+	// ErrStoreRequired is returned when a journal is created without a store
+	ErrStoreRequired = errors.New("journal store is required")
+	// ErrBadEntry is returned when a journal entry is nil or missing its type
+	ErrBadEntry = errors.New("bad journal entry")
+	// ErrBadParameter is returned when a bad parameter is passed to a journal store
+	ErrBadParameter = errors.New("bad journal store parameter")
+)
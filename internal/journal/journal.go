@@ -0,0 +1,194 @@
+package journal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultRetention is the default for how long journal entries are kept
+	DefaultRetention = 400 * 24 * time.Hour
+)
+
+// Entry is a single applied okta mutation
+type Entry struct {
+	ID         string            `json:"id"`
+	Time       time.Time         `json:"time"`
+	Type       string            `json:"type"`
+	Resources  map[string]string `json:"resources"`
+	BeforeHash string            `json:"before_hash,omitempty"`
+	AfterHash  string            `json:"after_hash,omitempty"`
+	RunID      string            `json:"run_id,omitempty"`
+	Source     string            `json:"source,omitempty"`
+}
+
+// Filter limits the journal entries returned from a query, empty fields match everything
+type Filter struct {
+	Type    string
+	GroupID string
+	UserID  string
+	Since   time.Time
+	Until   time.Time
+}
+
+// Store is the interface for persisting journal entries, List may use the filter to skip the entries that can't
+// match but the entries it returns are still matched against the filter
+type Store interface {
+	Put(context.Context, *Entry) error
+	List(context.Context, *Filter) ([]*Entry, error)
+}
+
+// Journal records applied okta mutations to a store
+type Journal struct {
+	logger *zap.Logger
+	store  Store
+}
+
+// Option is a functional configuration option
+type Option func(j *Journal)
+
+// WithLogger sets logger
+func WithLogger(l *zap.Logger) Option {
+	return func(j *Journal) {
+		j.logger = l
+	}
+}
+
+// WithStore sets the journal store
+func WithStore(s Store) Option {
+	return func(j *Journal) {
+		j.store = s
+	}
+}
+
+// New returns a new journal
+func New(opts ...Option) (*Journal, error) {
+	j := Journal{
+		logger: zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(&j)
+	}
+
+	if j.store == nil {
+		return nil, ErrStoreRequired
+	}
+
+	return &j, nil
+}
+
+// Record adds an entry to the journal, the ID and time are set if they are empty
+func (j *Journal) Record(ctx context.Context, e *Entry) error {
+	if e == nil || e.Type == "" {
+		return ErrBadEntry
+	}
+
+	if e.ID == "" {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+
+		e.ID = id.String()
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	j.logger.Debug("recording journal entry", redact.Any("journal.entry", e))
+
+	return j.store.Put(ctx, e)
+}
+
+// Query returns the journal entries matching the filter sorted by time
+func (j *Journal) Query(ctx context.Context, f *Filter) ([]*Entry, error) {
+	entries, err := j.store.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := []*Entry{}
+
+	for _, e := range entries {
+		if f.Matches(e) {
+			resp = append(resp, e)
+		}
+	}
+
+	sort.SliceStable(resp, func(a, b int) bool {
+		return resp[a].Time.Before(resp[b].Time)
+	})
+
+	return resp, nil
+}
+
+// Matches returns true if the entry matches the filter
+func (f *Filter) Matches(e *Entry) bool {
+	if e == nil {
+		return false
+	}
+
+	if f == nil {
+		return true
+	}
+
+	if f.Type != "" && f.Type != e.Type {
+		return false
+	}
+
+	if f.GroupID != "" && !containsValue(e.Resources, f.GroupID, "governor.group.id", "okta.group.id") {
+		return false
+	}
+
+	if f.UserID != "" && !containsValue(e.Resources, f.UserID, "governor.user.id", "okta.user.id") {
+		return false
+	}
+
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+// Hash returns a stable hash of the given state, or an empty string for nil
+func Hash(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	// json marshaling sorts map keys, so this is stable for the maps we pass around
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// containsValue returns true if any of the given keys in the resources map have the value
+func containsValue(resources map[string]string, value string, keys ...string) bool {
+	for _, k := range keys {
+		if resources[k] == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,201 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStore struct {
+	err     error
+	entries []*Entry
+}
+
+func (m *mockStore) Put(_ context.Context, e *Entry) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.entries = append(m.entries, e)
+
+	return nil
+}
+
+func (m *mockStore) List(_ context.Context, _ *Filter) ([]*Entry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.entries, nil
+}
+
+var (
+	testTime = time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	testEntries = []*Entry{
+		{
+			ID:   "entry3",
+			Time: testTime.Add(2 * time.Hour),
+			Type: "GroupMemberRemove",
+			Resources: map[string]string{
+				"governor.group.id": "gov-group-1",
+				"okta.group.id":     "okta-group-1",
+				"okta.user.id":      "okta-user-1",
+			},
+		},
+		{
+			ID:   "entry1",
+			Time: testTime,
+			Type: "GroupMemberAdd",
+			Resources: map[string]string{
+				"governor.group.id": "gov-group-1",
+				"governor.user.id":  "gov-user-1",
+				"okta.group.id":     "okta-group-1",
+				"okta.user.id":      "okta-user-1",
+			},
+		},
+		{
+			ID:   "entry2",
+			Time: testTime.Add(time.Hour),
+			Type: "GroupCreate",
+			Resources: map[string]string{
+				"governor.group.id": "gov-group-2",
+				"okta.group.id":     "okta-group-2",
+			},
+		},
+	}
+)
+
+func TestJournal_Record(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   *Entry
+		err     error
+		wantErr bool
+	}{
+		{
+			name:  "example record",
+			entry: &Entry{Type: "GroupCreate"},
+		},
+		{
+			name:    "nil entry",
+			wantErr: true,
+		},
+		{
+			name:    "missing type",
+			entry:   &Entry{},
+			wantErr: true,
+		},
+		{
+			name:    "store error",
+			entry:   &Entry{Type: "GroupCreate"},
+			err:     errors.New("boom"), //nolint:goerr113
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &mockStore{err: tt.err}
+
+			j, err := New(WithStore(s))
+			assert.NoError(t, err)
+
+			err = j.Record(context.TODO(), tt.entry)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, s.entries, 1)
+			assert.NotEmpty(t, s.entries[0].ID)
+			assert.False(t, s.entries[0].Time.IsZero())
+		})
+	}
+}
+
+func TestJournal_Query(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  *Filter
+		err     error
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "nil filter returns everything in time order",
+			filter: nil,
+			want:   []string{"entry1", "entry2", "entry3"},
+		},
+		{
+			name:   "filter by governor group",
+			filter: &Filter{GroupID: "gov-group-1"},
+			want:   []string{"entry1", "entry3"},
+		},
+		{
+			name:   "filter by okta group",
+			filter: &Filter{GroupID: "okta-group-2"},
+			want:   []string{"entry2"},
+		},
+		{
+			name:   "filter by governor user",
+			filter: &Filter{UserID: "gov-user-1"},
+			want:   []string{"entry1"},
+		},
+		{
+			name:   "filter by type",
+			filter: &Filter{Type: "GroupMemberRemove"},
+			want:   []string{"entry3"},
+		},
+		{
+			name:   "filter by time",
+			filter: &Filter{Since: testTime.Add(30 * time.Minute), Until: testTime.Add(90 * time.Minute)},
+			want:   []string{"entry2"},
+		},
+		{
+			name:   "no matches",
+			filter: &Filter{UserID: "nobody"},
+			want:   []string{},
+		},
+		{
+			name:    "store error",
+			err:     errors.New("boom"), //nolint:goerr113
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := make([]*Entry, len(testEntries))
+			copy(entries, testEntries)
+
+			j, err := New(WithStore(&mockStore{err: tt.err, entries: entries}))
+			assert.NoError(t, err)
+
+			got, err := j.Query(context.TODO(), tt.filter)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			ids := []string{}
+			for _, e := range got {
+				ids = append(ids, e.ID)
+			}
+
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	assert.Empty(t, Hash(nil))
+	assert.Equal(t, Hash(map[string]string{"a": "1", "b": "2"}), Hash(map[string]string{"b": "2", "a": "1"}))
+	assert.NotEqual(t, Hash(map[string]string{"a": "1"}), Hash(map[string]string{"a": "2"}))
+}
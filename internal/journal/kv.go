@@ -0,0 +1,106 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// KVStore is a journal store backed by a JetStream key-value bucket. Retention is
+// handled by the bucket TTL.
+type KVStore struct {
+	kv nats.KeyValue
+}
+
+// NewKVStore returns a new journal store using the given key-value bucket
+func NewKVStore(kv nats.KeyValue) (*KVStore, error) {
+	if kv == nil {
+		return nil, ErrBadParameter
+	}
+
+	return &KVStore{kv: kv}, nil
+}
+
+// kvKeyTimeLen is the length of the zero padded entry time prefix of the keys
+const kvKeyTimeLen = 20
+
+// kvKeyTime returns the zero padded key prefix of an entry time
+func kvKeyTime(t time.Time) string {
+	return fmt.Sprintf("%0*d", kvKeyTimeLen, t.UnixNano())
+}
+
+// Put writes the entry to the bucket, keyed by time so keys are naturally ordered
+func (s *KVStore) Put(_ context.Context, e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.kv.Put(kvKeyTime(e.Time)+"."+e.ID, b)
+
+	return err
+}
+
+// List returns the entries in the bucket within the time window of the filter, or all of them without one.  The
+// keys start with the entry time so the entries outside the window are skipped without getting them.
+func (s *KVStore) List(ctx context.Context, f *Filter) ([]*Entry, error) {
+	keys, err := s.kv.Keys(nats.Context(ctx))
+	if err != nil {
+		if errors.Is(err, nats.ErrNoKeysFound) {
+			return []*Entry{}, nil
+		}
+
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(keys))
+
+	for _, k := range keys {
+		if !keyInWindow(k, f) {
+			continue
+		}
+
+		kve, err := s.kv.Get(k)
+		if err != nil {
+			// entries can expire between listing the keys and getting them
+			if errors.Is(err, nats.ErrKeyNotFound) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		e := &Entry{}
+		if err := json.Unmarshal(kve.Value(), e); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// keyInWindow returns false for the keys of entries outside the time window of the filter, the zero padded times
+// compare like the times they encode.  Keys without a time prefix are kept.
+func keyInWindow(key string, f *Filter) bool {
+	if f == nil || len(key) < kvKeyTimeLen {
+		return true
+	}
+
+	t := key[:kvKeyTimeLen]
+
+	if !f.Since.IsZero() && t < kvKeyTime(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && t > kvKeyTime(f.Until) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,121 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockKV is a key-value bucket counting the entries read from it
+type mockKV struct {
+	nats.KeyValue
+
+	values map[string][]byte
+	gets   int
+}
+
+func (m *mockKV) Put(key string, value []byte) (uint64, error) {
+	m.values[key] = value
+
+	return uint64(len(m.values)), nil
+}
+
+func (m *mockKV) Keys(_ ...nats.WatchOpt) ([]string, error) {
+	keys := make([]string, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+func (m *mockKV) Get(key string) (nats.KeyValueEntry, error) {
+	m.gets++
+
+	v, ok := m.values[key]
+	if !ok {
+		return nil, nats.ErrKeyNotFound
+	}
+
+	return &mockKVEntry{value: v}, nil
+}
+
+type mockKVEntry struct {
+	nats.KeyValueEntry
+
+	value []byte
+}
+
+func (e *mockKVEntry) Value() []byte { return e.value }
+
+func TestKVStore_List(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   *Filter
+		wantIDs  []string
+		wantGets int
+	}{
+		{
+			name:     "no filter",
+			wantIDs:  []string{"entry1", "entry2", "entry3"},
+			wantGets: 3,
+		},
+		{
+			name:     "since",
+			filter:   &Filter{Since: testTime.Add(time.Hour)},
+			wantIDs:  []string{"entry2", "entry3"},
+			wantGets: 2,
+		},
+		{
+			name:     "window",
+			filter:   &Filter{Since: testTime.Add(time.Minute), Until: testTime.Add(time.Hour)},
+			wantIDs:  []string{"entry2"},
+			wantGets: 1,
+		},
+		{
+			name:     "other filters read the whole bucket",
+			filter:   &Filter{Type: "GroupMemberAdd"},
+			wantIDs:  []string{"entry1", "entry2", "entry3"},
+			wantGets: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv := &mockKV{values: map[string][]byte{}}
+
+			s, err := NewKVStore(kv)
+			require.NoError(t, err)
+
+			for _, e := range testEntries {
+				require.NoError(t, s.Put(context.TODO(), e))
+			}
+
+			// keys without a time prefix are still read
+			b, err := json.Marshal(&Entry{ID: "legacy"})
+			require.NoError(t, err)
+
+			_, err = kv.Put("legacy", b)
+			require.NoError(t, err)
+
+			got, err := s.List(context.TODO(), tt.filter)
+			require.NoError(t, err)
+
+			ids := []string{}
+
+			for _, e := range got {
+				if e.ID != "legacy" {
+					ids = append(ids, e.ID)
+				}
+			}
+
+			assert.ElementsMatch(t, tt.wantIDs, ids)
+			assert.Equal(t, tt.wantGets+1, kv.gets)
+		})
+	}
+}
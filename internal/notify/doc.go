@@ -0,0 +1,3 @@
+// Package notify posts a human readable notice of the destructive changes made by gov-okta-addon to a Slack
+// compatible webhook
+package notify
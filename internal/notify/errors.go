@@ -0,0 +1,6 @@
+package notify
+
+import "errors"
+
+// ErrWebhookNonSuccess is returned when the webhook responds with a non success status
+var ErrWebhookNonSuccess = errors.New("webhook responded with a non success status")
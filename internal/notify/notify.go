@@ -0,0 +1,214 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultTimeout is the default timeout of a webhook post
+	DefaultTimeout = 10 * time.Second
+
+	// notification results
+	notifySent   = "sent"
+	notifyFailed = "failed"
+)
+
+// DefaultEventTypes are the audit event types of the destructive changes notified by default: okta user deletes,
+// okta group deletes and application assignment removals
+var DefaultEventTypes = []string{"UserDelete", "GroupDelete", "GroupApplicationRemove"}
+
+// summaries are the human readable summaries of the event types
+var summaries = map[string]string{
+	"UserDeactivate":         "deactivated an Okta user",
+	"UserDelete":             "deleted an Okta user",
+	"GroupDelete":            "deleted an Okta group",
+	"GroupMemberRemove":      "removed a member from an Okta group",
+	"GroupApplicationRemove": "removed an Okta application assignment",
+	"GroupPushMappingRemove": "removed an Okta push group mapping",
+}
+
+var notificationsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Subsystem: "gov_okta_addon",
+		Name:      "webhook_notifications_total",
+		Help:      "Total count of destructive change notifications posted to the webhook by result (sent or failed).",
+	},
+	[]string{"result"},
+)
+
+// Message is the json body posted to the webhook.  Slack only uses the text, other webhooks can use the event type
+// and target.
+type Message struct {
+	Text      string            `json:"text"`
+	EventType string            `json:"event_type"`
+	Target    map[string]string `json:"target"`
+	Time      time.Time         `json:"time"`
+}
+
+// Notifier posts the destructive changes to a webhook
+type Notifier struct {
+	url        string
+	httpClient *http.Client
+	logger     *zap.Logger
+	timeout    time.Duration
+	types      map[string]bool
+}
+
+// Option is a functional configuration option
+type Option func(n *Notifier)
+
+// WithHTTPClient sets the http client the webhook is posted with
+func WithHTTPClient(c *http.Client) Option {
+	return func(n *Notifier) {
+		n.httpClient = c
+	}
+}
+
+// WithLogger sets logger
+func WithLogger(l *zap.Logger) Option {
+	return func(n *Notifier) {
+		n.logger = l
+	}
+}
+
+// WithTimeout sets the timeout of a webhook post
+func WithTimeout(d time.Duration) Option {
+	return func(n *Notifier) {
+		if d > 0 {
+			n.timeout = d
+		}
+	}
+}
+
+// WithEventTypes sets the audit event types that are notified, instead of the DefaultEventTypes
+func WithEventTypes(types ...string) Option {
+	return func(n *Notifier) {
+		if len(types) == 0 {
+			return
+		}
+
+		n.types = map[string]bool{}
+
+		for _, t := range types {
+			n.types[t] = true
+		}
+	}
+}
+
+// New returns a notifier posting to the webhook url
+func New(url string, opts ...Option) *Notifier {
+	n := &Notifier{
+		url:        url,
+		httpClient: &http.Client{},
+		logger:     zap.NewNop(),
+		timeout:    DefaultTimeout,
+	}
+
+	WithEventTypes(DefaultEventTypes...)(n)
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	return n
+}
+
+// Notify posts a change of the event type to the webhook if the event type is notified, notifying with a nil
+// notifier does nothing.  The change was already made, so errors are logged and counted but not returned.  The post
+// isn't cancelled with the context, so a notice isn't lost when the addon shuts down.
+func (n *Notifier) Notify(ctx context.Context, eventType string, target map[string]string) {
+	if n == nil || !n.types[eventType] {
+		return
+	}
+
+	logger := n.logger.With(zap.String("event.type", eventType))
+
+	msg := Message{
+		Text:      Text(eventType, target),
+		EventType: eventType,
+		Target:    target,
+		Time:      time.Now().UTC(),
+	}
+
+	if err := n.post(ctx, msg); err != nil {
+		notificationsCounter.WithLabelValues(notifyFailed).Inc()
+		logger.Error("error posting change notification to webhook", zap.Error(err))
+
+		return
+	}
+
+	notificationsCounter.WithLabelValues(notifySent).Inc()
+	logger.Debug("posted change notification to webhook")
+}
+
+func (n *Notifier) post(ctx context.Context, msg Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), n.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", ErrWebhookNonSuccess, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Text returns the human readable notice of a change, ie.
+// "gov-okta-addon deleted an Okta user: governor.user.email=user@example.com, okta.user.id=00u1"
+func Text(eventType string, target map[string]string) string {
+	summary, ok := summaries[eventType]
+	if !ok {
+		summary = "made a " + eventType + " change"
+	}
+
+	keys := make([]string, 0, len(target))
+
+	for k, v := range target {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	attrs := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		attrs = append(attrs, k+"="+target[k])
+	}
+
+	if len(attrs) == 0 {
+		return "gov-okta-addon " + summary
+	}
+
+	return "gov-okta-addon " + summary + ": " + strings.Join(attrs, ", ")
+}
@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier_Notify(t *testing.T) {
+	target := map[string]string{"okta.group.id": "00g1", "okta.group.name": "Platform"}
+
+	tests := []struct {
+		name       string
+		eventType  string
+		opts       []Option
+		status     int
+		wantPosted bool
+		wantResult string
+	}{
+		{
+			name:       "default event type",
+			eventType:  "GroupDelete",
+			status:     http.StatusOK,
+			wantPosted: true,
+			wantResult: notifySent,
+		},
+		{
+			name:      "event type not notified",
+			eventType: "GroupCreate",
+			status:    http.StatusOK,
+		},
+		{
+			name:       "configured event type",
+			eventType:  "GroupMemberRemove",
+			opts:       []Option{WithEventTypes("GroupMemberRemove")},
+			status:     http.StatusNoContent,
+			wantPosted: true,
+			wantResult: notifySent,
+		},
+		{
+			name:       "webhook error",
+			eventType:  "UserDelete",
+			status:     http.StatusInternalServerError,
+			wantPosted: true,
+			wantResult: notifyFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posted []Message
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+				var msg Message
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+
+				posted = append(posted, msg)
+
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			sent := testutil.ToFloat64(notificationsCounter.WithLabelValues(notifySent))
+			failed := testutil.ToFloat64(notificationsCounter.WithLabelValues(notifyFailed))
+
+			n := New(ts.URL, append([]Option{WithHTTPClient(ts.Client())}, tt.opts...)...)
+			n.Notify(context.TODO(), tt.eventType, target)
+
+			if !tt.wantPosted {
+				assert.Empty(t, posted)
+				assert.Equal(t, sent, testutil.ToFloat64(notificationsCounter.WithLabelValues(notifySent)))
+				assert.Equal(t, failed, testutil.ToFloat64(notificationsCounter.WithLabelValues(notifyFailed)))
+
+				return
+			}
+
+			require.Len(t, posted, 1)
+			assert.Equal(t, tt.eventType, posted[0].EventType)
+			assert.Equal(t, target, posted[0].Target)
+			assert.Equal(t, Text(tt.eventType, target), posted[0].Text)
+
+			switch tt.wantResult {
+			case notifySent:
+				assert.Equal(t, sent+1, testutil.ToFloat64(notificationsCounter.WithLabelValues(notifySent)))
+			case notifyFailed:
+				assert.Equal(t, failed+1, testutil.ToFloat64(notificationsCounter.WithLabelValues(notifyFailed)))
+			}
+		})
+	}
+}
+
+func TestNotifier_Notify_nil(t *testing.T) {
+	var n *Notifier
+
+	assert.NotPanics(t, func() { n.Notify(context.TODO(), "GroupDelete", nil) })
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		target    map[string]string
+		want      string
+	}{
+		{
+			name:      "sorted attributes",
+			eventType: "UserDelete",
+			target:    map[string]string{"okta.user.id": "00u1", "governor.user.email": "user@example.com"},
+			want:      "gov-okta-addon deleted an Okta user: governor.user.email=user@example.com, okta.user.id=00u1",
+		},
+		{
+			name:      "empty attributes are dropped",
+			eventType: "GroupDelete",
+			target:    map[string]string{"okta.group.id": "00g1", "governor.group.id": ""},
+			want:      "gov-okta-addon deleted an Okta group: okta.group.id=00g1",
+		},
+		{
+			name:      "no attributes",
+			eventType: "GroupApplicationRemove",
+			want:      "gov-okta-addon removed an Okta application assignment",
+		},
+		{
+			name:      "unknown event type",
+			eventType: "GroupCreate",
+			target:    map[string]string{"okta.group.id": "00g1"},
+			want:      "gov-okta-addon made a GroupCreate change: okta.group.id=00g1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Text(tt.eventType, tt.target))
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package okta
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// AccountRule matches okta accounts by an attribute of their profile, ie. userType, email or a custom bot
+// flag.  String attributes match one of the values or end with one of the suffixes (ie. an email domain),
+// case insensitively.  A rule without values and suffixes matches any non-empty string or true boolean.
+type AccountRule struct {
+	Attribute string
+	Values    []string
+	Suffixes  []string
+}
+
+// AccountRules match non-human okta accounts, ie. service or bot accounts, an account is matched when
+// any of the rules matches
+type AccountRules []AccountRule
+
+// Match returns the first rule matching the okta user
+func (rs AccountRules) Match(u *okta.User) (AccountRule, bool) {
+	if u == nil || u.Profile == nil {
+		return AccountRule{}, false
+	}
+
+	for _, rule := range rs {
+		if rule.Match(u) {
+			return rule, true
+		}
+	}
+
+	return AccountRule{}, false
+}
+
+// Match returns true if the rule matches the profile of the okta user
+func (r AccountRule) Match(u *okta.User) bool {
+	if u == nil || u.Profile == nil {
+		return false
+	}
+
+	v, ok := (*u.Profile)[r.Attribute]
+	if !ok || v == nil {
+		return false
+	}
+
+	var value string
+
+	switch pv := v.(type) {
+	case string:
+		value = pv
+	case bool:
+		if len(r.Values) == 0 && len(r.Suffixes) == 0 {
+			return pv
+		}
+
+		value = fmt.Sprint(pv)
+	default:
+		value = fmt.Sprint(pv)
+	}
+
+	if value == "" {
+		return false
+	}
+
+	if len(r.Values) == 0 && len(r.Suffixes) == 0 {
+		return true
+	}
+
+	value = strings.ToLower(value)
+
+	if slices.ContainsFunc(r.Values, func(s string) bool { return strings.EqualFold(s, value) }) {
+		return true
+	}
+
+	return slices.ContainsFunc(r.Suffixes, func(s string) bool { return s != "" && strings.HasSuffix(value, strings.ToLower(s)) })
+}
+
+// String returns a description of the rule for logging
+func (r AccountRule) String() string {
+	switch {
+	case len(r.Values) > 0 && len(r.Suffixes) > 0:
+		return fmt.Sprintf("%s in %v or ends with %v", r.Attribute, r.Values, r.Suffixes)
+	case len(r.Values) > 0:
+		return fmt.Sprintf("%s in %v", r.Attribute, r.Values)
+	case len(r.Suffixes) > 0:
+		return fmt.Sprintf("%s ends with %v", r.Attribute, r.Suffixes)
+	default:
+		return fmt.Sprintf("%s is set", r.Attribute)
+	}
+}
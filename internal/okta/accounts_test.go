@@ -0,0 +1,72 @@
+package okta
+
+import (
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountRules_Match(t *testing.T) {
+	rules := AccountRules{
+		{Attribute: "userType", Values: []string{"service"}},
+		{Attribute: "email", Suffixes: []string{"@bots.example.com"}},
+		{Attribute: "bot"},
+	}
+
+	tests := []struct {
+		name      string
+		profile   okta.UserProfile
+		want      bool
+		wantMatch string
+	}{
+		{
+			name:      "user type",
+			profile:   okta.UserProfile{"userType": "Service", "email": "ci@example.com"},
+			want:      true,
+			wantMatch: "userType",
+		},
+		{
+			name:      "email domain",
+			profile:   okta.UserProfile{"userType": "employee", "email": "deploy@Bots.example.com"},
+			want:      true,
+			wantMatch: "email",
+		},
+		{
+			name:      "bot flag",
+			profile:   okta.UserProfile{"email": "ci@example.com", "bot": true},
+			want:      true,
+			wantMatch: "bot",
+		},
+		{
+			name:    "bot flag false",
+			profile: okta.UserProfile{"email": "someone@example.com", "bot": false},
+		},
+		{
+			name:    "human",
+			profile: okta.UserProfile{"userType": "employee", "email": "someone@example.com"},
+		},
+		{
+			name:    "email domain in the local part",
+			profile: okta.UserProfile{"email": "bots.example.com@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := rules.Match(&okta.User{Id: "user-1", Profile: &tt.profile})
+			assert.Equal(t, tt.want, ok)
+			assert.Equal(t, tt.wantMatch, rule.Attribute)
+		})
+	}
+}
+
+func TestAccountRules_MatchNoProfile(t *testing.T) {
+	rules := AccountRules{{Attribute: "bot"}}
+
+	_, ok := rules.Match(&okta.User{Id: "user-1"})
+	assert.False(t, ok)
+
+	_, ok = AccountRules(nil).Match(&okta.User{Id: "user-1", Profile: &okta.UserProfile{"bot": true}})
+	assert.False(t, ok)
+}
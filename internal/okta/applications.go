@@ -1,8 +1,14 @@
 package okta
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 	"go.uber.org/zap"
@@ -21,7 +27,7 @@ func (c *Client) GithubCloudApplications(ctx context.Context) (map[string]string
 		return nil, err
 	}
 
-	c.logger.Debug("applications list from Okta", zap.Any("okta.apps", applications))
+	c.logger.Debug("applications list from Okta", redact.Any("okta.apps", applications))
 
 	apps := map[string]string{}
 
@@ -37,7 +43,7 @@ func (c *Client) GithubCloudApplications(ctx context.Context) (map[string]string
 				if k == "githubOrg" {
 					org, ok := v.(string)
 					if !ok {
-						c.logger.Warn("okta app setting for githubOrg is not a string", zap.Any("okta.app.settings", *app.Settings.App))
+						c.logger.Warn("okta app setting for githubOrg is not a string", redact.Any("okta.app.settings", *app.Settings.App))
 						break
 					}
 
@@ -52,12 +58,15 @@ func (c *Client) GithubCloudApplications(ctx context.Context) (map[string]string
 
 // listApplications returns all of the applications modified by the query parameters
 func (c *Client) listApplications(ctx context.Context, qp *query.Params) ([]okta.App, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
 	apps, resp, err := c.appIface.ListApplications(ctx, qp)
 	if err != nil {
-		return nil, err
+		return nil, apiError(resp, err)
 	}
 
-	c.logger.Debug("output from listing applications", zap.Any("okta.application", apps), zap.Any("response", resp))
+	c.logger.Debug("output from listing applications", redact.Any("okta.application", apps), redact.Any("response", resp))
 
 	list := make([]okta.App, len(apps))
 	copy(list, apps)
@@ -69,7 +78,7 @@ func (c *Client) listApplications(ctx context.Context, qp *query.Params) ([]okta
 
 		resp, err = resp.Next(ctx, &apps)
 		if err != nil {
-			return nil, err
+			return nil, apiError(resp, err)
 		}
 
 		list = append(list, apps...)
@@ -78,33 +87,139 @@ func (c *Client) listApplications(ctx context.Context, qp *query.Params) ([]okta
 	return list, nil
 }
 
-// AssignGroupToApplication assigns a group to an okta application
-func (c *Client) AssignGroupToApplication(ctx context.Context, appID, groupID string) error {
+// AppGroupAssignment are the settings of the assignment of an okta group to an application, the unset ones are left
+// to okta.  The priority decides which group profile wins when a user is assigned the application through more than
+// one group, 0 being the highest.
+type AppGroupAssignment struct {
+	Priority *int64
+	Profile  map[string]interface{}
+}
+
+// Drifted returns true when an existing assignment doesn't match the settings, nil settings never drift
+func (s *AppGroupAssignment) Drifted(a *okta.ApplicationGroupAssignment) bool {
+	if s == nil || a == nil {
+		return false
+	}
+
+	if s.Priority != nil {
+		priority := a.Priority
+		if a.PriorityPtr != nil {
+			priority = *a.PriorityPtr
+		}
+
+		if priority != *s.Priority {
+			return true
+		}
+	}
+
+	if len(s.Profile) == 0 {
+		return false
+	}
+
+	profile, _ := a.Profile.(map[string]interface{})
+
+	for k, v := range s.Profile {
+		current, ok := profile[k]
+		if !ok {
+			return true
+		}
+
+		// compare the json values since the profile of the assignment is decoded from json
+		want, _ := json.Marshal(v)
+		got, _ := json.Marshal(current)
+
+		if !bytes.Equal(want, got) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CurrentAppGroupAssignment returns the priority and profile of an existing assignment
+func CurrentAppGroupAssignment(a *okta.ApplicationGroupAssignment) *AppGroupAssignment {
+	if a == nil {
+		return nil
+	}
+
+	current := &AppGroupAssignment{Priority: okta.Int64Ptr(a.Priority)}
+	if a.PriorityPtr != nil {
+		current.Priority = okta.Int64Ptr(*a.PriorityPtr)
+	}
+
+	current.Profile, _ = a.Profile.(map[string]interface{})
+
+	return current
+}
+
+// AssignGroupToApplication assigns a group to an okta application with the assignment settings, if any.  Okta
+// replaces the settings of a group already assigned to the application, so it also fixes a drifted assignment.
+func (c *Client) AssignGroupToApplication(ctx context.Context, appID, groupID string, settings *AppGroupAssignment) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	if appID == "" || groupID == "" {
 		return ErrApplicationBadParameters
 	}
 
 	c.logger.Info("adding okta application group assignments", zap.Any("okta.application.id", appID), zap.Any("okta.group.id", groupID))
 
-	assignment, _, err := c.appIface.CreateApplicationGroupAssignment(ctx, appID, groupID, okta.ApplicationGroupAssignment{})
-	if err != nil {
+	args := map[string]string{"app.id": appID, "group.id": groupID}
+	body := okta.ApplicationGroupAssignment{}
+
+	if settings != nil {
+		if settings.Priority != nil {
+			body.PriorityPtr = okta.Int64Ptr(*settings.Priority)
+			args["priority"] = strconv.FormatInt(*settings.Priority, 10)
+		}
+
+		if len(settings.Profile) > 0 {
+			body.Profile = settings.Profile
+
+			attrs := make([]string, 0, len(settings.Profile))
+			for k := range settings.Profile {
+				attrs = append(attrs, k)
+			}
+
+			slices.Sort(attrs)
+
+			args["profile.attributes"] = strings.Join(attrs, ",")
+		}
+	}
+
+	if c.simulate("AssignGroupToApplication", args) {
+		return nil
+	}
+
+	assignment, resp, err := c.appIface.CreateApplicationGroupAssignment(ctx, appID, groupID, body)
+	if err := c.audited(ctx, "AssignGroupToApplication", args, resp, err); err != nil {
 		return err
 	}
 
-	c.logger.Debug("output from application group assignment", zap.Any("okta.assignment", assignment))
+	c.logger.Debug("output from application group assignment", redact.Any("okta.assignment", assignment))
 
 	return nil
 }
 
 // RemoveApplicationGroupAssignment removes an application group assignment
 func (c *Client) RemoveApplicationGroupAssignment(ctx context.Context, appID, groupID string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	if appID == "" || groupID == "" {
 		return ErrApplicationBadParameters
 	}
 
 	c.logger.Info("removing okta application group assignments", zap.Any("okta.application.id", appID), zap.Any("okta.group.id", groupID))
 
-	if _, err := c.appIface.DeleteApplicationGroupAssignment(ctx, appID, groupID); err != nil {
+	args := map[string]string{"app.id": appID, "group.id": groupID}
+
+	if c.simulate("RemoveApplicationGroupAssignment", args) {
+		return nil
+	}
+
+	resp, err := c.appIface.DeleteApplicationGroupAssignment(ctx, appID, groupID)
+	if err := c.audited(ctx, "RemoveApplicationGroupAssignment", args, resp, err); err != nil {
 		return err
 	}
 
@@ -115,39 +230,51 @@ func (c *Client) RemoveApplicationGroupAssignment(ctx context.Context, appID, gr
 
 // ListGroupApplicationAssignment returns a list of the groups assigned to an application
 func (c *Client) ListGroupApplicationAssignment(ctx context.Context, appID string) ([]string, error) {
+	assignments, err := c.ListApplicationGroupAssignments(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0, len(assignments))
+
+	for _, a := range assignments {
+		groups = append(groups, a.Id)
+	}
+
+	return groups, nil
+}
+
+// ListApplicationGroupAssignments returns the group assignments of an application with their priority and profile,
+// the id of an assignment is the id of the assigned group
+func (c *Client) ListApplicationGroupAssignments(ctx context.Context, appID string) ([]*okta.ApplicationGroupAssignment, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
 	if appID == "" {
 		return nil, ErrApplicationBadParameters
 	}
 
 	c.logger.Debug("listing okta application group assignments", zap.Any("okta.application.id", appID))
 
-	groups := []string{}
-
 	assignments, resp, err := c.appIface.ListApplicationGroupAssignments(ctx, appID, &query.Params{Limit: defaultPageLimit})
 	if err != nil {
-		return nil, err
+		return nil, apiError(resp, err)
 	}
 
-	c.logger.Debug("output from listing application group assignments", zap.Any("okta.assignment", assignments))
+	c.logger.Debug("output from listing application group assignments", redact.Any("okta.assignment", assignments))
 
-	for _, a := range assignments {
-		groups = append(groups, a.Id)
-	}
+	all := assignments
 
-	for {
-		if !resp.HasNextPage() {
-			break
-		}
+	for resp != nil && resp.HasNextPage() {
+		var page []*okta.ApplicationGroupAssignment
 
-		resp, err = resp.Next(ctx, &assignments)
+		resp, err = resp.Next(ctx, &page)
 		if err != nil {
-			return nil, err
+			return nil, apiError(resp, err)
 		}
 
-		for _, a := range assignments {
-			groups = append(groups, a.Id)
-		}
+		all = append(all, page...)
 	}
 
-	return groups, nil
+	return all, nil
 }
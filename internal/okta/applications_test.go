@@ -17,6 +17,7 @@ type mockApplicationClient struct {
 	resp                *okta.Response
 	apps                []okta.App
 	appGroupAssignments []*okta.ApplicationGroupAssignment
+	assigned            *okta.ApplicationGroupAssignment
 }
 
 func (m *mockApplicationClient) ListApplications(context.Context, *query.Params) ([]okta.App, *okta.Response, error) {
@@ -27,11 +28,13 @@ func (m *mockApplicationClient) ListApplications(context.Context, *query.Params)
 	return m.apps, m.resp, nil
 }
 
-func (m *mockApplicationClient) CreateApplicationGroupAssignment(_ context.Context, _, _ string, _ okta.ApplicationGroupAssignment) (*okta.ApplicationGroupAssignment, *okta.Response, error) {
+func (m *mockApplicationClient) CreateApplicationGroupAssignment(_ context.Context, _, _ string, body okta.ApplicationGroupAssignment) (*okta.ApplicationGroupAssignment, *okta.Response, error) {
 	if m.err != nil {
 		return nil, nil, m.err
 	}
 
+	m.assigned = &body
+
 	return nil, m.resp, nil
 }
 
@@ -67,17 +70,31 @@ func (o *otherApplication) IsApplicationInstance() bool {
 
 func TestClient_AssignGroupToApplication(t *testing.T) {
 	tests := []struct {
-		name    string
-		appID   string
-		groupID string
-		err     error
-		wantErr bool
+		name         string
+		appID        string
+		groupID      string
+		settings     *AppGroupAssignment
+		err          error
+		wantPriority *int64
+		wantProfile  interface{}
+		wantErr      bool
 	}{
 		{
 			name:    "example",
 			appID:   "14270ca5-ea9f-43b7-a560-f2014399bddc",
 			groupID: "39712500-37a8-4102-bce9-432cbe2c28d2",
 		},
+		{
+			name:    "settings",
+			appID:   "14270ca5-ea9f-43b7-a560-f2014399bddc",
+			groupID: "39712500-37a8-4102-bce9-432cbe2c28d2",
+			settings: &AppGroupAssignment{
+				Priority: okta.Int64Ptr(0),
+				Profile:  map[string]interface{}{"role": "member"},
+			},
+			wantPriority: okta.Int64Ptr(0),
+			wantProfile:  map[string]interface{}{"role": "member"},
+		},
 		{
 			name:    "empty appID",
 			groupID: "39712500-37a8-4102-bce9-432cbe2c28d2",
@@ -98,21 +115,53 @@ func TestClient_AssignGroupToApplication(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			m := &mockApplicationClient{
+				t:   t,
+				err: tt.err,
+			}
+
 			c := &Client{
-				logger: zap.NewNop(),
-				appIface: &mockApplicationClient{
-					t:   t,
-					err: tt.err,
-				},
+				logger:   zap.NewNop(),
+				appIface: m,
 			}
 
-			err := c.AssignGroupToApplication(context.TODO(), tt.appID, tt.groupID)
+			err := c.AssignGroupToApplication(context.TODO(), tt.appID, tt.groupID, tt.settings)
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
 			}
 
 			assert.NoError(t, err)
+			assert.Equal(t, tt.wantPriority, m.assigned.PriorityPtr)
+			assert.Equal(t, tt.wantProfile, m.assigned.Profile)
+		})
+	}
+}
+
+func TestAppGroupAssignment_Drifted(t *testing.T) {
+	assignment := &okta.ApplicationGroupAssignment{
+		Id:          "00g1",
+		PriorityPtr: okta.Int64Ptr(2),
+		Profile:     map[string]interface{}{"role": "member", "level": float64(1)},
+	}
+
+	tests := []struct {
+		name     string
+		settings *AppGroupAssignment
+		want     bool
+	}{
+		{name: "no settings"},
+		{name: "empty settings", settings: &AppGroupAssignment{}},
+		{name: "same priority", settings: &AppGroupAssignment{Priority: okta.Int64Ptr(2)}},
+		{name: "priority drift", settings: &AppGroupAssignment{Priority: okta.Int64Ptr(0)}, want: true},
+		{name: "same profile", settings: &AppGroupAssignment{Profile: map[string]interface{}{"level": 1}}},
+		{name: "profile drift", settings: &AppGroupAssignment{Profile: map[string]interface{}{"role": "admin"}}, want: true},
+		{name: "missing profile attribute", settings: &AppGroupAssignment{Profile: map[string]interface{}{"team": "sre"}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.settings.Drifted(assignment))
 		})
 	}
 }
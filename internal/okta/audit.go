@@ -0,0 +1,32 @@
+package okta
+
+import (
+	"context"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// AuditHook is called with every mutating okta call made by a client and its error, nil when the call succeeded.
+// Calls simulated by a recorder aren't made and aren't passed to the hook.
+type AuditHook func(ctx context.Context, m Mutation, err error)
+
+// WithAuditHook sets the hook called with every mutating okta call made by the client, ie. to write an audit event
+// for each of them
+func WithAuditHook(h AuditHook) Option {
+	return func(c *Client) {
+		c.auditHook = h
+	}
+}
+
+// audited translates the error of a mutating call made by the client like apiError and passes the call to the
+// audit hook, it returns the translated error
+func (c *Client) audited(ctx context.Context, method string, args map[string]string, resp *okta.Response, err error) error {
+	err = apiError(resp, err)
+
+	if c.auditHook != nil {
+		c.auditHook(ctx, Mutation{Time: time.Now().UTC(), Method: method, Args: args}, err)
+	}
+
+	return err
+}
@@ -0,0 +1,68 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// auditedCall is a mutating call passed to the audit hook of a test client
+type auditedCall struct {
+	Mutation
+	err error
+}
+
+func TestClient_auditHook(t *testing.T) {
+	calls := []auditedCall{}
+
+	hook := func(_ context.Context, m Mutation, err error) {
+		calls = append(calls, auditedCall{Mutation: m, err: err})
+	}
+
+	groups := &mockGroupClient{t: t, group: &okta.Group{Id: "00g-new"}}
+
+	c := &Client{groupIface: groups, logger: zap.NewNop(), auditHook: hook}
+
+	gid, err := c.CreateGroup(context.TODO(), "testgroup", "my test group", map[string]interface{}{"governor_id": "abc123"})
+	require.NoError(t, err)
+	require.NoError(t, c.AddGroupUser(context.TODO(), gid, "00u-1"))
+
+	// failed calls are audited with the translated error
+	groups.err = &okta.Error{ErrorCode: notFoundErrorCode}
+	groups.resp = &okta.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+
+	err = c.RemoveGroupUser(context.TODO(), gid, "00u-2")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.Len(t, calls, 3)
+
+	assert.Equal(t, "CreateGroup", calls[0].Method)
+	assert.Equal(t, "00g-new", calls[0].Args["group.id"])
+	assert.Equal(t, "testgroup", calls[0].Args["name"])
+	assert.NoError(t, calls[0].err)
+
+	assert.Equal(t, "AddGroupUser", calls[1].Method)
+	assert.Equal(t, map[string]string{"group.id": "00g-new", "user.id": "00u-1"}, calls[1].Args)
+	assert.NoError(t, calls[1].err)
+
+	assert.Equal(t, "RemoveGroupUser", calls[2].Method)
+	assert.ErrorIs(t, calls[2].err, ErrNotFound)
+	assert.False(t, calls[2].Time.IsZero())
+
+	// recorded calls aren't made and aren't audited
+	calls = nil
+	c.recorder = NewRecorder(0)
+
+	require.NoError(t, c.AddGroupUser(context.TODO(), gid, "00u-3"))
+	assert.Empty(t, calls)
+
+	// a client without a hook makes the calls as usual
+	c = &Client{groupIface: &mockGroupClient{t: t, err: errors.New("boom")}, logger: zap.NewNop()} //nolint:goerr113
+	assert.Error(t, c.AddGroupUser(context.TODO(), gid, "00u-4"))
+}
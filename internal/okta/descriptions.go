@@ -0,0 +1,57 @@
+package okta
+
+import "strings"
+
+// GroupDescriptionMarker marks the descriptions of the okta groups managed by governor with a prefix and/or a
+// suffix, ie. "[managed by governor]", so okta admins can tell the groups they shouldn't edit by hand.  The zero
+// value leaves descriptions alone.
+type GroupDescriptionMarker struct {
+	Prefix string
+	Suffix string
+}
+
+// Mark returns the okta group description of a governor group description, a description that's already marked
+// isn't marked twice
+func (m GroupDescriptionMarker) Mark(desc string) string {
+	parts := []string{}
+
+	if m.Prefix != "" {
+		parts = append(parts, m.Prefix)
+	}
+
+	if d := m.Strip(desc); d != "" {
+		parts = append(parts, d)
+	}
+
+	if m.Suffix != "" {
+		parts = append(parts, m.Suffix)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Strip returns the governor group description of an okta group description, without the marker
+func (m GroupDescriptionMarker) Strip(desc string) string {
+	desc = strings.TrimSpace(desc)
+
+	if m.Prefix != "" {
+		desc = strings.TrimSpace(strings.TrimPrefix(desc, m.Prefix))
+	}
+
+	if m.Suffix != "" {
+		desc = strings.TrimSpace(strings.TrimSuffix(desc, m.Suffix))
+	}
+
+	return desc
+}
+
+// Marked returns true if the okta group description has the marker, it's always false for the zero value
+func (m GroupDescriptionMarker) Marked(desc string) bool {
+	if m.Prefix == "" && m.Suffix == "" {
+		return false
+	}
+
+	desc = strings.TrimSpace(desc)
+
+	return strings.HasPrefix(desc, m.Prefix) && strings.HasSuffix(desc, m.Suffix)
+}
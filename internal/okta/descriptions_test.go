@@ -0,0 +1,71 @@
+package okta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupDescriptionMarker(t *testing.T) {
+	tests := []struct {
+		name       string
+		marker     GroupDescriptionMarker
+		desc       string
+		wantMark   string
+		wantStrip  string
+		wantMarked bool
+	}{
+		{
+			name:      "no marker",
+			desc:      "the platform team",
+			wantMark:  "the platform team",
+			wantStrip: "the platform team",
+		},
+		{
+			name:      "prefix",
+			marker:    GroupDescriptionMarker{Prefix: "[managed by governor]"},
+			desc:      "the platform team",
+			wantMark:  "[managed by governor] the platform team",
+			wantStrip: "the platform team",
+		},
+		{
+			name:      "suffix",
+			marker:    GroupDescriptionMarker{Suffix: "(governor)"},
+			desc:      "the platform team",
+			wantMark:  "the platform team (governor)",
+			wantStrip: "the platform team",
+		},
+		{
+			name:       "already marked",
+			marker:     GroupDescriptionMarker{Prefix: "[managed by governor]", Suffix: "(governor)"},
+			desc:       "[managed by governor] the platform team (governor)",
+			wantMark:   "[managed by governor] the platform team (governor)",
+			wantStrip:  "the platform team",
+			wantMarked: true,
+		},
+		{
+			name:       "empty description",
+			marker:     GroupDescriptionMarker{Prefix: "[managed by governor]"},
+			desc:       "",
+			wantMark:   "[managed by governor]",
+			wantStrip:  "",
+			wantMarked: false,
+		},
+		{
+			name:       "only the marker",
+			marker:     GroupDescriptionMarker{Prefix: "[managed by governor]"},
+			desc:       "[managed by governor]",
+			wantMark:   "[managed by governor]",
+			wantStrip:  "",
+			wantMarked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantMark, tt.marker.Mark(tt.desc))
+			assert.Equal(t, tt.wantStrip, tt.marker.Strip(tt.desc))
+			assert.Equal(t, tt.wantMarked, tt.marker.Marked(tt.desc))
+		})
+	}
+}
@@ -1,6 +1,20 @@
 package okta
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+const (
+	// notFoundErrorCode is the okta api error code for a resource that doesn't exist
+	notFoundErrorCode = "E0000007"
+	// forbiddenErrorCode is the okta api error code for a call the api token isn't allowed to make
+	forbiddenErrorCode = "E0000006"
+	// rateLimitedErrorCode is the okta api error code for a call over the rate limit
+	rateLimitedErrorCode = "E0000047"
+)
 
 var (
 	// ErrBadOktaGroupParameter is returned when a bad or unexpected okta group is passed to a function
@@ -15,8 +29,31 @@ var (
 	ErrGroupsNotFound = errors.New("group(s) not found")
 	// ErrUnexpectedGroupsCount is returned when we get an unexpected number of groups, usually != 1
 	ErrUnexpectedGroupsCount = errors.New("unexpected number of groups returned")
+	// ErrUserNotDeactivated is returned when permanently deleting a user that isn't deactivated
+	ErrUserNotDeactivated = errors.New("okta user is not deactivated")
+	// ErrUsersNotFound is returned when a user is not found in Okta
+	ErrUsersNotFound = errors.New("user(s) not found")
+	// ErrUserGovernorIDNotFound is returned when the governor id is not found on a user profile
+	ErrUserGovernorIDNotFound = errors.New("governor id not found on user profile")
+	// ErrUserGovernorIDNotString is returned if the Governor ID on a user is not a string
+	ErrUserGovernorIDNotString = errors.New("governor id on user profile is not a string")
 	// ErrUnexpectedUsersCount is returned when we get an unexpected number of users, usually != 1
 	ErrUnexpectedUsersCount = errors.New("unexpected number of users returned")
+	// ErrGroupUpdateConflict is returned when a group keeps changing in okta while it's being updated
+	ErrGroupUpdateConflict = errors.New("okta group changed during update, too many conflicts")
+	// ErrInvalidUserMatchKey is returned when the user matching key isn't one of email, login or externalId
+	ErrInvalidUserMatchKey = errors.New("invalid user matching key")
+	// ErrInvalidGroupNameTemplate is returned when the okta group name template doesn't parse or doesn't use any
+	// of the governor group fields
+	ErrInvalidGroupNameTemplate = errors.New("invalid okta group name template")
+	// ErrNotFound is returned when okta responds that the requested resource doesn't exist or is gone
+	ErrNotFound = errors.New("okta resource not found")
+	// ErrRateLimited is returned when okta responds that the call is over the rate limit
+	ErrRateLimited = errors.New("okta rate limit exceeded")
+	// ErrConflict is returned when okta responds that the call conflicts with the current state of the resource
+	ErrConflict = errors.New("okta resource conflict")
+	// ErrForbidden is returned when okta responds that the api token isn't allowed to make the call
+	ErrForbidden = errors.New("okta call forbidden")
 	// ErrApplicationBadParameters is returned when bad parameters are not passed to an app request
 	ErrApplicationBadParameters = errors.New("application request bad parameters")
 
@@ -30,6 +67,113 @@ var (
 	ErrOktaUserIDEmpty = errors.New("okta user id empty")
 	// ErrOktaUserLastNameNotString is returned when the okta user profile contains a last name that's not a string
 	ErrOktaUserLastNameNotString = errors.New("okta user last name in profile is not a string")
+	// ErrOktaUserLoginNotString is returned when the okta user profile contains a login that's not a string
+	ErrOktaUserLoginNotString = errors.New("okta user login in profile is not a string")
 	// ErrOktaUserTypeNotString is returned when the okta user profile contains a user type that's not a string
 	ErrOktaUserTypeNotString = errors.New("okta user type in profile is not a string")
 )
+
+// APIError is an error response of the okta api with the okta error code and summary.  It matches ErrNotFound,
+// ErrRateLimited, ErrConflict or ErrForbidden with errors.Is depending on the status and error code of the response,
+// and unwraps to the okta sdk error.
+type APIError struct {
+	// Kind is the typed error of the response, nil when it isn't one of them
+	Kind       error
+	StatusCode int
+	Code       string
+	Summary    string
+	ID         string
+
+	err error
+}
+
+// Error returns the okta sdk error prefixed with the kind and code of the error
+func (e *APIError) Error() string {
+	msg := e.Summary
+
+	if e.err != nil {
+		msg = e.err.Error()
+	}
+
+	if e.Code != "" {
+		msg = e.Code + ": " + msg
+	}
+
+	if e.Kind != nil {
+		msg = e.Kind.Error() + ": " + msg
+	}
+
+	return msg
+}
+
+// Unwrap returns the okta sdk error
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// Is returns true if the target is the kind of the error
+func (e *APIError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// apiError translates the error of an okta call to an APIError with the status of the response and the okta error
+// code, summary and id.  Errors that aren't okta api errors (ie. timeouts) are returned unchanged.
+func apiError(resp *okta.Response, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var mapped *APIError
+	if errors.As(err, &mapped) {
+		return err
+	}
+
+	e := &APIError{err: err}
+
+	if resp != nil && resp.Response != nil {
+		e.StatusCode = resp.StatusCode
+	}
+
+	var oktaErr *okta.Error
+	if errors.As(err, &oktaErr) {
+		e.Code = oktaErr.ErrorCode
+		e.Summary = oktaErr.ErrorSummary
+		e.ID = oktaErr.ErrorId
+	}
+
+	if e.StatusCode == 0 && e.Code == "" {
+		return err
+	}
+
+	switch {
+	case e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone || e.Code == notFoundErrorCode:
+		e.Kind = ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests || e.Code == rateLimitedErrorCode:
+		e.Kind = ErrRateLimited
+	case e.StatusCode == http.StatusConflict:
+		e.Kind = ErrConflict
+	case e.StatusCode == http.StatusForbidden || e.Code == forbiddenErrorCode:
+		e.Kind = ErrForbidden
+	}
+
+	return e
+}
+
+// Retryable returns true if an okta call failed with an error that may go away when the call is retried, ie. a
+// rate limit or a server error.  Missing resources, conflicts and forbidden calls fail the same way again.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) || errors.Is(err, ErrForbidden) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Kind == nil && apiErr.StatusCode >= http.StatusBadRequest && apiErr.StatusCode < http.StatusInternalServerError {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,100 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_apiError(t *testing.T) {
+	errTimeout := errors.New("timeout") //nolint:goerr113
+
+	response := func(status int) *okta.Response {
+		return &okta.Response{Response: &http.Response{StatusCode: status}}
+	}
+
+	tests := []struct {
+		name          string
+		resp          *okta.Response
+		err           error
+		wantKind      error
+		wantRetryable bool
+	}{
+		{name: "nil", resp: response(http.StatusOK), err: nil},
+		{name: "not an api error", err: errTimeout, wantRetryable: true},
+		{name: "not found", resp: response(http.StatusNotFound), err: &okta.Error{ErrorCode: notFoundErrorCode}, wantKind: ErrNotFound},
+		{name: "gone", resp: response(http.StatusGone), err: errTimeout, wantKind: ErrNotFound},
+		{name: "not found code", err: &okta.Error{ErrorCode: notFoundErrorCode}, wantKind: ErrNotFound},
+		{name: "rate limited", resp: response(http.StatusTooManyRequests), err: &okta.Error{ErrorCode: rateLimitedErrorCode}, wantKind: ErrRateLimited, wantRetryable: true},
+		{name: "conflict", resp: response(http.StatusConflict), err: &okta.Error{ErrorCode: "E0000001"}, wantKind: ErrConflict},
+		{name: "forbidden", resp: response(http.StatusForbidden), err: &okta.Error{ErrorCode: forbiddenErrorCode}, wantKind: ErrForbidden},
+		{name: "bad request", resp: response(http.StatusBadRequest), err: &okta.Error{ErrorCode: "E0000001"}},
+		{name: "server error", resp: response(http.StatusInternalServerError), err: &okta.Error{ErrorCode: "E0000009"}, wantRetryable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := apiError(tt.resp, tt.err)
+
+			if tt.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.wantRetryable, Retryable(fmt.Errorf("wrapped: %w", err)))
+
+			if tt.wantKind != nil {
+				assert.ErrorIs(t, err, tt.wantKind)
+			}
+
+			for _, kind := range []error{ErrNotFound, ErrRateLimited, ErrConflict, ErrForbidden} {
+				if kind != tt.wantKind {
+					assert.NotErrorIs(t, err, kind)
+				}
+			}
+
+			// mapping an error twice doesn't wrap it again
+			assert.Equal(t, err, apiError(tt.resp, err))
+		})
+	}
+}
+
+func TestAPIError_fields(t *testing.T) {
+	err := apiError(
+		&okta.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+		&okta.Error{ErrorCode: notFoundErrorCode, ErrorSummary: "Not found: Resource not found: 00u1 (User)", ErrorId: "oae1"},
+	)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, notFoundErrorCode, apiErr.Code)
+	assert.Equal(t, "Not found: Resource not found: 00u1 (User)", apiErr.Summary)
+	assert.Equal(t, "oae1", apiErr.ID)
+	assert.Contains(t, err.Error(), ErrNotFound.Error())
+	assert.Contains(t, err.Error(), notFoundErrorCode)
+}
+
+func TestClient_nextPage_notRetryable(t *testing.T) {
+	c := &Client{logger: zap.NewNop(), pageRetries: 5, pageRetryWait: time.Millisecond}
+
+	calls := 0
+
+	_, err := c.nextPage(context.TODO(), "ListGroupMembership", func() (*okta.Response, error) {
+		calls++
+		return &okta.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}, &okta.Error{ErrorCode: notFoundErrorCode}
+	})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 1, calls)
+}
@@ -3,7 +3,12 @@ package okta
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 	"go.uber.org/zap"
@@ -12,6 +17,9 @@ import (
 const (
 	// GroupProfileGovernorIDKey is the map key for the governor ID in an Okta group profile
 	GroupProfileGovernorIDKey = "governor_id"
+
+	// groupUpdateMaxRetries is the max number of times a group update merge is retried on conflicts
+	groupUpdateMaxRetries = 3
 )
 
 // GroupModifierFunc modifies a an okta group response
@@ -19,20 +27,33 @@ type GroupModifierFunc func(context.Context, *okta.Group) (*okta.Group, error)
 
 // CreateGroup creates a simple group in Okta with a name, description and an extended schema profile
 func (c *Client) CreateGroup(ctx context.Context, name, desc string, profile map[string]interface{}) (string, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Info("creating Okta group",
 		zap.String("okta.group.name", name),
 		zap.String("okta.group.description", desc),
-		zap.Any("okta.group.profile", profile),
+		redact.Any("okta.group.profile", profile),
 	)
 
-	group, _, err := c.groupIface.CreateGroup(ctx, okta.Group{
+	args := map[string]string{"name": name, "description": desc, "profile": jsonArg(profile)}
+
+	if c.simulate("CreateGroup", args) {
+		return SimulatedIDPrefix + name, nil
+	}
+
+	group, resp, err := c.groupIface.CreateGroup(ctx, okta.Group{
 		Profile: &okta.GroupProfile{
 			Name:            name,
 			Description:     desc,
 			GroupProfileMap: okta.GroupProfileMap(profile),
 		},
 	})
-	if err != nil {
+	if err == nil {
+		args["group.id"] = group.Id
+	}
+
+	if err := c.audited(ctx, "CreateGroup", args, resp, err); err != nil {
 		return "", err
 	}
 
@@ -41,36 +62,190 @@ func (c *Client) CreateGroup(ctx context.Context, name, desc string, profile map
 	return group.Id, nil
 }
 
-// UpdateGroup updates a group in Okta and returns the updated group
+// GroupUpdateMerge describes how a group update was merged with the current okta group
+type GroupUpdateMerge struct {
+	// Conflicts is the number of times the group changed in okta while the update was being merged
+	Conflicts int
+	// Preserved are the okta group profile attributes kept from okta that were not part of the update
+	Preserved []string
+	// Unchanged is true when the okta group already matched the update and wasn't written
+	Unchanged bool
+	// Previous is the okta group the update was merged into, as read before it was written
+	Previous *okta.Group
+}
+
+// UpdateGroup updates a group in Okta and returns the updated group, profile attributes
+// that are not part of the update are preserved
 func (c *Client) UpdateGroup(ctx context.Context, id, name, desc string, profile map[string]interface{}) (*okta.Group, error) {
+	group, _, err := c.UpdateGroupMerge(ctx, id, name, desc, profile)
+
+	return group, err
+}
+
+// UpdateGroupMerge fetches the current okta group, merges the name, description and profile attributes
+// into its profile and updates the group. Okta doesn't support conditional group updates, so the group
+// is fetched again before updating and the merge is retried if it changed in the meantime (by comparing
+// lastUpdated). ErrGroupUpdateConflict is returned when the group keeps changing. The group isn't written when
+// its name, description and profile attributes already match the update.
+func (c *Client) UpdateGroupMerge(ctx context.Context, id, name, desc string, profile map[string]interface{}) (*okta.Group, *GroupUpdateMerge, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Info("updating Okta group",
 		zap.String("okta.group.id", id),
 		zap.String("okta.group.name", name),
 		zap.String("okta.group.description", desc),
-		zap.Any("okta.group.profile", profile),
+		redact.Any("okta.group.profile", profile),
 	)
 
-	group, _, err := c.groupIface.UpdateGroup(ctx, id, okta.Group{
-		Profile: &okta.GroupProfile{
-			Name:            name,
-			Description:     desc,
-			GroupProfileMap: okta.GroupProfileMap(profile),
-		},
-	})
+	merge := &GroupUpdateMerge{}
+
+	current, resp, err := c.groupIface.GetGroup(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, apiError(resp, err)
 	}
 
-	c.logger.Debug("updated okta group", zap.String("okta.group.id", id))
+	for {
+		if !groupChanged(current, name, desc, profile) {
+			merge.Unchanged = true
 
-	return group, nil
+			c.logger.Debug("okta group unchanged, skipping update", zap.String("okta.group.id", id))
+
+			return current, merge, nil
+		}
+
+		merged, preserved := mergeGroupProfile(current, name, desc, profile)
+
+		latest, resp, err := c.groupIface.GetGroup(ctx, id)
+		if err != nil {
+			return nil, nil, apiError(resp, err)
+		}
+
+		if !sameLastUpdated(current, latest) {
+			merge.Conflicts++
+
+			c.logger.Warn("okta group changed during update, retrying merge",
+				zap.String("okta.group.id", id),
+				zap.Int("okta.group.update.conflicts", merge.Conflicts),
+			)
+
+			if merge.Conflicts > groupUpdateMaxRetries {
+				return nil, merge, ErrGroupUpdateConflict
+			}
+
+			current = latest
+
+			continue
+		}
+
+		merge.Preserved = preserved
+		merge.Previous = current
+
+		args := map[string]string{
+			"group.id":    id,
+			"name":        merged.Name,
+			"description": merged.Description,
+			"profile":     jsonArg(merged.GroupProfileMap),
+		}
+
+		if c.simulate("UpdateGroup", args) {
+			return &okta.Group{Id: id, Profile: merged}, merge, nil
+		}
+
+		group, resp, err := c.groupIface.UpdateGroup(ctx, id, okta.Group{Profile: merged})
+		if err := c.audited(ctx, "UpdateGroup", args, resp, err); err != nil {
+			return nil, merge, err
+		}
+
+		c.logger.Debug("updated okta group",
+			zap.String("okta.group.id", id),
+			zap.Strings("okta.group.profile.preserved", preserved),
+			zap.Int("okta.group.update.conflicts", merge.Conflicts),
+		)
+
+		return group, merge, nil
+	}
+}
+
+// mergeGroupProfile returns the group profile with the name, description and profile attributes applied
+// on top of the current group profile, and the sorted attributes kept from the current profile
+func mergeGroupProfile(current *okta.Group, name, desc string, profile map[string]interface{}) (*okta.GroupProfile, []string) {
+	merged := okta.GroupProfileMap{}
+	preserved := []string{}
+
+	if current != nil && current.Profile != nil {
+		for k, v := range current.Profile.GroupProfileMap {
+			merged[k] = v
+
+			if _, ok := profile[k]; !ok {
+				preserved = append(preserved, k)
+			}
+		}
+	}
+
+	for k, v := range profile {
+		merged[k] = v
+	}
+
+	sort.Strings(preserved)
+
+	return &okta.GroupProfile{
+		Name:            name,
+		Description:     desc,
+		GroupProfileMap: merged,
+	}, preserved
+}
+
+// groupChanged returns true if the name, description or any of the profile attributes differ from the okta group
+func groupChanged(current *okta.Group, name, desc string, profile map[string]interface{}) bool {
+	if current == nil || current.Profile == nil {
+		return true
+	}
+
+	if current.Profile.Name != name || current.Profile.Description != desc {
+		return true
+	}
+
+	for k, v := range profile {
+		cv, ok := current.Profile.GroupProfileMap[k]
+		if !ok || !reflect.DeepEqual(cv, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameLastUpdated returns true if both groups have the same lastUpdated time
+func sameLastUpdated(a, b *okta.Group) bool {
+	var at, bt time.Time
+
+	if a != nil && a.LastUpdated != nil {
+		at = *a.LastUpdated
+	}
+
+	if b != nil && b.LastUpdated != nil {
+		bt = *b.LastUpdated
+	}
+
+	return at.Equal(bt)
 }
 
 // DeleteGroup deletes a group in Okta
 func (c *Client) DeleteGroup(ctx context.Context, id string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Info("deleting Okta group", zap.String("okta.group.id", id))
 
-	if _, err := c.groupIface.DeleteGroup(ctx, id); err != nil {
+	args := map[string]string{"group.id": id}
+
+	if c.simulate("DeleteGroup", args) {
+		return nil
+	}
+
+	resp, err := c.groupIface.DeleteGroup(ctx, id)
+	if err := c.audited(ctx, "DeleteGroup", args, resp, err); err != nil {
 		return err
 	}
 
@@ -81,13 +256,16 @@ func (c *Client) DeleteGroup(ctx context.Context, id string) error {
 
 // GetGroupByGovernorID gets an okta group ID from the governor id by searching for the profile field
 func (c *Client) GetGroupByGovernorID(ctx context.Context, id string) (string, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Debug("getting okta group by governor id", zap.String("governor.id", id))
 
 	f := fmt.Sprintf("profile.governor_id eq \"%s\"", id)
 
-	groups, _, err := c.groupIface.ListGroups(ctx, &query.Params{Search: f})
+	groups, resp, err := c.groupIface.ListGroups(ctx, &query.Params{Search: f})
 	if err != nil {
-		return "", err
+		return "", apiError(resp, err)
 	}
 
 	if len(groups) == 0 {
@@ -103,22 +281,58 @@ func (c *Client) GetGroupByGovernorID(ctx context.Context, id string) (string, e
 	return gid, nil
 }
 
+// GetGroup gets an okta group by id
+func (c *Client) GetGroup(ctx context.Context, id string) (*okta.Group, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	c.logger.Debug("getting okta group", zap.String("okta.group.id", id))
+
+	group, resp, err := c.groupIface.GetGroup(ctx, id)
+	if err != nil {
+		return nil, apiError(resp, err)
+	}
+
+	return group, nil
+}
+
 // AddGroupUser adds a user to a group by user id and group id
 func (c *Client) AddGroupUser(ctx context.Context, groupID, userID string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Info("adding user to okta group", zap.String("okta.user.id", userID), zap.String("okta.group.id", groupID))
 
-	if _, err := c.groupIface.AddUserToGroup(ctx, groupID, userID); err != nil {
+	args := map[string]string{"group.id": groupID, "user.id": userID}
+
+	if c.simulate("AddGroupUser", args) {
+		return nil
+	}
+
+	resp, err := c.groupIface.AddUserToGroup(ctx, groupID, userID)
+	if err := c.audited(ctx, "AddGroupUser", args, resp, err); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// RemoveGroupUser removes a user from a group by user id and group id
+// RemoveGroupUser removes a user from a group by user id and group id, ErrNotFound is returned when the user or
+// the group doesn't exist in okta anymore
 func (c *Client) RemoveGroupUser(ctx context.Context, groupID, userID string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Info("removing user from okta group", zap.String("okta.user.id", userID), zap.String("okta.group.id", groupID))
 
-	if _, err := c.groupIface.RemoveUserFromGroup(ctx, groupID, userID); err != nil {
+	args := map[string]string{"group.id": groupID, "user.id": userID}
+
+	if c.simulate("RemoveGroupUser", args) {
+		return nil
+	}
+
+	resp, err := c.groupIface.RemoveUserFromGroup(ctx, groupID, userID)
+	if err := c.audited(ctx, "RemoveGroupUser", args, resp, err); err != nil {
 		return err
 	}
 
@@ -127,14 +341,17 @@ func (c *Client) RemoveGroupUser(ctx context.Context, groupID, userID string) er
 
 // ListGroupMembership returns the full list of members of an okta group
 func (c *Client) ListGroupMembership(ctx context.Context, gid string) ([]*okta.User, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
 	c.logger.Debug("listing okta group members", zap.String("okta.group.id", gid))
 
 	users, resp, err := c.groupIface.ListGroupUsers(ctx, gid, &query.Params{Limit: defaultPageLimit})
 	if err != nil {
-		return nil, err
+		return nil, apiError(resp, err)
 	}
 
-	c.logger.Debug("output from listing group users", zap.Any("okta.group.users", users))
+	c.logger.Debug("output from listing group users", redact.Any("okta.group.users", users))
 
 	usersResp := users
 
@@ -143,8 +360,14 @@ func (c *Client) ListGroupMembership(ctx context.Context, gid string) ([]*okta.U
 			break
 		}
 
-		resp, err = resp.Next(ctx, &users)
+		resp, err = c.nextPage(ctx, "ListGroupMembership", func() (*okta.Response, error) {
+			return resp.Next(ctx, &users)
+		})
 		if err != nil {
+			if c.partialListing("ListGroupMembership", len(usersResp), err) {
+				break
+			}
+
 			return nil, err
 		}
 
@@ -154,56 +377,108 @@ func (c *Client) ListGroupMembership(ctx context.Context, gid string) ([]*okta.U
 	return usersResp, nil
 }
 
-// ListGroupsWithModifier lists okta groups and modifies the group response with the given
-// GroupModifierFunc.  If nil is returned from the GroupModifierFunc, the group will not be returned
-// in the response.
-func (c *Client) ListGroupsWithModifier(ctx context.Context, f GroupModifierFunc, q *query.Params) ([]*okta.Group, error) {
-	c.logger.Debug("listing groups with func")
+// FindGroupsByNamePrefix returns the okta groups with a name that starts with the given prefix. This uses
+// the okta group search (q) parameter so we don't have to list every group in the org and filter client side.
+func (c *Client) FindGroupsByNamePrefix(ctx context.Context, prefix string) ([]*okta.Group, error) {
+	if prefix == "" {
+		return nil, ErrBadOktaGroupParameter
+	}
 
-	groups, resp, err := c.groupIface.ListGroups(ctx, q)
+	c.logger.Debug("finding okta groups by name prefix", zap.String("okta.group.prefix", prefix))
+
+	groups, err := c.listGroups(ctx, &query.Params{Q: prefix, Limit: defaultPageLimit})
 	if err != nil {
 		return nil, err
 	}
 
-	groupResp := []*okta.Group{}
+	groupResp := filterGroupsByNamePrefix(groups, prefix)
 
-	for _, g := range groups {
-		c.logger.Debug("running function on group", zap.Any("group", g))
+	c.logger.Debug("returning list of groups by name prefix",
+		zap.String("okta.group.prefix", prefix),
+		zap.Int("num.okta.groups", len(groupResp)),
+	)
 
-		group, err := f(ctx, g)
-		if err != nil {
-			return nil, err
+	return groupResp, nil
+}
+
+// filterGroupsByNamePrefix returns the groups with a name that starts with the prefix (case insensitive).  The okta
+// search parameter is a startsWith match, but we double check since it's not documented as a strict guarantee.
+func filterGroupsByNamePrefix(groups []*okta.Group, prefix string) []*okta.Group {
+	resp := []*okta.Group{}
+
+	for _, g := range groups {
+		if g == nil || g.Profile == nil {
+			continue
 		}
 
-		if group != nil {
-			groupResp = append(groupResp, group)
+		if strings.HasPrefix(strings.ToLower(g.Profile.Name), strings.ToLower(prefix)) {
+			resp = append(resp, g)
 		}
 	}
 
-	for {
-		if !resp.HasNextPage() {
-			break
-		}
+	return resp
+}
+
+// ListGroups returns all of the okta groups matching the search expression, ie. `profile.governor_id pr`, every
+// group is listed when the search is empty.  All of the pages are listed within the list deadline.
+func (c *Client) ListGroups(ctx context.Context, search string) ([]*okta.Group, error) {
+	c.logger.Debug("listing okta groups", zap.String("okta.group.search", search))
+
+	q := &query.Params{Limit: defaultPageLimit}
+	if search != "" {
+		q.Search = search
+	}
+
+	return c.listGroups(ctx, q)
+}
+
+// listGroups lists all of the pages of okta groups for the query parameters
+func (c *Client) listGroups(ctx context.Context, q *query.Params) ([]*okta.Group, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
+	groups, resp, err := c.groupIface.ListGroups(ctx, q)
+	if err != nil {
+		return nil, apiError(resp, err)
+	}
 
+	for resp != nil && resp.HasNextPage() {
 		nextPage := []*okta.Group{}
 
 		resp, err = resp.Next(ctx, &nextPage)
 		if err != nil {
-			return nil, err
+			return nil, apiError(resp, err)
 		}
 
-		for _, g := range nextPage {
-			c.logger.Debug("running function on group", zap.Any("group", g))
+		groups = append(groups, nextPage...)
+	}
 
-			group, err := f(ctx, g)
-			if err != nil {
-				return nil, err
-			}
+	c.logger.Debug("listed okta groups", zap.Int("num.okta.groups", len(groups)))
 
-			if group != nil {
-				groupResp = append(groupResp, group)
-			}
-		}
+	return groups, nil
+}
+
+// ListGroupsWithModifier lists okta groups and modifies the group response with the given
+// GroupModifierFunc.  If nil is returned from the GroupModifierFunc, the group will not be returned
+// in the response.  The GroupModifierFunc is run concurrently when the client concurrency is greater
+// than 1, so it must be safe for concurrent use.  The modifier can be slow, so it runs once all of the
+// groups are listed and isn't limited by the list deadline.
+func (c *Client) ListGroupsWithModifier(ctx context.Context, f GroupModifierFunc, q *query.Params) ([]*okta.Group, error) {
+	c.logger.Debug("listing groups with func")
+
+	groups, err := c.listGroups(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	modifier := func(ctx context.Context, g *okta.Group) (*okta.Group, error) {
+		c.logger.Debug("running function on group", redact.Any("group", g))
+		return f(ctx, g)
+	}
+
+	groupResp, err := modifyAll(ctx, c.concurrency, groups, modifier)
+	if err != nil {
+		return nil, err
 	}
 
 	c.logger.Debug("returning list of groups", zap.Int("num.okta.groups", len(groupResp)))
@@ -211,6 +486,53 @@ func (c *Client) ListGroupsWithModifier(ctx context.Context, f GroupModifierFunc
 	return groupResp, nil
 }
 
+// GovernorGroupsUsersCount returns a map of governor group ids to the number of users in the matching okta
+// group for all of the okta groups that have a governor id.  The counts come from the group stats so this
+// doesn't require listing the members of each group.
+func (c *Client) GovernorGroupsUsersCount(ctx context.Context) (map[string]int, error) {
+	counts := map[string]int{}
+
+	if _, err := c.ListGroupsWithModifier(ctx, func(_ context.Context, g *okta.Group) (*okta.Group, error) {
+		gid, err := GroupGovernorID(g)
+		if err != nil {
+			return nil, nil //nolint:nilerr
+		}
+
+		counts[gid] = GroupUsersCount(g)
+
+		return nil, nil
+	}, &query.Params{Expand: "stats", Limit: defaultPageLimit}); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// GroupUsersCount returns the number of users in the group from the embedded group stats, groups
+// listed without the stats expanded always return 0
+func GroupUsersCount(group *okta.Group) int {
+	if group == nil {
+		return 0
+	}
+
+	embedded, ok := group.Embedded.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	stats, ok := embedded["stats"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	count, ok := stats["usersCount"].(float64)
+	if !ok {
+		return 0
+	}
+
+	return int(count)
+}
+
 // GroupGovernorID gets the governor group id from the okta group profile
 func GroupGovernorID(group *okta.Group) (string, error) {
 	if group == nil {
@@ -249,7 +571,7 @@ func (c *Client) GroupGithubCloudApplications(ctx context.Context, groupID strin
 		return nil, err
 	}
 
-	c.logger.Debug("applications list from Okta", zap.Any("okta.apps", applications))
+	c.logger.Debug("applications list from Okta", redact.Any("okta.apps", applications))
 
 	apps := map[string]string{}
 
@@ -262,12 +584,12 @@ func (c *Client) GroupGithubCloudApplications(ctx context.Context, groupID strin
 		// trudge through the app settings looking for the github org
 		if app.Settings != nil && app.Settings.App != nil {
 			for k, v := range *app.Settings.App {
-				c.logger.Debug("okta app setting", zap.String("okta.app.setting.key", k), zap.Any("okta.app.setting.value", v))
+				c.logger.Debug("okta app setting", zap.String("okta.app.setting.key", k), redact.Any("okta.app.setting.value", v))
 
 				if k == "githubOrg" {
 					org, ok := v.(string)
 					if !ok {
-						c.logger.Warn("okta app setting for githubOrg is not a string", zap.Any("okta.app.settings", *app.Settings.App))
+						c.logger.Warn("okta app setting for githubOrg is not a string", redact.Any("okta.app.settings", *app.Settings.App))
 						break
 					}
 
@@ -282,6 +604,9 @@ func (c *Client) GroupGithubCloudApplications(ctx context.Context, groupID strin
 
 // listAssignedApplicationsForGroup lists the applications that are assigned to a group ID
 func (c *Client) listAssignedApplicationsForGroup(ctx context.Context, groupID string, qp *query.Params) ([]okta.App, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
 	if groupID == "" {
 		return nil, ErrApplicationBadParameters
 	}
@@ -290,10 +615,10 @@ func (c *Client) listAssignedApplicationsForGroup(ctx context.Context, groupID s
 
 	apps, resp, err := c.groupIface.ListAssignedApplicationsForGroup(ctx, groupID, qp)
 	if err != nil {
-		return nil, err
+		return nil, apiError(resp, err)
 	}
 
-	c.logger.Debug("output from listing application group assignments", zap.Any("okta.applications", apps))
+	c.logger.Debug("output from listing application group assignments", redact.Any("okta.applications", apps))
 
 	list := make([]okta.App, len(apps))
 	copy(list, apps)
@@ -305,7 +630,7 @@ func (c *Client) listAssignedApplicationsForGroup(ctx context.Context, groupID s
 
 		resp, err = resp.Next(ctx, &apps)
 		if err != nil {
-			return nil, err
+			return nil, apiError(resp, err)
 		}
 
 		list = append(list, apps...)
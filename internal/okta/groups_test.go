@@ -2,8 +2,14 @@ package okta
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
@@ -20,8 +26,15 @@ type mockGroupClient struct {
 	group  *okta.Group
 	groups []*okta.Group
 
+	// getGroups are returned by GetGroup in order, before falling back to group
+	getGroups []*okta.Group
+	updated   *okta.Group
+
 	users []*okta.User
 
+	// removed are the ids of the groups users were removed from
+	removed []string
+
 	resp *okta.Response
 }
 
@@ -33,11 +46,28 @@ func (m *mockGroupClient) CreateGroup(_ context.Context, _ okta.Group) (*okta.Gr
 	return m.group, m.resp, nil
 }
 
-func (m *mockGroupClient) UpdateGroup(_ context.Context, _ string, _ okta.Group) (*okta.Group, *okta.Response, error) {
+func (m *mockGroupClient) GetGroup(_ context.Context, _ string) (*okta.Group, *okta.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	if len(m.getGroups) > 0 {
+		g := m.getGroups[0]
+		m.getGroups = m.getGroups[1:]
+
+		return g, m.resp, nil
+	}
+
+	return m.group, m.resp, nil
+}
+
+func (m *mockGroupClient) UpdateGroup(_ context.Context, _ string, g okta.Group) (*okta.Group, *okta.Response, error) {
 	if m.err != nil {
 		return nil, nil, m.err
 	}
 
+	m.updated = &g
+
 	return m.group, m.resp, nil
 }
 
@@ -65,11 +95,13 @@ func (m *mockGroupClient) AddUserToGroup(_ context.Context, _, _ string) (*okta.
 	return m.resp, nil
 }
 
-func (m *mockGroupClient) RemoveUserFromGroup(_ context.Context, _, _ string) (*okta.Response, error) {
+func (m *mockGroupClient) RemoveUserFromGroup(_ context.Context, gid, _ string) (*okta.Response, error) {
 	if m.err != nil {
-		return nil, m.err
+		return m.resp, m.err
 	}
 
+	m.removed = append(m.removed, gid)
+
 	return m.resp, nil
 }
 
@@ -206,6 +238,172 @@ func TestClient_UpdateGroup(t *testing.T) {
 	}
 }
 
+func TestClient_UpdateGroupMerge(t *testing.T) {
+	t1 := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+
+	testGroup := func(lastUpdated time.Time, profile map[string]interface{}) *okta.Group {
+		return &okta.Group{
+			Id:          "11111111",
+			LastUpdated: &lastUpdated,
+			Profile: &okta.GroupProfile{
+				Name:            "oldname",
+				Description:     "old description",
+				GroupProfileMap: okta.GroupProfileMap(profile),
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		getGroups     []*okta.Group
+		wantProfile   okta.GroupProfileMap
+		wantConflicts int
+		wantPreserved []string
+		wantErr       bool
+	}{
+		{
+			name:          "no existing attributes",
+			getGroups:     []*okta.Group{testGroup(t1, nil), testGroup(t1, nil)},
+			wantProfile:   okta.GroupProfileMap{"governor_id": "abc123"},
+			wantPreserved: []string{},
+		},
+		{
+			name: "existing attributes are preserved",
+			getGroups: []*okta.Group{
+				testGroup(t1, map[string]interface{}{"governor_id": "old", "owner": "someone"}),
+				testGroup(t1, map[string]interface{}{"governor_id": "old", "owner": "someone"}),
+			},
+			wantProfile:   okta.GroupProfileMap{"governor_id": "abc123", "owner": "someone"},
+			wantPreserved: []string{"owner"},
+		},
+		{
+			name: "concurrent change is merged",
+			getGroups: []*okta.Group{
+				testGroup(t1, nil),
+				testGroup(t2, map[string]interface{}{"owner": "someone"}),
+				testGroup(t2, map[string]interface{}{"owner": "someone"}),
+			},
+			wantProfile:   okta.GroupProfileMap{"governor_id": "abc123", "owner": "someone"},
+			wantConflicts: 1,
+			wantPreserved: []string{"owner"},
+		},
+		{
+			name: "too many conflicts",
+			getGroups: []*okta.Group{
+				testGroup(t1, nil),
+				testGroup(t2, nil),
+				testGroup(t1, nil),
+				testGroup(t2, nil),
+				testGroup(t1, nil),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockGroupClient{
+				t:         t,
+				getGroups: tt.getGroups,
+				group:     &okta.Group{Id: "11111111"},
+			}
+
+			c := &Client{
+				groupIface: m,
+				logger:     zap.NewNop(),
+			}
+
+			_, merge, err := c.UpdateGroupMerge(context.TODO(), "11111111", "testgroup", "my test group", map[string]interface{}{"governor_id": "abc123"})
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrGroupUpdateConflict)
+				assert.Nil(t, m.updated)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantConflicts, merge.Conflicts)
+			assert.Equal(t, tt.wantPreserved, merge.Preserved)
+			assert.Equal(t, "oldname", merge.Previous.Profile.Name)
+			assert.Equal(t, "testgroup", m.updated.Profile.Name)
+			assert.Equal(t, "my test group", m.updated.Profile.Description)
+			assert.Equal(t, tt.wantProfile, m.updated.Profile.GroupProfileMap)
+		})
+	}
+}
+
+func TestClient_UpdateGroupMerge_unchanged(t *testing.T) {
+	lastUpdated := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	current := func(name, desc string, profile map[string]interface{}) *okta.Group {
+		return &okta.Group{
+			Id:          "11111111",
+			LastUpdated: &lastUpdated,
+			Profile: &okta.GroupProfile{
+				Name:            name,
+				Description:     desc,
+				GroupProfileMap: okta.GroupProfileMap(profile),
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		current       *okta.Group
+		wantUnchanged bool
+	}{
+		{
+			name:          "unchanged",
+			current:       current("testgroup", "my test group", map[string]interface{}{"governor_id": "abc123", "owner": "someone"}),
+			wantUnchanged: true,
+		},
+		{
+			name:    "name changed",
+			current: current("oldname", "my test group", map[string]interface{}{"governor_id": "abc123"}),
+		},
+		{
+			name:    "description changed",
+			current: current("testgroup", "old description", map[string]interface{}{"governor_id": "abc123"}),
+		},
+		{
+			name:    "profile attribute changed",
+			current: current("testgroup", "my test group", map[string]interface{}{"governor_id": "old"}),
+		},
+		{
+			name:    "profile attribute missing",
+			current: current("testgroup", "my test group", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockGroupClient{
+				t:         t,
+				getGroups: []*okta.Group{tt.current, tt.current},
+				group:     &okta.Group{Id: "11111111"},
+			}
+
+			c := &Client{
+				groupIface: m,
+				logger:     zap.NewNop(),
+			}
+
+			group, merge, err := c.UpdateGroupMerge(context.TODO(), "11111111", "testgroup", "my test group", map[string]interface{}{"governor_id": "abc123"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantUnchanged, merge.Unchanged)
+
+			if tt.wantUnchanged {
+				assert.Nil(t, m.updated)
+				assert.Equal(t, tt.current, group)
+
+				return
+			}
+
+			assert.NotNil(t, m.updated)
+		})
+	}
+}
+
 func TestClient_DeleteGroup(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -355,11 +553,13 @@ func TestClient_AddGroupUser(t *testing.T) {
 
 func TestClient_RemoveGroupUser(t *testing.T) {
 	tests := []struct {
-		name    string
-		groupID string
-		userID  string
-		err     error
-		wantErr bool
+		name         string
+		groupID      string
+		userID       string
+		resp         *okta.Response
+		err          error
+		wantErr      bool
+		wantNotFound bool
 	}{
 		{
 			name:    "example add user to group",
@@ -373,13 +573,39 @@ func TestClient_RemoveGroupUser(t *testing.T) {
 			err:     errors.New("boom"), //nolint:goerr113
 			wantErr: true,
 		},
+		{
+			name:         "okta not found error code",
+			groupID:      "11111111",
+			userID:       "22222222",
+			err:          &okta.Error{ErrorCode: "E0000007"},
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:         "gone",
+			groupID:      "11111111",
+			userID:       "22222222",
+			resp:         &okta.Response{Response: &http.Response{StatusCode: http.StatusGone}},
+			err:          errors.New("gone"), //nolint:goerr113
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:    "server error",
+			groupID: "11111111",
+			userID:  "22222222",
+			resp:    &okta.Response{Response: &http.Response{StatusCode: http.StatusInternalServerError}},
+			err:     &okta.Error{ErrorCode: "E0000009"},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Client{
 				groupIface: &mockGroupClient{
-					t:   t,
-					err: tt.err,
+					t:    t,
+					err:  tt.err,
+					resp: tt.resp,
 				},
 				logger: zap.NewNop(),
 			}
@@ -387,6 +613,9 @@ func TestClient_RemoveGroupUser(t *testing.T) {
 			err := c.RemoveGroupUser(context.TODO(), tt.groupID, tt.userID)
 			if tt.wantErr {
 				assert.Error(t, err)
+				assert.ErrorIs(t, err, tt.err)
+				assert.Equal(t, tt.wantNotFound, errors.Is(err, ErrNotFound))
+
 				return
 			}
 
@@ -538,6 +767,228 @@ func TestClient_ListGroupsWithModifier(t *testing.T) {
 	}
 }
 
+// newPagedGroupClient returns an okta sdk group client listing the pages of groups from a test server, the page
+// in the failPage position returns an error.  The query of every page request is recorded in queries.
+func newPagedGroupClient(t *testing.T, pages [][]*okta.Group, failPage int, queries *[]string) GroupInterface {
+	t.Helper()
+
+	return newPagedGroupsSDKClient(t, pages, failPage, queries).Group
+}
+
+// newPagedGroupsSDKClient returns an okta sdk client whose group listings (ie. the groups of the org or the groups of
+// a user) list the pages of groups from a test server, like newPagedGroupClient
+func newPagedGroupsSDKClient(t *testing.T, pages [][]*okta.Group, failPage int, queries *[]string) *okta.Client {
+	t.Helper()
+
+	var ts *httptest.Server
+
+	ts = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.RawQuery)
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("after"))
+
+		if page == failPage {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if page+1 < len(pages) {
+			w.Header().Add("Link", fmt.Sprintf(`<%s/api/v1/groups?after=%d>; rel="next"`, ts.URL, page+1))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(pages[page]); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	_, c, err := okta.NewClient(context.TODO(),
+		okta.WithOrgUrl(ts.URL),
+		okta.WithToken("token"),
+		okta.WithCache(false),
+		okta.WithHttpClientPtr(ts.Client()),
+		okta.WithRateLimitMaxRetries(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestClient_ListGroups(t *testing.T) {
+	pages := [][]*okta.Group{
+		{{Id: "group1"}, {Id: "group2"}},
+		{{Id: "group3"}},
+		{{Id: "group4"}},
+	}
+
+	tests := []struct {
+		name        string
+		search      string
+		failPage    int
+		want        []string
+		wantQueries int
+		wantErr     bool
+	}{
+		{
+			name:        "all pages",
+			failPage:    -1,
+			want:        []string{"group1", "group2", "group3", "group4"},
+			wantQueries: 3,
+		},
+		{
+			name:        "search",
+			search:      "profile.governor_id pr",
+			failPage:    -1,
+			want:        []string{"group1", "group2", "group3", "group4"},
+			wantQueries: 3,
+		},
+		{
+			name:        "error on a later page",
+			failPage:    1,
+			wantQueries: 2,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries := []string{}
+
+			c := &Client{
+				logger:     zap.NewNop(),
+				groupIface: newPagedGroupClient(t, pages, tt.failPage, &queries),
+			}
+
+			got, err := c.ListGroups(context.TODO(), tt.search)
+
+			assert.Len(t, queries, tt.wantQueries)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			ids := []string{}
+			for _, g := range got {
+				ids = append(ids, g.Id)
+			}
+
+			assert.Equal(t, tt.want, ids)
+
+			if tt.search != "" {
+				assert.Contains(t, queries[0], "search=profile.governor_id+pr")
+			} else {
+				assert.NotContains(t, queries[0], "search=")
+			}
+		})
+	}
+}
+
+func TestClient_ListGroupsWithModifierPages(t *testing.T) {
+	pages := [][]*okta.Group{
+		{{Id: "group1"}, {Id: "skipMe"}},
+		{{Id: "group2"}},
+	}
+
+	queries := []string{}
+
+	c := &Client{
+		logger:      zap.NewNop(),
+		concurrency: 2,
+		groupIface:  newPagedGroupClient(t, pages, -1, &queries),
+	}
+
+	got, err := c.ListGroupsWithModifier(context.TODO(), func(_ context.Context, g *okta.Group) (*okta.Group, error) {
+		if g.Id == "skipMe" {
+			return nil, nil
+		}
+
+		return g, nil
+	}, &query.Params{Q: "group"})
+	assert.NoError(t, err)
+
+	ids := []string{}
+	for _, g := range got {
+		ids = append(ids, g.Id)
+	}
+
+	assert.ElementsMatch(t, []string{"group1", "group2"}, ids)
+	assert.Len(t, queries, 2)
+	assert.Contains(t, queries[0], "q=group")
+}
+
+func TestClient_FindGroupsByNamePrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		err     error
+		groups  []*okta.Group
+		want    []*okta.Group
+		wantErr bool
+	}{
+		{
+			name:   "example find groups",
+			prefix: "eng-",
+			groups: []*okta.Group{
+				{Id: "group1", Profile: &okta.GroupProfile{Name: "eng-platform"}},
+				{Id: "group2", Profile: &okta.GroupProfile{Name: "ENG-Storage"}},
+				{Id: "group3", Profile: &okta.GroupProfile{Name: "sales-eng"}},
+				{Id: "group4"},
+			},
+			want: []*okta.Group{
+				{Id: "group1", Profile: &okta.GroupProfile{Name: "eng-platform"}},
+				{Id: "group2", Profile: &okta.GroupProfile{Name: "ENG-Storage"}},
+			},
+		},
+		{
+			name:   "no matching groups",
+			prefix: "eng-",
+			groups: []*okta.Group{},
+			want:   []*okta.Group{},
+		},
+		{
+			name:    "empty prefix",
+			prefix:  "",
+			wantErr: true,
+		},
+		{
+			name:    "okta error",
+			prefix:  "eng-",
+			err:     errors.New("boom"), //nolint:goerr113
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				logger: zap.NewNop(),
+				groupIface: &mockGroupClient{
+					t:      t,
+					err:    tt.err,
+					groups: tt.groups,
+					resp:   &okta.Response{},
+				},
+			}
+
+			got, err := c.FindGroupsByNamePrefix(context.TODO(), tt.prefix)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestGroupGovernorID(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -599,6 +1050,49 @@ func TestGroupGovernorID(t *testing.T) {
 	}
 }
 
+func TestGroupUsersCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		group *okta.Group
+		want  int
+	}{
+		{
+			name: "example group with stats",
+			group: &okta.Group{
+				Embedded: map[string]interface{}{
+					"stats": map[string]interface{}{
+						"usersCount": float64(42),
+					},
+				},
+			},
+			want: 42,
+		},
+		{
+			name:  "group without stats",
+			group: &okta.Group{},
+			want:  0,
+		},
+		{
+			name: "unexpected stats type",
+			group: &okta.Group{
+				Embedded: map[string]interface{}{
+					"stats": "nope",
+				},
+			},
+			want: 0,
+		},
+		{
+			name: "nil group",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, GroupUsersCount(tt.group))
+		})
+	}
+}
+
 func TestClient_listAssignedApplicationsForGroup(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -6,12 +6,51 @@ import (
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
 )
 
+var (
+	eventlogPollDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "eventlog_poll_duration_seconds",
+			Help:      "Duration of the okta event log polls.",
+		},
+	)
+
+	eventlogPollErrorsCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "eventlog_poll_errors_total",
+			Help:      "Total count of failed okta event log polls.",
+		},
+	)
+
+	eventlogLastPollGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "eventlog_last_successful_poll_timestamp_seconds",
+			Help:      "Unix time of the last successful okta event log poll.",
+		},
+	)
+
+	eventlogLastEventGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "eventlog_last_event_timestamp_seconds",
+			Help:      "Unix time the last polled okta log event was published, the poller lag is the time since.",
+		},
+	)
+)
+
 // GetLogsBounded returns the okta log events bounded by since and until with the passed query parameters.  Note if we don't
 // pass both since and until to okta, the API assumes this is a polling request and always returns a "NextPage".
 func (c *Client) GetLogsBounded(ctx context.Context, since, until time.Time, qp *query.Params) ([]*okta.LogEvent, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
 	if qp == nil {
 		qp = &query.Params{}
 	}
@@ -24,7 +63,7 @@ func (c *Client) GetLogsBounded(ctx context.Context, since, until time.Time, qp
 
 	events, resp, err := c.logEventIface.GetLogs(ctx, qp)
 	if err != nil {
-		return nil, err
+		return nil, apiError(resp, err)
 	}
 
 	evtsResp := events
@@ -38,7 +77,7 @@ func (c *Client) GetLogsBounded(ctx context.Context, since, until time.Time, qp
 
 		resp, err = resp.Next(ctx, &nextPage)
 		if err != nil {
-			return nil, err
+			return nil, apiError(resp, err)
 		}
 
 		evtsResp = append(evtsResp, nextPage...)
@@ -50,13 +89,23 @@ func (c *Client) GetLogsBounded(ctx context.Context, since, until time.Time, qp
 // LogEventHandlerFn is a handler functions for a log event entry
 type LogEventHandlerFn func(context.Context, *okta.LogEvent)
 
+// LeaderFn reports if this instance is the leader and should be polling
+type LeaderFn func() (bool, error)
+
 // PollLogs starts a goroutine that queries the okta event log api in "polling mode".
 // https://developer.okta.com/docs/reference/api/system-log/#polling-requests
 func (c *Client) PollLogs(ctx context.Context, interval time.Duration, start time.Time, qp *query.Params, handler LogEventHandlerFn) {
-	go c.pollLogs(ctx, interval, start, qp, handler)
+	go c.pollLogs(ctx, interval, start, qp, handler, nil, 0)
 }
 
-func (c *Client) pollLogs(ctx context.Context, interval time.Duration, start time.Time, qp *query.Params, handler LogEventHandlerFn) {
+// PollLogsAsLeader is like PollLogs, but only polls while isLeader returns true and stands by otherwise.  When
+// leadership is gained after standing by, polling restarts from the handoff duration before the current time
+// so events that were in flight with the previous leader aren't missed.
+func (c *Client) PollLogsAsLeader(ctx context.Context, interval time.Duration, start time.Time, handoff time.Duration, qp *query.Params, handler LogEventHandlerFn, isLeader LeaderFn) {
+	go c.pollLogs(ctx, interval, start, qp, handler, isLeader, handoff)
+}
+
+func (c *Client) pollLogs(ctx context.Context, interval time.Duration, start time.Time, qp *query.Params, handler LogEventHandlerFn, isLeader LeaderFn, handoff time.Duration) {
 	if qp == nil {
 		qp = &query.Params{}
 	}
@@ -65,33 +114,76 @@ func (c *Client) pollLogs(ctx context.Context, interval time.Duration, start tim
 
 	tick := time.NewTicker(interval)
 
-	var resp *okta.Response
+	var (
+		resp     *okta.Response
+		standing bool
+	)
 
 	for {
 		select {
 		case <-tick.C:
+			if isLeader != nil {
+				lead, err := isLeader()
+				if err != nil {
+					c.logger.Error("error checking for leader lock, not polling", zap.Error(err))
+					continue
+				}
+
+				if !lead {
+					if !standing {
+						c.logger.Info("not leader, standing by and not polling okta log events")
+					}
+
+					// drop the polling cursor, it will be stale by the time we are leader again
+					standing = true
+					resp = nil
+
+					continue
+				}
+
+				if standing {
+					since := time.Now().UTC().Add(-handoff)
+
+					c.logger.Info("became leader, resuming polling okta log events", zap.Time("events.since", since))
+
+					standing = false
+					qp.Since = since.Format("2006-01-02T15:04:05Z")
+				}
+			}
+
 			c.logger.Debug("running poller loop")
 
 			var err error
 
 			events := []*okta.LogEvent{}
 
+			callCtx, cancel := c.callContext(ctx)
+			started := time.Now()
+
 			if resp == nil {
-				events, resp, err = c.logEventIface.GetLogs(ctx, qp)
-				if err != nil {
-					c.logger.Error("error getting log events from okta", zap.Error(err))
-					continue
-				}
+				events, resp, err = c.logEventIface.GetLogs(callCtx, qp)
 			} else {
-				resp, err = resp.Next(ctx, &events)
-				if err != nil {
-					c.logger.Error("error calling next log events from okta", zap.Error(err))
-					continue
-				}
+				resp, err = resp.Next(callCtx, &events)
+			}
+
+			cancel()
+			eventlogPollDuration.Observe(time.Since(started).Seconds())
+
+			if err != nil {
+				eventlogPollErrorsCounter.Inc()
+				c.logger.Error("error getting log events from okta", zap.Error(err))
+
+				continue
 			}
 
+			eventlogLastPollGauge.SetToCurrentTime()
+
 			for _, evt := range events {
 				handler(ctx, evt)
+
+				if evt.Published != nil {
+					eventlogLastEventGauge.Set(float64(evt.Published.Unix()))
+				}
 			}
 		case <-ctx.Done():
 			tick.Stop()
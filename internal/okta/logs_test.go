@@ -8,6 +8,7 @@ import (
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
@@ -163,6 +164,8 @@ func TestClient_pollLogs(t *testing.T) {
 		func(_ context.Context, le *okta.LogEvent) {
 			events = append(events, le)
 		},
+		nil,
+		0,
 	)
 
 	<-ctx.Done()
@@ -192,9 +195,109 @@ func TestClient_pollLogs(t *testing.T) {
 		func(_ context.Context, le *okta.LogEvent) {
 			events = append(events, le)
 		},
+		nil,
+		0,
 	)
 
 	<-errCtx.Done()
 
 	assert.Equal(t, []*okta.LogEvent{}, errEvents)
 }
+
+func TestClient_pollLogsAsLeader(t *testing.T) {
+	testTime := time.Date(2011, time.September, 20, 15, 15, 00, 00, time.UTC) //nolint:gofumpt
+
+	tests := []struct {
+		name     string
+		isLeader LeaderFn
+		want     []*okta.LogEvent
+	}{
+		{
+			name:     "leader polls",
+			isLeader: func() (bool, error) { return true, nil },
+			want:     testEvents,
+		},
+		{
+			name:     "follower stands by",
+			isLeader: func() (bool, error) { return false, nil },
+			want:     []*okta.LogEvent{},
+		},
+		{
+			name:     "leader check error",
+			isLeader: func() (bool, error) { return false, errors.New("boomsauce") }, //nolint:goerr113
+			want:     []*okta.LogEvent{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
+			defer cancel()
+
+			client := &Client{
+				logger: zap.NewNop(),
+				logEventIface: &mockLogEventsClient{
+					t:         t,
+					logEvents: testEvents,
+					maxIter:   1,
+				},
+			}
+
+			events := []*okta.LogEvent{}
+
+			client.pollLogs(
+				ctx,
+				1*time.Microsecond,
+				testTime,
+				nil,
+				func(_ context.Context, le *okta.LogEvent) {
+					events = append(events, le)
+				},
+				tt.isLeader,
+				time.Minute,
+			)
+
+			<-ctx.Done()
+
+			assert.Equal(t, tt.want, events)
+		})
+	}
+}
+
+func TestClient_pollLogs_metrics(t *testing.T) {
+	testTime := time.Date(2011, time.September, 20, 15, 15, 00, 00, time.UTC) //nolint:gofumpt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	client := &Client{
+		logger: zap.NewNop(),
+		logEventIface: &mockLogEventsClient{
+			t:         t,
+			logEvents: testEvents,
+			maxIter:   1,
+		},
+	}
+
+	client.pollLogs(ctx, time.Millisecond, testTime, nil, func(context.Context, *okta.LogEvent) {}, nil, 0)
+
+	assert.Equal(t, float64(testEvents[2].Published.Unix()), testutil.ToFloat64(eventlogLastEventGauge))
+	assert.Positive(t, testutil.ToFloat64(eventlogLastPollGauge))
+
+	errors0 := testutil.ToFloat64(eventlogPollErrorsCounter)
+
+	errCtx, errCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer errCancel()
+
+	errClient := &Client{
+		logger: zap.NewNop(),
+		logEventIface: &mockLogEventsClient{
+			t:   t,
+			err: errors.New("boomsauce"), //nolint:goerr113
+		},
+	}
+
+	errClient.pollLogs(errCtx, time.Millisecond, testTime, nil, func(context.Context, *okta.LogEvent) {}, nil, 0)
+
+	assert.Greater(t, testutil.ToFloat64(eventlogPollErrorsCounter), errors0)
+}
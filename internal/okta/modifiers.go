@@ -0,0 +1,86 @@
+package okta
+
+import (
+	"context"
+	"sync"
+)
+
+// modifyAll runs the modifier function on each of the items with up to workers goroutines and returns
+// the non-nil results in the same order as the items.  The first error stops any unstarted work and is
+// returned.
+func modifyAll[T comparable](ctx context.Context, workers int, items []T, f func(context.Context, T) (T, error)) ([]T, error) {
+	var zero T
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	results := make([]T, len(items))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	idx := make(chan int)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range idx {
+				res, err := f(ctx, items[i])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+
+					continue
+				}
+
+				results[i] = res
+			}
+		}()
+	}
+
+feed:
+	for i := range items {
+		select {
+		case idx <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(idx)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]T, 0, len(results))
+
+	for _, r := range results {
+		if r != zero {
+			out = append(out, r)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_modifyAll(t *testing.T) {
+	items := []*string{}
+
+	for i := 0; i < 20; i++ {
+		s := strconv.Itoa(i)
+		items = append(items, &s)
+	}
+
+	tests := []struct {
+		name    string
+		workers int
+		f       func(context.Context, *string) (*string, error)
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "serial keeps order and drops nil results",
+			workers: 1,
+			f: func(_ context.Context, s *string) (*string, error) {
+				if n, _ := strconv.Atoi(*s); n%2 == 1 {
+					return nil, nil
+				}
+
+				return s, nil
+			},
+			want: []string{"0", "2", "4", "6", "8", "10", "12", "14", "16", "18"},
+		},
+		{
+			name:    "concurrent keeps order",
+			workers: 8,
+			f: func(_ context.Context, s *string) (*string, error) {
+				n, _ := strconv.Atoi(*s)
+				time.Sleep(time.Duration(20-n) * time.Millisecond)
+
+				if n >= 5 {
+					return nil, nil
+				}
+
+				return s, nil
+			},
+			want: []string{"0", "1", "2", "3", "4"},
+		},
+		{
+			name:    "zero workers is serial",
+			workers: 0,
+			f: func(_ context.Context, s *string) (*string, error) {
+				if *s != "3" {
+					return nil, nil
+				}
+
+				return s, nil
+			},
+			want: []string{"3"},
+		},
+		{
+			name:    "error",
+			workers: 4,
+			f: func(_ context.Context, s *string) (*string, error) {
+				if *s == "7" {
+					return nil, errors.New("boomsauce") //nolint:goerr113
+				}
+
+				return s, nil
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := modifyAll(context.TODO(), tt.workers, items, tt.f)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			gotStrs := []string{}
+			for _, s := range got {
+				gotStrs = append(gotStrs, *s)
+			}
+
+			assert.Equal(t, tt.want, gotStrs)
+		})
+	}
+}
+
+func Test_modifyAll_concurrency(t *testing.T) {
+	items := make([]*int, 16)
+
+	for i := range items {
+		n := i
+		items[i] = &n
+	}
+
+	var running, peak atomic.Int32
+
+	_, err := modifyAll(context.TODO(), 4, items, func(_ context.Context, n *int) (*int, error) {
+		cur := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		return n, nil
+	})
+
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, peak.Load(), int32(4))
+	assert.Greater(t, peak.Load(), int32(1))
+}
@@ -0,0 +1,177 @@
+package okta
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// groupNameMarker surrounds the field names when the group name template is rendered to build its reverse
+// matching expression
+const groupNameMarker = "\x00"
+
+// GroupNameRule renames groups matching the pattern, the replacement is expanded with the capture groups of
+// the pattern, ie. $1 or ${team}
+type GroupNameRule struct {
+	Pattern *regexp.Regexp
+	Replace string
+}
+
+// Apply returns the new name of a group and true if the name matches the rule
+func (r GroupNameRule) Apply(name string) (string, bool) {
+	if r.Pattern == nil {
+		return name, false
+	}
+
+	m := r.Pattern.FindStringSubmatchIndex(name)
+	if m == nil {
+		return name, false
+	}
+
+	return string(r.Pattern.ExpandString(nil, r.Replace, name, m)), true
+}
+
+// GroupNameMapping maps okta group names to governor group names and back, for okta groups following naming
+// conventions that don't slugify cleanly.  The first matching rule renames a group, names matching none of the
+// rules are kept as they are.
+type GroupNameMapping struct {
+	ToGovernor []GroupNameRule
+	ToOkta     []GroupNameRule
+}
+
+// GovernorName returns the governor group name of an okta group name
+func (m GroupNameMapping) GovernorName(oktaName string) string {
+	return applyGroupNameRules(m.ToGovernor, oktaName)
+}
+
+// OktaName returns the okta group name of a governor group name
+func (m GroupNameMapping) OktaName(governorName string) string {
+	return applyGroupNameRules(m.ToOkta, governorName)
+}
+
+func applyGroupNameRules(rules []GroupNameRule, name string) string {
+	for _, rule := range rules {
+		if n, ok := rule.Apply(name); ok {
+			return n
+		}
+	}
+
+	return name
+}
+
+// GroupNameFields are the governor group fields available to the okta group name template
+type GroupNameFields struct {
+	ID   string
+	Name string
+	Slug string
+}
+
+// GroupNameTemplate names the okta groups created for governor groups, ie. "gov-{{ .Slug }}", so they're
+// distinguishable in the okta console.  Okta group names can be matched against the template to find the
+// governor group fields back.
+type GroupNameTemplate struct {
+	text  string
+	tmpl  *template.Template
+	match *regexp.Regexp
+}
+
+// ParseGroupNameTemplate parses an okta group name template, the template must use at least one of the governor
+// group fields
+func ParseGroupNameTemplate(text string) (*GroupNameTemplate, error) {
+	tmpl, err := template.New("okta-group-name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGroupNameTemplate, err)
+	}
+
+	t := &GroupNameTemplate{text: text, tmpl: tmpl}
+
+	// render the template with markers in place of the fields and turn the markers into capture groups
+	rendered, err := t.Execute(GroupNameFields{
+		ID:   groupNameMarker + "ID" + groupNameMarker,
+		Name: groupNameMarker + "Name" + groupNameMarker,
+		Slug: groupNameMarker + "Slug" + groupNameMarker,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(rendered, groupNameMarker)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("%w: %q doesn't use any governor group field", ErrInvalidGroupNameTemplate, text)
+	}
+
+	// fields transformed by the template (ie. truncated) can't be matched back
+	if len(parts)%2 == 0 {
+		return nil, fmt.Errorf("%w: %q transforms the governor group fields", ErrInvalidGroupNameTemplate, text)
+	}
+
+	expr := strings.Builder{}
+	seen := map[string]bool{}
+
+	expr.WriteString("^")
+
+	// even parts are literals and odd parts are field names
+	for i, part := range parts {
+		switch {
+		case i%2 == 0:
+			expr.WriteString(regexp.QuoteMeta(part))
+		case part != "ID" && part != "Name" && part != "Slug":
+			return nil, fmt.Errorf("%w: %q transforms the governor group fields", ErrInvalidGroupNameTemplate, text)
+		case seen[part]:
+			expr.WriteString("(?:.+?)")
+		default:
+			seen[part] = true
+			expr.WriteString("(?P<" + part + ">.+?)")
+		}
+	}
+
+	expr.WriteString("$")
+
+	t.match, err = regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGroupNameTemplate, err)
+	}
+
+	return t, nil
+}
+
+// String returns the text of the template
+func (t *GroupNameTemplate) String() string {
+	return t.text
+}
+
+// Execute returns the okta group name of a governor group
+func (t *GroupNameTemplate) Execute(f GroupNameFields) (string, error) {
+	b := strings.Builder{}
+
+	if err := t.tmpl.Execute(&b, f); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidGroupNameTemplate, err)
+	}
+
+	return b.String(), nil
+}
+
+// Match returns the governor group fields found in an okta group name and true if the name matches the
+// template, fields the template doesn't use are empty
+func (t *GroupNameTemplate) Match(name string) (GroupNameFields, bool) {
+	m := t.match.FindStringSubmatch(name)
+	if m == nil {
+		return GroupNameFields{}, false
+	}
+
+	f := GroupNameFields{}
+
+	for i, field := range t.match.SubexpNames() {
+		switch field {
+		case "ID":
+			f.ID = m[i]
+		case "Name":
+			f.Name = m[i]
+		case "Slug":
+			f.Slug = m[i]
+		}
+	}
+
+	return f, true
+}
@@ -0,0 +1,119 @@
+package okta
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupNameMapping(t *testing.T) {
+	m := GroupNameMapping{
+		ToGovernor: []GroupNameRule{
+			{Pattern: regexp.MustCompile(`^app-(?P<app>[a-z]+)-(?P<team>[a-z]+)-(?P<env>[a-z]+)$`), Replace: "${team} ${app} ${env}"},
+			{Pattern: regexp.MustCompile(`^team-(.+)$`), Replace: "$1"},
+		},
+		ToOkta: []GroupNameRule{
+			{Pattern: regexp.MustCompile(`^([a-z]+) ([a-z]+) ([a-z]+)$`), Replace: "app-$2-$1-$3"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		oktaName     string
+		governorName string
+	}{
+		{name: "first rule", oktaName: "app-billing-payments-prod", governorName: "payments billing prod"},
+		{name: "second rule", oktaName: "team-platform", governorName: "platform"},
+		{name: "no match", oktaName: "Platform Engineering", governorName: "Platform Engineering"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.governorName, m.GovernorName(tt.oktaName))
+		})
+	}
+
+	assert.Equal(t, "app-billing-payments-prod", m.OktaName("payments billing prod"))
+	assert.Equal(t, "Platform Engineering", m.OktaName("Platform Engineering"))
+
+	// an empty mapping keeps the names
+	assert.Equal(t, "app-billing-payments-prod", GroupNameMapping{}.GovernorName("app-billing-payments-prod"))
+	assert.Equal(t, "payments", GroupNameMapping{}.OktaName("payments"))
+}
+
+func TestGroupNameTemplate(t *testing.T) {
+	fields := GroupNameFields{ID: "ba6ffb77-1a5e-4ad4-b18b-5b7a0cb16c06", Name: "Platform Eng", Slug: "platform-eng"}
+
+	tests := []struct {
+		name      string
+		template  string
+		want      string
+		wantMatch GroupNameFields
+		wantErr   bool
+	}{
+		{
+			name:      "slug prefix",
+			template:  "gov-{{ .Slug }}",
+			want:      "gov-platform-eng",
+			wantMatch: GroupNameFields{Slug: "platform-eng"},
+		},
+		{
+			name:      "name and id",
+			template:  "{{ .Name }} (governor {{ .ID }})",
+			want:      "Platform Eng (governor ba6ffb77-1a5e-4ad4-b18b-5b7a0cb16c06)",
+			wantMatch: GroupNameFields{Name: "Platform Eng", ID: "ba6ffb77-1a5e-4ad4-b18b-5b7a0cb16c06"},
+		},
+		{
+			name:      "repeated field",
+			template:  "[{{ .Slug }}] {{ .Slug }}",
+			want:      "[platform-eng] platform-eng",
+			wantMatch: GroupNameFields{Slug: "platform-eng"},
+		},
+		{
+			name:     "no fields",
+			template: "governor",
+			wantErr:  true,
+		},
+		{
+			name:     "transformed field",
+			template: `{{ printf "%.3s" .Slug }}`,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown field",
+			template: "{{ .Team }}",
+			wantErr:  true,
+		},
+		{
+			name:     "parse error",
+			template: "gov-{{ .Slug",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseGroupNameTemplate(tt.template)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidGroupNameTemplate)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			got, err := tmpl.Execute(fields)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			match, ok := tmpl.Match(got)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantMatch, match)
+
+			_, ok = tmpl.Match("Platform Eng")
+			assert.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// user offboarding steps, in the order they run
+const (
+	OffboardStepDeactivate    = "deactivate"
+	OffboardStepClearSessions = "clear-sessions"
+	OffboardStepRemoveGroups  = "remove-groups"
+	OffboardStepDelete        = "delete"
+)
+
+// okta group type of groups managed in okta, built-in groups like Everyone can't be left and app groups are
+// managed by their application
+const oktaGroupType = "OKTA_GROUP"
+
+// OffboardStep is the result of a step of a user offboarding, a step that didn't need to run (ie. deactivating a
+// user that's already deactivated) is skipped
+type OffboardStep struct {
+	Name    string
+	Skipped bool
+	Err     error
+	// Groups are the ids of the okta groups the user was removed from
+	Groups []string
+}
+
+// Status returns done, skipped or failed
+func (s OffboardStep) Status() string {
+	switch {
+	case s.Err != nil:
+		return "failed"
+	case s.Skipped:
+		return "skipped"
+	default:
+		return "done"
+	}
+}
+
+// OffboardResult is the result of each step of a user offboarding, the steps that weren't requested are left out
+type OffboardResult struct {
+	UserID string
+	Steps  []OffboardStep
+}
+
+// Step returns the result of a step and true if the step ran or was skipped
+func (r *OffboardResult) Step(name string) (OffboardStep, bool) {
+	for _, s := range r.Steps {
+		if s.Name == name {
+			return s, true
+		}
+	}
+
+	return OffboardStep{}, false
+}
+
+// Done returns true if the step ran successfully
+func (r *OffboardResult) Done(name string) bool {
+	s, ok := r.Step(name)
+
+	return ok && !s.Skipped && s.Err == nil
+}
+
+// Partial returns true if some of the steps failed and others made changes
+func (r *OffboardResult) Partial() bool {
+	failed, done := false, false
+
+	for _, s := range r.Steps {
+		switch s.Status() {
+		case "failed":
+			failed = true
+		case "done":
+			done = true
+		}
+	}
+
+	return failed && done
+}
+
+// Err returns the errors of the failed steps
+func (r *OffboardResult) Err() error {
+	errs := []error{}
+
+	for _, s := range r.Steps {
+		if s.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name, s.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// String returns the status of each step, ie. deactivate=done,clear-sessions=failed
+func (r *OffboardResult) String() string {
+	steps := make([]string, 0, len(r.Steps))
+
+	for _, s := range r.Steps {
+		steps = append(steps, s.Name+"="+s.Status())
+	}
+
+	return strings.Join(steps, ",")
+}
+
+func (r *OffboardResult) add(s OffboardStep) {
+	r.Steps = append(r.Steps, s)
+}
+
+// WithGroupRemoval removes the user from all of their okta groups after they are deactivated
+func WithGroupRemoval() DeleteUserOption {
+	return func(o *deleteUserOptions) {
+		o.removeGroups = true
+	}
+}
+
+// OffboardUser deactivates a user in Okta (if they aren't already) and, depending on the options, clears their
+// sessions, removes them from all of their okta groups and permanently deletes them.  A failed step doesn't stop
+// the following ones, except for the permanent delete which needs the user to be deactivated, so the result has
+// what was done when some of the steps fail.  The returned error joins the errors of the failed steps.  Without
+// WithPermanentDelete the user is only deactivated and can be reactivated later.
+func (c *Client) OffboardUser(ctx context.Context, id string, opts ...DeleteUserOption) (*OffboardResult, error) {
+	o := &deleteUserOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	result := &OffboardResult{UserID: id}
+
+	user, err := c.GetUser(ctx, id)
+	if err != nil {
+		return result, err
+	}
+
+	c.logger.Debug("got okta user status", zap.String("okta.user.status", user.Status))
+
+	if user.Status == UserStatusDeprovisioned {
+		result.add(OffboardStep{Name: OffboardStepDeactivate, Skipped: true})
+	} else {
+		result.add(OffboardStep{Name: OffboardStepDeactivate, Err: c.DeactivateUser(ctx, id)})
+	}
+
+	if o.clearSessions {
+		result.add(OffboardStep{Name: OffboardStepClearSessions, Err: c.ClearUserSessions(ctx, id)})
+	}
+
+	if o.removeGroups {
+		result.add(c.removeUserGroups(ctx, id))
+	}
+
+	if o.permanent {
+		result.add(c.offboardDelete(ctx, id, result))
+	}
+
+	c.logger.Info("offboarded okta user", zap.String("okta.user.id", id), zap.String("okta.user.offboard.steps", result.String()))
+
+	return result, result.Err()
+}
+
+// removeUserGroups removes the user from all of their okta groups
+func (c *Client) removeUserGroups(ctx context.Context, id string) OffboardStep {
+	step := OffboardStep{Name: OffboardStepRemoveGroups, Groups: []string{}}
+
+	groups, err := c.ListUserGroups(ctx, id)
+	if err != nil {
+		step.Err = err
+		return step
+	}
+
+	errs := []error{}
+
+	for _, g := range groups {
+		if g.Type != oktaGroupType {
+			continue
+		}
+
+		if err := c.RemoveGroupUser(ctx, g.Id, id); err != nil {
+			// the group went away since the user groups were listed
+			if errors.Is(err, ErrNotFound) {
+				c.logger.Warn("okta group not found, user already removed", zap.String("okta.group.id", g.Id), zap.Error(err))
+				continue
+			}
+
+			errs = append(errs, fmt.Errorf("group %s: %w", g.Id, err))
+
+			continue
+		}
+
+		step.Groups = append(step.Groups, g.Id)
+	}
+
+	step.Err = errors.Join(errs...)
+	step.Skipped = step.Err == nil && len(step.Groups) == 0
+
+	return step
+}
+
+// offboardDelete permanently deletes the user once they are deactivated
+func (c *Client) offboardDelete(ctx context.Context, id string, result *OffboardResult) OffboardStep {
+	step := OffboardStep{Name: OffboardStepDelete}
+
+	if s, _ := result.Step(OffboardStepDeactivate); s.Err != nil {
+		step.Err = fmt.Errorf("%w: %s", ErrUserNotDeactivated, id)
+		return step
+	}
+
+	if c.Recording() {
+		// the deactivation was only recorded, so the user isn't deactivated yet
+		c.simulate("PermanentlyDeleteUser", map[string]string{"user.id": id})
+		return step
+	}
+
+	step.Err = c.PermanentlyDeleteUser(ctx, id)
+
+	return step
+}
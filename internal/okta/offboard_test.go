@@ -0,0 +1,179 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestClient_OffboardUser(t *testing.T) {
+	errBoom := errors.New("boom") //nolint:goerr113
+
+	groups := []*okta.Group{
+		{Id: "everyone", Type: "BUILT_IN"},
+		{Id: "group1", Type: oktaGroupType},
+		{Id: "app-group", Type: "APP_GROUP"},
+		{Id: "group2", Type: oktaGroupType},
+	}
+
+	tests := []struct {
+		name          string
+		status        string
+		opts          []DeleteUserOption
+		deactivateErr error
+		sessionsErr   error
+		wantSteps     string
+		wantRemoved   []string
+		wantDeleted   bool
+		wantPartial   bool
+		wantErr       bool
+	}{
+		{
+			name:        "all steps",
+			status:      "ACTIVE",
+			opts:        []DeleteUserOption{WithClearSessions(), WithGroupRemoval(), WithPermanentDelete()},
+			wantSteps:   "deactivate=done,clear-sessions=done,remove-groups=done,delete=done",
+			wantRemoved: []string{"group1", "group2"},
+			wantDeleted: true,
+		},
+		{
+			name:      "already deactivated",
+			status:    UserStatusDeprovisioned,
+			opts:      []DeleteUserOption{WithClearSessions()},
+			wantSteps: "deactivate=skipped,clear-sessions=done",
+		},
+		{
+			name:        "clearing sessions fails",
+			status:      "ACTIVE",
+			opts:        []DeleteUserOption{WithClearSessions(), WithGroupRemoval()},
+			sessionsErr: errBoom,
+			wantSteps:   "deactivate=done,clear-sessions=failed,remove-groups=done",
+			wantRemoved: []string{"group1", "group2"},
+			wantPartial: true,
+			wantErr:     true,
+		},
+		{
+			name:          "deactivation fails",
+			status:        "ACTIVE",
+			opts:          []DeleteUserOption{WithClearSessions(), WithPermanentDelete()},
+			deactivateErr: errBoom,
+			wantSteps:     "deactivate=failed,clear-sessions=done,delete=failed",
+			wantPartial:   true,
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			um := &mockUserClient{
+				t:             t,
+				deactivateErr: tt.deactivateErr,
+				sessionsErr:   tt.sessionsErr,
+				users:         []*okta.User{{Id: "user101", Status: tt.status}},
+				groups:        groups,
+				resp:          &okta.Response{},
+			}
+
+			gm := &mockGroupClient{t: t, resp: &okta.Response{}}
+
+			c := &Client{
+				logger:     zap.NewNop(),
+				userIface:  um,
+				groupIface: gm,
+			}
+
+			result, err := c.OffboardUser(context.TODO(), "user101", tt.opts...)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errBoom)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantSteps, result.String())
+			assert.Equal(t, tt.wantRemoved, gm.removed)
+			assert.Equal(t, tt.wantDeleted, um.deletedUser)
+			assert.Equal(t, tt.wantPartial, result.Partial())
+		})
+	}
+}
+
+func TestClient_OffboardUser_deactivate(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		status      string
+		opts        []DeleteUserOption
+		err         error
+		wantDA      bool
+		wantCleared bool
+		wantDeleted bool
+		wantErr     error
+	}{
+		{
+			name:   "deactivate only by default",
+			id:     "user101",
+			status: "ACTIVE",
+			wantDA: true,
+		},
+		{
+			name:        "deactivate, clear sessions and delete",
+			id:          "user101",
+			status:      "ACTIVE",
+			opts:        []DeleteUserOption{WithClearSessions(), WithPermanentDelete()},
+			wantDA:      true,
+			wantCleared: true,
+			wantDeleted: true,
+		},
+		{
+			name:        "already deactivated",
+			id:          "user101",
+			status:      "DEPROVISIONED",
+			opts:        []DeleteUserOption{WithPermanentDelete()},
+			wantDeleted: true,
+		},
+		{
+			name:    "okta error",
+			id:      "user101",
+			status:  "ACTIVE",
+			err:     errors.New("boom"), //nolint:goerr113
+			wantErr: errors.New("boom"), //nolint:goerr113
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockUserClient{
+				t:     t,
+				err:   tt.err,
+				users: []*okta.User{{Id: tt.id, Status: tt.status}},
+				resp:  &okta.Response{},
+			}
+
+			c := &Client{
+				logger:    zap.NewNop(),
+				userIface: m,
+			}
+
+			_, err := c.OffboardUser(context.TODO(), tt.id, tt.opts...)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+
+				if tt.err == nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+
+				assert.False(t, m.deletedUser)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDA, m.deactivatedUser)
+			assert.Equal(t, tt.wantCleared, m.clearedSessions)
+			assert.Equal(t, tt.wantDeleted, m.deletedUser)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package okta
 
 import (
 	"context"
+	"net/http"
+	"time"
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
@@ -13,12 +15,26 @@ type Client struct {
 	appIface      ApplicationInterface
 	groupIface    GroupInterface
 	logEventIface LogEventInterface
+	ownerIface    GroupOwnerInterface
+	pushIface     PushGroupMappingInterface
 	userIface     UserInterface
 	logger        *zap.Logger
+	httpClient    *http.Client
+	recorder      *Recorder
+	auditHook     AuditHook
 
 	url          string
 	token        string
+	tokens       []string
+	strategy     string
 	cacheEnabled bool
+	concurrency  int
+	callTimeout  time.Duration
+	listTimeout  time.Duration
+
+	pageRetries   int
+	pageRetryWait time.Duration
+	partialPages  bool
 }
 
 // ApplicationInterface abstracts the interactions with okta applications
@@ -33,6 +49,7 @@ type ApplicationInterface interface {
 // GroupInterface is the interface for managing groups in Okta
 type GroupInterface interface {
 	CreateGroup(context.Context, okta.Group) (*okta.Group, *okta.Response, error)
+	GetGroup(context.Context, string) (*okta.Group, *okta.Response, error)
 	UpdateGroup(context.Context, string, okta.Group) (*okta.Group, *okta.Response, error)
 	DeleteGroup(context.Context, string) (*okta.Response, error)
 	ListGroups(context.Context, *query.Params) ([]*okta.Group, *okta.Response, error)
@@ -49,6 +66,8 @@ type UserInterface interface {
 	DeactivateOrDeleteUser(context.Context, string, *query.Params) (*okta.Response, error)
 	GetUser(context.Context, string) (*okta.User, *okta.Response, error)
 	ListUsers(context.Context, *query.Params) ([]*okta.User, *okta.Response, error)
+	ListUserGroups(context.Context, string) ([]*okta.Group, *okta.Response, error)
+	PartialUpdateUser(context.Context, string, okta.User, *query.Params) (*okta.User, *okta.Response, error)
 	SuspendUser(context.Context, string) (*okta.Response, error)
 	UnsuspendUser(context.Context, string) (*okta.Response, error)
 }
@@ -75,6 +94,22 @@ func WithToken(t string) Option {
 	}
 }
 
+// WithSecondaryTokens sets additional okta api tokens that requests are spread over with the token strategy.
+// Whether this helps depends on the org's rate limit policy, since only some okta rate limits are per token.
+func WithSecondaryTokens(t []string) Option {
+	return func(c *Client) {
+		c.tokens = t
+	}
+}
+
+// WithTokenStrategy sets how the api token of each request is selected when there are secondary tokens,
+// TokenStrategyRoundRobin or TokenStrategyLeastUsed. Default TokenStrategyRoundRobin.
+func WithTokenStrategy(s string) Option {
+	return func(c *Client) {
+		c.strategy = s
+	}
+}
+
 // WithCache enabled the okta client memory cache, default enabled.
 func WithCache(t bool) Option {
 	return func(c *Client) {
@@ -82,6 +117,42 @@ func WithCache(t bool) Option {
 	}
 }
 
+// WithHTTPClient overrides the default http client used to talk to okta, ie. to rate limit requests
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = h
+	}
+}
+
+// WithConcurrency sets how many modifier functions are run at once when listing with a modifier, default 1.
+func WithConcurrency(n int) Option {
+	return func(c *Client) {
+		c.concurrency = n
+	}
+}
+
+// WithCallTimeout sets the default deadline for a single okta call, 0 or less disables it. Default DefaultCallTimeout.
+func WithCallTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.callTimeout = d
+	}
+}
+
+// WithListTimeout sets the default deadline for listing calls that page through all of the results, 0 or
+// less disables it. Default DefaultListTimeout.
+func WithListTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.listTimeout = d
+	}
+}
+
+// WithRecorder records the mutating okta calls with the recorder instead of making them
+func WithRecorder(r *Recorder) Option {
+	return func(c *Client) {
+		c.recorder = r
+	}
+}
+
 // WithLogger sets logger
 func WithLogger(l *zap.Logger) Option {
 	return func(c *Client) {
@@ -92,19 +163,49 @@ func WithLogger(l *zap.Logger) Option {
 // NewClient returns a new Okta client
 func NewClient(opts ...Option) (*Client, error) {
 	client := Client{
-		logger: zap.NewNop(),
+		logger:      zap.NewNop(),
+		concurrency: 1,
+		strategy:    TokenStrategyRoundRobin,
+		callTimeout: DefaultCallTimeout,
+		listTimeout: DefaultListTimeout,
+
+		pageRetries:   DefaultPageRetries,
+		pageRetryWait: DefaultPageRetryWait,
 	}
 
 	for _, opt := range opts {
 		opt(&client)
 	}
 
-	_, c, err := okta.NewClient(
-		context.TODO(),
+	oktaOpts := []okta.ConfigSetter{
 		okta.WithOrgUrl(client.url),
 		okta.WithToken(client.token),
 		okta.WithCache(client.cacheEnabled),
-	)
+	}
+
+	if len(client.tokens) > 0 {
+		// same timeout as the okta sdk default http client
+		hc := &http.Client{Timeout: DefaultCallTimeout}
+		if client.httpClient != nil {
+			hc = client.httpClient
+		}
+
+		next := hc.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+
+		sharded := *hc
+		sharded.Transport = newTokenTransport(next, client.strategy, client.token, client.tokens)
+
+		client.httpClient = &sharded
+	}
+
+	if client.httpClient != nil {
+		oktaOpts = append(oktaOpts, okta.WithHttpClientPtr(client.httpClient))
+	}
+
+	_, c, err := okta.NewClient(context.TODO(), oktaOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -113,6 +214,8 @@ func NewClient(opts ...Option) (*Client, error) {
 	client.groupIface = c.Group
 	client.userIface = c.User
 	client.logEventIface = c.LogEvent
+	client.ownerIface = &groupOwnerResource{client: c}
+	client.pushIface = &pushGroupMappingResource{client: c}
 
 	return &client, nil
 }
@@ -0,0 +1,36 @@
+package okta
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// ListGroupsWithGovernorID returns the okta groups with a governor id in their profile
+func (c *Client) ListGroupsWithGovernorID(ctx context.Context) ([]*okta.Group, error) {
+	return c.ListGroups(ctx, fmt.Sprintf("profile.%s pr", GroupProfileGovernorIDKey))
+}
+
+// ClearGroupGovernorID removes the governor id from the profile of an okta group, ie. when its governor group
+// doesn't exist anymore.  The group name, description and other profile attributes are kept.
+func (c *Client) ClearGroupGovernorID(ctx context.Context, id string) error {
+	getCtx, cancel := c.callContext(ctx)
+	group, resp, err := c.groupIface.GetGroup(getCtx, id)
+
+	cancel()
+
+	if err != nil {
+		return apiError(resp, err)
+	}
+
+	if group == nil || group.Profile == nil {
+		return ErrNilGroupProfile
+	}
+
+	_, _, err = c.UpdateGroupMerge(ctx, id, group.Profile.Name, group.Profile.Description, map[string]interface{}{
+		GroupProfileGovernorIDKey: nil,
+	})
+
+	return err
+}
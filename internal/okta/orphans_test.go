@@ -0,0 +1,54 @@
+package okta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestClient_ClearGroupGovernorID(t *testing.T) {
+	m := &mockGroupClient{
+		t: t,
+		group: &okta.Group{
+			Id: "11111111",
+			Profile: &okta.GroupProfile{
+				Name:            "testgroup",
+				Description:     "my test group",
+				GroupProfileMap: okta.GroupProfileMap{"governor_id": "abc123", "team": "platform"},
+			},
+		},
+	}
+
+	c := &Client{
+		groupIface: m,
+		logger:     zap.NewNop(),
+	}
+
+	assert.NoError(t, c.ClearGroupGovernorID(context.TODO(), "11111111"))
+	assert.Equal(t, "testgroup", m.updated.Profile.Name)
+	assert.Equal(t, "my test group", m.updated.Profile.Description)
+	assert.Equal(t, okta.GroupProfileMap{
+		GroupProfileGovernorIDKey: nil,
+		"team":                    "platform",
+	}, m.updated.Profile.GroupProfileMap)
+}
+
+func TestClient_ListGroupsWithGovernorID(t *testing.T) {
+	groups := []*okta.Group{{Id: "group1"}, {Id: "group2"}}
+
+	c := &Client{
+		logger: zap.NewNop(),
+		groupIface: &mockGroupClient{
+			t:      t,
+			groups: groups,
+			resp:   &okta.Response{},
+		},
+	}
+
+	got, err := c.ListGroupsWithGovernorID(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, groups, got)
+}
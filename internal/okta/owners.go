@@ -0,0 +1,172 @@
+package okta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/okta/okta-sdk-golang/v2/okta/query"
+	"go.uber.org/zap"
+)
+
+// GroupOwnerTypeUser is the okta group owner type for users
+const GroupOwnerTypeUser = "USER"
+
+// GroupOwner is an owner of an okta group
+type GroupOwner struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	DisplayName string `json:"displayName,omitempty"`
+	OriginType  string `json:"originType,omitempty"`
+}
+
+// GroupOwnerInterface is the interface for managing group owners in Okta
+type GroupOwnerInterface interface {
+	ListGroupOwners(context.Context, string, *query.Params) ([]*GroupOwner, *okta.Response, error)
+	AssignGroupOwner(context.Context, string, GroupOwner) (*GroupOwner, *okta.Response, error)
+	DeleteGroupOwner(context.Context, string, string) (*okta.Response, error)
+}
+
+// groupOwnerResource implements the group owners api, which isn't supported by the okta sdk
+type groupOwnerResource struct {
+	client *okta.Client
+}
+
+// ListGroupOwners lists the owners of a group
+func (r *groupOwnerResource) ListGroupOwners(ctx context.Context, groupID string, qp *query.Params) ([]*GroupOwner, *okta.Response, error) {
+	url := fmt.Sprintf("/api/v1/groups/%v/owners", groupID)
+	if qp != nil {
+		url += qp.String()
+	}
+
+	rq := r.client.CloneRequestExecutor()
+
+	req, err := rq.WithAccept("application/json").WithContentType("application/json").NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var owners []*GroupOwner
+
+	resp, err := rq.Do(ctx, req, &owners)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return owners, resp, nil
+}
+
+// AssignGroupOwner assigns an owner to a group
+func (r *groupOwnerResource) AssignGroupOwner(ctx context.Context, groupID string, owner GroupOwner) (*GroupOwner, *okta.Response, error) {
+	url := fmt.Sprintf("/api/v1/groups/%v/owners", groupID)
+
+	rq := r.client.CloneRequestExecutor()
+
+	req, err := rq.WithAccept("application/json").WithContentType("application/json").NewRequest(http.MethodPost, url, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out *GroupOwner
+
+	resp, err := rq.Do(ctx, req, &out)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return out, resp, nil
+}
+
+// DeleteGroupOwner removes an owner from a group
+func (r *groupOwnerResource) DeleteGroupOwner(ctx context.Context, groupID, ownerID string) (*okta.Response, error) {
+	url := fmt.Sprintf("/api/v1/groups/%v/owners/%v", groupID, ownerID)
+
+	rq := r.client.CloneRequestExecutor()
+
+	req, err := rq.WithAccept("application/json").WithContentType("application/json").NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return rq.Do(ctx, req, nil)
+}
+
+// ListGroupOwners returns the user ids of the owners of an okta group, owners that aren't users (ie. groups)
+// are ignored
+func (c *Client) ListGroupOwners(ctx context.Context, groupID string) ([]string, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
+	c.logger.Debug("listing okta group owners", zap.String("okta.group.id", groupID))
+
+	owners, resp, err := c.ownerIface.ListGroupOwners(ctx, groupID, &query.Params{Limit: defaultPageLimit})
+	if err != nil {
+		return nil, apiError(resp, err)
+	}
+
+	all := owners
+
+	for resp != nil && resp.HasNextPage() {
+		var page []*GroupOwner
+
+		resp, err = resp.Next(ctx, &page)
+		if err != nil {
+			return nil, apiError(resp, err)
+		}
+
+		all = append(all, page...)
+	}
+
+	ids := []string{}
+
+	for _, o := range all {
+		if o.Type == GroupOwnerTypeUser {
+			ids = append(ids, o.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+// AddGroupOwner makes a user an owner of an okta group
+func (c *Client) AddGroupOwner(ctx context.Context, groupID, userID string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	c.logger.Info("adding owner to okta group", zap.String("okta.user.id", userID), zap.String("okta.group.id", groupID))
+
+	args := map[string]string{"group.id": groupID, "user.id": userID}
+
+	if c.simulate("AddGroupOwner", args) {
+		return nil
+	}
+
+	_, resp, err := c.ownerIface.AssignGroupOwner(ctx, groupID, GroupOwner{ID: userID, Type: GroupOwnerTypeUser})
+	if err := c.audited(ctx, "AddGroupOwner", args, resp, err); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RemoveGroupOwner removes a user from the owners of an okta group
+func (c *Client) RemoveGroupOwner(ctx context.Context, groupID, userID string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	c.logger.Info("removing owner from okta group", zap.String("okta.user.id", userID), zap.String("okta.group.id", groupID))
+
+	args := map[string]string{"group.id": groupID, "user.id": userID}
+
+	if c.simulate("RemoveGroupOwner", args) {
+		return nil
+	}
+
+	resp, err := c.ownerIface.DeleteGroupOwner(ctx, groupID, userID)
+	if err := c.audited(ctx, "RemoveGroupOwner", args, resp, err); err != nil {
+		return err
+	}
+
+	return nil
+}
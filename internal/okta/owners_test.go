@@ -0,0 +1,111 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/okta/okta-sdk-golang/v2/okta/query"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type mockGroupOwnerClient struct {
+	err    error
+	owners []*GroupOwner
+
+	assigned *GroupOwner
+	deleted  string
+}
+
+func (m *mockGroupOwnerClient) ListGroupOwners(_ context.Context, _ string, _ *query.Params) ([]*GroupOwner, *okta.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	return m.owners, &okta.Response{}, nil
+}
+
+func (m *mockGroupOwnerClient) AssignGroupOwner(_ context.Context, _ string, o GroupOwner) (*GroupOwner, *okta.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	m.assigned = &o
+
+	return &o, &okta.Response{}, nil
+}
+
+func (m *mockGroupOwnerClient) DeleteGroupOwner(_ context.Context, _, id string) (*okta.Response, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	m.deleted = id
+
+	return &okta.Response{}, nil
+}
+
+func TestClient_ListGroupOwners(t *testing.T) {
+	tests := []struct {
+		name    string
+		owners  []*GroupOwner
+		err     error
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "user owners",
+			owners: []*GroupOwner{
+				{ID: "user1", Type: GroupOwnerTypeUser},
+				{ID: "group1", Type: "GROUP"},
+				{ID: "user2", Type: GroupOwnerTypeUser},
+			},
+			want: []string{"user1", "user2"},
+		},
+		{
+			name: "no owners",
+			want: []string{},
+		},
+		{
+			name:    "error",
+			err:     errors.New("boom"), //nolint:goerr113
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				logger:     zap.NewNop(),
+				ownerIface: &mockGroupOwnerClient{err: tt.err, owners: tt.owners},
+			}
+
+			got, err := c.ListGroupOwners(context.TODO(), "okta-group")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClient_AddRemoveGroupOwner(t *testing.T) {
+	m := &mockGroupOwnerClient{}
+	c := &Client{logger: zap.NewNop(), ownerIface: m}
+
+	assert.NoError(t, c.AddGroupOwner(context.TODO(), "okta-group", "user1"))
+	assert.Equal(t, &GroupOwner{ID: "user1", Type: GroupOwnerTypeUser}, m.assigned)
+
+	assert.NoError(t, c.RemoveGroupOwner(context.TODO(), "okta-group", "user2"))
+	assert.Equal(t, "user2", m.deleted)
+
+	m.err = errors.New("boom") //nolint:goerr113
+
+	assert.Error(t, c.AddGroupOwner(context.TODO(), "okta-group", "user1"))
+	assert.Error(t, c.RemoveGroupOwner(context.TODO(), "okta-group", "user2"))
+}
@@ -0,0 +1,106 @@
+package okta
+
+import (
+	"context"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultPageRetries is the default number of times a failed page of an okta listing is retried
+	DefaultPageRetries = 2
+	// DefaultPageRetryWait is the default wait before the first retry of a failed page, it doubles on each retry
+	DefaultPageRetryWait = time.Second
+)
+
+var (
+	pageRetriesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "okta_page_retries_total",
+			Help:      "Total count of retried pages of okta listings.",
+		},
+		[]string{"listing"},
+	)
+
+	partialListingsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "okta_partial_listings_total",
+			Help:      "Total count of okta listings that returned the pages listed before a page failed.",
+		},
+		[]string{"listing"},
+	)
+)
+
+// WithPageRetries sets how many times a failed page of a listing is retried and the wait before the first retry,
+// which doubles on each retry. Default DefaultPageRetries and DefaultPageRetryWait.
+func WithPageRetries(n int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.pageRetries = n
+		c.pageRetryWait = wait
+	}
+}
+
+// WithPartialPages returns the pages listed so far instead of failing a listing when a page after the first one
+// still fails once it's retried.  The partial listings are logged and counted, default disabled.
+func WithPartialPages(t bool) Option {
+	return func(c *Client) {
+		c.partialPages = t
+	}
+}
+
+// nextPage gets the next page of a listing with next, retrying it on retryable errors until the context is done
+func (c *Client) nextPage(ctx context.Context, listing string, next func() (*okta.Response, error)) (*okta.Response, error) {
+	wait := c.pageRetryWait
+
+	for attempt := 0; ; attempt++ {
+		resp, err := next()
+		err = apiError(resp, err)
+
+		if err == nil || attempt >= c.pageRetries || ctx.Err() != nil || !Retryable(err) {
+			return resp, err
+		}
+
+		c.logger.Warn("error getting okta listing page, retrying",
+			zap.String("okta.listing", listing),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("wait", wait),
+			zap.Error(err),
+		)
+
+		pageRetriesCounter.WithLabelValues(listing).Inc()
+
+		t := time.NewTimer(wait)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+
+		wait *= 2
+	}
+}
+
+// partialListing returns true if a listing returns the pages listed before a page failed
+func (c *Client) partialListing(listing string, listed int, err error) bool {
+	if !c.partialPages {
+		return false
+	}
+
+	c.logger.Warn("error getting okta listing page, returning partial listing",
+		zap.String("okta.listing", listing),
+		zap.Int("okta.listing.count", listed),
+		zap.Error(err),
+	)
+
+	partialListingsCounter.WithLabelValues(listing).Inc()
+
+	return true
+}
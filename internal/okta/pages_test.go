@@ -0,0 +1,100 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClient_nextPage(t *testing.T) {
+	errPage := errors.New("boom") //nolint:goerr113
+
+	tests := []struct {
+		name      string
+		retries   int
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "first attempt", retries: 2, failures: 0, wantCalls: 1},
+		{name: "recovered", retries: 2, failures: 2, wantCalls: 3},
+		{name: "out of retries", retries: 2, failures: 3, wantCalls: 3, wantErr: true},
+		{name: "no retries", retries: 0, failures: 1, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{logger: zap.NewNop(), pageRetries: tt.retries, pageRetryWait: time.Millisecond}
+
+			calls := 0
+
+			resp, err := c.nextPage(context.TODO(), "ListUsers", func() (*okta.Response, error) {
+				calls++
+				if calls <= tt.failures {
+					return nil, errPage
+				}
+
+				return &okta.Response{}, nil
+			})
+
+			assert.Equal(t, tt.wantCalls, calls)
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errPage)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, resp)
+		})
+	}
+}
+
+func TestClient_nextPage_cancelled(t *testing.T) {
+	c := &Client{logger: zap.NewNop(), pageRetries: 5, pageRetryWait: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+
+	_, err := c.nextPage(ctx, "ListUsers", func() (*okta.Response, error) {
+		calls++
+		return nil, errors.New("boom") //nolint:goerr113
+	})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, calls)
+}
+
+func TestClient_ListGroupMembership_partialPages(t *testing.T) {
+	users := []*okta.User{{Id: "user-01"}, {Id: "user-02"}}
+
+	newClient := func(partial bool) *Client {
+		return &Client{
+			groupIface: &mockGroupClient{
+				t:     t,
+				users: users,
+				// the next page fails since there's no request executor to get it with
+				resp: &okta.Response{NextPage: "https://example.okta.com/api/v1/groups/group-01/users?after=user-02"},
+			},
+			logger:        zap.NewNop(),
+			pageRetries:   1,
+			pageRetryWait: time.Millisecond,
+			partialPages:  partial,
+		}
+	}
+
+	_, err := newClient(false).ListGroupMembership(context.TODO(), "group-01")
+	assert.Error(t, err)
+
+	got, err := newClient(true).ListGroupMembership(context.TODO(), "group-01")
+	require.NoError(t, err)
+	assert.Equal(t, users, got)
+}
@@ -0,0 +1,66 @@
+package okta
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// GroupProfilePendingDeleteKey is the okta group profile key of the time the governor group was deleted, okta
+// groups with the key are deleted once the delete grace period is over
+const GroupProfilePendingDeleteKey = "pending_delete_at"
+
+// SetGroupPendingDelete flags an okta group for deletion with the time its governor group was deleted, the zero
+// time clears the flag.  The group name and description are kept.
+func (c *Client) SetGroupPendingDelete(ctx context.Context, id string, at time.Time) error {
+	var value interface{}
+
+	if !at.IsZero() {
+		value = at.UTC().Format(time.RFC3339)
+	}
+
+	getCtx, cancel := c.callContext(ctx)
+	group, resp, err := c.groupIface.GetGroup(getCtx, id)
+
+	cancel()
+
+	if err != nil {
+		return apiError(resp, err)
+	}
+
+	if group == nil || group.Profile == nil {
+		return ErrNilGroupProfile
+	}
+
+	_, _, err = c.UpdateGroupMerge(ctx, id, group.Profile.Name, group.Profile.Description, map[string]interface{}{
+		GroupProfilePendingDeleteKey: value,
+	})
+
+	return err
+}
+
+// ListGroupsPendingDelete returns the okta groups flagged for deletion
+func (c *Client) ListGroupsPendingDelete(ctx context.Context) ([]*okta.Group, error) {
+	return c.ListGroups(ctx, fmt.Sprintf("profile.%s pr", GroupProfilePendingDeleteKey))
+}
+
+// GroupPendingDeleteAt returns the time an okta group was flagged for deletion and true if it's flagged
+func GroupPendingDeleteAt(group *okta.Group) (time.Time, bool) {
+	if group == nil || group.Profile == nil {
+		return time.Time{}, false
+	}
+
+	v, ok := group.Profile.GroupProfileMap[GroupProfilePendingDeleteKey].(string)
+	if !ok || v == "" {
+		return time.Time{}, false
+	}
+
+	at, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return at, true
+}
@@ -0,0 +1,108 @@
+package okta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestClient_SetGroupPendingDelete(t *testing.T) {
+	at := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.FixedZone("test", 3600))
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want interface{}
+	}{
+		{name: "flag", at: at, want: "2023-03-01T11:00:00Z"},
+		{name: "clear", at: time.Time{}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockGroupClient{
+				t: t,
+				group: &okta.Group{
+					Id: "11111111",
+					Profile: &okta.GroupProfile{
+						Name:            "testgroup",
+						Description:     "my test group",
+						GroupProfileMap: okta.GroupProfileMap{"governor_id": "abc123"},
+					},
+				},
+			}
+
+			c := &Client{
+				groupIface: m,
+				logger:     zap.NewNop(),
+			}
+
+			assert.NoError(t, c.SetGroupPendingDelete(context.TODO(), "11111111", tt.at))
+			assert.Equal(t, "testgroup", m.updated.Profile.Name)
+			assert.Equal(t, "my test group", m.updated.Profile.Description)
+			assert.Equal(t, okta.GroupProfileMap{
+				"governor_id":                "abc123",
+				GroupProfilePendingDeleteKey: tt.want,
+			}, m.updated.Profile.GroupProfileMap)
+		})
+	}
+}
+
+func TestClient_ListGroupsPendingDelete(t *testing.T) {
+	groups := []*okta.Group{{Id: "group1"}, {Id: "group2"}}
+
+	c := &Client{
+		logger: zap.NewNop(),
+		groupIface: &mockGroupClient{
+			t:      t,
+			groups: groups,
+			resp:   &okta.Response{},
+		},
+	}
+
+	got, err := c.ListGroupsPendingDelete(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, groups, got)
+}
+
+func TestGroupPendingDeleteAt(t *testing.T) {
+	at := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		group  *okta.Group
+		want   time.Time
+		wantOk bool
+	}{
+		{
+			name:   "flagged",
+			group:  &okta.Group{Profile: &okta.GroupProfile{GroupProfileMap: okta.GroupProfileMap{GroupProfilePendingDeleteKey: "2023-03-01T12:00:00Z"}}},
+			want:   at,
+			wantOk: true,
+		},
+		{
+			name:  "cleared",
+			group: &okta.Group{Profile: &okta.GroupProfile{GroupProfileMap: okta.GroupProfileMap{GroupProfilePendingDeleteKey: nil}}},
+		},
+		{
+			name:  "not a time",
+			group: &okta.Group{Profile: &okta.GroupProfile{GroupProfileMap: okta.GroupProfileMap{GroupProfilePendingDeleteKey: "tomorrow"}}},
+		},
+		{
+			name:  "nil profile",
+			group: &okta.Group{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := GroupPendingDeleteAt(tt.group)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.True(t, tt.want.Equal(got))
+		})
+	}
+}
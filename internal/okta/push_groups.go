@@ -0,0 +1,264 @@
+package okta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/okta/okta-sdk-golang/v2/okta/query"
+	"go.uber.org/zap"
+)
+
+const (
+	// PushGroupMappingActive is the status of a push group mapping pushing the group to the application
+	PushGroupMappingActive = "ACTIVE"
+	// PushGroupMappingInactive is the status of a paused push group mapping, mappings are deactivated before
+	// they're deleted
+	PushGroupMappingInactive = "INACTIVE"
+	// PushGroupMappingError is the status of a push group mapping that failed to push the group
+	PushGroupMappingError = "ERROR"
+)
+
+// PushGroupMapping maps an okta group to a group of an application (ie. a github team) the okta group members are
+// pushed to.  The target group name is only used when creating a mapping, the target group is then created by okta.
+type PushGroupMapping struct {
+	ID              string     `json:"id,omitempty"`
+	SourceGroupID   string     `json:"sourceGroupId"`
+	TargetGroupID   string     `json:"targetGroupId,omitempty"`
+	TargetGroupName string     `json:"targetGroupName,omitempty"`
+	Status          string     `json:"status,omitempty"`
+	ErrorSummary    string     `json:"errorSummary,omitempty"`
+	LastPush        *time.Time `json:"lastPush,omitempty"`
+}
+
+// PushGroupMappingInterface is the interface for managing the push group mappings of applications in Okta
+type PushGroupMappingInterface interface {
+	ListPushGroupMappings(context.Context, string, *query.Params) ([]*PushGroupMapping, *okta.Response, error)
+	GetPushGroupMapping(context.Context, string, string) (*PushGroupMapping, *okta.Response, error)
+	CreatePushGroupMapping(context.Context, string, PushGroupMapping) (*PushGroupMapping, *okta.Response, error)
+	UpdatePushGroupMapping(context.Context, string, string, PushGroupMapping) (*PushGroupMapping, *okta.Response, error)
+	DeletePushGroupMapping(context.Context, string, string) (*okta.Response, error)
+}
+
+// pushGroupMappingResource implements the push group mappings api, which isn't supported by the okta sdk
+type pushGroupMappingResource struct {
+	client *okta.Client
+}
+
+// ListPushGroupMappings lists the push group mappings of an application
+func (r *pushGroupMappingResource) ListPushGroupMappings(ctx context.Context, appID string, qp *query.Params) ([]*PushGroupMapping, *okta.Response, error) {
+	url := fmt.Sprintf("/api/v1/apps/%v/group-push/mappings", appID)
+	if qp != nil {
+		url += qp.String()
+	}
+
+	var mappings []*PushGroupMapping
+
+	resp, err := r.do(ctx, http.MethodGet, url, nil, &mappings)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return mappings, resp, nil
+}
+
+// GetPushGroupMapping gets a push group mapping of an application
+func (r *pushGroupMappingResource) GetPushGroupMapping(ctx context.Context, appID, mappingID string) (*PushGroupMapping, *okta.Response, error) {
+	url := fmt.Sprintf("/api/v1/apps/%v/group-push/mappings/%v", appID, mappingID)
+
+	var out *PushGroupMapping
+
+	resp, err := r.do(ctx, http.MethodGet, url, nil, &out)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return out, resp, nil
+}
+
+// CreatePushGroupMapping creates a push group mapping of an application
+func (r *pushGroupMappingResource) CreatePushGroupMapping(ctx context.Context, appID string, m PushGroupMapping) (*PushGroupMapping, *okta.Response, error) {
+	url := fmt.Sprintf("/api/v1/apps/%v/group-push/mappings", appID)
+
+	var out *PushGroupMapping
+
+	resp, err := r.do(ctx, http.MethodPost, url, m, &out)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return out, resp, nil
+}
+
+// UpdatePushGroupMapping updates the status of a push group mapping of an application
+func (r *pushGroupMappingResource) UpdatePushGroupMapping(ctx context.Context, appID, mappingID string, m PushGroupMapping) (*PushGroupMapping, *okta.Response, error) {
+	url := fmt.Sprintf("/api/v1/apps/%v/group-push/mappings/%v", appID, mappingID)
+
+	var out *PushGroupMapping
+
+	resp, err := r.do(ctx, http.MethodPatch, url, struct {
+		Status string `json:"status"`
+	}{m.Status}, &out)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return out, resp, nil
+}
+
+// DeletePushGroupMapping deletes an inactive push group mapping of an application, the target group is kept
+func (r *pushGroupMappingResource) DeletePushGroupMapping(ctx context.Context, appID, mappingID string) (*okta.Response, error) {
+	url := fmt.Sprintf("/api/v1/apps/%v/group-push/mappings/%v?deleteTargetGroup=false", appID, mappingID)
+
+	return r.do(ctx, http.MethodDelete, url, nil, nil)
+}
+
+// do sends a push group mapping request with the body, if any, and decodes the response into v
+func (r *pushGroupMappingResource) do(ctx context.Context, method, url string, body, v interface{}) (*okta.Response, error) {
+	rq := r.client.CloneRequestExecutor()
+
+	req, err := rq.WithAccept("application/json").WithContentType("application/json").NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return rq.Do(ctx, req, v)
+}
+
+// ListPushGroupMappings returns the push group mappings of an okta application
+func (c *Client) ListPushGroupMappings(ctx context.Context, appID string) ([]*PushGroupMapping, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
+	if appID == "" {
+		return nil, ErrApplicationBadParameters
+	}
+
+	c.logger.Debug("listing okta push group mappings", zap.String("okta.app.id", appID))
+
+	mappings, resp, err := c.pushIface.ListPushGroupMappings(ctx, appID, &query.Params{Limit: defaultPageLimit})
+	if err != nil {
+		return nil, apiError(resp, err)
+	}
+
+	all := mappings
+
+	for resp != nil && resp.HasNextPage() {
+		var page []*PushGroupMapping
+
+		resp, err = resp.Next(ctx, &page)
+		if err != nil {
+			return nil, apiError(resp, err)
+		}
+
+		all = append(all, page...)
+	}
+
+	return all, nil
+}
+
+// PushGroupMappingStatus returns a push group mapping of an okta application with its current status
+func (c *Client) PushGroupMappingStatus(ctx context.Context, appID, mappingID string) (*PushGroupMapping, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	if appID == "" || mappingID == "" {
+		return nil, ErrApplicationBadParameters
+	}
+
+	m, resp, err := c.pushIface.GetPushGroupMapping(ctx, appID, mappingID)
+	if err != nil {
+		return nil, apiError(resp, err)
+	}
+
+	return m, nil
+}
+
+// CreatePushGroupMapping pushes an okta group to a new group of an okta application with the target name, ie. a
+// github team, and returns the active mapping
+func (c *Client) CreatePushGroupMapping(ctx context.Context, appID, groupID, targetName string) (*PushGroupMapping, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	if appID == "" || groupID == "" || targetName == "" {
+		return nil, ErrApplicationBadParameters
+	}
+
+	c.logger.Info("creating okta push group mapping",
+		zap.String("okta.app.id", appID),
+		zap.String("okta.group.id", groupID),
+		zap.String("target.group.name", targetName),
+	)
+
+	args := map[string]string{"app.id": appID, "group.id": groupID, "target.name": targetName}
+
+	if c.simulate("CreatePushGroupMapping", args) {
+		return &PushGroupMapping{ID: SimulatedIDPrefix + groupID, SourceGroupID: groupID, Status: PushGroupMappingActive}, nil
+	}
+
+	m, resp, err := c.pushIface.CreatePushGroupMapping(ctx, appID, PushGroupMapping{
+		SourceGroupID:   groupID,
+		TargetGroupName: targetName,
+		Status:          PushGroupMappingActive,
+	})
+	if err == nil {
+		args["mapping.id"] = m.ID
+	}
+
+	if err := c.audited(ctx, "CreatePushGroupMapping", args, resp, err); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ActivatePushGroupMapping activates an inactive push group mapping of an okta application
+func (c *Client) ActivatePushGroupMapping(ctx context.Context, appID, mappingID string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	if appID == "" || mappingID == "" {
+		return ErrApplicationBadParameters
+	}
+
+	c.logger.Info("activating okta push group mapping", zap.String("okta.app.id", appID), zap.String("okta.mapping.id", mappingID))
+
+	args := map[string]string{"app.id": appID, "mapping.id": mappingID}
+
+	if c.simulate("ActivatePushGroupMapping", args) {
+		return nil
+	}
+
+	_, resp, err := c.pushIface.UpdatePushGroupMapping(ctx, appID, mappingID, PushGroupMapping{Status: PushGroupMappingActive})
+
+	return c.audited(ctx, "ActivatePushGroupMapping", args, resp, err)
+}
+
+// DeletePushGroupMapping stops pushing an okta group to an okta application, the mapping is deactivated and deleted
+// but the target group (ie. the github team) is left alone
+func (c *Client) DeletePushGroupMapping(ctx context.Context, appID, mappingID string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	if appID == "" || mappingID == "" {
+		return ErrApplicationBadParameters
+	}
+
+	c.logger.Info("deleting okta push group mapping", zap.String("okta.app.id", appID), zap.String("okta.mapping.id", mappingID))
+
+	args := map[string]string{"app.id": appID, "mapping.id": mappingID}
+
+	if c.simulate("DeletePushGroupMapping", args) {
+		return nil
+	}
+
+	// okta only deletes inactive mappings
+	_, resp, err := c.pushIface.UpdatePushGroupMapping(ctx, appID, mappingID, PushGroupMapping{Status: PushGroupMappingInactive})
+	if err == nil {
+		resp, err = c.pushIface.DeletePushGroupMapping(ctx, appID, mappingID)
+	}
+
+	return c.audited(ctx, "DeletePushGroupMapping", args, resp, err)
+}
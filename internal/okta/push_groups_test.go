@@ -0,0 +1,129 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/okta/okta-sdk-golang/v2/okta/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type mockPushGroupMappingClient struct {
+	err      error
+	mappings []*PushGroupMapping
+
+	created  *PushGroupMapping
+	statuses []string
+	deleted  string
+}
+
+func (m *mockPushGroupMappingClient) ListPushGroupMappings(_ context.Context, _ string, _ *query.Params) ([]*PushGroupMapping, *okta.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	return m.mappings, &okta.Response{}, nil
+}
+
+func (m *mockPushGroupMappingClient) GetPushGroupMapping(_ context.Context, _, id string) (*PushGroupMapping, *okta.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	for _, pm := range m.mappings {
+		if pm.ID == id {
+			return pm, &okta.Response{}, nil
+		}
+	}
+
+	return nil, &okta.Response{}, ErrNotFound
+}
+
+func (m *mockPushGroupMappingClient) CreatePushGroupMapping(_ context.Context, _ string, pm PushGroupMapping) (*PushGroupMapping, *okta.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	m.created = &pm
+
+	out := pm
+	out.ID = "mapping-" + pm.SourceGroupID
+
+	return &out, &okta.Response{}, nil
+}
+
+func (m *mockPushGroupMappingClient) UpdatePushGroupMapping(_ context.Context, _, _ string, pm PushGroupMapping) (*PushGroupMapping, *okta.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	m.statuses = append(m.statuses, pm.Status)
+
+	return &pm, &okta.Response{}, nil
+}
+
+func (m *mockPushGroupMappingClient) DeletePushGroupMapping(_ context.Context, _, id string) (*okta.Response, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	m.deleted = id
+
+	return &okta.Response{}, nil
+}
+
+func TestClient_ListPushGroupMappings(t *testing.T) {
+	mappings := []*PushGroupMapping{
+		{ID: "mapping-1", SourceGroupID: "group-1", TargetGroupID: "team-1", Status: PushGroupMappingActive},
+		{ID: "mapping-2", SourceGroupID: "group-2", Status: PushGroupMappingError, ErrorSummary: "team exists"},
+	}
+
+	m := &mockPushGroupMappingClient{mappings: mappings}
+	c := &Client{logger: zap.NewNop(), pushIface: m}
+
+	got, err := c.ListPushGroupMappings(context.TODO(), "app-1")
+	require.NoError(t, err)
+	assert.Equal(t, mappings, got)
+
+	status, err := c.PushGroupMappingStatus(context.TODO(), "app-1", "mapping-2")
+	require.NoError(t, err)
+	assert.Equal(t, PushGroupMappingError, status.Status)
+
+	_, err = c.ListPushGroupMappings(context.TODO(), "")
+	assert.ErrorIs(t, err, ErrApplicationBadParameters)
+
+	m.err = errors.New("boom") //nolint:goerr113
+
+	_, err = c.ListPushGroupMappings(context.TODO(), "app-1")
+	assert.Error(t, err)
+}
+
+func TestClient_CreateDeletePushGroupMapping(t *testing.T) {
+	m := &mockPushGroupMappingClient{}
+	c := &Client{logger: zap.NewNop(), pushIface: m}
+
+	got, err := c.CreatePushGroupMapping(context.TODO(), "app-1", "group-1", "platform")
+	require.NoError(t, err)
+	assert.Equal(t, "mapping-group-1", got.ID)
+	assert.Equal(t, &PushGroupMapping{SourceGroupID: "group-1", TargetGroupName: "platform", Status: PushGroupMappingActive}, m.created)
+
+	require.NoError(t, c.ActivatePushGroupMapping(context.TODO(), "app-1", "mapping-1"))
+	require.NoError(t, c.DeletePushGroupMapping(context.TODO(), "app-1", "mapping-2"))
+
+	// mappings are deactivated before they're deleted
+	assert.Equal(t, []string{PushGroupMappingActive, PushGroupMappingInactive}, m.statuses)
+	assert.Equal(t, "mapping-2", m.deleted)
+
+	_, err = c.CreatePushGroupMapping(context.TODO(), "app-1", "group-1", "")
+	assert.ErrorIs(t, err, ErrApplicationBadParameters)
+
+	m.err = errors.New("boom") //nolint:goerr113
+
+	_, err = c.CreatePushGroupMapping(context.TODO(), "app-1", "group-1", "platform")
+	assert.Error(t, err)
+	assert.Error(t, c.DeletePushGroupMapping(context.TODO(), "app-1", "mapping-2"))
+}
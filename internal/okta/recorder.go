@@ -0,0 +1,117 @@
+package okta
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
+	"go.uber.org/zap"
+)
+
+const (
+	// SimulatedIDPrefix is the prefix of the ids returned for okta objects created while recording
+	SimulatedIDPrefix = "simulated:"
+
+	// defaultMaxRecordedMutations is the default max number of mutations kept by a recorder
+	defaultMaxRecordedMutations = 10000
+)
+
+// Mutation is a mutating okta call captured by a recorder
+type Mutation struct {
+	Time   time.Time         `json:"time"`
+	Method string            `json:"method"`
+	Args   map[string]string `json:"args"`
+}
+
+// Recorder captures the mutating okta calls of a client instead of making them, so a run can be simulated
+// and the exact list of okta changes it would make can be reported.  Read calls still go to okta.
+type Recorder struct {
+	mu        sync.Mutex
+	max       int
+	mutations []Mutation
+	dropped   int
+}
+
+// NewRecorder returns a recorder that keeps up to max mutations, dropping the oldest ones once it's
+// full.  A max of 0 or less uses the default.
+func NewRecorder(maxMutations int) *Recorder {
+	if maxMutations <= 0 {
+		maxMutations = defaultMaxRecordedMutations
+	}
+
+	return &Recorder{max: maxMutations}
+}
+
+// record adds a mutation to the recorder
+func (r *Recorder) record(method string, args map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.mutations) >= r.max {
+		r.mutations = r.mutations[1:]
+		r.dropped++
+	}
+
+	r.mutations = append(r.mutations, Mutation{Time: time.Now().UTC(), Method: method, Args: args})
+}
+
+// Mutations returns the recorded mutations in the order they were made
+func (r *Recorder) Mutations() []Mutation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Mutation{}, r.mutations...)
+}
+
+// Dropped returns the number of mutations dropped because the recorder was full
+func (r *Recorder) Dropped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.dropped
+}
+
+// Reset removes the recorded mutations
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mutations = nil
+	r.dropped = 0
+}
+
+// Recording returns true if the mutating calls of the client are recorded instead of made
+func (c *Client) Recording() bool {
+	return c.recorder != nil
+}
+
+// Recorder returns the recorder of the client, nil when the client isn't recording
+func (c *Client) Recorder() *Recorder {
+	return c.recorder
+}
+
+// simulate records a mutating call when the client is recording and returns true if the call should
+// not be made
+func (c *Client) simulate(method string, args map[string]string) bool {
+	if c.recorder == nil {
+		return false
+	}
+
+	c.logger.Info("SIMULATE okta mutation", zap.String("okta.method", method), redact.Any("okta.args", args))
+
+	c.recorder.record(method, args)
+
+	return true
+}
+
+// jsonArg formats a structured argument of a recorded mutation
+func jsonArg(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,57 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClient_Recording(t *testing.T) {
+	rec := NewRecorder(0)
+
+	c := &Client{
+		// any call that reaches okta fails
+		groupIface: &mockGroupClient{t: t, err: errors.New("boom")}, //nolint:goerr113
+		logger:     zap.NewNop(),
+		recorder:   rec,
+	}
+
+	assert.True(t, c.Recording())
+
+	gid, err := c.CreateGroup(context.TODO(), "testgroup", "my test group", map[string]interface{}{"governor_id": "abc123"})
+	require.NoError(t, err)
+	assert.Equal(t, SimulatedIDPrefix+"testgroup", gid)
+
+	require.NoError(t, c.AddGroupUser(context.TODO(), gid, "22222222"))
+	require.NoError(t, c.RemoveGroupUser(context.TODO(), "11111111", "33333333"))
+
+	mutations := rec.Mutations()
+	require.Len(t, mutations, 3)
+
+	assert.Equal(t, "CreateGroup", mutations[0].Method)
+	assert.Equal(t, `{"governor_id":"abc123"}`, mutations[0].Args["profile"])
+	assert.Equal(t, "AddGroupUser", mutations[1].Method)
+	assert.Equal(t, map[string]string{"group.id": gid, "user.id": "22222222"}, mutations[1].Args)
+	assert.Equal(t, "RemoveGroupUser", mutations[2].Method)
+
+	rec.Reset()
+	assert.Empty(t, rec.Mutations())
+}
+
+func TestRecorder_Dropped(t *testing.T) {
+	rec := NewRecorder(2)
+
+	rec.record("first", nil)
+	rec.record("second", nil)
+	rec.record("third", nil)
+
+	mutations := rec.Mutations()
+	require.Len(t, mutations, 2)
+	assert.Equal(t, "second", mutations[0].Method)
+	assert.Equal(t, "third", mutations[1].Method)
+	assert.Equal(t, 1, rec.Dropped())
+}
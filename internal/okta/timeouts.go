@@ -0,0 +1,45 @@
+package okta
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultCallTimeout is the default deadline for a single okta call
+	DefaultCallTimeout = 30 * time.Second
+	// DefaultListTimeout is the default deadline for listing calls that page through all of the results
+	DefaultListTimeout = 5 * time.Minute
+)
+
+type timeoutContextKey struct{}
+
+// ContextWithTimeout overrides the client default timeouts for the okta calls made with the returned
+// context, ie. for a call that is known to be slow. A timeout of 0 disables the deadline.
+func ContextWithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutContextKey{}, d)
+}
+
+// callContext returns a context with the single call deadline
+func (c *Client) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return timeoutContext(ctx, c.callTimeout)
+}
+
+// listContext returns a context with the listing deadline
+func (c *Client) listContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return timeoutContext(ctx, c.listTimeout)
+}
+
+// timeoutContext returns a context with the timeout from the context override or the default, a
+// timeout of 0 (or less) returns the context as is
+func timeoutContext(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if override, ok := ctx.Value(timeoutContextKey{}).(time.Duration); ok {
+		d = override
+	}
+
+	if d <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,70 @@
+package okta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_timeoutContext(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		override     *time.Duration
+		wantDeadline bool
+		wantMax      time.Duration
+	}{
+		{
+			name:         "default timeout",
+			timeout:      DefaultCallTimeout,
+			wantDeadline: true,
+			wantMax:      DefaultCallTimeout,
+		},
+		{
+			name:    "default disabled",
+			timeout: 0,
+		},
+		{
+			name:         "override",
+			timeout:      DefaultCallTimeout,
+			override:     durationPtr(time.Second),
+			wantDeadline: true,
+			wantMax:      time.Second,
+		},
+		{
+			name:     "override disables",
+			timeout:  DefaultCallTimeout,
+			override: durationPtr(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{callTimeout: tt.timeout, listTimeout: tt.timeout}
+
+			ctx := context.TODO()
+			if tt.override != nil {
+				ctx = ContextWithTimeout(ctx, *tt.override)
+			}
+
+			for _, f := range []func(context.Context) (context.Context, context.CancelFunc){c.callContext, c.listContext} {
+				got, cancel := f(ctx)
+
+				deadline, ok := got.Deadline()
+				assert.Equal(t, tt.wantDeadline, ok)
+
+				if tt.wantDeadline {
+					assert.LessOrEqual(t, time.Until(deadline), tt.wantMax)
+				}
+
+				cancel()
+			}
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
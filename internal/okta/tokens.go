@@ -0,0 +1,153 @@
+package okta
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// TokenStrategyRoundRobin spreads the okta requests evenly over the api tokens
+	TokenStrategyRoundRobin = "round-robin"
+	// TokenStrategyLeastUsed sends each okta request with the api token that has the most rate limit remaining
+	TokenStrategyLeastUsed = "least-used"
+
+	// oktaAuthScheme is the authorization scheme of okta api tokens
+	oktaAuthScheme = "SSWS "
+)
+
+var (
+	tokenRequestsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "okta_token_requests_total",
+			Help:      "Total count of okta requests by api token.",
+		},
+		[]string{"token"},
+	)
+
+	tokenRateLimitRemainingGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "okta_token_rate_limit_remaining",
+			Help:      "Okta rate limit remaining from the last response by api token.",
+		},
+		[]string{"token"},
+	)
+)
+
+// apiToken is an okta api token and the rate limit state from its last response
+type apiToken struct {
+	name      string
+	token     string
+	remaining int
+	reset     time.Time
+}
+
+// tokenTransport sets the okta api token of each request from a pool of tokens. Okta rate limits are
+// tracked per token for some endpoints (depending on the org's rate limit policy), so spreading the
+// requests over several tokens can keep large reconciles under the limits.
+type tokenTransport struct {
+	next     http.RoundTripper
+	strategy string
+
+	mu     sync.Mutex
+	tokens []*apiToken
+	last   int
+}
+
+// newTokenTransport returns a transport that uses the primary and secondary tokens with the given
+// selection strategy
+func newTokenTransport(next http.RoundTripper, strategy, primary string, secondary []string) *tokenTransport {
+	t := &tokenTransport{
+		next:     next,
+		strategy: strategy,
+		tokens:   []*apiToken{{name: "primary", token: primary, remaining: -1}},
+		last:     -1,
+	}
+
+	for i, s := range secondary {
+		t.tokens = append(t.tokens, &apiToken{name: fmt.Sprintf("secondary-%d", i+1), token: s, remaining: -1})
+	}
+
+	return t
+}
+
+// RoundTrip sends the request with the selected token and records the rate limit of the response
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok := t.pick(time.Now())
+
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", oktaAuthScheme+tok.token)
+
+	tokenRequestsCounter.WithLabelValues(tok.name).Inc()
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	t.update(tok, resp.Header)
+
+	return resp, nil
+}
+
+// pick returns the token for the next request
+func (t *tokenTransport) pick(now time.Time) *apiToken {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.strategy != TokenStrategyLeastUsed {
+		t.last = (t.last + 1) % len(t.tokens)
+
+		return t.tokens[t.last]
+	}
+
+	best, bestRemaining := 0, -1
+
+	for i := range t.tokens {
+		// start after the last picked token so ties are spread out
+		idx := (t.last + 1 + i) % len(t.tokens)
+
+		remaining := t.tokens[idx].remaining
+		if remaining < 0 || now.After(t.tokens[idx].reset) {
+			// unknown or already reset
+			remaining = math.MaxInt
+		}
+
+		if remaining > bestRemaining {
+			best, bestRemaining = idx, remaining
+		}
+	}
+
+	t.last = best
+
+	return t.tokens[best]
+}
+
+// update records the rate limit headers of a response for the token
+func (t *tokenTransport) update(tok *apiToken, h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-Rate-Limit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(h.Get("X-Rate-Limit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tok.remaining = remaining
+	tok.reset = time.Unix(reset, 0)
+
+	tokenRateLimitRemainingGauge.WithLabelValues(tok.name).Set(float64(remaining))
+}
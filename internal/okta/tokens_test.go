@@ -0,0 +1,104 @@
+package okta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenTransport_pick(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		strategy string
+		state    func(tokens []*apiToken)
+		want     []string
+	}{
+		{
+			name:     "round robin",
+			strategy: TokenStrategyRoundRobin,
+			want:     []string{"primary", "secondary-1", "secondary-2", "primary"},
+		},
+		{
+			name:     "least used without rate limits spreads out",
+			strategy: TokenStrategyLeastUsed,
+			want:     []string{"primary", "secondary-1", "secondary-2", "primary"},
+		},
+		{
+			name:     "least used prefers the most remaining",
+			strategy: TokenStrategyLeastUsed,
+			state: func(tokens []*apiToken) {
+				tokens[0].remaining, tokens[0].reset = 10, now.Add(time.Minute)
+				tokens[1].remaining, tokens[1].reset = 50, now.Add(time.Minute)
+				tokens[2].remaining, tokens[2].reset = 20, now.Add(time.Minute)
+			},
+			want: []string{"secondary-1", "secondary-1"},
+		},
+		{
+			name:     "least used ignores rate limits that already reset",
+			strategy: TokenStrategyLeastUsed,
+			state: func(tokens []*apiToken) {
+				tokens[0].remaining, tokens[0].reset = 0, now.Add(-time.Minute)
+				tokens[1].remaining, tokens[1].reset = 50, now.Add(time.Minute)
+				tokens[2].remaining, tokens[2].reset = 20, now.Add(time.Minute)
+			},
+			want: []string{"primary"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTokenTransport(http.DefaultTransport, tt.strategy, "token0", []string{"token1", "token2"})
+
+			if tt.state != nil {
+				tt.state(tr.tokens)
+			}
+
+			got := []string{}
+			for range tt.want {
+				got = append(got, tr.pick(now).name)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTokenTransport_RoundTrip(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Unix()
+	auth := []string{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+
+		w.Header().Set("X-Rate-Limit-Remaining", "42")
+		w.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(reset, 10))
+	}))
+	defer ts.Close()
+
+	tr := newTokenTransport(http.DefaultTransport, TokenStrategyRoundRobin, "token0", []string{"token1"})
+	hc := &http.Client{Transport: tr}
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		assert.NoError(t, err)
+
+		req.Header.Set("Authorization", "SSWS token0")
+
+		resp, err := hc.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, []string{"SSWS token0", "SSWS token1"}, auth)
+
+	for _, tok := range tr.tokens {
+		assert.Equal(t, 42, tok.remaining)
+		assert.Equal(t, reset, tok.reset.Unix())
+	}
+}
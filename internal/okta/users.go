@@ -3,42 +3,73 @@ package okta
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 	"go.uber.org/zap"
 )
 
+// UserProfileGovernorIDKey is the map key for the governor ID in an Okta user profile.  It's a custom attribute
+// that has to be added to the okta user profile schema before it can be set.
+const UserProfileGovernorIDKey = "governor_id"
+
+// UserStatusDeprovisioned is the status of deactivated okta users
+const UserStatusDeprovisioned = "DEPROVISIONED"
+
 // UserModifierFunc modifies a an okta user response
 type UserModifierFunc func(context.Context, *okta.User) (*okta.User, error)
 
 // UserDetails contains the details of an Okta user
 type UserDetails struct {
-	ID     string
-	Name   string
-	Email  string
-	Status string
+	ID          string
+	Name        string
+	Email       string
+	SecondEmail string
+	Login       string
+	Status      string
+	GovernorID  string
+
+	// LastUpdated and StatusChanged are when the okta user last changed, zero when they're unknown
+	LastUpdated   time.Time
+	StatusChanged time.Time
 }
 
 // GetUser gets an okta user by id
 func (c *Client) GetUser(ctx context.Context, id string) (*okta.User, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Debug("getting okta user", zap.String("okta.user.id", id))
 
-	user, _, err := c.userIface.GetUser(ctx, id)
+	user, resp, err := c.userIface.GetUser(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, apiError(resp, err)
 	}
 
-	c.logger.Debug("returning okta user", zap.Any("okta.user", user))
+	c.logger.Debug("returning okta user", redact.Any("okta.user", user))
 
 	return user, nil
 }
 
 // DeactivateUser deactivates a user in Okta
 func (c *Client) DeactivateUser(ctx context.Context, id string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Info("deactivating okta user", zap.String("okta.user.id", id))
 
-	if _, err := c.userIface.DeactivateUser(ctx, id, &query.Params{}); err != nil {
+	args := map[string]string{"user.id": id}
+
+	if c.simulate("DeactivateUser", args) {
+		return nil
+	}
+
+	resp, err := c.userIface.DeactivateUser(ctx, id, &query.Params{})
+	if err := c.audited(ctx, "DeactivateUser", args, resp, err); err != nil {
 		return err
 	}
 
@@ -47,44 +78,79 @@ func (c *Client) DeactivateUser(ctx context.Context, id string) error {
 	return nil
 }
 
-// DeleteUser deletes a user in Okta
-// since Okta requires that a user must be first deactivated before being deleted, we do this in two steps
-func (c *Client) DeleteUser(ctx context.Context, id string) error {
-	c.logger.Info("deleting okta user", zap.String("okta.user.id", id))
+// DeleteUserOption is a functional option for OffboardUser
+type DeleteUserOption func(*deleteUserOptions)
+
+type deleteUserOptions struct {
+	clearSessions bool
+	removeGroups  bool
+	permanent     bool
+}
 
-	// look up the user in okta so we can get their status
-	user, _, err := c.userIface.GetUser(ctx, id)
+// WithClearSessions clears the user's sessions after they are deactivated
+func WithClearSessions() DeleteUserOption {
+	return func(o *deleteUserOptions) {
+		o.clearSessions = true
+	}
+}
+
+// WithPermanentDelete permanently deletes the user after they are deactivated, a permanently deleted user and
+// their history can't be restored
+func WithPermanentDelete() DeleteUserOption {
+	return func(o *deleteUserOptions) {
+		o.permanent = true
+	}
+}
+
+// PermanentlyDeleteUser permanently deletes a deactivated user in Okta, the user and their history can't
+// be restored.  Okta deactivates active users on the first delete call, so users that aren't deactivated
+// yet are refused instead.
+func (c *Client) PermanentlyDeleteUser(ctx context.Context, id string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	user, resp, err := c.userIface.GetUser(ctx, id)
 	if err != nil {
-		return err
+		return apiError(resp, err)
 	}
 
-	c.logger.Debug("got okta user status", zap.String("okta.user.status", user.Status))
+	if user.Status != UserStatusDeprovisioned {
+		return fmt.Errorf("%w: %s is %s", ErrUserNotDeactivated, id, user.Status)
+	}
 
-	// make sure the user is deactivated first
-	if user.Status != "DEPROVISIONED" {
-		c.logger.Debug("deactivating user", zap.String("okta.user.id", id))
+	c.logger.Info("permanently deleting okta user", zap.String("okta.user.id", id))
 
-		if _, err := c.userIface.DeactivateUser(ctx, id, &query.Params{}); err != nil {
-			return err
-		}
+	args := map[string]string{"user.id": id}
+
+	if c.simulate("PermanentlyDeleteUser", args) {
+		return nil
 	}
 
-	if _, err := c.userIface.DeactivateOrDeleteUser(ctx, id, &query.Params{}); err != nil {
+	resp, err = c.userIface.DeactivateOrDeleteUser(ctx, id, &query.Params{})
+	if err := c.audited(ctx, "PermanentlyDeleteUser", args, resp, err); err != nil {
 		return err
 	}
 
-	// TODO clear any sessions in Okta
-
-	c.logger.Debug("deleted okta user", zap.String("okta.user.id", id))
+	c.logger.Debug("permanently deleted okta user", zap.String("okta.user.id", id))
 
 	return nil
 }
 
 // ClearUserSessions removes all active idp sessiosn and forces the user to reauthenticate.
 func (c *Client) ClearUserSessions(ctx context.Context, id string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Info("clearing user sessions", zap.String("okta.user.id", id))
 
-	if _, err := c.userIface.ClearUserSessions(ctx, id, &query.Params{}); err != nil {
+	args := map[string]string{"user.id": id}
+
+	if c.simulate("ClearUserSessions", args) {
+		return nil
+	}
+
+	resp, err := c.userIface.ClearUserSessions(ctx, id, &query.Params{})
+	if err := c.audited(ctx, "ClearUserSessions", args, resp, err); err != nil {
 		return err
 	}
 
@@ -93,35 +159,282 @@ func (c *Client) ClearUserSessions(ctx context.Context, id string) error {
 	return nil
 }
 
+// UserMatchKey is the okta user attribute that governor users are matched on
+type UserMatchKey string
+
+const (
+	// UserMatchKeyEmail matches the governor user email with the okta profile email
+	UserMatchKeyEmail UserMatchKey = "email"
+	// UserMatchKeyLogin matches the governor user email with the okta profile login, which stays stable when
+	// the email of a user is rotated (ie. after an email domain change)
+	UserMatchKeyLogin UserMatchKey = "login"
+	// UserMatchKeyExternalID matches the governor user external id with the okta user id
+	UserMatchKeyExternalID UserMatchKey = "externalId"
+)
+
+// ParseUserMatchKey parses a user matching key, an empty key is the email
+func ParseUserMatchKey(s string) (UserMatchKey, error) {
+	switch k := UserMatchKey(s); k {
+	case "":
+		return UserMatchKeyEmail, nil
+	case UserMatchKeyEmail, UserMatchKeyLogin, UserMatchKeyExternalID:
+		return k, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidUserMatchKey, s)
+	}
+}
+
+// UserMatchValue returns the value of the matching key for an okta user
+func UserMatchValue(u *okta.User, key UserMatchKey) (string, error) {
+	switch key {
+	case UserMatchKeyExternalID:
+		if u.Id == "" {
+			return "", ErrOktaUserIDEmpty
+		}
+
+		return u.Id, nil
+	case UserMatchKeyLogin:
+		return LoginFromUserProfile(u)
+	default:
+		return EmailFromUserProfile(u)
+	}
+}
+
+// UserMatchAliases returns the other okta user attributes a governor user email is matched with, in order, when it
+// doesn't match the matching key of an okta user: the secondEmail and login when matching by email, the email and
+// secondEmail when matching by login, and none when matching by externalId
+func UserMatchAliases(u *okta.User, key UserMatchKey) []string {
+	if u == nil || u.Profile == nil {
+		return nil
+	}
+
+	attr := func(name string) string {
+		s, _ := (*u.Profile)[name].(string)
+		return s
+	}
+
+	return matchAliases(key, attr("email"), attr("login"), attr("secondEmail"))
+}
+
+// MatchAliases returns the match aliases of the okta user details, like UserMatchAliases
+func (d *UserDetails) MatchAliases(key UserMatchKey) []string {
+	return matchAliases(key, d.Email, d.Login, d.SecondEmail)
+}
+
+// matchAliases returns the non-empty aliases in precedence order, without the value of the matching key or duplicates
+func matchAliases(key UserMatchKey, email, login, secondEmail string) []string {
+	var primary string
+
+	var candidates []string
+
+	switch key {
+	case UserMatchKeyExternalID:
+		return nil
+	case UserMatchKeyLogin:
+		primary, candidates = login, []string{email, secondEmail}
+	default:
+		primary, candidates = email, []string{secondEmail, login}
+	}
+
+	aliases := []string{}
+
+	for _, a := range candidates {
+		if a == "" || strings.EqualFold(a, primary) || slices.ContainsFunc(aliases, func(s string) bool { return strings.EqualFold(s, a) }) {
+			continue
+		}
+
+		aliases = append(aliases, a)
+	}
+
+	return aliases
+}
+
+// GetUserIDBy gets an okta user id by the given matching key, the value is the governor user email or, when
+// matching by externalId, the governor user external id
+func (c *Client) GetUserIDBy(ctx context.Context, key UserMatchKey, value string) (string, error) {
+	if key == UserMatchKeyExternalID {
+		// the governor external id is the okta user id
+		u, err := c.GetUser(ctx, value)
+		if err != nil {
+			return "", err
+		}
+
+		return u.Id, nil
+	}
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	attr := "email"
+	if key == UserMatchKeyLogin {
+		attr = "login"
+	}
+
+	c.logger.Debug("getting okta user by "+attr, zap.String("user."+attr, value))
+
+	f := fmt.Sprintf("profile.%s eq \"%s\"", attr, value)
+
+	users, resp, err := c.userIface.ListUsers(ctx, &query.Params{Search: f})
+	if err != nil {
+		return "", apiError(resp, err)
+	}
+
+	if len(users) != 1 {
+		return "", ErrUnexpectedUsersCount
+	}
+
+	uid := users[0].Id
+
+	c.logger.Debug("found okta user by "+attr, zap.String("user."+attr, value), zap.String("okta.user.id", uid))
+
+	return uid, nil
+}
+
 // GetUserIDByEmail gets an okta user id from the user's email address
 func (c *Client) GetUserIDByEmail(ctx context.Context, email string) (string, error) {
-	c.logger.Debug("getting okta user by email", zap.String("user.email", email))
+	return c.GetUserIDBy(ctx, UserMatchKeyEmail, email)
+}
+
+// GetUserIDByGovernorID gets an okta user id from the governor id by searching for the profile field
+func (c *Client) GetUserIDByGovernorID(ctx context.Context, id string) (string, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
 
-	f := fmt.Sprintf("profile.email eq \"%s\"", email)
+	c.logger.Debug("getting okta user by governor id", zap.String("governor.id", id))
 
-	users, _, err := c.userIface.ListUsers(ctx, &query.Params{Search: f})
+	f := fmt.Sprintf("profile.%s eq \"%s\"", UserProfileGovernorIDKey, id)
+
+	users, resp, err := c.userIface.ListUsers(ctx, &query.Params{Search: f})
 	if err != nil {
-		return "", err
+		return "", apiError(resp, err)
 	}
 
-	if len(users) != 1 {
+	if len(users) == 0 {
+		return "", ErrUsersNotFound
+	} else if len(users) > 1 {
 		return "", ErrUnexpectedUsersCount
 	}
 
 	uid := users[0].Id
 
-	c.logger.Debug("found okta user by email", zap.String("user.email", email), zap.String("okta.user.id", uid))
+	c.logger.Debug("found okta user by governor id", zap.String("governor.id", id), zap.String("okta.user.id", uid))
 
 	return uid, nil
 }
 
-// ListUsers lists all okta users
-func (c *Client) ListUsers(ctx context.Context) ([]*okta.User, error) {
-	c.logger.Debug("listing users")
+// SetUserGovernorID writes the governor id to the okta user profile, leaving the rest of the profile as is
+func (c *Client) SetUserGovernorID(ctx context.Context, id, governorID string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	c.logger.Info("setting governor id on okta user", zap.String("okta.user.id", id), zap.String("governor.id", governorID))
+
+	args := map[string]string{"user.id": id, "governor.id": governorID}
+
+	if c.simulate("SetUserGovernorID", args) {
+		return nil
+	}
+
+	_, resp, err := c.userIface.PartialUpdateUser(ctx, id, okta.User{
+		Profile: &okta.UserProfile{UserProfileGovernorIDKey: governorID},
+	}, nil)
+	if err := c.audited(ctx, "SetUserGovernorID", args, resp, err); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListUsersOption is a functional option for ListUsers, the options are combined into a single okta search
+// expression so users are filtered by okta instead of being listed and dropped
+type ListUsersOption func(*listUsersOptions)
 
-	users, resp, err := c.userIface.ListUsers(ctx, &query.Params{})
+type listUsersOptions struct {
+	search       string
+	filter       string
+	statuses     []string
+	excludeTypes []string
+	limit        int64
+}
+
+// WithUserSearch filters the users with an okta (SCIM style) search expression, ie. profile.department eq "Engineering"
+func WithUserSearch(expr string) ListUsersOption {
+	return func(o *listUsersOptions) {
+		o.search = expr
+	}
+}
+
+// WithUserFilter only lists users matching the okta filter expression, ie. `lastUpdated gt "2023-01-01T00:00:00.000Z"`.
+// Okta filters support fewer attributes than searches and are sent as is, they aren't combined with the search.
+func WithUserFilter(expr string) ListUsersOption {
+	return func(o *listUsersOptions) {
+		o.filter = expr
+	}
+}
+
+// WithUserStatuses only lists users with one of the given statuses, ie. ACTIVE and SUSPENDED
+func WithUserStatuses(statuses ...string) ListUsersOption {
+	return func(o *listUsersOptions) {
+		o.statuses = append(o.statuses, statuses...)
+	}
+}
+
+// WithoutUserTypes leaves out users with one of the given profile userType values, ie. service accounts
+func WithoutUserTypes(types ...string) ListUsersOption {
+	return func(o *listUsersOptions) {
+		o.excludeTypes = append(o.excludeTypes, types...)
+	}
+}
+
+// WithUserPageLimit sets the number of users per page, okta's default is used when it's 0
+func WithUserPageLimit(n int64) ListUsersOption {
+	return func(o *listUsersOptions) {
+		o.limit = n
+	}
+}
+
+// params returns the okta query params for the options
+func (o *listUsersOptions) params() *query.Params {
+	clauses := []string{}
+
+	if o.search != "" {
+		clauses = append(clauses, "("+o.search+")")
+	}
+
+	if len(o.statuses) > 0 {
+		statuses := make([]string, len(o.statuses))
+		for i, st := range o.statuses {
+			statuses[i] = fmt.Sprintf("status eq \"%s\"", st)
+		}
+
+		clauses = append(clauses, "("+strings.Join(statuses, " or ")+")")
+	}
+
+	for _, t := range o.excludeTypes {
+		clauses = append(clauses, fmt.Sprintf("profile.userType ne \"%s\"", t))
+	}
+
+	return &query.Params{Search: strings.Join(clauses, " and "), Filter: o.filter, Limit: o.limit}
+}
+
+// ListUsers lists all okta users, or the users matching the options
+func (c *Client) ListUsers(ctx context.Context, opts ...ListUsersOption) ([]*okta.User, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
+	o := &listUsersOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	q := o.params()
+
+	c.logger.Debug("listing users", zap.String("okta.search", q.Search))
+
+	users, resp, err := c.userIface.ListUsers(ctx, q)
 	if err != nil {
-		return nil, err
+		return nil, apiError(resp, err)
 	}
 
 	userResp := users
@@ -133,8 +446,14 @@ func (c *Client) ListUsers(ctx context.Context) ([]*okta.User, error) {
 
 		nextPage := []*okta.User{}
 
-		resp, err = resp.Next(ctx, &nextPage)
+		resp, err = c.nextPage(ctx, "ListUsers", func() (*okta.Response, error) {
+			return resp.Next(ctx, &nextPage)
+		})
 		if err != nil {
+			if c.partialListing("ListUsers", len(userResp), err) {
+				break
+			}
+
 			return nil, err
 		}
 
@@ -147,66 +466,114 @@ func (c *Client) ListUsers(ctx context.Context) ([]*okta.User, error) {
 }
 
 // ListUsersWithModifier lists okta users and modifies the user response with the given UserModifierFunc.  If nil is
-// returned from the UserModifierFunc, the user will not be returned in the response.
+// returned from the UserModifierFunc, the user will not be returned in the response.  The UserModifierFunc is run
+// concurrently when the client concurrency is greater than 1, so it must be safe for concurrent use.
 func (c *Client) ListUsersWithModifier(ctx context.Context, f UserModifierFunc, q *query.Params) ([]*okta.User, error) {
 	c.logger.Debug("listing users with func")
 
-	users, resp, err := c.userIface.ListUsers(ctx, q)
+	// the modifier can be slow, so the deadline applies to each page request instead of the whole listing
+	pageCtx, cancel := c.callContext(ctx)
+	users, resp, err := c.userIface.ListUsers(pageCtx, q)
+	cancel()
+
+	if err != nil {
+		return nil, apiError(resp, err)
+	}
+
+	modifier := func(ctx context.Context, u *okta.User) (*okta.User, error) {
+		c.logger.Debug("running function on user", redact.Any("user", u))
+		return f(ctx, u)
+	}
+
+	userResp, err := modifyAll(ctx, c.concurrency, users, modifier)
 	if err != nil {
 		return nil, err
 	}
 
-	userResp := []*okta.User{}
+	for {
+		if !resp.HasNextPage() {
+			break
+		}
+
+		nextPage := []*okta.User{}
+
+		pageCtx, cancel := c.callContext(ctx)
+		resp, err = resp.Next(pageCtx, &nextPage)
 
-	for _, u := range users {
-		c.logger.Debug("running function on user", zap.Any("user", u))
+		cancel()
 
-		user, err := f(ctx, u)
 		if err != nil {
-			return nil, err
+			return nil, apiError(resp, err)
 		}
 
-		if user != nil {
-			userResp = append(userResp, user)
+		modified, err := modifyAll(ctx, c.concurrency, nextPage, modifier)
+		if err != nil {
+			return nil, err
 		}
+
+		userResp = append(userResp, modified...)
+	}
+
+	c.logger.Debug("returning list of users", zap.Int("num.okta.users", len(userResp)))
+
+	return userResp, nil
+}
+
+// ListUserGroups lists the okta groups the user is a member of, following the pages of the listing
+func (c *Client) ListUserGroups(ctx context.Context, id string) ([]*okta.Group, error) {
+	ctx, cancel := c.listContext(ctx)
+	defer cancel()
+
+	c.logger.Debug("listing okta user groups", zap.String("okta.user.id", id))
+
+	groups, resp, err := c.userIface.ListUserGroups(ctx, id)
+	if err != nil {
+		return nil, apiError(resp, err)
 	}
 
+	groupResp := groups
+
 	for {
 		if !resp.HasNextPage() {
 			break
 		}
 
-		nextPage := []*okta.User{}
+		nextPage := []*okta.Group{}
 
-		resp, err = resp.Next(ctx, &nextPage)
+		resp, err = c.nextPage(ctx, "ListUserGroups", func() (*okta.Response, error) {
+			return resp.Next(ctx, &nextPage)
+		})
 		if err != nil {
-			return nil, err
-		}
-
-		for _, u := range nextPage {
-			c.logger.Debug("running function on user", zap.Any("user", u))
-
-			user, err := f(ctx, u)
-			if err != nil {
-				return nil, err
+			if c.partialListing("ListUserGroups", len(groupResp), err) {
+				break
 			}
 
-			if user != nil {
-				userResp = append(userResp, user)
-			}
+			return nil, err
 		}
+
+		groupResp = append(groupResp, nextPage...)
 	}
 
-	c.logger.Debug("returning list of users", zap.Int("num.okta.users", len(userResp)))
+	c.logger.Debug("returning list of user groups", zap.String("okta.user.id", id), zap.Int("num.okta.groups", len(groupResp)))
 
-	return userResp, nil
+	return groupResp, nil
 }
 
 // SuspendUser suspends an active user in Okta
 func (c *Client) SuspendUser(ctx context.Context, id string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Info("suspending okta user", zap.String("okta.user.id", id))
 
-	if _, err := c.userIface.SuspendUser(ctx, id); err != nil {
+	args := map[string]string{"user.id": id}
+
+	if c.simulate("SuspendUser", args) {
+		return nil
+	}
+
+	resp, err := c.userIface.SuspendUser(ctx, id)
+	if err := c.audited(ctx, "SuspendUser", args, resp, err); err != nil {
 		return err
 	}
 
@@ -217,9 +584,19 @@ func (c *Client) SuspendUser(ctx context.Context, id string) error {
 
 // UnsuspendUser un-suspends a user in Okta and returns them to active state
 func (c *Client) UnsuspendUser(ctx context.Context, id string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
 	c.logger.Info("un-suspending okta user", zap.String("okta.user.id", id))
 
-	if _, err := c.userIface.UnsuspendUser(ctx, id); err != nil {
+	args := map[string]string{"user.id": id}
+
+	if c.simulate("UnsuspendUser", args) {
+		return nil
+	}
+
+	resp, err := c.userIface.UnsuspendUser(ctx, id)
+	if err := c.audited(ctx, "UnsuspendUser", args, resp, err); err != nil {
 		return err
 	}
 
@@ -244,6 +621,44 @@ func EmailFromUserProfile(u *okta.User) (string, error) {
 	return "", fmt.Errorf("email not found for user %s", u.Id) //nolint:goerr113
 }
 
+// UserGovernorID gets the governor user id from the okta user profile
+func UserGovernorID(u *okta.User) (string, error) {
+	if u == nil || u.Profile == nil {
+		return "", ErrUserGovernorIDNotFound
+	}
+
+	v, ok := (*u.Profile)[UserProfileGovernorIDKey]
+	if !ok || v == nil {
+		return "", ErrUserGovernorIDNotFound
+	}
+
+	id, ok := v.(string)
+	if !ok {
+		return "", ErrUserGovernorIDNotString
+	}
+
+	if id == "" {
+		return "", ErrUserGovernorIDNotFound
+	}
+
+	return id, nil
+}
+
+// LoginFromUserProfile parses the login from the okta user profile
+func LoginFromUserProfile(u *okta.User) (string, error) {
+	if u.Profile != nil {
+		if v, ok := (*u.Profile)["login"]; ok {
+			if fv, ok := v.(string); ok {
+				return fv, nil
+			}
+
+			return "", ErrOktaUserLoginNotString
+		}
+	}
+
+	return "", fmt.Errorf("login not found for user %s", u.Id) //nolint:goerr113
+}
+
 // FirstNameFromUserProfile parses the firstName from the okta user profile
 func FirstNameFromUserProfile(u *okta.User) (string, error) {
 	// get the firstName from the user profile
@@ -283,6 +698,14 @@ func UserDetailsFromOktaUser(u *okta.User) (*UserDetails, error) {
 		Status: u.Status,
 	}
 
+	if u.LastUpdated != nil {
+		d.LastUpdated = *u.LastUpdated
+	}
+
+	if u.StatusChanged != nil {
+		d.StatusChanged = *u.StatusChanged
+	}
+
 	var firstName, lastName string
 
 	for k, v := range *u.Profile {
@@ -312,6 +735,27 @@ func UserDetailsFromOktaUser(u *okta.User) (*UserDetails, error) {
 
 			d.Email = e
 		}
+
+		if k == "login" {
+			l, ok := v.(string)
+			if !ok {
+				return nil, ErrOktaUserLoginNotString
+			}
+
+			d.Login = l
+		}
+
+		// the secondary email is optional, okta returns null when it's not set
+		if k == "secondEmail" {
+			if e, ok := v.(string); ok {
+				d.SecondEmail = e
+			}
+		}
+	}
+
+	// the governor id is optional, users are matched by email when it's missing
+	if id, err := UserGovernorID(u); err == nil {
+		d.GovernorID = id
 	}
 
 	if firstName == "" {
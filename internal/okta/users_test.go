@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -15,11 +17,21 @@ type mockUserClient struct {
 	t   *testing.T
 	err error
 
-	users []*okta.User
+	// deactivateErr and sessionsErr only fail their call
+	deactivateErr error
+	sessionsErr   error
+
+	users  []*okta.User
+	groups []*okta.Group
 
 	resp *okta.Response
 
 	deactivatedUser bool
+	deletedUser     bool
+	clearedSessions bool
+	updatedProfile  *okta.UserProfile
+	search          string
+	filter          string
 }
 
 func (m *mockUserClient) ClearUserSessions(_ context.Context, _ string, _ *query.Params) (*okta.Response, error) {
@@ -27,6 +39,12 @@ func (m *mockUserClient) ClearUserSessions(_ context.Context, _ string, _ *query
 		return nil, m.err
 	}
 
+	if m.sessionsErr != nil {
+		return nil, m.sessionsErr
+	}
+
+	m.clearedSessions = true
+
 	return m.resp, nil
 }
 
@@ -37,6 +55,14 @@ func (m *mockUserClient) DeactivateUser(_ context.Context, _ string, _ *query.Pa
 		return nil, m.err
 	}
 
+	if m.deactivateErr != nil {
+		return nil, m.deactivateErr
+	}
+
+	if len(m.users) > 0 {
+		m.users[0].Status = UserStatusDeprovisioned
+	}
+
 	return m.resp, nil
 }
 
@@ -45,6 +71,8 @@ func (m *mockUserClient) DeactivateOrDeleteUser(_ context.Context, _ string, _ *
 		return nil, m.err
 	}
 
+	m.deletedUser = true
+
 	return m.resp, nil
 }
 
@@ -56,7 +84,12 @@ func (m *mockUserClient) GetUser(_ context.Context, _ string) (*okta.User, *okta
 	return m.users[0], m.resp, nil
 }
 
-func (m *mockUserClient) ListUsers(_ context.Context, _ *query.Params) ([]*okta.User, *okta.Response, error) {
+func (m *mockUserClient) ListUsers(_ context.Context, q *query.Params) ([]*okta.User, *okta.Response, error) {
+	if q != nil {
+		m.search = q.Search
+		m.filter = q.Filter
+	}
+
 	if m.err != nil {
 		return nil, nil, m.err
 	}
@@ -64,6 +97,24 @@ func (m *mockUserClient) ListUsers(_ context.Context, _ *query.Params) ([]*okta.
 	return m.users, m.resp, nil
 }
 
+func (m *mockUserClient) ListUserGroups(_ context.Context, _ string) ([]*okta.Group, *okta.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	return m.groups, m.resp, nil
+}
+
+func (m *mockUserClient) PartialUpdateUser(_ context.Context, _ string, u okta.User, _ *query.Params) (*okta.User, *okta.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	m.updatedProfile = u.Profile
+
+	return &u, m.resp, nil
+}
+
 func (m *mockUserClient) SuspendUser(_ context.Context, _ string) (*okta.Response, error) {
 	if m.err != nil {
 		return nil, m.err
@@ -158,49 +209,46 @@ func TestClient_DeactivateUser(t *testing.T) {
 	}
 }
 
-func TestClient_DeleteUser(t *testing.T) {
+func TestClient_PermanentlyDeleteUser(t *testing.T) {
 	tests := []struct {
 		name    string
 		id      string
 		users   []*okta.User
 		err     error
-		wantDA  bool
-		wantErr bool
+		wantErr error
 	}{
 		{
-			name: "delete active user",
+			name: "delete deactivated user",
 			id:   "user101",
 			users: []*okta.User{
-				{Id: "11111111", Status: "ACTIVE"},
+				{Id: "user101", Status: "DEPROVISIONED"},
 			},
-			wantDA: true,
 		},
 		{
-			name: "delete deactivated user",
+			name: "refuse active user",
 			id:   "user101",
 			users: []*okta.User{
-				{Id: "11111111", Status: "DEPROVISIONED"},
+				{Id: "user101", Status: "ACTIVE"},
 			},
-			wantDA: false,
+			wantErr: ErrUserNotDeactivated,
 		},
 		{
 			name: "okta error",
 			id:   "user101",
 			users: []*okta.User{
-				{Id: "11111111"},
+				{Id: "user101"},
 			},
 			err:     errors.New("boom"), //nolint:goerr113
-			wantErr: true,
+			wantErr: errors.New("boom"), //nolint:goerr113
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := &mockUserClient{
-				t:               t,
-				err:             tt.err,
-				users:           tt.users,
-				resp:            &okta.Response{},
-				deactivatedUser: false,
+				t:     t,
+				err:   tt.err,
+				users: tt.users,
+				resp:  &okta.Response{},
 			}
 
 			c := &Client{
@@ -208,14 +256,21 @@ func TestClient_DeleteUser(t *testing.T) {
 				userIface: m,
 			}
 
-			err := c.DeleteUser(context.TODO(), tt.id)
-			if tt.wantErr {
+			err := c.PermanentlyDeleteUser(context.TODO(), tt.id)
+			if tt.wantErr != nil {
 				assert.Error(t, err)
+
+				if tt.err == nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+
+				assert.False(t, m.deletedUser)
+
 				return
 			}
 
 			assert.NoError(t, err)
-			assert.Equal(t, tt.wantDA, m.deactivatedUser)
+			assert.True(t, m.deletedUser)
 		})
 	}
 }
@@ -363,6 +418,266 @@ func TestClient_GetUserIDByEmail(t *testing.T) {
 	}
 }
 
+func TestClient_GetUserIDBy(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        UserMatchKey
+		value      string
+		users      []*okta.User
+		want       string
+		wantSearch string
+	}{
+		{
+			name:       "by email",
+			key:        UserMatchKeyEmail,
+			value:      "foo@example.com",
+			users:      []*okta.User{{Id: "11111111"}},
+			want:       "11111111",
+			wantSearch: `profile.email eq "foo@example.com"`,
+		},
+		{
+			name:       "by login",
+			key:        UserMatchKeyLogin,
+			value:      "foo@old.example.com",
+			users:      []*okta.User{{Id: "11111111"}},
+			want:       "11111111",
+			wantSearch: `profile.login eq "foo@old.example.com"`,
+		},
+		{
+			name:  "by external id",
+			key:   UserMatchKeyExternalID,
+			value: "11111111",
+			users: []*okta.User{{Id: "11111111"}},
+			want:  "11111111",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockUserClient{t: t, users: tt.users}
+
+			c := &Client{
+				logger:    zap.NewNop(),
+				userIface: m,
+			}
+
+			got, err := c.GetUserIDBy(context.TODO(), tt.key, tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSearch, m.search)
+		})
+	}
+}
+
+func TestParseUserMatchKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		want    UserMatchKey
+		wantErr error
+	}{
+		{name: "default", want: UserMatchKeyEmail},
+		{name: "email", key: "email", want: UserMatchKeyEmail},
+		{name: "login", key: "login", want: UserMatchKeyLogin},
+		{name: "external id", key: "externalId", want: UserMatchKeyExternalID},
+		{name: "invalid", key: "name", wantErr: ErrInvalidUserMatchKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUserMatchKey(tt.key)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUserMatchValue(t *testing.T) {
+	u := &okta.User{
+		Id: "11111111",
+		Profile: &okta.UserProfile{
+			"email": "foo@new.example.com",
+			"login": "foo@old.example.com",
+		},
+	}
+
+	tests := []struct {
+		key  UserMatchKey
+		want string
+	}{
+		{key: UserMatchKeyEmail, want: "foo@new.example.com"},
+		{key: UserMatchKeyLogin, want: "foo@old.example.com"},
+		{key: UserMatchKeyExternalID, want: "11111111"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.key), func(t *testing.T) {
+			got, err := UserMatchValue(u, tt.key)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUserMatchAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     UserMatchKey
+		profile okta.UserProfile
+		want    []string
+	}{
+		{
+			name:    "email",
+			key:     UserMatchKeyEmail,
+			profile: okta.UserProfile{"email": "foo@new.example.com", "secondEmail": "foo@alias.example.com", "login": "foo@old.example.com"},
+			want:    []string{"foo@alias.example.com", "foo@old.example.com"},
+		},
+		{
+			name:    "login",
+			key:     UserMatchKeyLogin,
+			profile: okta.UserProfile{"email": "foo@new.example.com", "secondEmail": "foo@alias.example.com", "login": "foo@old.example.com"},
+			want:    []string{"foo@new.example.com", "foo@alias.example.com"},
+		},
+		{
+			name:    "duplicates and null secondary email",
+			key:     UserMatchKeyEmail,
+			profile: okta.UserProfile{"email": "foo@example.com", "secondEmail": nil, "login": "FOO@example.com"},
+			want:    []string{},
+		},
+		{
+			name:    "external id",
+			key:     UserMatchKeyExternalID,
+			profile: okta.UserProfile{"email": "foo@new.example.com", "secondEmail": "foo@alias.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, UserMatchAliases(&okta.User{Id: "11111111", Profile: &tt.profile}, tt.key))
+		})
+	}
+}
+
+func TestClient_GetUserIDByGovernorID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		users   []*okta.User
+		err     error
+		want    string
+		wantErr error
+	}{
+		{
+			name: "example get user by governor id",
+			users: []*okta.User{
+				{Id: "11111111"},
+			},
+			id:   "2222222",
+			want: "11111111",
+		},
+		{
+			name:    "empty list",
+			users:   []*okta.User{},
+			id:      "2222222",
+			wantErr: ErrUsersNotFound,
+		},
+		{
+			name: "more than one user returned",
+			users: []*okta.User{
+				{Id: "11111111"},
+				{Id: "33333333"},
+			},
+			id:      "2222222",
+			wantErr: ErrUnexpectedUsersCount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				logger: zap.NewNop(),
+				userIface: &mockUserClient{
+					t:     t,
+					err:   tt.err,
+					users: tt.users,
+				},
+			}
+
+			got, err := c.GetUserIDByGovernorID(context.TODO(), tt.id)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClient_SetUserGovernorID(t *testing.T) {
+	m := &mockUserClient{t: t}
+
+	c := &Client{
+		logger:    zap.NewNop(),
+		userIface: m,
+	}
+
+	assert.NoError(t, c.SetUserGovernorID(context.TODO(), "11111111", "2222222"))
+	assert.Equal(t, &okta.UserProfile{"governor_id": "2222222"}, m.updatedProfile)
+
+	m.err = errors.New("boom") //nolint:goerr113
+	assert.Error(t, c.SetUserGovernorID(context.TODO(), "11111111", "2222222"))
+}
+
+func TestClient_UserGovernorID(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    *okta.User
+		want    string
+		wantErr error
+	}{
+		{
+			name: "governor id set",
+			user: &okta.User{Profile: &okta.UserProfile{"governor_id": "2222222"}},
+			want: "2222222",
+		},
+		{
+			name:    "governor id missing",
+			user:    &okta.User{Profile: &okta.UserProfile{"email": "foo@example.com"}},
+			wantErr: ErrUserGovernorIDNotFound,
+		},
+		{
+			name:    "governor id empty",
+			user:    &okta.User{Profile: &okta.UserProfile{"governor_id": ""}},
+			wantErr: ErrUserGovernorIDNotFound,
+		},
+		{
+			name:    "governor id not a string",
+			user:    &okta.User{Profile: &okta.UserProfile{"governor_id": 12345}},
+			wantErr: ErrUserGovernorIDNotString,
+		},
+		{
+			name:    "nil profile",
+			user:    &okta.User{},
+			wantErr: ErrUserGovernorIDNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UserGovernorID(tt.user)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestClient_ListUsers(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -419,6 +734,186 @@ func TestClient_ListUsers(t *testing.T) {
 	}
 }
 
+func TestClient_ListUsersOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []ListUsersOption
+		wantSearch string
+		wantFilter string
+	}{
+		{
+			name: "no options",
+		},
+		{
+			name:       "statuses",
+			opts:       []ListUsersOption{WithUserStatuses("ACTIVE", "SUSPENDED")},
+			wantSearch: `(status eq "ACTIVE" or status eq "SUSPENDED")`,
+		},
+		{
+			name:       "excluded user types",
+			opts:       []ListUsersOption{WithoutUserTypes("service", "bot")},
+			wantSearch: `profile.userType ne "service" and profile.userType ne "bot"`,
+		},
+		{
+			name: "search combined with statuses and user types",
+			opts: []ListUsersOption{
+				WithUserSearch(`profile.department eq "Engineering"`),
+				WithUserStatuses("ACTIVE"),
+				WithoutUserTypes("service"),
+			},
+			wantSearch: `(profile.department eq "Engineering") and (status eq "ACTIVE") and profile.userType ne "service"`,
+		},
+		{
+			name:       "filter",
+			opts:       []ListUsersOption{WithUserFilter(`lastUpdated gt "2023-01-01T00:00:00.000Z"`)},
+			wantFilter: `lastUpdated gt "2023-01-01T00:00:00.000Z"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockUserClient{
+				t:     t,
+				users: []*okta.User{{Id: "user1"}},
+				resp:  &okta.Response{},
+			}
+
+			c := &Client{
+				logger:    zap.NewNop(),
+				userIface: m,
+			}
+
+			_, err := c.ListUsers(context.TODO(), tt.opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSearch, m.search)
+			assert.Equal(t, tt.wantFilter, m.filter)
+		})
+	}
+}
+
+func TestClient_ListUserGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  []*okta.Group
+		err     error
+		want    []*okta.Group
+		wantErr bool
+	}{
+		{
+			name:   "example user groups",
+			groups: []*okta.Group{{Id: "group1"}, {Id: "group2"}},
+			want:   []*okta.Group{{Id: "group1"}, {Id: "group2"}},
+		},
+		{
+			name:   "no groups",
+			groups: []*okta.Group{},
+			want:   []*okta.Group{},
+		},
+		{
+			name:    "okta error",
+			err:     errors.New("boomsauce"), //nolint:goerr113
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				logger: zap.NewNop(),
+				userIface: &mockUserClient{
+					t:      t,
+					err:    tt.err,
+					groups: tt.groups,
+					resp:   &okta.Response{},
+				},
+			}
+
+			got, err := c.ListUserGroups(context.TODO(), "user101")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClient_ListUserGroups_pages(t *testing.T) {
+	pages := [][]*okta.Group{
+		{{Id: "group1"}, {Id: "group2"}},
+		{{Id: "group3"}},
+		{{Id: "group4"}},
+	}
+
+	tests := []struct {
+		name        string
+		failPage    int
+		partial     bool
+		want        []string
+		wantQueries int
+		wantErr     bool
+	}{
+		{
+			name:        "all pages",
+			failPage:    -1,
+			want:        []string{"group1", "group2", "group3", "group4"},
+			wantQueries: 3,
+		},
+		{
+			name:        "first page fails",
+			failPage:    0,
+			wantQueries: 1,
+			wantErr:     true,
+		},
+		{
+			name:        "later page fails",
+			failPage:    2,
+			wantQueries: 4,
+			wantErr:     true,
+		},
+		{
+			name:        "later page fails with partial pages",
+			failPage:    2,
+			partial:     true,
+			want:        []string{"group1", "group2", "group3"},
+			wantQueries: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries := []string{}
+
+			c := &Client{
+				logger:        zap.NewNop(),
+				userIface:     newPagedGroupsSDKClient(t, pages, tt.failPage, &queries).User,
+				pageRetries:   1,
+				pageRetryWait: time.Millisecond,
+				partialPages:  tt.partial,
+			}
+
+			got, err := c.ListUserGroups(context.TODO(), "user101")
+
+			assert.Len(t, queries, tt.wantQueries)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			ids := []string{}
+			for _, g := range got {
+				ids = append(ids, g.Id)
+			}
+
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
 func TestClient_ListUsersWithModifier(t *testing.T) {
 	skipUser := func(_ context.Context, u *okta.User) (*okta.User, error) {
 		if u.Id == "skipMe" {
@@ -674,6 +1169,85 @@ func TestClient_UserDetailsFromOktaUser(t *testing.T) {
 				Status: "ACTIVE",
 			},
 		},
+		{
+			name: "with governor id",
+			user: &okta.User{
+				Id:     "00u123456789abcde697",
+				Status: "ACTIVE",
+				Profile: &okta.UserProfile{
+					"firstName":   "Burrow",
+					"lastName":    "Blaster",
+					"email":       "bblaster@gopher.com",
+					"governor_id": "2222222",
+				},
+			},
+			want: &UserDetails{
+				ID:         "00u123456789abcde697",
+				Name:       "Burrow Blaster",
+				Email:      "bblaster@gopher.com",
+				Status:     "ACTIVE",
+				GovernorID: "2222222",
+			},
+		},
+		{
+			name: "with login",
+			user: &okta.User{
+				Id:     "00u123456789abcde697",
+				Status: "ACTIVE",
+				Profile: &okta.UserProfile{
+					"firstName": "Burrow",
+					"lastName":  "Blaster",
+					"email":     "bblaster@gopher.com",
+					"login":     "bblaster@burrow.com",
+				},
+			},
+			want: &UserDetails{
+				ID:     "00u123456789abcde697",
+				Name:   "Burrow Blaster",
+				Email:  "bblaster@gopher.com",
+				Login:  "bblaster@burrow.com",
+				Status: "ACTIVE",
+			},
+		},
+		{
+			name: "with secondary email",
+			user: &okta.User{
+				Id:     "00u123456789abcde697",
+				Status: "ACTIVE",
+				Profile: &okta.UserProfile{
+					"firstName":   "Burrow",
+					"lastName":    "Blaster",
+					"email":       "bblaster@gopher.com",
+					"secondEmail": "burrow@gopher.com",
+				},
+			},
+			want: &UserDetails{
+				ID:          "00u123456789abcde697",
+				Name:        "Burrow Blaster",
+				Email:       "bblaster@gopher.com",
+				SecondEmail: "burrow@gopher.com",
+				Status:      "ACTIVE",
+			},
+		},
+		{
+			name: "with null secondary email",
+			user: &okta.User{
+				Id:     "00u123456789abcde697",
+				Status: "ACTIVE",
+				Profile: &okta.UserProfile{
+					"firstName":   "Burrow",
+					"lastName":    "Blaster",
+					"email":       "bblaster@gopher.com",
+					"secondEmail": nil,
+				},
+			},
+			want: &UserDetails{
+				ID:     "00u123456789abcde697",
+				Name:   "Burrow Blaster",
+				Email:  "bblaster@gopher.com",
+				Status: "ACTIVE",
+			},
+		},
 		{
 			name: "empty profile",
 			user: &okta.User{
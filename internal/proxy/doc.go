@@ -0,0 +1,2 @@
+// Package proxy routes the outgoing okta, governor and tracing traffic through an authenticated http proxy
+package proxy
@@ -0,0 +1,12 @@
+package proxy
+
+import "errors"
+
+var (
+	// ErrProxyURLInvalid is returned when the proxy url can't be parsed or isn't an http(s) url
+	ErrProxyURLInvalid = errors.New("proxy url must be an http or https url")
+	// ErrProxyConnect is returned when the proxy refuses to open a tunnel
+	ErrProxyConnect = errors.New("proxy refused to connect")
+	// ErrProxyAuthRequired is returned when the proxy rejects the credentials
+	ErrProxyAuthRequired = errors.New("proxy authentication required")
+)
@@ -0,0 +1,230 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// checkTimeout is the timeout of a connectivity check through the proxy
+const checkTimeout = 10 * time.Second
+
+// Proxy is an http proxy with optional basic auth credentials and a list of hosts that bypass it. A nil
+// Proxy connects directly.
+type Proxy struct {
+	url       *url.URL
+	proxyFunc func(*url.URL) (*url.URL, error)
+}
+
+// New returns a proxy for the url, the credentials are added to the url when a username is set. The no
+// proxy list has the same format as the NO_PROXY environment variable entries. An empty url returns a
+// nil proxy.
+func New(rawURL, username, password string, noProxy []string) (*Proxy, error) {
+	if rawURL == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrProxyURLInvalid, rawURL)
+	}
+
+	if username != "" {
+		u.User = url.UserPassword(username, password)
+	}
+
+	cfg := httpproxy.Config{
+		HTTPProxy:  u.String(),
+		HTTPSProxy: u.String(),
+		NoProxy:    strings.Join(noProxy, ","),
+	}
+
+	return &Proxy{url: u, proxyFunc: cfg.ProxyFunc()}, nil
+}
+
+// String returns the proxy url without the credentials
+func (p *Proxy) String() string {
+	if p == nil {
+		return ""
+	}
+
+	return p.url.Redacted()
+}
+
+// URL returns the proxy url to use for a request url, nil when the request should connect directly
+func (p *Proxy) URL(reqURL *url.URL) (*url.URL, error) {
+	if p == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	return p.proxyFunc(reqURL)
+}
+
+// Func returns the proxy function for an http.Transport
+func (p *Proxy) Func() func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		return p.URL(req.URL)
+	}
+}
+
+// Install routes the requests made with the default http transport through the proxy. The okta sdk, the
+// governor client and the oauth token requests all use the default transport unless a client with a custom
+// transport is passed to them.
+func (p *Proxy) Install() {
+	if p == nil {
+		return
+	}
+
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		t.Proxy = p.Func()
+	}
+}
+
+// DialContext connects to a host:port address through the proxy with an http CONNECT tunnel, for clients
+// that don't speak http like the grpc tracing exporter
+func (p *Proxy) DialContext(ctx context.Context, addr string) (net.Conn, error) {
+	d := &net.Dialer{}
+
+	proxyURL, err := p.URL(&url.URL{Scheme: "https", Host: addr})
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL == nil {
+		return d.DialContext(ctx, "tcp", addr)
+	}
+
+	conn, err := d.DialContext(ctx, "tcp", proxyHostPort(proxyURL))
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), MinVersion: tls.VersionTLS12})
+	}
+
+	tunnel, err := connect(ctx, conn, proxyURL, addr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tunnel, nil
+}
+
+// Check makes a request to the target url through the proxy to make sure it's reachable and the proxy
+// accepts the credentials, any http response from the target counts as reachable
+func (p *Proxy) Check(ctx context.Context, target string) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: p.Func()},
+		// don't follow redirects, reaching the target is enough
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// the transport doesn't return the response of a failed https CONNECT
+		if strings.Contains(err.Error(), http.StatusText(http.StatusProxyAuthRequired)) {
+			return fmt.Errorf("%w: %s", ErrProxyAuthRequired, p)
+		}
+
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		return fmt.Errorf("%w: %s", ErrProxyAuthRequired, p)
+	}
+
+	return nil
+}
+
+// connect opens an http CONNECT tunnel to addr on a connection to the proxy
+func connect(ctx context.Context, conn net.Conn, proxyURL *url.URL, addr string) (net.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+
+		defer conn.SetDeadline(time.Time{}) //nolint:errcheck
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+
+	if u := proxyURL.User; u != nil {
+		pw, _ := u.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.Username()+":"+pw)))
+	}
+
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusProxyAuthRequired:
+		return nil, ErrProxyAuthRequired
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrProxyConnect, resp.Status)
+	}
+
+	// the proxy can send data from the target right after the response, don't lose what's buffered
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+
+	return conn, nil
+}
+
+// proxyHostPort returns the host:port of the proxy, with the default port of the scheme when it's missing
+func proxyHostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// bufferedConn is a connection that reads what was already buffered from it first
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
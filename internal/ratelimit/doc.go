@@ -0,0 +1,2 @@
+// Package ratelimit limits the rate of outgoing http requests to okta and governor
+package ratelimit
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket rate limiter that is safe to share between goroutines. A nil
+// Limiter doesn't limit anything.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	tokens   int
+	last     time.Time
+}
+
+// New returns a limiter that allows rate requests per second with bursts of up to burst
+// requests. A rate of zero or less returns a nil (unlimited) limiter.
+func New(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		interval: time.Duration(float64(time.Second) / rate),
+		burst:    burst,
+		tokens:   burst,
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed or the context is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		wait := l.reserve(time.Now())
+		if wait == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token if one is available and returns 0, otherwise it returns how long
+// until the next token is available
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elapsed := now.Sub(l.last); elapsed >= l.interval {
+		refill := int(elapsed / l.interval)
+
+		l.tokens += refill
+		l.last = l.last.Add(time.Duration(refill) * l.interval)
+
+		if l.tokens >= l.burst {
+			l.tokens = l.burst
+			l.last = now
+		}
+	}
+
+	if l.tokens > 0 {
+		l.tokens--
+		return 0
+	}
+
+	return l.interval - now.Sub(l.last)
+}
+
+// transport is a http.RoundTripper that waits on the limiter before each request
+type transport struct {
+	limiter *Limiter
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// HTTPClient returns a copy of the http client with its requests limited by the limiter.
+// A nil limiter returns the client unchanged.
+func HTTPClient(c *http.Client, l *Limiter) *http.Client {
+	if l == nil {
+		return c
+	}
+
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	limited := *c
+	limited.Transport = &transport{limiter: l, next: next}
+
+	return &limited
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(0, 10))
+	assert.Nil(t, New(-1, 10))
+
+	l := New(10, 0)
+	assert.NotNil(t, l)
+	assert.Equal(t, 1, l.burst)
+	assert.Equal(t, 100*time.Millisecond, l.interval)
+}
+
+func TestLimiter_reserve(t *testing.T) {
+	now := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	l := New(10, 2)
+	l.last = now
+
+	// burst is available right away
+	assert.Zero(t, l.reserve(now))
+	assert.Zero(t, l.reserve(now))
+
+	// then we have to wait for the next token
+	assert.Equal(t, 100*time.Millisecond, l.reserve(now))
+	assert.Equal(t, 40*time.Millisecond, l.reserve(now.Add(60*time.Millisecond)))
+	assert.Zero(t, l.reserve(now.Add(100*time.Millisecond)))
+
+	// tokens don't build up past the burst
+	later := now.Add(time.Hour)
+	assert.Zero(t, l.reserve(later))
+	assert.Zero(t, l.reserve(later))
+	assert.NotZero(t, l.reserve(later))
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	var l *Limiter
+	assert.NoError(t, l.Wait(context.TODO()))
+
+	l = New(1, 1)
+	assert.NoError(t, l.Wait(context.TODO()))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	base := &http.Client{Timeout: time.Second}
+
+	assert.Same(t, base, HTTPClient(base, nil))
+
+	c := HTTPClient(base, New(1, 1))
+	assert.NotSame(t, base, c)
+	assert.Equal(t, base.Timeout, c.Timeout)
+	assert.Nil(t, base.Transport)
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, srv.URL, nil)
+	assert.NoError(t, err)
+
+	resp, err := c.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	// the second request has to wait a second for a token
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	assert.NoError(t, err)
+
+	_, err = c.Do(req) //nolint:bodyclose
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package reconciler
+
+import (
+	"slices"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// AppAssignmentReasonUnmanaged is the reason the application of a github org not managed by governor is skipped
+	AppAssignmentReasonUnmanaged = "unmanaged"
+	// AppAssignmentReasonIgnored is the reason the application of a github org in the ignore mode is skipped
+	AppAssignmentReasonIgnored = "ignored"
+
+	// appAssignmentChangeAssign is the change assigning an okta group to an application
+	appAssignmentChangeAssign = "assign"
+	// appAssignmentChangeRemove is the change removing an okta group from an application
+	appAssignmentChangeRemove = "remove"
+	// appAssignmentChangeUpdate is the change fixing the drifted settings of an application group assignment
+	appAssignmentChangeUpdate = "update"
+	// appAssignmentChangePush is the change pushing an okta group to an application
+	appAssignmentChangePush = "push"
+	// appAssignmentChangeUnpush is the change deleting the push group mapping of an okta group
+	appAssignmentChangeUnpush = "unpush"
+)
+
+// AppAssignmentResult summarizes the application assignment reconciliation of the okta github application of an
+// org with the okta group ids assigned, updated (ie. their drifted priority fixed), removed, pushed to or unpushed
+// from the org teams and skipped (ie. in dry-run or with skip-delete), and the failed changes.  Applications that
+// are skipped as a whole have a reason, and an error when their assignments or push mappings couldn't be listed.
+type AppAssignmentResult struct {
+	Org       string                 `json:"org"`
+	OktaAppID string                 `json:"okta_app_id"`
+	Reason    string                 `json:"reason,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Assigned  []string               `json:"assigned"`
+	Updated   []string               `json:"updated"`
+	Removed   []string               `json:"removed"`
+	Pushed    []string               `json:"pushed"`
+	Unpushed  []string               `json:"unpushed"`
+	Skipped   []string               `json:"skipped"`
+	Failed    []AppAssignmentFailure `json:"failed"`
+}
+
+// AppAssignmentFailure is an application assignment change of an okta group that failed
+type AppAssignmentFailure struct {
+	Org               string `json:"org"`
+	OktaAppID         string `json:"okta_app_id"`
+	GovernorGroupID   string `json:"governor_group_id"`
+	GovernorGroupSlug string `json:"governor_group_slug,omitempty"`
+	OktaGroupID       string `json:"okta_group_id"`
+	Change            string `json:"change"`
+	Error             string `json:"error"`
+}
+
+func newAppAssignmentResult(org, appID string) *AppAssignmentResult {
+	return &AppAssignmentResult{
+		Org:       org,
+		OktaAppID: appID,
+		Assigned:  []string{},
+		Updated:   []string{},
+		Removed:   []string{},
+		Pushed:    []string{},
+		Unpushed:  []string{},
+		Skipped:   []string{},
+		Failed:    []AppAssignmentFailure{},
+	}
+}
+
+// MarshalLogObject logs the org and the number of groups of each change
+func (a *AppAssignmentResult) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("org", a.Org)
+	enc.AddString("okta.app.id", a.OktaAppID)
+
+	if a.Reason != "" {
+		enc.AddString("reason", a.Reason)
+	}
+
+	if a.Error != "" {
+		enc.AddString("error", a.Error)
+	}
+
+	enc.AddInt("assigned", len(a.Assigned))
+	enc.AddInt("updated", len(a.Updated))
+	enc.AddInt("removed", len(a.Removed))
+	enc.AddInt("pushed", len(a.Pushed))
+	enc.AddInt("unpushed", len(a.Unpushed))
+	enc.AddInt("skipped", len(a.Skipped))
+	enc.AddInt("failed", len(a.Failed))
+
+	return nil
+}
+
+// AppAssignmentResults are the application assignment results of each org
+type AppAssignmentResults []*AppAssignmentResult
+
+// MarshalLogArray logs the result of each org
+func (r AppAssignmentResults) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, a := range r {
+		if err := enc.AppendObject(a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Failures returns the failed changes of all of the orgs
+func (r AppAssignmentResults) Failures() []AppAssignmentFailure {
+	failures := []AppAssignmentFailure{}
+
+	for _, a := range r {
+		failures = append(failures, a.Failed...)
+	}
+
+	return failures
+}
+
+// FailedOrgs returns the sorted orgs with an error or failed changes
+func (r AppAssignmentResults) FailedOrgs() []string {
+	orgs := []string{}
+
+	for _, a := range r {
+		if a.Error != "" || len(a.Failed) > 0 {
+			orgs = append(orgs, a.Org)
+		}
+	}
+
+	slices.Sort(orgs)
+
+	return orgs
+}
+
+// FailedGroups returns the sorted ids of the governor groups with a failed change, to retry them
+func (r AppAssignmentResults) FailedGroups() []string {
+	ids := []string{}
+
+	for _, f := range r.Failures() {
+		ids = append(ids, f.GovernorGroupID)
+	}
+
+	slices.Sort(ids)
+
+	return slices.Compact(ids)
+}
@@ -0,0 +1,134 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"go.uber.org/zap"
+)
+
+// AppAssignmentMode controls which application assignment changes the reconciler makes for a github org
+type AppAssignmentMode string
+
+const (
+	// AppAssignmentFull assigns and unassigns the okta groups of the org application, it's the default
+	AppAssignmentFull AppAssignmentMode = "full"
+	// AppAssignmentAssignOnly assigns okta groups to the org application but never unassigns them
+	AppAssignmentAssignOnly AppAssignmentMode = "assign-only"
+	// AppAssignmentIgnore leaves the assignments of the org application alone
+	AppAssignmentIgnore AppAssignmentMode = "ignore"
+)
+
+// Valid returns true for a known application assignment mode
+func (m AppAssignmentMode) Valid() bool {
+	switch m {
+	case AppAssignmentFull, AppAssignmentAssignOnly, AppAssignmentIgnore:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithAppAssignmentModes sets the application assignment mode of github orgs by org slug, the orgs that aren't
+// set are fully reconciled
+func WithAppAssignmentModes(modes map[string]AppAssignmentMode) Option {
+	return func(r *Reconciler) {
+		r.appAssignModes = make(map[string]AppAssignmentMode, len(modes))
+
+		for org, mode := range modes {
+			r.appAssignModes[strings.ToLower(org)] = mode
+		}
+	}
+}
+
+// WithAppAssignmentSettings sets the okta group assignment settings (ie. the priority) of the application of github
+// orgs by org slug.  The groups of those applications are assigned with the settings and their drifted assignments
+// are fixed.
+func WithAppAssignmentSettings(settings map[string]*okta.AppGroupAssignment) Option {
+	return func(r *Reconciler) {
+		r.appAssignSettings = make(map[string]*okta.AppGroupAssignment, len(settings))
+
+		for org, s := range settings {
+			r.appAssignSettings[strings.ToLower(org)] = s
+		}
+	}
+}
+
+// appAssignmentSettings returns the okta group assignment settings of the application of a github org, nil when
+// the assignments are left to okta
+func (r *Reconciler) appAssignmentSettings(org string) *okta.AppGroupAssignment {
+	return r.appAssignSettings[strings.ToLower(org)]
+}
+
+// formatPriority returns the audited okta group assignment priority, empty when the priority is left to okta
+func formatPriority(p *int64) string {
+	if p == nil {
+		return ""
+	}
+
+	return strconv.FormatInt(*p, 10)
+}
+
+// appAssignmentMode returns the application assignment mode of a github org
+func (r *Reconciler) appAssignmentMode(org string) AppAssignmentMode {
+	if mode, ok := r.appAssignModes[strings.ToLower(org)]; ok {
+		return mode
+	}
+
+	return AppAssignmentFull
+}
+
+// ApplicationAssignments reconciles the application assignments in okta of the governor groups with the given
+// slugs, or of all of the governor groups when no slugs are given.  It's meant for one-off runs, ie. after an
+// okta admin removed an assignment by hand.
+func (r *Reconciler) ApplicationAssignments(ctx context.Context, slugs ...string) (AppAssignmentResults, error) {
+	ctx = r.withReconcileAuditEvent(ctx, "ApplicationAssignments")
+
+	groups, err := callOp(ctx, r, "governor.Groups", func(ctx context.Context) ([]*v1alpha1.Group, error) {
+		return r.governorClient.Groups(ctx)
+	})
+	if err != nil {
+		r.logger.Error("error listing groups", zap.Error(err))
+		return nil, err
+	}
+
+	ids, err := groupIDsBySlug(groups, slugs)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GroupsApplicationAssignments(ctx, ids...)
+}
+
+// groupIDsBySlug returns the ids of the governor groups with the given slugs, or of all of the groups when no
+// slugs are given
+func groupIDsBySlug(groups []*v1alpha1.Group, slugs []string) ([]string, error) {
+	bySlug := make(map[string]string, len(groups))
+	ids := make([]string, 0, len(groups))
+
+	for _, g := range groups {
+		bySlug[g.Slug] = g.ID
+		ids = append(ids, g.ID)
+	}
+
+	if len(slugs) == 0 {
+		return ids, nil
+	}
+
+	ids = make([]string, 0, len(slugs))
+
+	for _, s := range slugs {
+		id, ok := bySlug[s]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrGroupSlugNotFound, s)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
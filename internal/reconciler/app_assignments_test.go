@@ -0,0 +1,217 @@
+package reconciler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciler_appAssignmentMode(t *testing.T) {
+	r := &Reconciler{}
+
+	assert.Equal(t, AppAssignmentFull, r.appAssignmentMode("main"))
+
+	WithAppAssignmentModes(map[string]AppAssignmentMode{
+		"Legal-Hold": AppAssignmentAssignOnly,
+		"archived":   AppAssignmentIgnore,
+	})(r)
+
+	assert.Equal(t, AppAssignmentAssignOnly, r.appAssignmentMode("legal-hold"))
+	assert.Equal(t, AppAssignmentIgnore, r.appAssignmentMode("Archived"))
+	assert.Equal(t, AppAssignmentFull, r.appAssignmentMode("main"))
+}
+
+func Test_groupIDsBySlug(t *testing.T) {
+	groups := []*v1alpha1.Group{testGovGroup(t, "platform", nil, nil), testGovGroup(t, "security", nil, nil)}
+
+	tests := []struct {
+		name    string
+		slugs   []string
+		want    []string
+		wantErr error
+	}{
+		{name: "all groups", want: []string{"platform", "security"}},
+		{name: "selected groups", slugs: []string{"security"}, want: []string{"security"}},
+		{name: "unknown group", slugs: []string{"security", "sales"}, wantErr: ErrGroupSlugNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := groupIDsBySlug(groups, tt.slugs)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReconciler_GroupsApplicationAssignments_results(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddOrganization(&testserver.GovernorOrganization{ID: "org-1", Name: "Main", Slug: "main"})
+	g.AddOrganization(&testserver.GovernorOrganization{ID: "org-2", Name: "Secondary", Slug: "secondary"})
+	g.AddOrganization(&testserver.GovernorOrganization{ID: "org-3", Name: "Archived", Slug: "archived"})
+
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Organizations: []string{"org-1", "org-2"}})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-2", Name: "Storage", Slug: "storage"})
+
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"})
+	o.AddGroup("00g-storage", "Storage", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-2"})
+
+	o.AddApp("app-main", "githubcloud", map[string]interface{}{"githubOrg": "main"})
+	o.AddApp("app-secondary", "githubcloud", map[string]interface{}{"githubOrg": "secondary"}, "00g-storage")
+	o.AddApp("app-archived", "githubcloud", map[string]interface{}{"githubOrg": "archived"}, "00g-storage")
+	o.AddApp("app-unmanaged", "githubcloud", map[string]interface{}{"githubOrg": "unmanaged"})
+
+	o.FailRequests(func(req testserver.Request) int {
+		if req.Method == http.MethodPut && req.Path == "/api/v1/apps/app-secondary/groups/00g-platform" {
+			return http.StatusInternalServerError
+		}
+
+		return 0
+	})
+
+	r, _ := newTestServerReconciler(t, o, g, WithAppAssignmentModes(map[string]AppAssignmentMode{"archived": AppAssignmentIgnore}))
+
+	res, err := r.GroupsApplicationAssignments(context.TODO(), "group-1", "group-2")
+	assert.ErrorIs(t, err, ErrAppAssignmentFailed)
+
+	require.Len(t, res, 4)
+
+	assert.Equal(t, "archived", res[0].Org)
+	assert.Equal(t, AppAssignmentReasonIgnored, res[0].Reason)
+
+	assert.Equal(t, "main", res[1].Org)
+	assert.Equal(t, []string{"00g-platform"}, res[1].Assigned)
+	assert.Empty(t, res[1].Failed)
+
+	assert.Equal(t, "secondary", res[2].Org)
+	assert.Equal(t, []string{"00g-storage"}, res[2].Removed)
+	require.Len(t, res[2].Failed, 1)
+	assert.Equal(t, "group-1", res[2].Failed[0].GovernorGroupID)
+	assert.Equal(t, appAssignmentChangeAssign, res[2].Failed[0].Change)
+	assert.NotEmpty(t, res[2].Failed[0].Error)
+
+	assert.Equal(t, "unmanaged", res[3].Org)
+	assert.Equal(t, AppAssignmentReasonUnmanaged, res[3].Reason)
+
+	assert.Equal(t, []string{"secondary"}, res.FailedOrgs())
+	assert.Equal(t, []string{"group-1"}, res.FailedGroups())
+
+	// the failing org doesn't stop the others
+	assert.Equal(t, []string{"00g-platform"}, o.AppGroups("app-main"))
+	assert.Empty(t, o.AppGroups("app-secondary"))
+	assert.Equal(t, []string{"00g-storage"}, o.AppGroups("app-archived"))
+}
+
+func TestReconciler_GroupsApplicationAssignments_settings(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddOrganization(&testserver.GovernorOrganization{ID: "org-1", Name: "Main", Slug: "main"})
+
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Organizations: []string{"org-1"}})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-2", Name: "Storage", Slug: "storage", Organizations: []string{"org-1"}})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-3", Name: "Security", Slug: "security", Organizations: []string{"org-1"}})
+
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"})
+	o.AddGroup("00g-storage", "Storage", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-2"})
+	o.AddGroup("00g-security", "Security", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-3"})
+
+	o.AddApp("app-main", "githubcloud", map[string]interface{}{"githubOrg": "main"}, "00g-platform", "00g-storage")
+	o.SetAppGroupAssignment("app-main", "00g-platform", 0, map[string]interface{}{"role": "member"})
+	o.SetAppGroupAssignment("app-main", "00g-storage", 7, map[string]interface{}{"role": "member"})
+
+	settings := &okta.AppGroupAssignment{Priority: okt.Int64Ptr(0), Profile: map[string]interface{}{"role": "member"}}
+
+	r, audit := newTestServerReconciler(t, o, g, WithAppAssignmentSettings(map[string]*okta.AppGroupAssignment{"Main": settings}))
+
+	ctx := r.withReconcileAuditEvent(context.TODO(), "test")
+
+	res, err := r.GroupsApplicationAssignments(ctx, "group-1", "group-2", "group-3")
+	require.NoError(t, err)
+
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{"00g-security"}, res[0].Assigned)
+	assert.Equal(t, []string{"00g-storage"}, res[0].Updated)
+
+	for _, gid := range []string{"00g-platform", "00g-storage", "00g-security"} {
+		a := o.AppGroupAssignment("app-main", gid)
+		require.NotNil(t, a, gid)
+		assert.False(t, settings.Drifted(a), gid)
+	}
+
+	// the drifted assignment update is audited with the priority before and after
+	assert.Equal(t, 1, strings.Count(audit.String(), `"GroupApplicationUpdate"`))
+	assert.Contains(t, audit.String(), `"okta.assignment.priority.before":"7"`)
+	assert.Contains(t, audit.String(), `"okta.assignment.priority.after":"0"`)
+}
+
+func TestReconciler_GroupsApplicationAssignments_pushGroups(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddOrganization(&testserver.GovernorOrganization{ID: "org-1", Name: "Main", Slug: "main"})
+	g.AddOrganization(&testserver.GovernorOrganization{ID: "org-2", Name: "Secondary", Slug: "secondary"})
+
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Organizations: []string{"org-1", "org-2"}})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-2", Name: "Storage", Slug: "storage", Organizations: []string{"org-1"}})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-3", Name: "Security", Slug: "security"})
+
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"})
+	o.AddGroup("00g-storage", "Storage", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-2"})
+	o.AddGroup("00g-security", "Security", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-3"})
+
+	o.AddApp("app-main", "githubcloud", map[string]interface{}{"githubOrg": "main"}, "00g-storage", "00g-security")
+	o.AddPushMapping("app-main", &testserver.OktaPushMapping{ID: "gPm-storage", SourceGroupID: "00g-storage", Status: okta.PushGroupMappingInactive})
+	o.AddPushMapping("app-main", &testserver.OktaPushMapping{ID: "gPm-security", SourceGroupID: "00g-security", Status: okta.PushGroupMappingActive})
+
+	// groups of orgs without push groups aren't pushed
+	o.AddApp("app-secondary", "githubcloud", map[string]interface{}{"githubOrg": "secondary"})
+
+	r, audit := newTestServerReconciler(t, o, g, WithPushGroups("Main"))
+
+	ctx := r.withReconcileAuditEvent(context.TODO(), "test")
+
+	res, err := r.GroupsApplicationAssignments(ctx, "group-1", "group-2", "group-3")
+	require.NoError(t, err)
+
+	require.Len(t, res, 2)
+	assert.ElementsMatch(t, []string{"00g-platform", "00g-storage"}, res[0].Pushed)
+	assert.Equal(t, []string{"00g-security"}, res[0].Unpushed)
+	assert.Empty(t, res[1].Pushed)
+
+	mappings := map[string]string{}
+	for _, m := range o.PushMappings("app-main") {
+		mappings[m.SourceGroupID] = m.Status
+	}
+
+	assert.Equal(t, map[string]string{
+		"00g-platform": okta.PushGroupMappingActive,
+		"00g-storage":  okta.PushGroupMappingActive,
+	}, mappings)
+	assert.Empty(t, o.PushMappings("app-secondary"))
+
+	// the created and activated mappings and the deleted mapping are audited
+	assert.Equal(t, 2, strings.Count(audit.String(), `"GroupPushMappingAdd"`))
+	assert.Equal(t, 1, strings.Count(audit.String(), `"GroupPushMappingRemove"`))
+	assert.Contains(t, audit.String(), "gPm-storage")
+	assert.Contains(t, audit.String(), "gPm-security")
+}
@@ -0,0 +1,236 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultBreakerFailures is the default number of consecutive failures of a backend that open its circuit
+	DefaultBreakerFailures = 5
+	// DefaultBreakerCooldown is the default time a circuit stays open before calls are let through again
+	DefaultBreakerCooldown = 5 * time.Minute
+
+	// backendOkta and backendGovernor are the backends with a circuit breaker, they're the prefix of the
+	// operation names
+	backendOkta     = "okta"
+	backendGovernor = "governor"
+)
+
+// breaker states, the values are exported in the circuit breaker state metric
+const (
+	breakerClosed   = 0
+	breakerHalfOpen = 1
+	breakerOpen     = 2
+)
+
+// lookupErrors are errors of calls that reached a healthy backend, they don't count as breaker failures
+var lookupErrors = []error{
+	okta.ErrNotFound,
+	okta.ErrConflict,
+	okta.ErrForbidden,
+	okta.ErrGroupsNotFound,
+	okta.ErrUsersNotFound,
+	okta.ErrGroupGovernorIDNotFound,
+	okta.ErrUserGovernorIDNotFound,
+	governor.ErrGroupNotFound,
+	governor.ErrUserNotFound,
+	govclient.ErrMemberRequestExists,
+}
+
+// WithCircuitBreaker opens the circuit of the okta or governor backend for the cooldown after the given number of
+// consecutive failed calls, 0 or less failures disables the circuit breakers
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(r *Reconciler) {
+		r.breakers = nil
+
+		if failures <= 0 {
+			return
+		}
+
+		r.breakers = map[string]*circuitBreaker{
+			backendOkta:     newCircuitBreaker(backendOkta, failures, cooldown),
+			backendGovernor: newCircuitBreaker(backendGovernor, failures, cooldown),
+		}
+	}
+}
+
+// circuitBreaker stops calling a backend for a cooldown after consecutive failures.  Once the cooldown is over
+// the circuit is half-open, the next call closes it again if it succeeds or re-opens it if it fails.
+type circuitBreaker struct {
+	backend   string
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	state     int
+	failures  int
+	openUntil time.Time
+}
+
+func newCircuitBreaker(backend string, threshold int, cooldown time.Duration) *circuitBreaker {
+	circuitBreakerStateGauge.WithLabelValues(backend).Set(breakerClosed)
+
+	return &circuitBreaker{
+		backend:   backend,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow returns ErrCircuitOpen while the circuit is open
+func (b *circuitBreaker) allow(now time.Time) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != breakerOpen {
+		return nil
+	}
+
+	if now.Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+
+	b.setState(breakerHalfOpen)
+
+	return nil
+}
+
+// record counts the result of a call, it returns true if the call opened the circuit
+func (b *circuitBreaker) record(now time.Time, err error) bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		b.setState(breakerClosed)
+
+		return false
+	}
+
+	b.failures++
+
+	if b.state == breakerOpen || (b.state != breakerHalfOpen && b.failures < b.threshold) {
+		return false
+	}
+
+	b.openUntil = now.Add(b.cooldown)
+	b.setState(breakerOpen)
+
+	circuitBreakerTripsCounter.WithLabelValues(b.backend).Inc()
+
+	return true
+}
+
+// open returns the time the circuit closes again if it's open
+func (b *circuitBreaker) open(now time.Time) (time.Time, bool) {
+	if b == nil {
+		return time.Time{}, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.openUntil, b.state == breakerOpen && now.Before(b.openUntil)
+}
+
+func (b *circuitBreaker) setState(state int) {
+	b.state = state
+
+	circuitBreakerStateGauge.WithLabelValues(b.backend).Set(float64(state))
+}
+
+// breaker returns the circuit breaker of the backend of an operation, nil when there's none
+func (r *Reconciler) breaker(op string) *circuitBreaker {
+	backend, _, _ := strings.Cut(op, ".")
+
+	return r.breakers[backend]
+}
+
+// breakerAllow returns ErrCircuitOpen when the circuit of the operation's backend is open
+func (r *Reconciler) breakerAllow(op string) error {
+	return r.breaker(op).allow(r.now())
+}
+
+// breakerRecord counts the result of an operation in its backend's circuit breaker, operations cancelled by
+// the parent context (ie. on shutdown) and lookups of missing resources aren't failures
+func (r *Reconciler) breakerRecord(ctx context.Context, op string, err error) {
+	b := r.breaker(op)
+	if b == nil || ctx.Err() != nil {
+		return
+	}
+
+	if !breakerFailure(err) {
+		err = nil
+	}
+
+	if b.record(r.now(), err) {
+		r.logger.Error("circuit breaker opened, calls are short-circuited until the cooldown is over",
+			zap.String("backend", b.backend),
+			zap.String("operation", op),
+			zap.Int("failures", b.threshold),
+			zap.Duration("cooldown", b.cooldown),
+			zap.Error(err),
+		)
+	}
+}
+
+// openCircuit returns the backend of the first open circuit, if any, and the time it closes again
+func (r *Reconciler) openCircuit() (string, time.Time, bool) {
+	now := r.now()
+
+	for _, backend := range []string{backendOkta, backendGovernor} {
+		if until, ok := r.breakers[backend].open(now); ok {
+			return backend, until, true
+		}
+	}
+
+	return "", time.Time{}, false
+}
+
+// circuitOpen logs and returns true if the circuit of a backend is open, so the reconciler loop doesn't go on
+// with calls that are going to fail
+func (r *Reconciler) circuitOpen() bool {
+	backend, until, ok := r.openCircuit()
+	if !ok {
+		return false
+	}
+
+	r.logger.Warn("skipping reconciler loop, circuit breaker is open",
+		zap.String("backend", backend),
+		zap.String("retry.after", until.UTC().Format(time.RFC3339)),
+	)
+
+	return true
+}
+
+// breakerFailure returns true if the error of a call counts as a failure of the backend
+func breakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, e := range lookupErrors {
+		if errors.Is(err, e) {
+			return false
+		}
+	}
+
+	return true
+}
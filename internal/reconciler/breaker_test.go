@@ -0,0 +1,114 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var errBackendDown = errors.New("backend down")
+
+func Test_circuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(backendOkta, 2, time.Minute)
+
+	assert.False(t, b.record(now, errBackendDown))
+	assert.NoError(t, b.allow(now))
+
+	// a success resets the consecutive failures
+	assert.False(t, b.record(now, nil))
+	assert.False(t, b.record(now, errBackendDown))
+	assert.True(t, b.record(now, errBackendDown))
+
+	assert.ErrorIs(t, b.allow(now.Add(30*time.Second)), ErrCircuitOpen)
+
+	until, open := b.open(now)
+	assert.True(t, open)
+	assert.Equal(t, now.Add(time.Minute), until)
+
+	// half-open after the cooldown, a single failure opens the circuit again
+	assert.NoError(t, b.allow(now.Add(time.Minute)))
+	assert.True(t, b.record(now.Add(time.Minute), errBackendDown))
+	assert.ErrorIs(t, b.allow(now.Add(90*time.Second)), ErrCircuitOpen)
+
+	// and a success closes it
+	assert.NoError(t, b.allow(now.Add(2*time.Minute)))
+	assert.False(t, b.record(now.Add(2*time.Minute), nil))
+
+	_, open = b.open(now.Add(2 * time.Minute))
+	assert.False(t, open)
+
+	var disabled *circuitBreaker
+
+	assert.NoError(t, disabled.allow(now))
+	assert.False(t, disabled.record(now, errBackendDown))
+}
+
+func Test_breakerFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "backend error", err: errBackendDown, want: true},
+		{name: "okta group not found", err: fmt.Errorf("getting group: %w", okta.ErrGroupsNotFound), want: false},
+		{name: "governor user not found", err: governor.ErrUserNotFound, want: false},
+		{name: "okta not found", err: &okta.APIError{Kind: okta.ErrNotFound, StatusCode: http.StatusNotFound}, want: false},
+		{name: "okta resource gone", err: fmt.Errorf("%w: %w", okta.ErrNotFound, errBackendDown), want: false},
+		{name: "okta conflict", err: &okta.APIError{Kind: okta.ErrConflict, StatusCode: http.StatusConflict}, want: false},
+		{name: "okta forbidden", err: &okta.APIError{Kind: okta.ErrForbidden, StatusCode: http.StatusForbidden}, want: false},
+		{name: "okta rate limited", err: &okta.APIError{Kind: okta.ErrRateLimited, StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "okta invalid token", err: &okta.APIError{StatusCode: http.StatusUnauthorized, Code: "E0000011"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, breakerFailure(tt.err))
+		})
+	}
+}
+
+func TestReconciler_callOpCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	r := New(WithCircuitBreaker(2, time.Hour))
+
+	calls := 0
+
+	fail := func(context.Context) error {
+		calls++
+		return errBackendDown
+	}
+
+	assert.ErrorIs(t, r.doOp(ctx, "okta.ListUsers", fail), errBackendDown)
+	assert.ErrorIs(t, r.doOp(ctx, "okta.ListUsers", fail), errBackendDown)
+	assert.True(t, r.circuitOpen())
+
+	// the okta backend isn't called while its circuit is open, governor still is
+	assert.ErrorIs(t, r.doOp(ctx, "okta.GetUser", fail), ErrCircuitOpen)
+	assert.ErrorIs(t, r.doOp(ctx, "governor.Groups", fail), errBackendDown)
+	assert.Equal(t, 3, calls)
+
+	r.reconcileLoop(ctx)
+
+	if runs := r.Status().Runs; assert.Len(t, runs, 1) {
+		assert.Equal(t, RunResultCircuitOpen, runs[0].Result)
+	}
+
+	// operations cancelled by the parent context don't count as failures
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	r = New(WithCircuitBreaker(1, time.Hour), WithLogger(zap.NewNop()))
+
+	assert.Error(t, r.doOp(cancelled, "governor.Groups", fail))
+	assert.False(t, r.circuitOpen())
+}
@@ -0,0 +1,33 @@
+package reconciler
+
+import "time"
+
+// Clock tells the reconciler the current time for its time based decisions (deleted users cutoff, circuit breakers,
+// quarantines, schedules), it can be replaced to control the time in tests
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the clock of the system
+type systemClock struct{}
+
+// Now returns the current time of the system
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock sets the clock the reconciler tells the time with, nil uses the system clock
+func WithClock(c Clock) Option {
+	return func(r *Reconciler) {
+		r.clock = c
+	}
+}
+
+// now returns the current time of the reconciler's clock
+func (r *Reconciler) now() time.Time {
+	if r.clock == nil {
+		return time.Now()
+	}
+
+	return r.clock.Now()
+}
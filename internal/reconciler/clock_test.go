@@ -0,0 +1,69 @@
+package reconciler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock is a clock that only moves when it's told to
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestReconciler_now(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, at, New(WithClock(&testClock{now: at})).now())
+	assert.WithinDuration(t, time.Now(), New().now(), time.Minute)
+	assert.WithinDuration(t, time.Now(), New(WithClock(nil)).now(), time.Minute)
+	assert.WithinDuration(t, time.Now(), (&Reconciler{}).now(), time.Minute)
+}
+
+func TestReconciler_UserDelete_cutoff(t *testing.T) {
+	// the reconciler has been running for a week when the user is deleted
+	clock := &testClock{now: time.Now().Add(7 * 24 * time.Hour)}
+	deletedAt := clock.Now().Add(-time.Hour)
+
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Name: "User 1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive, DeletedAt: &deletedAt})
+	o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+
+	r, _ := newTestServerReconciler(t, o, g, WithClock(clock), WithDryRun(true))
+
+	extID, err := r.UserDelete(context.TODO(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "okta-1", extID)
+
+	// the cutoff moves with the clock, so the user is no longer in the window a day later
+	clock.advance(24 * time.Hour)
+
+	_, err = r.UserDelete(context.TODO(), "user-1")
+	assert.ErrorIs(t, err, ErrUserStillExists)
+}
@@ -0,0 +1,78 @@
+package reconciler
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/ratelimit"
+)
+
+// DefaultMembershipConcurrency is the default number of okta group membership changes in flight at once
+const DefaultMembershipConcurrency = 1
+
+// WithMembershipConcurrency sets how many okta group membership changes are in flight at once.  The limit is
+// shared by all of the groups changed at the same time (ie. by the reconciler loop and NATS events), 1 makes
+// the changes one at a time.
+func WithMembershipConcurrency(n int) Option {
+	return func(r *Reconciler) {
+		r.memberSem = nil
+
+		if n > 1 {
+			r.memberSem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithOktaRateLimit limits the okta calls of the reconciler to rate calls per second with bursts of up to burst
+// calls, a rate of zero or less doesn't limit them
+func WithOktaRateLimit(rate float64, burst int) Option {
+	return func(r *Reconciler) {
+		r.oktaLimiter = ratelimit.New(rate, burst)
+	}
+}
+
+// oktaRateWait waits on the okta rate limiter before an okta operation
+func (r *Reconciler) oktaRateWait(ctx context.Context, op string) error {
+	if !strings.HasPrefix(op, "okta.") {
+		return nil
+	}
+
+	return r.oktaLimiter.Wait(ctx)
+}
+
+// forEachMember calls f for each of the members with up to the membership concurrency calls in flight across
+// the reconciler, it returns once all of the calls are done.  The members left when the context is done are
+// skipped.
+func (r *Reconciler) forEachMember(ctx context.Context, members []MembershipDiffMember, f func(MembershipDiffMember)) {
+	if r.memberSem == nil {
+		for _, m := range members {
+			f(m)
+		}
+
+		return
+	}
+
+	wg := sync.WaitGroup{}
+
+	defer wg.Wait()
+
+	for _, m := range members {
+		select {
+		case r.memberSem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		wg.Add(1)
+
+		go func(m MembershipDiffMember) {
+			defer func() {
+				<-r.memberSem
+				wg.Done()
+			}()
+
+			f(m)
+		}(m)
+	}
+}
@@ -0,0 +1,96 @@
+package reconciler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconciler_forEachMember(t *testing.T) {
+	members := make([]MembershipDiffMember, 20)
+	for i := range members {
+		members[i] = MembershipDiffMember{OktaUserID: string(rune('a' + i))}
+	}
+
+	tests := []struct {
+		name        string
+		concurrency int
+		wantMax     int32
+	}{
+		{name: "sequential", concurrency: 1, wantMax: 1},
+		{name: "concurrent", concurrency: 5, wantMax: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(WithMembershipConcurrency(tt.concurrency))
+
+			var inFlight, maxInFlight atomic.Int32
+
+			mu := sync.Mutex{}
+			seen := map[string]bool{}
+
+			r.forEachMember(context.TODO(), members, func(m MembershipDiffMember) {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+
+				for {
+					cur := maxInFlight.Load()
+					if n <= cur || maxInFlight.CompareAndSwap(cur, n) {
+						break
+					}
+				}
+
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				seen[m.OktaUserID] = true
+				mu.Unlock()
+			})
+
+			assert.Len(t, seen, len(members))
+			assert.LessOrEqual(t, maxInFlight.Load(), tt.wantMax)
+			assert.Zero(t, inFlight.Load())
+		})
+	}
+}
+
+func TestReconciler_forEachMember_cancelled(t *testing.T) {
+	r := New(WithMembershipConcurrency(2))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	// fill the shared slots so the cancelled context is the only way out
+	r.memberSem <- struct{}{}
+	r.memberSem <- struct{}{}
+
+	called := false
+
+	r.forEachMember(ctx, []MembershipDiffMember{{OktaUserID: "a"}}, func(_ MembershipDiffMember) { called = true })
+
+	assert.False(t, called)
+}
+
+func TestReconciler_oktaRateWait(t *testing.T) {
+	r := New(WithOktaRateLimit(1, 1))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	// the burst allows the first okta call right away
+	assert.NoError(t, r.oktaRateWait(ctx, "okta.AddGroupUser"))
+
+	// governor calls aren't limited
+	assert.NoError(t, r.oktaRateWait(ctx, "governor.Group"))
+
+	// the next okta call waits past the deadline
+	assert.ErrorIs(t, r.oktaRateWait(ctx, "okta.RemoveGroupUser"), context.DeadlineExceeded)
+
+	// without a rate limit okta calls aren't limited
+	assert.NoError(t, New().oktaRateWait(ctx, "okta.AddGroupUser"))
+}
@@ -0,0 +1,53 @@
+package reconciler
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+)
+
+// WithDefaultGroups adds the governor users created for new okta users to the governor groups, by id or slug,
+// ie. all-employees
+func WithDefaultGroups(groups ...string) Option {
+	return func(r *Reconciler) {
+		r.defaultGroups = groups
+	}
+}
+
+// addToDefaultGroups adds a governor user created for a new okta user to the default governor groups.  A group
+// that can't be found or joined is logged and skipped, the user was already created.
+func (r *Reconciler) addToDefaultGroups(ctx context.Context, logger *zap.Logger, user *v1alpha1.User, oktaUserID string) {
+	for _, g := range r.defaultGroups {
+		logger := logger.With(zap.String("governor.group", g))
+
+		group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+			return r.governorClient.Group(ctx, g, false)
+		})
+		if err != nil {
+			logger.Warn("error getting default governor group", zap.Error(err))
+			continue
+		}
+
+		logger = logger.With(zap.String("governor.group.id", group.ID), zap.String("governor.group.slug", group.Slug))
+
+		if err := r.doOp(ctx, "governor.AddGroupMember", func(ctx context.Context) error {
+			return r.governorClient.AddGroupMember(ctx, group.ID, user.ID, false)
+		}); err != nil {
+			logger.Warn("error adding governor user to default governor group", zap.Error(err))
+			continue
+		}
+
+		logger.Info("added governor user to default governor group")
+
+		r.writeGovernorUserEvent(ctx, logger, auctx.GovernorGroupMemberAdd{
+			GovernorGroupSlug: group.Slug,
+			GovernorGroupID:   group.ID,
+			GovernorUserEmail: user.Email,
+			GovernorUserID:    user.ID,
+			OktaUserID:        oktaUserID,
+		})
+	}
+}
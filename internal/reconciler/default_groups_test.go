@@ -0,0 +1,81 @@
+package reconciler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/metal-toolbox/auditevent"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestReconciler_addToDefaultGroups(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name          string
+		client        *mockGovClient
+		defaultGroups []string
+		want          map[string][]string
+		wantAudit     bool
+	}{
+		{
+			name:          "added",
+			client:        &mockGovClient{group: testGovGroup(t, "all-employees", nil, nil)},
+			defaultGroups: []string{"all-employees"},
+			want:          map[string][]string{"all-employees": {"user-1"}},
+			wantAudit:     true,
+		},
+		{
+			name:   "no default groups",
+			client: &mockGovClient{group: testGovGroup(t, "all-employees", nil, nil)},
+		},
+		{
+			name:          "group not found",
+			client:        &mockGovClient{err: errBoom},
+			defaultGroups: []string{"all-employees"},
+		},
+		{
+			name:          "add member error",
+			client:        &mockGovClient{group: testGovGroup(t, "all-employees", nil, nil), addMemberErr: errBoom},
+			defaultGroups: []string{"all-employees"},
+		},
+	}
+
+	user := &v1alpha1.User{}
+	if err := json.Unmarshal([]byte(`{"id":"user-1","email":"user@example.com"}`), user); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &bytes.Buffer{}
+
+			r := &Reconciler{
+				auditEventWriter: auditevent.NewDefaultAuditEventWriter(audit),
+				governorClient:   tt.client,
+				logger:           zap.NewNop(),
+			}
+
+			WithDefaultGroups(tt.defaultGroups...)(r)
+
+			ctx := r.withEventlogAuditEvent(context.TODO(), &okta.LogEvent{EventType: "user.lifecycle.create"})
+
+			r.addToDefaultGroups(ctx, r.logger, user, "okta-1")
+
+			assert.Equal(t, tt.want, tt.client.addedMembers)
+
+			if tt.wantAudit {
+				assert.Contains(t, audit.String(), `"GovernorGroupMemberAdd"`)
+				assert.Contains(t, audit.String(), `"governor.group.slug":"all-employees"`)
+			} else {
+				assert.Empty(t, audit.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+const (
+	// SkipReasonPending is the reason a governor group member with a pending status isn't compared
+	SkipReasonPending = "pending"
+	// SkipReasonStatus is the reason a governor group member with another skipped status isn't compared
+	SkipReasonStatus = "status"
+	// SkipReasonMissingExternalID is the reason a governor group member without an okta external id isn't compared
+	SkipReasonMissingExternalID = "missing-external-id"
+)
+
+// MembershipDiffMember is a member of a governor or okta group in a membership diff
+type MembershipDiffMember struct {
+	GovernorUserID string `json:"governor_user_id,omitempty"`
+	OktaUserID     string `json:"okta_user_id,omitempty"`
+	Email          string `json:"email,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// MembershipDiff is the difference between the members of a governor group and the members of its okta group,
+// the same way the reconciler matches them.  Governor members that aren't compared (ie. pending users) are
+// listed as skipped.
+type MembershipDiff struct {
+	GovernorGroupID   string                 `json:"governor_group_id"`
+	GovernorGroupSlug string                 `json:"governor_group_slug"`
+	OktaGroupID       string                 `json:"okta_group_id"`
+	OnlyGovernor      []MembershipDiffMember `json:"only_governor"`
+	OnlyOkta          []MembershipDiffMember `json:"only_okta"`
+	Skipped           []MembershipDiffMember `json:"skipped"`
+}
+
+// GroupMembershipDiff returns the difference between the members of a governor group and its okta group
+func (r *Reconciler) GroupMembershipDiff(ctx context.Context, gid string) (*MembershipDiff, error) {
+	group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+		return r.governorClient.Group(ctx, gid, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	oktaGID, err := callOp(ctx, r, "okta.GetGroupByGovernorID", func(ctx context.Context) (string, error) {
+		return r.oktaClient.GetGroupByGovernorID(ctx, gid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	oktaGroupMembers, err := callOp(ctx, r, "okta.ListGroupMembership", func(ctx context.Context) ([]*okt.User, error) {
+		return r.oktaClient.ListGroupMembership(ctx, oktaGID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	memberUsers, err := r.groupMemberUsers(ctx, gid)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.membershipDiff(group, oktaGID, memberUsers, oktaGroupMembers)
+}
+
+// membershipDiff compares the members of a governor group with the members of its okta group.  Governor members
+// are matched to okta users by their external id, users with a skipped status (ie. pending) and users without an
+// external id are skipped.
+func (r *Reconciler) membershipDiff(group *v1alpha1.Group, oktaGID string, memberUsers map[string]*v1beta1.User, oktaMembers []*okt.User) (*MembershipDiff, error) {
+	diff := &MembershipDiff{
+		GovernorGroupID:   group.ID,
+		GovernorGroupSlug: group.Slug,
+		OktaGroupID:       oktaGID,
+		OnlyGovernor:      []MembershipDiffMember{},
+		OnlyOkta:          []MembershipDiffMember{},
+		Skipped:           []MembershipDiffMember{},
+	}
+
+	inOkta := make(map[string]bool, len(oktaMembers))
+	for _, u := range oktaMembers {
+		inOkta[u.Id] = true
+	}
+
+	// okta uids of the compared governor members
+	inGovernor := map[string]bool{}
+
+	for _, uid := range group.Members {
+		user, ok := memberUsers[uid]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrGovernorUserNotFound, uid)
+		}
+
+		member := MembershipDiffMember{
+			GovernorUserID: user.ID,
+			OktaUserID:     user.ExternalID.String,
+			Email:          user.Email,
+		}
+
+		switch {
+		case r.skipUserStatus(user.Status.String) && user.Status.String == v1alpha1.UserStatusPending:
+			member.Reason = SkipReasonPending
+		case r.skipUserStatus(user.Status.String):
+			member.Reason = SkipReasonStatus
+		case user.ExternalID.String == "":
+			member.Reason = SkipReasonMissingExternalID
+		}
+
+		if member.Reason != "" {
+			diff.Skipped = append(diff.Skipped, member)
+			continue
+		}
+
+		// NOTE: we are skipping group members if the external id is empty and then
+		// assuming the external id is an okta ID.  This works for now, but may need
+		// to be updated if external_id could be am ID in a different system or missing
+		// for valid okta users.
+		inGovernor[member.OktaUserID] = true
+
+		if !inOkta[member.OktaUserID] {
+			diff.OnlyGovernor = append(diff.OnlyGovernor, member)
+		}
+	}
+
+	for _, u := range oktaMembers {
+		if inGovernor[u.Id] {
+			continue
+		}
+
+		member := MembershipDiffMember{OktaUserID: u.Id}
+
+		// the email is informational, a profile without one is still a member
+		if u.Profile != nil {
+			member.Email, _ = okta.EmailFromUserProfile(u)
+		}
+
+		diff.OnlyOkta = append(diff.OnlyOkta, member)
+	}
+
+	return diff, nil
+}
@@ -0,0 +1,62 @@
+package reconciler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_membershipDiff(t *testing.T) {
+	pending := &v1beta1.User{}
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"user-3","email":"three@example.com","external_id":"okta-user-3","status":"pending"}`), pending))
+
+	noExternalID := &v1beta1.User{}
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"user-4","email":"four@example.com"}`), noExternalID))
+
+	users := map[string]*v1beta1.User{
+		"user-1": testGovUser(t, "user-1", "one@example.com"),
+		"user-2": testGovUser(t, "user-2", "two@example.com"),
+		"user-3": pending,
+		"user-4": noExternalID,
+	}
+
+	group := testGovGroup(t, "group-1", []string{"user-1", "user-2", "user-3", "user-4"}, nil)
+
+	oktaMembers := []*okt.User{
+		{Id: "okta-user-1"},
+		{Id: "okta-user-3", Profile: &okt.UserProfile{"email": "three@example.com"}},
+		{Id: "okta-user-5", Profile: &okt.UserProfile{"email": "five@example.com"}},
+	}
+
+	r := &Reconciler{}
+
+	got, err := r.membershipDiff(group, "okta-group-1", users, oktaMembers)
+	require.NoError(t, err)
+
+	assert.Equal(t, &MembershipDiff{
+		GovernorGroupID:   "group-1",
+		GovernorGroupSlug: "group-1",
+		OktaGroupID:       "okta-group-1",
+		OnlyGovernor: []MembershipDiffMember{
+			{GovernorUserID: "user-2", OktaUserID: "okta-user-2", Email: "two@example.com"},
+		},
+		// pending users aren't compared, so their okta membership isn't expected
+		OnlyOkta: []MembershipDiffMember{
+			{OktaUserID: "okta-user-3", Email: "three@example.com"},
+			{OktaUserID: "okta-user-5", Email: "five@example.com"},
+		},
+		Skipped: []MembershipDiffMember{
+			{GovernorUserID: "user-3", OktaUserID: "okta-user-3", Email: "three@example.com", Reason: SkipReasonPending},
+			{GovernorUserID: "user-4", Email: "four@example.com", Reason: SkipReasonMissingExternalID},
+		},
+	}, got)
+
+	group.Members = append(group.Members, "user-6")
+
+	_, err = r.membershipDiff(group, "okta-group-1", users, oktaMembers)
+	assert.ErrorIs(t, err, ErrGovernorUserNotFound)
+}
@@ -9,14 +9,39 @@ var (
 	// ErrGroupMembershipFound is returned when a group membership delete request finds the
 	// user in the governor group
 	ErrGroupMembershipFound = errors.New("delete request user found in group")
-	// ErrGovernorUserPendingStatus is returned when an event it received for a user with pending status
-	ErrGovernorUserPendingStatus = errors.New("governor user has pending status")
+	// ErrGovernorUserPendingStatus is returned when an event it received for a user with a skipped status (ie. pending)
+	ErrGovernorUserPendingStatus = errors.New("governor user has a skipped status")
 	// ErrUserStillExists is returned when a user delete request finds the user still exists in governor
 	ErrUserStillExists = errors.New("delete request user still exists")
-	// ErrUserStatusPending is returned when a user request finds the user status is pending in governor
-	ErrUserStatusPending = errors.New("user status is pending")
+	// ErrGroupStillExists is returned when a group delete request finds the group still exists in governor
+	ErrGroupStillExists = errors.New("delete request group still exists")
+	// ErrAppAssignmentFailed is returned when the application assignments of a github org failed to reconcile
+	ErrAppAssignmentFailed = errors.New("application assignments failed to reconcile")
+	// ErrGroupSlugNotFound is returned when no governor group has the requested slug
+	ErrGroupSlugNotFound = errors.New("governor group slug not found")
+	// ErrUserStatusPending is returned when a user request finds the user status is skipped (ie. pending) in governor
+	ErrUserStatusPending = errors.New("user status is skipped")
 	// ErrUserExternalIDMissing is returned when an action is requested that requires the external id, but its missing
 	ErrUserExternalIDMissing = errors.New("user external id is missing")
+	// ErrGovernorUserNotFound is returned when a governor group member can't be found in the governor users list
+	ErrGovernorUserNotFound = errors.New("governor group member user not found")
 	// ErrUserListEmpty is returned when a user reconcile gets an empty user list from governor or okta
 	ErrUserListEmpty = errors.New("reconcile got an empty user list")
+	// ErrChangeNotApplied is returned when okta doesn't reflect a change the reconciler applied
+	ErrChangeNotApplied = errors.New("applied change not found in okta")
+	// ErrStateNotFound is returned by a state store for a key that was never written
+	ErrStateNotFound = errors.New("reconciler state not found")
+	// ErrOktaGroupMembershipList is returned when the members of an okta group can't be listed, the group membership
+	// isn't reconciled
+	ErrOktaGroupMembershipList = errors.New("error listing okta group members")
+	// ErrCircuitOpen is returned without calling okta or governor while the circuit breaker of the backend is open
+	ErrCircuitOpen = errors.New("circuit breaker open")
+	// ErrUserNotActive is returned when activating the group memberships of a governor user that isn't active
+	ErrUserNotActive = errors.New("user is not active")
+	// ErrEventlogReplayWindowInvalid is returned when an okta event log replay doesn't end after it starts
+	ErrEventlogReplayWindowInvalid = errors.New("event log replay until must be after since")
+	// ErrEventTypeNotHandled is returned when replaying an okta event type the event log poller doesn't handle
+	ErrEventTypeNotHandled = errors.New("okta event type not handled")
+	// ErrEventlogReplayFailed is returned when handling some of the replayed okta log events failed
+	ErrEventlogReplayFailed = errors.New("error replaying okta log events")
 )
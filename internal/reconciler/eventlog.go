@@ -2,13 +2,20 @@ package reconciler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/metal-toolbox/auditevent"
 	"go.uber.org/zap"
 
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
 	okt "github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
@@ -21,36 +28,103 @@ var (
 	DefaultEventlogColdStartLookback = 8 * time.Hour
 )
 
+// WithEventlogProfileUpdates propagates the name and email changes of okta user profiles from the okta event log
+// to the governor users
+func WithEventlogProfileUpdates(b bool) Option {
+	return func(r *Reconciler) {
+		r.profileUpdates = b
+	}
+}
+
+// eventlogTypes returns the handled okta event log event types
+func (r *Reconciler) eventlogTypes() []string {
+	types := []string{"user.lifecycle.create", "user.lifecycle.suspend", "user.lifecycle.unsuspend"}
+
+	if r.profileUpdates {
+		types = append(types, "user.account.update_profile")
+	}
+
+	return types
+}
+
+// eventlogFilter returns the okta event log filter of the handled event types
+func (r *Reconciler) eventlogFilter() string {
+	return eventTypesFilter(r.eventlogTypes())
+}
+
+// eventTypesFilter returns the okta event log filter matching any of the event types
+func eventTypesFilter(types []string) string {
+	filters := make([]string, 0, len(types))
+
+	for _, t := range types {
+		filters = append(filters, fmt.Sprintf("eventType eq %q", t))
+	}
+
+	return "(" + strings.Join(filters, " or ") + ")"
+}
+
 func (r *Reconciler) startEventLogPollerSubscriptions(ctx context.Context) {
-	r.logger.Debug("starting okta event log polling")
-	r.oktaClient.PollLogs(
-		ctx,
-		r.eventlogInterval,
-		time.Now().UTC().Add(-r.eventlogLookback),
-		&query.Params{
-			// https://developer.okta.com/docs/reference/core-okta-api/#filter
-			Filter: `(eventType eq "user.lifecycle.create" or eventType eq "user.lifecycle.suspend" or eventType eq "user.lifecycle.unsuspend")`,
-		},
-		r.oktaLogEventHandler)
+	qp := &query.Params{
+		// https://developer.okta.com/docs/reference/core-okta-api/#filter
+		Filter: r.eventlogFilter(),
+	}
+
+	start := r.eventlogStart(ctx)
+
+	if r.locker == nil {
+		r.logger.Debug("starting okta event log polling")
+		r.oktaClient.PollLogs(ctx, r.eventlogInterval, start, qp, r.oktaLogEventHandler)
+
+		return
+	}
+
+	// the previous leader may have been gone for up to the lock ttl before we take over, so look back
+	// that far (plus an interval) when taking the lead
+	handoff := r.locker.TTL() + r.eventlogInterval
+
+	r.logger.Debug("starting leader aware okta event log polling", zap.Duration("eventlog.handoff", handoff))
+	r.oktaClient.PollLogsAsLeader(ctx, r.eventlogInterval, start, handoff, qp, r.oktaLogEventHandler, r.locker.AcquireLead)
 }
 
 func (r *Reconciler) oktaLogEventHandler(ctx context.Context, evt *okta.LogEvent) {
-	r.logger.Debug("handling event from okta log", zap.String("okta.event.type", evt.EventType), zap.Any("okta.event", evt))
+	r.logger.Debug("handling event from okta log", zap.String("okta.event.type", evt.EventType), redact.Any("okta.event", evt))
+
+	r.oktaEventsSeen.Store(true)
+
+	err := r.handleOktaLogEvent(r.withEventlogAuditEvent(ctx, evt), evt)
+
+	eventlogEventsCounter.WithLabelValues(evt.EventType).Inc()
+
+	if err != nil {
+		eventlogHandlerErrorsCounter.WithLabelValues(evt.EventType).Inc()
+	}
+
+	r.eventlog.handled(evt)
+}
 
+// handleOktaLogEvent runs the handler of the okta log event type, the context has the audit event of the okta event
+func (r *Reconciler) handleOktaLogEvent(ctx context.Context, evt *okta.LogEvent) error {
 	switch evt.EventType {
 	case "user.lifecycle.create":
-		r.userLifecycleCreateHandler(ctx, evt)
+		return r.userLifecycleCreateHandler(ctx, evt)
 
 	case "user.lifecycle.suspend", "user.lifecycle.unsuspend":
-		r.userLifecycleSuspendHandler(ctx, evt)
+		return r.userLifecycleSuspendHandler(ctx, evt)
+
+	case "user.account.update_profile":
+		return r.userProfileUpdateHandler(ctx, evt)
 
 	default:
 		r.logger.Warn("unhandled okta event type", zap.String("okta.event.type", evt.EventType))
 	}
+
+	return nil
 }
 
 // userLifecycleCreateHandler will create a new user in governor if the user does not exist
-func (r *Reconciler) userLifecycleCreateHandler(ctx context.Context, evt *okta.LogEvent) {
+func (r *Reconciler) userLifecycleCreateHandler(ctx context.Context, evt *okta.LogEvent) error {
+	var errs []error
+
 	for _, target := range evt.Target {
 		if target.Type != "User" {
 			r.logger.Warn("unexpected target type for user.lifecycle.create", zap.String("okta.event.target.type", target.Type))
@@ -60,12 +134,18 @@ func (r *Reconciler) userLifecycleCreateHandler(ctx context.Context, evt *okta.L
 		oktUser, err := r.oktaClient.GetUser(ctx, target.Id)
 		if err != nil {
 			r.logger.Warn("error getting user from okta", zap.String("okta.user.id", target.Id), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+
+		if r.nonHumanAccount(ctx, r.logger.With(zap.String("okta.event.type", evt.EventType)), oktUser) {
 			continue
 		}
 
 		email, err := okt.EmailFromUserProfile(oktUser)
 		if err != nil {
 			r.logger.Warn("error getting user email from okta profile", zap.String("okta.user.id", target.Id), zap.Error(err))
+			errs = append(errs, err)
 			continue
 		}
 
@@ -78,28 +158,31 @@ func (r *Reconciler) userLifecycleCreateHandler(ctx context.Context, evt *okta.L
 		first, err := okt.FirstNameFromUserProfile(oktUser)
 		if err != nil {
 			logger.Warn("error getting users first name from okta profile")
+			errs = append(errs, err)
 			continue
 		}
 
 		last, err := okt.LastNameFromUserProfile(oktUser)
 		if err != nil {
 			logger.Warn("error getting users last name from okta profile")
+			errs = append(errs, err)
 			continue
 		}
 
 		govUsers, err := r.governorClient.UsersQuery(ctx, map[string][]string{"email": {email}})
 		if err != nil {
 			logger.Warn("error getting user by email from governor")
+			errs = append(errs, err)
 			continue
 		}
 
-		logger.Debug("got user(s) from governor by email", zap.Any("governor.users", govUsers))
+		logger.Debug("got user(s) from governor by email", redact.Any("governor.users", govUsers))
 
 		switch len(govUsers) {
 		case 0:
 			logger.Debug("okta user does not exist in governor, creating")
 
-			if !r.dryrun {
+			if !r.skipGovernorWrites() {
 				govUser, err := r.governorClient.CreateUser(ctx, &v1alpha1.UserReq{
 					Email:      email,
 					ExternalID: oktUser.Id,
@@ -108,15 +191,24 @@ func (r *Reconciler) userLifecycleCreateHandler(ctx context.Context, evt *okta.L
 				})
 				if err != nil {
 					logger.Warn("error creating governor user", zap.Error(err))
+					errs = append(errs, err)
 					continue
 				}
 
 				logger.Info("created governor user", zap.String("governor.user.id", govUser.ID))
 
+				r.writeGovernorUserEvent(ctx, logger, auctx.GovernorUserCreate{
+					GovernorUserID:    govUser.ID,
+					GovernorUserEmail: email,
+					OktaUserID:        oktUser.Id,
+				})
+
+				r.addToDefaultGroups(ctx, logger.With(zap.String("governor.user.id", govUser.ID)), govUser, oktUser.Id)
+
 				continue
 			}
 
-			logger.Info("SKIP created governor user")
+			logger.Info("SKIP created governor user", zap.Strings("governor.default_groups", r.defaultGroups))
 		case 1:
 			govUser := govUsers[0]
 
@@ -131,7 +223,7 @@ func (r *Reconciler) userLifecycleCreateHandler(ctx context.Context, evt *okta.L
 				continue
 			}
 
-			if !r.dryrun {
+			if !r.skipGovernorWrites() {
 				payload := &v1alpha1.UserReq{
 					Email:      email,
 					ExternalID: oktUser.Id,
@@ -139,16 +231,25 @@ func (r *Reconciler) userLifecycleCreateHandler(ctx context.Context, evt *okta.L
 					Status:     v1alpha1.UserStatusActive,
 				}
 
-				logger.Debug("updating governor user with payload", zap.Any("payload", payload))
+				logger.Debug("updating governor user with payload", redact.Any("payload", payload))
 
 				govUser, err := r.governorClient.UpdateUser(ctx, govUser.ID, payload)
 				if err != nil {
 					logger.Warn("error updating governor user", zap.Error(err))
+					errs = append(errs, err)
 					continue
 				}
 
 				logger.Info("updated governor user", zap.String("governor.user.id", govUser.ID))
 
+				r.forgetGovernorUser(govUsers[0].Email, email)
+
+				r.writeGovernorUserEvent(ctx, logger, auctx.GovernorUserUpdate{
+					GovernorUserID:    govUser.ID,
+					GovernorUserEmail: email,
+					OktaUserID:        oktUser.Id,
+				})
+
 				continue
 			}
 
@@ -159,11 +260,15 @@ func (r *Reconciler) userLifecycleCreateHandler(ctx context.Context, evt *okta.L
 			continue
 		}
 	}
+
+	return errors.Join(errs...)
 }
 
 // userLifecycleSuspendHandler will suspend or un-suspend a governor user. It does not rely on the lifecycle
 // event name but will look up the current user status in okta and update the governor user accordingly.
-func (r *Reconciler) userLifecycleSuspendHandler(ctx context.Context, evt *okta.LogEvent) {
+func (r *Reconciler) userLifecycleSuspendHandler(ctx context.Context, evt *okta.LogEvent) error {
+	var errs []error
+
 	for _, target := range evt.Target {
 		if target.Type != "User" {
 			r.logger.Warn("unexpected target type for user.lifecycle.create", zap.String("okta.event.target.type", target.Type))
@@ -173,12 +278,18 @@ func (r *Reconciler) userLifecycleSuspendHandler(ctx context.Context, evt *okta.
 		oktUser, err := r.oktaClient.GetUser(ctx, target.Id)
 		if err != nil {
 			r.logger.Warn("error getting user from okta", zap.String("okta.user.id", target.Id), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+
+		if r.nonHumanAccount(ctx, r.logger.With(zap.String("okta.event.type", evt.EventType)), oktUser) {
 			continue
 		}
 
 		details, err := okt.UserDetailsFromOktaUser(oktUser)
 		if err != nil {
 			r.logger.Warn("error getting user details from okta profile", zap.String("okta.user.id", target.Id), zap.Error(err))
+			errs = append(errs, err)
 			continue
 		}
 
@@ -192,10 +303,11 @@ func (r *Reconciler) userLifecycleSuspendHandler(ctx context.Context, evt *okta.
 		govUsers, err := r.governorClient.UsersQuery(ctx, map[string][]string{"email": {details.Email}})
 		if err != nil {
 			logger.Warn("error getting user by email from governor")
+			errs = append(errs, err)
 			continue
 		}
 
-		logger.Debug("got user(s) from governor by email", zap.Any("governor.users", govUsers))
+		logger.Debug("got user(s) from governor by email", redact.Any("governor.users", govUsers))
 
 		switch len(govUsers) {
 		case 0:
@@ -214,8 +326,18 @@ func (r *Reconciler) userLifecycleSuspendHandler(ctx context.Context, evt *okta.
 				continue
 			}
 
+			var governorAt time.Time
+			if govUser.User != nil {
+				governorAt = govUser.UpdatedAt
+			}
+
+			if govUser.Status.String != strings.ToLower(details.Status) && r.conflictWinner(ctx, ConflictResourceUsers, governorAt, details.StatusChanged) == SourceOfTruthGovernor {
+				logger.Info("governor is the source of truth of the user status, skipping", zap.String("governor.user.status", govUser.Status.String))
+				continue
+			}
+
 			if govUser.Status.String == v1alpha1.UserStatusActive && details.Status == "SUSPENDED" {
-				if !r.dryrun {
+				if !r.skipGovernorWrites() {
 					payload := &v1alpha1.UserReq{
 						Status: v1alpha1.UserStatusSuspended,
 					}
@@ -223,11 +345,20 @@ func (r *Reconciler) userLifecycleSuspendHandler(ctx context.Context, evt *okta.
 					govUser, err := r.governorClient.UpdateUser(ctx, govUser.ID, payload)
 					if err != nil {
 						logger.Warn("error suspending governor user", zap.Error(err))
+						errs = append(errs, err)
 						continue
 					}
 
 					logger.Info("suspended governor user", zap.String("governor.user.id", govUser.ID))
 
+					r.forgetGovernorUser(details.Email)
+
+					r.writeGovernorUserEvent(ctx, logger, auctx.GovernorUserSuspend{
+						GovernorUserID:    govUser.ID,
+						GovernorUserEmail: details.Email,
+						OktaUserID:        oktUser.Id,
+					})
+
 					continue
 				}
 
@@ -237,7 +368,7 @@ func (r *Reconciler) userLifecycleSuspendHandler(ctx context.Context, evt *okta.
 			}
 
 			if govUser.Status.String == v1alpha1.UserStatusSuspended && details.Status == "ACTIVE" {
-				if !r.dryrun {
+				if !r.skipGovernorWrites() {
 					payload := &v1alpha1.UserReq{
 						Status: v1alpha1.UserStatusActive,
 					}
@@ -245,11 +376,20 @@ func (r *Reconciler) userLifecycleSuspendHandler(ctx context.Context, evt *okta.
 					govUser, err := r.governorClient.UpdateUser(ctx, govUser.ID, payload)
 					if err != nil {
 						logger.Warn("error un-suspending governor user", zap.Error(err))
+						errs = append(errs, err)
 						continue
 					}
 
 					logger.Info("un-suspended governor user", zap.String("governor.user.id", govUser.ID))
 
+					r.forgetGovernorUser(details.Email)
+
+					r.writeGovernorUserEvent(ctx, logger, auctx.GovernorUserUnsuspend{
+						GovernorUserID:    govUser.ID,
+						GovernorUserEmail: details.Email,
+						OktaUserID:        oktUser.Id,
+					})
+
 					continue
 				}
 
@@ -265,4 +405,163 @@ func (r *Reconciler) userLifecycleSuspendHandler(ctx context.Context, evt *okta.
 			continue
 		}
 	}
+
+	return errors.Join(errs...)
+}
+
+// userProfileUpdateHandler updates the name and email of the governor user of an okta user whose profile changed
+func (r *Reconciler) userProfileUpdateHandler(ctx context.Context, evt *okta.LogEvent) error {
+	if !r.profileUpdates {
+		return nil
+	}
+
+	var errs []error
+
+	for _, target := range evt.Target {
+		if target.Type != "User" {
+			r.logger.Warn("unexpected target type for user.account.update_profile", zap.String("okta.event.target.type", target.Type))
+			continue
+		}
+
+		oktUser, err := r.oktaClient.GetUser(ctx, target.Id)
+		if err != nil {
+			r.logger.Warn("error getting user from okta", zap.String("okta.user.id", target.Id), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+
+		logger := r.logger.With(zap.String("okta.event.type", evt.EventType))
+
+		if r.nonHumanAccount(ctx, logger, oktUser) {
+			continue
+		}
+
+		details, err := okt.UserDetailsFromOktaUser(oktUser)
+		if err != nil {
+			logger.Warn("error getting user details from okta profile", zap.String("okta.user.id", target.Id), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := r.updateGovernorUserProfile(ctx, logger, details); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// updateGovernorUserProfile updates the name and email of the governor user matched by external id to the okta
+// user details, the email may have just changed so it can't be matched by email.  It returns the error of a failed
+// governor call, skipped users aren't errors.
+func (r *Reconciler) updateGovernorUserProfile(ctx context.Context, logger *zap.Logger, details *okt.UserDetails) error {
+	logger = logger.With(
+		zap.String("okta.user.id", details.ID),
+		zap.String("okta.user.email", details.Email),
+	)
+
+	govUsers, err := callOp(ctx, r, "governor.UsersV2", func(ctx context.Context) ([]*v1beta1.User, error) {
+		return r.governorClient.UsersV2(ctx, map[string][]string{"external_id": {details.ID}})
+	})
+	if err != nil {
+		logger.Warn("error getting user by external id from governor", zap.Error(err))
+		return err
+	}
+
+	if len(govUsers) != 1 {
+		logger.Info("unexpected number of governor users with external id, skipping", zap.Int("num.governor.users", len(govUsers)))
+		return nil
+	}
+
+	govUser := govUsers[0]
+	logger = logger.With(zap.String("governor.user.id", govUser.ID))
+
+	if govUser.Status.String == v1alpha1.UserStatusPending {
+		logger.Info("skipping pending governor user")
+		return nil
+	}
+
+	if govUser.Name == details.Name && strings.EqualFold(govUser.Email, details.Email) {
+		logger.Debug("governor user profile is up to date")
+		return nil
+	}
+
+	var governorAt time.Time
+	if govUser.User != nil {
+		governorAt = govUser.UpdatedAt
+	}
+
+	if r.conflictWinner(ctx, ConflictResourceUsers, governorAt, details.LastUpdated) == SourceOfTruthGovernor {
+		logger.Info("governor is the source of truth of the user profile, skipping update")
+		return nil
+	}
+
+	if r.skipGovernorWrites() {
+		logger.Info("SKIP updating governor user profile")
+		return nil
+	}
+
+	if _, err := callOp(ctx, r, "governor.UpdateUser", func(ctx context.Context) (*v1alpha1.User, error) {
+		return r.governorClient.UpdateUser(ctx, govUser.ID, &v1alpha1.UserReq{
+			Email:      details.Email,
+			ExternalID: govUser.ExternalID.String,
+			Name:       details.Name,
+			Status:     govUser.Status.String,
+		})
+	}); err != nil {
+		logger.Warn("error updating governor user profile", zap.Error(err))
+		return err
+	}
+
+	logger.Info("updated governor user profile from okta")
+
+	r.forgetGovernorUser(govUser.Email, details.Email)
+
+	r.writeGovernorUserEvent(ctx, logger, auctx.GovernorUserProfileUpdate{
+		GovernorUserID:       govUser.ID,
+		GovernorUserEmail:    govUser.Email,
+		GovernorUserNewEmail: details.Email,
+		GovernorUserName:     details.Name,
+		OktaUserID:           details.ID,
+	})
+
+	return nil
+}
+
+// writeGovernorUserEvent writes the audit event and publishes the change event for a governor user changed from an
+// okta user
+func (r *Reconciler) writeGovernorUserEvent(ctx context.Context, logger *zap.Logger, p auctx.Payload) {
+	r.changes.Publish(ctx, changes.SystemGovernor, p.EventType(), auctx.Target(p))
+
+	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, p); err != nil {
+		logger.Error("error writing audit event", zap.Error(err))
+	}
+}
+
+// withEventlogAuditEvent returns a context with a new audit event for handling an okta log event, the okta actor
+// of the event is the actor
+func (r *Reconciler) withEventlogAuditEvent(ctx context.Context, evt *okta.LogEvent) context.Context {
+	actor := auctx.Actor{Source: auctx.ActorSourceOkta}
+
+	if evt.Actor != nil {
+		actor.ID = evt.Actor.Id
+		actor.Type = evt.Actor.Type
+		actor.Name = evt.Actor.AlternateId
+	}
+
+	return auctx.WithAuditEvent(ctx, auditevent.NewAuditEvent(
+		"", // eventType to be populated later
+		auditevent.EventSource{
+			Type:  "OktaEventLog",
+			Value: evt.EventType,
+			Extra: map[string]interface{}{
+				"okta.event.uuid": evt.Uuid,
+			},
+		},
+		auditevent.OutcomeSucceeded,
+		actor.Subjects(map[string]string{
+			"event": "okta",
+		}),
+		"gov-okta-addon",
+	))
 }
@@ -0,0 +1,132 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	okt "github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReconciler_withEventlogAuditEvent(t *testing.T) {
+	r := &Reconciler{}
+
+	ctx := r.withEventlogAuditEvent(context.Background(), &okta.LogEvent{
+		Uuid:      "event-1",
+		EventType: "user.lifecycle.suspend",
+		Actor:     &okta.LogActor{Id: "okta-admin1", Type: "User", AlternateId: "admin@example.com"},
+	})
+
+	ae := auctx.GetAuditEvent(ctx)
+	require.NotNil(t, ae)
+
+	assert.Equal(t, "user.lifecycle.suspend", ae.Source.Value)
+	assert.Equal(t, "event-1", ae.Source.Extra["okta.event.uuid"])
+	assert.Equal(t, map[string]string{
+		"event":        "okta",
+		"actor.source": "okta",
+		"actor.id":     "okta-admin1",
+		"actor.type":   "User",
+		"actor.name":   "admin@example.com",
+	}, ae.Subjects)
+
+	// events without an actor only have the event subject
+	ae = auctx.GetAuditEvent(r.withEventlogAuditEvent(context.Background(), &okta.LogEvent{EventType: "user.lifecycle.create"}))
+	require.NotNil(t, ae)
+	assert.Equal(t, map[string]string{"event": "okta"}, ae.Subjects)
+}
+
+func TestReconciler_eventlogFilter(t *testing.T) {
+	r := &Reconciler{}
+	assert.Equal(t, `(eventType eq "user.lifecycle.create" or eventType eq "user.lifecycle.suspend" or eventType eq "user.lifecycle.unsuspend")`, r.eventlogFilter())
+
+	WithEventlogProfileUpdates(true)(r)
+	assert.Contains(t, r.eventlogFilter(), ` or eventType eq "user.account.update_profile")`)
+}
+
+func TestReconciler_updateGovernorUserProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		details *okt.UserDetails
+		dryrun  bool
+		want    map[string]*v1alpha1.UserReq
+	}{
+		{
+			name:    "name and email changed",
+			details: &okt.UserDetails{ID: "okta-user-1", Name: "Jane Smith", Email: "jane.smith@example.com"},
+			want: map[string]*v1alpha1.UserReq{
+				"user-1": {Email: "jane.smith@example.com", ExternalID: "okta-user-1", Name: "Jane Smith"},
+			},
+		},
+		{
+			name:    "up to date",
+			details: &okt.UserDetails{ID: "okta-user-1", Name: "Jane Doe", Email: "Jane@example.com"},
+		},
+		{
+			name:    "dry run",
+			details: &okt.UserDetails{ID: "okta-user-1", Name: "Jane Smith", Email: "jane@example.com"},
+			dryrun:  true,
+		},
+		{
+			name:    "no governor user",
+			details: &okt.UserDetails{ID: "okta-user-2", Name: "John Doe", Email: "john@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := testGovUser(t, "user-1", "jane@example.com")
+			u.Name = "Jane Doe"
+
+			gc := &mockGovClient{users: []*v1beta1.User{u}}
+
+			r := &Reconciler{
+				governorClient: gc,
+				logger:         zap.NewNop(),
+				dryrun:         tt.dryrun,
+				profileUpdates: true,
+			}
+
+			r.updateGovernorUserProfile(context.TODO(), r.logger, tt.details)
+			assert.Equal(t, tt.want, gc.updatedUsers)
+		})
+	}
+}
+
+func TestReconciler_oktaLogEventHandler_metrics(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	o.AddUser("okta-1", "SUSPENDED", testOktaProfile("user-1@example.com"))
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusSuspended})
+
+	r, _ := newTestServerReconciler(t, o, g)
+
+	const eventType = "user.lifecycle.suspend"
+
+	events := testutil.ToFloat64(eventlogEventsCounter.WithLabelValues(eventType))
+	errs := testutil.ToFloat64(eventlogHandlerErrorsCounter.WithLabelValues(eventType))
+
+	// the user is already suspended in governor
+	r.oktaLogEventHandler(context.TODO(), &okta.LogEvent{EventType: eventType, Target: []*okta.LogTarget{{Id: "okta-1", Type: "User"}}})
+
+	assert.Equal(t, events+1, testutil.ToFloat64(eventlogEventsCounter.WithLabelValues(eventType)))
+	assert.Equal(t, errs, testutil.ToFloat64(eventlogHandlerErrorsCounter.WithLabelValues(eventType)))
+
+	// the okta user doesn't exist
+	r.oktaLogEventHandler(context.TODO(), &okta.LogEvent{EventType: eventType, Target: []*okta.LogTarget{{Id: "okta-2", Type: "User"}}})
+
+	assert.Equal(t, events+2, testutil.ToFloat64(eventlogEventsCounter.WithLabelValues(eventType)))
+	assert.Equal(t, errs+1, testutil.ToFloat64(eventlogHandlerErrorsCounter.WithLabelValues(eventType)))
+}
@@ -0,0 +1,103 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"go.uber.org/zap"
+)
+
+// correctGovernorMembers corrects the members of a governor group to match its okta group when okta wins the
+// conflict about the members.  Okta group members are added to the governor group when they match a governor user
+// by external id and governor members missing from the okta group are removed, the okta group is left alone so
+// the members are all skipped in the result.
+func (r *Reconciler) correctGovernorMembers(
+	ctx context.Context,
+	logger *zap.Logger,
+	group *v1alpha1.Group,
+	oktaGID string,
+	diff *MembershipDiff,
+	result *MembershipResult,
+) {
+	skip := r.skipGovernorWrites() || r.detectOnlyGroup(ctx, group.ID, group)
+
+	for _, member := range diff.OnlyOkta {
+		result.Skipped = append(result.Skipped, member.OktaUserID)
+
+		logger := logger.With(zap.String("okta.user.id", member.OktaUserID))
+
+		users, err := callOp(ctx, r, "governor.UsersV2", func(ctx context.Context) ([]*v1beta1.User, error) {
+			return r.governorClient.UsersV2(ctx, map[string][]string{"external_id": {member.OktaUserID}})
+		})
+		if err != nil {
+			logger.Error("error getting governor user of okta group member", zap.Error(err))
+			continue
+		}
+
+		if len(users) == 0 {
+			logger.Info("okta group member has no governor user to add to the governor group")
+			continue
+		}
+
+		user := users[0]
+		logger = logger.With(zap.String("governor.user.id", user.ID), zap.String("governor.user.email", user.Email))
+
+		if skip {
+			logger.Info("SKIP adding okta group member to governor group")
+			continue
+		}
+
+		if err := r.doOp(ctx, "governor.AddGroupMember", func(ctx context.Context) error {
+			return r.governorClient.AddGroupMember(ctx, group.ID, user.ID, false)
+		}); err != nil {
+			logger.Error("error adding okta group member to governor group", zap.Error(err))
+			continue
+		}
+
+		logger.Info("added okta group member to governor group")
+
+		r.writeGovernorUserEvent(ctx, logger, auctx.GovernorGroupMemberAdd{
+			GovernorGroupSlug: group.Slug,
+			GovernorGroupID:   group.ID,
+			GovernorUserEmail: user.Email,
+			GovernorUserID:    user.ID,
+			OktaGroupID:       oktaGID,
+			OktaUserID:        member.OktaUserID,
+		})
+	}
+
+	for _, member := range diff.OnlyGovernor {
+		result.Skipped = append(result.Skipped, member.OktaUserID)
+
+		logger := logger.With(
+			zap.String("governor.user.id", member.GovernorUserID),
+			zap.String("governor.user.email", member.Email),
+			zap.String("okta.user.id", member.OktaUserID),
+		)
+
+		if skip || r.skipDelete {
+			logger.Info("SKIP removing user missing from okta group from governor group")
+			continue
+		}
+
+		if err := r.doOp(ctx, "governor.RemoveGroupMember", func(ctx context.Context) error {
+			return r.governorClient.RemoveGroupMember(ctx, group.ID, member.GovernorUserID)
+		}); err != nil {
+			logger.Error("error removing user missing from okta group from governor group", zap.Error(err))
+			continue
+		}
+
+		logger.Info("removed user missing from okta group from governor group")
+
+		r.writeGovernorUserEvent(ctx, logger, auctx.GovernorGroupMemberRemove{
+			GovernorGroupSlug: group.Slug,
+			GovernorGroupID:   group.ID,
+			GovernorUserEmail: member.Email,
+			GovernorUserID:    member.GovernorUserID,
+			OktaGroupID:       oktaGID,
+			OktaUserID:        member.OktaUserID,
+		})
+	}
+}
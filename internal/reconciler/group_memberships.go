@@ -2,18 +2,54 @@ package reconciler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-// GroupMembership performs a full reconciliation on the membership of a group in okta
-func (r *Reconciler) GroupMembership(ctx context.Context, gid, oktaGID string) error {
-	group, err := r.governorClient.Group(ctx, gid, false)
+// MembershipResult summarizes a group membership reconciliation with the okta user ids of the members added to
+// and removed from the okta group, and of the members whose change was skipped (ie. in dry-run, with skip-delete,
+// when a membership request was filed or when the governor group was corrected instead)
+type MembershipResult struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Skipped []string `json:"skipped"`
+}
+
+// MarshalLogObject logs the number of members of each change
+func (m *MembershipResult) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("added", len(m.Added))
+	enc.AddInt("removed", len(m.Removed))
+	enc.AddInt("skipped", len(m.Skipped))
+
+	return nil
+}
+
+// Changed returns true if members were added or removed
+func (m *MembershipResult) Changed() bool {
+	return len(m.Added) > 0 || len(m.Removed) > 0
+}
+
+// GroupMembership performs a full reconciliation on the membership of a group in okta and returns a summary of
+// the changes
+func (r *Reconciler) GroupMembership(ctx context.Context, gid, oktaGID string) (*MembershipResult, error) {
+	group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+		return r.governorClient.Group(ctx, gid, false)
+	})
 	if err != nil {
 		r.logger.Error("error getting governor group", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	logger := r.logger.With(
@@ -21,137 +57,263 @@ func (r *Reconciler) GroupMembership(ctx context.Context, gid, oktaGID string) e
 		zap.String("okta.group.id", oktaGID),
 	)
 
-	oktaGroupMembers, err := r.oktaClient.ListGroupMembership(ctx, oktaGID)
+	oktaGroupMembers, err := callOp(ctx, r, "okta.ListGroupMembership", func(ctx context.Context) ([]*okt.User, error) {
+		return r.oktaClient.ListGroupMembership(ctx, oktaGID)
+	})
 	if err != nil {
-		logger.Error("error getting group membership for okta group")
-		return err
+		// without the okta members every governor member looks missing, so the group is left alone
+		logger.Error("error getting group membership for okta group, skipping the group", zap.Error(err))
+		return nil, fmt.Errorf("%w: %w", ErrOktaGroupMembershipList, err)
 	}
 
-	oktaGroupMemberIDs := make([]string, len(oktaGroupMembers))
-	for i, g := range oktaGroupMembers {
-		oktaGroupMemberIDs[i] = g.Id
+	memberUsers, err := r.groupMemberUsers(ctx, gid)
+	if err != nil {
+		logger.Error("error getting governor group member users", zap.Error(err))
+		return nil, err
 	}
 
-	// keep a map of okta uids to governor uids for quick lookup and less calls
-	oktaUserMap := make(map[string]string)
+	diff, err := r.membershipDiff(group, oktaGID, memberUsers, oktaGroupMembers)
+	if err != nil {
+		logger.Error("error comparing governor and okta group members", zap.Error(err))
+		return nil, err
+	}
 
-	for _, uid := range group.Members {
-		user, err := r.governorClient.User(ctx, uid, false)
-		if err != nil {
-			logger.Error("error getting governor user", zap.Error(err))
-			return err
+	result := &MembershipResult{Added: []string{}, Removed: []string{}, Skipped: []string{}}
+
+	// the changes are made concurrently
+	mu := sync.Mutex{}
+	record := func(ids *[]string, id string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		*ids = append(*ids, id)
+	}
+
+	for _, member := range diff.Skipped {
+		logger.Debug("skipping group member",
+			zap.String("reason", member.Reason),
+			zap.String("governor.user.email", member.Email),
+			zap.String("governor.user.id", member.GovernorUserID),
+		)
+	}
+
+	if (len(diff.OnlyGovernor) > 0 || len(diff.OnlyOkta) > 0) &&
+		r.groupConflictWinner(ctx, logger, ConflictResourceMembers, group, oktaGID, func(g *okt.Group) *time.Time { return g.LastMembershipUpdated }) == SourceOfTruthOkta {
+		logger.Info("okta is the source of truth of the group members, correcting governor group")
+
+		r.correctGovernorMembers(ctx, logger, group, oktaGID, diff, result)
+
+		if r.groupOwners {
+			return result, r.GroupOwners(ctx, gid, oktaGID)
 		}
 
-		if user.Status.String == v1alpha1.UserStatusPending {
-			logger.Debug("skipping user with pending status",
-				zap.String("governor.user.email", user.Email),
-				zap.String("governor.user.id", user.ID),
+		return result, nil
+	}
+
+	// add the members missing from the okta group
+	r.forEachMember(ctx, diff.OnlyGovernor, func(member MembershipDiffMember) {
+		oktaUID := member.OktaUserID
+
+		if r.dryRun() || r.detectOnlyGroup(ctx, group.ID, group) {
+			logger.Info("SKIP adding user to okta group",
+				zap.String("user.email", member.Email),
+				zap.String("okta.user.id", oktaUID),
 			)
 
-			continue
-		} else if user.ExternalID.String == "" {
-			logger.Debug("skipping user with missing external id",
-				zap.String("governor.user.email", user.Email),
-				zap.String("governor.user.id", user.ID),
+			record(&result.Skipped, oktaUID)
+
+			return
+		}
+
+		if err := r.doOp(ctx, "okta.AddGroupUser", func(ctx context.Context) error {
+			return r.oktaClient.AddGroupUser(ctx, oktaGID, oktaUID)
+		}); err != nil {
+			logger.Error("failed to add user to okta group",
+				zap.String("user.email", member.Email),
+				zap.String("okta.user.id", oktaUID),
+				zap.Error(err),
 			)
 
-			continue
+			return
 		}
 
-		// NOTE: we are skipping group members if the external id is empty and then
-		// assuming the external id is an okta ID.  This works for now, but may need
-		// to be updated if external_id could be am ID in a different system or missing
-		// for valid okta users.
-		oktaUID := user.ExternalID.String
-		oktaUserMap[oktaUID] = uid
-
-		// if the okta group already contains the uid, continue
-		if contains(oktaGroupMemberIDs, oktaUID) {
-			logger.Debug("okta group already contains member, not adding")
-			continue
+		incCounter(ctx, groupMembershipCreatedCounter)
+		record(&result.Added, oktaUID)
+
+		if err := r.writeMutationEvent(ctx, auctx.GroupMemberAdd{
+			GovernorGroupSlug: group.Slug,
+			GovernorGroupID:   group.ID,
+			GovernorUserEmail: member.Email,
+			GovernorUserID:    member.GovernorUserID,
+			OktaGroupID:       oktaGID,
+			OktaUserID:        oktaUID,
+		}, nil, map[string]string{"okta.group.id": oktaGID, "okta.user.id": oktaUID}); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
 		}
+	})
+
+	requests := r.groupMemberRequests(ctx, logger, group.ID, diff)
+	remove := make([]MembershipDiffMember, 0, len(diff.OnlyOkta))
 
-		// otherwise add the member
-		if !r.dryrun {
-			if err := r.oktaClient.AddGroupUser(ctx, oktaGID, oktaUID); err != nil {
-				logger.Error("failed to add user to okta group",
-					zap.String("user.email", user.Email),
-					zap.String("okta.user.id", oktaUID),
-					zap.Error(err),
-				)
+	for _, member := range diff.OnlyOkta {
+		// file a governor membership request instead of removing the member, members without a governor user
+		// can't request to join so they're still removed
+		if r.memberRequests {
+			if r.dryRun() || r.detectOnlyGroup(ctx, group.ID, group) {
+				logger.Info("SKIP requesting governor group membership", zap.String("okta.user.id", member.OktaUserID))
+				record(&result.Skipped, member.OktaUserID)
 
 				continue
 			}
 
-			groupMembershipCreatedCounter.Inc()
-
-			if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "GroupMemberAdd", map[string]string{
-				"governor.group.slug": group.Slug,
-				"governor.group.id":   group.ID,
-				"governor.user.email": user.Email,
-				"governor.user.id":    user.ID,
-				"okta.group.id":       oktaGID,
-				"okta.user.id":        oktaUID,
-			}); err != nil {
-				logger.Error("error writing audit event", zap.Error(err))
+			if r.requestGroupMember(ctx, logger, group, oktaGID, member, requests) {
+				record(&result.Skipped, member.OktaUserID)
+				continue
 			}
-		} else {
-			logger.Info("SKIP adding user to okta group",
-				zap.String("user.email", user.Email),
+		}
+
+		remove = append(remove, member)
+	}
+
+	// remove the members that aren't in the governor group
+	r.forEachMember(ctx, remove, func(member MembershipDiffMember) {
+		oktaUID := member.OktaUserID
+
+		if r.dryRun() || r.skipDelete || r.detectOnlyGroup(ctx, group.ID, group) {
+			logger.Info("SKIP removing user from okta group",
 				zap.String("okta.user.id", oktaUID),
 			)
+
+			r.status.pendingDeletion(PendingDeletion{
+				Type:            "GroupMemberRemove",
+				GovernorGroupID: gid,
+				OktaGroupID:     oktaGID,
+				OktaUserID:      oktaUID,
+			})
+
+			record(&result.Skipped, oktaUID)
+
+			return
+		}
+
+		err := r.doOp(ctx, "okta.RemoveGroupUser", func(ctx context.Context) error {
+			return r.oktaClient.RemoveGroupUser(ctx, oktaGID, oktaUID)
+		})
+		if memberAlreadyRemoved(logger, oktaUID, err) {
+			return
 		}
+
+		if err != nil {
+			logger.Error("failed to remove user from okta group",
+				zap.String("okta.user.id", oktaUID),
+				zap.Error(err),
+			)
+
+			return
+		}
+
+		incCounter(ctx, groupMembershipDeletedCounter)
+		record(&result.Removed, oktaUID)
+
+		if err := r.writeMutationEvent(ctx, auctx.GroupMemberRemove{
+			GovernorGroupSlug: group.Slug,
+			GovernorGroupID:   group.ID,
+			OktaGroupID:       oktaGID,
+			OktaUserID:        oktaUID,
+		}, map[string]string{"okta.group.id": oktaGID, "okta.user.id": oktaUID}, nil); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
+		}
+	})
+
+	if r.groupOwners {
+		return result, r.GroupOwners(ctx, gid, oktaGID)
 	}
 
-	for _, oktaUID := range oktaGroupMemberIDs {
-		// if the governor group contains the uid, continue
-		if contains(group.Members, oktaUserMap[oktaUID]) {
-			logger.Debug("governor group contains member, not removing")
-			continue
+	return result, nil
+}
+
+// groupMemberUsers returns a map of governor user ids to governor users for all of the members of a governor
+// group.  The users are fetched in bulk by email rather than getting each member individually.
+func (r *Reconciler) groupMemberUsers(ctx context.Context, gid string) (map[string]*v1beta1.User, error) {
+	members, err := callOp(ctx, r, "governor.GroupMembers", func(ctx context.Context) ([]*v1alpha1.GroupMember, error) {
+		return r.governorClient.GroupMembers(ctx, gid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.membersUsers(ctx, gid, members)
+}
+
+// membersUsers returns a map of governor user ids to governor users for the given members of a governor group
+func (r *Reconciler) membersUsers(ctx context.Context, gid string, members []*v1alpha1.GroupMember) (map[string]*v1beta1.User, error) {
+	emails := make([]string, 0, len(members))
+
+	for _, m := range members {
+		if m.Email != "" {
+			emails = append(emails, m.Email)
 		}
+	}
+
+	users := make(map[string]*v1beta1.User, len(members))
 
-		// otherwise remove the member
-		if !r.dryrun && !r.skipDelete {
-			if err := r.oktaClient.RemoveGroupUser(ctx, oktaGID, oktaUID); err != nil {
-				logger.Error("failed to remove user from okta group",
-					zap.String("okta.user.id", oktaUID),
-					zap.Error(err),
-				)
+	// the users in the cache aren't queried again
+	missing := emails
 
+	if r.userCache != nil {
+		missing = make([]string, 0, len(emails))
+
+		for _, e := range emails {
+			if u, ok := r.userCache.Get(e); ok {
+				users[u.ID] = u
 				continue
 			}
 
-			groupMembershipDeletedCounter.Inc()
+			missing = append(missing, e)
+		}
+	}
 
-			if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "GroupMemberRemove", map[string]string{
-				"governor.group.slug": group.Slug,
-				"governor.group.id":   group.ID,
-				"okta.group.id":       oktaGID,
-				"okta.user.id":        oktaUID,
-			}); err != nil {
-				logger.Error("error writing audit event", zap.Error(err))
-			}
-		} else {
-			logger.Info("SKIP removing user from okta group",
-				zap.String("okta.user.id", oktaUID),
-			)
+	found, err := govclient.UsersByEmail(ctx, missing, func(ctx context.Context, q map[string][]string) ([]*v1beta1.User, error) {
+		return callOp(ctx, r, "governor.UsersV2", func(ctx context.Context) ([]*v1beta1.User, error) {
+			return r.governorClient.UsersV2(ctx, q)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range found {
+		users[u.ID] = u
+
+		if r.userCache != nil && u.User != nil {
+			r.userCache.Set(u.Email, u)
 		}
 	}
 
-	return nil
+	r.logger.Debug("got governor group member users",
+		zap.String("governor.group.id", gid),
+		zap.Int("num.governor.group.members", len(members)),
+		zap.Int("num.governor.users", len(users)),
+	)
+
+	return users, nil
 }
 
 // GroupMembershipCreate reconciles the existence of a user in an okta group based on the given governor user and group ids
 func (r *Reconciler) GroupMembershipCreate(ctx context.Context, gid, uid string) (string, string, error) {
-	group, err := r.governorClient.Group(ctx, gid, false)
+	group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+		return r.governorClient.Group(ctx, gid, false)
+	})
 	if err != nil {
 		r.logger.Error("error getting governor group", zap.Error(err))
 		return "", "", err
 	}
 
-	r.logger.Debug("got group response", zap.Any("group details", group))
+	r.logger.Debug("got group response", redact.Any("group details", group))
 
-	user, err := r.governorClient.User(ctx, uid, false)
+	user, err := callOp(ctx, r, "governor.User", func(ctx context.Context) (*v1alpha1.User, error) {
+		return r.governorClient.User(ctx, uid, false)
+	})
 	if err != nil {
 		r.logger.Error("error getting governor user", zap.Error(err))
 		return "", "", err
@@ -164,8 +326,8 @@ func (r *Reconciler) GroupMembershipCreate(ctx context.Context, gid, uid string)
 		zap.String("governor.user.email", user.Email),
 	)
 
-	if user.Status.String == v1alpha1.UserStatusPending {
-		logger.Info("skipping pending user")
+	if r.skipUserStatus(user.Status.String) {
+		logger.Info("skipping user with a skipped status", zap.String("governor.user.status", user.Status.String))
 		return "", "", ErrGovernorUserPendingStatus
 	}
 
@@ -174,19 +336,19 @@ func (r *Reconciler) GroupMembershipCreate(ctx context.Context, gid, uid string)
 		return "", "", ErrGroupMembershipNotFound
 	}
 
-	oktaUID, err := r.oktaClient.GetUserIDByEmail(ctx, user.Email)
+	oktaUID, err := r.oktaUserIDByMatchKey(ctx, user.Email, user.ExternalID.String)
 	if err != nil {
-		logger.Error("error getting okta user by email", zap.Error(err))
+		logger.Error("error getting okta user by matching key", zap.String("user.match_key", string(r.userMatchKey)), zap.Error(err))
 		return "", "", err
 	}
 
-	oktaGID, err := r.oktaClient.GetGroupByGovernorID(ctx, gid)
+	oktaGID, err := r.oktaGroupID(ctx, logger, group)
 	if err != nil {
 		logger.Error("error getting group by governor id", zap.String("governor.group.id", gid), zap.Error(err))
 		return "", "", err
 	}
 
-	if r.dryrun {
+	if r.dryRun() || r.detectOnlyGroup(ctx, group.ID, group) {
 		logger.Info("SKIP adding user to okta group",
 			zap.String("user.email", user.Email),
 			zap.String("okta.user.id", oktaUID),
@@ -196,7 +358,9 @@ func (r *Reconciler) GroupMembershipCreate(ctx context.Context, gid, uid string)
 		return oktaGID, oktaUID, nil
 	}
 
-	if err := r.oktaClient.AddGroupUser(ctx, oktaGID, oktaUID); err != nil {
+	if err := r.doOp(ctx, "okta.AddGroupUser", func(ctx context.Context) error {
+		return r.oktaClient.AddGroupUser(ctx, oktaGID, oktaUID)
+	}); err != nil {
 		logger.Error("failed to add user to group",
 			zap.String("user.email", user.Email),
 			zap.String("okta.user.id", oktaUID),
@@ -207,16 +371,16 @@ func (r *Reconciler) GroupMembershipCreate(ctx context.Context, gid, uid string)
 		return "", "", err
 	}
 
-	groupMembershipCreatedCounter.Inc()
+	incCounter(ctx, groupMembershipCreatedCounter)
 
-	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "GroupMemberAdd", map[string]string{
-		"governor.group.slug": group.Slug,
-		"governor.group.id":   group.ID,
-		"governor.user.email": user.Email,
-		"governor.user.id":    user.ID,
-		"okta.group.id":       oktaGID,
-		"okta.user.id":        oktaUID,
-	}); err != nil {
+	if err := r.writeMutationEvent(ctx, auctx.GroupMemberAdd{
+		GovernorGroupSlug: group.Slug,
+		GovernorGroupID:   group.ID,
+		GovernorUserEmail: user.Email,
+		GovernorUserID:    user.ID,
+		OktaGroupID:       oktaGID,
+		OktaUserID:        oktaUID,
+	}, nil, map[string]string{"okta.group.id": oktaGID, "okta.user.id": oktaUID}); err != nil {
 		logger.Error("error writing audit event", zap.Error(err))
 	}
 
@@ -225,15 +389,19 @@ func (r *Reconciler) GroupMembershipCreate(ctx context.Context, gid, uid string)
 
 // GroupMembershipDelete reconciles the removal a user from an okta group based on the given governor group and user ids
 func (r *Reconciler) GroupMembershipDelete(ctx context.Context, gid, uid string) (string, string, error) {
-	group, err := r.governorClient.Group(ctx, gid, false)
+	group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+		return r.governorClient.Group(ctx, gid, false)
+	})
 	if err != nil {
 		r.logger.Error("error getting governor group", zap.Error(err))
 		return "", "", err
 	}
 
-	r.logger.Debug("got group response", zap.Any("group details", group))
+	r.logger.Debug("got group response", redact.Any("group details", group))
 
-	user, err := r.governorClient.User(ctx, uid, false)
+	user, err := callOp(ctx, r, "governor.User", func(ctx context.Context) (*v1alpha1.User, error) {
+		return r.governorClient.User(ctx, uid, false)
+	})
 	if err != nil {
 		r.logger.Error("error getting governor user", zap.Error(err))
 		return "", "", err
@@ -246,8 +414,8 @@ func (r *Reconciler) GroupMembershipDelete(ctx context.Context, gid, uid string)
 		zap.String("governor.user.email", user.Email),
 	)
 
-	if user.Status.String == v1alpha1.UserStatusPending {
-		logger.Info("skipping pending user")
+	if r.skipUserStatus(user.Status.String) {
+		logger.Info("skipping user with a skipped status", zap.String("governor.user.status", user.Status.String))
 		return "", "", ErrGovernorUserPendingStatus
 	}
 
@@ -256,19 +424,19 @@ func (r *Reconciler) GroupMembershipDelete(ctx context.Context, gid, uid string)
 		return "", "", ErrGroupMembershipFound
 	}
 
-	oktaUID, err := r.oktaClient.GetUserIDByEmail(ctx, user.Email)
+	oktaUID, err := r.oktaUserIDByMatchKey(ctx, user.Email, user.ExternalID.String)
 	if err != nil {
-		logger.Error("error getting okta user by email", zap.Error(err))
+		logger.Error("error getting okta user by matching key", zap.String("user.match_key", string(r.userMatchKey)), zap.Error(err))
 		return "", "", err
 	}
 
-	oktaGID, err := r.oktaClient.GetGroupByGovernorID(ctx, gid)
+	oktaGID, err := r.oktaGroupID(ctx, logger, group)
 	if err != nil {
 		logger.Error("error getting group by governor id", zap.String("governor.group.id", gid), zap.Error(err))
 		return "", "", err
 	}
 
-	if r.dryrun {
+	if r.dryRun() || r.detectOnlyGroup(ctx, group.ID, group) {
 		logger.Info("SKIP removing user from okta group",
 			zap.String("user.email", user.Email),
 			zap.String("okta.user.id", oktaUID),
@@ -278,7 +446,14 @@ func (r *Reconciler) GroupMembershipDelete(ctx context.Context, gid, uid string)
 		return oktaGID, oktaUID, nil
 	}
 
-	if err := r.oktaClient.RemoveGroupUser(context.Background(), oktaGID, oktaUID); err != nil {
+	err = r.doOp(ctx, "okta.RemoveGroupUser", func(ctx context.Context) error {
+		return r.oktaClient.RemoveGroupUser(ctx, oktaGID, oktaUID)
+	})
+	if memberAlreadyRemoved(logger.With(zap.String("okta.group.id", oktaGID)), oktaUID, err) {
+		return oktaGID, oktaUID, nil
+	}
+
+	if err != nil {
 		logger.Error("failed to remove user from group",
 			zap.String("user.email", user.Email),
 			zap.String("okta.user.id", oktaUID),
@@ -289,18 +464,30 @@ func (r *Reconciler) GroupMembershipDelete(ctx context.Context, gid, uid string)
 		return "", "", err
 	}
 
-	groupMembershipDeletedCounter.Inc()
+	incCounter(ctx, groupMembershipDeletedCounter)
 
-	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "GroupMemberRemove", map[string]string{
-		"governor.group.slug": group.Slug,
-		"governor.group.id":   group.ID,
-		"governor.user.email": user.Email,
-		"governor.user.id":    user.ID,
-		"okta.group.id":       oktaGID,
-		"okta.user.id":        oktaUID,
-	}); err != nil {
+	if err := r.writeMutationEvent(ctx, auctx.GroupMemberRemove{
+		GovernorGroupSlug: group.Slug,
+		GovernorGroupID:   group.ID,
+		GovernorUserEmail: user.Email,
+		GovernorUserID:    user.ID,
+		OktaGroupID:       oktaGID,
+		OktaUserID:        oktaUID,
+	}, map[string]string{"okta.group.id": oktaGID, "okta.user.id": oktaUID}, nil); err != nil {
 		logger.Error("error writing audit event", zap.Error(err))
 	}
 
 	return oktaGID, oktaUID, nil
 }
+
+// memberAlreadyRemoved returns true when removing a member from an okta group failed because the okta user or
+// group doesn't exist anymore, which leaves the member removed as wanted
+func memberAlreadyRemoved(logger *zap.Logger, oktaUID string, err error) bool {
+	if !errors.Is(err, okta.ErrNotFound) {
+		return false
+	}
+
+	logger.Warn("okta user or group not found, treating the member as removed", zap.String("okta.user.id", oktaUID), zap.Error(err))
+
+	return true
+}
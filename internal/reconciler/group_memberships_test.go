@@ -0,0 +1,217 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type mockGovClient struct {
+	govClientIface
+
+	err          error
+	group        *v1alpha1.Group
+	groupMembers []*v1alpha1.GroupMember
+	users        []*v1beta1.User
+
+	usersV2Calls int
+	updatedUsers map[string]*v1alpha1.UserReq
+
+	requestErr     error
+	memberRequests []*govclient.MemberRequest
+
+	addMemberErr error
+	addedMembers map[string][]string
+}
+
+func (m *mockGovClient) AddGroupMember(_ context.Context, groupID, userID string, _ bool) error {
+	if m.addMemberErr != nil {
+		return m.addMemberErr
+	}
+
+	if m.addedMembers == nil {
+		m.addedMembers = map[string][]string{}
+	}
+
+	m.addedMembers[groupID] = append(m.addedMembers[groupID], userID)
+
+	return nil
+}
+
+func (m *mockGovClient) CreateGroupMemberRequest(_ context.Context, _ string, req *govclient.MemberRequest) error {
+	if m.requestErr != nil {
+		return m.requestErr
+	}
+
+	m.memberRequests = append(m.memberRequests, req)
+
+	return nil
+}
+
+func (m *mockGovClient) UpdateUser(_ context.Context, id string, req *v1alpha1.UserReq) (*v1alpha1.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if m.updatedUsers == nil {
+		m.updatedUsers = map[string]*v1alpha1.UserReq{}
+	}
+
+	m.updatedUsers[id] = req
+
+	return &v1alpha1.User{}, nil
+}
+
+func (m *mockGovClient) Group(_ context.Context, _ string, _ bool) (*v1alpha1.Group, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.group, nil
+}
+
+func (m *mockGovClient) GroupMembers(_ context.Context, _ string) ([]*v1alpha1.GroupMember, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.groupMembers, nil
+}
+
+func (m *mockGovClient) UsersV2(_ context.Context, q map[string][]string) ([]*v1beta1.User, error) {
+	m.usersV2Calls++
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	out := []*v1beta1.User{}
+
+	for _, u := range m.users {
+		for _, e := range q["email"] {
+			if strings.EqualFold(u.Email, e) {
+				out = append(out, u)
+			}
+		}
+
+		for _, id := range q["external_id"] {
+			if u.ExternalID.String == id {
+				out = append(out, u)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func testGovUser(t *testing.T, id, email string) *v1beta1.User {
+	t.Helper()
+
+	u := &v1beta1.User{}
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{"id":%q,"email":%q,"external_id":"okta-%s"}`, id, email, id)), u); err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func TestReconciler_groupMemberUsers(t *testing.T) {
+	manyMembers := []*v1alpha1.GroupMember{}
+	manyUsers := []*v1beta1.User{}
+
+	for i := 0; i < govclient.UsersQueryBatchSize+1; i++ {
+		id, email := fmt.Sprintf("user-%d", i), fmt.Sprintf("user-%d@example.com", i)
+		manyMembers = append(manyMembers, &v1alpha1.GroupMember{ID: id, Email: email})
+		manyUsers = append(manyUsers, testGovUser(t, id, email))
+	}
+
+	tests := []struct {
+		name      string
+		client    *mockGovClient
+		wantIDs   []string
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name: "example members",
+			client: &mockGovClient{
+				groupMembers: []*v1alpha1.GroupMember{
+					{ID: "user-1", Email: "one@example.com"},
+					{ID: "user-2", Email: "two@example.com"},
+				},
+				users: []*v1beta1.User{
+					testGovUser(t, "user-1", "one@example.com"),
+					testGovUser(t, "user-2", "two@example.com"),
+					testGovUser(t, "user-3", "three@example.com"),
+				},
+			},
+			wantIDs:   []string{"user-1", "user-2"},
+			wantCalls: 1,
+		},
+		{
+			name:      "no members",
+			client:    &mockGovClient{},
+			wantIDs:   []string{},
+			wantCalls: 0,
+		},
+		{
+			name:      "members are batched",
+			client:    &mockGovClient{groupMembers: manyMembers, users: manyUsers},
+			wantCalls: 2,
+		},
+		{
+			name:    "governor error",
+			client:  &mockGovClient{err: errors.New("boom")}, //nolint:goerr113
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reconciler{
+				governorClient: tt.client,
+				logger:         zap.NewNop(),
+			}
+
+			got, err := r.groupMemberUsers(context.TODO(), "group-1")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCalls, tt.client.usersV2Calls)
+			assert.Len(t, got, len(tt.client.groupMembers))
+
+			for _, id := range tt.wantIDs {
+				assert.Contains(t, got, id)
+			}
+		})
+	}
+}
+
+func TestMembershipResult(t *testing.T) {
+	res := &MembershipResult{Added: []string{}, Removed: []string{}, Skipped: []string{"okta-user1"}}
+	assert.False(t, res.Changed())
+
+	res.Removed = append(res.Removed, "okta-user2")
+	assert.True(t, res.Changed())
+
+	enc := zapcore.NewMapObjectEncoder()
+	assert.NoError(t, res.MarshalLogObject(enc))
+	assert.Equal(t, map[string]interface{}{"added": 0, "removed": 1, "skipped": 1}, enc.Fields)
+
+	b, err := json.Marshal(res)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"added":[],"removed":["okta-user2"],"skipped":["okta-user1"]}`, string(b))
+}
@@ -0,0 +1,165 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"go.uber.org/zap"
+)
+
+// WithGroupOwners enables reconciling governor group admins into okta group owners
+func WithGroupOwners(o bool) Option {
+	return func(r *Reconciler) {
+		r.groupOwners = o
+	}
+}
+
+// GroupOwnersUpdate reconciles the okta group owners of a governor group when group owners are enabled and
+// returns the okta group id
+func (r *Reconciler) GroupOwnersUpdate(ctx context.Context, gid string) (string, error) {
+	if !r.groupOwners {
+		return "", nil
+	}
+
+	oktaGID, err := callOp(ctx, r, "okta.GetGroupByGovernorID", func(ctx context.Context) (string, error) {
+		return r.oktaClient.GetGroupByGovernorID(ctx, gid)
+	})
+	if err != nil {
+		r.logger.Error("error getting okta group by governor id", zap.String("governor.group.id", gid), zap.Error(err))
+		return "", err
+	}
+
+	return oktaGID, r.GroupOwners(ctx, gid, oktaGID)
+}
+
+// GroupOwners performs a full reconciliation of the okta group owners, making the active admins of the
+// governor group the owners of the okta group
+func (r *Reconciler) GroupOwners(ctx context.Context, gid, oktaGID string) error {
+	logger := r.logger.With(
+		zap.String("governor.group.id", gid),
+		zap.String("okta.group.id", oktaGID),
+	)
+
+	members, err := callOp(ctx, r, "governor.GroupMembers", func(ctx context.Context) ([]*v1alpha1.GroupMember, error) {
+		return r.governorClient.GroupMembers(ctx, gid)
+	})
+	if err != nil {
+		logger.Error("error getting governor group members", zap.Error(err))
+		return err
+	}
+
+	admins := []*v1alpha1.GroupMember{}
+
+	for _, m := range members {
+		if m.IsAdmin {
+			admins = append(admins, m)
+		}
+	}
+
+	adminUsers, err := r.membersUsers(ctx, gid, admins)
+	if err != nil {
+		logger.Error("error getting governor group admin users", zap.Error(err))
+		return err
+	}
+
+	desired := []string{}
+
+	for _, u := range adminUsers {
+		// same as members, users with a skipped status (ie. pending) and users without an okta id are skipped
+		if r.skipUserStatus(u.Status.String) || u.ExternalID.String == "" {
+			continue
+		}
+
+		desired = append(desired, u.ExternalID.String)
+	}
+
+	current, err := callOp(ctx, r, "okta.ListGroupOwners", func(ctx context.Context) ([]string, error) {
+		return r.oktaClient.ListGroupOwners(ctx, oktaGID)
+	})
+	if err != nil {
+		logger.Error("error listing okta group owners", zap.Error(err))
+		return err
+	}
+
+	add, remove := groupOwnersDiff(desired, current)
+
+	for _, oktaUID := range add {
+		if r.dryRun() || r.detectOnlyGroup(ctx, gid, nil) {
+			logger.Info("SKIP adding owner to okta group", zap.String("okta.user.id", oktaUID))
+			continue
+		}
+
+		if err := r.doOp(ctx, "okta.AddGroupOwner", func(ctx context.Context) error {
+			return r.oktaClient.AddGroupOwner(ctx, oktaGID, oktaUID)
+		}); err != nil {
+			logger.Error("failed to add owner to okta group", zap.String("okta.user.id", oktaUID), zap.Error(err))
+			continue
+		}
+
+		incCounter(ctx, groupOwnerCreatedCounter)
+
+		if err := r.writeMutationEvent(ctx, auctx.GroupOwnerAdd{
+			GovernorGroupID: gid,
+			OktaGroupID:     oktaGID,
+			OktaUserID:      oktaUID,
+		}, nil, map[string]string{"okta.group.id": oktaGID, "okta.owner.id": oktaUID}); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
+		}
+	}
+
+	for _, oktaUID := range remove {
+		if r.dryRun() || r.skipDelete || r.detectOnlyGroup(ctx, gid, nil) {
+			logger.Info("SKIP removing owner from okta group", zap.String("okta.user.id", oktaUID))
+
+			r.status.pendingDeletion(PendingDeletion{
+				Type:            "GroupOwnerRemove",
+				GovernorGroupID: gid,
+				OktaGroupID:     oktaGID,
+				OktaUserID:      oktaUID,
+			})
+
+			continue
+		}
+
+		if err := r.doOp(ctx, "okta.RemoveGroupOwner", func(ctx context.Context) error {
+			return r.oktaClient.RemoveGroupOwner(ctx, oktaGID, oktaUID)
+		}); err != nil {
+			logger.Error("failed to remove owner from okta group", zap.String("okta.user.id", oktaUID), zap.Error(err))
+			continue
+		}
+
+		incCounter(ctx, groupOwnerDeletedCounter)
+
+		if err := r.writeMutationEvent(ctx, auctx.GroupOwnerRemove{
+			GovernorGroupID: gid,
+			OktaGroupID:     oktaGID,
+			OktaUserID:      oktaUID,
+		}, map[string]string{"okta.group.id": oktaGID, "okta.owner.id": oktaUID}, nil); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// groupOwnersDiff returns the okta user ids that need to be added and removed to make the current owners
+// match the desired owners
+func groupOwnersDiff(desired, current []string) ([]string, []string) {
+	add := []string{}
+	remove := []string{}
+
+	for _, id := range desired {
+		if !contains(current, id) {
+			add = append(add, id)
+		}
+	}
+
+	for _, id := range current {
+		if !contains(desired, id) {
+			remove = append(remove, id)
+		}
+	}
+
+	return add, remove
+}
@@ -0,0 +1,46 @@
+package reconciler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_groupOwnersDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		desired    []string
+		current    []string
+		wantAdd    []string
+		wantRemove []string
+	}{
+		{
+			name:       "in sync",
+			desired:    []string{"user1", "user2"},
+			current:    []string{"user2", "user1"},
+			wantAdd:    []string{},
+			wantRemove: []string{},
+		},
+		{
+			name:       "add and remove",
+			desired:    []string{"user1", "user2"},
+			current:    []string{"user2", "user3"},
+			wantAdd:    []string{"user1"},
+			wantRemove: []string{"user3"},
+		},
+		{
+			name:       "no admins removes all owners",
+			current:    []string{"user1"},
+			wantAdd:    []string{},
+			wantRemove: []string{"user1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			add, remove := groupOwnersDiff(tt.desired, tt.current)
+			assert.Equal(t, tt.wantAdd, add)
+			assert.Equal(t, tt.wantRemove, remove)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"go.uber.org/zap"
+)
+
+// WithOktaGroupIDRecording records the okta group id on the governor groups, so later lookups get the okta
+// group by id instead of searching okta for the governor id.  It needs the update:governor:groups scope.
+func WithOktaGroupIDRecording(enabled bool) Option {
+	return func(r *Reconciler) {
+		r.recordOktaGroupIDs = enabled
+	}
+}
+
+// oktaGroupID returns the okta group id of a governor group.  The okta group id recorded on the governor group is
+// used as long as that okta group still belongs to the governor group, otherwise the okta group is searched by
+// governor id and its id is recorded.
+func (r *Reconciler) oktaGroupID(ctx context.Context, logger *zap.Logger, group *v1alpha1.Group) (string, error) {
+	if recorded := govclient.OktaGroupID(group); recorded != "" {
+		og, err := callOp(ctx, r, "okta.GetGroup", func(ctx context.Context) (*okt.Group, error) {
+			return r.oktaClient.GetGroup(ctx, recorded)
+		})
+
+		switch {
+		case err != nil:
+			logger.Debug("error getting recorded okta group, searching by governor id", zap.String("okta.group.id", recorded), zap.Error(err))
+		case og.Profile == nil || og.Profile.GroupProfileMap[okta.GroupProfileGovernorIDKey] != group.ID:
+			logger.Warn("recorded okta group belongs to another governor group, searching by governor id", zap.String("okta.group.id", recorded))
+		default:
+			return recorded, nil
+		}
+	}
+
+	oktaGID, err := callOp(ctx, r, "okta.GetGroupByGovernorID", func(ctx context.Context) (string, error) {
+		return r.oktaClient.GetGroupByGovernorID(ctx, group.ID)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	r.recordOktaGroupID(ctx, logger, group, oktaGID)
+
+	return oktaGID, nil
+}
+
+// recordOktaGroupID records the okta group id on the governor group, errors are logged since the okta group can
+// still be searched by governor id
+func (r *Reconciler) recordOktaGroupID(ctx context.Context, logger *zap.Logger, group *v1alpha1.Group, oktaGID string) {
+	if !r.recordOktaGroupIDs || groupDeleted(group) || govclient.OktaGroupID(group) == oktaGID {
+		return
+	}
+
+	logger = logger.With(zap.String("okta.group.id", oktaGID))
+
+	if r.skipGovernorWrites() || r.detectOnlyGroup(ctx, group.ID, group) {
+		logger.Info("SKIP recording okta group id on governor group")
+		return
+	}
+
+	if err := r.doOp(ctx, "governor.RecordOktaGroupID", func(ctx context.Context) error {
+		return r.governorClient.RecordOktaGroupID(ctx, group, oktaGID)
+	}); err != nil {
+		logger.Error("error recording okta group id on governor group", zap.Error(err))
+		return
+	}
+
+	logger.Info("recorded okta group id on governor group")
+
+	r.writeGovernorUserEvent(ctx, logger, auctx.GovernorGroupOktaIDUpdate{
+		GovernorGroupSlug: group.Slug,
+		GovernorGroupID:   group.ID,
+		OktaGroupID:       oktaGID,
+	})
+}
@@ -0,0 +1,102 @@
+package reconciler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciler_oktaGroupID_recording(t *testing.T) {
+	search := `profile.governor_id eq "group-1"`
+
+	oktaSearches := func(o *testserver.Okta) int {
+		n := 0
+
+		for _, req := range o.Requests() {
+			if req.Method == http.MethodGet && req.Path == "/api/v1/groups" && req.Query.Get("search") == search {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	tests := []struct {
+		name       string
+		note       string
+		opts       []Option
+		wantNote   string
+		wantSearch bool
+		wantAudit  bool
+	}{
+		{
+			name:       "recorded after searching",
+			opts:       []Option{WithOktaGroupIDRecording(true)},
+			wantNote:   "okta_group_id: 00g-platform",
+			wantSearch: true,
+			wantAudit:  true,
+		},
+		{
+			name:     "recorded id is used",
+			note:     "okta_group_id: 00g-platform",
+			opts:     []Option{WithOktaGroupIDRecording(true)},
+			wantNote: "okta_group_id: 00g-platform",
+		},
+		{
+			name:       "stale recorded id is replaced",
+			note:       "owned by platform\nokta_group_id: 00g-gone",
+			opts:       []Option{WithOktaGroupIDRecording(true)},
+			wantNote:   "owned by platform\nokta_group_id: 00g-platform",
+			wantSearch: true,
+			wantAudit:  true,
+		},
+		{
+			name:       "recording disabled",
+			wantSearch: true,
+		},
+		{
+			name:       "dry run",
+			opts:       []Option{WithOktaGroupIDRecording(true), WithDryRun(true)},
+			wantSearch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Note: tt.note, Members: []string{"user-1"}})
+
+			o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-1")
+
+			r, audit := newTestServerReconciler(t, o, g, tt.opts...)
+
+			r.reconcileLoop(context.TODO())
+
+			runs := r.Status().Runs
+			require.Len(t, runs, 1)
+			assert.Equal(t, RunResultSucceeded, runs[0].Result, runs[0].Error)
+
+			assert.Equal(t, tt.wantNote, g.Group("group-1").Note)
+			assert.Equal(t, tt.wantSearch, oktaSearches(o) > 0)
+
+			if tt.wantAudit {
+				assert.Contains(t, audit.String(), "GovernorGroupOktaIDUpdate")
+			} else {
+				assert.NotContains(t, audit.String(), "GovernorGroupOktaIDUpdate")
+			}
+		})
+	}
+}
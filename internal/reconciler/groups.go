@@ -2,30 +2,39 @@ package reconciler
 
 import (
 	"context"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
 	"go.uber.org/zap"
 )
 
-// GroupsApplicationAssignments reconciles application assignments in okta for a list of governor groups
-func (r *Reconciler) GroupsApplicationAssignments(ctx context.Context, ids ...string) error {
+// GroupsApplicationAssignments reconciles application assignments in okta for a list of governor groups and
+// returns the result of each github org, the error joins the errors of the failing orgs
+func (r *Reconciler) GroupsApplicationAssignments(ctx context.Context, ids ...string) (AppAssignmentResults, error) {
 	groupMap := map[string]*v1alpha1.Group{}
 
 	for _, id := range ids {
 		logger := r.logger.With(zap.String("group.id", id))
 
 		// get the details about a governor group
-		group, err := r.governorClient.Group(ctx, id, false)
+		group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+			return r.governorClient.Group(ctx, id, false)
+		})
 		if err != nil {
 			logger.Error("error getting governor group details", zap.Error(err))
 			continue
 		}
 
-		logger.Debug("got governor group response", zap.Any("group details", group))
+		logger.Debug("got governor group response", redact.Any("group details", group))
 
 		// get the okta id for the governor group
-		oktaGID, err := r.oktaClient.GetGroupByGovernorID(ctx, id)
+		oktaGID, err := r.oktaGroupID(ctx, logger, group)
 		if err != nil {
 			logger.Error("error getting okta group by governor id", zap.Error(err))
 			continue
@@ -37,9 +46,23 @@ func (r *Reconciler) GroupsApplicationAssignments(ctx context.Context, ids ...st
 	return r.reconcileGroupApplicationAssignments(ctx, groupMap)
 }
 
+// oktaGroupName returns the okta group name of a governor group, the governor group name is mapped to the okta
+// naming conventions before it's rendered with the group name template
+func (r *Reconciler) oktaGroupName(group *v1alpha1.Group) (string, error) {
+	name := r.groupNames.OktaName(group.Name)
+
+	if r.groupNameTemplate == nil {
+		return name, nil
+	}
+
+	return r.groupNameTemplate.Execute(okta.GroupNameFields{ID: group.ID, Name: name, Slug: group.Slug})
+}
+
 // GroupCreate creates a governor group in okta
 func (r *Reconciler) GroupCreate(ctx context.Context, id string) (string, error) {
-	group, err := r.governorClient.Group(ctx, id, false)
+	group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+		return r.governorClient.Group(ctx, id, false)
+	})
 	if err != nil {
 		r.logger.Error("error getting governor group", zap.Error(err))
 		return "", err
@@ -47,26 +70,36 @@ func (r *Reconciler) GroupCreate(ctx context.Context, id string) (string, error)
 
 	logger := r.logger.With(zap.String("governor.group.id", group.ID), zap.String("governor.group.slug", group.Slug))
 
-	if r.dryrun {
+	if r.dryRun() || r.detectOnlyGroup(ctx, group.ID, group) {
 		logger.Info("SKIP creating okta group")
 		return "dryrun", nil
 	}
 
-	oktaGID, err := r.oktaClient.CreateGroup(ctx, group.Name, group.Description, map[string]interface{}{"governor_id": group.ID})
+	name, err := r.oktaGroupName(group)
+	if err != nil {
+		logger.Error("error naming okta group", zap.Error(err))
+		return "", err
+	}
+
+	oktaGID, err := callOp(ctx, r, "okta.CreateGroup", func(ctx context.Context) (string, error) {
+		return r.oktaClient.CreateGroup(ctx, name, r.descriptionMarker.Mark(group.Description), map[string]interface{}{"governor_id": group.ID})
+	})
 	if err != nil {
 		logger.Error("error creating okta group", zap.Error(err))
 		return "", err
 	}
 
-	groupsCreatedCounter.Inc()
+	incCounter(ctx, groupsCreatedCounter)
 
-	logger.Info("created okta group", zap.String("okta.group.id", oktaGID))
+	logger.Info("created okta group", zap.String("okta.group.id", oktaGID), zap.String("okta.group.name", name))
 
-	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "GroupCreate", map[string]string{
-		"governor.group.slug": group.Slug,
-		"governor.group.id":   group.ID,
-		"okta.group.id":       oktaGID,
-	}); err != nil {
+	r.recordOktaGroupID(ctx, logger, group, oktaGID)
+
+	if err := r.writeMutationEvent(ctx, auctx.GroupCreate{
+		GovernorGroupSlug: group.Slug,
+		GovernorGroupID:   group.ID,
+		OktaGroupID:       oktaGID,
+	}, nil, map[string]string{"okta.group.id": oktaGID, "name": name, "description": r.descriptionMarker.Mark(group.Description)}); err != nil {
 		logger.Error("error writing audit event", zap.Error(err))
 	}
 
@@ -75,7 +108,9 @@ func (r *Reconciler) GroupCreate(ctx context.Context, id string) (string, error)
 
 // GroupUpdate updates an existing governor group in okta
 func (r *Reconciler) GroupUpdate(ctx context.Context, id string) (string, error) {
-	group, err := r.governorClient.Group(ctx, id, false)
+	group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+		return r.governorClient.Group(ctx, id, false)
+	})
 	if err != nil {
 		r.logger.Error("failed to get group from governor", zap.Error(err))
 		return "", err
@@ -83,66 +118,144 @@ func (r *Reconciler) GroupUpdate(ctx context.Context, id string) (string, error)
 
 	logger := r.logger.With(zap.String("governor.group.id", group.ID), zap.String("governor.group.slug", group.Slug))
 
-	oktaGID, err := r.oktaClient.GetGroupByGovernorID(ctx, group.ID)
+	oktaGID, err := r.oktaGroupID(ctx, logger, group)
 	if err != nil {
 		logger.Error("error getting group by governor id", zap.String("governor.group.id", group.ID), zap.Error(err))
 		return "", err
 	}
 
-	if r.dryrun {
+	if r.dryRun() || r.detectOnlyGroup(ctx, group.ID, group) {
 		logger.Info("SKIP updating okta group")
 		return oktaGID, nil
 	}
 
-	if _, err := r.oktaClient.UpdateGroup(ctx, oktaGID, group.Name, group.Description, map[string]interface{}{"governor_id": group.ID}); err != nil {
+	if r.groupConflictWinner(ctx, logger, ConflictResourceGroups, group, oktaGID, func(g *okt.Group) *time.Time { return g.LastUpdated }) == SourceOfTruthOkta {
+		logger.Info("okta is the source of truth of the group, skipping update", zap.String("okta.group.id", oktaGID))
+		return oktaGID, nil
+	}
+
+	name, err := r.oktaGroupName(group)
+	if err != nil {
+		logger.Error("error naming okta group", zap.Error(err))
+		return "", err
+	}
+
+	merge, err := callOp(ctx, r, "okta.UpdateGroupMerge", func(ctx context.Context) (*okta.GroupUpdateMerge, error) {
+		_, merge, err := r.oktaClient.UpdateGroupMerge(ctx, oktaGID, name, r.descriptionMarker.Mark(group.Description), map[string]interface{}{"governor_id": group.ID})
+		return merge, err
+	})
+	if err != nil {
 		logger.Error("error updating group", zap.Error(err))
 		return "", err
 	}
 
-	groupsUpdatedCounter.Inc()
+	if merge.Unchanged {
+		logger.Debug("okta group unchanged, skipped update", zap.String("okta.group.id", oktaGID))
+		incCounter(ctx, groupsUpdateSkippedCounter)
 
-	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "GroupUpdate", map[string]string{
-		"governor.group.slug": group.Slug,
-		"governor.group.id":   group.ID,
-		"okta.group.id":       oktaGID,
-	}); err != nil {
+		return oktaGID, nil
+	}
+
+	incCounter(ctx, groupsUpdatedCounter)
+
+	if merge.Conflicts > 0 {
+		logger.Warn("okta group changed during update, merged concurrent changes", zap.Int("okta.group.update.conflicts", merge.Conflicts))
+
+		if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, auctx.GroupUpdateConflict{
+			GovernorGroupSlug:   group.Slug,
+			GovernorGroupID:     group.ID,
+			OktaGroupID:         oktaGID,
+			Conflicts:           strconv.Itoa(merge.Conflicts),
+			PreservedAttributes: strings.Join(merge.Preserved, ","),
+		}); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
+		}
+	}
+
+	before := map[string]string{"okta.group.id": oktaGID}
+	if merge.Previous != nil && merge.Previous.Profile != nil {
+		before["name"] = merge.Previous.Profile.Name
+		before["description"] = merge.Previous.Profile.Description
+	}
+
+	if err := r.writeMutationEvent(ctx, auctx.GroupUpdate{
+		GovernorGroupSlug: group.Slug,
+		GovernorGroupID:   group.ID,
+		OktaGroupID:       oktaGID,
+	}, before, map[string]string{"okta.group.id": oktaGID, "name": name, "description": r.descriptionMarker.Mark(group.Description)}); err != nil {
 		logger.Error("error writing audit event", zap.Error(err))
 	}
 
 	return oktaGID, nil
 }
 
-// GroupDelete deletes an existing governor group in okta
+// GroupDelete deletes an existing governor group in okta, with a delete grace period the okta group is only
+// flagged for deletion and the reconciler loop deletes it once the grace period is over.  An error is returned
+// if the group still exists in governor.
 func (r *Reconciler) GroupDelete(ctx context.Context, id string) (string, error) {
-	// TODO validate the group is deleted from governor API by ID
-	oktaGID, err := r.oktaClient.GetGroupByGovernorID(ctx, id)
+	// get details about this group and verify it was actually deleted in governor
+	group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+		return r.governorClient.Group(ctx, id, true)
+	})
+	if err != nil {
+		r.logger.Error("failed to get group from governor", zap.String("governor.group.id", id), zap.Error(err))
+		return "", err
+	}
+
+	if !groupDeleted(group) {
+		r.logger.Error("group still exists in governor", zap.String("governor.group.id", id))
+		return "", ErrGroupStillExists
+	}
+
+	oktaGID, err := r.oktaGroupID(ctx, r.logger.With(zap.String("governor.group.id", id)), group)
 	if err != nil {
 		r.logger.Error("error getting okta group by governor id", zap.String("governor.group.id", id), zap.Error(err))
 		return "", err
 	}
 
-	if r.dryrun {
+	if r.dryRun() || r.detectOnlyGroup(ctx, id, nil) {
 		r.logger.Info("dryrun deleting okta group", zap.String("okta.group.id", oktaGID))
 		return oktaGID, nil
 	}
 
-	if err := r.oktaClient.DeleteGroup(ctx, oktaGID); err != nil {
+	if r.groupDeleteGrace > 0 {
+		return oktaGID, r.groupDeletePending(ctx, id, oktaGID)
+	}
+
+	if err := r.doOp(ctx, "okta.DeleteGroup", func(ctx context.Context) error {
+		return r.oktaClient.DeleteGroup(ctx, oktaGID)
+	}); err != nil {
 		r.logger.Error("error deleting group", zap.Error(err))
 		return "", err
 	}
 
-	groupsDeletedCounter.Inc()
+	incCounter(ctx, groupsDeletedCounter)
 
-	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "GroupDelete", map[string]string{
-		"governor.group.id": id,
-		"okta.group.id":     oktaGID,
-	}); err != nil {
+	if err := r.writeMutationEvent(ctx, auctx.GroupDelete{
+		GovernorGroupID: id,
+		OktaGroupID:     oktaGID,
+	}, map[string]string{"okta.group.id": oktaGID}, nil); err != nil {
 		r.logger.Error("error writing audit event", zap.Error(err))
 	}
 
 	return oktaGID, nil
 }
 
+// groupDeleted returns true if the given group has been deleted in governor.  The function also performs some
+// basic group validation and will return false if anything with the group doesn't look right
+func groupDeleted(group *v1alpha1.Group) bool {
+	if group == nil || group.Group == nil {
+		return false
+	}
+
+	// these fields should always be defined for a group
+	if group.ID == "" || group.Slug == "" {
+		return false
+	}
+
+	return !group.DeletedAt.IsZero()
+}
+
 // getGroupOrgSlugs returns the github organization slugs assigned to a governor group
 func getGroupOrgSlugs(group *v1alpha1.Group, orgs []*v1alpha1.Organization) []string {
 	slugs := []string{}
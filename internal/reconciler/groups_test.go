@@ -1,11 +1,18 @@
 package reconciler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"github.com/volatiletech/null/v8"
+	"go.uber.org/zap"
 )
 
 var testOrganizationsList = []byte(`
@@ -105,3 +112,95 @@ func Test_getGroupOrgSlugs(t *testing.T) {
 		})
 	}
 }
+
+func TestReconciler_oktaGroupName(t *testing.T) {
+	tmpl, err := okta.ParseGroupNameTemplate("gov-{{ .Name }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := okta.GroupNameMapping{
+		ToOkta: []okta.GroupNameRule{{Pattern: regexp.MustCompile(`^team-(.+)$`), Replace: "$1-team"}},
+	}
+
+	tests := []struct {
+		name  string
+		group string
+		opts  []Option
+		want  string
+	}{
+		{name: "governor name", group: "team-platform", want: "team-platform"},
+		{name: "mapped name", group: "team-platform", opts: []Option{WithGroupNames(names)}, want: "platform-team"},
+		{name: "template", group: "team-platform", opts: []Option{WithGroupNameTemplate(tmpl)}, want: "gov-team-platform"},
+		{
+			name:  "mapped name in template",
+			group: "team-platform",
+			opts:  []Option{WithGroupNames(names), WithGroupNameTemplate(tmpl)},
+			want:  "gov-platform-team",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.opts...).oktaGroupName(testGovGroup(t, tt.group, nil, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func testGovGroupDeleted(t *testing.T, id string, at time.Time) *v1alpha1.Group {
+	t.Helper()
+
+	g := testGovGroup(t, id, nil, nil)
+	g.DeletedAt = null.TimeFrom(at)
+
+	return g
+}
+
+func Test_groupDeleted(t *testing.T) {
+	tests := []struct {
+		name  string
+		group *v1alpha1.Group
+		want  bool
+	}{
+		{name: "deleted", group: testGovGroupDeleted(t, "platform", time.Now()), want: true},
+		{name: "not deleted", group: testGovGroup(t, "platform", nil, nil), want: false},
+		{name: "missing slug", group: testGovGroupDeleted(t, "", time.Now()), want: false},
+		{name: "empty group", group: &v1alpha1.Group{}, want: false},
+		{name: "nil group", group: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, groupDeleted(tt.group))
+		})
+	}
+}
+
+func TestReconciler_GroupDelete(t *testing.T) {
+	govErr := errors.New("boom") //nolint:goerr113
+
+	tests := []struct {
+		name    string
+		client  *mockGovClient
+		wantErr error
+	}{
+		{name: "group still exists", client: &mockGovClient{group: testGovGroup(t, "platform", nil, nil)}, wantErr: ErrGroupStillExists},
+		{name: "empty governor response", client: &mockGovClient{}, wantErr: ErrGroupStillExists},
+		{name: "governor error", client: &mockGovClient{err: govErr}, wantErr: govErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reconciler{
+				governorClient: tt.client,
+				logger:         zap.NewNop(),
+			}
+
+			got, err := r.GroupDelete(context.TODO(), "platform")
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Empty(t, got)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultInvariantTolerance is the default for how far apart (as a fraction of the larger count) governor
+	// and okta counts can be before the invariants check reports a divergence
+	DefaultInvariantTolerance = 0.05
+
+	invariantUsers       = "users"
+	invariantGroups      = "groups"
+	invariantMemberships = "memberships"
+)
+
+// InvariantTolerances are the allowed divergence between the governor and okta counts checked at the
+// end of each reconciler loop, as a fraction of the larger count
+type InvariantTolerances struct {
+	Users       float64
+	Groups      float64
+	Memberships float64
+}
+
+// invariant is a single count compared between governor and okta
+type invariant struct {
+	name      string
+	governor  int
+	okta      int
+	tolerance float64
+}
+
+// divergence returns how far apart the counts are as a fraction of the larger count
+func (i invariant) divergence() float64 {
+	larger := math.Max(float64(i.governor), float64(i.okta))
+	if larger == 0 {
+		return 0
+	}
+
+	return math.Abs(float64(i.governor-i.okta)) / larger
+}
+
+// exceeded returns true if the divergence is beyond the tolerance
+func (i invariant) exceeded() bool {
+	return i.divergence() > i.tolerance
+}
+
+// checkInvariants compares the active users, managed groups and total memberships in governor and okta at the
+// end of a reconciler loop.  A divergence beyond the tolerance points to a systemic sync failure, even when the
+// individual operations report success, so it's logged, counted and written as an audit event.
+func (r *Reconciler) checkInvariants(ctx context.Context, groups []*v1alpha1.Group, govUsers []*v1beta1.User, oktaUsers []*okt.User) {
+	oktaGroupCounts, err := r.oktaClient.GovernorGroupsUsersCount(ctx)
+	if err != nil {
+		r.logger.Error("error getting okta group counts for invariants check", zap.Error(err))
+		return
+	}
+
+	invariants := invariantCounts(groups, govUsers, oktaUsers, oktaGroupCounts, *r.invariants)
+	drift := make([]DriftStatus, 0, len(invariants))
+
+	for _, inv := range invariants {
+		invariantDivergenceGauge.WithLabelValues(inv.name).Set(inv.divergence())
+
+		drift = append(drift, DriftStatus{
+			Name:       inv.name,
+			Governor:   inv.governor,
+			Okta:       inv.okta,
+			Divergence: inv.divergence(),
+			Tolerance:  inv.tolerance,
+			Exceeded:   inv.exceeded(),
+		})
+
+		logger := r.logger.With(
+			zap.String("invariant", inv.name),
+			zap.Int("governor.count", inv.governor),
+			zap.Int("okta.count", inv.okta),
+			zap.Float64("divergence", inv.divergence()),
+			zap.Float64("tolerance", inv.tolerance),
+		)
+
+		if !inv.exceeded() {
+			logger.Debug("invariant within tolerance")
+			continue
+		}
+
+		incCounter(ctx, invariantViolationsCounter.WithLabelValues(inv.name))
+
+		logger.Warn("governor and okta counts diverge beyond tolerance")
+
+		if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, auctx.InvariantViolation{
+			Invariant:     inv.name,
+			GovernorCount: strconv.Itoa(inv.governor),
+			OktaCount:     strconv.Itoa(inv.okta),
+			Divergence:    fmt.Sprintf("%.4f", inv.divergence()),
+			Tolerance:     fmt.Sprintf("%.4f", inv.tolerance),
+		}); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
+		}
+	}
+
+	r.status.setDrift(drift)
+}
+
+// invariantCounts collects the governor and okta counts for each of the invariants
+func invariantCounts(groups []*v1alpha1.Group, govUsers []*v1beta1.User, oktaUsers []*okt.User, oktaGroupCounts map[string]int, tol InvariantTolerances) []invariant {
+	users := invariant{name: invariantUsers, tolerance: tol.Users}
+
+	for _, u := range govUsers {
+		if u.Status.String == v1alpha1.UserStatusActive && !u.DeletedAt.Valid {
+			users.governor++
+		}
+	}
+
+	for _, u := range oktaUsers {
+		if u.Status == "ACTIVE" {
+			users.okta++
+		}
+	}
+
+	grps := invariant{name: invariantGroups, tolerance: tol.Groups, governor: len(groups), okta: len(oktaGroupCounts)}
+	memberships := invariant{name: invariantMemberships, tolerance: tol.Memberships}
+
+	for _, g := range groups {
+		memberships.governor += len(g.Members)
+	}
+
+	for _, c := range oktaGroupCounts {
+		memberships.okta += c
+	}
+
+	return []invariant{users, grps, memberships}
+}
@@ -0,0 +1,87 @@
+package reconciler
+
+import (
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_invariant(t *testing.T) {
+	tests := []struct {
+		name         string
+		inv          invariant
+		wantDiv      float64
+		wantExceeded bool
+	}{
+		{
+			name: "equal counts",
+			inv:  invariant{governor: 100, okta: 100, tolerance: 0.05},
+		},
+		{
+			name: "both empty",
+			inv:  invariant{tolerance: 0.05},
+		},
+		{
+			name:    "within tolerance",
+			inv:     invariant{governor: 100, okta: 96, tolerance: 0.05},
+			wantDiv: 0.04,
+		},
+		{
+			name:         "beyond tolerance",
+			inv:          invariant{governor: 80, okta: 100, tolerance: 0.05},
+			wantDiv:      0.2,
+			wantExceeded: true,
+		},
+		{
+			name:         "okta empty",
+			inv:          invariant{governor: 10, tolerance: 0.05},
+			wantDiv:      1,
+			wantExceeded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.wantDiv, tt.inv.divergence(), 0.0001)
+			assert.Equal(t, tt.wantExceeded, tt.inv.exceeded())
+		})
+	}
+}
+
+func Test_invariantCounts(t *testing.T) {
+	deleted := testGovUser(t, "user-3", "three@example.com")
+	deleted.DeletedAt.Valid = true
+
+	govUsers := []*v1beta1.User{
+		testGovUser(t, "user-1", "one@example.com"),
+		testGovUser(t, "user-2", "two@example.com"),
+		deleted,
+	}
+
+	for _, u := range govUsers {
+		u.Status.SetValid(v1alpha1.UserStatusActive)
+	}
+
+	oktaUsers := []*okt.User{
+		{Id: "okta-1", Status: "ACTIVE"},
+		{Id: "okta-2", Status: "SUSPENDED"},
+	}
+
+	groups := []*v1alpha1.Group{
+		testGovGroup(t, "group-1", []string{"user-1", "user-2"}, nil),
+		testGovGroup(t, "group-2", []string{"user-2"}, nil),
+	}
+
+	tol := InvariantTolerances{Users: 0.1, Groups: 0.2, Memberships: 0.3}
+
+	got := invariantCounts(groups, govUsers, oktaUsers, map[string]int{"group-1": 2}, tol)
+
+	assert.Equal(t, []invariant{
+		{name: invariantUsers, governor: 2, okta: 1, tolerance: 0.1},
+		{name: invariantGroups, governor: 2, okta: 1, tolerance: 0.2},
+		{name: invariantMemberships, governor: 3, okta: 2, tolerance: 0.3},
+	}, got)
+}
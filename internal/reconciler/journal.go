@@ -0,0 +1,48 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/journal"
+)
+
+// writeMutationEvent writes the audit event for an applied okta mutation and records it in the change
+// journal when one is configured. The before and after states are hashed, nil means the resource didn't
+// exist before or doesn't exist after the mutation. The mutation is also offered to the sample of changes
+// verified after the reconciler loop, counted for its pilot cohort, published as a change event and posted to
+// the notification webhook. Nothing is written in what-if mode since the mutation was only recorded.
+func (r *Reconciler) writeMutationEvent(ctx context.Context, p auctx.Payload, before, after interface{}) error {
+	if r.whatIf() {
+		return nil
+	}
+
+	target := auctx.Target(p)
+
+	r.sampleAppliedChange(p.EventType(), target)
+	r.pilot.changeApplied(target)
+	r.changes.Publish(ctx, changes.SystemOkta, p.EventType(), target)
+	r.notifier.Notify(ctx, p.EventType(), target)
+
+	auErr := auctx.WriteAuditEvent(ctx, r.auditEventWriter, p)
+
+	if r.journal == nil {
+		return auErr
+	}
+
+	entry := &journal.Entry{
+		Type:       p.EventType(),
+		Resources:  target,
+		BeforeHash: journal.Hash(before),
+		AfterHash:  journal.Hash(after),
+	}
+
+	if ae := auctx.GetAuditEvent(ctx); ae != nil {
+		entry.RunID = ae.Metadata.AuditID
+		entry.Source = ae.Source.Type + ":" + ae.Source.Value
+	}
+
+	return errors.Join(auErr, r.journal.Record(ctx, entry))
+}
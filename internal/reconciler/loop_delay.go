@@ -0,0 +1,56 @@
+package reconciler
+
+import (
+	"math/rand"
+	"time"
+)
+
+// WithInitialDelay delays the reconciler loops after the reconciler starts, so the replicas started by a deploy
+// can be staggered
+func WithInitialDelay(d time.Duration) Option {
+	return func(r *Reconciler) {
+		r.initialDelay = max(d, 0)
+	}
+}
+
+// WithLoopJitter adds a random delay of up to the jitter to the interval of each reconciler loop, so replicas
+// started at the same time drift apart
+func WithLoopJitter(d time.Duration) Option {
+	return func(r *Reconciler) {
+		r.loopJitter = max(d, 0)
+	}
+}
+
+// WithRunOnStart runs a reconciler loop when the reconciler starts (after the initial delay) instead of waiting
+// for the first interval
+func WithRunOnStart(b bool) Option {
+	return func(r *Reconciler) {
+		r.runOnStart = b
+	}
+}
+
+// firstLoopDelay returns how long after the reconciler starts the first loop runs
+func (r *Reconciler) firstLoopDelay(int63n func(int64) int64) time.Duration {
+	if r.runOnStart {
+		return r.initialDelay + r.jitter(int63n)
+	}
+
+	return r.initialDelay + r.nextLoopDelay(int63n)
+}
+
+// nextLoopDelay returns how long after a loop the next one runs
+func (r *Reconciler) nextLoopDelay(int63n func(int64) int64) time.Duration {
+	return r.reconcilerInterval + r.jitter(int63n)
+}
+
+// jitter returns a random delay of up to the loop jitter
+func (r *Reconciler) jitter(int63n func(int64) int64) time.Duration {
+	if r.loopJitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(int63n(int64(r.loopJitter)))
+}
+
+// loopRand is the random source of the loop jitter
+var loopRand = rand.Int63n
@@ -0,0 +1,57 @@
+package reconciler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconciler_loopDelay(t *testing.T) {
+	// the random source returns the largest delay
+	maxRand := func(n int64) int64 { return n - 1 }
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantFirst time.Duration
+		wantNext  time.Duration
+	}{
+		{
+			name:      "defaults",
+			wantFirst: time.Hour,
+			wantNext:  time.Hour,
+		},
+		{
+			name:      "initial delay and jitter",
+			opts:      []Option{WithInitialDelay(time.Minute), WithLoopJitter(time.Second)},
+			wantFirst: time.Hour + time.Minute + time.Second - 1,
+			wantNext:  time.Hour + time.Second - 1,
+		},
+		{
+			name:      "run on start",
+			opts:      []Option{WithInitialDelay(time.Minute), WithLoopJitter(time.Second), WithRunOnStart(true)},
+			wantFirst: time.Minute + time.Second - 1,
+			wantNext:  time.Hour + time.Second - 1,
+		},
+		{
+			name:      "negative durations",
+			opts:      []Option{WithInitialDelay(-time.Minute), WithLoopJitter(-time.Second)},
+			wantFirst: time.Hour,
+			wantNext:  time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reconciler{reconcilerInterval: DefaultReconcileInterval}
+
+			for _, opt := range tt.opts {
+				opt(r)
+			}
+
+			assert.Equal(t, tt.wantFirst, r.firstLoopDelay(maxRand))
+			assert.Equal(t, tt.wantNext, r.nextLoopDelay(maxRand))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"go.uber.org/zap"
+)
+
+// groupMemberRequests returns the pending membership requests of a governor group by governor user id, when
+// membership requests are enabled and some okta group members aren't members of the governor group.  Requests
+// are still filed when they can't be listed, governor rejects the duplicates.
+func (r *Reconciler) groupMemberRequests(ctx context.Context, logger *zap.Logger, gid string, diff *MembershipDiff) map[string]*v1alpha1.GroupMemberRequest {
+	if !r.memberRequests || len(diff.OnlyOkta) == 0 {
+		return nil
+	}
+
+	requests, err := callOp(ctx, r, "governor.GroupMemberRequests", func(ctx context.Context) (map[string]*v1alpha1.GroupMemberRequest, error) {
+		return r.governorClient.GroupMemberRequestsByUser(ctx, gid)
+	})
+	if err != nil {
+		logger.Warn("error listing governor group membership requests", zap.Error(err))
+		return nil
+	}
+
+	return requests
+}
+
+// requestGroupMember files a governor membership request for an okta group member that isn't a member of the
+// governor group.  It returns false when the member has no governor user and can't request to join.
+func (r *Reconciler) requestGroupMember(
+	ctx context.Context,
+	logger *zap.Logger,
+	group *v1alpha1.Group,
+	oktaGID string,
+	member MembershipDiffMember,
+	requests map[string]*v1alpha1.GroupMemberRequest,
+) bool {
+	logger = logger.With(zap.String("okta.user.id", member.OktaUserID))
+
+	users, err := callOp(ctx, r, "governor.UsersV2", func(ctx context.Context) ([]*v1beta1.User, error) {
+		return r.governorClient.UsersV2(ctx, map[string][]string{"external_id": {member.OktaUserID}})
+	})
+	if err != nil {
+		// keep the member until they can be looked up
+		logger.Error("error getting governor user of okta group member", zap.Error(err))
+		return true
+	}
+
+	if len(users) == 0 {
+		logger.Info("okta group member has no governor user to request membership for")
+		return false
+	}
+
+	user := users[0]
+	logger = logger.With(zap.String("governor.user.id", user.ID), zap.String("governor.user.email", user.Email))
+
+	if _, ok := requests[user.ID]; ok {
+		logger.Debug("governor group membership already requested")
+		return true
+	}
+
+	if err := r.doOp(ctx, "governor.CreateGroupMemberRequest", func(ctx context.Context) error {
+		return r.governorClient.CreateGroupMemberRequest(ctx, group.ID, &govclient.MemberRequest{
+			UserID: user.ID,
+			Note:   fmt.Sprintf("gov-okta-addon: member of the okta group of %s", group.Slug),
+		})
+	}); err != nil {
+		if errors.Is(err, govclient.ErrMemberRequestExists) {
+			logger.Debug("governor group membership already requested")
+			return true
+		}
+
+		logger.Error("error requesting governor group membership", zap.Error(err))
+
+		return true
+	}
+
+	logger.Info("requested governor group membership for okta group member")
+
+	incCounter(ctx, groupMemberRequestsCounter)
+
+	event := auctx.GovernorGroupMemberRequest{
+		GovernorGroupSlug: group.Slug,
+		GovernorGroupID:   group.ID,
+		GovernorUserEmail: user.Email,
+		GovernorUserID:    user.ID,
+		OktaGroupID:       oktaGID,
+		OktaUserID:        member.OktaUserID,
+	}
+
+	r.changes.Publish(ctx, changes.SystemGovernor, event.EventType(), auctx.Target(event))
+
+	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, event); err != nil {
+		logger.Error("error writing audit event", zap.Error(err))
+	}
+
+	return true
+}
@@ -0,0 +1,75 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestReconciler_requestGroupMember(t *testing.T) {
+	group := testGovGroup(t, "platform", nil, nil)
+	users := []*v1beta1.User{testGovUser(t, "user-1", "one@example.com")}
+
+	tests := []struct {
+		name         string
+		client       *mockGovClient
+		oktaUserID   string
+		requests     map[string]*v1alpha1.GroupMemberRequest
+		want         bool
+		wantRequests []*govclient.MemberRequest
+	}{
+		{
+			name:         "requested",
+			client:       &mockGovClient{users: users},
+			oktaUserID:   "okta-user-1",
+			want:         true,
+			wantRequests: []*govclient.MemberRequest{{UserID: "user-1", Note: "gov-okta-addon: member of the okta group of platform"}},
+		},
+		{
+			name:       "pending request",
+			client:     &mockGovClient{users: users},
+			oktaUserID: "okta-user-1",
+			requests:   map[string]*v1alpha1.GroupMemberRequest{"user-1": {ID: "req-1"}},
+			want:       true,
+		},
+		{
+			name:       "already requested",
+			client:     &mockGovClient{users: users, requestErr: fmt.Errorf("%w: user-1", govclient.ErrMemberRequestExists)},
+			oktaUserID: "okta-user-1",
+			want:       true,
+		},
+		{
+			name:       "no governor user",
+			client:     &mockGovClient{users: users},
+			oktaUserID: "okta-user-2",
+			want:       false,
+		},
+		{
+			name:       "governor error",
+			client:     &mockGovClient{err: errors.New("boom")}, //nolint:goerr113
+			oktaUserID: "okta-user-1",
+			want:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reconciler{
+				governorClient: tt.client,
+				logger:         zap.NewNop(),
+				memberRequests: true,
+			}
+
+			got := r.requestGroupMember(context.TODO(), r.logger, group, "okta-group-1", MembershipDiffMember{OktaUserID: tt.oktaUserID}, tt.requests)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantRequests, tt.client.memberRequests)
+		})
+	}
+}
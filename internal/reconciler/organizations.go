@@ -0,0 +1,28 @@
+package reconciler
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// OrganizationApplicationAssignments reconciles the application assignments of the governor groups after an
+// organization is created, updated or deleted in governor. Organization events don't include the groups that
+// are affected (and deleting an organization unlinks its groups), so all of the groups are re-evaluated.
+func (r *Reconciler) OrganizationApplicationAssignments(ctx context.Context) (AppAssignmentResults, error) {
+	groups, err := r.governorClient.Groups(ctx)
+	if err != nil {
+		r.logger.Error("error listing governor groups", zap.Error(err))
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(groups))
+
+	for _, g := range groups {
+		ids = append(ids, g.ID)
+	}
+
+	r.logger.Debug("reconciling application assignments for organization change", zap.Int("num.governor.groups", len(ids)))
+
+	return r.GroupsApplicationAssignments(ctx, ids...)
+}
@@ -0,0 +1,92 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"go.uber.org/zap"
+)
+
+// clearOrphanGroups clears the governor id from the okta groups of governor groups that don't exist anymore, so they
+// don't get in the way of later lookups.  The okta groups pending deletion keep their governor id until they're
+// deleted.  Nothing is listed when orphan cleanup is disabled, and nothing is cleared when there are no governor
+// groups since an empty list is more likely a governor problem than every group being deleted.  Each candidate is
+// checked again in governor since groups created after the governor list was taken aren't orphans.
+func (r *Reconciler) clearOrphanGroups(ctx context.Context, govGroups []*v1alpha1.Group) error {
+	if !r.orphanCleanup || len(govGroups) == 0 {
+		return nil
+	}
+
+	oktaGroups, err := callOp(ctx, r, "okta.ListGroupsWithGovernorID", func(ctx context.Context) ([]*okt.Group, error) {
+		return r.oktaClient.ListGroupsWithGovernorID(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]struct{}, len(govGroups))
+	for _, g := range govGroups {
+		existing[g.ID] = struct{}{}
+	}
+
+	for _, og := range oktaGroups {
+		gid, err := okta.GroupGovernorID(og)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := existing[gid]; ok {
+			continue
+		}
+
+		if _, pending := okta.GroupPendingDeleteAt(og); pending {
+			continue
+		}
+
+		logger := r.logger.With(zap.String("governor.group.id", gid), zap.String("okta.group.id", og.Id))
+
+		group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+			return r.governorClient.Group(ctx, gid, true)
+		})
+
+		switch {
+		case errors.Is(err, governor.ErrGroupNotFound):
+		case err != nil:
+			logger.Warn("error checking orphaned okta group in governor", zap.Error(err))
+			continue
+		case !groupDeleted(group):
+			logger.Debug("okta group belongs to a governor group created after the governor group list")
+			continue
+		}
+
+		if r.dryRun() || r.detectOnlyGroup(ctx, gid, nil) {
+			logger.Info("SKIP clearing the governor id of orphaned okta group")
+			continue
+		}
+
+		if err := r.doOp(ctx, "okta.ClearGroupGovernorID", func(ctx context.Context) error {
+			return r.oktaClient.ClearGroupGovernorID(ctx, og.Id)
+		}); err != nil {
+			logger.Error("error clearing the governor id of orphaned okta group", zap.Error(err))
+			return err
+		}
+
+		incCounter(ctx, groupsOrphanClearedCounter)
+
+		logger.Info("cleared the governor id of orphaned okta group")
+
+		if err := r.writeMutationEvent(ctx, auctx.GroupGovernorIDClear{
+			GovernorGroupID: gid,
+			OktaGroupID:     og.Id,
+		}, map[string]string{"okta.group.id": og.Id, okta.GroupProfileGovernorIDKey: gid}, nil); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciler_clearOrphanGroups(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		govGroups   bool
+		wantCleared bool
+	}{
+		{name: "cleanup", opts: []Option{WithOrphanCleanup(true)}, govGroups: true, wantCleared: true},
+		{name: "disabled", govGroups: true},
+		{name: "dry run", opts: []Option{WithOrphanCleanup(true), WithDryRun(true)}, govGroups: true},
+		{name: "no governor groups", opts: []Option{WithOrphanCleanup(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"})
+			o.AddGroup("00g-orphan", "Orphan", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-2", "team": "storage"})
+			o.AddGroup("00g-pending", "Pending", map[string]interface{}{
+				okta.GroupProfileGovernorIDKey:    "group-3",
+				okta.GroupProfilePendingDeleteKey: "2024-01-01T00:00:00Z",
+			})
+
+			o.AddGroup("00g-deleted", "Deleted", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-4"})
+			o.AddGroup("00g-new", "New", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-5"})
+
+			deletedAt := time.Now()
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-4", Name: "Deleted", Slug: "deleted", DeletedAt: &deletedAt})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-5", Name: "New", Slug: "new"})
+
+			r, audit := newTestServerReconciler(t, o, g, tt.opts...)
+
+			govGroups := []*v1alpha1.Group{}
+			if tt.govGroups {
+				govGroups = append(govGroups, testGovGroup(t, "group-1", nil, nil))
+			}
+
+			ctx := r.withReconcileAuditEvent(context.TODO(), "test")
+
+			require.NoError(t, r.clearOrphanGroups(ctx, govGroups))
+
+			gid, err := okta.GroupGovernorID(o.Group("00g-orphan"))
+			if tt.wantCleared {
+				assert.Error(t, err)
+				_, err = okta.GroupGovernorID(o.Group("00g-deleted"))
+				assert.Error(t, err)
+				assert.Equal(t, "storage", o.Group("00g-orphan").Profile.GroupProfileMap["team"])
+				assert.Contains(t, audit.String(), "GroupGovernorIDClear")
+			} else {
+				assert.Equal(t, "group-2", gid)
+				assert.NotContains(t, audit.String(), "GroupGovernorIDClear")
+			}
+
+			// groups of existing governor groups, including groups created after the governor list was taken, and
+			// groups pending deletion keep their governor id
+			gid, err = okta.GroupGovernorID(o.Group("00g-platform"))
+			require.NoError(t, err)
+			assert.Equal(t, "group-1", gid)
+
+			gid, err = okta.GroupGovernorID(o.Group("00g-new"))
+			require.NoError(t, err)
+			assert.Equal(t, "group-5", gid)
+
+			gid, err = okta.GroupGovernorID(o.Group("00g-pending"))
+			require.NoError(t, err)
+			assert.Equal(t, "group-3", gid)
+		})
+	}
+}
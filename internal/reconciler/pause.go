@@ -0,0 +1,93 @@
+package reconciler
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pauseState tracks whether an operator paused the reconciler mutations.  The generation is bumped on every
+// pause, so a loop can tell it was paused at some point while it ran even if it was resumed since.
+type pauseState struct {
+	mu sync.RWMutex
+
+	paused     bool
+	since      time.Time
+	reason     string
+	generation uint64
+}
+
+// isPaused returns true if the mutations are paused
+func (p *pauseState) isPaused() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.paused
+}
+
+// current returns the current pause generation
+func (p *pauseState) current() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.generation
+}
+
+// unpausedSince returns true if the mutations weren't paused at any point since the generation
+func (p *pauseState) unpausedSince(generation uint64) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return !p.paused && p.generation == generation
+}
+
+// Pause halts the okta and governor mutations of the reconciler loop, the event log poller and the NATS events
+// like dry-run does, until Resume is called.  It returns false if the reconciler was already paused.
+func (r *Reconciler) Pause(reason string) bool {
+	r.pause.mu.Lock()
+	defer r.pause.mu.Unlock()
+
+	if r.pause.paused {
+		return false
+	}
+
+	r.pause.paused = true
+	r.pause.since = r.now().UTC()
+	r.pause.reason = reason
+	r.pause.generation++
+
+	reconcilerPausedGauge.Set(1)
+
+	r.logger.Warn("reconciler paused, mutations are skipped until it's resumed", zap.String("pause.reason", reason))
+
+	return true
+}
+
+// Resume resumes the mutations halted by Pause, it returns false if the reconciler wasn't paused
+func (r *Reconciler) Resume() bool {
+	r.pause.mu.Lock()
+	defer r.pause.mu.Unlock()
+
+	if !r.pause.paused {
+		return false
+	}
+
+	r.logger.Warn("reconciler resumed",
+		zap.Duration("pause.duration", time.Since(r.pause.since)),
+		zap.String("pause.reason", r.pause.reason),
+	)
+
+	r.pause.paused = false
+	r.pause.since = time.Time{}
+	r.pause.reason = ""
+
+	reconcilerPausedGauge.Set(0)
+
+	return true
+}
+
+// dryRun returns true if mutations are skipped, either because of dry-run or because the reconciler is paused
+func (r *Reconciler) dryRun() bool {
+	return r.dryrun || r.pause.isPaused()
+}
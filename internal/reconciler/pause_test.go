@@ -0,0 +1,67 @@
+package reconciler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciler_Pause(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1"}})
+
+	o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+	o.AddUser("okta-stray", "ACTIVE", testOktaProfile("stray@example.com"))
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-stray")
+
+	r, _ := newTestServerReconciler(t, o, g, WithSnapshotShortCircuit(true))
+
+	assert.True(t, r.Pause("incident 42"))
+	assert.False(t, r.Pause("again"), "pausing twice is a no-op")
+	assert.True(t, r.dryRun())
+	assert.Equal(t, float64(1), testutil.ToFloat64(reconcilerPausedGauge))
+
+	st := r.Status()
+	assert.True(t, st.Paused)
+	require.NotNil(t, st.PausedSince)
+	assert.Equal(t, "incident 42", st.PauseReason)
+	assert.False(t, st.DryRun, "the configured dry-run is reported separately")
+
+	r.reconcileLoop(context.TODO())
+
+	// nothing changes in okta while paused
+	assert.Equal(t, []string{"okta-stray"}, o.GroupMembers("00g-platform"))
+
+	for _, req := range o.Requests() {
+		assert.Equal(t, http.MethodGet, req.Method, "unexpected okta request %s %s", req.Method, req.Path)
+	}
+
+	assert.True(t, r.Resume())
+	assert.False(t, r.Resume(), "resuming twice is a no-op")
+	assert.False(t, r.dryRun())
+	assert.Equal(t, float64(0), testutil.ToFloat64(reconcilerPausedGauge))
+
+	st = r.Status()
+	assert.False(t, st.Paused)
+	assert.Nil(t, st.PausedSince)
+	assert.Empty(t, st.PauseReason)
+
+	// the loop that ran while paused didn't record its snapshot, so this one isn't short-circuited
+	r.reconcileLoop(context.TODO())
+
+	assert.Equal(t, []string{"okta-1"}, o.GroupMembers("00g-platform"))
+	assert.Equal(t, RunResultSucceeded, r.Status().Runs[0].Result)
+}
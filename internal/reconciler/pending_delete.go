@@ -0,0 +1,141 @@
+package reconciler
+
+import (
+	"context"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"go.uber.org/zap"
+)
+
+// groupDeletePending flags the okta group of a deleted governor group for deletion after the grace period
+func (r *Reconciler) groupDeletePending(ctx context.Context, gid, oktaGID string) error {
+	now := r.now()
+
+	logger := r.logger.With(zap.String("governor.group.id", gid), zap.String("okta.group.id", oktaGID))
+
+	if err := r.doOp(ctx, "okta.SetGroupPendingDelete", func(ctx context.Context) error {
+		return r.oktaClient.SetGroupPendingDelete(ctx, oktaGID, now)
+	}); err != nil {
+		logger.Error("error flagging okta group for deletion", zap.Error(err))
+		return err
+	}
+
+	logger.Info("flagged okta group for deletion", zap.Time("okta.group.delete_after", now.Add(r.groupDeleteGrace)))
+
+	if err := r.writeMutationEvent(ctx, auctx.GroupDeletePending{
+		GovernorGroupID: gid,
+		OktaGroupID:     oktaGID,
+		PendingDeleteAt: now.UTC().Format(time.RFC3339),
+	}, nil, map[string]string{"okta.group.id": oktaGID, okta.GroupProfilePendingDeleteKey: now.UTC().Format(time.RFC3339)}); err != nil {
+		logger.Error("error writing audit event", zap.Error(err))
+	}
+
+	return nil
+}
+
+// deletePendingGroups deletes the okta groups flagged for deletion once the delete grace period is over.  The
+// flag is cleared from okta groups of governor groups that exist again.  Nothing is listed when there's no
+// grace period.
+func (r *Reconciler) deletePendingGroups(ctx context.Context, govGroups []*v1alpha1.Group) error {
+	if r.groupDeleteGrace <= 0 {
+		return nil
+	}
+
+	pending, err := callOp(ctx, r, "okta.ListGroupsPendingDelete", func(ctx context.Context) ([]*okt.Group, error) {
+		return r.oktaClient.ListGroupsPendingDelete(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]*v1alpha1.Group, len(govGroups))
+	for _, g := range govGroups {
+		existing[g.ID] = g
+	}
+
+	now := r.now()
+	waiting := 0
+
+	for _, og := range pending {
+		at, ok := okta.GroupPendingDeleteAt(og)
+		if !ok {
+			continue
+		}
+
+		gid, _ := okta.GroupGovernorID(og)
+
+		logger := r.logger.With(zap.String("governor.group.id", gid), zap.String("okta.group.id", og.Id))
+
+		if group, ok := existing[gid]; ok {
+			r.cancelGroupDelete(ctx, logger, group, og.Id)
+			continue
+		}
+
+		if now.Before(at.Add(r.groupDeleteGrace)) {
+			waiting++
+
+			logger.Debug("okta group pending deletion", zap.Time("okta.group.delete_after", at.Add(r.groupDeleteGrace)))
+
+			continue
+		}
+
+		if r.dryRun() || r.skipDelete || r.detectOnlyGroup(ctx, gid, nil) {
+			logger.Info("SKIP deleting okta group after the delete grace period")
+
+			r.status.pendingDeletion(PendingDeletion{Type: "GroupDelete", GovernorGroupID: gid, OktaGroupID: og.Id})
+
+			continue
+		}
+
+		if err := r.doOp(ctx, "okta.DeleteGroup", func(ctx context.Context) error {
+			return r.oktaClient.DeleteGroup(ctx, og.Id)
+		}); err != nil {
+			logger.Error("error deleting okta group after the delete grace period", zap.Error(err))
+			return err
+		}
+
+		incCounter(ctx, groupsDeletedCounter)
+
+		logger.Info("deleted okta group after the delete grace period", zap.Time("okta.group.pending_delete_at", at))
+
+		if err := r.writeMutationEvent(ctx, auctx.GroupDelete{
+			GovernorGroupID: gid,
+			OktaGroupID:     og.Id,
+		}, map[string]string{"okta.group.id": og.Id}, nil); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
+		}
+	}
+
+	groupsPendingDeleteGauge.Set(float64(waiting))
+
+	return nil
+}
+
+// cancelGroupDelete clears the deletion flag of the okta group of a governor group that exists again
+func (r *Reconciler) cancelGroupDelete(ctx context.Context, logger *zap.Logger, group *v1alpha1.Group, oktaGID string) {
+	if r.dryRun() || r.detectOnlyGroup(ctx, group.ID, group) {
+		logger.Info("SKIP clearing the deletion flag of okta group, the governor group exists")
+		return
+	}
+
+	if err := r.doOp(ctx, "okta.SetGroupPendingDelete", func(ctx context.Context) error {
+		return r.oktaClient.SetGroupPendingDelete(ctx, oktaGID, time.Time{})
+	}); err != nil {
+		logger.Error("error clearing the deletion flag of okta group", zap.Error(err))
+		return
+	}
+
+	logger.Info("cleared the deletion flag of okta group, the governor group exists")
+
+	if err := r.writeMutationEvent(ctx, auctx.GroupDeleteCancel{
+		GovernorGroupSlug: group.Slug,
+		GovernorGroupID:   group.ID,
+		OktaGroupID:       oktaGID,
+	}, nil, map[string]string{"okta.group.id": oktaGID}); err != nil {
+		logger.Error("error writing audit event", zap.Error(err))
+	}
+}
@@ -0,0 +1,377 @@
+package reconciler
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// pilotCohortNoteKey is the key in a governor group note that adds the group to a pilot cohort, ie. a line in
+// the note like "gov-okta-addon.cohort: wave-1"
+const pilotCohortNoteKey = "gov-okta-addon.cohort"
+
+var (
+	pilotCohortGroupsGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "pilot_cohort_groups",
+			Help:      "Number of governor groups in each pilot cohort.",
+		},
+		[]string{"cohort"},
+	)
+
+	pilotCohortUsersGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "pilot_cohort_users",
+			Help:      "Number of governor users in each pilot cohort.",
+		},
+		[]string{"cohort"},
+	)
+
+	pilotCohortChangesGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "pilot_cohort_changes",
+			Help:      "Number of okta changes applied to each pilot cohort in the last reconciler loop.",
+		},
+		[]string{"cohort"},
+	)
+
+	pilotCohortConvergedGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "pilot_cohort_converged",
+			Help:      "Whether the last reconciler loop applied no okta changes to each pilot cohort (1) or not (0).",
+		},
+		[]string{"cohort"},
+	)
+
+	pilotDetectOnlyChangesCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "pilot_detect_only_changes_total",
+			Help:      "Total count of okta changes detected outside of the pilot cohorts and not applied.",
+		},
+	)
+
+	pilotDetectOnlyChangesGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "pilot_detect_only_changes",
+			Help:      "Number of okta changes detected outside of the pilot cohorts and not applied in the last reconciler loop.",
+		},
+	)
+)
+
+// Cohort is a named set of governor groups and users that the addon fully manages during a pilot rollout,
+// groups are given by slug or id and users by email or id.  The members of the cohort groups are in the
+// cohort too.
+type Cohort struct {
+	Name   string
+	Groups []string
+	Users  []string
+}
+
+// PilotStatus is the state of the pilot cohorts as of the last reconciler loop
+type PilotStatus struct {
+	Cohorts           []CohortStatus `json:"cohorts"`
+	DetectOnlyChanges int            `json:"detect_only_changes"`
+}
+
+// CohortStatus is the membership and convergence of a pilot cohort
+type CohortStatus struct {
+	Name      string   `json:"name"`
+	Groups    []string `json:"groups"`
+	Users     []string `json:"users"`
+	Changes   int      `json:"changes"`
+	Converged bool     `json:"converged"`
+}
+
+// pilot restricts the okta changes to the governor groups and users of the pilot cohorts, changes to
+// everything else are only detected.  The cohort groups and users are resolved from the governor groups and
+// users in each reconciler loop, groups and users created since are matched by their slug, id, email or note.
+type pilot struct {
+	cohorts []Cohort
+
+	mu sync.Mutex
+	// governor group and user ids to their cohort name, from the last resolve
+	groups map[string]string
+	users  map[string]string
+
+	// changes applied per cohort and detected outside of the cohorts in the running loop, and the last loop
+	applied      map[string]int
+	detected     int
+	lastApplied  map[string]int
+	lastDetected int
+}
+
+// newPilot returns a pilot for the cohorts
+func newPilot(cohorts []Cohort) *pilot {
+	return &pilot{
+		cohorts:     cohorts,
+		groups:      map[string]string{},
+		users:       map[string]string{},
+		applied:     map[string]int{},
+		lastApplied: map[string]int{},
+	}
+}
+
+// resolve refreshes the cohort groups and users from the governor groups (with their members) and users
+func (p *pilot) resolve(groups []*v1alpha1.Group, users []*v1beta1.User) {
+	if p == nil {
+		return
+	}
+
+	groupCohorts := map[string]string{}
+	userCohorts := map[string]string{}
+
+	for _, g := range groups {
+		name, ok := p.staticGroupCohort(g.ID, g)
+		if !ok {
+			continue
+		}
+
+		groupCohorts[g.ID] = name
+
+		for _, uid := range g.Members {
+			if _, ok := userCohorts[uid]; !ok {
+				userCohorts[uid] = name
+			}
+		}
+	}
+
+	for _, u := range users {
+		if name, ok := p.staticUserCohort(u.ID, u.Email); ok {
+			userCohorts[u.ID] = name
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.groups = groupCohorts
+	p.users = userCohorts
+}
+
+// staticGroupCohort returns the cohort of a governor group from the configured slugs and ids and the group note
+func (p *pilot) staticGroupCohort(id string, g *v1alpha1.Group) (string, bool) {
+	if g != nil && g.Group != nil {
+		if name, ok := groupNoteValue(g, pilotCohortNoteKey); ok && name != "" {
+			return name, true
+		}
+	}
+
+	for _, c := range p.cohorts {
+		for _, ref := range c.Groups {
+			if ref == id || (g != nil && g.Group != nil && ref == g.Slug) {
+				return c.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// groupCohort returns the cohort of a governor group, the group details are optional
+func (p *pilot) groupCohort(id string, g *v1alpha1.Group) (string, bool) {
+	if name, ok := p.staticGroupCohort(id, g); ok {
+		return name, true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name, ok := p.groups[id]
+
+	return name, ok
+}
+
+// staticUserCohort returns the cohort of a governor user from the configured emails and ids
+func (p *pilot) staticUserCohort(id, email string) (string, bool) {
+	for _, c := range p.cohorts {
+		for _, ref := range c.Users {
+			if ref == id || (email != "" && strings.EqualFold(ref, email)) {
+				return c.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// userCohort returns the cohort of a governor user, from the configured emails and ids or the cohort groups
+// the user is a member of
+func (p *pilot) userCohort(id, email string) (string, bool) {
+	if name, ok := p.staticUserCohort(id, email); ok {
+		return name, true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name, ok := p.users[id]
+
+	return name, ok
+}
+
+// changeApplied counts an applied okta change for the cohort of the governor group or user in its target
+func (p *pilot) changeApplied(target map[string]string) {
+	if p == nil {
+		return
+	}
+
+	name, ok := p.groupCohort(target["governor.group.id"], nil)
+	if !ok {
+		name, ok = p.userCohort(target["governor.user.id"], target["governor.user.email"])
+	}
+
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.applied[name]++
+}
+
+// changeDetected counts an okta change that was detected outside of the cohorts and not applied
+func (p *pilot) changeDetected() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.detected++
+}
+
+// finishLoop publishes the cohort metrics of a reconciler loop and starts counting the next one
+func (p *pilot) finishLoop() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastApplied, p.applied = p.applied, map[string]int{}
+	p.lastDetected, p.detected = p.detected, 0
+
+	for _, st := range p.statusLocked().Cohorts {
+		pilotCohortGroupsGauge.WithLabelValues(st.Name).Set(float64(len(st.Groups)))
+		pilotCohortUsersGauge.WithLabelValues(st.Name).Set(float64(len(st.Users)))
+		pilotCohortChangesGauge.WithLabelValues(st.Name).Set(float64(st.Changes))
+
+		converged := 0.0
+		if st.Converged {
+			converged = 1
+		}
+
+		pilotCohortConvergedGauge.WithLabelValues(st.Name).Set(converged)
+	}
+
+	pilotDetectOnlyChangesGauge.Set(float64(p.lastDetected))
+}
+
+// status returns the cohort membership and convergence as of the last reconciler loop
+func (p *pilot) status() *PilotStatus {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.statusLocked()
+}
+
+func (p *pilot) statusLocked() *PilotStatus {
+	byName := map[string]*CohortStatus{}
+
+	cohort := func(name string) *CohortStatus {
+		if _, ok := byName[name]; !ok {
+			byName[name] = &CohortStatus{Name: name, Groups: []string{}, Users: []string{}}
+		}
+
+		return byName[name]
+	}
+
+	// configured cohorts are listed even when none of their groups and users were found
+	for _, c := range p.cohorts {
+		cohort(c.Name)
+	}
+
+	for gid, name := range p.groups {
+		c := cohort(name)
+		c.Groups = append(c.Groups, gid)
+	}
+
+	for uid, name := range p.users {
+		c := cohort(name)
+		c.Users = append(c.Users, uid)
+	}
+
+	st := &PilotStatus{Cohorts: []CohortStatus{}, DetectOnlyChanges: p.lastDetected}
+
+	for name, c := range byName {
+		sort.Strings(c.Groups)
+		sort.Strings(c.Users)
+
+		c.Changes = p.lastApplied[name]
+		c.Converged = c.Changes == 0
+
+		st.Cohorts = append(st.Cohorts, *c)
+	}
+
+	slices.SortFunc(st.Cohorts, func(a, b CohortStatus) int { return strings.Compare(a.Name, b.Name) })
+
+	return st
+}
+
+// detectOnlyGroup returns true when the okta changes for a governor group (including its memberships, owners
+// and application assignments) must only be detected because the group is outside of the pilot cohorts.  The
+// group details are optional.
+func (r *Reconciler) detectOnlyGroup(ctx context.Context, gid string, g *v1alpha1.Group) bool {
+	if r.pilot == nil {
+		return false
+	}
+
+	if _, ok := r.pilot.groupCohort(gid, g); ok {
+		return false
+	}
+
+	r.pilot.changeDetected()
+
+	incCounter(ctx, pilotDetectOnlyChangesCounter)
+
+	r.logger.Info("SKIP okta change for group outside of the pilot cohorts", zap.String("governor.group.id", gid))
+
+	return true
+}
+
+// detectOnlyUser returns true when the okta changes for a governor user must only be detected because the user
+// is outside of the pilot cohorts
+func (r *Reconciler) detectOnlyUser(ctx context.Context, uid, email string) bool {
+	if r.pilot == nil {
+		return false
+	}
+
+	if _, ok := r.pilot.userCohort(uid, email); ok {
+		return false
+	}
+
+	r.pilot.changeDetected()
+
+	incCounter(ctx, pilotDetectOnlyChangesCounter)
+
+	r.logger.Info("SKIP okta change for user outside of the pilot cohorts", zap.String("governor.user.id", uid))
+
+	return true
+}
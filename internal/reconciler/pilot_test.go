@@ -0,0 +1,165 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_pilot_groupCohort(t *testing.T) {
+	p := newPilot([]Cohort{
+		{Name: "wave-1", Groups: []string{"group-1", "id-2"}},
+	})
+
+	tests := []struct {
+		name   string
+		id     string
+		group  *v1alpha1.Group
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "by slug",
+			id:     "id-1",
+			group:  testGovGroup(t, "group-1", nil, nil),
+			want:   "wave-1",
+			wantOK: true,
+		},
+		{
+			name:   "by id without details",
+			id:     "id-2",
+			want:   "wave-1",
+			wantOK: true,
+		},
+		{
+			name:   "by note",
+			id:     "group-3",
+			group:  testGovGroupWithNote(t, "group-3", "gov-okta-addon.cohort: wave-2"),
+			want:   "wave-2",
+			wantOK: true,
+		},
+		{
+			name:  "outside of the cohorts",
+			id:    "group-4",
+			group: testGovGroup(t, "group-4", nil, nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := p.groupCohort(tt.id, tt.group)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_pilot_userCohort(t *testing.T) {
+	p := newPilot([]Cohort{
+		{Name: "wave-1", Groups: []string{"group-1"}, Users: []string{"Pilot@example.com"}},
+	})
+
+	p.resolve(
+		[]*v1alpha1.Group{
+			testGovGroup(t, "group-1", []string{"user-2"}, nil),
+			testGovGroup(t, "group-2", []string{"user-3"}, nil),
+		},
+		[]*v1beta1.User{
+			testGovUser(t, "user-1", "pilot@example.com"),
+			testGovUser(t, "user-2", "member@example.com"),
+			testGovUser(t, "user-3", "other@example.com"),
+		},
+	)
+
+	tests := []struct {
+		name   string
+		id     string
+		email  string
+		wantOK bool
+	}{
+		{
+			name:   "by email",
+			id:     "user-9",
+			email:  "pilot@example.com",
+			wantOK: true,
+		},
+		{
+			name:   "by cohort group membership",
+			id:     "user-2",
+			wantOK: true,
+		},
+		{
+			name:  "outside of the cohorts",
+			id:    "user-3",
+			email: "other@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := p.userCohort(tt.id, tt.email)
+			assert.Equal(t, tt.wantOK, ok)
+
+			if tt.wantOK {
+				assert.Equal(t, "wave-1", got)
+			}
+		})
+	}
+}
+
+func TestReconciler_detectOnly(t *testing.T) {
+	ctx := context.Background()
+
+	r := &Reconciler{logger: zap.NewNop()}
+	assert.False(t, r.detectOnlyGroup(ctx, "group-2", nil))
+	assert.False(t, r.detectOnlyUser(ctx, "user-2", ""))
+
+	r.pilot = newPilot([]Cohort{{Name: "wave-1", Groups: []string{"group-1"}, Users: []string{"user-1"}}})
+	assert.False(t, r.detectOnlyGroup(ctx, "group-1", nil))
+	assert.False(t, r.detectOnlyUser(ctx, "user-1", ""))
+	assert.True(t, r.detectOnlyGroup(ctx, "group-2", nil))
+	assert.True(t, r.detectOnlyUser(ctx, "user-2", ""))
+
+	r.pilot.finishLoop()
+
+	assert.Equal(t, 2, r.pilot.status().DetectOnlyChanges)
+}
+
+func Test_pilot_status(t *testing.T) {
+	p := newPilot([]Cohort{
+		{Name: "wave-1", Groups: []string{"group-1"}},
+		{Name: "wave-2", Groups: []string{"group-2"}},
+	})
+
+	p.resolve(
+		[]*v1alpha1.Group{
+			testGovGroup(t, "group-1", []string{"user-1"}, nil),
+			testGovGroup(t, "group-2", nil, nil),
+		},
+		nil,
+	)
+
+	p.changeApplied(map[string]string{"governor.group.id": "group-1", "okta.group.id": "okta-1"})
+	p.changeApplied(map[string]string{"governor.user.id": "user-1", "okta.user.id": "okta-user-1"})
+	p.changeApplied(map[string]string{"governor.group.id": "group-3"})
+
+	// counts are only published once the loop is finished
+	assert.True(t, p.status().Cohorts[0].Converged)
+
+	p.finishLoop()
+
+	assert.Equal(t, &PilotStatus{
+		Cohorts: []CohortStatus{
+			{Name: "wave-1", Groups: []string{"group-1"}, Users: []string{"user-1"}, Changes: 2},
+			{Name: "wave-2", Groups: []string{"group-2"}, Users: []string{}, Converged: true},
+		},
+	}, p.status())
+
+	p.finishLoop()
+
+	assert.True(t, p.status().Cohorts[0].Converged)
+}
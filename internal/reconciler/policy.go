@@ -0,0 +1,158 @@
+package reconciler
+
+import (
+	"context"
+	"time"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// SourceOfTruth is the side that wins when governor and okta disagree about a resource
+type SourceOfTruth string
+
+const (
+	// SourceOfTruthGovernor corrects okta to match governor
+	SourceOfTruthGovernor SourceOfTruth = "governor"
+	// SourceOfTruthOkta corrects governor to match okta, or leaves okta alone when governor can't be corrected
+	SourceOfTruthOkta SourceOfTruth = "okta"
+	// SourceOfTruthNewest corrects the side that changed first, governor wins ties
+	SourceOfTruthNewest SourceOfTruth = "newest"
+)
+
+const (
+	// ConflictResourceGroups are the names and descriptions of groups
+	ConflictResourceGroups = "groups"
+	// ConflictResourceMembers are the members of groups
+	ConflictResourceMembers = "members"
+	// ConflictResourceUsers are the statuses and profiles of users
+	ConflictResourceUsers = "users"
+)
+
+var conflictDecisionsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Subsystem: subsystem,
+		Name:      "conflict_policy_decisions_total",
+		Help:      "Total count of conflict policy decisions on the direction of a correction, by resource and winning side.",
+	},
+	[]string{"resource", "winner"},
+)
+
+// Valid returns true for a known source of truth
+func (s SourceOfTruth) Valid() bool {
+	switch s {
+	case SourceOfTruthGovernor, SourceOfTruthOkta, SourceOfTruthNewest:
+		return true
+	default:
+		return false
+	}
+}
+
+// winner returns the side winning a conflict about a resource last changed in governor and okta at the given
+// times, zero times are unknown.  It's empty when the winner can't be told apart.
+func (s SourceOfTruth) winner(governorAt, oktaAt time.Time) SourceOfTruth {
+	switch s {
+	case SourceOfTruthGovernor, SourceOfTruthOkta:
+		return s
+	case SourceOfTruthNewest:
+		switch {
+		case governorAt.IsZero() && oktaAt.IsZero():
+			return ""
+		case oktaAt.After(governorAt):
+			return SourceOfTruthOkta
+		default:
+			return SourceOfTruthGovernor
+		}
+	default:
+		return ""
+	}
+}
+
+// ConflictPolicy is the source of truth of each resource type when governor and okta disagree.  Without a source of
+// truth a resource is corrected in the direction of the code path that noticed the drift, ie. the reconciler loop
+// corrects okta and okta log events correct governor.
+type ConflictPolicy struct {
+	Groups  SourceOfTruth `json:"groups,omitempty"`
+	Members SourceOfTruth `json:"members,omitempty"`
+	Users   SourceOfTruth `json:"users,omitempty"`
+}
+
+// SourceOfTruth returns the source of truth of the resource type, empty when it isn't set
+func (p ConflictPolicy) SourceOfTruth(resource string) SourceOfTruth {
+	switch resource {
+	case ConflictResourceGroups:
+		return p.Groups
+	case ConflictResourceMembers:
+		return p.Members
+	case ConflictResourceUsers:
+		return p.Users
+	default:
+		return ""
+	}
+}
+
+// WithConflictPolicy sets the source of truth of each resource type when governor and okta disagree
+func WithConflictPolicy(p ConflictPolicy) Option {
+	return func(r *Reconciler) {
+		r.conflictPolicy = p
+	}
+}
+
+// ConflictPolicy returns the source of truth of each resource type
+func (r *Reconciler) ConflictPolicy() ConflictPolicy {
+	return r.conflictPolicy
+}
+
+// conflictWinner resolves a conflict about a resource last changed in governor and okta at the given times with the
+// conflict policy and counts the decision.  It's empty when there's no decision and the caller corrects the resource
+// in its own direction.
+func (r *Reconciler) conflictWinner(ctx context.Context, resource string, governorAt, oktaAt time.Time) SourceOfTruth {
+	winner := r.conflictPolicy.SourceOfTruth(resource).winner(governorAt, oktaAt)
+	if winner != "" {
+		incCounter(ctx, conflictDecisionsCounter.WithLabelValues(resource, string(winner)))
+	}
+
+	return winner
+}
+
+// groupConflictWinner resolves a conflict about a governor group and its okta group, ie. their names or members.
+// The okta group is only looked up when its last change matters.
+func (r *Reconciler) groupConflictWinner(
+	ctx context.Context,
+	logger *zap.Logger,
+	resource string,
+	group *v1alpha1.Group,
+	oktaGID string,
+	oktaUpdated func(*okt.Group) *time.Time,
+) SourceOfTruth {
+	var governorAt, oktaAt time.Time
+
+	if r.conflictPolicy.SourceOfTruth(resource) == SourceOfTruthNewest {
+		if group.Group != nil {
+			governorAt = group.UpdatedAt
+		}
+
+		og, err := callOp(ctx, r, "okta.GetGroup", func(ctx context.Context) (*okt.Group, error) {
+			return r.oktaClient.GetGroup(ctx, oktaGID)
+		})
+		if err != nil {
+			logger.Warn("error getting okta group to resolve a conflict", zap.Error(err))
+		} else {
+			oktaAt = oktaTime(oktaUpdated(og))
+		}
+	}
+
+	return r.conflictWinner(ctx, resource, governorAt, oktaAt)
+}
+
+// oktaTime returns the okta timestamp, zero when it's unknown
+func oktaTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return *t
+}
@@ -0,0 +1,189 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceOfTruth_winner(t *testing.T) {
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		s          SourceOfTruth
+		governorAt time.Time
+		oktaAt     time.Time
+		want       SourceOfTruth
+	}{
+		{name: "unset", governorAt: earlier, oktaAt: later, want: ""},
+		{name: "governor", s: SourceOfTruthGovernor, governorAt: earlier, oktaAt: later, want: SourceOfTruthGovernor},
+		{name: "okta", s: SourceOfTruthOkta, governorAt: later, oktaAt: earlier, want: SourceOfTruthOkta},
+		{name: "newest okta", s: SourceOfTruthNewest, governorAt: earlier, oktaAt: later, want: SourceOfTruthOkta},
+		{name: "newest governor", s: SourceOfTruthNewest, governorAt: later, oktaAt: earlier, want: SourceOfTruthGovernor},
+		{name: "newest tie", s: SourceOfTruthNewest, governorAt: earlier, oktaAt: earlier, want: SourceOfTruthGovernor},
+		{name: "newest unknown okta", s: SourceOfTruthNewest, governorAt: earlier, want: SourceOfTruthGovernor},
+		{name: "newest unknown governor", s: SourceOfTruthNewest, oktaAt: earlier, want: SourceOfTruthOkta},
+		{name: "newest unknown", s: SourceOfTruthNewest, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.s.winner(tt.governorAt, tt.oktaAt))
+		})
+	}
+}
+
+func TestReconciler_GroupMembership_conflictPolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          ConflictPolicy
+		governorUpdated time.Duration
+		wantOkta        []string
+		wantGovernor    []string
+	}{
+		{
+			name:         "unset corrects okta",
+			wantOkta:     []string{"okta-1", "okta-2"},
+			wantGovernor: []string{"user-1", "user-2"},
+		},
+		{
+			name:         "governor",
+			policy:       ConflictPolicy{Members: SourceOfTruthGovernor},
+			wantOkta:     []string{"okta-1", "okta-2"},
+			wantGovernor: []string{"user-1", "user-2"},
+		},
+		{
+			name:         "okta",
+			policy:       ConflictPolicy{Members: SourceOfTruthOkta},
+			wantOkta:     []string{"okta-1", "okta-3"},
+			wantGovernor: []string{"user-1", "user-3"},
+		},
+		{
+			name:            "newest okta",
+			policy:          ConflictPolicy{Members: SourceOfTruthNewest},
+			governorUpdated: -time.Hour,
+			wantOkta:        []string{"okta-1", "okta-3"},
+			wantGovernor:    []string{"user-1", "user-3"},
+		},
+		{
+			name:            "newest governor",
+			policy:          ConflictPolicy{Members: SourceOfTruthNewest},
+			governorUpdated: time.Hour,
+			wantOkta:        []string{"okta-1", "okta-2"},
+			wantGovernor:    []string{"user-1", "user-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			for _, id := range []string{"1", "2", "3"} {
+				email := "user-" + id + "@example.com"
+
+				g.AddUser(&testserver.GovernorUser{ID: "user-" + id, ExternalID: "okta-" + id, Email: email, Status: v1alpha1.UserStatusActive})
+				o.AddUser("okta-"+id, "ACTIVE", testOktaProfile(email))
+			}
+
+			g.AddGroup(&testserver.GovernorGroup{
+				ID:        "group-1",
+				Name:      "Platform",
+				Slug:      "platform",
+				Members:   []string{"user-1", "user-2"},
+				UpdatedAt: time.Now().Add(tt.governorUpdated),
+			})
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-1", "okta-3")
+
+			r, _ := newTestServerReconciler(t, o, g, WithConflictPolicy(tt.policy))
+
+			_, err := r.GroupMembership(context.TODO(), "group-1", "00g-platform")
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tt.wantOkta, o.GroupMembers("00g-platform"))
+			assert.ElementsMatch(t, tt.wantGovernor, g.Group("group-1").Members)
+		})
+	}
+}
+
+func TestReconciler_UserUpdate_conflictPolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          ConflictPolicy
+		governorUpdated time.Duration
+		wantStatus      string
+	}{
+		{name: "unset", wantStatus: "SUSPENDED"},
+		{name: "okta", policy: ConflictPolicy{Users: SourceOfTruthOkta}, wantStatus: "ACTIVE"},
+		{name: "newest okta", policy: ConflictPolicy{Users: SourceOfTruthNewest}, governorUpdated: -time.Hour, wantStatus: "ACTIVE"},
+		{name: "newest governor", policy: ConflictPolicy{Users: SourceOfTruthNewest}, governorUpdated: time.Hour, wantStatus: "SUSPENDED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			g.AddUser(&testserver.GovernorUser{
+				ID:         "user-1",
+				ExternalID: "okta-1",
+				Email:      "user-1@example.com",
+				Status:     v1alpha1.UserStatusSuspended,
+				UpdatedAt:  time.Now().Add(tt.governorUpdated),
+			})
+			o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+
+			r, _ := newTestServerReconciler(t, o, g, WithConflictPolicy(tt.policy))
+
+			_, err := r.UserUpdate(context.TODO(), "user-1")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatus, o.User("okta-1").Status)
+		})
+	}
+}
+
+func TestReconciler_GroupUpdate_conflictPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   ConflictPolicy
+		wantName string
+	}{
+		{name: "unset", wantName: "Platform Engineering"},
+		{name: "okta", policy: ConflictPolicy{Groups: SourceOfTruthOkta}, wantName: "Platform"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform Engineering", Slug: "platform"})
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"})
+
+			r, _ := newTestServerReconciler(t, o, g, WithConflictPolicy(tt.policy))
+
+			_, err := r.GroupUpdate(context.TODO(), "group-1")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantName, o.Group("00g-platform").Profile.Name)
+		})
+	}
+}
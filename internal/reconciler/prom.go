@@ -1,8 +1,11 @@
 package reconciler
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const subsystem = "gov_okta_addon"
@@ -24,6 +27,14 @@ var (
 		},
 	)
 
+	groupsUpdateSkippedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "groups_update_skipped_total",
+			Help:      "Total count of group updates skipped because the okta group was unchanged.",
+		},
+	)
+
 	groupsDeletedCounter = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Subsystem: subsystem,
@@ -32,6 +43,22 @@ var (
 		},
 	)
 
+	reconcilerPausedGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "reconciler_paused",
+			Help:      "Whether the reconciler mutations are paused by an operator (1) or not (0).",
+		},
+	)
+
+	groupsPendingDeleteGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "groups_pending_delete",
+			Help:      "Number of okta groups flagged for deletion waiting for the delete grace period.",
+		},
+	)
+
 	groupsApplicationAssignedCounter = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Subsystem: subsystem,
@@ -48,6 +75,38 @@ var (
 		},
 	)
 
+	groupsApplicationAssignmentUpdatedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "groups_application_assignment_updated_total",
+			Help:      "Total count of drifted application group assignments updated.",
+		},
+	)
+
+	groupsPushMappingCreatedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "groups_push_mapping_created_total",
+			Help:      "Total count of okta push group mappings created or activated.",
+		},
+	)
+
+	groupsPushMappingDeletedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "groups_push_mapping_deleted_total",
+			Help:      "Total count of okta push group mappings deleted.",
+		},
+	)
+
+	groupsOrphanClearedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "groups_orphan_cleared_total",
+			Help:      "Total count of orphaned okta groups with their governor id cleared.",
+		},
+	)
+
 	groupMembershipCreatedCounter = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Subsystem: subsystem,
@@ -64,11 +123,99 @@ var (
 		},
 	)
 
+	groupMemberRequestsCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "group_member_requests_total",
+			Help:      "Total count of governor membership requests filed for okta group members.",
+		},
+	)
+
+	groupOwnerCreatedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "group_owners_created_total",
+			Help:      "Total count of okta group owners added.",
+		},
+	)
+
+	groupOwnerDeletedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "group_owners_deleted_total",
+			Help:      "Total count of okta group owners removed.",
+		},
+	)
+
+	userEmailDriftCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "user_email_drift_total",
+			Help:      "Total count of governor users found with a different email than their okta user.",
+		},
+	)
+
+	nonHumanUsersSkippedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "non_human_users_skipped_total",
+			Help:      "Total count of non-human okta accounts skipped.",
+		},
+	)
+
+	changesVerifiedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "changes_verified_total",
+			Help:      "Total count of applied okta changes sampled and re-read from okta.",
+		},
+	)
+
+	changeVerificationFailedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "change_verification_failed_total",
+			Help:      "Total count of sampled okta changes that didn't take effect in okta.",
+		},
+	)
+
+	changeVerificationErrorsCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "change_verification_errors_total",
+			Help:      "Total count of sampled okta changes that couldn't be verified because reading okta failed.",
+		},
+	)
+
+	usersDeactivatedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "users_deactivated_total",
+			Help:      "Total count of users deactivated without being permanently deleted.",
+		},
+	)
+
 	usersDeletedCounter = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Subsystem: subsystem,
 			Name:      "users_deleted_total",
-			Help:      "Total count of users deleted.",
+			Help:      "Total count of users permanently deleted.",
+		},
+	)
+
+	usersSuspendedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "users_suspended_total",
+			Help:      "Total count of users suspended.",
+		},
+	)
+
+	usersUnsuspendedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "users_unsuspended_total",
+			Help:      "Total count of users un-suspended.",
 		},
 	)
 
@@ -79,4 +226,108 @@ var (
 			Help:      "Total count of users updated.",
 		},
 	)
+
+	noopRunsCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "reconciler_noop_runs_total",
+			Help:      "Total count of reconciler loops short-circuited because nothing changed.",
+		},
+	)
+
+	invariantDivergenceGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "invariant_divergence_ratio",
+			Help:      "Divergence between governor and okta counts as a fraction of the larger count.",
+		},
+		[]string{"invariant"},
+	)
+
+	invariantViolationsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "invariant_violations_total",
+			Help:      "Total count of governor and okta counts diverging beyond tolerance.",
+		},
+		[]string{"invariant"},
+	)
+
+	operationTimeoutsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "operation_timeouts_total",
+			Help:      "Total count of external okta and governor operations that timed out.",
+		},
+		[]string{"operation"},
+	)
+
+	circuitBreakerStateGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "circuit_breaker_state",
+			Help:      "State of the circuit breaker of the okta and governor backends, 0 closed, 1 half-open and 2 open.",
+		},
+		[]string{"backend"},
+	)
+
+	circuitBreakerTripsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "circuit_breaker_trips_total",
+			Help:      "Total count of circuit breakers opened by consecutive failures of the okta and governor backends.",
+		},
+		[]string{"backend"},
+	)
+
+	quarantinedGroupsGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "groups_quarantined",
+			Help:      "Number of governor groups quarantined after consecutive reconcile failures.",
+		},
+	)
+
+	quarantinedGroupsCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "groups_quarantined_total",
+			Help:      "Total count of governor groups quarantined after consecutive reconcile failures.",
+		},
+	)
+
+	eventlogEventsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "eventlog_events_total",
+			Help:      "Total count of okta log events handled, by event type.",
+		},
+		[]string{"event_type"},
+	)
+
+	eventlogHandlerErrorsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "eventlog_handler_errors_total",
+			Help:      "Total count of okta log events that failed to be handled, by event type.",
+		},
+		[]string{"event_type"},
+	)
 )
+
+// incCounter increments the counter with the trace id of a sampled span in the context as an exemplar, so metric
+// spikes can be linked to the trace of the run that caused them
+func incCounter(ctx context.Context, c prometheus.Counter) {
+	sc := trace.SpanContextFromContext(ctx)
+
+	ea, ok := c.(prometheus.ExemplarAdder)
+	if !ok || !sc.IsSampled() {
+		c.Inc()
+		return
+	}
+
+	ea.AddWithExemplar(1, prometheus.Labels{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	})
+}
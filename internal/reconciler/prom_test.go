@@ -0,0 +1,70 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_incCounter(t *testing.T) {
+	traceID := trace.TraceID{0x01}
+	spanID := trace.SpanID{0x02}
+
+	tests := []struct {
+		name         string
+		flags        trace.TraceFlags
+		noSpan       bool
+		wantExemplar bool
+	}{
+		{
+			name:         "sampled span",
+			flags:        trace.FlagsSampled,
+			wantExemplar: true,
+		},
+		{
+			name:  "unsampled span",
+			flags: 0,
+		},
+		{
+			name:   "no span",
+			noSpan: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total"})
+
+			ctx := context.TODO()
+			if !tt.noSpan {
+				ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID:    traceID,
+					SpanID:     spanID,
+					TraceFlags: tt.flags,
+				}))
+			}
+
+			incCounter(ctx, c)
+
+			m := &dto.Metric{}
+			assert.NoError(t, c.Write(m))
+			assert.Equal(t, float64(1), m.GetCounter().GetValue())
+
+			if !tt.wantExemplar {
+				assert.Nil(t, m.GetCounter().GetExemplar())
+				return
+			}
+
+			labels := map[string]string{}
+			for _, l := range m.GetCounter().GetExemplar().GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+
+			assert.Equal(t, map[string]string{"trace_id": traceID.String(), "span_id": spanID.String()}, labels)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package reconciler
+
+import (
+	"context"
+	"strings"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"go.uber.org/zap"
+)
+
+// WithPushGroups enables pushing the okta groups assigned to the application of the github orgs with the given slugs
+// to github teams, named after the governor groups
+func WithPushGroups(orgs ...string) Option {
+	return func(r *Reconciler) {
+		r.pushGroupOrgs = make(map[string]struct{}, len(orgs))
+
+		for _, org := range orgs {
+			r.pushGroupOrgs[strings.ToLower(org)] = struct{}{}
+		}
+	}
+}
+
+// pushGroups returns true when the okta groups of the application of a github org are pushed to github teams
+func (r *Reconciler) pushGroups(org string) bool {
+	_, ok := r.pushGroupOrgs[strings.ToLower(org)]
+	return ok
+}
+
+// reconcilePushGroups reconciles the push group mappings of the application of a github org: the okta groups of the
+// governor groups in the org are pushed to the application and inactive mappings are activated, and the mappings of
+// the governor groups that left the org are deleted unless the application is assign-only.  Mappings of okta groups
+// that aren't in the group map are left alone.
+func (r *Reconciler) reconcilePushGroups(
+	ctx context.Context,
+	logger *zap.Logger,
+	org, appID string,
+	mode AppAssignmentMode,
+	groupMap map[string]*v1alpha1.Group,
+	govOrgs []*v1alpha1.Organization,
+	result *AppAssignmentResult,
+	failed func(groupDetails *v1alpha1.Group, oktaGID, change string, err error),
+) error {
+	list, err := callOp(ctx, r, "okta.ListPushGroupMappings", func(ctx context.Context) ([]*okta.PushGroupMapping, error) {
+		return r.oktaClient.ListPushGroupMappings(ctx, appID)
+	})
+	if err != nil {
+		logger.Error("error listing okta push group mappings", zap.Error(err))
+		return err
+	}
+
+	mappings := make(map[string]*okta.PushGroupMapping, len(list))
+	for _, m := range list {
+		mappings[m.SourceGroupID] = m
+	}
+
+	for oktaGID, groupDetails := range groupMap {
+		logger := logger.With(
+			zap.String("governor.group.id", groupDetails.ID),
+			zap.String("governor.group.slug", groupDetails.Slug),
+			zap.String("okta.group.id", oktaGID),
+		)
+
+		mapping, mapped := mappings[oktaGID]
+		skip := r.dryRun() || r.detectOnlyGroup(ctx, groupDetails.ID, groupDetails)
+
+		if contains(getGroupOrgSlugs(groupDetails, govOrgs), org) {
+			switch {
+			case !mapped:
+				if skip {
+					logger.Info("SKIP pushing okta group to okta application")
+					continue
+				}
+
+				created, err := callOp(ctx, r, "okta.CreatePushGroupMapping", func(ctx context.Context) (*okta.PushGroupMapping, error) {
+					return r.oktaClient.CreatePushGroupMapping(ctx, appID, oktaGID, groupDetails.Name)
+				})
+				if err != nil {
+					logger.Error("error pushing okta group to okta application", zap.Error(err))
+
+					failed(groupDetails, oktaGID, appAssignmentChangePush, err)
+
+					continue
+				}
+
+				incCounter(ctx, groupsPushMappingCreatedCounter)
+
+				result.Pushed = append(result.Pushed, oktaGID)
+
+				if err := r.writeMutationEvent(ctx, auctx.GroupPushMappingAdd{
+					GovernorGroupSlug: groupDetails.Slug,
+					GovernorGroupID:   groupDetails.ID,
+					GovernorAppSlug:   org,
+					OktaGroupID:       oktaGID,
+					OktaAppID:         appID,
+					OktaAppSlug:       org,
+					OktaMappingID:     created.ID,
+				}, nil, created); err != nil {
+					logger.Error("error writing audit event", zap.Error(err))
+				}
+			case mapping.Status == okta.PushGroupMappingInactive:
+				if skip {
+					logger.Info("SKIP activating okta push group mapping", zap.String("okta.mapping.id", mapping.ID))
+					continue
+				}
+
+				if err := r.doOp(ctx, "okta.ActivatePushGroupMapping", func(ctx context.Context) error {
+					return r.oktaClient.ActivatePushGroupMapping(ctx, appID, mapping.ID)
+				}); err != nil {
+					logger.Error("error activating okta push group mapping", zap.String("okta.mapping.id", mapping.ID), zap.Error(err))
+
+					failed(groupDetails, oktaGID, appAssignmentChangePush, err)
+
+					continue
+				}
+
+				incCounter(ctx, groupsPushMappingCreatedCounter)
+
+				result.Pushed = append(result.Pushed, oktaGID)
+
+				activated := *mapping
+				activated.Status = okta.PushGroupMappingActive
+
+				if err := r.writeMutationEvent(ctx, auctx.GroupPushMappingAdd{
+					GovernorGroupSlug: groupDetails.Slug,
+					GovernorGroupID:   groupDetails.ID,
+					GovernorAppSlug:   org,
+					OktaGroupID:       oktaGID,
+					OktaAppID:         appID,
+					OktaAppSlug:       org,
+					OktaMappingID:     mapping.ID,
+				}, mapping, &activated); err != nil {
+					logger.Error("error writing audit event", zap.Error(err))
+				}
+			case mapping.Status == okta.PushGroupMappingError:
+				// okta retries failed pushes, there's nothing to fix on our side
+				logger.Warn("okta push group mapping failed",
+					zap.String("okta.mapping.id", mapping.ID),
+					zap.String("okta.mapping.error", mapping.ErrorSummary),
+				)
+			}
+
+			continue
+		}
+
+		if !mapped || mode == AppAssignmentAssignOnly {
+			continue
+		}
+
+		if skip || r.skipDelete {
+			logger.Info("SKIP deleting okta push group mapping", zap.String("okta.mapping.id", mapping.ID))
+
+			r.status.pendingDeletion(PendingDeletion{
+				Type:            "PushGroupMappingDelete",
+				GovernorGroupID: groupDetails.ID,
+				OktaGroupID:     oktaGID,
+				OktaAppID:       appID,
+			})
+
+			continue
+		}
+
+		if err := r.doOp(ctx, "okta.DeletePushGroupMapping", func(ctx context.Context) error {
+			return r.oktaClient.DeletePushGroupMapping(ctx, appID, mapping.ID)
+		}); err != nil {
+			logger.Error("error deleting okta push group mapping", zap.String("okta.mapping.id", mapping.ID), zap.Error(err))
+
+			failed(groupDetails, oktaGID, appAssignmentChangeUnpush, err)
+
+			continue
+		}
+
+		incCounter(ctx, groupsPushMappingDeletedCounter)
+
+		result.Unpushed = append(result.Unpushed, oktaGID)
+
+		if err := r.writeMutationEvent(ctx, auctx.GroupPushMappingRemove{
+			GovernorGroupSlug: groupDetails.Slug,
+			GovernorGroupID:   groupDetails.ID,
+			GovernorAppSlug:   org,
+			OktaGroupID:       oktaGID,
+			OktaAppID:         appID,
+			OktaAppSlug:       org,
+			OktaMappingID:     mapping.ID,
+		}, mapping, nil); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package reconciler
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+)
+
+const (
+	// DefaultQuarantineFailures is the default number of consecutive failures of a governor group before it's
+	// quarantined
+	DefaultQuarantineFailures = 5
+	// DefaultQuarantineBackoff is the default time a quarantined governor group is skipped by the reconciler loop
+	DefaultQuarantineBackoff = time.Hour
+)
+
+// QuarantinedGroup is a governor group skipped by the reconciler loop after failing to reconcile too many times in
+// a row.  Once the backoff is over the group is retried, it's released if it succeeds or quarantined again if it
+// fails.
+type QuarantinedGroup struct {
+	GroupID   string    `json:"group_id"`
+	GroupSlug string    `json:"group_slug,omitempty"`
+	Failures  int       `json:"failures"`
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until"`
+	Error     string    `json:"error"`
+}
+
+// WithGroupQuarantine skips a governor group in the reconciler loop for the backoff after the given number of
+// consecutive failures to reconcile it, 0 or less failures disables the quarantine
+func WithGroupQuarantine(failures int, backoff time.Duration) Option {
+	return func(r *Reconciler) {
+		r.quarantine = nil
+
+		if failures <= 0 {
+			return
+		}
+
+		r.quarantine = newGroupQuarantine(failures, backoff)
+	}
+}
+
+// groupQuarantine counts the consecutive failures of the governor groups and tracks the quarantined ones
+type groupQuarantine struct {
+	threshold int
+	backoff   time.Duration
+
+	mu       sync.Mutex
+	failures map[string]int
+	groups   map[string]*QuarantinedGroup
+}
+
+func newGroupQuarantine(threshold int, backoff time.Duration) *groupQuarantine {
+	quarantinedGroupsGauge.Set(0)
+
+	return &groupQuarantine{
+		threshold: threshold,
+		backoff:   backoff,
+		failures:  map[string]int{},
+		groups:    map[string]*QuarantinedGroup{},
+	}
+}
+
+// skip returns true if the group is quarantined and its backoff isn't over
+func (q *groupQuarantine) skip(gid string, now time.Time) bool {
+	if q == nil {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	g, ok := q.groups[gid]
+
+	return ok && now.Before(g.Until)
+}
+
+// failed records a failure of the group and returns true if the group was quarantined by it
+func (q *groupQuarantine) failed(gid, slug string, err error, now time.Time) bool {
+	if q == nil {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.failures[gid]++
+
+	failures := q.failures[gid]
+	if failures < q.threshold {
+		return false
+	}
+
+	g, ok := q.groups[gid]
+	if !ok {
+		g = &QuarantinedGroup{GroupID: gid, Since: now}
+		q.groups[gid] = g
+	}
+
+	if slug != "" {
+		g.GroupSlug = slug
+	}
+
+	g.Failures = failures
+	g.Until = now.Add(q.backoff)
+
+	if err != nil {
+		g.Error = err.Error()
+	}
+
+	quarantinedGroupsGauge.Set(float64(len(q.groups)))
+
+	return true
+}
+
+// succeeded resets the failures of the group and releases it from the quarantine
+func (q *groupQuarantine) succeeded(gid string) {
+	q.release(gid)
+}
+
+// release resets the failures of the group and releases it from the quarantine, it returns false if the group
+// wasn't quarantined
+func (q *groupQuarantine) release(gid string) bool {
+	if q == nil {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	_, ok := q.groups[gid]
+
+	delete(q.failures, gid)
+	delete(q.groups, gid)
+
+	quarantinedGroupsGauge.Set(float64(len(q.groups)))
+
+	return ok
+}
+
+// list returns the quarantined groups sorted by group id
+func (q *groupQuarantine) list() []QuarantinedGroup {
+	groups := []QuarantinedGroup{}
+
+	if q == nil {
+		return groups
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, g := range q.groups {
+		groups = append(groups, *g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].GroupID < groups[j].GroupID })
+
+	return groups
+}
+
+// groupFailed records a governor group that failed to reconcile in the status of the loop, and quarantines it
+// after too many consecutive failures
+func (r *Reconciler) groupFailed(logger *zap.Logger, gid, slug string, err error) {
+	r.status.groupFailed(gid, slug, err)
+	r.groupFailure(logger, gid, slug, err)
+}
+
+// groupFailure counts a failure to reconcile a governor group and quarantines it after too many consecutive
+// failures, okta rate limiting isn't a failure of the group and isn't counted
+func (r *Reconciler) groupFailure(logger *zap.Logger, gid, slug string, err error) {
+	if errors.Is(err, okta.ErrRateLimited) {
+		logger.Debug("not counting rate limited failure towards the quarantine", zap.Error(err))
+		return
+	}
+
+	if !r.quarantine.failed(gid, slug, err, r.now()) {
+		return
+	}
+
+	logger.Warn("quarantining governor group after consecutive failures",
+		zap.Int("quarantine.failures", r.quarantine.threshold),
+		zap.Duration("quarantine.backoff", r.quarantine.backoff),
+		zap.Error(err),
+	)
+
+	quarantinedGroupsCounter.Inc()
+}
+
+// UnquarantineGroup releases a quarantined governor group so the next reconciler loop retries it, it returns false
+// if the group wasn't quarantined
+func (r *Reconciler) UnquarantineGroup(gid string) bool {
+	if !r.quarantine.release(gid) {
+		return false
+	}
+
+	r.logger.Info("released governor group from quarantine", zap.String("governor.group.id", gid))
+
+	return true
+}
@@ -0,0 +1,149 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_groupQuarantine(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	errBoom := errors.New("boom") //nolint:goerr113
+
+	q := newGroupQuarantine(2, time.Hour)
+
+	assert.False(t, q.failed("group-1", "platform", errBoom, now))
+	assert.False(t, q.skip("group-1", now), "a single failure doesn't quarantine")
+
+	assert.True(t, q.failed("group-1", "platform", errBoom, now))
+	assert.True(t, q.skip("group-1", now.Add(time.Minute)))
+	assert.False(t, q.skip("group-2", now))
+
+	assert.Equal(t, []QuarantinedGroup{{
+		GroupID:   "group-1",
+		GroupSlug: "platform",
+		Failures:  2,
+		Since:     now,
+		Until:     now.Add(time.Hour),
+		Error:     "boom",
+	}}, q.list())
+
+	// the group is retried after the backoff and quarantined again right away if it still fails
+	later := now.Add(2 * time.Hour)
+
+	assert.False(t, q.skip("group-1", later))
+	assert.True(t, q.failed("group-1", "", errBoom, later))
+	assert.True(t, q.skip("group-1", later))
+
+	list := q.list()
+	require.Len(t, list, 1)
+	assert.Equal(t, now, list[0].Since)
+	assert.Equal(t, later.Add(time.Hour), list[0].Until)
+	assert.Equal(t, "platform", list[0].GroupSlug)
+	assert.Equal(t, 3, list[0].Failures)
+
+	// a success resets the failures
+	q.succeeded("group-1")
+
+	assert.Empty(t, q.list())
+	assert.False(t, q.failed("group-1", "platform", errBoom, later))
+
+	assert.False(t, q.release("group-2"), "releasing a group that isn't quarantined is a no-op")
+
+	// a nil quarantine is disabled
+	var disabled *groupQuarantine
+
+	assert.False(t, disabled.failed("group-1", "platform", errBoom, now))
+	assert.False(t, disabled.skip("group-1", now))
+	assert.Empty(t, disabled.list())
+}
+
+func TestReconciler_groupQuarantine(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1"}})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-2", Name: "Storage", Slug: "storage", Members: []string{"user-1"}})
+
+	o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"})
+	o.AddGroup("00g-storage", "Storage", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-2"})
+
+	// the members of the platform group can never be listed
+	o.FailRequests(func(req testserver.Request) int {
+		if req.Path == "/api/v1/groups/00g-platform/users" {
+			return http.StatusInternalServerError
+		}
+
+		return 0
+	})
+
+	r, _ := newTestServerReconciler(t, o, g, WithGroupQuarantine(2, time.Hour))
+
+	memberListings := func() int {
+		n := 0
+
+		for _, req := range o.Requests() {
+			if req.Path == "/api/v1/groups/00g-platform/users" {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	r.reconcileLoop(context.TODO())
+	assert.Empty(t, r.Status().QuarantinedGroups)
+
+	r.reconcileLoop(context.TODO())
+
+	st := r.Status()
+	require.Len(t, st.QuarantinedGroups, 1)
+	assert.Equal(t, "group-1", st.QuarantinedGroups[0].GroupID)
+	assert.Equal(t, "platform", st.QuarantinedGroups[0].GroupSlug)
+	assert.Equal(t, 2, st.QuarantinedGroups[0].Failures)
+
+	// the quarantined group is skipped while the other groups are still reconciled
+	listings := memberListings()
+
+	r.reconcileLoop(context.TODO())
+
+	assert.Equal(t, listings, memberListings())
+	assert.Equal(t, []string{"okta-1"}, o.GroupMembers("00g-storage"))
+	assert.Equal(t, RunResultPartial, r.Status().Runs[0].Result)
+
+	// once released the group is retried by the next loop
+	assert.True(t, r.UnquarantineGroup("group-1"))
+	assert.False(t, r.UnquarantineGroup("group-1"))
+
+	r.reconcileLoop(context.TODO())
+
+	assert.Greater(t, memberListings(), listings)
+	assert.Empty(t, r.Status().QuarantinedGroups)
+}
+
+func TestReconciler_groupFailure_rateLimited(t *testing.T) {
+	r := &Reconciler{quarantine: newGroupQuarantine(1, time.Hour)}
+
+	rateLimited := fmt.Errorf("listing members: %w", &okta.APIError{Kind: okta.ErrRateLimited, StatusCode: http.StatusTooManyRequests})
+
+	r.groupFailure(zap.NewNop(), "group-1", "platform", rateLimited)
+	assert.Empty(t, r.quarantine.list(), "rate limiting isn't counted")
+
+	r.groupFailure(zap.NewNop(), "group-1", "platform", errors.New("boom")) //nolint:goerr113
+	assert.Len(t, r.quarantine.list(), 1)
+}
@@ -3,16 +3,27 @@ package reconciler
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/metal-toolbox/addonx/natslock"
 	"github.com/metal-toolbox/auditevent"
 	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/journal"
+	"github.com/metal-toolbox/gov-okta-addon/internal/notify"
 	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/ratelimit"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
-	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
 
 	"go.uber.org/zap"
 )
@@ -23,10 +34,16 @@ const (
 )
 
 type govClientIface interface {
+	AddGroupMember(context.Context, string, string, bool) error
+	CreateGroupMemberRequest(context.Context, string, *govclient.MemberRequest) error
 	CreateUser(context.Context, *v1alpha1.UserReq) (*v1alpha1.User, error)
 	Group(context.Context, string, bool) (*v1alpha1.Group, error)
+	GroupMemberRequestsByUser(context.Context, string) (map[string]*v1alpha1.GroupMemberRequest, error)
+	GroupMembers(context.Context, string) ([]*v1alpha1.GroupMember, error)
 	Groups(context.Context) ([]*v1alpha1.Group, error)
 	Organizations(context.Context) ([]*v1alpha1.Organization, error)
+	RecordOktaGroupID(context.Context, *v1alpha1.Group, string) error
+	RemoveGroupMember(context.Context, string, string) error
 	UpdateUser(context.Context, string, *v1alpha1.UserReq) (*v1alpha1.User, error)
 	URL() string
 	User(context.Context, string, bool) (*v1alpha1.User, error)
@@ -36,17 +53,66 @@ type govClientIface interface {
 
 // Reconciler reconciles Governor groups/users with Okta
 type Reconciler struct {
-	auditEventWriter   *auditevent.EventWriter
-	reconcilerInterval time.Duration
-	eventlogInterval   time.Duration
-	eventlogLookback   time.Duration
-	governorClient     govClientIface
-	id                 uuid.UUID
-	locker             *natslock.Locker
-	logger             *zap.Logger
-	oktaClient         *okta.Client
-	dryrun             bool
-	skipDelete         bool
+	appAssignModes      map[string]AppAssignmentMode
+	appAssignSettings   map[string]*okta.AppGroupAssignment
+	auditEventWriter    *auditevent.EventWriter
+	breakers            map[string]*circuitBreaker
+	changes             *changes.Publisher
+	clock               Clock
+	conflictPolicy      ConflictPolicy
+	defaultGroups       []string
+	descriptionMarker   okta.GroupDescriptionMarker
+	deprovisionNotify   *changes.DeprovisionNotifier
+	reconcilerInterval  time.Duration
+	eventlog            eventlogCheckpoint
+	eventlogInterval    time.Duration
+	eventlogLookback    time.Duration
+	governorClient      govClientIface
+	groupDeleteGrace    time.Duration
+	groupNames          okta.GroupNameMapping
+	groupNameTemplate   *okta.GroupNameTemplate
+	groupOwners         bool
+	id                  uuid.UUID
+	initialDelay        time.Duration
+	invariants          *InvariantTolerances
+	journal             *journal.Journal
+	lastSnapshot        string
+	listUsersOpts       []okta.ListUsersOption
+	locker              *natslock.Locker
+	logger              *zap.Logger
+	loopJitter          time.Duration
+	memberRequests      bool
+	memberSem           chan struct{}
+	nonHumanAccounts    okta.AccountRules
+	notifier            *notify.Notifier
+	oktaClient          *okta.Client
+	oktaEventsSeen      atomic.Bool
+	oktaLimiter         *ratelimit.Limiter
+	offboardGroups      bool
+	opTimeout           time.Duration
+	orphanCleanup       bool
+	pause               pauseState
+	permanentUserDelete bool
+	pilot               *pilot
+	profileUpdates      bool
+	pushGroupOrgs       map[string]struct{}
+	quarantine          *groupQuarantine
+	recordOktaGroupIDs  bool
+	runOnStart          bool
+	schedule            *groupSchedule
+	scheduleResolution  time.Duration
+	status              *statusTracker
+	syncUserEmail       bool
+	userGovernorID      bool
+	userLifecycles      map[string]UserLifecycle
+	userCache           usercache.Store[*v1beta1.User]
+	userMatchKey        okta.UserMatchKey
+	verifySampler       *changeSampler
+	dryrun              bool
+	skipDelete          bool
+	stateStore          StateStore
+
+	snapshotShortCircuit bool
 }
 
 // Option is a functional configuration option
@@ -61,6 +127,13 @@ func WithIntervals(ri, ei, el time.Duration) Option {
 	}
 }
 
+// WithGroupScheduleResolution sets how often groups with a reconcile interval override are checked
+func WithGroupScheduleResolution(d time.Duration) Option {
+	return func(r *Reconciler) {
+		r.scheduleResolution = d
+	}
+}
+
 // WithLogger sets logger
 func WithLogger(l *zap.Logger) Option {
 	return func(r *Reconciler) {
@@ -75,6 +148,28 @@ func WithAuditEventWriter(a *auditevent.EventWriter) Option {
 	}
 }
 
+// WithChangePublisher publishes an event for every change the reconciler makes in okta and governor
+func WithChangePublisher(p *changes.Publisher) Option {
+	return func(r *Reconciler) {
+		r.changes = p
+	}
+}
+
+// WithDeprovisionNotifier publishes a notification for every okta user deprovisioned after their governor user
+// was deleted
+func WithDeprovisionNotifier(n *changes.DeprovisionNotifier) Option {
+	return func(r *Reconciler) {
+		r.deprovisionNotify = n
+	}
+}
+
+// WithWebhookNotifier posts a notice of the destructive okta changes to a webhook
+func WithWebhookNotifier(n *notify.Notifier) Option {
+	return func(r *Reconciler) {
+		r.notifier = n
+	}
+}
+
 // WithDryRun sets dryrun
 func WithDryRun(d bool) Option {
 	return func(r *Reconciler) {
@@ -89,6 +184,14 @@ func WithSkipDelete(s bool) Option {
 	}
 }
 
+// WithVerifySampleSize sets the number of applied okta changes randomly sampled and verified after each
+// reconciler loop, 0 disables verification
+func WithVerifySampleSize(n int) Option {
+	return func(r *Reconciler) {
+		r.verifySampler = newChangeSampler(n)
+	}
+}
+
 // WithOktaClient sets okta client
 func WithOktaClient(o *okta.Client) Option {
 	return func(r *Reconciler) {
@@ -97,12 +200,158 @@ func WithOktaClient(o *okta.Client) Option {
 }
 
 // WithGovernorClient sets governor api client
-func WithGovernorClient(c *governor.Client) Option {
+func WithGovernorClient(c *govclient.Client) Option {
 	return func(r *Reconciler) {
 		r.governorClient = c
 	}
 }
 
+// WithJournal sets the change journal for applied okta mutations
+func WithJournal(j *journal.Journal) Option {
+	return func(r *Reconciler) {
+		r.journal = j
+	}
+}
+
+// WithSnapshotShortCircuit enables skipping the okta side of the reconciler loop when the governor
+// state hasn't changed since the last successful loop and no okta events were handled
+func WithSnapshotShortCircuit(s bool) Option {
+	return func(r *Reconciler) {
+		r.snapshotShortCircuit = s
+	}
+}
+
+// WithInvariantsCheck enables comparing governor and okta counts at the end of each reconciler loop
+// with the given tolerances
+func WithInvariantsCheck(t *InvariantTolerances) Option {
+	return func(r *Reconciler) {
+		r.invariants = t
+	}
+}
+
+// WithPermanentUserDelete enables permanently deleting okta users that are deleted in governor, instead
+// of only deactivating them
+func WithPermanentUserDelete(p bool) Option {
+	return func(r *Reconciler) {
+		r.permanentUserDelete = p
+	}
+}
+
+// WithSyncUserEmail enables updating the email of governor users to the email of their okta user when
+// they drift apart
+func WithSyncUserEmail(s bool) Option {
+	return func(r *Reconciler) {
+		r.syncUserEmail = s
+	}
+}
+
+// WithUserGovernorID enables writing the governor user id to the okta user profile during user reconciliation
+func WithUserGovernorID(u bool) Option {
+	return func(r *Reconciler) {
+		r.userGovernorID = u
+	}
+}
+
+// WithGroupDeleteGracePeriod flags the okta groups of deleted governor groups for deletion instead of deleting
+// them, they're deleted by the reconciler loop once the grace period is over.  0 deletes them right away.
+func WithGroupDeleteGracePeriod(d time.Duration) Option {
+	return func(r *Reconciler) {
+		r.groupDeleteGrace = d
+	}
+}
+
+// WithOrphanCleanup enables clearing the governor id from the okta groups of governor groups that don't exist
+// anymore, ie. when deleting the okta group failed or with skip-delete
+func WithOrphanCleanup(c bool) Option {
+	return func(r *Reconciler) {
+		r.orphanCleanup = c
+	}
+}
+
+// WithGroupNames sets the mapping of governor group names to okta group names used when creating or updating
+// okta groups
+func WithGroupNames(m okta.GroupNameMapping) Option {
+	return func(r *Reconciler) {
+		r.groupNames = m
+	}
+}
+
+// WithGroupNameTemplate names the okta groups created or updated for governor groups after the template,
+// nil names them after the governor group name
+func WithGroupNameTemplate(t *okta.GroupNameTemplate) Option {
+	return func(r *Reconciler) {
+		r.groupNameTemplate = t
+	}
+}
+
+// WithGroupDescriptionMarker marks the descriptions of the okta groups created or updated for governor groups
+func WithGroupDescriptionMarker(m okta.GroupDescriptionMarker) Option {
+	return func(r *Reconciler) {
+		r.descriptionMarker = m
+	}
+}
+
+// WithOffboardGroupRemoval removes the okta users of deleted governor users from all of their okta groups when
+// they're deactivated
+func WithOffboardGroupRemoval(b bool) Option {
+	return func(r *Reconciler) {
+		r.offboardGroups = b
+	}
+}
+
+// WithMemberChangesAsRequests files governor membership requests for the okta group members that aren't members
+// of the governor group, instead of removing them from the okta group
+func WithMemberChangesAsRequests(b bool) Option {
+	return func(r *Reconciler) {
+		r.memberRequests = b
+	}
+}
+
+// WithUserMatchKey sets the okta user attribute governor users are matched on when they can't be matched by
+// id, ie. the okta login for tenants that rotated their email domain
+func WithUserMatchKey(k okta.UserMatchKey) Option {
+	return func(r *Reconciler) {
+		r.userMatchKey = k
+	}
+}
+
+// WithListUsersOptions filters the okta users listed by the reconciler loop, ie. to leave out deprovisioned
+// users and service accounts it never touches
+func WithListUsersOptions(opts ...okta.ListUsersOption) Option {
+	return func(r *Reconciler) {
+		r.listUsersOpts = opts
+	}
+}
+
+// WithNonHumanAccounts sets the rules matching non-human okta accounts, ie. service or bot accounts, which are
+// never created, updated or deleted in governor and left alone by the reconciler loop
+func WithNonHumanAccounts(rules okta.AccountRules) Option {
+	return func(r *Reconciler) {
+		r.nonHumanAccounts = rules
+	}
+}
+
+// WithStateStore persists the okta event log checkpoint and the group schedule across restarts
+func WithStateStore(s StateStore) Option {
+	return func(r *Reconciler) {
+		r.stateStore = s
+	}
+}
+
+// WithPilotCohorts restricts the okta changes to the groups and users of the pilot cohorts, everything else
+// is detect-only.  Cohorts can also be set in the governor group notes, so an empty list still enables the
+// pilot while nil disables it.
+func WithPilotCohorts(cohorts []Cohort) Option {
+	return func(r *Reconciler) {
+		if cohorts == nil {
+			r.pilot = nil
+			return
+		}
+
+		r.pilot = newPilot(cohorts)
+	}
+}
+
 // WithLocker sets the lead election locker
 func WithLocker(l *natslock.Locker) Option {
 	return func(r *Reconciler) {
@@ -114,9 +363,15 @@ func WithLocker(l *natslock.Locker) Option {
 func New(opts ...Option) *Reconciler {
 	rec := Reconciler{
 		logger:             zap.NewNop(),
+		clock:              systemClock{},
 		eventlogInterval:   DefaultEventlogPollerInterval,
 		eventlogLookback:   DefaultEventlogColdStartLookback,
 		reconcilerInterval: DefaultReconcileInterval,
+		opTimeout:          DefaultOpTimeout,
+		schedule:           newGroupSchedule(),
+		scheduleResolution: DefaultGroupScheduleResolution,
+		status:             newStatusTracker(),
+		userMatchKey:       okta.UserMatchKeyEmail,
 	}
 
 	for _, opt := range opts {
@@ -143,18 +398,28 @@ func New(opts ...Option) *Reconciler {
 func (r *Reconciler) Run(ctx context.Context) {
 	r.logger = r.logger.With(zap.String("reconciler.id", r.id.String()))
 
+	r.restoreState(ctx)
 	r.startEventLogPollerSubscriptions(ctx)
 
-	ticker := time.NewTicker(r.reconcilerInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(r.firstLoopDelay(loopRand))
+	defer timer.Stop()
+
+	scheduleTicker := time.NewTicker(r.scheduleResolution)
+	defer scheduleTicker.Stop()
 
 	r.logger.Info("starting reconciler loop",
 		zap.Duration("reconciler.interval", r.reconcilerInterval),
 		zap.Duration("eventlog.interval", r.eventlogInterval),
 		zap.Duration("eventlog.lookback", r.eventlogLookback),
+		zap.Duration("schedule.resolution", r.scheduleResolution),
 		zap.String("governor.url", r.governorClient.URL()),
 		zap.Bool("dryrun", r.dryrun),
 		zap.Bool("skip-delete", r.skipDelete),
+		zap.Bool("snapshot-short-circuit", r.snapshotShortCircuit),
+		zap.Duration("group.delete.grace", r.groupDeleteGrace),
+		zap.Duration("reconciler.initial-delay", r.initialDelay),
+		zap.Duration("reconciler.jitter", r.loopJitter),
+		zap.Bool("reconciler.run-on-start", r.runOnStart),
 	)
 
 	if r.locker != nil {
@@ -166,167 +431,429 @@ func (r *Reconciler) Run(ctx context.Context) {
 
 	for {
 		select {
-		case <-ticker.C:
-			r.logger.Info("executing reconciler loop",
+		case <-timer.C:
+			r.reconcileLoop(ctx)
+
+			timer.Reset(r.nextLoopDelay(loopRand))
+
+		case <-scheduleTicker.C:
+			r.reconcileDueGroups(ctx)
+
+		case <-ctx.Done():
+			r.logger.Info("shutting down reconciler",
 				zap.String("time", time.Now().UTC().Format(time.RFC3339)),
 			)
 
-			if r.locker != nil {
-				isLead, err := r.locker.AcquireLead()
-				if err != nil {
-					r.logger.Error("error checking for leader lock", zap.Error(err))
-					continue
-				}
+			return
+		}
+	}
+}
 
-				if !isLead {
-					r.logger.Debug("not leader, skipping loop")
-					continue
-				}
-			}
+// reconcileLoop runs a full reconciliation of governor groups, memberships, application assignments and users
+func (r *Reconciler) reconcileLoop(ctx context.Context) {
+	ctx, span := startSpan(ctx, "ReconcileLoop")
+	defer span.End()
 
-			ctx = auctx.WithAuditEvent(ctx, auditevent.NewAuditEvent(
-				"", // eventType to be populated later
-				auditevent.EventSource{
-					Type:  "local",
-					Value: "ReconcileLoop",
-					Extra: map[string]interface{}{
-						"governor.url": r.governorClient.URL(),
-					},
-				},
-				auditevent.OutcomeSucceeded,
-				map[string]string{
-					"event": "reconciler",
-				},
-				"gov-okta-addon",
-			))
-
-			groups, err := r.governorClient.Groups(ctx)
-			if err != nil {
-				r.logger.Error("error listing group", zap.Error(err))
-				continue
-			}
+	r.logger.Info("executing reconciler loop",
+		zap.String("time", time.Now().UTC().Format(time.RFC3339)),
+	)
 
-			r.logger.Debug("got groups response", zap.Any("groups list", groups))
+	// result and runErr are recorded in the status when the loop returns
+	result := RunResultFailed
 
-			// collect a map of okta group ids to governor groups so we don't have to
-			// go back to the okta API for this data and risk getting throttled
-			groupMap := map[string]*v1alpha1.Group{}
+	var runErr error
 
-			for _, g := range groups {
-				logger := r.logger.With(zap.String("governor.group.id", g.ID), zap.String("governor.group.slug", g.Slug))
+	pauseGeneration := r.pause.current()
 
-				groupDetails, err := r.governorClient.Group(ctx, g.ID, false)
-				if err != nil {
-					logger.Error("error getting governor group details", zap.Error(err))
-					continue
-				}
+	ctx = withRunCounts(ctx, r.status.begin(r.now()))
 
-				logger.Debug("got governor group response", zap.Any("group details", groupDetails))
+	defer func() {
+		run := r.status.finish(r.now(), result, runErr)
+		r.writeRunCompletedEvent(ctx, run)
 
-				oktaGroupID, err := r.groupExists(ctx, g.ID)
-				if err != nil {
-					logger.Error("error reconciling governor group exists")
-					continue
-				}
+		if result != RunResultNoop && result != RunResultNotLeader && result != RunResultCircuitOpen {
+			r.pilot.finishLoop()
+		}
+	}()
 
-				groupMap[oktaGroupID] = groupDetails
+	if r.locker != nil {
+		isLead, err := r.locker.AcquireLead()
+		if err != nil {
+			r.logger.Error("error checking for leader lock", zap.Error(err))
 
-				if err := r.GroupMembership(ctx, g.ID, oktaGroupID); err != nil {
-					logger.Error("error reconciling governor group membership")
-					continue
-				}
-			}
+			runErr = err
 
-			if err := r.reconcileGroupApplicationAssignments(ctx, groupMap); err != nil {
-				r.logger.Error("error reconciling group application links", zap.Error(err))
-			}
+			return
+		}
 
-			// reconcile users
-			govUsers, err := r.governorClient.UsersV2(ctx, map[string][]string{"deleted": {"true"}})
-			if err != nil {
-				r.logger.Error("error listing governor users", zap.Error(err))
-				continue
-			}
+		if !isLead {
+			r.logger.Debug("not leader, skipping loop")
 
-			r.logger.Debug("got governor users (including deleted)", zap.Any("num.governor.users", len(govUsers)))
+			result = RunResultNotLeader
 
-			oktaUsers, err := r.oktaClient.ListUsers(ctx)
-			if err != nil {
-				r.logger.Error("error listing okta users", zap.Error(err))
-				continue
-			}
+			// another replica may have reconciled in the meantime, so don't trust the last snapshot
+			r.lastSnapshot = ""
 
-			// collect a map of okta user emails to okta user details which will be used to reconcile users
-			oktaUserMap := map[string]*okta.UserDetails{}
+			return
+		}
+	}
 
-			for _, oktaUser := range oktaUsers {
-				details, err := okta.UserDetailsFromOktaUser(oktaUser)
-				if err != nil {
-					r.logger.Error("error getting okta user details from profile", zap.Error(err))
-				}
+	if r.circuitOpen() {
+		result = RunResultCircuitOpen
 
-				oktaUserMap[details.Email] = details
-			}
+		return
+	}
 
-			r.logger.Debug("got okta users", zap.Any("okta.users", oktaUserMap))
+	ctx = r.withReconcileAuditEvent(ctx, "ReconcileLoop")
 
-			if err := r.reconcileUsers(ctx, govUsers, oktaUserMap); err != nil {
-				r.logger.Error("error reconciling users", zap.Error(err))
-				continue
-			}
+	groups, err := callOp(ctx, r, "governor.Groups", func(ctx context.Context) ([]*v1alpha1.Group, error) {
+		return r.governorClient.Groups(ctx)
+	})
+	if err != nil {
+		r.logger.Error("error listing group", zap.Error(err))
 
-			r.logger.Info("finished reconciler loop",
-				zap.String("time", time.Now().UTC().Format(time.RFC3339)),
-			)
+		runErr = err
 
-		case <-ctx.Done():
-			r.logger.Info("shutting down reconciler",
+		return
+	}
+
+	r.logger.Debug("got groups response", redact.Any("groups list", groups))
+
+	// clean tracks if the whole loop succeeded, only then is the snapshot safe to compare against
+	clean := true
+
+	groupDetailsList := make([]*v1alpha1.Group, 0, len(groups))
+
+	for _, g := range groups {
+		if r.circuitOpen() {
+			result = RunResultCircuitOpen
+
+			return
+		}
+
+		logger := r.logger.With(zap.String("governor.group.id", g.ID), zap.String("governor.group.slug", g.Slug))
+
+		groupDetails, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+			return r.governorClient.Group(ctx, g.ID, false)
+		})
+		if err != nil {
+			logger.Error("error getting governor group details", zap.Error(err))
+
+			r.groupFailed(logger, g.ID, g.Slug, err)
+
+			clean = false
+
+			continue
+		}
+
+		logger.Debug("got governor group response", redact.Any("group details", groupDetails))
+
+		groupDetailsList = append(groupDetailsList, groupDetails)
+	}
+
+	r.schedule.update(groupDetailsList, r.now())
+
+	var snapshot string
+
+	if r.snapshotShortCircuit {
+		snapshot, err = r.governorSnapshot(ctx, groupDetailsList)
+		if err != nil {
+			r.logger.Warn("error getting governor snapshot, not short-circuiting", zap.Error(err))
+		}
+
+		if r.shortCircuit(snapshot) {
+			incCounter(ctx, noopRunsCounter)
+
+			result = RunResultNoop
+
+			r.logger.Info("finished no-op reconciler loop, governor state is unchanged and no okta events were handled",
+				zap.String("governor.snapshot", snapshot),
+				zap.Int("num.governor.groups", len(groupDetailsList)),
 				zap.String("time", time.Now().UTC().Format(time.RFC3339)),
 			)
 
 			return
 		}
+
+		// the okta events consumed by the short-circuit check are only handled once this loop finishes clean, until
+		// then the next loop can't be short-circuited
+		r.lastSnapshot = ""
 	}
+
+	// collect a map of okta group ids to governor groups so we don't have to
+	// go back to the okta API for this data and risk getting throttled
+	groupMap := map[string]*v1alpha1.Group{}
+
+	for _, groupDetails := range groupDetailsList {
+		if r.circuitOpen() {
+			result = RunResultCircuitOpen
+
+			return
+		}
+
+		logger := r.logger.With(zap.String("governor.group.id", groupDetails.ID), zap.String("governor.group.slug", groupDetails.Slug))
+
+		// quarantined groups weren't reconciled, so the loop isn't clean
+		if r.quarantine.skip(groupDetails.ID, r.now()) {
+			logger.Debug("skipping quarantined governor group")
+
+			clean = false
+
+			continue
+		}
+
+		oktaGroupID, err := r.groupExists(ctx, groupDetails)
+		if err != nil {
+			logger.Error("error reconciling governor group exists")
+
+			r.groupFailed(logger, groupDetails.ID, groupDetails.Slug, err)
+
+			clean = false
+
+			continue
+		}
+
+		groupMap[oktaGroupID] = groupDetails
+
+		runCountsFrom(ctx).groupChecked()
+
+		if r.schedule.scheduled(groupDetails.ID) {
+			logger.Debug("skipping membership for group with an interval override, it is reconciled on its own schedule")
+			continue
+		}
+
+		res, err := r.GroupMembership(ctx, groupDetails.ID, oktaGroupID)
+
+		runCountsFrom(ctx).membership(res)
+
+		if err != nil {
+			logger.Error("error reconciling governor group membership", zap.Error(err))
+
+			r.groupFailed(logger, groupDetails.ID, groupDetails.Slug, err)
+
+			clean = false
+
+			continue
+		}
+
+		r.quarantine.succeeded(groupDetails.ID)
+
+		if res.Changed() || len(res.Skipped) > 0 {
+			logger.Info("reconciled governor group membership", zap.Object("membership", res))
+		}
+	}
+
+	appResults, err := r.reconcileGroupApplicationAssignments(ctx, groupMap)
+
+	r.status.appAssignmentsFailed(appResults.Failures())
+
+	if err != nil {
+		r.logger.Error("error reconciling group application links", zap.Error(err), zap.Strings("failed.orgs", appResults.FailedOrgs()))
+
+		runErr = err
+
+		clean = false
+	}
+
+	if err := r.deletePendingGroups(ctx, groupDetailsList); err != nil {
+		r.logger.Error("error deleting okta groups pending deletion", zap.Error(err))
+
+		runErr = err
+
+		clean = false
+	}
+
+	if err := r.clearOrphanGroups(ctx, groups); err != nil {
+		r.logger.Error("error clearing the governor id of orphaned okta groups", zap.Error(err))
+
+		runErr = err
+
+		clean = false
+	}
+
+	// reconcile users
+	govUsers, err := callOp(ctx, r, "governor.UsersV2", func(ctx context.Context) ([]*v1beta1.User, error) {
+		return r.governorClient.UsersV2(ctx, map[string][]string{"deleted": {"true"}})
+	})
+	if err != nil {
+		r.logger.Error("error listing governor users", zap.Error(err))
+
+		runErr = err
+
+		return
+	}
+
+	r.logger.Debug("got governor users (including deleted)", zap.Any("num.governor.users", len(govUsers)))
+
+	r.pilot.resolve(groupDetailsList, govUsers)
+
+	oktaUsers, err := callOp(ctx, r, "okta.ListUsers", func(ctx context.Context) ([]*okt.User, error) {
+		return r.oktaClient.ListUsers(ctx, r.listUsersOpts...)
+	})
+	if err != nil {
+		r.logger.Error("error listing okta users", zap.Error(err))
+
+		runErr = err
+
+		return
+	}
+
+	// collect the okta user details by governor id and email which will be used to reconcile users
+	oktaUserDetails := make([]*okta.UserDetails, 0, len(oktaUsers))
+
+	for _, oktaUser := range oktaUsers {
+		if r.nonHumanAccount(ctx, r.logger, oktaUser) {
+			continue
+		}
+
+		details, err := okta.UserDetailsFromOktaUser(oktaUser)
+		if err != nil {
+			r.logger.Error("error getting okta user details from profile", zap.Error(err))
+			continue
+		}
+
+		oktaUserDetails = append(oktaUserDetails, details)
+	}
+
+	r.logger.Debug("got okta users", redact.Any("okta.users", oktaUserDetails))
+
+	if err := r.reconcileUsers(ctx, govUsers, newOktaUserIndex(oktaUserDetails, r.userMatchKey)); err != nil {
+		r.logger.Error("error reconciling users", zap.Error(err))
+
+		runErr = err
+
+		return
+	}
+
+	if clean {
+		// mutations skipped while paused still need to be applied, so the next loop can't be short-circuited
+		if r.pause.unpausedSince(pauseGeneration) {
+			r.lastSnapshot = snapshot
+		}
+
+		result = RunResultSucceeded
+	} else {
+		result = RunResultPartial
+	}
+
+	if r.invariants != nil {
+		r.checkInvariants(ctx, groupDetailsList, govUsers, oktaUsers)
+	}
+
+	r.verifyAppliedChanges(ctx)
+
+	r.logger.Info("finished reconciler loop",
+		zap.String("time", time.Now().UTC().Format(time.RFC3339)),
+	)
+}
+
+// withReconcileAuditEvent returns a context with a new audit event for a local reconcile run
+func (r *Reconciler) withReconcileAuditEvent(ctx context.Context, source string) context.Context {
+	return auctx.WithAuditEvent(ctx, auditevent.NewAuditEvent(
+		"", // eventType to be populated later
+		auditevent.EventSource{
+			Type:  "local",
+			Value: source,
+			Extra: map[string]interface{}{
+				"governor.url": r.governorClient.URL(),
+			},
+		},
+		auditevent.OutcomeSucceeded,
+		map[string]string{
+			"event": "reconciler",
+		},
+		"gov-okta-addon",
+	))
 }
 
 // reconcileGroupApplicationAssignments reconciles the application assignments for all groups.  It takes a map
 // of okta group ids to governor groups and does it's best to make as few calls to okta as possible to prevent
 // throttling.  A call to this function without any changes will result in n+1 calls to the Okta API where
-// n is the number of Okta github cloud applications.
-func (r *Reconciler) reconcileGroupApplicationAssignments(ctx context.Context, groupMap map[string]*v1alpha1.Group) error {
+// n is the number of Okta github cloud applications.  It returns the result of each org, a failing org or
+// assignment change doesn't stop the others and the returned error joins their errors.
+func (r *Reconciler) reconcileGroupApplicationAssignments(ctx context.Context, groupMap map[string]*v1alpha1.Group) (AppAssignmentResults, error) {
 	// get the github cloud apps first from okta
-	oktaAppOrgs, err := r.oktaClient.GithubCloudApplications(ctx)
+	oktaAppOrgs, err := callOp(ctx, r, "okta.GithubCloudApplications", func(ctx context.Context) (map[string]string, error) {
+		return r.oktaClient.GithubCloudApplications(ctx)
+	})
 	if err != nil {
 		r.logger.Error("error listing okta github cloud applications", zap.Error(err))
-		return err
+		return nil, err
 	}
 
-	r.logger.Debug("got okta github cloud orgs", zap.Any("github.orgs", oktaAppOrgs))
+	r.logger.Debug("got okta github cloud orgs", redact.Any("github.orgs", oktaAppOrgs))
 
-	govOrgs, err := r.governorClient.Organizations(ctx)
+	govOrgs, err := callOp(ctx, r, "governor.Organizations", func(ctx context.Context) ([]*v1alpha1.Organization, error) {
+		return r.governorClient.Organizations(ctx)
+	})
 	if err != nil {
 		r.logger.Error("error listing governor organizations", zap.Error(err))
-		return err
+		return nil, err
 	}
 
-	r.logger.Debug("got governor organizations", zap.Any("governor.orgs", govOrgs))
+	r.logger.Debug("got governor organizations", redact.Any("governor.orgs", govOrgs))
+
+	results := make(AppAssignmentResults, 0, len(oktaAppOrgs))
+	errs := []error{}
 
 	// for each of the okta github cloud applications, get the groups assigned to the application
 	for org, appID := range oktaAppOrgs {
 		logger := r.logger.With(zap.String("okta.app.org", org), zap.String("okta.app.id", appID))
 
+		result := newAppAssignmentResult(org, appID)
+		results = append(results, result)
+
 		if !containsOrg(org, govOrgs) {
 			logger.Info("skipping okta github org not managed by governor")
+
+			result.Reason = AppAssignmentReasonUnmanaged
+
 			continue
 		}
 
-		assignments, err := r.oktaClient.ListGroupApplicationAssignment(ctx, appID)
+		mode := r.appAssignmentMode(org)
+		if mode == AppAssignmentIgnore {
+			logger.Info("skipping okta github org with ignored application assignments")
+
+			result.Reason = AppAssignmentReasonIgnored
+
+			continue
+		}
+
+		list, err := callOp(ctx, r, "okta.ListApplicationGroupAssignments", func(ctx context.Context) ([]*okt.ApplicationGroupAssignment, error) {
+			return r.oktaClient.ListApplicationGroupAssignments(ctx, appID)
+		})
 		if err != nil {
-			logger.Error("error listing okta group assigned to okta application")
-			return err
+			logger.Error("error listing okta group assigned to okta application", zap.Error(err))
+
+			result.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrAppAssignmentFailed, org, err))
+
+			continue
+		}
+
+		logger.Debug("list of groups for application", redact.Any("groups", list))
+
+		assignments := make(map[string]*okt.ApplicationGroupAssignment, len(list))
+		for _, a := range list {
+			assignments[a.Id] = a
 		}
 
-		logger.Debug("list of groups for application", zap.Any("groups", assignments))
+		settings := r.appAssignmentSettings(org)
+
+		failed := func(groupDetails *v1alpha1.Group, oktaGID, change string, err error) {
+			result.Failed = append(result.Failed, AppAssignmentFailure{
+				Org:               org,
+				OktaAppID:         appID,
+				GovernorGroupID:   groupDetails.ID,
+				GovernorGroupSlug: groupDetails.Slug,
+				OktaGroupID:       oktaGID,
+				Change:            change,
+				Error:             err.Error(),
+			})
+
+			errs = append(errs, fmt.Errorf("%w: %s: %s %s: %w", ErrAppAssignmentFailed, org, change, groupDetails.Slug, err))
+		}
 
 		// foreach governor/okta group, check if should be assigned to the app and reconcile
 		for oktaGID, groupDetails := range groupMap {
@@ -344,32 +871,85 @@ func (r *Reconciler) reconcileGroupApplicationAssignments(ctx context.Context, g
 			if contains(slugs, org) {
 				logger.Debug("group org list contains app org slug, ensuring group is assigned to okta app")
 
-				// ensure it exists in the app in okta
-				if contains(assignments, oktaGID) {
+				// ensure it exists in the app in okta with the assignment settings
+				if a, ok := assignments[oktaGID]; ok {
+					if !settings.Drifted(a) {
+						continue
+					}
+
+					if r.dryRun() || r.detectOnlyGroup(ctx, groupDetails.ID, groupDetails) {
+						logger.Info("SKIP updating drifted okta application group assignment", zap.String("okta.app.id", appID))
+
+						result.Skipped = append(result.Skipped, oktaGID)
+
+						continue
+					}
+
+					if err := r.doOp(ctx, "okta.AssignGroupToApplication", func(ctx context.Context) error {
+						return r.oktaClient.AssignGroupToApplication(ctx, appID, oktaGID, settings)
+					}); err != nil {
+						logger.Error("error updating drifted okta application group assignment", zap.String("okta.app.id", appID), zap.Error(err))
+
+						failed(groupDetails, oktaGID, appAssignmentChangeUpdate, err)
+
+						continue
+					}
+
+					logger.Info("updated drifted okta application group assignment", zap.String("okta.app.id", appID))
+
+					incCounter(ctx, groupsApplicationAssignmentUpdatedCounter)
+
+					result.Updated = append(result.Updated, oktaGID)
+
+					before := okta.CurrentAppGroupAssignment(a)
+
+					if err := r.writeMutationEvent(ctx, auctx.GroupApplicationUpdate{
+						GovernorGroupSlug:  groupDetails.Slug,
+						GovernorGroupID:    groupDetails.ID,
+						GovernorAppSlug:    org,
+						OktaGroupID:        oktaGID,
+						OktaAppID:          appID,
+						OktaAppSlug:        org,
+						OktaPriorityBefore: formatPriority(before.Priority),
+						OktaPriorityAfter:  formatPriority(settings.Priority),
+					}, before, settings); err != nil {
+						logger.Error("error writing audit event", zap.Error(err))
+					}
+
 					continue
 				}
 
 				// assign group to the application
-				if r.dryrun {
+				if r.dryRun() || r.detectOnlyGroup(ctx, groupDetails.ID, groupDetails) {
 					logger.Info("SKIP assigning okta group to okta application", zap.String("okta.app.id", appID))
+
+					result.Skipped = append(result.Skipped, oktaGID)
+
 					continue
 				}
 
-				if err := r.oktaClient.AssignGroupToApplication(ctx, appID, oktaGID); err != nil {
-					logger.Error("error assigning okta group to okta application", zap.String("okta.app.id", appID))
-					return err
+				if err := r.doOp(ctx, "okta.AssignGroupToApplication", func(ctx context.Context) error {
+					return r.oktaClient.AssignGroupToApplication(ctx, appID, oktaGID, settings)
+				}); err != nil {
+					logger.Error("error assigning okta group to okta application", zap.String("okta.app.id", appID), zap.Error(err))
+
+					failed(groupDetails, oktaGID, appAssignmentChangeAssign, err)
+
+					continue
 				}
 
-				groupsApplicationAssignedCounter.Inc()
+				incCounter(ctx, groupsApplicationAssignedCounter)
 
-				if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "GroupApplicationAdd", map[string]string{
-					"governor.group.slug": groupDetails.Slug,
-					"governor.group.id":   groupDetails.ID,
-					"governor.app.slug":   org,
-					"okta.group.id":       oktaGID,
-					"okta.app.id":         appID,
-					"okta.app.slug":       org,
-				}); err != nil {
+				result.Assigned = append(result.Assigned, oktaGID)
+
+				if err := r.writeMutationEvent(ctx, auctx.GroupApplicationAdd{
+					GovernorGroupSlug: groupDetails.Slug,
+					GovernorGroupID:   groupDetails.ID,
+					GovernorAppSlug:   org,
+					OktaGroupID:       oktaGID,
+					OktaAppID:         appID,
+					OktaAppSlug:       org,
+				}, nil, map[string]string{"okta.app.id": appID, "okta.group.id": oktaGID}); err != nil {
 					logger.Error("error writing audit event", zap.Error(err))
 				}
 
@@ -379,51 +959,91 @@ func (r *Reconciler) reconcileGroupApplicationAssignments(ctx context.Context, g
 			logger.Debug("group org list does not contain app org slug, ensuring group is not assigned to okta app")
 
 			// ensure it doesn't exist in the okta app
-			if !contains(assignments, oktaGID) {
+			if _, ok := assignments[oktaGID]; !ok {
+				continue
+			}
+
+			if mode == AppAssignmentAssignOnly {
+				logger.Info("SKIP removing assignment of okta group from assign-only okta application", zap.String("okta.app.id", appID))
+
+				result.Skipped = append(result.Skipped, oktaGID)
+
 				continue
 			}
 
 			// remove group from the application
-			if r.dryrun || r.skipDelete {
+			if r.dryRun() || r.skipDelete || r.detectOnlyGroup(ctx, groupDetails.ID, groupDetails) {
 				logger.Info("SKIP removing assignment of okta group from okta application", zap.String("okta.app.id", appID))
-			} else {
-				if err := r.oktaClient.RemoveApplicationGroupAssignment(ctx, appID, oktaGID); err != nil {
-					logger.Error("error removing okta group from okta application", zap.String("okta.app.id", appID))
-					return err
-				}
 
-				groupsApplicationUnassignedCounter.Inc()
+				r.status.pendingDeletion(PendingDeletion{
+					Type:            "GroupApplicationRemove",
+					GovernorGroupID: groupDetails.ID,
+					OktaGroupID:     oktaGID,
+					OktaAppID:       appID,
+				})
 
-				if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "GroupApplicationRemove", map[string]string{
-					"governor.group.slug": groupDetails.Slug,
-					"governor.group.id":   groupDetails.ID,
-					"governor.app.slug":   org,
-					"okta.group.id":       oktaGID,
-					"okta.app.id":         appID,
-					"okta.app.slug":       org,
-				}); err != nil {
-					logger.Error("error writing audit event", zap.Error(err))
-				}
+				result.Skipped = append(result.Skipped, oktaGID)
+
+				continue
+			}
+
+			if err := r.doOp(ctx, "okta.RemoveApplicationGroupAssignment", func(ctx context.Context) error {
+				return r.oktaClient.RemoveApplicationGroupAssignment(ctx, appID, oktaGID)
+			}); err != nil {
+				logger.Error("error removing okta group from okta application", zap.String("okta.app.id", appID), zap.Error(err))
+
+				failed(groupDetails, oktaGID, appAssignmentChangeRemove, err)
+
+				continue
+			}
+
+			incCounter(ctx, groupsApplicationUnassignedCounter)
+
+			result.Removed = append(result.Removed, oktaGID)
+
+			if err := r.writeMutationEvent(ctx, auctx.GroupApplicationRemove{
+				GovernorGroupSlug: groupDetails.Slug,
+				GovernorGroupID:   groupDetails.ID,
+				GovernorAppSlug:   org,
+				OktaGroupID:       oktaGID,
+				OktaAppID:         appID,
+				OktaAppSlug:       org,
+			}, map[string]string{"okta.app.id": appID, "okta.group.id": oktaGID}, nil); err != nil {
+				logger.Error("error writing audit event", zap.Error(err))
 			}
 		}
+
+		if !r.pushGroups(org) {
+			continue
+		}
+
+		if err := r.reconcilePushGroups(ctx, logger, org, appID, mode, groupMap, govOrgs, result, failed); err != nil {
+			result.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrAppAssignmentFailed, org, err))
+		}
 	}
 
-	return nil
+	// the okta applications are listed in a map, sort the results for stable reports
+	slices.SortFunc(results, func(a, b *AppAssignmentResult) int { return strings.Compare(a.Org, b.Org) })
+
+	return results, errors.Join(errs...)
 }
 
-// reconcileUsers gets a list of governor users and a map of user details from okta, and
+// reconcileUsers gets a list of governor users and an index of user details from okta, and
 // updates the okta users to match the governor users. It also deletes any okta user that
 // has been deleted in governor. We are specifically targeting users who have existed in
 // governor and have been deleted, and not just users who do not exist in governor.
-func (r *Reconciler) reconcileUsers(ctx context.Context, govUsers []*v1beta1.User, oktaUserMap map[string]*okta.UserDetails) error {
-	if govUsers == nil || oktaUserMap == nil {
+func (r *Reconciler) reconcileUsers(ctx context.Context, govUsers []*v1beta1.User, oktaUsers *oktaUserIndex) error {
+	if govUsers == nil || oktaUsers == nil {
 		return ErrUserListEmpty
 	}
 
 	r.logger.Debug("reconciling users")
 
+	oktaUsers.claim(govUsers)
+
 	for _, u := range govUsers {
-		if u.Status.String == v1alpha1.UserStatusPending {
+		if r.skipUserStatus(u.Status.String) {
 			continue
 		}
 
@@ -434,42 +1054,23 @@ func (r *Reconciler) reconcileUsers(ctx context.Context, govUsers []*v1beta1.Use
 			zap.String("governor.user.status", u.Status.String),
 		)
 
-		if userDeletedV2(u) {
+		if userDeletedV2(u, r.now()) {
 			logger.Debug("got deleted governor user")
 
 			// user has been deleted in governor, so delete it in okta if still there
-			if userDetails, found := oktaUserMap[u.Email]; found {
-				if r.dryrun || r.skipDelete {
+			if userDetails, found := oktaUsers.lookupPrimary(u.ID, u.ExternalID.String, u.Email); found {
+				if r.dryRun() || r.skipDelete {
 					logger.Info("SKIP deleting okta user", zap.String("okta.user.id", userDetails.ID))
+
+					r.status.pendingDeletion(PendingDeletion{Type: "UserDelete", OktaUserID: userDetails.ID})
+
 					continue
 				}
 
-				// TODO: re-enable when we feel confident, or when we dry-run
-				// if err := r.oktaClient.DeactivateUser(ctx, oktaID); err != nil {
-				// 	logger.Error("error deactivating user", zap.String("okta.user.id", oktaID), zap.Error(err))
-				// 	continue
-				// }
-
-				// if err := r.oktaClient.ClearUserSessions(ctx, oktaID); err != nil {
-				// 	logger.Error("error clearing user sessions", zap.String("okta.user.id", oktaID), zap.Error(err))
-				// 	continue
-				// }
-
-				// if err := r.oktaClient.DeleteUser(ctx, oktaID); err != nil {
-				// 	logger.Error("error deleting user", zap.Error(err))
-				// 	continue
-				// }
-				//
-				// logger.Info("successfully deleted okta user")
-
-				// if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "UserDelete", map[string]string{
-				// 	"governor.user.email": u.Email,
-				// 	"governor.user.id":    u.ID,
-				//  "governor.external_id":    u.ID,
-				// 	"okta.user.id":        oktaID,
-				// }); err != nil {
-				// 	logger.Error("error writing audit event", zap.Error(err))
-				// }
+				// the okta user is only deleted by the user delete event, the loop removes them from the managed groups
+				if err := r.removeUserManagedGroups(ctx, logger, u.ID, u.Email, userDetails.ID); err != nil {
+					logger.Warn("error removing deleted user from managed okta groups", zap.Error(err))
+				}
 
 				logger.Debug("skipping user deletion in okta")
 			} else {
@@ -479,36 +1080,16 @@ func (r *Reconciler) reconcileUsers(ctx context.Context, govUsers []*v1beta1.Use
 			continue
 		}
 
-		if userDetails, found := oktaUserMap[u.Email]; found {
-			// check if suspended user
-			if u.Status.String == v1alpha1.UserStatusSuspended && userDetails.Status == "ACTIVE" {
-				if r.dryrun {
-					logger.Info("SKIP suspending okta user")
-					continue
-				}
-
-				if err := r.oktaClient.SuspendUser(ctx, userDetails.ID); err != nil {
-					logger.Error("error suspending okta user", zap.Error(err))
-					continue
-				}
-
-				continue
+		if userDetails, found := oktaUsers.lookup(u.ID, u.ExternalID.String, u.Email); found {
+			if r.userGovernorID && userDetails.GovernorID != u.ID {
+				r.setUserGovernorID(ctx, logger, u.ID, userDetails)
 			}
 
-			// check if un-suspended user
-			if u.Status.String == v1alpha1.UserStatusActive && userDetails.Status == "SUSPENDED" {
-				if r.dryrun {
-					logger.Info("SKIP un-suspending okta user")
-					continue
-				}
-
-				if err := r.oktaClient.UnsuspendUser(ctx, userDetails.ID); err != nil {
-					logger.Error("error un-suspending okta user", zap.Error(err))
-					continue
-				}
-
-				continue
+			if !strings.EqualFold(userDetails.Email, u.Email) {
+				r.userEmailDrift(ctx, logger, u, userDetails)
 			}
+
+			r.reconcileUserLifecycle(ctx, logger, u, userDetails)
 		}
 	}
 
@@ -516,17 +1097,17 @@ func (r *Reconciler) reconcileUsers(ctx context.Context, govUsers []*v1beta1.Use
 }
 
 // groupExists ensures the governor group exists in okta
-func (r *Reconciler) groupExists(ctx context.Context, id string) (string, error) {
-	logger := r.logger.With(zap.String("governor.group.id", id))
+func (r *Reconciler) groupExists(ctx context.Context, group *v1alpha1.Group) (string, error) {
+	logger := r.logger.With(zap.String("governor.group.id", group.ID))
 
-	oktaGroup, err := r.oktaClient.GetGroupByGovernorID(ctx, id)
+	oktaGroup, err := r.oktaGroupID(ctx, logger, group)
 	if err != nil {
 		if !errors.Is(err, okta.ErrGroupsNotFound) {
 			logger.Error("error getting okta group by governor id", zap.Error(err))
 			return "", err
 		}
 
-		oktaGID, err := r.GroupCreate(ctx, id)
+		oktaGID, err := r.GroupCreate(ctx, group.ID)
 		if err != nil {
 			return "", err
 		}
@@ -534,7 +1115,7 @@ func (r *Reconciler) groupExists(ctx context.Context, id string) (string, error)
 		return oktaGID, nil
 	}
 
-	logger.Debug("got okta group", zap.Any("okta.group", oktaGroup))
+	logger.Debug("got okta group", zap.String("okta.group", oktaGroup))
 
 	return oktaGroup, nil
 }
@@ -0,0 +1,86 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/okta/okta-sdk-golang/v2/okta/query"
+	"go.uber.org/zap"
+)
+
+// EventlogReplayResult counts the replayed okta log events by event type, and the events whose handler failed
+type EventlogReplayResult struct {
+	Events map[string]int `json:"events"`
+	Failed map[string]int `json:"failed"`
+}
+
+// ReplayEventlog fetches the okta log events published between since and until and runs them through the handlers
+// of the event log poller, ie. to catch up on the events published while the addon was down.  Only the given event
+// types are replayed, all of the handled types when none are given.  A failed event doesn't stop the replay, the
+// failures are returned as ErrEventlogReplayFailed once every event was replayed.
+func (r *Reconciler) ReplayEventlog(ctx context.Context, since, until time.Time, eventTypes ...string) (*EventlogReplayResult, error) {
+	if !until.After(since) {
+		return nil, ErrEventlogReplayWindowInvalid
+	}
+
+	handled := r.eventlogTypes()
+
+	if len(eventTypes) == 0 {
+		eventTypes = handled
+	}
+
+	for _, t := range eventTypes {
+		if !contains(handled, t) {
+			return nil, fmt.Errorf("%w: %s", ErrEventTypeNotHandled, t)
+		}
+	}
+
+	logger := r.logger.With(zap.Time("eventlog.since", since), zap.Time("eventlog.until", until), zap.Strings("okta.event.types", eventTypes))
+
+	events, err := callOp(ctx, r, "okta.GetLogsBounded", func(ctx context.Context) ([]*okta.LogEvent, error) {
+		return r.oktaClient.GetLogsBounded(ctx, since.UTC(), until.UTC(), &query.Params{Filter: eventTypesFilter(eventTypes)})
+	})
+	if err != nil {
+		logger.Error("error getting okta log events", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("replaying okta log events", zap.Int("okta.events", len(events)))
+
+	res := &EventlogReplayResult{Events: map[string]int{}, Failed: map[string]int{}}
+	failed := 0
+
+	for _, evt := range events {
+		// the filter is applied by okta, events of other types are left out in case it wasn't
+		if !contains(eventTypes, evt.EventType) {
+			continue
+		}
+
+		res.Events[evt.EventType]++
+
+		ctx := r.withEventlogAuditEvent(ctx, evt)
+		if ae := auctx.GetAuditEvent(ctx); ae != nil {
+			ae.Source.Extra["okta.event.replay"] = true
+		}
+
+		if err := r.handleOktaLogEvent(ctx, evt); err != nil {
+			logger.Warn("error replaying okta log event",
+				zap.String("okta.event.uuid", evt.Uuid),
+				zap.String("okta.event.type", evt.EventType),
+				zap.Error(err),
+			)
+
+			res.Failed[evt.EventType]++
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return res, fmt.Errorf("%w: %d", ErrEventlogReplayFailed, failed)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,106 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciler_ReplayEventlog(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		eventTypes []string
+		dryrun     bool
+		want       *EventlogReplayResult
+		wantStatus string
+		wantErr    error
+	}{
+		{
+			name:       "replay",
+			want:       &EventlogReplayResult{Events: map[string]int{"user.lifecycle.suspend": 1, "user.lifecycle.create": 1}, Failed: map[string]int{"user.lifecycle.create": 1}},
+			wantStatus: v1alpha1.UserStatusSuspended,
+			wantErr:    ErrEventlogReplayFailed,
+		},
+		{
+			name:       "event types",
+			eventTypes: []string{"user.lifecycle.suspend"},
+			want:       &EventlogReplayResult{Events: map[string]int{"user.lifecycle.suspend": 1}, Failed: map[string]int{}},
+			wantStatus: v1alpha1.UserStatusSuspended,
+		},
+		{
+			name:       "dry run",
+			eventTypes: []string{"user.lifecycle.suspend"},
+			dryrun:     true,
+			want:       &EventlogReplayResult{Events: map[string]int{"user.lifecycle.suspend": 1}, Failed: map[string]int{}},
+			wantStatus: v1alpha1.UserStatusActive,
+		},
+		{
+			name:       "unhandled event type",
+			eventTypes: []string{"user.account.update_profile"},
+			wantStatus: v1alpha1.UserStatusActive,
+			wantErr:    ErrEventTypeNotHandled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			o.AddUser("okta-1", "SUSPENDED", testOktaProfile("user-1@example.com"))
+			g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+
+			for _, e := range []struct {
+				eventType, target string
+				published         time.Time
+			}{
+				{"user.lifecycle.suspend", "okta-1", since.Add(time.Minute)},
+				// the okta user doesn't exist anymore
+				{"user.lifecycle.create", "okta-2", since.Add(2 * time.Minute)},
+				// outside of the replayed window
+				{"user.lifecycle.suspend", "okta-1", until.Add(time.Minute)},
+			} {
+				o.AddLogEvent(&okta.LogEvent{
+					Uuid:      e.eventType + "-" + e.target,
+					EventType: e.eventType,
+					Published: &e.published,
+					Target:    []*okta.LogTarget{{Id: e.target, Type: "User"}},
+				})
+			}
+
+			r, audit := newTestServerReconciler(t, o, g, WithDryRun(tt.dryrun))
+
+			got, err := r.ReplayEventlog(context.TODO(), since, until, tt.eventTypes...)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantStatus, g.User("user-1").Status)
+
+			if tt.wantStatus == v1alpha1.UserStatusSuspended {
+				assert.Contains(t, audit.String(), `"GovernorUserSuspend"`)
+				assert.Contains(t, audit.String(), `"okta.event.replay":true`)
+			}
+		})
+	}
+
+	r := New()
+
+	_, err := r.ReplayEventlog(context.TODO(), until, since)
+	assert.ErrorIs(t, err, ErrEventlogReplayWindowInvalid)
+}
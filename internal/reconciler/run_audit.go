@@ -0,0 +1,39 @@
+package reconciler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/metal-toolbox/auditevent"
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"go.uber.org/zap"
+)
+
+// writeRunCompletedEvent writes the ReconcileRunCompleted audit event summarizing a reconciler loop.  Loops skipped
+// on a replica that isn't the leader aren't written, the leader writes its own.
+func (r *Reconciler) writeRunCompletedEvent(ctx context.Context, run RunStatus) {
+	if r.auditEventWriter == nil || run.Result == RunResultNotLeader {
+		return
+	}
+
+	ctx = r.withReconcileAuditEvent(ctx, "ReconcileLoop")
+
+	if run.Result == RunResultFailed {
+		auctx.GetAuditEvent(ctx).Outcome = auditevent.OutcomeFailed
+	}
+
+	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, auctx.ReconcileRunCompleted{
+		Result:         run.Result,
+		Started:        run.Started.UTC().Format(time.RFC3339),
+		Duration:       run.Duration.String(),
+		GroupsChecked:  strconv.Itoa(run.Summary.GroupsChecked),
+		MembersAdded:   strconv.Itoa(run.Summary.MembersAdded),
+		MembersRemoved: strconv.Itoa(run.Summary.MembersRemoved),
+		UsersSuspended: strconv.Itoa(run.Summary.UsersSuspended),
+		Errors:         strconv.Itoa(run.Summary.Errors),
+		Error:          run.Error,
+	}); err != nil {
+		r.logger.Error("error writing audit event", zap.Error(err))
+	}
+}
@@ -0,0 +1,117 @@
+package reconciler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/metal-toolbox/auditevent"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// auditEventsOfType returns the audit events of the event type written to the buffer
+func auditEventsOfType(t *testing.T, buf *bytes.Buffer, eventType string) []*auditevent.AuditEvent {
+	t.Helper()
+
+	events := []*auditevent.AuditEvent{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		ae := &auditevent.AuditEvent{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), ae))
+
+		if ae.Type == eventType {
+			events = append(events, ae)
+		}
+	}
+
+	return events
+}
+
+func TestReconciler_writeRunCompletedEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		governorDown bool
+		wantResult   string
+		wantOutcome  string
+		wantTarget   map[string]string
+	}{
+		{
+			name:        "succeeded",
+			wantResult:  RunResultSucceeded,
+			wantOutcome: auditevent.OutcomeSucceeded,
+			wantTarget: map[string]string{
+				"run.result":          RunResultSucceeded,
+				"run.groups_checked":  "1",
+				"run.members_added":   "1",
+				"run.members_removed": "1",
+				"run.users_suspended": "0",
+				"run.errors":          "0",
+			},
+		},
+		{
+			name:         "failed",
+			governorDown: true,
+			wantResult:   RunResultFailed,
+			wantOutcome:  auditevent.OutcomeFailed,
+			wantTarget: map[string]string{
+				"run.result":          RunResultFailed,
+				"run.groups_checked":  "0",
+				"run.members_added":   "0",
+				"run.members_removed": "0",
+				"run.users_suspended": "0",
+				"run.errors":          "1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+			g.AddUser(&testserver.GovernorUser{ID: "user-2", ExternalID: "okta-2", Email: "user-2@example.com", Status: v1alpha1.UserStatusActive})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1", "user-2"}})
+
+			o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+			o.AddUser("okta-2", "ACTIVE", testOktaProfile("user-2@example.com"))
+			o.AddUser("okta-stray", "ACTIVE", testOktaProfile("stray@example.com"))
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-1", "okta-stray")
+
+			r, audit := newTestServerReconciler(t, o, g)
+
+			if tt.governorDown {
+				g.Close()
+			}
+
+			r.reconcileLoop(context.TODO())
+
+			runs := r.Status().Runs
+			require.Len(t, runs, 1)
+			assert.Equal(t, tt.wantResult, runs[0].Result, runs[0].Error)
+
+			events := auditEventsOfType(t, audit, "ReconcileRunCompleted")
+			require.Len(t, events, 1)
+
+			assert.Equal(t, tt.wantOutcome, events[0].Outcome)
+
+			for k, v := range tt.wantTarget {
+				assert.Equal(t, v, events[0].Target[k], k)
+			}
+
+			assert.Equal(t, runs[0].Duration.String(), events[0].Target["run.duration"])
+			assert.NotEmpty(t, events[0].Target["run.started"])
+			assert.Equal(t, runs[0].Error, events[0].Target["run.error"])
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package reconciler
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultGroupScheduleResolution is the default for how often groups with an interval override are checked
+	DefaultGroupScheduleResolution = 1 * time.Minute
+
+	// groupIntervalNoteKey is the key in a governor group note that overrides the reconcile interval for the group,
+	// ie. a line in the note like "gov-okta-addon.reconcile-interval: 5m"
+	groupIntervalNoteKey = "gov-okta-addon.reconcile-interval"
+)
+
+// groupIntervalOverride returns the reconcile interval override from the governor group note, if there is one
+func groupIntervalOverride(g *v1alpha1.Group) (time.Duration, bool) {
+	val, ok := groupNoteValue(g, groupIntervalNoteKey)
+	if !ok {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+
+	return d, true
+}
+
+// groupNoteValue returns the value of the first "key: value" (or "key=value") line for the key in a governor
+// group note
+func groupNoteValue(g *v1alpha1.Group, noteKey string) (string, bool) {
+	if g == nil || g.Group == nil {
+		return "", false
+	}
+
+	for _, line := range strings.Split(g.Note, "\n") {
+		key, val, found := strings.Cut(line, ":")
+		if !found {
+			key, val, found = strings.Cut(line, "=")
+		}
+
+		if !found || strings.TrimSpace(key) != noteKey {
+			continue
+		}
+
+		return strings.TrimSpace(val), true
+	}
+
+	return "", false
+}
+
+// groupSchedule tracks the next due time for each governor group with a reconcile interval override
+type groupSchedule struct {
+	mu        sync.Mutex
+	intervals map[string]time.Duration
+	next      map[string]time.Time
+}
+
+func newGroupSchedule() *groupSchedule {
+	return &groupSchedule{
+		intervals: map[string]time.Duration{},
+		next:      map[string]time.Time{},
+	}
+}
+
+// update refreshes the interval overrides from the list of governor groups. Groups that are new to the schedule
+// are due right away, groups that no longer have an override are dropped from the schedule.
+func (s *groupSchedule) update(groups []*v1alpha1.Group, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	intervals := map[string]time.Duration{}
+
+	for _, g := range groups {
+		if d, ok := groupIntervalOverride(g); ok {
+			intervals[g.ID] = d
+		}
+	}
+
+	for id := range s.next {
+		if _, ok := intervals[id]; !ok {
+			delete(s.next, id)
+		}
+	}
+
+	for id, d := range intervals {
+		next, ok := s.next[id]
+
+		switch {
+		case !ok:
+			s.next[id] = now
+		case d < s.intervals[id] && next.After(now.Add(d)):
+			// pull the next run in if the interval got shorter
+			s.next[id] = now.Add(d)
+		}
+	}
+
+	s.intervals = intervals
+}
+
+// scheduled returns true if the group has an interval override
+func (s *groupSchedule) scheduled(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.intervals[id]
+
+	return ok
+}
+
+// due returns the sorted list of group ids that are due to be reconciled
+func (s *groupSchedule) due(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := []string{}
+
+	for id, next := range s.next {
+		if !next.After(now) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// done sets the next due time for the group after it has been reconciled
+func (s *groupSchedule) done(id string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, ok := s.intervals[id]; ok {
+		s.next[id] = now.Add(d)
+	}
+}
+
+// reconcileDueGroups reconciles the existence, membership and application assignments of the groups
+// with an interval override that are due
+func (r *Reconciler) reconcileDueGroups(ctx context.Context) {
+	due := r.schedule.due(r.now())
+	if len(due) == 0 {
+		return
+	}
+
+	if r.locker != nil {
+		isLead, err := r.locker.AcquireLead()
+		if err != nil {
+			r.logger.Error("error checking for leader lock", zap.Error(err))
+			return
+		}
+
+		if !isLead {
+			r.logger.Debug("not leader, skipping scheduled groups")
+			return
+		}
+	}
+
+	r.logger.Info("reconciling scheduled groups", zap.Strings("governor.group.ids", due))
+
+	ctx, span := startSpan(ctx, "ScheduledGroupReconcile")
+	defer span.End()
+
+	ctx = r.withReconcileAuditEvent(ctx, "ScheduledGroupReconcile")
+
+	groupMap := map[string]*v1alpha1.Group{}
+
+	for _, id := range due {
+		logger := r.logger.With(zap.String("governor.group.id", id))
+
+		// failed groups are retried on their next interval rather than on every tick
+		r.schedule.done(id, r.now())
+
+		if r.quarantine.skip(id, r.now()) {
+			logger.Debug("skipping quarantined governor group")
+			continue
+		}
+
+		groupDetails, err := r.governorClient.Group(ctx, id, false)
+		if err != nil {
+			logger.Error("error getting governor group details", zap.Error(err))
+			r.groupFailure(logger, id, "", err)
+
+			continue
+		}
+
+		oktaGroupID, err := r.groupExists(ctx, groupDetails)
+		if err != nil {
+			logger.Error("error reconciling governor group exists")
+			r.groupFailure(logger, id, groupDetails.Slug, err)
+
+			continue
+		}
+
+		groupMap[oktaGroupID] = groupDetails
+
+		res, err := r.GroupMembership(ctx, id, oktaGroupID)
+		if err != nil {
+			logger.Error("error reconciling governor group membership", zap.Error(err))
+			r.groupFailure(logger, id, groupDetails.Slug, err)
+
+			continue
+		}
+
+		r.quarantine.succeeded(id)
+
+		logger.Debug("reconciled governor group membership", zap.Object("membership", res))
+	}
+
+	if res, err := r.reconcileGroupApplicationAssignments(ctx, groupMap); err != nil {
+		r.logger.Error("error reconciling group application links", zap.Error(err), zap.Strings("failed.governor.group.ids", res.FailedGroups()))
+	}
+}
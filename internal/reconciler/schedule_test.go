@@ -0,0 +1,97 @@
+package reconciler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func testGovGroupWithNote(t *testing.T, id, note string) *v1alpha1.Group {
+	t.Helper()
+
+	g := testGovGroup(t, id, nil, nil)
+	g.Note = note
+
+	return g
+}
+
+func Test_groupIntervalOverride(t *testing.T) {
+	tests := []struct {
+		name   string
+		note   string
+		want   time.Duration
+		wantOK bool
+	}{
+		{
+			name:   "example override",
+			note:   "gov-okta-addon.reconcile-interval: 5m",
+			want:   5 * time.Minute,
+			wantOK: true,
+		},
+		{
+			name:   "override with equals in a longer note",
+			note:   "prod admin access\ngov-okta-addon.reconcile-interval = 2m\nask #team for access",
+			want:   2 * time.Minute,
+			wantOK: true,
+		},
+		{
+			name: "no override",
+			note: "just a note",
+		},
+		{
+			name: "bad duration",
+			note: "gov-okta-addon.reconcile-interval: soon",
+		},
+		{
+			name: "negative duration",
+			note: "gov-okta-addon.reconcile-interval: -5m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := groupIntervalOverride(testGovGroupWithNote(t, "group-1", tt.note))
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_groupSchedule(t *testing.T) {
+	now := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	s := newGroupSchedule()
+
+	s.update([]*v1alpha1.Group{
+		testGovGroupWithNote(t, "critical", "gov-okta-addon.reconcile-interval: 5m"),
+		testGovGroupWithNote(t, "slow", "gov-okta-addon.reconcile-interval: 6h"),
+		testGovGroupWithNote(t, "default", ""),
+	}, now)
+
+	assert.True(t, s.scheduled("critical"))
+	assert.True(t, s.scheduled("slow"))
+	assert.False(t, s.scheduled("default"))
+
+	// new groups are due right away
+	assert.Equal(t, []string{"critical", "slow"}, s.due(now))
+
+	s.done("critical", now)
+	s.done("slow", now)
+	s.done("default", now)
+
+	assert.Empty(t, s.due(now.Add(time.Minute)))
+	assert.Equal(t, []string{"critical"}, s.due(now.Add(5*time.Minute)))
+	assert.Equal(t, []string{"critical", "slow"}, s.due(now.Add(6*time.Hour)))
+
+	// shortening the interval pulls the next run in, removing the override drops the group
+	s.update([]*v1alpha1.Group{
+		testGovGroupWithNote(t, "slow", "gov-okta-addon.reconcile-interval: 10m"),
+		testGovGroupWithNote(t, "critical", ""),
+	}, now.Add(time.Minute))
+
+	assert.False(t, s.scheduled("critical"))
+	assert.Empty(t, s.due(now.Add(10*time.Minute)))
+	assert.Equal(t, []string{"slow"}, s.due(now.Add(11*time.Minute)))
+}
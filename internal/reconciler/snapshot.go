@@ -0,0 +1,128 @@
+package reconciler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+)
+
+// snapshotGroup is the subset of a governor group that affects okta
+type snapshotGroup struct {
+	ID            string   `json:"id"`
+	Slug          string   `json:"slug"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Members       []string `json:"members"`
+	Organizations []string `json:"organizations"`
+}
+
+// snapshotUser is the subset of a governor user that affects okta
+type snapshotUser struct {
+	ID         string `json:"id"`
+	Email      string `json:"email"`
+	ExternalID string `json:"external_id"`
+	Status     string `json:"status"`
+	Deleted    bool   `json:"deleted"`
+}
+
+// governorSnapshot returns a hash of the governor state (groups, members, organizations and users) that
+// the reconciler loop acts on. The group details are passed in since the loop already has them.
+func (r *Reconciler) governorSnapshot(ctx context.Context, groups []*v1alpha1.Group) (string, error) {
+	orgs, err := r.governorClient.Organizations(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	users, err := r.governorClient.UsersV2(ctx, map[string][]string{"deleted": {"true"}})
+	if err != nil {
+		return "", err
+	}
+
+	return snapshotHash(groups, orgs, users)
+}
+
+// snapshotHash builds a hash of the given governor state that doesn't depend on the order of the lists
+func snapshotHash(groups []*v1alpha1.Group, orgs []*v1alpha1.Organization, users []*v1beta1.User) (string, error) {
+	snap := struct {
+		Groups        []snapshotGroup `json:"groups"`
+		Organizations []string        `json:"organizations"`
+		Users         []snapshotUser  `json:"users"`
+	}{
+		Groups:        make([]snapshotGroup, 0, len(groups)),
+		Organizations: make([]string, 0, len(orgs)),
+		Users:         make([]snapshotUser, 0, len(users)),
+	}
+
+	for _, g := range groups {
+		if g == nil || g.Group == nil {
+			continue
+		}
+
+		snap.Groups = append(snap.Groups, snapshotGroup{
+			ID:            g.ID,
+			Slug:          g.Slug,
+			Name:          g.Name,
+			Description:   g.Description,
+			Members:       sortedCopy(g.Members),
+			Organizations: sortedCopy(g.Organizations),
+		})
+	}
+
+	sort.Slice(snap.Groups, func(i, j int) bool { return snap.Groups[i].ID < snap.Groups[j].ID })
+
+	for _, o := range orgs {
+		if o == nil || o.Organization == nil {
+			continue
+		}
+
+		snap.Organizations = append(snap.Organizations, o.ID+"/"+o.Slug)
+	}
+
+	sort.Strings(snap.Organizations)
+
+	for _, u := range users {
+		if u == nil || u.User == nil {
+			continue
+		}
+
+		snap.Users = append(snap.Users, snapshotUser{
+			ID:         u.ID,
+			Email:      u.Email,
+			ExternalID: u.ExternalID.String,
+			Status:     u.Status.String,
+			Deleted:    u.DeletedAt.Valid,
+		})
+	}
+
+	sort.Slice(snap.Users, func(i, j int) bool { return snap.Users[i].ID < snap.Users[j].ID })
+
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// shortCircuit returns true if the reconciler loop can skip all of the okta reads because the governor
+// state is the same as the last successful loop and no okta events were handled since then
+func (r *Reconciler) shortCircuit(snapshot string) bool {
+	oktaEvents := r.oktaEventsSeen.Swap(false)
+
+	return r.snapshotShortCircuit && snapshot != "" && snapshot == r.lastSnapshot && !oktaEvents
+}
+
+func sortedCopy(in []string) []string {
+	out := make([]string, len(in))
+	copy(out, in)
+	sort.Strings(out)
+
+	return out
+}
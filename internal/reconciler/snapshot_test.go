@@ -0,0 +1,203 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testGovGroup(t *testing.T, id string, members, orgs []string) *v1alpha1.Group {
+	t.Helper()
+
+	g := &v1alpha1.Group{}
+	if err := json.Unmarshal([]byte(`{"id":"`+id+`","slug":"`+id+`","name":"`+id+`"}`), g); err != nil {
+		t.Fatal(err)
+	}
+
+	g.Members = members
+	g.Organizations = orgs
+
+	return g
+}
+
+func Test_snapshotHash(t *testing.T) {
+	orgs := []*v1alpha1.Organization{}
+	if err := json.Unmarshal([]byte(`[{"id":"org-1","slug":"org-one"},{"id":"org-2","slug":"org-two"}]`), &orgs); err != nil {
+		t.Fatal(err)
+	}
+
+	users := []*v1beta1.User{testGovUser(t, "user-1", "one@example.com"), testGovUser(t, "user-2", "two@example.com")}
+
+	base, err := snapshotHash([]*v1alpha1.Group{
+		testGovGroup(t, "group-1", []string{"user-1", "user-2"}, []string{"org-1"}),
+		testGovGroup(t, "group-2", []string{"user-2"}, []string{"org-1", "org-2"}),
+	}, orgs, users)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, base)
+
+	tests := []struct {
+		name   string
+		groups []*v1alpha1.Group
+		orgs   []*v1alpha1.Organization
+		users  []*v1beta1.User
+		same   bool
+	}{
+		{
+			name: "reordered lists are the same",
+			groups: []*v1alpha1.Group{
+				testGovGroup(t, "group-2", []string{"user-2"}, []string{"org-2", "org-1"}),
+				testGovGroup(t, "group-1", []string{"user-2", "user-1"}, []string{"org-1"}),
+			},
+			orgs:  []*v1alpha1.Organization{orgs[1], orgs[0]},
+			users: []*v1beta1.User{users[1], users[0]},
+			same:  true,
+		},
+		{
+			name: "member removed",
+			groups: []*v1alpha1.Group{
+				testGovGroup(t, "group-1", []string{"user-1"}, []string{"org-1"}),
+				testGovGroup(t, "group-2", []string{"user-2"}, []string{"org-1", "org-2"}),
+			},
+			orgs:  orgs,
+			users: users,
+		},
+		{
+			name: "organization removed",
+			groups: []*v1alpha1.Group{
+				testGovGroup(t, "group-1", []string{"user-1", "user-2"}, []string{"org-1"}),
+				testGovGroup(t, "group-2", []string{"user-2"}, []string{"org-1", "org-2"}),
+			},
+			orgs:  orgs[:1],
+			users: users,
+		},
+		{
+			name: "user removed",
+			groups: []*v1alpha1.Group{
+				testGovGroup(t, "group-1", []string{"user-1", "user-2"}, []string{"org-1"}),
+				testGovGroup(t, "group-2", []string{"user-2"}, []string{"org-1", "org-2"}),
+			},
+			orgs:  orgs,
+			users: users[:1],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := snapshotHash(tt.groups, tt.orgs, tt.users)
+			assert.NoError(t, err)
+
+			if tt.same {
+				assert.Equal(t, base, got)
+				return
+			}
+
+			assert.NotEqual(t, base, got)
+		})
+	}
+}
+
+func TestReconciler_shortCircuit(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		last       string
+		snapshot   string
+		oktaEvents bool
+		want       bool
+	}{
+		{
+			name:     "unchanged",
+			enabled:  true,
+			last:     "abc",
+			snapshot: "abc",
+			want:     true,
+		},
+		{
+			name:     "disabled",
+			last:     "abc",
+			snapshot: "abc",
+		},
+		{
+			name:     "changed",
+			enabled:  true,
+			last:     "abc",
+			snapshot: "def",
+		},
+		{
+			name:    "no snapshot",
+			enabled: true,
+		},
+		{
+			name:       "okta events handled",
+			enabled:    true,
+			last:       "abc",
+			snapshot:   "abc",
+			oktaEvents: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reconciler{snapshotShortCircuit: tt.enabled, lastSnapshot: tt.last}
+			r.oktaEventsSeen.Store(tt.oktaEvents)
+
+			assert.Equal(t, tt.want, r.shortCircuit(tt.snapshot))
+			assert.False(t, r.oktaEventsSeen.Load())
+		})
+	}
+}
+
+func TestReconciler_reconcileLoopShortCircuitAfterFailure(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1"}})
+
+	o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+	o.AddUser("okta-stray", "ACTIVE", testOktaProfile("stray@example.com"))
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-1")
+
+	r, _ := newTestServerReconciler(t, o, g, WithSnapshotShortCircuit(true))
+
+	r.reconcileLoop(context.TODO())
+	assert.Equal(t, RunResultSucceeded, r.Status().Runs[0].Result)
+
+	// an okta event changes the group and the loop it triggers fails to fix it
+	require.NoError(t, r.oktaClient.AddGroupUser(context.TODO(), "00g-platform", "okta-stray"))
+	r.oktaEventsSeen.Store(true)
+
+	o.FailRequests(func(req testserver.Request) int {
+		if req.Method == http.MethodDelete || req.Path == "/api/v1/users" {
+			return http.StatusInternalServerError
+		}
+
+		return 0
+	})
+
+	r.reconcileLoop(context.TODO())
+	assert.NotEqual(t, RunResultNoop, r.Status().Runs[0].Result)
+	assert.NotEqual(t, RunResultSucceeded, r.Status().Runs[0].Result)
+
+	// governor is unchanged and no new okta events were handled, but the failed work is redone
+	o.FailRequests(func(testserver.Request) int { return 0 })
+
+	r.reconcileLoop(context.TODO())
+	assert.Equal(t, RunResultSucceeded, r.Status().Runs[0].Result)
+	assert.Equal(t, []string{"okta-1"}, o.GroupMembers("00g-platform"))
+
+	// and once a loop finished clean the next one is short-circuited again
+	r.reconcileLoop(context.TODO())
+	assert.Equal(t, RunResultNoop, r.Status().Runs[0].Result)
+}
@@ -0,0 +1,210 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"go.uber.org/zap"
+)
+
+const (
+	// eventlogCheckpointKey is the state key of the time of the last okta log event handled
+	eventlogCheckpointKey = "eventlog-checkpoint"
+	// groupScheduleKey is the state key of the next due times of the groups with an interval override
+	groupScheduleKey = "group-schedule"
+)
+
+// StateStore persists the reconciler state that should survive a restart, Get returns ErrStateNotFound for
+// keys that were never written
+type StateStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, value []byte) error
+}
+
+// KVStateStore is a state store backed by a JetStream key-value bucket
+type KVStateStore struct {
+	kv nats.KeyValue
+}
+
+// NewKVStateStore returns a state store using the given key-value bucket
+func NewKVStateStore(kv nats.KeyValue) *KVStateStore {
+	return &KVStateStore{kv: kv}
+}
+
+// Get returns the value of the key
+func (s *KVStateStore) Get(_ context.Context, key string) ([]byte, error) {
+	e, err := s.kv.Get(key)
+	if err != nil {
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			return nil, ErrStateNotFound
+		}
+
+		return nil, err
+	}
+
+	return e.Value(), nil
+}
+
+// Put writes the value of the key
+func (s *KVStateStore) Put(_ context.Context, key string, value []byte) error {
+	_, err := s.kv.Put(key, value)
+
+	return err
+}
+
+// eventlogCheckpoint tracks the publish time of the latest okta log event handled
+type eventlogCheckpoint struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// handled moves the checkpoint forward to the publish time of a handled event
+func (c *eventlogCheckpoint) handled(evt *okt.LogEvent) {
+	if evt == nil || evt.Published == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if evt.Published.After(c.last) {
+		c.last = evt.Published.UTC()
+	}
+}
+
+// get returns the checkpoint, zero when no event was handled
+func (c *eventlogCheckpoint) get() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.last
+}
+
+// snapshot returns the next due times of the scheduled groups
+func (s *groupSchedule) snapshot() map[string]time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make(map[string]time.Time, len(s.next))
+
+	for id, t := range s.next {
+		next[id] = t
+	}
+
+	return next
+}
+
+// restore sets the next due times of groups, ie. from before a restart, groups without an interval override
+// are dropped on the next update
+func (s *groupSchedule) restore(next map[string]time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range next {
+		s.next[id] = t
+	}
+}
+
+// eventlogStart returns the time to start polling the okta event log from, the persisted checkpoint when it's
+// within the cold start lookback
+func (r *Reconciler) eventlogStart(ctx context.Context) time.Time {
+	start := r.now().UTC().Add(-r.eventlogLookback)
+
+	if r.stateStore == nil {
+		return start
+	}
+
+	checkpoint := time.Time{}
+
+	if err := r.loadState(ctx, eventlogCheckpointKey, &checkpoint); err != nil {
+		return start
+	}
+
+	if checkpoint.After(start) {
+		r.logger.Info("resuming okta event log polling from checkpoint", zap.Time("eventlog.checkpoint", checkpoint))
+
+		return checkpoint
+	}
+
+	return start
+}
+
+// restoreState restores the group schedule persisted before a restart
+func (r *Reconciler) restoreState(ctx context.Context) {
+	if r.stateStore == nil {
+		return
+	}
+
+	next := map[string]time.Time{}
+
+	if err := r.loadState(ctx, groupScheduleKey, &next); err != nil {
+		return
+	}
+
+	r.schedule.restore(next)
+
+	r.logger.Info("restored group schedule", zap.Int("num.groups", len(next)))
+}
+
+// loadState reads a state key, missing keys and errors are logged and returned
+func (r *Reconciler) loadState(ctx context.Context, key string, v interface{}) error {
+	b, err := r.stateStore.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, ErrStateNotFound) {
+			r.logger.Warn("error loading reconciler state", zap.String("state.key", key), zap.Error(err))
+		}
+
+		return err
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		r.logger.Warn("error decoding reconciler state", zap.String("state.key", key), zap.Error(err))
+
+		return err
+	}
+
+	return nil
+}
+
+// SaveState persists the okta event log checkpoint and the group schedule, so they survive a restart.  The
+// checkpoint is only written when this instance handled events, so a standby instance doesn't overwrite the
+// leader's checkpoint.
+func (r *Reconciler) SaveState(ctx context.Context) error {
+	if r.stateStore == nil {
+		return nil
+	}
+
+	state := map[string]interface{}{
+		groupScheduleKey: r.schedule.snapshot(),
+	}
+
+	if checkpoint := r.eventlog.get(); !checkpoint.IsZero() {
+		state[eventlogCheckpointKey] = checkpoint
+	}
+
+	errs := []error{}
+
+	for key, v := range state {
+		b, err := json.Marshal(v)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := r.stateStore.Put(ctx, key, b); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// EventlogCheckpoint returns the publish time of the latest okta log event handled, zero when none was handled
+func (r *Reconciler) EventlogCheckpoint() time.Time {
+	return r.eventlog.get()
+}
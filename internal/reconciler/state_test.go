@@ -0,0 +1,65 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type memStateStore map[string][]byte
+
+func (m memStateStore) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, ErrStateNotFound
+	}
+
+	return v, nil
+}
+
+func (m memStateStore) Put(_ context.Context, key string, value []byte) error {
+	m[key] = value
+
+	return nil
+}
+
+func TestReconciler_SaveState(t *testing.T) {
+	ctx := context.Background()
+	store := memStateStore{}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	r := &Reconciler{logger: zap.NewNop(), schedule: newGroupSchedule(), stateStore: store, eventlogLookback: time.Hour}
+
+	// a standby instance that didn't handle events doesn't write the checkpoint
+	require.NoError(t, r.SaveState(ctx))
+	assert.NotContains(t, store, eventlogCheckpointKey)
+
+	published := now.Add(-time.Minute)
+	r.eventlog.handled(&okt.LogEvent{Published: &published})
+	r.eventlog.handled(&okt.LogEvent{Published: &[]time.Time{now.Add(-time.Hour)}[0]})
+	r.schedule.restore(map[string]time.Time{"group-1": now.Add(5 * time.Minute)})
+
+	require.NoError(t, r.SaveState(ctx))
+
+	restarted := &Reconciler{logger: zap.NewNop(), schedule: newGroupSchedule(), stateStore: store, eventlogLookback: time.Hour}
+	restarted.restoreState(ctx)
+
+	assert.Equal(t, map[string]time.Time{"group-1": now.Add(5 * time.Minute)}, restarted.schedule.snapshot())
+	assert.Equal(t, published, restarted.eventlogStart(ctx))
+
+	// checkpoints older than the cold start lookback aren't used
+	restarted.eventlogLookback = time.Second
+	assert.WithinDuration(t, time.Now().Add(-time.Second), restarted.eventlogStart(ctx), time.Second)
+}
+
+func TestReconciler_eventlogStartWithoutStore(t *testing.T) {
+	r := &Reconciler{logger: zap.NewNop(), eventlogLookback: time.Hour}
+
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), r.eventlogStart(context.Background()), time.Second)
+	assert.NoError(t, r.SaveState(context.Background()))
+}
@@ -0,0 +1,352 @@
+package reconciler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// RunResultSucceeded is the result of a reconciler loop that finished without errors
+	RunResultSucceeded = "succeeded"
+	// RunResultPartial is the result of a reconciler loop that finished with some failing groups
+	RunResultPartial = "partial"
+	// RunResultFailed is the result of a reconciler loop that was aborted by an error
+	RunResultFailed = "failed"
+	// RunResultNoop is the result of a reconciler loop that was short-circuited by the snapshot
+	RunResultNoop = "noop"
+	// RunResultNotLeader is the result of a reconciler loop that was skipped on a replica that isn't the leader
+	RunResultNotLeader = "not-leader"
+	// RunResultCircuitOpen is the result of a reconciler loop that was skipped or aborted by an open circuit breaker
+	RunResultCircuitOpen = "circuit-open"
+
+	// maxStatusRuns is the number of recent reconciler loops kept in the status
+	maxStatusRuns = 10
+
+	// maxStatusPendingDeletions is the max number of pending deletions kept in the status, with skip-delete
+	// enabled every deletion is pending so the list can be as large as the drift
+	maxStatusPendingDeletions = 500
+)
+
+// Status is a snapshot of the recent reconciler loops
+type Status struct {
+	ID                    string                 `json:"id"`
+	DryRun                bool                   `json:"dry_run"`
+	WhatIf                bool                   `json:"what_if"`
+	SkipDelete            bool                   `json:"skip_delete"`
+	Paused                bool                   `json:"paused"`
+	PausedSince           *time.Time             `json:"paused_since,omitempty"`
+	PauseReason           string                 `json:"pause_reason,omitempty"`
+	Running               bool                   `json:"running"`
+	Runs                  []RunStatus            `json:"runs"`
+	Drift                 []DriftStatus          `json:"drift"`
+	FailingGroups         []GroupFailure         `json:"failing_groups"`
+	FailingAppAssignments []AppAssignmentFailure `json:"failing_app_assignments"`
+	QuarantinedGroups     []QuarantinedGroup     `json:"quarantined_groups"`
+	PendingDeletions      []PendingDeletion      `json:"pending_deletions"`
+	PendingDeletionsTotal int                    `json:"pending_deletions_total"`
+	Pilot                 *PilotStatus           `json:"pilot,omitempty"`
+}
+
+// RunStatus is the outcome of a single reconciler loop
+type RunStatus struct {
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Duration time.Duration `json:"duration"`
+	Result   string        `json:"result"`
+	Error    string        `json:"error,omitempty"`
+	Summary  RunSummary    `json:"summary"`
+}
+
+// RunSummary counts the work of a reconciler loop, errors are the failing groups and the error that aborted
+// the loop
+type RunSummary struct {
+	GroupsChecked  int `json:"groups_checked"`
+	MembersAdded   int `json:"members_added"`
+	MembersRemoved int `json:"members_removed"`
+	MembersSkipped int `json:"members_skipped"`
+	UsersSuspended int `json:"users_suspended"`
+	Errors         int `json:"errors"`
+}
+
+// runCounts are the counts of the running reconciler loop, they're carried in the loop context so the changes
+// made by NATS events in the meantime aren't counted
+type runCounts struct {
+	groupsChecked  atomic.Int64
+	membersAdded   atomic.Int64
+	membersRemoved atomic.Int64
+	membersSkipped atomic.Int64
+	usersSuspended atomic.Int64
+}
+
+type runCountsKey struct{}
+
+// withRunCounts returns a context counting the work of a reconciler loop
+func withRunCounts(ctx context.Context, c *runCounts) context.Context {
+	return context.WithValue(ctx, runCountsKey{}, c)
+}
+
+// runCountsFrom returns the counts of the reconciler loop of the context, nil outside of a loop
+func runCountsFrom(ctx context.Context) *runCounts {
+	c, _ := ctx.Value(runCountsKey{}).(*runCounts)
+
+	return c
+}
+
+func (c *runCounts) groupChecked() {
+	if c != nil {
+		c.groupsChecked.Add(1)
+	}
+}
+
+func (c *runCounts) userSuspended() {
+	if c != nil {
+		c.usersSuspended.Add(1)
+	}
+}
+
+// membership counts the members changed by a group membership reconciliation
+func (c *runCounts) membership(res *MembershipResult) {
+	if c == nil || res == nil {
+		return
+	}
+
+	c.membersAdded.Add(int64(len(res.Added)))
+	c.membersRemoved.Add(int64(len(res.Removed)))
+	c.membersSkipped.Add(int64(len(res.Skipped)))
+}
+
+// DriftStatus is the governor and okta count of an invariant from the last invariants check
+type DriftStatus struct {
+	Name       string  `json:"name"`
+	Governor   int     `json:"governor"`
+	Okta       int     `json:"okta"`
+	Divergence float64 `json:"divergence"`
+	Tolerance  float64 `json:"tolerance"`
+	Exceeded   bool    `json:"exceeded"`
+}
+
+// GroupFailure is a governor group that failed to reconcile in the last reconciler loop
+type GroupFailure struct {
+	GroupID   string `json:"group_id"`
+	GroupSlug string `json:"group_slug,omitempty"`
+	Error     string `json:"error"`
+}
+
+// PendingDeletion is a deletion in okta that was skipped because of dry-run or skip-delete
+type PendingDeletion struct {
+	Type            string `json:"type"`
+	GovernorGroupID string `json:"governor_group_id,omitempty"`
+	OktaGroupID     string `json:"okta_group_id,omitempty"`
+	OktaUserID      string `json:"okta_user_id,omitempty"`
+	OktaAppID       string `json:"okta_app_id,omitempty"`
+}
+
+// statusTracker records the reconciler loops for the status.  Failing groups and pending deletions are
+// collected while a loop runs and only replace the previous ones when the loop finishes.
+type statusTracker struct {
+	mu sync.RWMutex
+
+	running      bool
+	started      time.Time
+	runs         []RunStatus
+	drift        []DriftStatus
+	failing      []GroupFailure
+	appFailing   []AppAssignmentFailure
+	pending      []PendingDeletion
+	pendingTotal int
+
+	curFailing      []GroupFailure
+	curAppFailing   []AppAssignmentFailure
+	curPending      []PendingDeletion
+	curPendingTotal int
+	curCounts       *runCounts
+}
+
+func newStatusTracker() *statusTracker {
+	return &statusTracker{}
+}
+
+// begin starts recording a reconciler loop and returns its counts
+func (s *statusTracker) begin(now time.Time) *runCounts {
+	counts := &runCounts{}
+
+	if s == nil {
+		return counts
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running = true
+	s.started = now
+	s.curFailing = nil
+	s.curAppFailing = nil
+	s.curPending = nil
+	s.curPendingTotal = 0
+	s.curCounts = counts
+
+	return counts
+}
+
+// finish records the result of the running reconciler loop and returns it.  The failing groups and pending
+// deletions are only replaced by loops that did the work, no-op and not-leader loops keep the previous ones.
+func (s *statusTracker) finish(now time.Time, result string, err error) RunStatus {
+	if s == nil {
+		return RunStatus{Finished: now, Result: result}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := RunStatus{
+		Started:  s.started,
+		Finished: now,
+		Duration: now.Sub(s.started),
+		Result:   result,
+	}
+
+	if err != nil {
+		run.Error = err.Error()
+		run.Summary.Errors++
+	}
+
+	if s.curCounts != nil {
+		run.Summary.GroupsChecked = int(s.curCounts.groupsChecked.Load())
+		run.Summary.MembersAdded = int(s.curCounts.membersAdded.Load())
+		run.Summary.MembersRemoved = int(s.curCounts.membersRemoved.Load())
+		run.Summary.MembersSkipped = int(s.curCounts.membersSkipped.Load())
+		run.Summary.UsersSuspended = int(s.curCounts.usersSuspended.Load())
+	}
+
+	run.Summary.Errors += len(s.curFailing)
+
+	s.runs = append([]RunStatus{run}, s.runs...)
+	if len(s.runs) > maxStatusRuns {
+		s.runs = s.runs[:maxStatusRuns]
+	}
+
+	s.running = false
+
+	if result == RunResultNoop || result == RunResultNotLeader || result == RunResultCircuitOpen {
+		return run
+	}
+
+	s.failing = s.curFailing
+	s.appFailing = s.curAppFailing
+	s.pending = s.curPending
+	s.pendingTotal = s.curPendingTotal
+
+	return run
+}
+
+// groupFailed records a governor group that failed to reconcile
+func (s *statusTracker) groupFailed(gid, slug string, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := GroupFailure{GroupID: gid, GroupSlug: slug}
+	if err != nil {
+		f.Error = err.Error()
+	}
+
+	s.curFailing = append(s.curFailing, f)
+}
+
+// appAssignmentsFailed records the application assignment changes that failed
+func (s *statusTracker) appAssignmentsFailed(failures []AppAssignmentFailure) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.curAppFailing = append(s.curAppFailing, failures...)
+}
+
+// pendingDeletion records a skipped deletion
+func (s *statusTracker) pendingDeletion(d PendingDeletion) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.curPendingTotal++
+
+	if len(s.curPending) < maxStatusPendingDeletions {
+		s.curPending = append(s.curPending, d)
+	}
+}
+
+// setDrift records the result of an invariants check
+func (s *statusTracker) setDrift(drift []DriftStatus) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.drift = drift
+}
+
+// snapshot returns a copy of the recorded status
+func (s *statusTracker) snapshot() Status {
+	st := Status{
+		Runs:                  []RunStatus{},
+		Drift:                 []DriftStatus{},
+		FailingGroups:         []GroupFailure{},
+		FailingAppAssignments: []AppAssignmentFailure{},
+		PendingDeletions:      []PendingDeletion{},
+	}
+
+	if s == nil {
+		return st
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st.Running = s.running
+	st.Runs = append(st.Runs, s.runs...)
+	st.Drift = append(st.Drift, s.drift...)
+	st.FailingGroups = append(st.FailingGroups, s.failing...)
+	st.FailingAppAssignments = append(st.FailingAppAssignments, s.appFailing...)
+	st.PendingDeletions = append(st.PendingDeletions, s.pending...)
+	st.PendingDeletionsTotal = s.pendingTotal
+
+	return st
+}
+
+// Status returns the status of the recent reconciler loops
+func (r *Reconciler) Status() Status {
+	st := r.status.snapshot()
+
+	st.ID = r.id.String()
+	st.DryRun = r.dryrun
+	st.WhatIf = r.whatIf()
+	st.SkipDelete = r.skipDelete
+	st.Pilot = r.pilot.status()
+	st.QuarantinedGroups = r.quarantine.list()
+
+	r.pause.mu.RLock()
+	defer r.pause.mu.RUnlock()
+
+	if r.pause.paused {
+		since := r.pause.since
+
+		st.Paused = true
+		st.PausedSince = &since
+		st.PauseReason = r.pause.reason
+	}
+
+	return st
+}
@@ -0,0 +1,99 @@
+package reconciler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_statusTracker(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s := newStatusTracker()
+
+	counts := s.begin(start)
+	counts.groupChecked()
+	counts.groupChecked()
+	counts.membership(&MembershipResult{Added: []string{"okta-user2"}, Removed: []string{"okta-user3"}, Skipped: []string{"okta-user1"}})
+	counts.membership(nil)
+	s.groupFailed("group1", "group-1", errors.New("boom"))
+	s.pendingDeletion(PendingDeletion{Type: "GroupMemberRemove", OktaGroupID: "okta-group1", OktaUserID: "okta-user1"})
+
+	st := s.snapshot()
+	assert.True(t, st.Running)
+	assert.Empty(t, st.FailingGroups, "failing groups are only published when the loop finishes")
+
+	s.finish(start.Add(time.Minute), RunResultPartial, nil)
+
+	st = s.snapshot()
+	assert.False(t, st.Running)
+	assert.Equal(t, []RunStatus{{
+		Started:  start,
+		Finished: start.Add(time.Minute),
+		Duration: time.Minute,
+		Result:   RunResultPartial,
+		Summary:  RunSummary{GroupsChecked: 2, MembersAdded: 1, MembersRemoved: 1, MembersSkipped: 1, Errors: 1},
+	}}, st.Runs)
+	assert.Equal(t, []GroupFailure{{GroupID: "group1", GroupSlug: "group-1", Error: "boom"}}, st.FailingGroups)
+	assert.Equal(t, 1, st.PendingDeletionsTotal)
+
+	// a no-op loop keeps the failures of the last loop that did the work
+	s.begin(start.Add(time.Hour))
+	s.finish(start.Add(time.Hour), RunResultNoop, nil)
+
+	st = s.snapshot()
+	assert.Len(t, st.Runs, 2)
+	assert.Equal(t, RunResultNoop, st.Runs[0].Result)
+	assert.Len(t, st.FailingGroups, 1)
+
+	// a failed loop replaces them
+	s.begin(start.Add(2 * time.Hour))
+	s.finish(start.Add(2*time.Hour), RunResultFailed, errors.New("governor down"))
+
+	st = s.snapshot()
+	assert.Equal(t, "governor down", st.Runs[0].Error)
+	assert.Equal(t, RunSummary{Errors: 1}, st.Runs[0].Summary)
+	assert.Empty(t, st.FailingGroups)
+	assert.Empty(t, st.PendingDeletions)
+	assert.Equal(t, 0, st.PendingDeletionsTotal)
+}
+
+func Test_statusTrackerLimits(t *testing.T) {
+	s := newStatusTracker()
+
+	for i := 0; i < maxStatusRuns+5; i++ {
+		s.begin(time.Now())
+
+		for j := 0; j < maxStatusPendingDeletions+1; j++ {
+			s.pendingDeletion(PendingDeletion{Type: "UserDelete"})
+		}
+
+		s.finish(time.Now(), RunResultSucceeded, nil)
+	}
+
+	st := s.snapshot()
+	assert.Len(t, st.Runs, maxStatusRuns)
+	assert.Len(t, st.PendingDeletions, maxStatusPendingDeletions)
+	assert.Equal(t, maxStatusPendingDeletions+1, st.PendingDeletionsTotal)
+}
+
+func Test_statusTrackerNil(t *testing.T) {
+	var s *statusTracker
+
+	s.begin(time.Now())
+	s.groupFailed("group1", "group-1", nil)
+	s.appAssignmentsFailed([]AppAssignmentFailure{{Org: "main"}})
+	s.pendingDeletion(PendingDeletion{})
+	s.setDrift(nil)
+	s.finish(time.Now(), RunResultSucceeded, nil)
+
+	assert.Equal(t, Status{
+		Runs:                  []RunStatus{},
+		Drift:                 []DriftStatus{},
+		FailingGroups:         []GroupFailure{},
+		FailingAppAssignments: []AppAssignmentFailure{},
+		PendingDeletions:      []PendingDeletion{},
+	}, s.snapshot())
+}
@@ -0,0 +1,525 @@
+package reconciler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/auditevent"
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/gov-okta-addon/internal/journal"
+	"github.com/metal-toolbox/gov-okta-addon/internal/notify"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// newTestServerReconciler returns a reconciler using real okta and governor clients against the fake apis, and
+// the buffer its audit events are written to
+func newTestServerReconciler(t *testing.T, o *testserver.Okta, g *testserver.Governor, opts ...Option) (*Reconciler, *bytes.Buffer) {
+	t.Helper()
+
+	oc, err := okta.NewClient(
+		okta.WithURL(o.URL),
+		okta.WithToken("okta-token"),
+		okta.WithCache(false),
+		okta.WithHTTPClient(o.Client()),
+		okta.WithPageRetries(0, 0),
+	)
+	require.NoError(t, err)
+
+	gc, err := governor.NewClient(
+		governor.WithURL(g.URL),
+		governor.WithClientCredentialConfig(&clientcredentials.Config{
+			ClientID:     "gov-okta-addon",
+			ClientSecret: "secret",
+			TokenURL:     g.TokenURL(),
+		}),
+		governor.WithHTTPClient(g.Client()),
+	)
+	require.NoError(t, err)
+
+	audit := &bytes.Buffer{}
+
+	opts = append([]Option{
+		WithAuditEventWriter(auditevent.NewDefaultAuditEventWriter(audit)),
+		WithOktaClient(oc),
+		WithGovernorClient(govclient.New(gc, g.URL, g.Client())),
+	}, opts...)
+
+	return New(opts...), audit
+}
+
+// testOktaProfile returns the okta user profile of an email address
+func testOktaProfile(email string) map[string]interface{} {
+	name, _, _ := strings.Cut(email, "@")
+
+	return map[string]interface{}{
+		"email":     email,
+		"login":     email,
+		"firstName": name,
+		"lastName":  "Example",
+	}
+}
+
+// testOktaGroupByGovernorID returns the okta group with the governor id
+func testOktaGroupByGovernorID(o *testserver.Okta, id string) string {
+	for _, g := range o.Groups() {
+		if g.Profile.GroupProfileMap[okta.GroupProfileGovernorIDKey] == id {
+			return g.Id
+		}
+	}
+
+	return ""
+}
+
+func TestReconciler_reconcileLoop_testserver(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	// small pages make the clients follow the pagination of both apis
+	o.PageSize = 2
+	g.PageSize = 2
+
+	deletedAt := time.Now().Add(-time.Hour)
+
+	g.AddOrganization(&testserver.GovernorOrganization{ID: "org-1", Name: "Metal Toolbox", Slug: "metal-toolbox"})
+
+	for i := 1; i <= 4; i++ {
+		g.AddUser(&testserver.GovernorUser{
+			ID:         fmt.Sprintf("user-%d", i),
+			ExternalID: fmt.Sprintf("okta-%d", i),
+			Email:      fmt.Sprintf("user-%d@example.com", i),
+			Status:     v1alpha1.UserStatusActive,
+		})
+
+		o.AddUser(fmt.Sprintf("okta-%d", i), "ACTIVE", testOktaProfile(fmt.Sprintf("user-%d@example.com", i)))
+	}
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-5", ExternalID: "okta-5", Email: "user-5@example.com", Status: v1alpha1.UserStatusSuspended})
+	g.AddUser(&testserver.GovernorUser{ID: "user-6", ExternalID: "okta-6", Email: "user-6@example.com", Status: v1alpha1.UserStatusActive, DeletedAt: &deletedAt})
+
+	o.AddUser("okta-5", "ACTIVE", testOktaProfile("user-5@example.com"))
+	o.AddUser("okta-6", "ACTIVE", testOktaProfile("user-6@example.com"))
+	o.AddUser("okta-stray", "ACTIVE", testOktaProfile("stray@example.com"))
+
+	// a new governor group in the github org and an existing one with a stray and a deleted member
+	g.AddGroup(&testserver.GovernorGroup{
+		ID:            "group-1",
+		Name:          "Platform",
+		Slug:          "platform",
+		Description:   "the platform team",
+		Organizations: []string{"org-1"},
+		Members:       []string{"user-1", "user-2", "user-3", "user-4"},
+	})
+
+	g.AddGroup(&testserver.GovernorGroup{
+		ID:      "group-2",
+		Name:    "Storage",
+		Slug:    "storage",
+		Members: []string{"user-1", "user-2"},
+	})
+
+	o.AddGroup("00g-storage", "Storage", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-2"},
+		"okta-1", "okta-stray", "okta-6",
+	)
+
+	o.AddApp("app-github", "githubcloud", map[string]interface{}{"githubOrg": "metal-toolbox"}, "00g-storage")
+	o.AddApp("app-other", "slack", nil)
+
+	r, audit := newTestServerReconciler(t, o, g)
+
+	r.reconcileLoop(context.TODO())
+
+	runs := r.Status().Runs
+	require.Len(t, runs, 1)
+	assert.Equal(t, RunResultSucceeded, runs[0].Result, runs[0].Error)
+
+	// the new group is created with the governor id and all of its members
+	platformID := testOktaGroupByGovernorID(o, "group-1")
+	require.NotEmpty(t, platformID)
+
+	platform := o.Group(platformID)
+	assert.Equal(t, "the platform team", platform.Profile.Description)
+	assert.ElementsMatch(t, []string{"okta-1", "okta-2", "okta-3", "okta-4"}, o.GroupMembers(platformID))
+
+	// the existing group gets its missing member and loses the stray and deleted ones
+	assert.ElementsMatch(t, []string{"okta-1", "okta-2"}, o.GroupMembers("00g-storage"))
+
+	// only the group of the github org is assigned to the github application
+	assert.Equal(t, []string{platformID}, o.AppGroups("app-github"))
+	assert.Empty(t, o.AppGroups("app-other"))
+
+	// the suspended governor user is suspended in okta and the deleted one is left alone
+	assert.Equal(t, "SUSPENDED", o.User("okta-5").Status)
+	assert.Equal(t, "ACTIVE", o.User("okta-6").Status)
+
+	var (
+		searches, memberPages, appFilters int
+		created                           []byte
+	)
+
+	for _, req := range o.Requests() {
+		switch {
+		case req.Method == http.MethodGet && req.Path == "/api/v1/groups" && req.Query.Get("search") == `profile.governor_id eq "group-1"`:
+			searches++
+		case req.Method == http.MethodGet && req.Path == "/api/v1/groups/00g-storage/users":
+			memberPages++
+		case req.Method == http.MethodGet && req.Path == "/api/v1/apps":
+			assert.Equal(t, `name eq "githubcloud"`, req.Query.Get("filter"))
+			appFilters++
+		case req.Method == http.MethodPost && req.Path == "/api/v1/groups":
+			created = req.Body
+		}
+	}
+
+	assert.Positive(t, searches)
+	assert.Equal(t, 1, appFilters)
+	assert.JSONEq(t, `{"profile":{"name":"Platform","description":"the platform team","governor_id":"group-1"}}`, string(created))
+
+	// three members in pages of two, listed before and after the changes
+	assert.GreaterOrEqual(t, memberPages, 2)
+
+	// the governor users are listed in pages of two
+	cursors := 0
+
+	for _, req := range g.Requests() {
+		if req.Path == "/api/v1beta1/users" && req.Query.Get("next_cursor") != "" {
+			cursors++
+		}
+	}
+
+	assert.Positive(t, cursors)
+
+	assert.Contains(t, audit.String(), "GroupCreate")
+	assert.Contains(t, audit.String(), "GroupMemberRemove")
+}
+
+func TestReconciler_reconcileLoop_testserver_dryrun(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1"}})
+
+	o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+	o.AddUser("okta-stray", "ACTIVE", testOktaProfile("stray@example.com"))
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-stray")
+
+	r, _ := newTestServerReconciler(t, o, g, WithDryRun(true))
+
+	r.reconcileLoop(context.TODO())
+
+	// nothing changes in okta
+	assert.Equal(t, []string{"okta-stray"}, o.GroupMembers("00g-platform"))
+
+	for _, req := range o.Requests() {
+		assert.Equal(t, http.MethodGet, req.Method, "unexpected okta request %s %s", req.Method, req.Path)
+	}
+}
+
+func TestReconciler_GroupCreate_descriptionMarker(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Description: "the platform team"})
+
+	r, _ := newTestServerReconciler(t, o, g, WithGroupDescriptionMarker(okta.GroupDescriptionMarker{Prefix: "[managed by governor]"}))
+
+	ctx := r.withReconcileAuditEvent(context.TODO(), "test")
+
+	oktaGID, err := r.GroupCreate(ctx, "group-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "[managed by governor] the platform team", o.Group(oktaGID).Profile.Description)
+}
+
+func TestReconciler_GroupMembership_listingFails(t *testing.T) {
+	tests := []struct {
+		name string
+		fail func(testserver.Request) int
+	}{
+		{
+			name: "first page",
+			fail: func(req testserver.Request) int {
+				if req.Method == http.MethodGet && req.Path == "/api/v1/groups/00g-platform/users" {
+					return http.StatusInternalServerError
+				}
+
+				return 0
+			},
+		},
+		{
+			name: "next page",
+			fail: func(req testserver.Request) int {
+				if req.Method == http.MethodGet && req.Path == "/api/v1/groups/00g-platform/users" && req.Query.Has("after") {
+					return http.StatusInternalServerError
+				}
+
+				return 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			o.PageSize = 1
+
+			for i := 1; i <= 3; i++ {
+				g.AddUser(&testserver.GovernorUser{
+					ID:         fmt.Sprintf("user-%d", i),
+					ExternalID: fmt.Sprintf("okta-%d", i),
+					Email:      fmt.Sprintf("user-%d@example.com", i),
+					Status:     v1alpha1.UserStatusActive,
+				})
+
+				o.AddUser(fmt.Sprintf("okta-%d", i), "ACTIVE", testOktaProfile(fmt.Sprintf("user-%d@example.com", i)))
+			}
+
+			o.AddUser("okta-stray", "ACTIVE", testOktaProfile("stray@example.com"))
+
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1", "user-2", "user-3"}})
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-1", "okta-2", "okta-stray")
+
+			o.FailRequests(tt.fail)
+
+			r, audit := newTestServerReconciler(t, o, g)
+
+			res, err := r.GroupMembership(r.withReconcileAuditEvent(context.TODO(), "test"), "group-1", "00g-platform")
+			assert.ErrorIs(t, err, ErrOktaGroupMembershipList)
+			assert.Nil(t, res)
+
+			// nothing is added or removed
+			assert.Equal(t, []string{"okta-1", "okta-2", "okta-stray"}, o.GroupMembers("00g-platform"))
+			assert.Empty(t, audit.String())
+
+			for _, req := range o.Requests() {
+				assert.Equal(t, http.MethodGet, req.Method, "unexpected okta request %s %s", req.Method, req.Path)
+			}
+		})
+	}
+}
+
+func TestReconciler_GroupMembership_memberGone(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddUser(&testserver.GovernorUser{ID: "user-2", ExternalID: "okta-2", Email: "user-2@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddUser(&testserver.GovernorUser{ID: "user-3", ExternalID: "okta-3", Email: "user-3@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1", "user-2"}})
+
+	o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+	o.AddUser("okta-2", "ACTIVE", testOktaProfile("user-2@example.com"))
+	o.AddUser("okta-3", "ACTIVE", testOktaProfile("user-3@example.com"))
+	o.AddUser("okta-stray", "ACTIVE", testOktaProfile("stray@example.com"))
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-1", "okta-stray")
+
+	// the members are deactivated in okta between the lookup and the removal
+	o.FailRequests(func(req testserver.Request) int {
+		switch {
+		case req.Method != http.MethodDelete:
+			return 0
+		case req.Path == "/api/v1/groups/00g-platform/users/okta-stray":
+			return http.StatusNotFound
+		case req.Path == "/api/v1/groups/00g-platform/users/okta-3":
+			return http.StatusGone
+		}
+
+		return 0
+	})
+
+	r, audit := newTestServerReconciler(t, o, g)
+
+	res, err := r.GroupMembership(r.withReconcileAuditEvent(context.TODO(), "test"), "group-1", "00g-platform")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"okta-2"}, res.Added)
+	assert.Empty(t, res.Removed)
+
+	// nothing was removed, so no removal is audited
+	assert.Contains(t, audit.String(), "GroupMemberAdd")
+	assert.NotContains(t, audit.String(), "GroupMemberRemove")
+
+	// removing a single membership succeeds too
+	oktaGID, oktaUID, err := r.GroupMembershipDelete(context.TODO(), "group-1", "user-3")
+	require.NoError(t, err)
+	assert.Equal(t, "00g-platform", oktaGID)
+	assert.Equal(t, "okta-3", oktaUID)
+	assert.NotContains(t, audit.String(), "GroupMemberRemove")
+}
+
+func TestReconciler_GroupUpdate_unchanged(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		wantWrite   bool
+	}{
+		{name: "unchanged", description: "the platform team"},
+		{name: "description changed", description: "the old platform team", wantWrite: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Description: "the platform team"})
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{
+				okta.GroupProfileGovernorIDKey: "group-1",
+				"description":                  tt.description,
+			})
+
+			r, audit := newTestServerReconciler(t, o, g)
+
+			oktaGID, err := r.GroupUpdate(r.withReconcileAuditEvent(context.TODO(), "test"), "group-1")
+			require.NoError(t, err)
+			assert.Equal(t, "00g-platform", oktaGID)
+			assert.Equal(t, "the platform team", o.Group("00g-platform").Profile.Description)
+
+			writes := 0
+
+			for _, req := range o.Requests() {
+				if req.Method != http.MethodGet {
+					writes++
+				}
+			}
+
+			if tt.wantWrite {
+				assert.Equal(t, 1, writes)
+				assert.Contains(t, audit.String(), "GroupUpdate")
+
+				return
+			}
+
+			assert.Zero(t, writes)
+			assert.Empty(t, audit.String())
+		})
+	}
+}
+
+// journalStore keeps the journal entries in memory
+type journalStore struct {
+	entries []*journal.Entry
+}
+
+func (s *journalStore) Put(_ context.Context, e *journal.Entry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *journalStore) List(_ context.Context, _ *journal.Filter) ([]*journal.Entry, error) {
+	return s.entries, nil
+}
+
+func TestReconciler_GroupUpdate_journal(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Description: "the platform team"})
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{
+		okta.GroupProfileGovernorIDKey: "group-1",
+		"description":                  "the old platform team",
+	})
+
+	store := &journalStore{}
+
+	j, err := journal.New(journal.WithStore(store))
+	require.NoError(t, err)
+
+	r, _ := newTestServerReconciler(t, o, g, WithJournal(j))
+
+	_, err = r.GroupUpdate(r.withReconcileAuditEvent(context.TODO(), "test"), "group-1")
+	require.NoError(t, err)
+
+	require.Len(t, store.entries, 1)
+
+	// the before state is the okta group as it was read before the update
+	assert.Equal(t, journal.Hash(map[string]string{
+		"okta.group.id": "00g-platform",
+		"name":          "Platform",
+		"description":   "the old platform team",
+	}), store.entries[0].BeforeHash)
+	assert.Equal(t, journal.Hash(map[string]string{
+		"okta.group.id": "00g-platform",
+		"name":          "Platform",
+		"description":   "the platform team",
+	}), store.entries[0].AfterHash)
+}
+
+func TestReconciler_GroupMembership_notify(t *testing.T) {
+	var posted []notify.Message
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg notify.Message
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+
+		posted = append(posted, msg)
+	}))
+	defer hook.Close()
+
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddUser(&testserver.GovernorUser{ID: "user-2", ExternalID: "okta-2", Email: "user-2@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1", "user-2"}})
+
+	o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+	o.AddUser("okta-2", "ACTIVE", testOktaProfile("user-2@example.com"))
+	o.AddUser("okta-stray", "ACTIVE", testOktaProfile("stray@example.com"))
+	o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"}, "okta-1", "okta-stray")
+
+	notifier := notify.New(hook.URL, notify.WithHTTPClient(hook.Client()), notify.WithEventTypes("GroupMemberRemove"))
+
+	r, _ := newTestServerReconciler(t, o, g, WithWebhookNotifier(notifier))
+
+	res, err := r.GroupMembership(r.withReconcileAuditEvent(context.TODO(), "test"), "group-1", "00g-platform")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"okta-2"}, res.Added)
+	assert.Equal(t, []string{"okta-stray"}, res.Removed)
+
+	// only the removal is notified
+	require.Len(t, posted, 1)
+	assert.Equal(t, "GroupMemberRemove", posted[0].EventType)
+	assert.Equal(t, "okta-stray", posted[0].Target["okta.user.id"])
+	assert.Contains(t, posted[0].Text, "removed a member from an Okta group")
+}
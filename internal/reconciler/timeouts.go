@@ -0,0 +1,76 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultOpTimeout is the default deadline for a single external okta or governor operation
+const DefaultOpTimeout = 5 * time.Minute
+
+// WithOpTimeout sets the deadline for each external okta and governor operation, 0 or less disables it
+func WithOpTimeout(d time.Duration) Option {
+	return func(r *Reconciler) {
+		r.opTimeout = d
+	}
+}
+
+// callOp runs an external operation that returns a value with the per-operation timeout, it returns
+// ErrCircuitOpen without calling the backend while its circuit is open.  Okta operations wait on the okta
+// rate limit first.
+func callOp[T any](ctx context.Context, r *Reconciler, op string, f func(context.Context) (T, error)) (T, error) {
+	if err := r.breakerAllow(op); err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	if err := r.oktaRateWait(ctx, op); err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	opCtx, cancel := r.opContext(ctx)
+	defer cancel()
+
+	v, err := f(opCtx)
+
+	r.opDone(ctx, opCtx, op)
+	r.breakerRecord(ctx, op, err)
+
+	return v, err
+}
+
+// doOp runs an external operation with the per-operation timeout
+func (r *Reconciler) doOp(ctx context.Context, op string, f func(context.Context) error) error {
+	_, err := callOp(ctx, r, op, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, f(ctx)
+	})
+
+	return err
+}
+
+// opContext returns a context with the per-operation deadline
+func (r *Reconciler) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.opTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, r.opTimeout)
+}
+
+// opDone logs and counts an operation that ran into the per-operation deadline, operations cancelled
+// by the parent context (ie. on shutdown) are not counted
+func (r *Reconciler) opDone(ctx, opCtx context.Context, op string) {
+	if ctx.Err() != nil || !errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+		return
+	}
+
+	r.logger.Warn("operation timed out", zap.String("operation", op), zap.Duration("timeout", r.opTimeout))
+
+	incCounter(ctx, operationTimeoutsCounter.WithLabelValues(op))
+}
@@ -0,0 +1,71 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestReconciler_callOp(t *testing.T) {
+	tests := []struct {
+		name         string
+		opTimeout    time.Duration
+		cancelParent bool
+		wantDeadline bool
+		wantErr      error
+		wantCount    float64
+	}{
+		{
+			name:         "operation times out",
+			opTimeout:    time.Millisecond,
+			wantDeadline: true,
+			wantErr:      context.DeadlineExceeded,
+			wantCount:    1,
+		},
+		{
+			name:         "parent cancelled is not counted",
+			opTimeout:    time.Hour,
+			cancelParent: true,
+			wantDeadline: true,
+			wantErr:      context.Canceled,
+		},
+		{
+			name:      "timeout disabled",
+			opTimeout: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reconciler{logger: zap.NewNop(), opTimeout: tt.opTimeout}
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			if tt.cancelParent {
+				cancel()
+			}
+
+			op := "test." + t.Name()
+
+			got, err := callOp(ctx, r, op, func(ctx context.Context) (bool, error) {
+				_, ok := ctx.Deadline()
+				if !ok {
+					return false, nil
+				}
+
+				<-ctx.Done()
+
+				return true, ctx.Err()
+			})
+
+			assert.Equal(t, tt.wantDeadline, got)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantCount, testutil.ToFloat64(operationTimeoutsCounter.WithLabelValues(op)))
+		})
+	}
+}
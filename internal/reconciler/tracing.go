@@ -0,0 +1,16 @@
+package reconciler
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the reconciler spans
+const tracerName = "github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+
+// startSpan starts a reconciler span with the global tracer provider, it's a no-op when tracing is disabled
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name)
+}
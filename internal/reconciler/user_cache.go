@@ -0,0 +1,33 @@
+package reconciler
+
+import (
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+)
+
+// DefaultUserCacheTTL is the default time the governor users of group members are cached
+const DefaultUserCacheTTL = time.Minute
+
+// WithUserCache caches the governor users of group members by email, so that the members of large groups aren't
+// queried from governor on every reconcile.  The users changed by the reconciler and the NATS user events are
+// removed from the cache, other changes are picked up once the cached users expire.  A nil cache disables it.
+func WithUserCache(c usercache.Store[*v1beta1.User]) Option {
+	return func(r *Reconciler) {
+		r.userCache = c
+	}
+}
+
+// forgetGovernorUser removes the governor users of the emails from the user cache once they changed
+func (r *Reconciler) forgetGovernorUser(emails ...string) {
+	if r.userCache == nil {
+		return
+	}
+
+	for _, e := range emails {
+		if e != "" {
+			r.userCache.Delete(e)
+		}
+	}
+}
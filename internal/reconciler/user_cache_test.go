@@ -0,0 +1,63 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciler_groupMemberUsers_userCache(t *testing.T) {
+	o := testserver.NewOkta()
+	defer o.Close()
+
+	g := testserver.NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Email: "user-1@example.com", Status: v1alpha1.UserStatusActive})
+	g.AddUser(&testserver.GovernorUser{ID: "user-2", ExternalID: "okta-2", Email: "user-2@example.com", Status: v1alpha1.UserStatusActive})
+	o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+	o.AddUser("okta-2", "ACTIVE", testOktaProfile("user-2@example.com"))
+	g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1", "user-2"}})
+
+	r, _ := newTestServerReconciler(t, o, g, WithUserCache(usercache.New[*v1beta1.User]("test", 10, time.Minute)))
+
+	// userQueries returns the emails of the governor user queries
+	userQueries := func() [][]string {
+		queries := [][]string{}
+
+		for _, req := range g.Requests() {
+			if req.Path == "/api/v1beta1/users" {
+				queries = append(queries, req.Query["email"])
+			}
+		}
+
+		return queries
+	}
+
+	for range 2 {
+		users, err := r.groupMemberUsers(context.TODO(), "group-1")
+		require.NoError(t, err)
+		assert.Len(t, users, 2)
+	}
+
+	require.Len(t, userQueries(), 1, "the second lookup is cached")
+
+	// the user update forgets the cached user
+	_, err := r.UserUpdate(context.TODO(), "user-2")
+	require.NoError(t, err)
+
+	users, err := r.groupMemberUsers(context.TODO(), "group-1")
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	queries := userQueries()
+	require.Len(t, queries, 2)
+	assert.Equal(t, []string{"user-2@example.com"}, queries[1])
+}
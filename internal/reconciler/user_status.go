@@ -0,0 +1,195 @@
+package reconciler
+
+import (
+	"context"
+	"time"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"go.uber.org/zap"
+)
+
+// UserLifecycle is the okta lifecycle state the reconciler keeps the okta users of a governor user status in
+type UserLifecycle string
+
+const (
+	// UserLifecycleSkip leaves the okta user alone and skips the user in group memberships and owners
+	UserLifecycleSkip UserLifecycle = "skip"
+	// UserLifecycleKeep leaves the lifecycle state of the okta user alone, it's the default of unmapped statuses
+	UserLifecycleKeep UserLifecycle = "keep"
+	// UserLifecycleActive un-suspends suspended okta users
+	UserLifecycleActive UserLifecycle = "active"
+	// UserLifecycleSuspended suspends active okta users
+	UserLifecycleSuspended UserLifecycle = "suspended"
+	// UserLifecycleDeprovisioned deactivates active and suspended okta users
+	UserLifecycleDeprovisioned UserLifecycle = "deprovisioned"
+	// UserLifecycleStaged expects okta users that haven't been activated yet, okta users can't be moved back to
+	// staged so the ones that aren't staged are only logged
+	UserLifecycleStaged UserLifecycle = "staged"
+)
+
+// okta user statuses the reconciler moves users between
+const (
+	oktaUserStatusActive    = "ACTIVE"
+	oktaUserStatusSuspended = "SUSPENDED"
+	oktaUserStatusStaged    = "STAGED"
+)
+
+// okta user lifecycle changes
+const (
+	lifecycleSuspend    = "suspend"
+	lifecycleUnsuspend  = "unsuspend"
+	lifecycleDeactivate = "deactivate"
+)
+
+// defaultUserLifecycles are the okta lifecycle states of the governor user statuses without an override
+var defaultUserLifecycles = map[string]UserLifecycle{
+	v1alpha1.UserStatusPending:   UserLifecycleSkip,
+	v1alpha1.UserStatusActive:    UserLifecycleActive,
+	v1alpha1.UserStatusSuspended: UserLifecycleSuspended,
+}
+
+// Valid returns true for a known okta user lifecycle state
+func (l UserLifecycle) Valid() bool {
+	switch l {
+	case UserLifecycleSkip, UserLifecycleKeep, UserLifecycleActive, UserLifecycleSuspended, UserLifecycleDeprovisioned, UserLifecycleStaged:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithUserLifecycles overrides the okta lifecycle state of governor user statuses, ie. to deactivate suspended
+// users instead of suspending them.  Pending users are skipped, active users are un-suspended, suspended users
+// are suspended and the lifecycle of users with other statuses is left alone unless they're overridden.
+func WithUserLifecycles(lifecycles map[string]UserLifecycle) Option {
+	return func(r *Reconciler) {
+		r.userLifecycles = lifecycles
+	}
+}
+
+// userLifecycle returns the okta lifecycle state of a governor user status
+func (r *Reconciler) userLifecycle(status string) UserLifecycle {
+	if l, ok := r.userLifecycles[status]; ok {
+		return l
+	}
+
+	if l, ok := defaultUserLifecycles[status]; ok {
+		return l
+	}
+
+	return UserLifecycleKeep
+}
+
+// skipUserStatus returns true if governor users with the status are skipped
+func (r *Reconciler) skipUserStatus(status string) bool {
+	return r.userLifecycle(status) == UserLifecycleSkip
+}
+
+// lifecycleChange returns the change moving an okta user with the status to the lifecycle state, empty when
+// there's nothing to change or the user can't be moved there
+func lifecycleChange(l UserLifecycle, oktaStatus string) string {
+	switch {
+	case l == UserLifecycleActive && oktaStatus == oktaUserStatusSuspended:
+		return lifecycleUnsuspend
+	case l == UserLifecycleSuspended && oktaStatus == oktaUserStatusActive:
+		return lifecycleSuspend
+	case l == UserLifecycleDeprovisioned && (oktaStatus == oktaUserStatusActive || oktaStatus == oktaUserStatusSuspended):
+		return lifecycleDeactivate
+	default:
+		return ""
+	}
+}
+
+// setUserLifecycle makes a lifecycle change to an okta user and counts it
+func (r *Reconciler) setUserLifecycle(ctx context.Context, logger *zap.Logger, oktaID, change string) error {
+	switch change {
+	case lifecycleSuspend:
+		if err := r.doOp(ctx, "okta.SuspendUser", func(ctx context.Context) error {
+			return r.oktaClient.SuspendUser(ctx, oktaID)
+		}); err != nil {
+			logger.Error("error suspending okta user", zap.Error(err))
+			return err
+		}
+
+		incCounter(ctx, usersSuspendedCounter)
+		runCountsFrom(ctx).userSuspended()
+	case lifecycleUnsuspend:
+		if err := r.doOp(ctx, "okta.UnsuspendUser", func(ctx context.Context) error {
+			return r.oktaClient.UnsuspendUser(ctx, oktaID)
+		}); err != nil {
+			logger.Error("error un-suspending okta user", zap.Error(err))
+			return err
+		}
+
+		incCounter(ctx, usersUnsuspendedCounter)
+	case lifecycleDeactivate:
+		if err := r.doOp(ctx, "okta.DeactivateUser", func(ctx context.Context) error {
+			return r.oktaClient.DeactivateUser(ctx, oktaID)
+		}); err != nil {
+			logger.Error("error deactivating okta user", zap.Error(err))
+			return err
+		}
+
+		incCounter(ctx, usersDeactivatedCounter)
+	}
+
+	return nil
+}
+
+// reconcileUserLifecycle moves the okta user of a governor user to the lifecycle state of the governor user
+// status and audits the change
+func (r *Reconciler) reconcileUserLifecycle(ctx context.Context, logger *zap.Logger, u *v1beta1.User, details *okta.UserDetails) {
+	l := r.userLifecycle(u.Status.String)
+
+	if l == UserLifecycleStaged && details.Status != oktaUserStatusStaged {
+		logger.Warn("okta user of a governor user status mapped to staged isn't staged", zap.String("okta.user.status", details.Status))
+		return
+	}
+
+	change := lifecycleChange(l, details.Status)
+	if change == "" {
+		return
+	}
+
+	logger = logger.With(zap.String("okta.user.id", details.ID), zap.String("okta.user.lifecycle", change))
+
+	var governorAt time.Time
+	if u.User != nil {
+		governorAt = u.UpdatedAt
+	}
+
+	if r.conflictWinner(ctx, ConflictResourceUsers, governorAt, details.StatusChanged) == SourceOfTruthOkta {
+		logger.Info("okta is the source of truth of the user status, skipping lifecycle change")
+		return
+	}
+
+	if r.dryRun() || r.detectOnlyUser(ctx, u.ID, u.Email) {
+		logger.Info("SKIP changing okta user lifecycle")
+		return
+	}
+
+	if err := r.setUserLifecycle(ctx, logger, details.ID, change); err != nil {
+		return
+	}
+
+	var event auctx.Payload
+
+	switch change {
+	case lifecycleSuspend:
+		event = auctx.UserSuspend{GovernorUserEmail: u.Email, GovernorUserID: u.ID, OktaUserID: details.ID}
+	case lifecycleUnsuspend:
+		event = auctx.UserUnsuspend{GovernorUserEmail: u.Email, GovernorUserID: u.ID, OktaUserID: details.ID}
+	default:
+		event = auctx.UserDeactivate{GovernorUserEmail: u.Email, GovernorUserID: u.ID, OktaUserID: details.ID}
+	}
+
+	if err := r.writeMutationEvent(ctx, event,
+		map[string]string{"okta.user.id": details.ID, "okta.user.status": details.Status},
+		map[string]string{"okta.user.id": details.ID, "governor.user.status": u.Status.String},
+	); err != nil {
+		logger.Error("error writing audit event", zap.Error(err))
+	}
+}
@@ -0,0 +1,164 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciler_userLifecycle(t *testing.T) {
+	r := New()
+
+	assert.Equal(t, UserLifecycleSkip, r.userLifecycle("pending"))
+	assert.Equal(t, UserLifecycleActive, r.userLifecycle("active"))
+	assert.Equal(t, UserLifecycleSuspended, r.userLifecycle("suspended"))
+	assert.Equal(t, UserLifecycleKeep, r.userLifecycle("contractor"))
+
+	r = New(WithUserLifecycles(map[string]UserLifecycle{
+		"suspended":  UserLifecycleDeprovisioned,
+		"contractor": UserLifecycleStaged,
+		"pending":    UserLifecycleKeep,
+	}))
+
+	assert.Equal(t, UserLifecycleKeep, r.userLifecycle("pending"))
+	assert.False(t, r.skipUserStatus("pending"))
+	assert.Equal(t, UserLifecycleActive, r.userLifecycle("active"))
+	assert.Equal(t, UserLifecycleDeprovisioned, r.userLifecycle("suspended"))
+	assert.Equal(t, UserLifecycleStaged, r.userLifecycle("contractor"))
+}
+
+func TestUserLifecycle_Valid(t *testing.T) {
+	assert.True(t, UserLifecycleDeprovisioned.Valid())
+	assert.True(t, UserLifecycleSkip.Valid())
+	assert.False(t, UserLifecycle("SUSPENDED").Valid())
+	assert.False(t, UserLifecycle("").Valid())
+}
+
+func Test_lifecycleChange(t *testing.T) {
+	tests := []struct {
+		lifecycle  UserLifecycle
+		oktaStatus string
+		want       string
+	}{
+		{lifecycle: UserLifecycleActive, oktaStatus: "SUSPENDED", want: lifecycleUnsuspend},
+		{lifecycle: UserLifecycleActive, oktaStatus: "ACTIVE"},
+		{lifecycle: UserLifecycleActive, oktaStatus: "DEPROVISIONED"},
+		{lifecycle: UserLifecycleSuspended, oktaStatus: "ACTIVE", want: lifecycleSuspend},
+		{lifecycle: UserLifecycleSuspended, oktaStatus: "STAGED"},
+		{lifecycle: UserLifecycleDeprovisioned, oktaStatus: "ACTIVE", want: lifecycleDeactivate},
+		{lifecycle: UserLifecycleDeprovisioned, oktaStatus: "SUSPENDED", want: lifecycleDeactivate},
+		{lifecycle: UserLifecycleDeprovisioned, oktaStatus: "DEPROVISIONED"},
+		{lifecycle: UserLifecycleStaged, oktaStatus: "ACTIVE"},
+		{lifecycle: UserLifecycleKeep, oktaStatus: "SUSPENDED"},
+		{lifecycle: UserLifecycleSkip, oktaStatus: "SUSPENDED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.lifecycle)+"/"+tt.oktaStatus, func(t *testing.T) {
+			assert.Equal(t, tt.want, lifecycleChange(tt.lifecycle, tt.oktaStatus))
+		})
+	}
+}
+
+func TestReconciler_reconcileUsersLifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		fail       bool
+		wantCalls  int
+		wantStatus map[string]string
+		wantEvents map[string]int
+	}{
+		{
+			name:       "suspend and unsuspend",
+			wantCalls:  2,
+			wantStatus: map[string]string{"okta-1": "SUSPENDED", "okta-2": "ACTIVE"},
+			wantEvents: map[string]int{"UserSuspend": 1, "UserUnsuspend": 1},
+		},
+		{
+			name:       "okta failure",
+			fail:       true,
+			wantCalls:  2,
+			wantStatus: map[string]string{"okta-1": "ACTIVE", "okta-2": "SUSPENDED"},
+			wantEvents: map[string]int{"UserSuspend": 0, "UserUnsuspend": 0},
+		},
+		{
+			name:       "dry run",
+			opts:       []Option{WithDryRun(true)},
+			wantStatus: map[string]string{"okta-1": "ACTIVE", "okta-2": "SUSPENDED"},
+			wantEvents: map[string]int{"UserSuspend": 0, "UserUnsuspend": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			o.AddUser("okta-1", "ACTIVE", map[string]interface{}{"email": "one@example.com"})
+			o.AddUser("okta-2", "SUSPENDED", map[string]interface{}{"email": "two@example.com"})
+
+			if tt.fail {
+				o.FailRequests(func(req testserver.Request) int {
+					if strings.Contains(req.Path, "/lifecycle/") {
+						return http.StatusInternalServerError
+					}
+
+					return 0
+				})
+			}
+
+			govUsers := []*v1beta1.User{}
+
+			for _, js := range []string{
+				`{"id":"gov-1","external_id":"okta-1","email":"one@example.com","status":"suspended"}`,
+				`{"id":"gov-2","external_id":"okta-2","email":"two@example.com","status":"active"}`,
+			} {
+				u := &v1beta1.User{}
+				require.NoError(t, json.Unmarshal([]byte(js), u))
+
+				govUsers = append(govUsers, u)
+			}
+
+			oktaUsers := newOktaUserIndex([]*okta.UserDetails{
+				{ID: "okta-1", Email: "one@example.com", Status: "ACTIVE"},
+				{ID: "okta-2", Email: "two@example.com", Status: "SUSPENDED"},
+			}, okta.UserMatchKeyEmail)
+
+			r, audit := newTestServerReconciler(t, o, g, tt.opts...)
+
+			ctx := r.withReconcileAuditEvent(context.TODO(), "test")
+
+			require.NoError(t, r.reconcileUsers(ctx, govUsers, oktaUsers))
+
+			calls := 0
+
+			for _, req := range o.Requests() {
+				if strings.Contains(req.Path, "/lifecycle/") {
+					calls++
+				}
+			}
+
+			assert.Equal(t, tt.wantCalls, calls)
+
+			for id, want := range tt.wantStatus {
+				assert.Equal(t, want, o.User(id).Status, id)
+			}
+
+			for event, want := range tt.wantEvents {
+				assert.Equal(t, want, strings.Count(audit.String(), `"`+event+`"`), event)
+			}
+		})
+	}
+}
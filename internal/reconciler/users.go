@@ -2,28 +2,37 @@ package reconciler
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/changes"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/redact"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
 	"go.uber.org/zap"
 )
 
-// cutoffUserDeleted is used to determine which deleted governor users will be removed from Okta
-var cutoffUserDeleted = time.Now().Add(-24 * time.Hour)
+// userDeletedWindow is how long after their deletion in governor deleted users are removed from Okta
+const userDeletedWindow = 24 * time.Hour
 
 // UserDelete deletes an okta user that has already been deleted in governor
 // an error will be returned if the user still exists in governor.
 func (r *Reconciler) UserDelete(ctx context.Context, govID string) (string, error) {
 	// get details about this user and verify it was actually deleted in governor
-	user, err := r.governorClient.User(ctx, govID, true)
+	user, err := callOp(ctx, r, "governor.User", func(ctx context.Context) (*v1alpha1.User, error) {
+		return r.governorClient.User(ctx, govID, true)
+	})
 	if err != nil {
 		r.logger.Error("failed to get user from governor", zap.Error(err))
 		return "", err
 	}
 
-	r.logger.Debug("got governor user response", zap.Any("user details", user))
+	r.logger.Debug("got governor user response", redact.Any("user details", user))
+
+	r.forgetGovernorUser(user.Email)
 
 	extID := user.ExternalID.String
 
@@ -33,62 +42,197 @@ func (r *Reconciler) UserDelete(ctx context.Context, govID string) (string, erro
 		zap.String("governor.user.email", user.Email),
 	)
 
-	if !userDeleted(user) {
+	if !userDeleted(user, r.now()) {
 		logger.Error("user still exists in governor")
 		return "", ErrUserStillExists
 	}
 
-	oktaID, err := r.oktaClient.GetUserIDByEmail(ctx, user.Email)
+	oktaID, err := r.oktaUserID(ctx, logger, user.ID, user.Email, extID)
 	if err != nil {
-		logger.Error("error looking up okta user by email address", zap.Error(err))
 		return "", err
 	}
 
 	logger = logger.With(zap.String("okta.user.id", oktaID))
 
-	if r.dryrun {
+	if r.dryRun() || r.detectOnlyUser(ctx, user.ID, user.Email) {
 		logger.Info("SKIP deleting okta user")
 		return extID, nil
 	}
 
-	logger.Info("deleting okta user")
+	// offboarding removes the user from all of their okta groups when enabled
+	if !r.offboardGroups {
+		if err := r.removeUserManagedGroups(ctx, logger, user.ID, user.Email, oktaID); err != nil {
+			logger.Warn("error removing deleted user from managed okta groups", zap.Error(err))
+		}
+	}
+
+	// users are only deactivated unless permanent deletes are enabled, a permanently deleted user
+	// and their history can't be restored
+	opts := []okta.DeleteUserOption{okta.WithClearSessions()}
+	counter := usersDeactivatedCounter
 
-	if err := r.oktaClient.DeactivateUser(ctx, oktaID); err != nil {
-		logger.Error("error deactivating user", zap.String("okta.user.id", oktaID), zap.Error(err))
+	var event auctx.Payload = auctx.UserDeactivate{GovernorUserEmail: user.Email, GovernorUserID: user.ID, OktaUserID: oktaID}
+
+	if r.offboardGroups {
+		opts = append(opts, okta.WithGroupRemoval())
 	}
 
-	if err := r.oktaClient.ClearUserSessions(ctx, oktaID); err != nil {
-		logger.Error("error clearing user sessions", zap.String("okta.user.id", oktaID), zap.Error(err))
+	if r.permanentUserDelete {
+		opts = append(opts, okta.WithPermanentDelete())
+		counter = usersDeletedCounter
+		event = auctx.UserDelete{GovernorUserEmail: user.Email, GovernorUserID: user.ID, OktaUserID: oktaID}
 	}
 
-	if err := r.oktaClient.DeleteUser(ctx, oktaID); err != nil {
-		logger.Error("error deleting okta user", zap.Error(err))
+	logger.Info("deleting okta user", zap.Bool("permanent", r.permanentUserDelete))
+
+	result, err := callOp(ctx, r, "okta.OffboardUser", func(ctx context.Context) (*okta.OffboardResult, error) {
+		return r.oktaClient.OffboardUser(ctx, oktaID, opts...)
+	})
+	if err != nil {
+		logger.Error("error deleting okta user", zap.Stringer("okta.user.offboard.steps", result), zap.Error(err))
+
+		if result != nil && result.Partial() {
+			r.userOffboardIncomplete(ctx, logger, user, oktaID, result, err)
+		}
+
 		return "", err
 	}
 
-	usersDeletedCounter.Inc()
+	incCounter(ctx, counter)
 
-	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "UserDelete", map[string]string{
-		"governor.user.email": user.Email,
-		"governor.user.id":    user.ID,
-		"okta.user.id":        oktaID,
-	}); err != nil {
+	if err := r.writeMutationEvent(ctx, event, map[string]string{"okta.user.id": oktaID}, nil); err != nil {
 		r.logger.Error("error writing audit event", zap.Error(err))
 	}
 
+	r.notifyDeprovisioned(ctx, user, oktaID)
+
 	return oktaID, nil
 }
 
+// notifyDeprovisioned publishes the deprovisioning notification of the okta user of a deleted governor user, the
+// notification is suppressed in dry-run and what-if mode
+func (r *Reconciler) notifyDeprovisioned(ctx context.Context, user *v1alpha1.User, oktaID string) {
+	d := changes.Deprovision{
+		Email:          user.Email,
+		OktaUserID:     oktaID,
+		GovernorUserID: user.ID,
+		Permanent:      r.permanentUserDelete,
+		Time:           r.now().UTC(),
+	}
+
+	if r.dryRun() || r.whatIf() {
+		r.deprovisionNotify.Suppress(d)
+		return
+	}
+
+	r.deprovisionNotify.Notify(ctx, d)
+}
+
+// userOffboardIncomplete audits the steps of an okta user offboarding when some of them failed, the user is
+// counted as deactivated if the deactivation went through
+func (r *Reconciler) userOffboardIncomplete(ctx context.Context, logger *zap.Logger, user *v1alpha1.User, oktaID string, result *okta.OffboardResult, err error) {
+	if result.Done(okta.OffboardStepDeactivate) {
+		incCounter(ctx, usersDeactivatedCounter)
+	}
+
+	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, auctx.UserOffboardIncomplete{
+		GovernorUserEmail: user.Email,
+		GovernorUserID:    user.ID,
+		OktaUserID:        oktaID,
+		Steps:             result.String(),
+		Error:             err.Error(),
+	}); err != nil {
+		logger.Error("error writing audit event", zap.Error(err))
+	}
+}
+
+// removeUserManagedGroups removes the okta user of a deleted governor user from the okta groups managed by
+// governor (the groups with a governor id) right away, instead of leaving the memberships until each of the
+// groups is reconciled.  Okta groups that aren't managed by governor are left alone.
+func (r *Reconciler) removeUserManagedGroups(ctx context.Context, logger *zap.Logger, govID, email, oktaID string) error {
+	groups, err := callOp(ctx, r, "okta.ListUserGroups", func(ctx context.Context) ([]*okt.Group, error) {
+		return r.oktaClient.ListUserGroups(ctx, oktaID)
+	})
+	if err != nil {
+		logger.Error("error listing okta user groups", zap.Error(err))
+		return err
+	}
+
+	errs := []error{}
+
+	for oktaGID, gid := range managedOktaGroups(groups) {
+		logger := logger.With(zap.String("okta.group.id", oktaGID), zap.String("governor.group.id", gid))
+
+		if r.dryRun() || r.detectOnlyGroup(ctx, gid, nil) {
+			logger.Info("SKIP removing deleted user from okta group")
+
+			r.status.pendingDeletion(PendingDeletion{Type: "GroupMemberRemove", OktaGroupID: oktaGID, OktaUserID: oktaID})
+
+			continue
+		}
+
+		err := r.doOp(ctx, "okta.RemoveGroupUser", func(ctx context.Context) error {
+			return r.oktaClient.RemoveGroupUser(ctx, oktaGID, oktaID)
+		})
+		if memberAlreadyRemoved(logger, oktaID, err) {
+			continue
+		}
+
+		if err != nil {
+			logger.Error("error removing deleted user from okta group", zap.Error(err))
+
+			errs = append(errs, err)
+
+			continue
+		}
+
+		incCounter(ctx, groupMembershipDeletedCounter)
+
+		if err := r.writeMutationEvent(ctx, auctx.GroupMemberRemove{
+			GovernorGroupID:   gid,
+			GovernorUserEmail: email,
+			GovernorUserID:    govID,
+			OktaGroupID:       oktaGID,
+			OktaUserID:        oktaID,
+		}, map[string]string{"okta.group.id": oktaGID, "okta.user.id": oktaID}, nil); err != nil {
+			logger.Error("error writing audit event", zap.Error(err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// managedOktaGroups returns the governor group id of the okta groups managed by governor, by okta group id
+func managedOktaGroups(groups []*okt.Group) map[string]string {
+	managed := map[string]string{}
+
+	for _, g := range groups {
+		gid, err := okta.GroupGovernorID(g)
+		if err != nil {
+			continue
+		}
+
+		managed[g.Id] = gid
+	}
+
+	return managed
+}
+
 // UserUpdate updates an existing governor user in okta.
-// Currently this is only used to suspend or un-suspend a user.
+// Currently this is only used to move the okta user to the lifecycle state of the governor user status, ie. to
+// suspend or un-suspend a user.
 func (r *Reconciler) UserUpdate(ctx context.Context, govID string) (string, error) {
-	user, err := r.governorClient.User(ctx, govID, false)
+	user, err := callOp(ctx, r, "governor.User", func(ctx context.Context) (*v1alpha1.User, error) {
+		return r.governorClient.User(ctx, govID, false)
+	})
 	if err != nil {
 		r.logger.Error("failed to get user from governor", zap.Error(err))
 		return "", err
 	}
 
-	r.logger.Debug("got governor user response", zap.Any("user details", user))
+	r.logger.Debug("got governor user response", redact.Any("user details", user))
+
+	r.forgetGovernorUser(user.Email)
 
 	extID := user.ExternalID.String
 
@@ -99,60 +243,430 @@ func (r *Reconciler) UserUpdate(ctx context.Context, govID string) (string, erro
 		zap.String("governor.user.status", user.Status.String),
 	)
 
-	if user.Status.String == v1alpha1.UserStatusPending {
-		logger.Info("user status is pending in governor, skipping")
+	if r.skipUserStatus(user.Status.String) {
+		logger.Info("user status is skipped in governor, skipping")
 		return "", ErrUserStatusPending
 	}
 
-	oktaUser, err := r.oktaClient.GetUser(ctx, extID)
+	oktaUser, err := callOp(ctx, r, "okta.GetUser", func(ctx context.Context) (*okt.User, error) {
+		return r.oktaClient.GetUser(ctx, extID)
+	})
 	if err != nil {
 		logger.Error("error getting okta user", zap.Error(err))
 		return "", err
 	}
 
-	if oktaUser.Status != "ACTIVE" && oktaUser.Status != "SUSPENDED" {
+	change := lifecycleChange(r.userLifecycle(user.Status.String), oktaUser.Status)
+	if change == "" {
 		return extID, nil
 	}
 
-	if r.dryrun {
+	var governorAt time.Time
+	if user.User != nil {
+		governorAt = user.UpdatedAt
+	}
+
+	if r.conflictWinner(ctx, ConflictResourceUsers, governorAt, oktaTime(oktaUser.StatusChanged)) == SourceOfTruthOkta {
+		logger.Info("okta is the source of truth of the user status, skipping update", zap.String("okta.user.status", oktaUser.Status))
+		return extID, nil
+	}
+
+	if r.dryRun() || r.detectOnlyUser(ctx, user.ID, user.Email) {
 		logger.Info("SKIP updating okta user")
 		return extID, nil
 	}
 
-	logger.Info("updating okta user")
+	logger.Info("updating okta user", zap.String("okta.user.lifecycle", change))
 
-	// user suspended
-	if user.Status.String == v1alpha1.UserStatusSuspended && oktaUser.Status == "ACTIVE" {
-		if err := r.oktaClient.SuspendUser(ctx, oktaUser.Id); err != nil {
-			logger.Error("error suspending okta user", zap.Error(err))
-			return "", err
+	if err := r.setUserLifecycle(ctx, logger, oktaUser.Id, change); err != nil {
+		return "", err
+	}
+
+	incCounter(ctx, usersUpdatedCounter)
+
+	if err := r.writeMutationEvent(ctx, auctx.UserUpdate{
+		GovernorUserEmail: user.Email,
+		GovernorUserID:    user.ID,
+		OktaUserID:        oktaUser.Id,
+	}, map[string]string{"okta.user.id": oktaUser.Id, "okta.user.status": oktaUser.Status}, map[string]string{"okta.user.id": oktaUser.Id, "governor.user.status": user.Status.String}); err != nil {
+		r.logger.Error("error writing audit event", zap.Error(err))
+	}
+
+	return oktaUser.Id, nil
+}
+
+// UserActivate adds an active governor user to the okta groups of their governor groups they aren't a member of.
+// Memberships assigned while the user was pending would otherwise only be added to okta by the next reconcile of
+// each group.  It returns the okta user id and the okta groups the user was added to.
+func (r *Reconciler) UserActivate(ctx context.Context, govID string) (string, []string, error) {
+	user, err := callOp(ctx, r, "governor.User", func(ctx context.Context) (*v1alpha1.User, error) {
+		return r.governorClient.User(ctx, govID, false)
+	})
+	if err != nil {
+		r.logger.Error("failed to get user from governor", zap.Error(err))
+		return "", nil, err
+	}
+
+	extID := user.ExternalID.String
+
+	logger := r.logger.With(
+		zap.String("governor.user.id", user.ID),
+		zap.String("governor.external_id", extID),
+		zap.String("governor.user.email", user.Email),
+		zap.String("governor.user.status", user.Status.String),
+	)
+
+	if r.userLifecycle(user.Status.String) != UserLifecycleActive {
+		logger.Debug("user isn't active in governor, skipping group memberships")
+		return "", nil, ErrUserNotActive
+	}
+
+	oktaID, err := r.oktaUserID(ctx, logger, user.ID, user.Email, extID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	logger = logger.With(zap.String("okta.user.id", oktaID))
+
+	if len(user.Memberships) == 0 {
+		return oktaID, nil, nil
+	}
+
+	groups, err := callOp(ctx, r, "okta.ListUserGroups", func(ctx context.Context) ([]*okt.Group, error) {
+		return r.oktaClient.ListUserGroups(ctx, oktaID)
+	})
+	if err != nil {
+		logger.Error("error listing okta user groups", zap.Error(err))
+		return oktaID, nil, err
+	}
+
+	member := map[string]bool{}
+	for _, gid := range managedOktaGroups(groups) {
+		member[gid] = true
+	}
+
+	added := []string{}
+	errs := []error{}
+
+	for _, gid := range sortedCopy(user.Memberships) {
+		if member[gid] {
+			continue
+		}
+
+		oktaGID, err := r.userActivateMembership(ctx, logger.With(zap.String("governor.group.id", gid)), user, oktaID, gid)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if oktaGID != "" {
+			added = append(added, oktaGID)
 		}
 	}
 
-	// user un-suspended
-	if user.Status.String == v1alpha1.UserStatusActive && oktaUser.Status == "SUSPENDED" {
-		if err := r.oktaClient.UnsuspendUser(ctx, oktaUser.Id); err != nil {
-			logger.Error("error un-suspending okta user", zap.Error(err))
+	return oktaID, added, errors.Join(errs...)
+}
+
+// userActivateMembership adds the okta user of an activated governor user to the okta group of one of their
+// governor groups, it returns the okta group id or an empty id when the user wasn't added
+func (r *Reconciler) userActivateMembership(ctx context.Context, logger *zap.Logger, user *v1alpha1.User, oktaID, gid string) (string, error) {
+	group, err := callOp(ctx, r, "governor.Group", func(ctx context.Context) (*v1alpha1.Group, error) {
+		return r.governorClient.Group(ctx, gid, false)
+	})
+	if err != nil {
+		logger.Error("error getting governor group", zap.Error(err))
+		return "", err
+	}
+
+	logger = logger.With(zap.String("governor.group.slug", group.Slug))
+
+	oktaGID, err := r.oktaGroupID(ctx, logger, group)
+
+	switch {
+	case errors.Is(err, okta.ErrGroupsNotFound):
+		// the group is created with its members by its next reconcile
+		logger.Info("okta group of governor group not found, skipping membership")
+		return "", nil
+	case err != nil:
+		logger.Error("error getting group by governor id", zap.Error(err))
+		return "", err
+	}
+
+	logger = logger.With(zap.String("okta.group.id", oktaGID))
+
+	if r.dryRun() || r.detectOnlyGroup(ctx, group.ID, group) {
+		logger.Info("SKIP adding activated user to okta group")
+		return "", nil
+	}
+
+	if err := r.doOp(ctx, "okta.AddGroupUser", func(ctx context.Context) error {
+		return r.oktaClient.AddGroupUser(ctx, oktaGID, oktaID)
+	}); err != nil {
+		logger.Error("failed to add activated user to okta group", zap.Error(err))
+		return "", err
+	}
+
+	logger.Info("added activated user to okta group")
+
+	incCounter(ctx, groupMembershipCreatedCounter)
+
+	if err := r.writeMutationEvent(ctx, auctx.GroupMemberAdd{
+		GovernorGroupSlug: group.Slug,
+		GovernorGroupID:   group.ID,
+		GovernorUserEmail: user.Email,
+		GovernorUserID:    user.ID,
+		OktaGroupID:       oktaGID,
+		OktaUserID:        oktaID,
+	}, nil, map[string]string{"okta.group.id": oktaGID, "okta.user.id": oktaID}); err != nil {
+		logger.Error("error writing audit event", zap.Error(err))
+	}
+
+	return oktaGID, nil
+}
+
+// oktaUserID looks up the okta user id of a governor user by the governor id in the okta user profile, when
+// the governor id is written to okta, and falls back to the user matching key
+func (r *Reconciler) oktaUserID(ctx context.Context, logger *zap.Logger, govID, email, externalID string) (string, error) {
+	if r.userGovernorID {
+		oktaID, err := callOp(ctx, r, "okta.GetUserIDByGovernorID", func(ctx context.Context) (string, error) {
+			return r.oktaClient.GetUserIDByGovernorID(ctx, govID)
+		})
+
+		switch {
+		case err == nil:
+			return oktaID, nil
+		case errors.Is(err, okta.ErrUsersNotFound):
+			logger.Debug("okta user not found by governor id, looking up by matching key", zap.String("user.match_key", string(r.userMatchKey)))
+		default:
+			logger.Error("error looking up okta user by governor id", zap.Error(err))
 			return "", err
 		}
 	}
 
-	usersUpdatedCounter.Inc()
+	oktaID, err := r.oktaUserIDByMatchKey(ctx, email, externalID)
+	if err != nil {
+		logger.Error("error looking up okta user by matching key", zap.String("user.match_key", string(r.userMatchKey)), zap.Error(err))
+		return "", err
+	}
 
-	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, "UserUpdate", map[string]string{
-		"governor.user.email": user.Email,
-		"governor.user.id":    user.ID,
-		"okta.user.id":        oktaUser.Id,
+	return oktaID, nil
+}
+
+// oktaUserIDByMatchKey looks up the okta user id of a governor user by the user matching key, the governor
+// email is matched with the okta email or login and the governor external id with the okta user id
+func (r *Reconciler) oktaUserIDByMatchKey(ctx context.Context, email, externalID string) (string, error) {
+	value := email
+	if r.userMatchKey == okta.UserMatchKeyExternalID {
+		value = externalID
+	}
+
+	return callOp(ctx, r, "okta.GetUserIDBy", func(ctx context.Context) (string, error) {
+		return r.oktaClient.GetUserIDBy(ctx, r.userMatchKey, value)
+	})
+}
+
+// setUserGovernorID writes the governor id to the profile of a matched okta user
+func (r *Reconciler) setUserGovernorID(ctx context.Context, logger *zap.Logger, govID string, details *okta.UserDetails) {
+	logger = logger.With(zap.String("okta.user.id", details.ID))
+
+	if r.dryRun() || r.detectOnlyUser(ctx, govID, "") {
+		logger.Info("SKIP setting governor id on okta user")
+		return
+	}
+
+	if err := r.doOp(ctx, "okta.SetUserGovernorID", func(ctx context.Context) error {
+		return r.oktaClient.SetUserGovernorID(ctx, details.ID, govID)
 	}); err != nil {
-		r.logger.Error("error writing audit event", zap.Error(err))
+		logger.Error("error setting governor id on okta user", zap.Error(err))
+		return
 	}
 
-	return oktaUser.Id, nil
+	if err := r.writeMutationEvent(ctx, auctx.UserGovernorIDUpdate{
+		GovernorUserID: govID,
+		OktaUserID:     details.ID,
+	}, map[string]string{"okta.user.id": details.ID, "governor.id": details.GovernorID}, map[string]string{"okta.user.id": details.ID, "governor.id": govID}); err != nil {
+		logger.Error("error writing audit event", zap.Error(err))
+	}
+
+	details.GovernorID = govID
+}
+
+// userEmailDrift handles a governor user whose email doesn't match the email of their okta user, ie. after
+// the primary email was changed in okta.  The drift is logged and counted, and the governor user's email is
+// updated to the okta email when enabled.
+func (r *Reconciler) userEmailDrift(ctx context.Context, logger *zap.Logger, u *v1beta1.User, details *okta.UserDetails) {
+	logger = logger.With(zap.String("okta.user.id", details.ID), zap.String("okta.user.email", details.Email))
+
+	incCounter(ctx, userEmailDriftCounter)
+
+	if !r.syncUserEmail {
+		logger.Warn("governor user email differs from okta user email")
+		return
+	}
+
+	if r.skipGovernorWrites() {
+		logger.Info("SKIP updating governor user email")
+		return
+	}
+
+	if _, err := callOp(ctx, r, "governor.UpdateUser", func(ctx context.Context) (*v1alpha1.User, error) {
+		return r.governorClient.UpdateUser(ctx, u.ID, &v1alpha1.UserReq{
+			Email:      details.Email,
+			ExternalID: u.ExternalID.String,
+			Name:       u.Name,
+			Status:     u.Status.String,
+		})
+	}); err != nil {
+		logger.Error("error updating governor user email", zap.Error(err))
+		return
+	}
+
+	logger.Info("updated governor user email from okta")
+
+	r.forgetGovernorUser(u.Email, details.Email)
+
+	event := auctx.GovernorUserEmailUpdate{
+		GovernorUserID:       u.ID,
+		GovernorUserEmail:    u.Email,
+		GovernorUserNewEmail: details.Email,
+		OktaUserID:           details.ID,
+	}
+
+	r.changes.Publish(ctx, changes.SystemGovernor, event.EventType(), auctx.Target(event))
+
+	if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, event); err != nil {
+		logger.Error("error writing audit event", zap.Error(err))
+	}
+}
+
+// nonHumanAccount returns true when the okta user is a non-human account (ie. a service or bot account)
+// matched by the configured rules, these are left out of governor
+func (r *Reconciler) nonHumanAccount(ctx context.Context, logger *zap.Logger, u *okt.User) bool {
+	rule, ok := r.nonHumanAccounts.Match(u)
+	if !ok {
+		return false
+	}
+
+	incCounter(ctx, nonHumanUsersSkippedCounter)
+
+	logger.Debug("skipping non-human okta account", zap.String("okta.user.id", u.Id), zap.Stringer("rule", rule))
+
+	return true
+}
+
+// oktaUserIndex looks up okta users for governor users by the okta user id in the governor external id,
+// then by the governor id in the okta user profile and finally by the user matching key (email or login)
+// for users that have neither, falling back to the match aliases (ie. the secondEmail).  Matching by email
+// alone breaks when users change their primary email.
+type oktaUserIndex struct {
+	byID         map[string]*okta.UserDetails
+	byGovernorID map[string]*okta.UserDetails
+	byMatchKey   map[string]*okta.UserDetails
+
+	// byAlias is nil for aliases shared by several okta users, which don't match any of them
+	byAlias map[string]*okta.UserDetails
+}
+
+// newOktaUserIndex indexes the okta user details, there is no fallback when matching by externalId
+func newOktaUserIndex(users []*okta.UserDetails, key okta.UserMatchKey) *oktaUserIndex {
+	idx := &oktaUserIndex{
+		byID:         make(map[string]*okta.UserDetails, len(users)),
+		byGovernorID: make(map[string]*okta.UserDetails),
+		byMatchKey:   make(map[string]*okta.UserDetails, len(users)),
+		byAlias:      make(map[string]*okta.UserDetails),
+	}
+
+	for _, u := range users {
+		idx.byID[u.ID] = u
+
+		if u.GovernorID != "" {
+			idx.byGovernorID[u.GovernorID] = u
+		}
+
+		switch key {
+		case okta.UserMatchKeyExternalID:
+		case okta.UserMatchKeyLogin:
+			if u.Login != "" {
+				idx.byMatchKey[u.Login] = u
+			}
+		default:
+			idx.byMatchKey[u.Email] = u
+		}
+
+		for _, a := range u.MatchAliases(key) {
+			if other, ok := idx.byAlias[a]; ok && other != u {
+				idx.byAlias[a] = nil
+				continue
+			}
+
+			idx.byAlias[a] = u
+		}
+	}
+
+	return idx
+}
+
+// claim marks the aliases of okta users that already belong to a governor user through the external id, the
+// governor id or the matching key as ambiguous, so an alias never hands the same okta user to a second governor user
+func (i *oktaUserIndex) claim(govUsers []*v1beta1.User) {
+	claimed := make(map[*okta.UserDetails]bool, len(govUsers))
+
+	for _, u := range govUsers {
+		if d, ok := i.lookupPrimary(u.ID, u.ExternalID.String, u.Email); ok {
+			claimed[d] = true
+		}
+	}
+
+	for a, d := range i.byAlias {
+		if d != nil && claimed[d] {
+			i.byAlias[a] = nil
+		}
+	}
+}
+
+// lookup returns the okta user for a governor user.  The aliases are only tried when no okta user has the email as
+// its matching key so an alias never takes the governor user of another okta user's primary email, and only for
+// governor users without an external id naming another okta user.
+func (i *oktaUserIndex) lookup(govID, externalID, email string) (*okta.UserDetails, bool) {
+	if u, ok := i.lookupPrimary(govID, externalID, email); ok {
+		return u, true
+	}
+
+	if _, ok := i.byMatchKey[email]; ok {
+		return nil, false
+	}
+
+	u, ok := i.byAlias[email]
+	if !ok || u == nil || (externalID != "" && externalID != u.ID) || (u.GovernorID != "" && u.GovernorID != govID) {
+		return nil, false
+	}
+
+	return u, true
+}
+
+// lookupPrimary returns the okta user for a governor user without the aliases, which are too loose to act on
+// deleted governor users.  An okta user with the same email (or login) that belongs to a different governor user
+// is not a match.
+func (i *oktaUserIndex) lookupPrimary(govID, externalID, email string) (*okta.UserDetails, bool) {
+	if u, ok := i.byID[externalID]; ok && externalID != "" {
+		return u, true
+	}
+
+	if u, ok := i.byGovernorID[govID]; ok {
+		return u, true
+	}
+
+	u, ok := i.byMatchKey[email]
+	if !ok || (u.GovernorID != "" && u.GovernorID != govID) {
+		return nil, false
+	}
+
+	return u, true
 }
 
-// userDeleted returns true if the given user has been deleted in governor within the specified cutoff time period.
+// userDeleted returns true if the given user has been deleted in governor within the userDeletedWindow before now.
 // The function also performs some basic user validation and will return false if anything with the user doesn't look right
-func userDeleted(user *v1alpha1.User) bool {
+func userDeleted(user *v1alpha1.User, now time.Time) bool {
 	if user == nil {
 		return false
 	}
@@ -166,16 +680,16 @@ func userDeleted(user *v1alpha1.User) bool {
 		return false
 	}
 
-	if user.DeletedAt.Time.After(cutoffUserDeleted) {
+	if user.DeletedAt.Time.After(now.Add(-userDeletedWindow)) {
 		return true
 	}
 
 	return false
 }
 
-// userDeletedV2 returns true if the given user has been deleted in governor within the specified cutoff time period.
+// userDeletedV2 returns true if the given user has been deleted in governor within the userDeletedWindow before now.
 // The function also performs some basic user validation and will return false if anything with the user doesn't look right
-func userDeletedV2(user *v1beta1.User) bool {
+func userDeletedV2(user *v1beta1.User, now time.Time) bool {
 	if user == nil {
 		return false
 	}
@@ -189,7 +703,7 @@ func userDeletedV2(user *v1beta1.User) bool {
 		return false
 	}
 
-	if user.DeletedAt.Time.After(cutoffUserDeleted) {
+	if user.DeletedAt.Time.After(now.Add(-userDeletedWindow)) {
 		return true
 	}
 
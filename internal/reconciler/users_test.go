@@ -1,12 +1,21 @@
 package reconciler
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 	"time"
 
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/testserver"
+	"github.com/metal-toolbox/gov-okta-addon/internal/usercache"
 	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1beta1"
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/volatiletech/null/v8"
+	"go.uber.org/zap"
 )
 
 func Test_userDeleted(t *testing.T) {
@@ -168,9 +177,356 @@ func Test_userDeleted(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := userDeleted(tt.args.user); got != tt.want {
+			if got := userDeleted(tt.args.user, time.Now()); got != tt.want {
 				t.Errorf("userDeleted() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_oktaUserIndex(t *testing.T) {
+	byGovID := &okta.UserDetails{ID: "okta1", Email: "old@example.com", GovernorID: "gov1"}
+	byEmail := &okta.UserDetails{ID: "okta2", Email: "bob@example.com"}
+	otherGovID := &okta.UserDetails{ID: "okta3", Email: "shared@example.com", GovernorID: "gov3"}
+	renamed := &okta.UserDetails{ID: "okta6", Email: "alice.new@example.com"}
+
+	idx := newOktaUserIndex([]*okta.UserDetails{byGovID, byEmail, otherGovID, renamed}, okta.UserMatchKeyEmail)
+
+	tests := []struct {
+		name       string
+		govID      string
+		externalID string
+		email      string
+		want       *okta.UserDetails
+		wantFound  bool
+	}{
+		{
+			name:       "external id wins over a changed email",
+			govID:      "gov6",
+			externalID: "okta6",
+			email:      "alice@example.com",
+			want:       renamed,
+			wantFound:  true,
+		},
+		{
+			name:      "governor id wins over a changed email",
+			govID:     "gov1",
+			email:     "new@example.com",
+			want:      byGovID,
+			wantFound: true,
+		},
+		{
+			name:      "email fallback without governor id",
+			govID:     "gov2",
+			email:     "bob@example.com",
+			want:      byEmail,
+			wantFound: true,
+		},
+		{
+			name:  "email belongs to another governor user",
+			govID: "gov4",
+			email: "shared@example.com",
+		},
+		{
+			name:       "not found",
+			govID:      "gov5",
+			externalID: "okta5",
+			email:      "nobody@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := idx.lookup(tt.govID, tt.externalID, tt.email)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_oktaUserIndexMatchKey(t *testing.T) {
+	rotated := &okta.UserDetails{ID: "okta1", Email: "alice@new.example.com", Login: "alice@old.example.com"}
+
+	tests := []struct {
+		name      string
+		key       okta.UserMatchKey
+		email     string
+		wantFound bool
+	}{
+		{
+			name:      "email falls back to the stable login",
+			key:       okta.UserMatchKeyEmail,
+			email:     "alice@old.example.com",
+			wantFound: true,
+		},
+		{
+			name:      "login matches after the email rotated",
+			key:       okta.UserMatchKeyLogin,
+			email:     "alice@old.example.com",
+			wantFound: true,
+		},
+		{
+			name:  "no email fallback when matching by external id",
+			key:   okta.UserMatchKeyExternalID,
+			email: "alice@new.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := newOktaUserIndex([]*okta.UserDetails{rotated}, tt.key)
+
+			_, found := idx.lookup("gov1", "", tt.email)
+			assert.Equal(t, tt.wantFound, found)
+		})
+	}
+}
+
+func Test_oktaUserIndexAliases(t *testing.T) {
+	alice := &okta.UserDetails{ID: "okta1", Email: "alice@example.com", SecondEmail: "alice@old.example.com"}
+	bob := &okta.UserDetails{ID: "okta2", Email: "bob@example.com", SecondEmail: "team@example.com"}
+	carol := &okta.UserDetails{ID: "okta3", Email: "carol@example.com", SecondEmail: "team@example.com"}
+	dave := &okta.UserDetails{ID: "okta4", Email: "dave@example.com", SecondEmail: "bob@example.com"}
+	erin := &okta.UserDetails{ID: "okta5", Email: "erin@example.com", SecondEmail: "erin@old.example.com", GovernorID: "gov5"}
+
+	idx := newOktaUserIndex([]*okta.UserDetails{alice, bob, carol, dave, erin}, okta.UserMatchKeyEmail)
+
+	tests := []struct {
+		name  string
+		govID string
+		email string
+		want  *okta.UserDetails
+	}{
+		{
+			name:  "secondary email",
+			govID: "gov1",
+			email: "alice@old.example.com",
+			want:  alice,
+		},
+		{
+			name:  "primary email wins over an alias",
+			govID: "gov2",
+			email: "bob@example.com",
+			want:  bob,
+		},
+		{
+			name:  "alias shared by several okta users",
+			govID: "gov3",
+			email: "team@example.com",
+		},
+		{
+			name:  "alias of an okta user of another governor user",
+			govID: "gov6",
+			email: "erin@old.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := idx.lookup(tt.govID, "", tt.email)
+			assert.Equal(t, tt.want != nil, found)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_oktaUserIndexAliasCollisions(t *testing.T) {
+	frank := &okta.UserDetails{ID: "okta1", Email: "frank@example.com", SecondEmail: "frank@old.example.com"}
+	grace := &okta.UserDetails{ID: "okta2", Email: "grace@example.com", SecondEmail: "grace@old.example.com"}
+
+	govUser := func(js string) *v1beta1.User {
+		u := &v1beta1.User{}
+		require.NoError(t, json.Unmarshal([]byte(js), u))
+
+		return u
+	}
+
+	idx := newOktaUserIndex([]*okta.UserDetails{frank, grace}, okta.UserMatchKeyEmail)
+	idx.claim([]*v1beta1.User{
+		govUser(`{"id":"gov1","email":"frank@example.com"}`),
+		govUser(`{"id":"gov2","email":"frank@old.example.com"}`),
+		govUser(`{"id":"gov3","email":"grace@old.example.com"}`),
+	})
+
+	tests := []struct {
+		name       string
+		govID      string
+		externalID string
+		email      string
+		want       *okta.UserDetails
+	}{
+		{
+			name:  "primary email claims the okta user",
+			govID: "gov1",
+			email: "frank@example.com",
+			want:  frank,
+		},
+		{
+			name:  "alias of an okta user claimed by another governor user",
+			govID: "gov2",
+			email: "frank@old.example.com",
+		},
+		{
+			name:  "alias of an unclaimed okta user",
+			govID: "gov3",
+			email: "grace@old.example.com",
+			want:  grace,
+		},
+		{
+			name:       "alias with an external id naming another okta user",
+			govID:      "gov3",
+			externalID: "okta9",
+			email:      "grace@old.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := idx.lookup(tt.govID, tt.externalID, tt.email)
+			assert.Equal(t, tt.want != nil, found)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("deleted users never match by alias", func(t *testing.T) {
+		_, found := idx.lookupPrimary("gov3", "", "grace@old.example.com")
+		assert.False(t, found)
+	})
+}
+
+func TestReconciler_userEmailDrift(t *testing.T) {
+	tests := []struct {
+		name          string
+		syncUserEmail bool
+		dryrun        bool
+		want          *v1alpha1.UserReq
+	}{
+		{
+			name: "drift is only logged by default",
+		},
+		{
+			name:          "email updated from okta",
+			syncUserEmail: true,
+			want: &v1alpha1.UserReq{
+				Email:      "alice.new@example.com",
+				ExternalID: "okta-user-1",
+				Status:     v1alpha1.UserStatusActive,
+			},
+		},
+		{
+			name:          "dry run",
+			syncUserEmail: true,
+			dryrun:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gc := &mockGovClient{}
+
+			u := testGovUser(t, "user-1", "alice@example.com")
+			u.Status = null.StringFrom(v1alpha1.UserStatusActive)
+
+			cache := usercache.New[*v1beta1.User]("test", 10, time.Minute)
+			cache.Set(u.Email, u)
+
+			r := &Reconciler{
+				governorClient: gc,
+				logger:         zap.NewNop(),
+				syncUserEmail:  tt.syncUserEmail,
+				dryrun:         tt.dryrun,
+				userCache:      cache,
+			}
+
+			r.userEmailDrift(context.TODO(), r.logger, u, &okta.UserDetails{ID: "okta-user-1", Email: "alice.new@example.com"})
+
+			assert.Equal(t, tt.want, gc.updatedUsers["user-1"])
+
+			// the user cache only forgets the user once its email was updated
+			_, cached := cache.Get(u.Email)
+			assert.Equal(t, tt.want == nil, cached)
+		})
+	}
+}
+
+func Test_managedOktaGroups(t *testing.T) {
+	groups := []*okt.Group{
+		{Id: "okta-group-1", Profile: &okt.GroupProfile{GroupProfileMap: okt.GroupProfileMap{"governor_id": "group-1"}}},
+		{Id: "okta-group-2", Profile: &okt.GroupProfile{Name: "Everyone"}},
+		{Id: "okta-group-3", Profile: &okt.GroupProfile{GroupProfileMap: okt.GroupProfileMap{"governor_id": ""}}},
+		{Id: "okta-group-4"},
+		{Id: "okta-group-5", Profile: &okt.GroupProfile{GroupProfileMap: okt.GroupProfileMap{"governor_id": "group-5"}}},
+	}
+
+	assert.Equal(t, map[string]string{"okta-group-1": "group-1", "okta-group-5": "group-5"}, managedOktaGroups(groups))
+	assert.Empty(t, managedOktaGroups(nil))
+}
+
+func TestReconciler_UserActivate(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		opts        []Option
+		wantAdded   []string
+		wantMembers []string
+		wantErr     error
+	}{
+		{
+			name:        "memberships added",
+			status:      v1alpha1.UserStatusActive,
+			wantAdded:   []string{"00g-platform"},
+			wantMembers: []string{"okta-1"},
+		},
+		{
+			name:    "pending user",
+			status:  v1alpha1.UserStatusPending,
+			wantErr: ErrUserNotActive,
+		},
+		{
+			name:   "dry run",
+			status: v1alpha1.UserStatusActive,
+			opts:   []Option{WithDryRun(true)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testserver.NewOkta()
+			defer o.Close()
+
+			g := testserver.NewGovernor()
+			defer g.Close()
+
+			g.AddUser(&testserver.GovernorUser{ID: "user-1", ExternalID: "okta-1", Name: "User 1", Email: "user-1@example.com", Status: tt.status})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Members: []string{"user-1"}})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-2", Name: "Security", Slug: "security", Members: []string{"user-1"}})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-3", Name: "New", Slug: "new", Members: []string{"user-1"}})
+			g.AddGroup(&testserver.GovernorGroup{ID: "group-4", Name: "Other", Slug: "other"})
+
+			o.AddUser("okta-1", "ACTIVE", testOktaProfile("user-1@example.com"))
+			o.AddGroup("00g-platform", "Platform", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-1"})
+			o.AddGroup("00g-security", "Security", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-2"}, "okta-1")
+			o.AddGroup("00g-other", "Other", map[string]interface{}{okta.GroupProfileGovernorIDKey: "group-4"})
+
+			r, audit := newTestServerReconciler(t, o, g, tt.opts...)
+
+			_, added, err := r.UserActivate(r.withReconcileAuditEvent(context.TODO(), "test"), "user-1")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.ElementsMatch(t, tt.wantAdded, added)
+			assert.ElementsMatch(t, tt.wantMembers, o.GroupMembers("00g-platform"))
+			assert.Equal(t, []string{"okta-1"}, o.GroupMembers("00g-security"))
+			assert.Empty(t, o.GroupMembers("00g-other"))
+
+			if len(tt.wantAdded) > 0 {
+				assert.Contains(t, audit.String(), "GroupMemberAdd")
+			} else {
+				assert.NotContains(t, audit.String(), "GroupMemberAdd")
+			}
+		})
+	}
+}
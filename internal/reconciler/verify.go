@@ -0,0 +1,294 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+
+	okt "github.com/okta/okta-sdk-golang/v2/okta"
+	"go.uber.org/zap"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+)
+
+// appliedChange is an okta mutation applied by the reconciler, identified by its audit event type and target
+type appliedChange struct {
+	Type   string
+	Target map[string]string
+}
+
+// changeVerifier re-reads okta to check that an applied change took effect, it returns ErrChangeNotApplied
+// when okta doesn't reflect the change
+type changeVerifier func(ctx context.Context, r *Reconciler, target map[string]string) error
+
+// changeVerifiers are the verifiers for the applied change types that can be checked with a read from okta
+var changeVerifiers = map[string]changeVerifier{
+	"GroupCreate":            verifyGroupExists,
+	"GroupUpdate":            verifyGroupExists,
+	"GroupDelete":            verifyGroupDeleted,
+	"GroupMemberAdd":         verifyGroupMember(true),
+	"GroupMemberRemove":      verifyGroupMember(false),
+	"GroupOwnerAdd":          verifyGroupOwner(true),
+	"GroupOwnerRemove":       verifyGroupOwner(false),
+	"GroupApplicationAdd":    verifyGroupApplication(true),
+	"GroupApplicationUpdate": verifyGroupApplication(true),
+	"GroupApplicationRemove": verifyGroupApplication(false),
+	"UserDeactivate":         verifyUserDeactivated,
+	"UserSuspend":            verifyUserStatus("SUSPENDED"),
+	"UserUnsuspend":          verifyUserStatus("ACTIVE"),
+	"UserDelete":             verifyUserDeleted,
+	"UserGovernorIDUpdate":   verifyUserGovernorID,
+}
+
+// changeSampler keeps a uniform random sample of the changes applied since it was last taken (reservoir
+// sampling), so verifying a run costs at most size okta reads no matter how many changes were applied
+type changeSampler struct {
+	mu     sync.Mutex
+	size   int
+	seen   int
+	sample []appliedChange
+	intn   func(int) int
+}
+
+func newChangeSampler(size int) *changeSampler {
+	if size <= 0 {
+		return nil
+	}
+
+	return &changeSampler{size: size, intn: rand.Intn}
+}
+
+// add offers an applied change to the sample
+func (s *changeSampler) add(c appliedChange) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen++
+
+	if len(s.sample) < s.size {
+		s.sample = append(s.sample, c)
+		return
+	}
+
+	if i := s.intn(s.seen); i < s.size {
+		s.sample[i] = c
+	}
+}
+
+// take returns the sampled changes and the number of changes they were sampled from, and starts a new sample
+func (s *changeSampler) take() ([]appliedChange, int) {
+	if s == nil {
+		return nil, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sample, seen := s.sample, s.seen
+
+	s.sample = nil
+	s.seen = 0
+
+	return sample, seen
+}
+
+// sampleAppliedChange offers an applied okta change for verification, changes that can't be verified with a
+// read from okta are ignored
+func (r *Reconciler) sampleAppliedChange(evType string, target map[string]string) {
+	if _, ok := changeVerifiers[evType]; !ok {
+		return
+	}
+
+	r.verifySampler.add(appliedChange{Type: evType, Target: target})
+}
+
+// verifyAppliedChanges re-reads okta for a random sample of the changes applied since the last verification
+// and reports the changes that didn't take effect
+func (r *Reconciler) verifyAppliedChanges(ctx context.Context) {
+	sample, seen := r.verifySampler.take()
+	if len(sample) == 0 {
+		return
+	}
+
+	failed := 0
+
+	for _, c := range sample {
+		logger := r.logger.With(zap.String("change.type", c.Type), zap.Any("change.target", c.Target))
+
+		incCounter(ctx, changesVerifiedCounter)
+
+		err := changeVerifiers[c.Type](ctx, r, c.Target)
+
+		switch {
+		case err == nil:
+			logger.Debug("verified applied change")
+		case errors.Is(err, ErrChangeNotApplied):
+			failed++
+
+			incCounter(ctx, changeVerificationFailedCounter)
+
+			logger.Error("applied change not found in okta", zap.Error(err))
+
+			if err := auctx.WriteAuditEvent(ctx, r.auditEventWriter, auctx.ChangeVerificationFailed{
+				ChangeType: c.Type,
+				Change:     c.Target,
+			}); err != nil {
+				logger.Error("error writing audit event", zap.Error(err))
+			}
+		default:
+			incCounter(ctx, changeVerificationErrorsCounter)
+
+			logger.Warn("error verifying applied change", zap.Error(err))
+		}
+	}
+
+	r.logger.Info("verified sample of applied changes",
+		zap.Int("num.changes.applied", seen),
+		zap.Int("num.changes.verified", len(sample)),
+		zap.Int("num.changes.failed", failed),
+	)
+}
+
+func verifyGroupExists(ctx context.Context, r *Reconciler, target map[string]string) error {
+	gid, err := r.oktaClient.GetGroupByGovernorID(ctx, target["governor.group.id"])
+	if err != nil {
+		if errors.Is(err, okta.ErrGroupsNotFound) {
+			return fmt.Errorf("%w: group not found", ErrChangeNotApplied)
+		}
+
+		return err
+	}
+
+	if gid != target["okta.group.id"] {
+		return fmt.Errorf("%w: governor group is okta group %s", ErrChangeNotApplied, gid)
+	}
+
+	return nil
+}
+
+func verifyGroupDeleted(ctx context.Context, r *Reconciler, target map[string]string) error {
+	gid, err := r.oktaClient.GetGroupByGovernorID(ctx, target["governor.group.id"])
+	if err != nil {
+		if errors.Is(err, okta.ErrGroupsNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	return fmt.Errorf("%w: okta group %s still exists", ErrChangeNotApplied, gid)
+}
+
+func verifyGroupMember(member bool) changeVerifier {
+	return func(ctx context.Context, r *Reconciler, target map[string]string) error {
+		groups, err := r.oktaClient.ListUserGroups(ctx, target["okta.user.id"])
+		if err != nil {
+			return err
+		}
+
+		found := slices.ContainsFunc(groups, func(g *okt.Group) bool { return g.Id == target["okta.group.id"] })
+
+		return checkApplied(found, member, "user membership")
+	}
+}
+
+func verifyGroupOwner(owner bool) changeVerifier {
+	return func(ctx context.Context, r *Reconciler, target map[string]string) error {
+		owners, err := r.oktaClient.ListGroupOwners(ctx, target["okta.group.id"])
+		if err != nil {
+			return err
+		}
+
+		return checkApplied(slices.Contains(owners, target["okta.user.id"]), owner, "group owner")
+	}
+}
+
+func verifyGroupApplication(assigned bool) changeVerifier {
+	return func(ctx context.Context, r *Reconciler, target map[string]string) error {
+		groups, err := r.oktaClient.ListGroupApplicationAssignment(ctx, target["okta.app.id"])
+		if err != nil {
+			return err
+		}
+
+		return checkApplied(slices.Contains(groups, target["okta.group.id"]), assigned, "application assignment")
+	}
+}
+
+func verifyUserDeactivated(ctx context.Context, r *Reconciler, target map[string]string) error {
+	u, err := r.oktaClient.GetUser(ctx, target["okta.user.id"])
+	if err != nil {
+		return err
+	}
+
+	if u.Status != okta.UserStatusDeprovisioned {
+		return fmt.Errorf("%w: user status is %s", ErrChangeNotApplied, u.Status)
+	}
+
+	return nil
+}
+
+func verifyUserStatus(status string) changeVerifier {
+	return func(ctx context.Context, r *Reconciler, target map[string]string) error {
+		u, err := r.oktaClient.GetUser(ctx, target["okta.user.id"])
+		if err != nil {
+			return err
+		}
+
+		if u.Status != status {
+			return fmt.Errorf("%w: user status is %s", ErrChangeNotApplied, u.Status)
+		}
+
+		return nil
+	}
+}
+
+func verifyUserDeleted(ctx context.Context, r *Reconciler, target map[string]string) error {
+	_, err := r.oktaClient.GetUser(ctx, target["okta.user.id"])
+	if err == nil {
+		return fmt.Errorf("%w: user still exists", ErrChangeNotApplied)
+	}
+
+	if errors.Is(err, okta.ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+func verifyUserGovernorID(ctx context.Context, r *Reconciler, target map[string]string) error {
+	u, err := r.oktaClient.GetUser(ctx, target["okta.user.id"])
+	if err != nil {
+		return err
+	}
+
+	id, err := okta.UserGovernorID(u)
+	if err != nil && !errors.Is(err, okta.ErrUserGovernorIDNotFound) {
+		return err
+	}
+
+	if id != target["governor.user.id"] {
+		return fmt.Errorf("%w: user governor id is %q", ErrChangeNotApplied, id)
+	}
+
+	return nil
+}
+
+// checkApplied returns ErrChangeNotApplied when an added resource isn't found or a removed one is still found
+func checkApplied(found, want bool, what string) error {
+	switch {
+	case want && !found:
+		return fmt.Errorf("%w: %s not found", ErrChangeNotApplied, what)
+	case !want && found:
+		return fmt.Errorf("%w: %s still exists", ErrChangeNotApplied, what)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package reconciler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_changeSampler(t *testing.T) {
+	s := newChangeSampler(2)
+
+	// always replace the first sampled change once the sample is full
+	s.intn = func(int) int { return 0 }
+
+	for i := 0; i < 5; i++ {
+		s.add(appliedChange{Type: fmt.Sprintf("change%d", i)})
+	}
+
+	sample, seen := s.take()
+	assert.Equal(t, 5, seen)
+	assert.Equal(t, []appliedChange{{Type: "change4"}, {Type: "change1"}}, sample)
+
+	sample, seen = s.take()
+	assert.Empty(t, sample)
+	assert.Equal(t, 0, seen)
+
+	// changes outside of the reservoir are dropped
+	s.intn = func(n int) int { return n - 1 }
+
+	for i := 0; i < 3; i++ {
+		s.add(appliedChange{Type: fmt.Sprintf("change%d", i)})
+	}
+
+	sample, _ = s.take()
+	assert.Equal(t, []appliedChange{{Type: "change0"}, {Type: "change1"}}, sample)
+}
+
+func Test_changeSamplerDisabled(t *testing.T) {
+	s := newChangeSampler(0)
+	require.Nil(t, s)
+
+	s.add(appliedChange{Type: "GroupMemberAdd"})
+
+	sample, seen := s.take()
+	assert.Nil(t, sample)
+	assert.Equal(t, 0, seen)
+}
+
+func TestReconciler_sampleAppliedChange(t *testing.T) {
+	r := &Reconciler{verifySampler: newChangeSampler(10)}
+
+	r.sampleAppliedChange("GroupMemberAdd", map[string]string{"okta.group.id": "group1", "okta.user.id": "user1"})
+	r.sampleAppliedChange("UserUpdate", map[string]string{"okta.user.id": "user1"})
+
+	sample, seen := r.verifySampler.take()
+	assert.Equal(t, 1, seen, "changes without a verifier are not sampled")
+	assert.Equal(t, "GroupMemberAdd", sample[0].Type)
+}
+
+func Test_checkApplied(t *testing.T) {
+	tests := []struct {
+		name    string
+		found   bool
+		want    bool
+		wantErr bool
+	}{
+		{name: "added", found: true, want: true},
+		{name: "removed", found: false, want: false},
+		{name: "add not applied", found: false, want: true, wantErr: true},
+		{name: "remove not applied", found: true, want: false, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkApplied(tt.found, tt.want, "user membership")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrChangeNotApplied)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
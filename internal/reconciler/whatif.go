@@ -0,0 +1,26 @@
+package reconciler
+
+import "github.com/metal-toolbox/gov-okta-addon/internal/okta"
+
+// whatIf returns true if the okta client records its mutations instead of making them
+func (r *Reconciler) whatIf() bool {
+	return r.oktaClient != nil && r.oktaClient.Recording()
+}
+
+// skipGovernorWrites returns true if changes to governor should be skipped, in what-if mode governor is left
+// untouched like in dry-run
+func (r *Reconciler) skipGovernorWrites() bool {
+	return r.dryRun() || r.whatIf()
+}
+
+// WhatIfMutations returns the okta mutations recorded in what-if mode and the number of mutations dropped
+// because the recorder was full, nil when not in what-if mode
+func (r *Reconciler) WhatIfMutations() ([]okta.Mutation, int) {
+	if !r.whatIf() {
+		return nil, 0
+	}
+
+	rec := r.oktaClient.Recorder()
+
+	return append([]okta.Mutation{}, rec.Mutations()...), rec.Dropped()
+}
@@ -0,0 +1,3 @@
+// Package redact masks the secrets, tokens and, when enabled, the personal information of the values logged by the
+// okta and governor clients
+package redact
@@ -0,0 +1,146 @@
+package redact
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// Mask replaces the redacted values
+const Mask = "[REDACTED]"
+
+var (
+	// secretKeys are the normalized key fragments of the values that are always masked
+	secretKeys = []string{"secret", "password", "passwd", "authorization", "apikey", "privatekey", "cookie", "answer"}
+
+	// tokenSuffix is the normalized key suffix of the tokens, which are always masked, ie. access_token but not
+	// token_url
+	tokenSuffix = "token"
+
+	// piiKeys are the normalized keys of the values that are masked when personal information is redacted
+	piiKeys = map[string]struct{}{
+		"email":         {},
+		"secondemail":   {},
+		"login":         {},
+		"firstname":     {},
+		"middlename":    {},
+		"lastname":      {},
+		"displayname":   {},
+		"nickname":      {},
+		"mobilephone":   {},
+		"primaryphone":  {},
+		"streetaddress": {},
+		"postaladdress": {},
+		"zipcode":       {},
+		"alternateid":   {},
+		"ipaddress":     {},
+		"avatarurl":     {},
+	}
+
+	// secretPrefixes are the prefixes of the authorization header values, masked whatever their key
+	secretPrefixes = []string{"bearer ", "ssws ", "basic "}
+
+	pii atomic.Bool
+)
+
+// SetPII sets whether the personal information of the logged values is redacted
+func SetPII(enabled bool) {
+	pii.Store(enabled)
+}
+
+// Any returns a zap field of the value with its secrets masked, like zap.Any.  The value is only marshaled and
+// redacted when the line is logged, so it's cheap to use on debug lines.
+func Any(key string, v interface{}) zap.Field {
+	return zap.Reflect(key, value{v: v})
+}
+
+// Value returns the json representation of the value with its secrets masked, ie. to add to an error or log it
+// outside of zap
+func Value(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Mask
+	}
+
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return Mask
+	}
+
+	return redact("", out, pii.Load())
+}
+
+// value marshals the redacted json representation of a value
+type value struct {
+	v interface{}
+}
+
+// MarshalJSON implements json.Marshaler
+func (v value) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Value(v.v))
+}
+
+func redact(key string, v interface{}, withPII bool) interface{} {
+	if isSecret(key) || (withPII && isPII(key)) {
+		if v == nil || v == "" {
+			return v
+		}
+
+		return Mask
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = redact(k, e, withPII)
+		}
+
+		return t
+	case []interface{}:
+		for i, e := range t {
+			t[i] = redact(key, e, withPII)
+		}
+
+		return t
+	case string:
+		l := strings.ToLower(t)
+
+		for _, p := range secretPrefixes {
+			if strings.HasPrefix(l, p) {
+				return Mask
+			}
+		}
+	}
+
+	return v
+}
+
+func normalize(key string) string {
+	return strings.NewReplacer("_", "", "-", "", ".", "").Replace(strings.ToLower(key))
+}
+
+func isSecret(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	k := normalize(key)
+	if strings.HasSuffix(k, tokenSuffix) {
+		return true
+	}
+
+	for _, s := range secretKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isPII(key string) bool {
+	_, ok := piiKeys[normalize(key)]
+	return ok
+}
@@ -0,0 +1,113 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+func TestValue(t *testing.T) {
+	user := &okta.User{
+		Id: "okta-1",
+		Credentials: &okta.UserCredentials{
+			Password:         &okta.PasswordCredential{Value: "hunter2"},
+			RecoveryQuestion: &okta.RecoveryQuestionCredential{Question: "pet", Answer: "rex"},
+		},
+		Profile: &okta.UserProfile{
+			"email":       "user@example.com",
+			"firstName":   "Jane",
+			"governor_id": "gov-1",
+		},
+	}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		pii  bool
+		want interface{}
+	}{
+		{
+			name: "client credentials",
+			v:    &clientcredentials.Config{ClientID: "client", ClientSecret: "secret", TokenURL: "https://example.com/token"},
+			want: map[string]interface{}{
+				"ClientID":       "client",
+				"ClientSecret":   Mask,
+				"TokenURL":       "https://example.com/token",
+				"Scopes":         nil,
+				"EndpointParams": nil,
+				"AuthStyle":      float64(0),
+			},
+		},
+		{
+			name: "headers",
+			v:    map[string]interface{}{"Authorization": []string{"SSWS abc"}, "Accept": "application/json", "x": "Bearer abc", "refresh_token": "abc"},
+			want: map[string]interface{}{"Authorization": Mask, "Accept": "application/json", "x": Mask, "refresh_token": Mask},
+		},
+		{
+			name: "empty secrets are kept",
+			v:    map[string]string{"client_secret": ""},
+			want: map[string]interface{}{"client_secret": ""},
+		},
+		{
+			name: "user without pii redaction",
+			v:    user,
+			want: map[string]interface{}{
+				"id": "okta-1",
+				"credentials": map[string]interface{}{
+					"password":          Mask,
+					"recovery_question": map[string]interface{}{"question": "pet", "answer": Mask},
+				},
+				"profile": map[string]interface{}{"email": "user@example.com", "firstName": "Jane", "governor_id": "gov-1"},
+			},
+		},
+		{
+			name: "user with pii redaction",
+			v:    user,
+			pii:  true,
+			want: map[string]interface{}{
+				"id": "okta-1",
+				"credentials": map[string]interface{}{
+					"password":          Mask,
+					"recovery_question": map[string]interface{}{"question": "pet", "answer": Mask},
+				},
+				"profile": map[string]interface{}{"email": Mask, "firstName": Mask, "governor_id": "gov-1"},
+			},
+		},
+		{name: "unmarshalable", v: func() {}, want: Mask},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPII(tt.pii)
+			defer SetPII(false)
+
+			assert.Equal(t, tt.want, Value(tt.v))
+		})
+	}
+}
+
+func TestAny(t *testing.T) {
+	buf := &bytes.Buffer{}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(buf), zap.DebugLevel)
+
+	zap.New(core).Debug("authenticating governor client",
+		Any("clientcredentialconfig", &clientcredentials.Config{ClientID: "client", ClientSecret: "secret"}),
+	)
+
+	line := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+
+	creds, ok := line["clientcredentialconfig"].(map[string]interface{})
+	require.True(t, ok)
+
+	assert.Equal(t, "client", creds["ClientID"])
+	assert.Equal(t, Mask, creds["ClientSecret"])
+	assert.NotContains(t, buf.String(), `"secret"`)
+}
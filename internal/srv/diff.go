@@ -0,0 +1,46 @@
+package srv
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
+	"go.uber.org/zap"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+)
+
+// groupDiffHandler returns the difference between the members of a governor group and its okta group
+func (s *Server) groupDiffHandler(c *gin.Context) {
+	if s.Reconciler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "reconciler not running"})
+		return
+	}
+
+	gid := c.Param("id")
+
+	diff, err := s.Reconciler.GroupMembershipDiff(c.Request.Context(), gid)
+	if err != nil {
+		s.Logger.Warn("error getting group membership diff", zap.String("governor.group.id", gid), zap.Error(err))
+
+		c.JSON(diffErrorStatus(err), gin.H{"message": err.Error()})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// diffErrorStatus returns the http status of a group membership diff error
+func diffErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, governor.ErrGroupNotFound), errors.Is(err, okta.ErrGroupsNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, reconciler.ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -3,15 +3,22 @@ package srv
 import (
 	"context"
 	"encoding/json"
+	"errors"
 
 	"github.com/metal-toolbox/auditevent"
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
 	"github.com/metal-toolbox/governor-api/pkg/events/v1alpha1"
 )
 
+// tracerName is the instrumentation name of the NATS message spans
+const tracerName = "github.com/metal-toolbox/gov-okta-addon/internal/srv"
+
 // groupsMessageHandler handles messages for governor group events
 func (s *Server) groupsMessageHandler(m *nats.Msg) {
 	payload, err := s.unmarshalPayload(m)
@@ -25,7 +32,8 @@ func (s *Server) groupsMessageHandler(m *nats.Msg) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx, span := startSpan(context.Background(), m.Subject)
+	defer span.End()
 
 	logger := s.Logger.With(zap.String("governor.group.id", payload.GroupID))
 
@@ -41,35 +49,23 @@ func (s *Server) groupsMessageHandler(m *nats.Msg) {
 			return
 		}
 
-		if err := s.Reconciler.GroupsApplicationAssignments(ctx, payload.GroupID); err != nil {
-			logger.Error("error reconciling group creation application assignment", zap.Error(err))
+		if res, err := s.Reconciler.GroupsApplicationAssignments(ctx, payload.GroupID); err != nil {
+			logger.Error("error reconciling group creation application assignment", zap.Error(err), zap.Array("app.assignments", res))
 			return
 		}
 
-		if err := s.Reconciler.GroupMembership(ctx, payload.GroupID, gid); err != nil {
+		res, err := s.Reconciler.GroupMembership(ctx, payload.GroupID, gid)
+		if err != nil {
 			logger.Error("error reconciling group creation membership", zap.Error(err))
 			return
 		}
 
-		logger.Info("successfully created group", zap.String("okta.group.id", gid))
+		logger.Info("successfully created group", zap.String("okta.group.id", gid), zap.Object("membership", res))
 
 	case v1alpha1.GovernorEventUpdate:
-		logger.Info("updating group")
-
-		ctx = auctx.WithAuditEvent(ctx, s.auditEventNATS(m.Subject, payload))
-
-		gid, err := s.Reconciler.GroupUpdate(ctx, payload.GroupID)
-		if err != nil {
-			logger.Error("error reconciling group update", zap.Error(err))
-			return
-		}
-
-		if err := s.Reconciler.GroupsApplicationAssignments(ctx, payload.GroupID); err != nil {
-			logger.Error("error reconciling group creation application assignment", zap.Error(err))
-			return
-		}
+		logger.Debug("queueing group update")
 
-		logger.Info("successfully updated group", zap.String("okta.group.id", gid))
+		s.enqueueGroup(payload.GroupID, groupWorkUpdate, m.Subject, payload)
 
 	case v1alpha1.GovernorEventDelete:
 		logger.Info("deleting group")
@@ -98,7 +94,8 @@ func (s *Server) membersMessageHandler(m *nats.Msg) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx, span := startSpan(context.Background(), m.Subject)
+	defer span.End()
 
 	logger := s.Logger.With(zap.String("governor.group.id", payload.GroupID), zap.String("governor.user.id", payload.UserID))
 
@@ -116,6 +113,8 @@ func (s *Server) membersMessageHandler(m *nats.Msg) {
 
 		logger.Info("successfully created group membership", zap.String("okta.group.id", gid), zap.String("okta.user.id", uid))
 
+		s.enqueueGroup(payload.GroupID, groupWorkOwners, m.Subject, payload)
+
 	case v1alpha1.GovernorEventDelete:
 		logger.Info("deleting group membership")
 
@@ -129,6 +128,14 @@ func (s *Server) membersMessageHandler(m *nats.Msg) {
 
 		logger.Info("successfully deleted group membership", zap.String("okta.group.id", gid), zap.String("okta.user.id", uid))
 
+		s.enqueueGroup(payload.GroupID, groupWorkOwners, m.Subject, payload)
+
+	case v1alpha1.GovernorEventUpdate:
+		// membership updates change the admin status of the member
+		logger.Debug("queueing group owners update")
+
+		s.enqueueGroup(payload.GroupID, groupWorkOwners, m.Subject, payload)
+
 	default:
 		logger.Warn("unexpected action in governor event", zap.String("governor.action", payload.Action))
 		return
@@ -148,7 +155,8 @@ func (s *Server) usersMessageHandler(m *nats.Msg) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx, span := startSpan(context.Background(), m.Subject)
+	defer span.End()
 
 	logger := s.Logger.With(zap.String("governor.user.id", payload.UserID))
 
@@ -179,12 +187,99 @@ func (s *Server) usersMessageHandler(m *nats.Msg) {
 
 		logger.Info("successfully updated user", zap.String("okta.user.id", uid))
 
+		// the memberships of a user assigned while they were pending are added to okta once they're active
+		uid, added, err := s.Reconciler.UserActivate(ctx, payload.UserID)
+
+		switch {
+		case errors.Is(err, reconciler.ErrUserNotActive):
+		case err != nil:
+			logger.Error("error adding user to okta groups", zap.Error(err))
+		case len(added) > 0:
+			logger.Info("added user to okta groups", zap.String("okta.user.id", uid), zap.Strings("okta.group.ids", added))
+		}
+
 	default:
 		logger.Warn("unexpected action in governor event", zap.String("governor.action", payload.Action))
 		return
 	}
 }
 
+// applinksMessageHandler handles messages for governor application link events, sent when a group
+// is linked to or unlinked from an organization
+func (s *Server) applinksMessageHandler(m *nats.Msg) {
+	payload, err := s.unmarshalPayload(m)
+	if err != nil {
+		s.Logger.Warn("unable to unmarshal governor payload", zap.Error(err))
+		return
+	}
+
+	if payload.GroupID == "" {
+		s.Logger.Error("bad event payload", zap.Error(ErrEventMissingGroupID))
+		return
+	}
+
+	logger := s.Logger.With(zap.String("governor.group.id", payload.GroupID), zap.String("governor.app.id", payload.ApplicationID))
+
+	switch payload.Action {
+	case v1alpha1.GovernorEventCreate, v1alpha1.GovernorEventUpdate, v1alpha1.GovernorEventDelete:
+		logger.Debug("queueing group application assignments for application link change")
+
+		s.enqueueGroup(payload.GroupID, groupWorkApplications, m.Subject, payload)
+
+	default:
+		logger.Warn("unexpected action in governor event", zap.String("governor.action", payload.Action))
+		return
+	}
+}
+
+// organizationsMessageHandler handles messages for governor organization events
+func (s *Server) organizationsMessageHandler(m *nats.Msg) {
+	payload, err := s.unmarshalPayload(m)
+	if err != nil {
+		s.Logger.Warn("unable to unmarshal governor payload", zap.Error(err))
+		return
+	}
+
+	ctx, span := startSpan(context.Background(), m.Subject)
+	defer span.End()
+
+	logger := s.Logger.With(zap.String("governor.action", payload.Action))
+
+	switch payload.Action {
+	case v1alpha1.GovernorEventCreate, v1alpha1.GovernorEventUpdate, v1alpha1.GovernorEventDelete:
+		logger.Info("reconciling group application assignments for organization change")
+
+		ctx = auctx.WithAuditEvent(ctx, s.auditEventNATS(m.Subject, payload))
+
+		res, err := s.Reconciler.OrganizationApplicationAssignments(ctx)
+		if err != nil {
+			logger.Error("error reconciling group application assignments for organization change",
+				zap.Error(err),
+				zap.Strings("failed.orgs", res.FailedOrgs()),
+				zap.Strings("failed.governor.group.ids", res.FailedGroups()),
+			)
+
+			return
+		}
+
+		logger.Info("successfully reconciled group application assignments for organization change", zap.Array("app.assignments", res))
+
+	default:
+		logger.Warn("unexpected action in governor event")
+		return
+	}
+}
+
+// unhandledMessageHandler logs and drops messages on enabled subjects without a handler
+func (s *Server) unhandledMessageHandler(m *nats.Msg) {
+	s.Logger.Warn("dropping message for subject without a handler", zap.String("nats.subject", m.Subject))
+}
+
+// startSpan starts a span for handling a NATS message, it's a no-op when tracing is disabled
+func startSpan(ctx context.Context, subject string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, subject, trace.WithSpanKind(trace.SpanKindConsumer))
+}
+
 func (s *Server) unmarshalPayload(m *nats.Msg) (*v1alpha1.Event, error) {
 	s.Logger.Debug("received a message:", zap.String("nats.data", string(m.Data)), zap.String("nats.subject", m.Subject))
 
@@ -196,8 +291,10 @@ func (s *Server) unmarshalPayload(m *nats.Msg) (*v1alpha1.Event, error) {
 	return &payload, nil
 }
 
-// auditEventNATS returns a stub NATS audit event
+// auditEventNATS returns a stub NATS audit event, the governor user that triggered the event is the actor
 func (s *Server) auditEventNATS(natsSubj string, event *v1alpha1.Event) *auditevent.AuditEvent {
+	actor := auctx.Actor{Source: auctx.ActorSourceGovernor, ID: event.ActorID, Type: "User"}
+
 	return auditevent.NewAuditEventWithID(
 		event.AuditID,
 		"", // eventType to be populated later
@@ -210,9 +307,9 @@ func (s *Server) auditEventNATS(natsSubj string, event *v1alpha1.Event) *auditev
 			},
 		},
 		auditevent.OutcomeSucceeded,
-		map[string]string{
+		actor.Subjects(map[string]string{
 			"event": "governor",
-		},
+		}),
 		"gov-okta-addon",
 	)
 }
@@ -2,11 +2,65 @@ package srv
 
 import (
 	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
 )
 
+const (
+	// DefaultNATSReconnectWait is the default for the first wait before reconnecting to NATS, it doubles on
+	// every failed attempt
+	DefaultNATSReconnectWait = 1 * time.Second
+	// DefaultNATSReconnectMaxWait is the default for the max wait between NATS reconnect attempts
+	DefaultNATSReconnectMaxWait = 1 * time.Minute
+
+	// natsReconnectJitter is the max fraction of the reconnect wait randomly added to it
+	natsReconnectJitter = 0.2
+)
+
+// DefaultNATSSubjects are the governor event subjects (without the prefix) subscribed to by default
+var DefaultNATSSubjects = []string{"groups", "members", "users", "organizations", "applinks"}
+
+var (
+	natsConnectedGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "nats_connected",
+			Help:      "1 when the NATS connection is up, 0 otherwise.",
+		},
+	)
+
+	natsDisconnectsCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "nats_disconnects_total",
+			Help:      "Total count of NATS disconnects.",
+		},
+	)
+
+	natsReconnectsCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "nats_reconnects_total",
+			Help:      "Total count of NATS reconnects.",
+		},
+	)
+
+	natsResubscribesCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "nats_resubscribes_total",
+			Help:      "Total count of NATS subscriptions registered again after a reconnect.",
+		},
+	)
+)
+
 // NATSClient is a NATS client with some configuration
 type NATSClient struct {
 	conn       *nats.Conn
@@ -14,6 +68,38 @@ type NATSClient struct {
 	prefix     string
 	queueGroup string
 	queueSize  int
+	subjects   []string
+
+	mu       sync.Mutex
+	subs     []*nats.Subscription
+	draining bool
+}
+
+// NATSReconnectOptions returns the NATS connection options to reconnect forever (or up to maxReconnects
+// times when it's not negative) with an exponential backoff from wait up to maxWait
+func NATSReconnectOptions(wait, maxWait time.Duration, maxReconnects int) []nats.Option {
+	return []nats.Option{
+		nats.MaxReconnects(maxReconnects),
+		nats.CustomReconnectDelay(natsReconnectBackoff(wait, maxWait, rand.Float64)),
+	}
+}
+
+// natsReconnectBackoff returns the wait before a NATS reconnect attempt, doubling from wait up to maxWait
+// with some jitter so replicas don't reconnect all at once
+func natsReconnectBackoff(wait, maxWait time.Duration, random func() float64) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		d := wait
+
+		for i := 1; i < attempts && d < maxWait; i++ {
+			d *= 2
+		}
+
+		if d > maxWait {
+			d = maxWait
+		}
+
+		return d + time.Duration(float64(d)*natsReconnectJitter*random())
+	}
 }
 
 // NATSOption is a functional configuration option for NATS
@@ -22,7 +108,8 @@ type NATSOption func(c *NATSClient)
 // NewNATSClient configures and establishes a new NATS client connection
 func NewNATSClient(opts ...NATSOption) (*NATSClient, error) {
 	client := NATSClient{
-		logger: zap.NewNop(),
+		logger:   zap.NewNop(),
+		subjects: DefaultNATSSubjects,
 	}
 
 	for _, opt := range opts {
@@ -54,6 +141,15 @@ func WithNATSQueueGroup(q string, i int) NATSOption {
 	}
 }
 
+// WithNATSSubjects sets the governor event subjects (without the prefix) to subscribe to
+func WithNATSSubjects(subjects []string) NATSOption {
+	return func(c *NATSClient) {
+		if len(subjects) > 0 {
+			c.subjects = subjects
+		}
+	}
+}
+
 // WithNATSLogger sets the NATS client logger
 func WithNATSLogger(l *zap.Logger) NATSOption {
 	return func(c *NATSClient) {
@@ -61,43 +157,182 @@ func WithNATSLogger(l *zap.Logger) NATSOption {
 	}
 }
 
+// HandleSubject registers a message handler for a governor event subject (without the prefix), the
+// handler is only subscribed when the subject is enabled on the NATS client. Handlers registered
+// for the built-in subjects replace the built-in handler.
+func (s *Server) HandleSubject(subject string, h nats.MsgHandler) {
+	if s.handlers == nil {
+		s.handlers = map[string]nats.MsgHandler{}
+	}
+
+	s.handlers[subject] = h
+}
+
+// subjectHandlers returns the known message handlers by subject (without the prefix)
+func (s *Server) subjectHandlers() map[string]nats.MsgHandler {
+	handlers := map[string]nats.MsgHandler{
+		"groups":        s.groupsMessageHandler,
+		"members":       s.membersMessageHandler,
+		"users":         s.usersMessageHandler,
+		"organizations": s.organizationsMessageHandler,
+		"applinks":      s.applinksMessageHandler,
+	}
+
+	for subj, h := range s.handlers {
+		handlers[subj] = h
+	}
+
+	return handlers
+}
+
+// subscriptions returns the message handlers for the enabled subjects keyed by the full subject name,
+// enabled subjects without a known handler log and drop their messages
+func (s *Server) subscriptions() map[string]nats.MsgHandler {
+	handlers := s.subjectHandlers()
+	subs := make(map[string]nats.MsgHandler, len(s.NATSClient.subjects))
+
+	for _, subj := range s.NATSClient.subjects {
+		h, ok := handlers[subj]
+		if !ok {
+			s.Logger.Warn("no handler for subject, messages will be dropped", zap.String("nats.subject", subj))
+
+			h = s.unhandledMessageHandler
+		}
+
+		subs[s.NATSClient.prefix+"."+subj] = h
+	}
+
+	return subs
+}
+
 func (s *Server) registerSubscriptionHandlers() error {
 	prefix := s.NATSClient.prefix
 	qg := s.NATSClient.queueGroup
 
-	s.Logger.Debug("registering subscription handlers", zap.String("nats.prefix", prefix), zap.String("nats.queue_group", qg))
+	s.Logger.Debug("registering subscription handlers",
+		zap.String("nats.prefix", prefix),
+		zap.String("nats.queue_group", qg),
+		zap.Strings("nats.subjects", s.NATSClient.subjects),
+	)
+
+	subs := s.subscriptions()
+
+	subjects := make([]string, 0, len(subs))
+	for subj := range subs {
+		subjects = append(subjects, subj)
+	}
+
+	sort.Strings(subjects)
+
+	s.NATSClient.mu.Lock()
+	defer s.NATSClient.mu.Unlock()
 
-	// Receive groups channel events
 	n := 1
 	for n < s.NATSClient.queueSize {
-		// Receive groups channel events
-		if _, err := s.NATSClient.conn.QueueSubscribe(prefix+".groups", qg, s.groupsMessageHandler); err != nil {
-			return err
+		for _, subj := range subjects {
+			sub, err := s.NATSClient.conn.QueueSubscribe(subj, qg, subs[subj])
+			if err != nil {
+				return err
+			}
+
+			s.NATSClient.subs = append(s.NATSClient.subs, sub)
+
+			s.Logger.Debug("added subscriber", zap.String("nats.subscriber_id", fmt.Sprintf("%s-%d", subj, n)))
+		}
+
+		n++
+	}
+
+	return nil
+}
+
+// watchNATSConnection logs the NATS connection state changes and subscribes again after a reconnect when
+// some subscriptions didn't survive it
+func (s *Server) watchNATSConnection() {
+	conn := s.NATSClient.conn
+
+	natsConnectedGauge.Set(1)
+
+	conn.SetDisconnectErrHandler(func(_ *nats.Conn, err error) {
+		natsConnectedGauge.Set(0)
+		natsDisconnectsCounter.Inc()
+
+		s.Logger.Warn("disconnected from NATS", zap.Error(err))
+	})
+
+	conn.SetReconnectHandler(func(nc *nats.Conn) {
+		natsConnectedGauge.Set(1)
+		natsReconnectsCounter.Inc()
+
+		s.Logger.Info("reconnected to NATS", zap.String("nats.url", nc.ConnectedUrlRedacted()))
+
+		if err := s.resubscribe(); err != nil {
+			s.Logger.Error("error subscribing again after NATS reconnect", zap.Error(err))
 		}
+	})
 
-		s.Logger.Debug("added subscriber", zap.String("nats.subscriber_id", fmt.Sprintf("%s.groups-%d", prefix, n)))
+	conn.SetClosedHandler(func(_ *nats.Conn) {
+		natsConnectedGauge.Set(0)
 
-		// Receive group memberships channel events
-		if _, err := s.NATSClient.conn.QueueSubscribe(prefix+".members", qg, s.membersMessageHandler); err != nil {
-			return err
+		if s.NATSClient.isDraining() {
+			s.Logger.Info("NATS connection closed")
+			return
 		}
 
-		s.Logger.Debug("added subscriber", zap.String("nats.subscriber_id", fmt.Sprintf("%s.members-%d", prefix, n)))
+		s.Logger.Error("NATS connection closed, no more governor events will be processed")
+	})
+}
+
+// resubscribe registers the subscription handlers again when any of the subscriptions is no longer valid
+func (s *Server) resubscribe() error {
+	s.NATSClient.mu.Lock()
+
+	valid := true
 
-		// Receive users channel events
-		if _, err := s.NATSClient.conn.QueueSubscribe(prefix+".users", qg, s.usersMessageHandler); err != nil {
-			return err
+	for _, sub := range s.NATSClient.subs {
+		if !sub.IsValid() {
+			valid = false
+			break
 		}
+	}
 
-		s.Logger.Debug("added subscriber", zap.String("nats.subscriber_id", fmt.Sprintf("%s.users-%d", prefix, n)))
+	if valid {
+		s.NATSClient.mu.Unlock()
+		return nil
+	}
 
-		n++
+	for _, sub := range s.NATSClient.subs {
+		if sub.IsValid() {
+			if err := sub.Unsubscribe(); err != nil {
+				s.Logger.Warn("error unsubscribing from NATS", zap.String("nats.subject", sub.Subject), zap.Error(err))
+			}
+		}
 	}
 
-	return nil
+	s.NATSClient.subs = nil
+
+	s.NATSClient.mu.Unlock()
+
+	natsResubscribesCounter.Inc()
+
+	s.Logger.Warn("NATS subscriptions lost on reconnect, subscribing again")
+
+	return s.registerSubscriptionHandlers()
+}
+
+// isDraining returns true if the connection is being drained on shutdown
+func (c *NATSClient) isDraining() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.draining
 }
 
 func (s *Server) shutdownSubscriptions() error {
+	s.NATSClient.mu.Lock()
+	s.NATSClient.draining = true
+	s.NATSClient.mu.Unlock()
+
 	// Drain and close the NATS connection
 	return s.NATSClient.conn.Drain()
 }
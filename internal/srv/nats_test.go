@@ -2,6 +2,7 @@ package srv
 
 import (
 	"testing"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
@@ -55,3 +56,77 @@ func TestServer_unmarshalPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_subscriptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		subjects []string
+		extra    []string
+		want     []string
+	}{
+		{
+			name: "default subjects",
+			want: []string{"governor.events.groups", "governor.events.members", "governor.events.users", "governor.events.organizations", "governor.events.applinks"},
+		},
+		{
+			name:     "subset of subjects",
+			subjects: []string{"users"},
+			want:     []string{"governor.events.users"},
+		},
+		{
+			name:     "registered handler",
+			subjects: []string{"groups", "applications"},
+			extra:    []string{"applications"},
+			want:     []string{"governor.events.groups", "governor.events.applications"},
+		},
+		{
+			name:     "unknown subject is still subscribed",
+			subjects: []string{"groups", "applications"},
+			want:     []string{"governor.events.groups", "governor.events.applications"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nc, err := NewNATSClient(WithNATSPrefix("governor.events"), WithNATSSubjects(tt.subjects))
+			assert.NoError(t, err)
+
+			s := &Server{
+				Logger:     zap.NewNop(),
+				NATSClient: nc,
+			}
+
+			for _, subj := range tt.extra {
+				s.HandleSubject(subj, func(_ *nats.Msg) {})
+			}
+
+			got := s.subscriptions()
+
+			keys := []string{}
+			for k, h := range got {
+				assert.NotNil(t, h)
+
+				keys = append(keys, k)
+			}
+
+			assert.ElementsMatch(t, tt.want, keys)
+		})
+	}
+}
+
+func Test_natsReconnectBackoff(t *testing.T) {
+	noJitter := func() float64 { return 0 }
+	fullJitter := func() float64 { return 1 }
+
+	backoff := natsReconnectBackoff(time.Second, 10*time.Second, noJitter)
+
+	assert.Equal(t, time.Second, backoff(1))
+	assert.Equal(t, 2*time.Second, backoff(2))
+	assert.Equal(t, 8*time.Second, backoff(4))
+	assert.Equal(t, 10*time.Second, backoff(5))
+	assert.Equal(t, 10*time.Second, backoff(1000))
+
+	backoff = natsReconnectBackoff(time.Second, 10*time.Second, fullJitter)
+
+	assert.Equal(t, 1200*time.Millisecond, backoff(1))
+	assert.Equal(t, 12*time.Second, backoff(100))
+}
@@ -0,0 +1,76 @@
+package srv
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// pauseRequest is the optional body of a reconciler pause request
+type pauseRequest struct {
+	Reason string `json:"reason"`
+}
+
+// adminAuth only lets through the requests with the admin token as a bearer token, every request is rejected
+// when no admin token is set
+func (s *Server) adminAuth(c *gin.Context) {
+	if s.AdminToken == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "admin endpoints are disabled, no admin token set"})
+		return
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "invalid admin token"})
+		return
+	}
+
+	c.Next()
+}
+
+// pauseHandler pauses the reconciler mutations
+func (s *Server) pauseHandler(c *gin.Context) {
+	if s.Reconciler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "reconciler not running"})
+		return
+	}
+
+	req := pauseRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid pause request", "error": err.Error()})
+		return
+	}
+
+	changed := s.Reconciler.Pause(req.Reason)
+
+	s.Logger.Info("reconciler pause requested",
+		zap.String("pause.reason", req.Reason),
+		zap.Bool("changed", changed),
+		zap.String("remote.addr", c.ClientIP()),
+	)
+
+	c.JSON(http.StatusOK, s.Reconciler.Status())
+}
+
+// resumeHandler resumes the reconciler mutations
+func (s *Server) resumeHandler(c *gin.Context) {
+	if s.Reconciler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "reconciler not running"})
+		return
+	}
+
+	changed := s.Reconciler.Resume()
+
+	s.Logger.Info("reconciler resume requested",
+		zap.Bool("changed", changed),
+		zap.String("remote.addr", c.ClientIP()),
+	)
+
+	c.JSON(http.StatusOK, s.Reconciler.Status())
+}
@@ -0,0 +1,131 @@
+package srv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+)
+
+func TestPauseResumeRoutes(t *testing.T) {
+	rec := reconciler.New()
+
+	tests := []struct {
+		name       string
+		adminToken string
+		rec        *reconciler.Reconciler
+		path       string
+		auth       string
+		body       string
+		wantCode   int
+		wantBody   string
+		wantPaused bool
+	}{
+		{
+			name:     "disabled without admin token",
+			rec:      rec,
+			path:     "/api/v1/reconciler/pause",
+			auth:     "Bearer ",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:       "missing token",
+			adminToken: "admin",
+			rec:        rec,
+			path:       "/api/v1/reconciler/pause",
+			wantCode:   http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong token",
+			adminToken: "admin",
+			rec:        rec,
+			path:       "/api/v1/reconciler/pause",
+			auth:       "Bearer nope",
+			wantCode:   http.StatusUnauthorized,
+		},
+		{
+			name:       "no reconciler",
+			adminToken: "admin",
+			path:       "/api/v1/reconciler/pause",
+			auth:       "Bearer admin",
+			wantCode:   http.StatusServiceUnavailable,
+		},
+		{
+			name:       "invalid body",
+			adminToken: "admin",
+			rec:        rec,
+			path:       "/api/v1/reconciler/pause",
+			auth:       "Bearer admin",
+			body:       "{",
+			wantCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "pause",
+			adminToken: "admin",
+			rec:        rec,
+			path:       "/api/v1/reconciler/pause",
+			auth:       "Bearer admin",
+			body:       `{"reason":"incident"}`,
+			wantCode:   http.StatusOK,
+			wantBody:   `"pause_reason":"incident"`,
+			wantPaused: true,
+		},
+		{
+			name:       "pause again without a body",
+			adminToken: "admin",
+			rec:        rec,
+			path:       "/api/v1/reconciler/pause",
+			auth:       "Bearer admin",
+			wantCode:   http.StatusOK,
+			wantBody:   `"pause_reason":"incident"`,
+			wantPaused: true,
+		},
+		{
+			name:       "resume",
+			adminToken: "admin",
+			rec:        rec,
+			path:       "/api/v1/reconciler/resume",
+			auth:       "Bearer admin",
+			wantCode:   http.StatusOK,
+			wantBody:   `"paused":false`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := Server{
+				Logger:     zap.NewNop(),
+				AdminToken: tt.adminToken,
+			}
+
+			if tt.rec != nil {
+				hs.Reconciler = tt.rec
+			}
+
+			router := hs.NewServer().Handler
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.TODO(), http.MethodPost, tt.path, strings.NewReader(tt.body))
+
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != "" {
+				assert.Contains(t, w.Body.String(), tt.wantBody)
+			}
+
+			assert.Equal(t, tt.wantPaused, rec.Status().Paused)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package srv
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// unquarantineHandler releases a quarantined governor group, so it's retried by the next reconciler loop
+func (s *Server) unquarantineHandler(c *gin.Context) {
+	if s.Reconciler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "reconciler not running"})
+		return
+	}
+
+	id := c.Param("id")
+
+	released := s.Reconciler.UnquarantineGroup(id)
+
+	s.Logger.Info("governor group unquarantine requested",
+		zap.String("governor.group.id", id),
+		zap.Bool("changed", released),
+		zap.String("remote.addr", c.ClientIP()),
+	)
+
+	if !released {
+		c.JSON(http.StatusNotFound, gin.H{"message": "governor group is not quarantined"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s.Reconciler.Status())
+}
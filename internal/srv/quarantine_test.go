@@ -0,0 +1,74 @@
+package srv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+)
+
+func TestUnquarantineRoute(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		rec        *reconciler.Reconciler
+		auth       string
+		wantCode   int
+	}{
+		{
+			name:     "disabled without admin token",
+			rec:      reconciler.New(),
+			auth:     "Bearer ",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:       "wrong token",
+			adminToken: "admin",
+			rec:        reconciler.New(),
+			auth:       "Bearer nope",
+			wantCode:   http.StatusUnauthorized,
+		},
+		{
+			name:       "no reconciler",
+			adminToken: "admin",
+			auth:       "Bearer admin",
+			wantCode:   http.StatusServiceUnavailable,
+		},
+		{
+			name:       "group not quarantined",
+			adminToken: "admin",
+			rec:        reconciler.New(reconciler.WithGroupQuarantine(1, time.Hour)),
+			auth:       "Bearer admin",
+			wantCode:   http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := Server{
+				Logger:     zap.NewNop(),
+				AdminToken: tt.adminToken,
+			}
+
+			if tt.rec != nil {
+				hs.Reconciler = tt.rec
+			}
+
+			router := hs.NewServer().Handler
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.TODO(), http.MethodDelete, "/api/v1/reconciler/quarantine/group-1", nil)
+			req.Header.Set("Authorization", tt.auth)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package srv
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/auctx"
+	"github.com/metal-toolbox/governor-api/pkg/events/v1alpha1"
+)
+
+// DefaultCoalesceWindow is the default for how long group reconcile requests are held to be coalesced
+const DefaultCoalesceWindow = 1 * time.Second
+
+// groupWork is the reconcile work requested for a group, requests for the same group are merged
+type groupWork uint8
+
+const (
+	// groupWorkUpdate updates the okta group and its application assignments
+	groupWorkUpdate groupWork = 1 << iota
+	// groupWorkApplications reconciles the application assignments of the group
+	groupWorkApplications
+	// groupWorkOwners reconciles the owners of the group
+	groupWorkOwners
+)
+
+var (
+	groupQueueDepthGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "group_queue_depth",
+			Help:      "Number of groups waiting to be reconciled from governor events.",
+		},
+	)
+
+	groupQueueCoalescedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "group_queue_coalesced_total",
+			Help:      "Total count of group reconcile requests merged into a request already queued.",
+		},
+	)
+)
+
+// groupRequest is a queued reconcile request for a group
+type groupRequest struct {
+	groupID string
+	work    groupWork
+	// subject and payload of the last event merged into the request, used for the audit event
+	subject  string
+	payload  *v1alpha1.Event
+	requests int
+}
+
+// groupQueue coalesces reconcile requests for the same group.  A request is held for the window and any
+// request for the same group in the meantime is merged into it, so an event storm for a group results in
+// a single reconcile.  A group is never reconciled concurrently, requests that come due while the group is
+// still being reconciled wait for another window.
+type groupQueue struct {
+	window time.Duration
+	handle func(*groupRequest)
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	pending map[string]*groupRequest
+	timers  map[string]*time.Timer
+	running map[string]bool
+	stopped bool
+}
+
+// newGroupQueue returns a queue that calls handle with the coalesced requests after the window
+func newGroupQueue(window time.Duration, logger *zap.Logger, handle func(*groupRequest)) *groupQueue {
+	return &groupQueue{
+		window:  window,
+		handle:  handle,
+		logger:  logger,
+		pending: map[string]*groupRequest{},
+		timers:  map[string]*time.Timer{},
+		running: map[string]bool{},
+	}
+}
+
+// enqueue queues the work for a group, merging it into the request already queued for the group
+func (q *groupQueue) enqueue(gid string, work groupWork, subject string, payload *v1alpha1.Event) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.stopped {
+		q.logger.Warn("group queue stopped, dropping request", zap.String("governor.group.id", gid))
+		return
+	}
+
+	if req, ok := q.pending[gid]; ok {
+		req.work |= work
+		req.subject = subject
+		req.payload = payload
+		req.requests++
+
+		groupQueueCoalescedCounter.Inc()
+
+		q.logger.Debug("coalesced group reconcile request", zap.String("governor.group.id", gid), zap.Int("requests", req.requests))
+
+		return
+	}
+
+	q.pending[gid] = &groupRequest{groupID: gid, work: work, subject: subject, payload: payload, requests: 1}
+	q.timers[gid] = time.AfterFunc(q.window, func() { q.fire(gid) })
+
+	groupQueueDepthGauge.Set(float64(len(q.pending)))
+}
+
+// fire handles the request for a group once its window has passed
+func (q *groupQueue) fire(gid string) {
+	q.mu.Lock()
+
+	req, ok := q.pending[gid]
+	if !ok || q.stopped {
+		q.mu.Unlock()
+		return
+	}
+
+	if q.running[gid] {
+		// wait for the running reconcile of the group to finish
+		q.timers[gid] = time.AfterFunc(q.window, func() { q.fire(gid) })
+		q.mu.Unlock()
+
+		return
+	}
+
+	delete(q.pending, gid)
+	delete(q.timers, gid)
+	q.running[gid] = true
+
+	groupQueueDepthGauge.Set(float64(len(q.pending)))
+
+	q.mu.Unlock()
+
+	q.handle(req)
+
+	q.mu.Lock()
+	delete(q.running, gid)
+	q.mu.Unlock()
+}
+
+// stop stops the queue and drops the requests that are still waiting, they are picked up by the next
+// reconciler loop
+func (q *groupQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.stopped = true
+
+	for gid, t := range q.timers {
+		t.Stop()
+		delete(q.timers, gid)
+	}
+
+	if len(q.pending) > 0 {
+		q.logger.Warn("dropping queued group reconcile requests on shutdown", zap.Int("num.groups", len(q.pending)))
+	}
+
+	q.pending = map[string]*groupRequest{}
+
+	groupQueueDepthGauge.Set(0)
+}
+
+// enqueueGroup queues the reconcile work for a group when coalescing is enabled, otherwise the work is
+// done right away
+func (s *Server) enqueueGroup(gid string, work groupWork, subject string, payload *v1alpha1.Event) {
+	if s.queue == nil {
+		s.reconcileGroupRequest(&groupRequest{groupID: gid, work: work, subject: subject, payload: payload, requests: 1})
+		return
+	}
+
+	s.queue.enqueue(gid, work, subject, payload)
+}
+
+// reconcileGroupRequest does the reconcile work of a (coalesced) group request
+func (s *Server) reconcileGroupRequest(req *groupRequest) {
+	ctx, span := startSpan(context.Background(), req.subject)
+	defer span.End()
+
+	ctx = auctx.WithAuditEvent(ctx, s.auditEventNATS(req.subject, req.payload))
+
+	logger := s.Logger.With(zap.String("governor.group.id", req.groupID), zap.Int("requests", req.requests))
+
+	if req.work&groupWorkUpdate != 0 {
+		logger.Info("updating group")
+
+		gid, err := s.Reconciler.GroupUpdate(ctx, req.groupID)
+		if err != nil {
+			logger.Error("error reconciling group update", zap.Error(err))
+			return
+		}
+
+		logger.Info("successfully updated group", zap.String("okta.group.id", gid))
+	}
+
+	if req.work&(groupWorkUpdate|groupWorkApplications) != 0 {
+		res, err := s.Reconciler.GroupsApplicationAssignments(ctx, req.groupID)
+		if err != nil {
+			logger.Error("error reconciling group application assignments", zap.Error(err), zap.Array("app.assignments", res))
+			return
+		}
+
+		logger.Info("successfully reconciled group application assignments", zap.Array("app.assignments", res))
+	}
+
+	if req.work&groupWorkOwners != 0 {
+		gid, err := s.Reconciler.GroupOwnersUpdate(ctx, req.groupID)
+		if err != nil {
+			logger.Error("error reconciling group owners", zap.Error(err))
+			return
+		}
+
+		logger.Info("successfully reconciled group owners", zap.String("okta.group.id", gid))
+	}
+}
@@ -0,0 +1,98 @@
+package srv
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metal-toolbox/governor-api/pkg/events/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_groupQueue(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		handled = map[string]*groupRequest{}
+		wg      sync.WaitGroup
+	)
+
+	wg.Add(2)
+
+	q := newGroupQueue(50*time.Millisecond, zap.NewNop(), func(req *groupRequest) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		handled[req.groupID] = req
+
+		wg.Done()
+	})
+
+	last := &v1alpha1.Event{GroupID: "group1", AuditID: "audit3"}
+
+	q.enqueue("group1", groupWorkApplications, "governor.events.applinks", &v1alpha1.Event{GroupID: "group1", AuditID: "audit1"})
+	q.enqueue("group2", groupWorkUpdate, "governor.events.groups", &v1alpha1.Event{GroupID: "group2"})
+	q.enqueue("group1", groupWorkOwners, "governor.events.members", &v1alpha1.Event{GroupID: "group1", AuditID: "audit2"})
+	q.enqueue("group1", groupWorkApplications, "governor.events.applinks", last)
+
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Len(t, handled, 2)
+	assert.Equal(t, &groupRequest{
+		groupID:  "group1",
+		work:     groupWorkApplications | groupWorkOwners,
+		subject:  "governor.events.applinks",
+		payload:  last,
+		requests: 3,
+	}, handled["group1"])
+	assert.Equal(t, 1, handled["group2"].requests)
+	assert.Empty(t, q.pending)
+}
+
+func Test_groupQueueRunning(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan int, 2)
+
+	calls := 0
+
+	q := newGroupQueue(10*time.Millisecond, zap.NewNop(), func(req *groupRequest) {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-release
+		}
+
+		done <- req.requests
+	})
+
+	q.enqueue("group1", groupWorkOwners, "governor.events.members", &v1alpha1.Event{})
+
+	<-started
+
+	// requests while the group is being reconciled wait for it to finish
+	q.enqueue("group1", groupWorkOwners, "governor.events.members", &v1alpha1.Event{})
+	q.enqueue("group1", groupWorkOwners, "governor.events.members", &v1alpha1.Event{})
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	assert.Equal(t, 1, <-done)
+	assert.Equal(t, 2, <-done)
+}
+
+func Test_groupQueueStop(t *testing.T) {
+	q := newGroupQueue(time.Hour, zap.NewNop(), func(_ *groupRequest) {
+		t.Error("stopped queue handled a request")
+	})
+
+	q.enqueue("group1", groupWorkUpdate, "governor.events.groups", &v1alpha1.Event{})
+	q.stop()
+	q.enqueue("group2", groupWorkUpdate, "governor.events.groups", &v1alpha1.Event{})
+
+	assert.Empty(t, q.pending)
+	assert.Empty(t, q.timers)
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/gin-contrib/cors"
 	ginzap "github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	ginprometheus "github.com/zsais/go-gin-prometheus"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
@@ -29,6 +32,16 @@ type Server struct {
 	AuditFileWriter io.Writer
 	NATSClient      *NATSClient
 	Reconciler      *reconciler.Reconciler
+	// CoalesceWindow is how long group reconcile requests from governor events are held so duplicate
+	// requests for the same group are merged, zero disables coalescing
+	CoalesceWindow time.Duration
+	// PushgatewayURL is the prometheus pushgateway the final metrics are pushed to on shutdown, empty disables it
+	PushgatewayURL string
+	// AdminToken is the bearer token of the admin endpoints, empty disables them
+	AdminToken string
+
+	handlers map[string]nats.MsgHandler
+	queue    *groupQueue
 }
 
 var (
@@ -57,7 +70,9 @@ func (s *Server) setup() *gin.Engine {
 		return c.FullPath()
 	}
 
-	p.Use(r)
+	// serve the metrics ourselves with openmetrics enabled so the counter exemplars are exposed
+	r.Use(p.HandlerFunc())
+	r.GET(p.MetricsPath, gin.WrapH(promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})))
 
 	customLogger := s.Logger.With(zap.String("component", "httpsrv"))
 	r.Use(
@@ -85,6 +100,20 @@ func (s *Server) setup() *gin.Engine {
 	r.GET("/healthz/liveness", s.livenessCheck)
 	r.GET("/healthz/readiness", s.readinessCheck)
 
+	// Reconciler status
+	r.GET("/api/v1/status", s.statusHandler)
+	r.GET("/api/v1/what-if", s.whatIfHandler)
+	r.GET("/ui", s.uiHandler)
+
+	// Group membership diff, it lists user emails and reads both systems on every request
+	r.GET("/api/v1/groups/:id/diff", s.adminAuth, s.groupDiffHandler)
+
+	// Reconciler controls
+	admin := r.Group("/api/v1/reconciler", s.adminAuth)
+	admin.POST("/pause", s.pauseHandler)
+	admin.POST("/resume", s.resumeHandler)
+	admin.DELETE("/quarantine/:id", s.unquarantineHandler)
+
 	r.NoRoute(func(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"message": "invalid request - route not found"})
 	})
@@ -120,10 +149,16 @@ func (s *Server) Run(ctx context.Context) error {
 
 	go s.Reconciler.Run(ctx)
 
+	if s.CoalesceWindow > 0 {
+		s.queue = newGroupQueue(s.CoalesceWindow, s.Logger.With(zap.String("component", "groupqueue")), s.reconcileGroupRequest)
+	}
+
 	if err := s.registerSubscriptionHandlers(); err != nil {
 		panic(err)
 	}
 
+	s.watchNATSConnection()
+
 	<-ctx.Done()
 
 	ctxShutDown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
@@ -133,6 +168,10 @@ func (s *Server) Run(ctx context.Context) error {
 
 	s.Reconciler.Stop()
 
+	if s.queue != nil {
+		s.queue.stop()
+	}
+
 	wg.Add(1)
 
 	go func() {
@@ -150,6 +189,11 @@ func (s *Server) Run(ctx context.Context) error {
 	// wait for clean shutdown
 	wg.Wait()
 
+	ctxFlush, cancelFlush := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelFlush()
+
+	s.flush(ctxFlush)
+
 	s.Logger.Info("server shutdown cleanly", zap.String("time", time.Now().UTC().Format(time.RFC3339)))
 
 	return nil
@@ -164,6 +208,16 @@ func (s *Server) livenessCheck(c *gin.Context) {
 
 // readinessCheck ensures that the server is up and that we are able to process requests.
 func (s *Server) readinessCheck(c *gin.Context) {
+	// a reconnecting connection isn't connected, governor events are buffered until it's back up
+	if s.NATSClient != nil && s.NATSClient.conn != nil && !s.NATSClient.conn.IsConnected() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "DOWN",
+			"nats":   s.NATSClient.conn.Status().String(),
+		})
+
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status": "UP",
 	})
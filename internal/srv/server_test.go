@@ -2,12 +2,18 @@ package srv
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	governor "github.com/metal-toolbox/governor-api/pkg/client"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/okta"
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
 )
 
 func TestUnknownRoute(t *testing.T) {
@@ -69,3 +75,162 @@ func TestReadinessRouteUp(t *testing.T) {
 	assert.Equal(t, 200, w.Code)
 	assert.Equal(t, `{"status":"UP"}`, w.Body.String())
 }
+
+func TestStatusRoute(t *testing.T) {
+	hs := Server{
+		Logger:     zap.NewNop(),
+		Reconciler: reconciler.New(reconciler.WithSkipDelete(true)),
+	}
+	s := hs.NewServer()
+	router := s.Handler
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/api/v1/status", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"skip_delete":true`)
+	assert.Contains(t, w.Body.String(), `"runs":[]`)
+}
+
+func TestStatusRouteNoReconciler(t *testing.T) {
+	hs := Server{
+		Logger: zap.NewNop(),
+	}
+	s := hs.NewServer()
+	router := s.Handler
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/api/v1/status", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 503, w.Code)
+}
+
+func TestWhatIfRoute(t *testing.T) {
+	oc, err := okta.NewClient(
+		okta.WithURL("https://example.okta.com"),
+		okta.WithToken("token"),
+		okta.WithRecorder(okta.NewRecorder(0)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		rec      *reconciler.Reconciler
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "what-if mode",
+			rec:      reconciler.New(reconciler.WithOktaClient(oc)),
+			wantCode: 200,
+			wantBody: `{"dropped":0,"mutations":[]}`,
+		},
+		{
+			name:     "what-if mode not enabled",
+			rec:      reconciler.New(),
+			wantCode: 404,
+		},
+		{
+			name:     "no reconciler",
+			wantCode: 503,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := Server{
+				Logger: zap.NewNop(),
+			}
+
+			if tt.rec != nil {
+				hs.Reconciler = tt.rec
+			}
+
+			router := hs.NewServer().Handler
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/api/v1/what-if", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUIRoute(t *testing.T) {
+	hs := Server{
+		Logger:     zap.NewNop(),
+		Reconciler: reconciler.New(),
+	}
+	s := hs.NewServer()
+	router := s.Handler
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/ui", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<h2>Last runs</h2>")
+	assert.Contains(t, w.Body.String(), "no runs yet")
+}
+
+func TestGroupDiffRoute(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		auth       string
+		wantCode   int
+	}{
+		{name: "disabled without admin token", wantCode: http.StatusForbidden},
+		{name: "missing token", adminToken: "admin", wantCode: http.StatusUnauthorized},
+		{name: "invalid token", adminToken: "admin", auth: "Bearer nope", wantCode: http.StatusUnauthorized},
+		{name: "no reconciler", adminToken: "admin", auth: "Bearer admin", wantCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := Server{
+				Logger:     zap.NewNop(),
+				AdminToken: tt.adminToken,
+			}
+			router := hs.NewServer().Handler
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/api/v1/groups/group-1/diff", nil)
+
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
+
+func Test_diffErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "governor group not found", err: governor.ErrGroupNotFound, want: 404},
+		{name: "okta group not found", err: fmt.Errorf("lookup: %w", okta.ErrGroupsNotFound), want: 404},
+		{name: "circuit open", err: reconciler.ErrCircuitOpen, want: 503},
+		{name: "other", err: reconciler.ErrGovernorUserNotFound, want: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, diffErrorStatus(tt.err))
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package srv
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"go.uber.org/zap"
+)
+
+// metricsPushJob is the pushgateway job the final metrics are pushed under
+const metricsPushJob = "gov-okta-addon"
+
+// syncer is implemented by writers that buffer writes, ie. an *os.File
+type syncer interface {
+	Sync() error
+}
+
+// flush saves what would otherwise be lost on termination: the reconciler state (okta event log checkpoint
+// and group schedule), the buffered audit events and the final metrics, and logs a shutdown summary.  Each
+// step is attempted even when a previous one failed.
+func (s *Server) flush(ctx context.Context) {
+	started := time.Now()
+
+	fields := []zap.Field{}
+
+	if s.Reconciler != nil {
+		if checkpoint := s.Reconciler.EventlogCheckpoint(); !checkpoint.IsZero() {
+			fields = append(fields, zap.Time("eventlog.checkpoint", checkpoint))
+		}
+
+		if runs := s.Reconciler.Status().Runs; len(runs) > 0 {
+			fields = append(fields, zap.String("reconciler.last_result", runs[len(runs)-1].Result))
+		}
+
+		fields = append(fields, zap.Bool("state.saved", s.step("saving reconciler state", s.Reconciler.SaveState(ctx))))
+	}
+
+	fields = append(fields, zap.Bool("audit.flushed", s.step("flushing audit events", flushWriter(s.AuditFileWriter))))
+
+	if s.PushgatewayURL != "" {
+		fields = append(fields, zap.Bool("metrics.pushed", s.step("pushing final metrics", s.pushMetrics(ctx))))
+	}
+
+	fields = append(fields, zap.Duration("duration", time.Since(started)))
+
+	s.Logger.Info("shutdown summary", fields...)
+}
+
+// step logs the error of a shutdown step and returns true if it succeeded
+func (s *Server) step(name string, err error) bool {
+	if err != nil {
+		s.Logger.Error("error "+name, zap.Error(err))
+		return false
+	}
+
+	return true
+}
+
+// pushMetrics pushes the metrics to the pushgateway
+func (s *Server) pushMetrics(ctx context.Context) error {
+	return push.New(s.PushgatewayURL, metricsPushJob).Gatherer(prometheus.DefaultGatherer).PushContext(ctx)
+}
+
+// flushWriter syncs a writer that buffers writes
+func flushWriter(w io.Writer) error {
+	if f, ok := w.(syncer); ok {
+		return f.Sync()
+	}
+
+	return nil
+}
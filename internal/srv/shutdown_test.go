@@ -0,0 +1,78 @@
+package srv
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+)
+
+type syncWriter struct {
+	bytes.Buffer
+	synced bool
+}
+
+func (w *syncWriter) Sync() error {
+	w.synced = true
+	return nil
+}
+
+func TestServer_flush(t *testing.T) {
+	pushed := ""
+
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed = r.Method + " " + r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gw.Close()
+
+	core, logs := observer.New(zap.InfoLevel)
+	auf := &syncWriter{}
+
+	s := &Server{
+		Logger:          zap.New(core),
+		AuditFileWriter: auf,
+		Reconciler:      reconciler.New(),
+		PushgatewayURL:  gw.URL,
+	}
+
+	s.flush(context.Background())
+
+	assert.True(t, auf.synced)
+	assert.Equal(t, "PUT /metrics/job/gov-okta-addon", pushed)
+
+	summary := logs.FilterMessage("shutdown summary").All()
+	if assert.Len(t, summary, 1) {
+		fields := summary[0].ContextMap()
+		assert.Equal(t, true, fields["state.saved"])
+		assert.Equal(t, true, fields["audit.flushed"])
+		assert.Equal(t, true, fields["metrics.pushed"])
+	}
+}
+
+func TestServer_flushPushError(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	s := &Server{
+		Logger:         zap.New(core),
+		PushgatewayURL: "http://127.0.0.1:0",
+	}
+
+	s.flush(context.Background())
+
+	summary := logs.FilterMessage("shutdown summary").All()
+	if assert.Len(t, summary, 1) {
+		fields := summary[0].ContextMap()
+		assert.Equal(t, false, fields["metrics.pushed"])
+		assert.NotContains(t, fields, "state.saved")
+	}
+
+	assert.Len(t, logs.FilterMessage("error pushing final metrics").All(), 1)
+}
@@ -0,0 +1,126 @@
+package srv
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/reconciler"
+)
+
+// uiRefreshSeconds is how often the dashboard reloads itself
+const uiRefreshSeconds = 30
+
+var uiTemplate = template.Must(template.New("ui").Funcs(template.FuncMap{
+	"ts": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+
+		return t.UTC().Format(time.RFC3339)
+	},
+	"pct": func(f float64) string {
+		return fmt.Sprintf("%.2f%%", f*100)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{ .Refresh }}">
+<title>gov-okta-addon status</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+.bad { color: #b00; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>gov-okta-addon</h1>
+<p>reconciler {{ .Status.ID }}{{ if .Status.Running }} (running){{ end }}, dry-run: {{ .Status.DryRun }}, what-if: {{ .Status.WhatIf }}, skip-delete: {{ .Status.SkipDelete }}{{ if .Status.Paused }}, <span class="bad">paused since {{ ts .Status.PausedSince }}{{ with .Status.PauseReason }}: {{ . }}{{ end }}</span>{{ end }}</p>
+
+<h2>Last runs</h2>
+<table>
+<tr><th>started</th><th>finished</th><th>duration</th><th>result</th><th>groups</th><th>added</th><th>removed</th><th>skipped</th><th>errors</th><th>error</th></tr>
+{{ range .Status.Runs }}<tr><td>{{ ts .Started }}</td><td>{{ ts .Finished }}</td><td>{{ .Duration }}</td><td{{ if or (eq .Result "failed") (eq .Result "partial") }} class="bad"{{ end }}>{{ .Result }}</td><td>{{ .Summary.GroupsChecked }}</td><td>{{ .Summary.MembersAdded }}</td><td>{{ .Summary.MembersRemoved }}</td><td>{{ .Summary.MembersSkipped }}</td><td>{{ .Summary.Errors }}</td><td>{{ .Error }}</td></tr>
+{{ else }}<tr><td colspan="10">no runs yet</td></tr>
+{{ end }}</table>
+
+<h2>Drift</h2>
+<table>
+<tr><th>invariant</th><th>governor</th><th>okta</th><th>divergence</th><th>tolerance</th></tr>
+{{ range .Status.Drift }}<tr><td>{{ .Name }}</td><td>{{ .Governor }}</td><td>{{ .Okta }}</td><td{{ if .Exceeded }} class="bad"{{ end }}>{{ pct .Divergence }}</td><td>{{ pct .Tolerance }}</td></tr>
+{{ else }}<tr><td colspan="5">no invariants check, enable with --invariants</td></tr>
+{{ end }}</table>
+
+<h2>Failing groups ({{ len .Status.FailingGroups }})</h2>
+<table>
+<tr><th>group</th><th>id</th><th>error</th></tr>
+{{ range .Status.FailingGroups }}<tr><td>{{ .GroupSlug }}</td><td>{{ .GroupID }}</td><td class="bad">{{ .Error }}</td></tr>
+{{ else }}<tr><td colspan="3">none</td></tr>
+{{ end }}</table>
+
+<h2>Failing application assignments ({{ len .Status.FailingAppAssignments }})</h2>
+<table>
+<tr><th>org</th><th>group</th><th>okta group</th><th>change</th><th>error</th></tr>
+{{ range .Status.FailingAppAssignments }}<tr><td>{{ .Org }}</td><td>{{ .GovernorGroupSlug }}</td><td>{{ .OktaGroupID }}</td><td>{{ .Change }}</td><td class="bad">{{ .Error }}</td></tr>
+{{ else }}<tr><td colspan="5">none</td></tr>
+{{ end }}</table>
+
+<h2>Quarantined groups ({{ len .Status.QuarantinedGroups }})</h2>
+<table>
+<tr><th>group</th><th>id</th><th>failures</th><th>since</th><th>until</th><th>error</th></tr>
+{{ range .Status.QuarantinedGroups }}<tr><td>{{ .GroupSlug }}</td><td>{{ .GroupID }}</td><td>{{ .Failures }}</td><td>{{ ts .Since }}</td><td>{{ ts .Until }}</td><td class="bad">{{ .Error }}</td></tr>
+{{ else }}<tr><td colspan="6">none</td></tr>
+{{ end }}</table>
+
+<h2>Pending deletions ({{ .Status.PendingDeletionsTotal }})</h2>
+<table>
+<tr><th>type</th><th>governor group</th><th>okta group</th><th>okta user</th><th>okta app</th></tr>
+{{ range .Status.PendingDeletions }}<tr><td>{{ .Type }}</td><td>{{ .GovernorGroupID }}</td><td>{{ .OktaGroupID }}</td><td>{{ .OktaUserID }}</td><td>{{ .OktaAppID }}</td></tr>
+{{ else }}<tr><td colspan="5">none</td></tr>
+{{ end }}</table>
+{{ if gt .Status.PendingDeletionsTotal (len .Status.PendingDeletions) }}<p>showing the first {{ len .Status.PendingDeletions }}</p>{{ end }}
+</body>
+</html>
+`))
+
+// statusHandler returns the reconciler status as JSON
+func (s *Server) statusHandler(c *gin.Context) {
+	if s.Reconciler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "reconciler not running"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s.Reconciler.Status())
+}
+
+// uiHandler renders the reconciler status as a simple html dashboard
+func (s *Server) uiHandler(c *gin.Context) {
+	status := reconciler.Status{}
+	if s.Reconciler != nil {
+		status = s.Reconciler.Status()
+	}
+
+	var buf bytes.Buffer
+
+	if err := uiTemplate.Execute(&buf, struct {
+		Refresh int
+		Status  reconciler.Status
+	}{
+		Refresh: uiRefreshSeconds,
+		Status:  status,
+	}); err != nil {
+		s.Logger.Error("error rendering status dashboard", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "error rendering status dashboard"})
+
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
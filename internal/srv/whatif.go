@@ -0,0 +1,26 @@
+package srv
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// whatIfHandler returns the okta mutations recorded by the reconciler in what-if mode
+func (s *Server) whatIfHandler(c *gin.Context) {
+	if s.Reconciler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "reconciler not running"})
+		return
+	}
+
+	mutations, dropped := s.Reconciler.WhatIfMutations()
+	if mutations == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "what-if mode not enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mutations": mutations,
+		"dropped":   dropped,
+	})
+}
@@ -0,0 +1,3 @@
+// Package testserver provides fake okta and governor apis on httptest servers, so the real clients can be tested
+// end to end against them, including the pagination, search expressions and request bodies of the calls
+package testserver
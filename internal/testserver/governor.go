@@ -0,0 +1,534 @@
+package testserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+)
+
+// GovernorGroup is a group served by the fake governor api, the admins are the members added as admins of the group
+type GovernorGroup struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Slug          string     `json:"slug"`
+	Description   string     `json:"description"`
+	Note          string     `json:"note"`
+	Organizations []string   `json:"organizations"`
+	Members       []string   `json:"members,omitempty"`
+	Admins        []string   `json:"admins,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+}
+
+// GovernorUser is a user served by the fake governor api
+type GovernorUser struct {
+	ID         string     `json:"id"`
+	ExternalID string     `json:"external_id,omitempty"`
+	Name       string     `json:"name"`
+	Email      string     `json:"email"`
+	Status     string     `json:"status,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+// GovernorOrganization is an organization served by the fake governor api
+type GovernorOrganization struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// governorUsersPage is a page of the v1beta1 users api
+type governorUsersPage struct {
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Records    []*GovernorUser `json:"records"`
+}
+
+// Governor is a fake governor api serving groups, group members and membership requests, users and organizations
+// from memory.  It also serves an oauth2 token endpoint at /oauth2/token for the client credentials flow.
+type Governor struct {
+	*httptest.Server
+
+	// PageSize is the maximum number of users in a page of the v1beta1 users api
+	PageSize int
+
+	mu       sync.Mutex
+	groups   []*GovernorGroup
+	users    []*GovernorUser
+	orgs     []*GovernorOrganization
+	requests map[string][]*v1alpha1.GroupMemberRequest
+	served   []Request
+}
+
+// NewGovernor starts a fake governor api, it must be closed when done
+func NewGovernor() *Governor {
+	g := &Governor{
+		PageSize: DefaultPageSize,
+		requests: map[string][]*v1alpha1.GroupMemberRequest{},
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /oauth2/token", g.token)
+	mux.HandleFunc("GET /api/v1alpha1/groups", g.listGroups)
+	mux.HandleFunc("GET /api/v1alpha1/groups/{id}", g.getGroup)
+	mux.HandleFunc("PUT /api/v1alpha1/groups/{id}", g.updateGroup)
+	mux.HandleFunc("GET /api/v1alpha1/groups/{id}/users", g.listGroupMembers)
+	mux.HandleFunc("PUT /api/v1alpha1/groups/{id}/users/{uid}", g.addGroupMember)
+	mux.HandleFunc("DELETE /api/v1alpha1/groups/{id}/users/{uid}", g.removeGroupMember)
+	mux.HandleFunc("GET /api/v1alpha1/groups/{id}/requests", g.listGroupRequests)
+	mux.HandleFunc("POST /api/v1alpha1/groups/{id}/requests", g.createGroupRequest)
+	mux.HandleFunc("GET /api/v1alpha1/organizations", g.listOrganizations)
+	mux.HandleFunc("GET /api/v1alpha1/users", g.queryUsers)
+	mux.HandleFunc("GET /api/v1alpha1/users/{id}", g.getUser)
+	mux.HandleFunc("PUT /api/v1alpha1/users/{id}", g.updateUser)
+	mux.HandleFunc("GET /api/v1beta1/users", g.listUsers)
+
+	g.Server = httptest.NewServer(g.record(mux))
+
+	return g
+}
+
+// TokenURL returns the url of the oauth2 token endpoint
+func (g *Governor) TokenURL() string {
+	return g.URL + "/oauth2/token"
+}
+
+// AddGroup adds a governor group, the members are governor user ids
+func (g *Governor) AddGroup(group *GovernorGroup) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.groups = append(g.groups, group)
+}
+
+// AddUser adds a governor user
+func (g *Governor) AddUser(u *GovernorUser) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.users = append(g.users, u)
+}
+
+// AddOrganization adds a governor organization
+func (g *Governor) AddOrganization(o *GovernorOrganization) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.orgs = append(g.orgs, o)
+}
+
+// User returns a copy of the governor user, nil when it doesn't exist
+func (g *Governor) User(id string) *GovernorUser {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if u := g.user(id); u != nil {
+		return clone(u)
+	}
+
+	return nil
+}
+
+// Group returns a copy of the governor group, nil when it doesn't exist
+func (g *Governor) Group(id string) *GovernorGroup {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if group := g.group(id); group != nil {
+		return clone(group)
+	}
+
+	return nil
+}
+
+// GroupMembers returns the governor user ids of the group members
+func (g *Governor) GroupMembers(id string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if group := g.group(id); group != nil {
+		return slices.Clone(group.Members)
+	}
+
+	return nil
+}
+
+// GroupRequests returns the membership requests of the governor group
+func (g *Governor) GroupRequests(id string) []*v1alpha1.GroupMemberRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return clone(g.requests[id])
+}
+
+// Requests returns the requests served so far, in order
+func (g *Governor) Requests() []Request {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return slices.Clone(g.served)
+}
+
+// record records the requests before serving them
+func (g *Governor) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+
+		g.mu.Lock()
+		g.served = append(g.served, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Body: body})
+		g.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (g *Governor) token(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "governor-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+func (g *Governor) listGroups(w http.ResponseWriter, _ *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	groups := []*GovernorGroup{}
+
+	for _, group := range g.groups {
+		if group.DeletedAt == nil {
+			groups = append(groups, group)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, groups)
+}
+
+func (g *Governor) getGroup(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group := g.group(r.PathValue("id"))
+	if group == nil || (group.DeletedAt != nil && !r.URL.Query().Has("deleted")) {
+		governorError(w, http.StatusNotFound, "group not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (g *Governor) updateGroup(w http.ResponseWriter, r *http.Request) {
+	req := v1alpha1.GroupReq{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		governorError(w, http.StatusBadRequest, "invalid group request")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group := g.group(r.PathValue("id"))
+	if group == nil || group.DeletedAt != nil {
+		governorError(w, http.StatusNotFound, "group not found")
+		return
+	}
+
+	// like governor, the update replaces the name, description and note
+	group.Name = req.Name
+	group.Description = req.Description
+	group.Note = req.Note
+
+	writeJSON(w, http.StatusAccepted, group)
+}
+
+func (g *Governor) listGroupMembers(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group := g.group(r.PathValue("id"))
+	if group == nil || group.DeletedAt != nil {
+		governorError(w, http.StatusNotFound, "group not found")
+		return
+	}
+
+	members := []*v1alpha1.GroupMember{}
+
+	for _, id := range group.Members {
+		if u := g.user(id); u != nil && u.DeletedAt == nil {
+			members = append(members, &v1alpha1.GroupMember{ID: u.ID, Name: u.Name, Email: u.Email, Status: u.Status, Direct: true})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, members)
+}
+
+func (g *Governor) addGroupMember(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group, u := g.group(r.PathValue("id")), g.user(r.PathValue("uid"))
+	if group == nil || group.DeletedAt != nil || u == nil || u.DeletedAt != nil {
+		governorError(w, http.StatusNotFound, "group or user not found")
+		return
+	}
+
+	body := struct {
+		IsAdmin bool `json:"is_admin"`
+	}{}
+
+	// the request body is optional, members are added as non admins without it
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	if !slices.Contains(group.Members, u.ID) {
+		group.Members = append(group.Members, u.ID)
+	}
+
+	if body.IsAdmin && !slices.Contains(group.Admins, u.ID) {
+		group.Admins = append(group.Admins, u.ID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (g *Governor) removeGroupMember(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group := g.group(r.PathValue("id"))
+	if group == nil || group.DeletedAt != nil || !slices.Contains(group.Members, r.PathValue("uid")) {
+		governorError(w, http.StatusNotFound, "group member not found")
+		return
+	}
+
+	group.Members = slices.DeleteFunc(group.Members, func(id string) bool { return id == r.PathValue("uid") })
+	group.Admins = slices.DeleteFunc(group.Admins, func(id string) bool { return id == r.PathValue("uid") })
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (g *Governor) listGroupRequests(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.group(r.PathValue("id")) == nil {
+		governorError(w, http.StatusNotFound, "group not found")
+		return
+	}
+
+	reqs := g.requests[r.PathValue("id")]
+	if reqs == nil {
+		reqs = []*v1alpha1.GroupMemberRequest{}
+	}
+
+	writeJSON(w, http.StatusOK, reqs)
+}
+
+func (g *Governor) createGroupRequest(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		UserID string `json:"user_id"`
+		Note   string `json:"note"`
+		Kind   string `json:"kind"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.UserID == "" {
+		governorError(w, http.StatusBadRequest, "invalid membership request")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := r.PathValue("id")
+
+	group := g.group(id)
+	if group == nil {
+		governorError(w, http.StatusNotFound, "group not found")
+		return
+	}
+
+	for _, req := range g.requests[id] {
+		if req.UserID == body.UserID {
+			governorError(w, http.StatusConflict, "membership request already exists")
+			return
+		}
+	}
+
+	g.requests[id] = append(g.requests[id], &v1alpha1.GroupMemberRequest{
+		ID:        strconv.Itoa(len(g.requests[id]) + 1),
+		GroupID:   id,
+		GroupSlug: group.Slug,
+		UserID:    body.UserID,
+		Note:      body.Note,
+		Kind:      body.Kind,
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (g *Governor) listOrganizations(w http.ResponseWriter, _ *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	orgs := g.orgs
+	if orgs == nil {
+		orgs = []*GovernorOrganization{}
+	}
+
+	writeJSON(w, http.StatusOK, orgs)
+}
+
+func (g *Governor) getUser(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	u := g.user(r.PathValue("id"))
+	if u == nil || (u.DeletedAt != nil && !r.URL.Query().Has("deleted")) {
+		governorError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	// like governor, a single user is served with the ids of their groups
+	memberships := []string{}
+
+	for _, grp := range g.groups {
+		if grp.DeletedAt == nil && slices.Contains(grp.Members, u.ID) {
+			memberships = append(memberships, grp.ID)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		*GovernorUser
+		Memberships []string `json:"memberships,omitempty"`
+	}{u, memberships})
+}
+
+func (g *Governor) updateUser(w http.ResponseWriter, r *http.Request) {
+	req := v1alpha1.UserReq{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		governorError(w, http.StatusBadRequest, "invalid user request")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	u := g.user(r.PathValue("id"))
+	if u == nil || u.DeletedAt != nil {
+		governorError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if req.Email != "" {
+		u.Email = req.Email
+	}
+
+	if req.Name != "" {
+		u.Name = req.Name
+	}
+
+	if req.ExternalID != "" {
+		u.ExternalID = req.ExternalID
+	}
+
+	if req.Status != "" {
+		u.Status = req.Status
+	}
+
+	writeJSON(w, http.StatusAccepted, u)
+}
+
+// queryUsers serves the v1alpha1 users api, filtered like the v1beta1 users api without pagination
+func (g *Governor) queryUsers(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, g.filterUsers(r.URL.Query()))
+}
+
+// listUsers serves the v1beta1 users api, filtered by the email and external_id query parameters.  Deleted users
+// are only listed with the deleted query parameter and the users are paginated with next_cursor.
+func (g *Governor) listUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	users := g.filterUsers(q)
+
+	start := 0
+
+	if cursor := q.Get("next_cursor"); cursor != "" {
+		start = slices.IndexFunc(users, func(u *GovernorUser) bool { return u.ID == cursor }) + 1
+	}
+
+	page := governorUsersPage{Records: users[start:]}
+
+	if g.PageSize > 0 && len(page.Records) > g.PageSize {
+		page.Records = page.Records[:g.PageSize]
+		page.NextCursor = page.Records[g.PageSize-1].ID
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// filterUsers returns the users matching the email and external_id query parameters, deleted users are only
+// returned with the deleted query parameter
+func (g *Governor) filterUsers(q url.Values) []*GovernorUser {
+	users := []*GovernorUser{}
+
+	for _, u := range g.users {
+		if u.DeletedAt != nil && q.Get("deleted") != "true" {
+			continue
+		}
+
+		if q.Has("email") && !slices.ContainsFunc(q["email"], func(e string) bool { return strings.EqualFold(e, u.Email) }) {
+			continue
+		}
+
+		if q.Has("external_id") && !slices.Contains(q["external_id"], u.ExternalID) {
+			continue
+		}
+
+		users = append(users, u)
+	}
+
+	return users
+}
+
+// group returns the group with the given id or slug, like the governor api
+func (g *Governor) group(id string) *GovernorGroup {
+	for _, group := range g.groups {
+		if group.ID == id || group.Slug == id {
+			return group
+		}
+	}
+
+	return nil
+}
+
+func (g *Governor) user(id string) *GovernorUser {
+	for _, u := range g.users {
+		if u.ID == id {
+			return u
+		}
+	}
+
+	return nil
+}
+
+// governorError writes a governor api error
+func governorError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"message": msg})
+}
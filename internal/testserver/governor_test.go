@@ -0,0 +1,50 @@
+package testserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/metal-toolbox/gov-okta-addon/internal/govclient"
+	"github.com/metal-toolbox/governor-api/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGovernor_groupMembers(t *testing.T) {
+	g := NewGovernor()
+	defer g.Close()
+
+	g.AddUser(&GovernorUser{ID: "user-1", Email: "one@example.com"})
+	g.AddGroup(&GovernorGroup{ID: "group-1", Slug: "platform"})
+
+	c := govclient.New(nil, g.URL, g.Client())
+
+	assert.NoError(t, c.AddGroupMember(context.TODO(), "group-1", "user-1", false))
+	assert.Equal(t, []string{"user-1"}, g.GroupMembers("group-1"))
+
+	// adding a member twice is a no-op
+	assert.NoError(t, c.AddGroupMember(context.TODO(), "group-1", "user-1", false))
+	assert.Equal(t, []string{"user-1"}, g.GroupMembers("group-1"))
+
+	assert.ErrorIs(t, c.AddGroupMember(context.TODO(), "group-1", "user-2", false), govclient.ErrGroupMemberNotFound)
+
+	assert.NoError(t, c.RemoveGroupMember(context.TODO(), "group-1", "user-1"))
+	assert.Empty(t, g.GroupMembers("group-1"))
+
+	assert.ErrorIs(t, c.RemoveGroupMember(context.TODO(), "group-1", "user-1"), govclient.ErrGroupMemberNotFound)
+}
+
+func TestGovernor_updateGroup(t *testing.T) {
+	g := NewGovernor()
+	defer g.Close()
+
+	g.AddGroup(&GovernorGroup{ID: "group-1", Name: "Platform", Slug: "platform", Description: "platform team"})
+
+	c := govclient.New(nil, g.URL, g.Client())
+
+	assert.NoError(t, c.UpdateGroup(context.TODO(), "group-1", &v1alpha1.GroupReq{Name: "Platform", Description: "platform team", Note: "okta_group_id: 00g-1"}))
+	assert.Equal(t, "okta_group_id: 00g-1", g.Group("group-1").Note)
+	assert.Equal(t, "platform team", g.Group("group-1").Description)
+
+	assert.ErrorIs(t, c.UpdateGroup(context.TODO(), "group-2", &v1alpha1.GroupReq{Name: "Other"}), govclient.ErrGroupNotFound)
+	assert.Nil(t, g.Group("group-2"))
+}
@@ -0,0 +1,1092 @@
+package testserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// DefaultPageSize is the default maximum number of results in a page of the fake apis
+const DefaultPageSize = 200
+
+// Request is a request recorded by a fake api
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   []byte
+}
+
+// Okta is a fake okta api serving groups, group members and owners, users, applications, application group
+// assignments, push group mappings and log events from memory.  It's served over TLS since the okta sdk requires an https org url,
+// the okta client must use the http client of the server.
+type Okta struct {
+	*httptest.Server
+
+	// PageSize is the maximum number of results in a page, the limit of the request is used when it's smaller
+	PageSize int
+
+	mu        sync.Mutex
+	ids       int
+	groups    []*okta.Group
+	members   map[string][]string
+	owners    map[string][]string
+	users     []*okta.User
+	apps      []*okta.Application
+	appGroups map[string][]string
+	assigned  map[string]*okta.ApplicationGroupAssignment
+	pushes    map[string][]*OktaPushMapping
+	logs      []*okta.LogEvent
+	requests  []Request
+	fail      func(Request) int
+}
+
+// NewOkta starts a fake okta api, it must be closed when done
+func NewOkta() *Okta {
+	o := &Okta{
+		PageSize:  DefaultPageSize,
+		members:   map[string][]string{},
+		owners:    map[string][]string{},
+		appGroups: map[string][]string{},
+		assigned:  map[string]*okta.ApplicationGroupAssignment{},
+		pushes:    map[string][]*OktaPushMapping{},
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/groups", o.listGroups)
+	mux.HandleFunc("POST /api/v1/groups", o.createGroup)
+	mux.HandleFunc("GET /api/v1/groups/{id}", o.getGroup)
+	mux.HandleFunc("PUT /api/v1/groups/{id}", o.updateGroup)
+	mux.HandleFunc("DELETE /api/v1/groups/{id}", o.deleteGroup)
+	mux.HandleFunc("GET /api/v1/groups/{id}/users", o.listGroupUsers)
+	mux.HandleFunc("PUT /api/v1/groups/{id}/users/{uid}", o.addGroupUser)
+	mux.HandleFunc("DELETE /api/v1/groups/{id}/users/{uid}", o.removeGroupUser)
+	mux.HandleFunc("GET /api/v1/groups/{id}/owners", o.listGroupOwners)
+	mux.HandleFunc("POST /api/v1/groups/{id}/owners", o.addGroupOwner)
+	mux.HandleFunc("DELETE /api/v1/groups/{id}/owners/{uid}", o.removeGroupOwner)
+	mux.HandleFunc("GET /api/v1/users", o.listUsers)
+	mux.HandleFunc("GET /api/v1/users/{id}", o.getUser)
+	mux.HandleFunc("POST /api/v1/users/{id}", o.updateUser)
+	mux.HandleFunc("DELETE /api/v1/users/{id}", o.deleteUser)
+	mux.HandleFunc("GET /api/v1/users/{id}/groups", o.listUserGroups)
+	mux.HandleFunc("POST /api/v1/users/{id}/lifecycle/{op}", o.userLifecycle)
+	mux.HandleFunc("GET /api/v1/apps", o.listApps)
+	mux.HandleFunc("GET /api/v1/apps/{id}/groups", o.listAppGroups)
+	mux.HandleFunc("PUT /api/v1/apps/{id}/groups/{gid}", o.assignAppGroup)
+	mux.HandleFunc("DELETE /api/v1/apps/{id}/groups/{gid}", o.removeAppGroup)
+	mux.HandleFunc("GET /api/v1/apps/{id}/group-push/mappings", o.listPushMappings)
+	mux.HandleFunc("POST /api/v1/apps/{id}/group-push/mappings", o.createPushMapping)
+	mux.HandleFunc("GET /api/v1/apps/{id}/group-push/mappings/{mid}", o.getPushMapping)
+	mux.HandleFunc("PATCH /api/v1/apps/{id}/group-push/mappings/{mid}", o.updatePushMapping)
+	mux.HandleFunc("DELETE /api/v1/apps/{id}/group-push/mappings/{mid}", o.deletePushMapping)
+	mux.HandleFunc("GET /api/v1/logs", o.listLogs)
+
+	o.Server = httptest.NewTLSServer(o.record(mux))
+
+	return o
+}
+
+// FailRequests fails the requests f returns an http status for with an okta error, the requests it returns 0 for
+// are served.  The failed requests are still recorded.
+func (o *Okta) FailRequests(f func(Request) int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.fail = f
+}
+
+// AddGroup adds an okta group with the profile attributes and members
+func (o *Okta) AddGroup(id, name string, profile map[string]interface{}, members ...string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	o.groups = append(o.groups, &okta.Group{
+		Id:                    id,
+		Type:                  "OKTA_GROUP",
+		LastUpdated:           &now,
+		LastMembershipUpdated: &now,
+		Profile: &okta.GroupProfile{
+			Name:            name,
+			GroupProfileMap: okta.GroupProfileMap(profile),
+		},
+	})
+
+	o.members[id] = members
+}
+
+// AddUser adds an okta user with the status and profile attributes
+func (o *Okta) AddUser(id, status string, profile map[string]interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p := okta.UserProfile(profile)
+	now := time.Now().UTC()
+
+	o.users = append(o.users, &okta.User{Id: id, Status: status, Created: &now, LastUpdated: &now, StatusChanged: &now, Profile: &p})
+}
+
+// SetUserLastLogin sets the last login and creation time of an okta user, a zero last login means the user never
+// logged in
+func (o *Okta) SetUserLastLogin(id string, lastLogin, created time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	u := o.user(id)
+	if u == nil {
+		return
+	}
+
+	u.Created = &created
+	u.LastLogin = nil
+
+	if !lastLogin.IsZero() {
+		u.LastLogin = &lastLogin
+	}
+}
+
+// AddApp adds an okta application with the app settings (ie. githubOrg), assigned to the groups
+func (o *Okta) AddApp(id, name string, settings map[string]interface{}, groups ...string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	app := okta.ApplicationSettingsApplication(settings)
+
+	o.apps = append(o.apps, &okta.Application{
+		Id:       id,
+		Name:     name,
+		Status:   "ACTIVE",
+		Settings: &okta.ApplicationSettings{App: &app},
+	})
+
+	o.appGroups[id] = groups
+
+	for i, gid := range groups {
+		o.assigned[id+"/"+gid] = &okta.ApplicationGroupAssignment{Id: gid, Priority: int64(i), PriorityPtr: okta.Int64Ptr(int64(i))}
+	}
+}
+
+// SetAppGroupAssignment sets the priority and profile of the assignment of a group to an okta application
+func (o *Okta) SetAppGroupAssignment(id, gid string, priority int64, profile map[string]interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.assigned[id+"/"+gid] = &okta.ApplicationGroupAssignment{Id: gid, Priority: priority, PriorityPtr: okta.Int64Ptr(priority), Profile: profile}
+}
+
+// OktaPushMapping is a push group mapping served by the fake okta api, the target group name is only set when the
+// mapping is created
+type OktaPushMapping struct {
+	ID              string `json:"id"`
+	SourceGroupID   string `json:"sourceGroupId"`
+	TargetGroupID   string `json:"targetGroupId,omitempty"`
+	TargetGroupName string `json:"targetGroupName,omitempty"`
+	Status          string `json:"status"`
+}
+
+// AddPushMapping adds a push group mapping of an okta group to an okta application with the status
+func (o *Okta) AddPushMapping(id string, m *OktaPushMapping) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pushes[id] = append(o.pushes[id], m)
+}
+
+// PushMappings returns a copy of the push group mappings of an okta application
+func (o *Okta) PushMappings(id string) []*OktaPushMapping {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return clone(o.pushes[id])
+}
+
+// AddLogEvent adds an okta log event, events are listed in the order they're added
+func (o *Okta) AddLogEvent(e *okta.LogEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.logs = append(o.logs, e)
+}
+
+// Group returns a copy of the okta group, nil when it doesn't exist
+func (o *Okta) Group(id string) *okta.Group {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if g := o.group(id); g != nil {
+		return clone(g)
+	}
+
+	return nil
+}
+
+// Groups returns a copy of the okta groups
+func (o *Okta) Groups() []*okta.Group {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return clone(o.groups)
+}
+
+// GroupMembers returns the user ids of the members of the okta group
+func (o *Okta) GroupMembers(id string) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return slices.Clone(o.members[id])
+}
+
+// User returns a copy of the okta user, nil when it doesn't exist
+func (o *Okta) User(id string) *okta.User {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if u := o.user(id); u != nil {
+		return clone(u)
+	}
+
+	return nil
+}
+
+// AppGroups returns the ids of the groups assigned to the okta application
+func (o *Okta) AppGroups(id string) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return slices.Clone(o.appGroups[id])
+}
+
+// AppGroupAssignment returns a copy of the assignment of a group to an okta application, nil when the group isn't
+// assigned
+func (o *Okta) AppGroupAssignment(id, gid string) *okta.ApplicationGroupAssignment {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !slices.Contains(o.appGroups[id], gid) {
+		return nil
+	}
+
+	return clone(o.appAssignment(id, gid))
+}
+
+// Requests returns the requests served so far, in order
+func (o *Okta) Requests() []Request {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return slices.Clone(o.requests)
+}
+
+// record records the requests before serving them
+func (o *Okta) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+
+		req := Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Body: body}
+
+		o.mu.Lock()
+		o.requests = append(o.requests, req)
+		fail := o.fail
+		o.mu.Unlock()
+
+		if fail != nil {
+			if status := fail(req); status != 0 {
+				oktaError(w, status, "E0000009", "injected failure")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (o *Okta) listGroups(w http.ResponseWriter, r *http.Request) {
+	match, err := parseSearch(r.URL.Query().Get("search"))
+	if err != nil {
+		oktaError(w, http.StatusBadRequest, "E0000031", err.Error())
+		return
+	}
+
+	prefix := strings.ToLower(r.URL.Query().Get("q"))
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	groups := []*okta.Group{}
+
+	for _, g := range o.groups {
+		if match(groupAttr(g)) && strings.HasPrefix(strings.ToLower(g.Profile.Name), prefix) {
+			groups = append(groups, g)
+		}
+	}
+
+	writeOktaPage(w, r, o.PageSize, groups, func(g *okta.Group) string { return g.Id })
+}
+
+func (o *Okta) createGroup(w http.ResponseWriter, r *http.Request) {
+	g := &okta.Group{}
+	if err := json.NewDecoder(r.Body).Decode(g); err != nil || g.Profile == nil {
+		oktaError(w, http.StatusBadRequest, "E0000003", "the request body was not well-formed")
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.ids++
+
+	now := time.Now().UTC()
+
+	g.Id = fmt.Sprintf("00g%d", o.ids)
+	g.Type = "OKTA_GROUP"
+	g.Created = &now
+	g.LastUpdated = &now
+
+	o.groups = append(o.groups, g)
+	o.members[g.Id] = nil
+
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (o *Okta) getGroup(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	g := o.group(r.PathValue("id"))
+	if g == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (o *Okta) updateGroup(w http.ResponseWriter, r *http.Request) {
+	update := &okta.Group{}
+	if err := json.NewDecoder(r.Body).Decode(update); err != nil || update.Profile == nil {
+		oktaError(w, http.StatusBadRequest, "E0000003", "the request body was not well-formed")
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	g := o.group(r.PathValue("id"))
+	if g == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	// okta group lastUpdated has a second resolution, so make sure an update changes it
+	updated := g.LastUpdated.Add(time.Second)
+
+	g.Profile = update.Profile
+	g.LastUpdated = &updated
+
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (o *Okta) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := r.PathValue("id")
+
+	i := slices.IndexFunc(o.groups, func(g *okta.Group) bool { return g.Id == id })
+	if i < 0 {
+		oktaNotFound(w)
+		return
+	}
+
+	o.groups = slices.Delete(o.groups, i, i+1)
+
+	delete(o.members, id)
+	delete(o.owners, id)
+
+	for app, groups := range o.appGroups {
+		o.appGroups[app] = slices.DeleteFunc(groups, func(g string) bool { return g == id })
+		delete(o.assigned, app+"/"+id)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (o *Okta) listGroupUsers(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := r.PathValue("id")
+	if o.group(id) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	users := []*okta.User{}
+
+	for _, uid := range o.members[id] {
+		if u := o.user(uid); u != nil {
+			users = append(users, u)
+		}
+	}
+
+	writeOktaPage(w, r, o.PageSize, users, func(u *okta.User) string { return u.Id })
+}
+
+func (o *Okta) addGroupUser(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id, uid := r.PathValue("id"), r.PathValue("uid")
+	if o.group(id) == nil || o.user(uid) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	if !slices.Contains(o.members[id], uid) {
+		o.members[id] = append(o.members[id], uid)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (o *Okta) removeGroupUser(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id, uid := r.PathValue("id"), r.PathValue("uid")
+	if o.group(id) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	o.members[id] = slices.DeleteFunc(o.members[id], func(m string) bool { return m == uid })
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// groupOwner is an okta group owner, the okta sdk doesn't support group owners
+type groupOwner struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+func (o *Okta) listGroupOwners(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := r.PathValue("id")
+	if o.group(id) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	owners := []groupOwner{}
+	for _, uid := range o.owners[id] {
+		owners = append(owners, groupOwner{ID: uid, Type: "USER"})
+	}
+
+	writeOktaPage(w, r, o.PageSize, owners, func(g groupOwner) string { return g.ID })
+}
+
+func (o *Okta) addGroupOwner(w http.ResponseWriter, r *http.Request) {
+	owner := groupOwner{}
+	if err := json.NewDecoder(r.Body).Decode(&owner); err != nil || owner.ID == "" {
+		oktaError(w, http.StatusBadRequest, "E0000003", "the request body was not well-formed")
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := r.PathValue("id")
+	if o.group(id) == nil || o.user(owner.ID) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	if !slices.Contains(o.owners[id], owner.ID) {
+		o.owners[id] = append(o.owners[id], owner.ID)
+	}
+
+	writeJSON(w, http.StatusCreated, owner)
+}
+
+func (o *Okta) removeGroupOwner(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id, uid := r.PathValue("id"), r.PathValue("uid")
+	if o.group(id) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	o.owners[id] = slices.DeleteFunc(o.owners[id], func(m string) bool { return m == uid })
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (o *Okta) listUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	search := q.Get("search")
+	if search == "" {
+		search = q.Get("filter")
+	}
+
+	match, err := parseSearch(search)
+	if err != nil {
+		oktaError(w, http.StatusBadRequest, "E0000031", err.Error())
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	users := []*okta.User{}
+
+	for _, u := range o.users {
+		// like okta, deprovisioned users are only listed by searches
+		if u.Status == "DEPROVISIONED" && search == "" {
+			continue
+		}
+
+		if match(userAttr(u)) {
+			users = append(users, u)
+		}
+	}
+
+	writeOktaPage(w, r, o.PageSize, users, func(u *okta.User) string { return u.Id })
+}
+
+func (o *Okta) getUser(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	u := o.user(r.PathValue("id"))
+	if u == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (o *Okta) updateUser(w http.ResponseWriter, r *http.Request) {
+	update := &okta.User{}
+	if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+		oktaError(w, http.StatusBadRequest, "E0000003", "the request body was not well-formed")
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	u := o.user(r.PathValue("id"))
+	if u == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	// the okta client only makes partial updates, which merge the profile attributes
+	if update.Profile != nil {
+		for k, v := range *update.Profile {
+			(*u.Profile)[k] = v
+		}
+	}
+
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (o *Okta) deleteUser(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	u := o.user(r.PathValue("id"))
+	if u == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	// like okta, deleting an active user deactivates it and deleting a deactivated user deletes it
+	if u.Status != "DEPROVISIONED" {
+		u.Status = "DEPROVISIONED"
+	} else {
+		o.users = slices.DeleteFunc(o.users, func(ou *okta.User) bool { return ou.Id == u.Id })
+
+		for g, members := range o.members {
+			o.members[g] = slices.DeleteFunc(members, func(m string) bool { return m == u.Id })
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (o *Okta) listUserGroups(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := r.PathValue("id")
+	if o.user(id) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	groups := []*okta.Group{}
+
+	for _, g := range o.groups {
+		if slices.Contains(o.members[g.Id], id) {
+			groups = append(groups, g)
+		}
+	}
+
+	writeOktaPage(w, r, o.PageSize, groups, func(g *okta.Group) string { return g.Id })
+}
+
+func (o *Okta) userLifecycle(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	u := o.user(r.PathValue("id"))
+	if u == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	switch op := r.PathValue("op"); {
+	case op == "suspend" && u.Status == "ACTIVE":
+		u.Status = "SUSPENDED"
+	case op == "unsuspend" && u.Status == "SUSPENDED":
+		u.Status = "ACTIVE"
+	case op == "deactivate":
+		u.Status = "DEPROVISIONED"
+	case op == "activate" || op == "reactivate":
+		u.Status = "ACTIVE"
+	default:
+		oktaError(w, http.StatusBadRequest, "E0000001", "cannot "+op+" a user that is "+u.Status)
+		return
+	}
+
+	now := time.Now().UTC()
+	u.StatusChanged = &now
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (o *Okta) listApps(w http.ResponseWriter, r *http.Request) {
+	match, err := parseSearch(r.URL.Query().Get("filter"))
+	if err != nil {
+		oktaError(w, http.StatusBadRequest, "E0000031", err.Error())
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	apps := []*okta.Application{}
+
+	for _, a := range o.apps {
+		if match(appAttr(a)) {
+			apps = append(apps, a)
+		}
+	}
+
+	writeOktaPage(w, r, o.PageSize, apps, func(a *okta.Application) string { return a.Id })
+}
+
+func (o *Okta) listAppGroups(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := r.PathValue("id")
+	if o.app(id) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	assignments := []*okta.ApplicationGroupAssignment{}
+	for _, gid := range o.appGroups[id] {
+		assignments = append(assignments, o.appAssignment(id, gid))
+	}
+
+	writeOktaPage(w, r, o.PageSize, assignments, func(a *okta.ApplicationGroupAssignment) string { return a.Id })
+}
+
+func (o *Okta) assignAppGroup(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id, gid := r.PathValue("id"), r.PathValue("gid")
+	if o.app(id) == nil || o.group(gid) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	body := &okta.ApplicationGroupAssignment{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		oktaError(w, http.StatusBadRequest, "E0000003", "the request body was not well-formed")
+		return
+	}
+
+	if !slices.Contains(o.appGroups[id], gid) {
+		o.appGroups[id] = append(o.appGroups[id], gid)
+	}
+
+	// like okta, assignments without a priority keep their priority or get the lowest one
+	a := o.appAssignment(id, gid)
+
+	if body.PriorityPtr != nil {
+		a.Priority, a.PriorityPtr = *body.PriorityPtr, body.PriorityPtr
+	}
+
+	if body.Profile != nil {
+		a.Profile = body.Profile
+	}
+
+	o.assigned[id+"/"+gid] = a
+
+	writeJSON(w, http.StatusOK, a)
+}
+
+// appAssignment returns the assignment of a group to an application, with the lowest priority when it isn't set
+func (o *Okta) appAssignment(id, gid string) *okta.ApplicationGroupAssignment {
+	if a, ok := o.assigned[id+"/"+gid]; ok {
+		return a
+	}
+
+	priority := int64(len(o.appGroups[id]) - 1)
+
+	return &okta.ApplicationGroupAssignment{Id: gid, Priority: priority, PriorityPtr: okta.Int64Ptr(priority)}
+}
+
+func (o *Okta) removeAppGroup(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id, gid := r.PathValue("id"), r.PathValue("gid")
+	if o.app(id) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	o.appGroups[id] = slices.DeleteFunc(o.appGroups[id], func(g string) bool { return g == gid })
+	delete(o.assigned, id+"/"+gid)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (o *Okta) listPushMappings(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := r.PathValue("id")
+	if o.app(id) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	mappings := append([]*OktaPushMapping{}, o.pushes[id]...)
+
+	writeOktaPage(w, r, o.PageSize, mappings, func(m *OktaPushMapping) string { return m.ID })
+}
+
+func (o *Okta) createPushMapping(w http.ResponseWriter, r *http.Request) {
+	m := &OktaPushMapping{}
+	if err := json.NewDecoder(r.Body).Decode(m); err != nil || m.SourceGroupID == "" {
+		oktaError(w, http.StatusBadRequest, "E0000003", "the request body was not well-formed")
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := r.PathValue("id")
+	if o.app(id) == nil || o.group(m.SourceGroupID) == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	// like okta, only the groups assigned to the application can be pushed, once
+	if !slices.Contains(o.appGroups[id], m.SourceGroupID) || o.pushMapping(id, func(p *OktaPushMapping) bool { return p.SourceGroupID == m.SourceGroupID }) != nil {
+		oktaError(w, http.StatusBadRequest, "E0000001", "the group can't be pushed to the application")
+		return
+	}
+
+	o.ids++
+
+	m.ID = fmt.Sprintf("gPm%d", o.ids)
+	m.TargetGroupID = fmt.Sprintf("target-%d", o.ids)
+
+	if m.Status == "" {
+		m.Status = "ACTIVE"
+	}
+
+	o.pushes[id] = append(o.pushes[id], m)
+
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (o *Okta) getPushMapping(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	m := o.pushMapping(r.PathValue("id"), func(p *OktaPushMapping) bool { return p.ID == r.PathValue("mid") })
+	if m == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (o *Okta) updatePushMapping(w http.ResponseWriter, r *http.Request) {
+	body := &OktaPushMapping{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil || body.Status == "" {
+		oktaError(w, http.StatusBadRequest, "E0000003", "the request body was not well-formed")
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	m := o.pushMapping(r.PathValue("id"), func(p *OktaPushMapping) bool { return p.ID == r.PathValue("mid") })
+	if m == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	m.Status = body.Status
+
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (o *Okta) deletePushMapping(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id, mid := r.PathValue("id"), r.PathValue("mid")
+
+	m := o.pushMapping(id, func(p *OktaPushMapping) bool { return p.ID == mid })
+	if m == nil {
+		oktaNotFound(w)
+		return
+	}
+
+	// like okta, active mappings can't be deleted
+	if m.Status == "ACTIVE" {
+		oktaError(w, http.StatusBadRequest, "E0000001", "the mapping must be inactive to be deleted")
+		return
+	}
+
+	o.pushes[id] = slices.DeleteFunc(o.pushes[id], func(p *OktaPushMapping) bool { return p.ID == mid })
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pushMapping returns the first push group mapping of an application matching f, nil when there's none
+func (o *Okta) pushMapping(id string, f func(*OktaPushMapping) bool) *OktaPushMapping {
+	if i := slices.IndexFunc(o.pushes[id], f); i >= 0 {
+		return o.pushes[id][i]
+	}
+
+	return nil
+}
+
+func (o *Okta) listLogs(w http.ResponseWriter, r *http.Request) {
+	var since, until time.Time
+
+	if s := r.URL.Query().Get("since"); s != "" {
+		since, _ = time.Parse(time.RFC3339, s)
+	}
+
+	if s := r.URL.Query().Get("until"); s != "" {
+		until, _ = time.Parse(time.RFC3339, s)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	events := []*okta.LogEvent{}
+
+	for _, e := range o.logs {
+		if e.Published != nil && (e.Published.Before(since) || (!until.IsZero() && !e.Published.Before(until))) {
+			continue
+		}
+
+		events = append(events, e)
+	}
+
+	writeOktaPage(w, r, o.PageSize, events, func(e *okta.LogEvent) string { return e.Uuid })
+}
+
+func (o *Okta) group(id string) *okta.Group {
+	for _, g := range o.groups {
+		if g.Id == id {
+			return g
+		}
+	}
+
+	return nil
+}
+
+// user returns the okta user by id or login, like the okta users api
+func (o *Okta) user(id string) *okta.User {
+	for _, u := range o.users {
+		if login, _ := (*u.Profile)["login"].(string); u.Id == id || (login != "" && strings.EqualFold(login, id)) {
+			return u
+		}
+	}
+
+	return nil
+}
+
+func (o *Okta) app(id string) *okta.Application {
+	for _, a := range o.apps {
+		if a.Id == id {
+			return a
+		}
+	}
+
+	return nil
+}
+
+// writeOktaPage writes the page of results after the id in the after query parameter, with a link to the next
+// page like the okta api when there are more results
+func writeOktaPage[T any](w http.ResponseWriter, r *http.Request, pageSize int, results []T, id func(T) string) {
+	q := r.URL.Query()
+
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || (pageSize > 0 && limit > pageSize) {
+		limit = pageSize
+	}
+
+	start := 0
+
+	if after := q.Get("after"); after != "" {
+		start = slices.IndexFunc(results, func(v T) bool { return id(v) == after }) + 1
+	}
+
+	end := len(results)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+
+		next := url.Values{}
+		for k, v := range q {
+			next[k] = v
+		}
+
+		next.Set("after", id(results[end-1]))
+		next.Set("limit", strconv.Itoa(limit))
+
+		w.Header().Add("Link", fmt.Sprintf(`<https://%s%s?%s>; rel="next"`, r.Host, r.URL.Path, next.Encode()))
+	}
+
+	writeJSON(w, http.StatusOK, results[start:end])
+}
+
+// groupAttr returns the search attributes of an okta group
+func groupAttr(g *okta.Group) attrFunc {
+	return func(attr string) (string, bool) {
+		switch attr {
+		case "id":
+			return g.Id, true
+		case "type":
+			return g.Type, true
+		case "profile.name":
+			return g.Profile.Name, true
+		case "profile.description":
+			return g.Profile.Description, g.Profile.Description != ""
+		}
+
+		return profileAttr(g.Profile.GroupProfileMap, attr)
+	}
+}
+
+// userAttr returns the search attributes of an okta user
+func userAttr(u *okta.User) attrFunc {
+	return func(attr string) (string, bool) {
+		switch attr {
+		case "id":
+			return u.Id, true
+		case "status":
+			return u.Status, true
+		}
+
+		return profileAttr(*u.Profile, attr)
+	}
+}
+
+// appAttr returns the filter attributes of an okta application
+func appAttr(a *okta.Application) attrFunc {
+	return func(attr string) (string, bool) {
+		switch attr {
+		case "id":
+			return a.Id, true
+		case "name":
+			return a.Name, true
+		case "status":
+			return a.Status, true
+		}
+
+		return "", false
+	}
+}
+
+// profileAttr returns a profile.<key> attribute from the profile, nil and empty values aren't present
+func profileAttr(profile map[string]interface{}, attr string) (string, bool) {
+	key, ok := strings.CutPrefix(attr, "profile.")
+	if !ok {
+		return "", false
+	}
+
+	v, ok := profile[key]
+	if !ok || v == nil || v == "" {
+		return "", false
+	}
+
+	return fmt.Sprint(v), true
+}
+
+// oktaError writes an okta api error
+func oktaError(w http.ResponseWriter, status int, code, summary string) {
+	writeJSON(w, status, map[string]interface{}{
+		"errorCode":    code,
+		"errorSummary": summary,
+		"errorCauses":  []interface{}{},
+	})
+}
+
+func oktaNotFound(w http.ResponseWriter) {
+	oktaError(w, http.StatusNotFound, "E0000007", "Not found: Resource not found")
+}
+
+// writeJSON writes the json encoded response with the status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// clone returns a deep copy of v by round tripping it through json
+func clone[T any](v T) T {
+	var out T
+
+	b, _ := json.Marshal(v)
+	_ = json.Unmarshal(b, &out)
+
+	return out
+}
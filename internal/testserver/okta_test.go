@@ -0,0 +1,114 @@
+package testserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/okta/okta-sdk-golang/v2/okta/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOkta_pagination(t *testing.T) {
+	o := NewOkta()
+	defer o.Close()
+
+	o.PageSize = 2
+
+	for _, id := range []string{"okta-1", "okta-2", "okta-3", "okta-4", "okta-5"} {
+		o.AddUser(id, "ACTIVE", map[string]interface{}{"email": id + "@example.com"})
+	}
+
+	o.AddUser("okta-6", "SUSPENDED", map[string]interface{}{"email": "okta-6@example.com"})
+
+	_, c, err := okta.NewClient(context.TODO(),
+		okta.WithOrgUrl(o.URL),
+		okta.WithToken("okta-token"),
+		okta.WithCache(false),
+		okta.WithHttpClientPtr(o.Client()),
+	)
+	require.NoError(t, err)
+
+	users, resp, err := c.User.ListUsers(context.TODO(), &query.Params{Search: `status eq "ACTIVE"`, Limit: 200})
+	require.NoError(t, err)
+
+	for resp.HasNextPage() {
+		page := []*okta.User{}
+
+		resp, err = resp.Next(context.TODO(), &page)
+		require.NoError(t, err)
+
+		users = append(users, page...)
+	}
+
+	ids := []string{}
+	for _, u := range users {
+		ids = append(ids, u.Id)
+	}
+
+	assert.Equal(t, []string{"okta-1", "okta-2", "okta-3", "okta-4", "okta-5"}, ids)
+
+	// the search is kept on every page
+	pages := 0
+
+	for _, req := range o.Requests() {
+		if req.Path == "/api/v1/users" {
+			assert.Equal(t, `status eq "ACTIVE"`, req.Query.Get("search"))
+			pages++
+		}
+	}
+
+	assert.Equal(t, 3, pages)
+}
+
+func TestOkta_groupMembers(t *testing.T) {
+	o := NewOkta()
+	defer o.Close()
+
+	o.AddUser("okta-1", "ACTIVE", map[string]interface{}{"email": "one@example.com"})
+	o.AddGroup("00g-1", "Platform", map[string]interface{}{"governor_id": "group-1"})
+
+	req := func(method, path string) int {
+		r, err := http.NewRequestWithContext(context.TODO(), method, o.URL+path, nil)
+		require.NoError(t, err)
+
+		resp, err := o.Client().Do(r)
+		require.NoError(t, err)
+
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusNoContent, req(http.MethodPut, "/api/v1/groups/00g-1/users/okta-1"))
+	assert.Equal(t, []string{"okta-1"}, o.GroupMembers("00g-1"))
+
+	// adding a member twice is a no-op, like okta
+	assert.Equal(t, http.StatusNoContent, req(http.MethodPut, "/api/v1/groups/00g-1/users/okta-1"))
+	assert.Equal(t, []string{"okta-1"}, o.GroupMembers("00g-1"))
+
+	assert.Equal(t, http.StatusNotFound, req(http.MethodPut, "/api/v1/groups/00g-1/users/okta-2"))
+	assert.Equal(t, http.StatusNotFound, req(http.MethodPut, "/api/v1/groups/00g-2/users/okta-1"))
+
+	assert.Equal(t, http.StatusNoContent, req(http.MethodDelete, "/api/v1/groups/00g-1/users/okta-1"))
+	assert.Empty(t, o.GroupMembers("00g-1"))
+
+	// the user groups follow the memberships
+	assert.Equal(t, http.StatusNoContent, req(http.MethodPut, "/api/v1/groups/00g-1/users/okta-1"))
+
+	r, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, o.URL+"/api/v1/users/okta-1/groups", nil)
+	require.NoError(t, err)
+
+	resp, err := o.Client().Do(r)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	groups := []*okta.Group{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&groups))
+	require.Len(t, groups, 1)
+	assert.Equal(t, "group-1", groups[0].Profile.GroupProfileMap["governor_id"])
+}
@@ -0,0 +1,216 @@
+package testserver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errInvalidSearch is returned for search and filter expressions the fake okta api doesn't understand
+var errInvalidSearch = errors.New("invalid search expression")
+
+// attrFunc returns the value of an attribute (ie. status or profile.email) of an okta object and true if it's set
+type attrFunc func(attr string) (string, bool)
+
+// expr is a parsed okta search or filter expression
+type expr func(attrFunc) bool
+
+// parseSearch parses the subset of the okta search and filter expressions used by the clients: the eq, ne, sw
+// and pr operators, combined with and, or and parentheses.  An empty search matches everything.
+func parseSearch(s string) (expr, error) {
+	if strings.TrimSpace(s) == "" {
+		return func(attrFunc) bool { return true }, nil
+	}
+
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &searchParser{tokens: tokens}
+
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", errInvalidSearch, p.tokens[p.pos].value)
+	}
+
+	return e, nil
+}
+
+// token is a token of a search expression, quoted tokens are string values
+type token struct {
+	value  string
+	quoted bool
+}
+
+// tokenize splits a search expression into parentheses, words and quoted strings
+func tokenize(s string) ([]token, error) {
+	tokens := []token{}
+
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, token{value: string(c)})
+			i++
+		case c == '"':
+			var b strings.Builder
+
+			i++
+
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+
+				b.WriteByte(s[i])
+			}
+
+			if i == len(s) {
+				return nil, fmt.Errorf("%w: unterminated string", errInvalidSearch)
+			}
+
+			tokens = append(tokens, token{value: b.String(), quoted: true})
+			i++
+		default:
+			j := i
+			for j < len(s) && s[j] != ' ' && s[j] != '(' && s[j] != ')' {
+				j++
+			}
+
+			tokens = append(tokens, token{value: s[i:j]})
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
+
+// searchParser is a recursive descent parser of tokenized search expressions
+type searchParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *searchParser) peek(v string) bool {
+	return p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].value, v)
+}
+
+func (p *searchParser) next() (token, error) {
+	if p.pos >= len(p.tokens) {
+		return token{}, fmt.Errorf("%w: unexpected end", errInvalidSearch)
+	}
+
+	t := p.tokens[p.pos]
+	p.pos++
+
+	return t, nil
+}
+
+func (p *searchParser) or() (expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek("or") {
+		p.pos++
+
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		left = func(a attrFunc) bool { return l(a) || right(a) }
+	}
+
+	return left, nil
+}
+
+func (p *searchParser) and() (expr, error) {
+	left, err := p.clause()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek("and") {
+		p.pos++
+
+		right, err := p.clause()
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		left = func(a attrFunc) bool { return l(a) && right(a) }
+	}
+
+	return left, nil
+}
+
+func (p *searchParser) clause() (expr, error) {
+	if p.peek("(") {
+		p.pos++
+
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.peek(")") {
+			return nil, fmt.Errorf("%w: missing )", errInvalidSearch)
+		}
+
+		p.pos++
+
+		return e, nil
+	}
+
+	attr, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(op.value, "pr") {
+		return func(a attrFunc) bool {
+			_, ok := a(attr.value)
+			return ok
+		}, nil
+	}
+
+	val, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(op.value) {
+	case "eq":
+		return func(a attrFunc) bool {
+			v, ok := a(attr.value)
+			return ok && strings.EqualFold(v, val.value)
+		}, nil
+	case "ne":
+		return func(a attrFunc) bool {
+			v, ok := a(attr.value)
+			return !ok || !strings.EqualFold(v, val.value)
+		}, nil
+	case "sw":
+		return func(a attrFunc) bool {
+			v, ok := a(attr.value)
+			return ok && strings.HasPrefix(strings.ToLower(v), strings.ToLower(val.value))
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported operator %q", errInvalidSearch, op.value)
+	}
+}
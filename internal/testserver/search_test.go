@@ -0,0 +1,56 @@
+package testserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseSearch(t *testing.T) {
+	attrs := map[string]string{
+		"status":              "ACTIVE",
+		"profile.email":       "user@example.com",
+		"profile.governor_id": "group-1",
+	}
+
+	attr := func(a string) (string, bool) {
+		v, ok := attrs[a]
+		return v, ok
+	}
+
+	tests := []struct {
+		name    string
+		search  string
+		want    bool
+		wantErr bool
+	}{
+		{name: "empty", search: "", want: true},
+		{name: "eq", search: `profile.governor_id eq "group-1"`, want: true},
+		{name: "eq case insensitive", search: `profile.email eq "USER@example.com"`, want: true},
+		{name: "eq mismatch", search: `profile.governor_id eq "group-2"`, want: false},
+		{name: "ne", search: `profile.userType ne "service"`, want: true},
+		{name: "sw", search: `profile.email sw "user@"`, want: true},
+		{name: "pr", search: `profile.governor_id pr`, want: true},
+		{name: "pr missing", search: `profile.pending_delete_at pr`, want: false},
+		{name: "and", search: `(profile.email eq "user@example.com") and (status eq "ACTIVE" or status eq "SUSPENDED")`, want: true},
+		{name: "and mismatch", search: `profile.email eq "user@example.com" and status eq "SUSPENDED"`, want: false},
+		{name: "escaped quote", search: `profile.email eq "user\"@example.com"`, want: false},
+		{name: "unterminated string", search: `profile.email eq "user`, wantErr: true},
+		{name: "missing paren", search: `(status eq "ACTIVE"`, wantErr: true},
+		{name: "unsupported operator", search: `status gt "ACTIVE"`, wantErr: true},
+		{name: "trailing tokens", search: `status eq "ACTIVE" status`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := parseSearch(tt.search)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errInvalidSearch)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, match(attr))
+		})
+	}
+}
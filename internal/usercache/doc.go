@@ -0,0 +1,3 @@
+// Package usercache is a bounded cache of governor users keyed by email, shared by the sync commands and the
+// reconciler
+package usercache
@@ -0,0 +1,243 @@
+package usercache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "user_cache_requests_total",
+			Help:      "Total count of governor user cache lookups by cache name and result, hit or miss.",
+		},
+		[]string{"cache", "result"},
+	)
+
+	evictionsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "gov_okta_addon",
+			Name:      "user_cache_evictions_total",
+			Help:      "Total count of governor users evicted from the user cache by cache name and reason, size or expired.",
+		},
+		[]string{"cache", "reason"},
+	)
+)
+
+// Store caches governor users of type U by email
+type Store[U any] interface {
+	// Get returns the cached user of an email
+	Get(email string) (U, bool)
+	// GetOrLoad returns the cached user of an email, or loads and caches it.  Concurrent loads of the same email
+	// are only loaded once and load errors aren't cached.
+	GetOrLoad(ctx context.Context, email string, load func(context.Context) (U, error)) (U, error)
+	// Set caches the user of an email
+	Set(email string, u U)
+	// Delete removes the user of an email from the cache, ie. once the user changed
+	Delete(email string)
+}
+
+// Cache is a least recently used cache of governor users with a time to live, it's safe to share between
+// goroutines.  A nil Cache doesn't cache anything.
+type Cache[U any] struct {
+	name string
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu       sync.Mutex
+	lru      *list.List
+	entries  map[string]*list.Element
+	inflight map[string]*load[U]
+}
+
+var _ Store[any] = (*Cache[any])(nil)
+
+type entry[U any] struct {
+	email   string
+	user    U
+	expires time.Time
+}
+
+type load[U any] struct {
+	done chan struct{}
+	user U
+	err  error
+}
+
+// New returns a cache of up to size users that expire ttl after they're cached, the name labels the cache metrics.
+// A size of zero or less returns a nil (disabled) cache and a ttl of zero or less never expires the users.
+func New[U any](name string, size int, ttl time.Duration) *Cache[U] {
+	if size <= 0 {
+		return nil
+	}
+
+	return &Cache[U]{
+		name:     name,
+		size:     size,
+		ttl:      ttl,
+		now:      time.Now,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		inflight: make(map[string]*load[U]),
+	}
+}
+
+// Get returns the cached user of an email
+func (c *Cache[U]) Get(email string) (U, bool) {
+	if c == nil {
+		var zero U
+		return zero, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(email)
+}
+
+// GetOrLoad returns the cached user of an email, or loads and caches it.  Concurrent loads of the same email are
+// only loaded once and load errors aren't cached.
+func (c *Cache[U]) GetOrLoad(ctx context.Context, email string, loadFn func(context.Context) (U, error)) (U, error) {
+	if c == nil {
+		return loadFn(ctx)
+	}
+
+	c.mu.Lock()
+
+	if u, ok := c.get(email); ok {
+		c.mu.Unlock()
+		return u, nil
+	}
+
+	if l, ok := c.inflight[email]; ok {
+		c.mu.Unlock()
+
+		select {
+		case <-l.done:
+			return l.user, l.err
+		case <-ctx.Done():
+			var zero U
+			return zero, ctx.Err()
+		}
+	}
+
+	l := &load[U]{done: make(chan struct{})}
+	c.inflight[email] = l
+
+	c.mu.Unlock()
+
+	l.user, l.err = loadFn(ctx)
+
+	c.mu.Lock()
+
+	delete(c.inflight, email)
+
+	if l.err == nil {
+		c.set(email, l.user)
+	}
+
+	c.mu.Unlock()
+
+	close(l.done)
+
+	return l.user, l.err
+}
+
+// Set caches the user of an email
+func (c *Cache[U]) Set(email string, u U) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(email, u)
+}
+
+// Delete removes the user of an email from the cache
+func (c *Cache[U]) Delete(email string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[email]; ok {
+		c.remove(e)
+	}
+}
+
+// Len returns the number of cached users, including the expired users that weren't evicted yet
+func (c *Cache[U]) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// get returns the cached user of an email and evicts it when it expired, c.mu must be held
+func (c *Cache[U]) get(email string) (U, bool) {
+	var zero U
+
+	e, ok := c.entries[email]
+	if !ok {
+		requestsCounter.WithLabelValues(c.name, "miss").Inc()
+		return zero, false
+	}
+
+	ent := e.Value.(*entry[U])
+
+	if c.ttl > 0 && !c.now().Before(ent.expires) {
+		c.remove(e)
+
+		evictionsCounter.WithLabelValues(c.name, "expired").Inc()
+		requestsCounter.WithLabelValues(c.name, "miss").Inc()
+
+		return zero, false
+	}
+
+	c.lru.MoveToFront(e)
+
+	requestsCounter.WithLabelValues(c.name, "hit").Inc()
+
+	return ent.user, true
+}
+
+// set caches the user of an email and evicts the least recently used users over the size, c.mu must be held
+func (c *Cache[U]) set(email string, u U) {
+	ent := &entry[U]{email: email, user: u, expires: c.now().Add(c.ttl)}
+
+	if e, ok := c.entries[email]; ok {
+		e.Value = ent
+		c.lru.MoveToFront(e)
+
+		return
+	}
+
+	c.entries[email] = c.lru.PushFront(ent)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+
+		evictionsCounter.WithLabelValues(c.name, "size").Inc()
+	}
+}
+
+// remove removes a cache element, c.mu must be held
+func (c *Cache[U]) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*entry[U]).email)
+}
@@ -0,0 +1,112 @@
+package usercache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	ID string
+}
+
+func TestCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c := New[*testUser]("test", 2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("user-1@example.com", &testUser{ID: "user-1"})
+	c.Set("user-2@example.com", &testUser{ID: "user-2"})
+
+	// user-1 is the most recently used, so user-2 is evicted
+	u, ok := c.Get("user-1@example.com")
+	require.True(t, ok)
+	assert.Equal(t, "user-1", u.ID)
+
+	c.Set("user-3@example.com", &testUser{ID: "user-3"})
+
+	_, ok = c.Get("user-2@example.com")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+
+	c.Delete("user-3@example.com")
+
+	_, ok = c.Get("user-3@example.com")
+	assert.False(t, ok)
+
+	now = now.Add(time.Minute)
+
+	_, ok = c.Get("user-1@example.com")
+	assert.False(t, ok, "expired")
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+	c := New[*testUser]("test", 10, time.Minute)
+
+	var loads atomic.Int32
+
+	release := make(chan struct{})
+
+	load := func(context.Context) (*testUser, error) {
+		loads.Add(1)
+		<-release
+
+		return &testUser{ID: "user-1"}, nil
+	}
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			u, err := c.GetOrLoad(context.TODO(), "user-1@example.com", load)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", u.ID)
+		}()
+	}
+
+	// let the goroutines pile up on the inflight load before releasing it
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+
+	errLoad := errors.New("boom")
+
+	_, err := c.GetOrLoad(context.TODO(), "user-2@example.com", func(context.Context) (*testUser, error) {
+		return nil, errLoad
+	})
+	assert.ErrorIs(t, err, errLoad)
+
+	_, ok := c.Get("user-2@example.com")
+	assert.False(t, ok, "errors aren't cached")
+}
+
+func TestCache_nil(t *testing.T) {
+	c := New[*testUser]("test", 0, time.Minute)
+	require.Nil(t, c)
+
+	c.Set("user-1@example.com", &testUser{ID: "user-1"})
+
+	_, ok := c.Get("user-1@example.com")
+	assert.False(t, ok)
+
+	u, err := c.GetOrLoad(context.TODO(), "user-1@example.com", func(context.Context) (*testUser, error) {
+		return &testUser{ID: "user-1"}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", u.ID)
+	assert.Equal(t, 0, c.Len())
+}